@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/config"
+)
+
+// adminClient calls the admin API of the running instance. Operator
+// commands that change data go through it while MiniClaw runs, so the
+// instance does not overwrite the change with its own state.
+type adminClient struct {
+	baseURL    string
+	apiKey     string
+	enabled    bool
+	httpClient *http.Client
+}
+
+func newAdminClient(cfg *config.Config, apiKey string) *adminClient {
+	host := cfg.API.Host
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	return &adminClient{
+		baseURL:    "http://" + net.JoinHostPort(host, strconv.Itoa(cfg.API.Port)),
+		apiKey:     apiKey,
+		enabled:    cfg.API.Enabled,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// running reports whether MiniClaw answers on the admin API.
+func (c *adminClient) running() bool {
+	if !c.enabled {
+		return false
+	}
+
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Get(c.baseURL + "/api/status")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// call sends in as JSON unless it is nil and decodes the response into out
+// unless it is nil. Responses other than 2xx are returned as errors with the
+// API's message.
+func (c *adminClient) call(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the admin API, is MiniClaw running?: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return fmt.Errorf("%s, pass an API key with --key or MINICLAW_API_KEY", apiErr.Error)
+		}
+		return fmt.Errorf("%s", apiErr.Error)
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/auth"
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const apiKeyUsage = `Usage:
+  miniclaw apikey create --name <name> --scopes chat,metrics:read,admin
+  miniclaw apikey list
+  miniclaw apikey rotate <id>
+  miniclaw apikey revoke <id>`
+
+func runAPIKeyCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", apiKeyUsage)
+	}
+
+	configMgr, err := config.NewFileConfigManager("./configs/config.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	store := auth.NewAPIKeyStore(storage.NewFileStorage(configMgr.GetConfig().Storage.BasePath))
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := flags.String("name", "", "descriptive name for the key")
+		scopes := flags.String("scopes", string(auth.ScopeChat), "comma separated scopes: chat, metrics:read, admin")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		parsed, err := auth.ParseScopes(strings.Split(*scopes, ","))
+		if err != nil {
+			return err
+		}
+
+		key, secret, err := store.Create(ctx, *name, parsed)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created API key %s (%s) with scopes %s\n", key.ID, key.Name, formatScopes(key.Scopes))
+		fmt.Printf("Key: %s\n", secret)
+		fmt.Println("Store it now, it cannot be shown again.")
+
+	case "list":
+		keys, err := store.List(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED\tSTATUS")
+		for _, key := range keys {
+			status := "active"
+			if key.Revoked() {
+				status = "revoked " + key.RevokedAt.Format(time.RFC3339)
+			}
+			lastUsed := "never"
+			if key.LastUsedAt != nil {
+				lastUsed = key.LastUsedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s…\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Prefix, formatScopes(key.Scopes),
+				key.CreatedAt.Format(time.RFC3339), lastUsed, status)
+		}
+		return w.Flush()
+
+	case "rotate":
+		if len(args) != 2 {
+			return fmt.Errorf("rotate needs a key id\n%s", apiKeyUsage)
+		}
+
+		key, secret, err := store.Rotate(ctx, args[1])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Rotated API key %s (%s), the previous value no longer works\n", key.ID, key.Name)
+		fmt.Printf("Key: %s\n", secret)
+
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("revoke needs a key id\n%s", apiKeyUsage)
+		}
+
+		if err := store.Revoke(ctx, args[1]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Revoked API key %s\n", args[1])
+
+	default:
+		return fmt.Errorf("unknown subcommand: %s\n%s", args[0], apiKeyUsage)
+	}
+
+	return nil
+}
+
+func formatScopes(scopes []auth.Scope) string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+	return strings.Join(names, ",")
+}
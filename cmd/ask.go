@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const askUsage = `Usage:
+  miniclaw ask [--session <id>] [--yes] [--timeout 5m] [run flags] <prompt>
+  echo <prompt> | miniclaw ask [flags]
+
+Runs one agent turn without starting the channels and prints the answer.
+The exit code is 1 when the agent fails or does not answer in time.`
+
+// askOptions are the run flags plus the ones for a single question.
+type askOptions struct {
+	*runOptions
+	session string
+	yes     bool
+	timeout time.Duration
+	prompt  string
+}
+
+func parseAskFlags(args []string, stdin io.Reader) (*askOptions, error) {
+	opts := &askOptions{runOptions: &runOptions{}}
+
+	flags := flag.NewFlagSet("ask", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), askUsage)
+		flags.PrintDefaults()
+	}
+	opts.register(flags)
+	flags.StringVar(&opts.session, "session", "", "chat to continue, its history is used and kept (default a new chat that is discarded)")
+	flags.BoolVar(&opts.yes, "yes", false, "allow tool calls that need confirmation instead of declining them")
+	flags.DurationVar(&opts.timeout, "timeout", 5*time.Minute, "give up when there is no answer after this long")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+
+	opts.prompt = strings.Join(flags.Args(), " ")
+	if opts.prompt == "" || opts.prompt == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the prompt: %w", err)
+		}
+		opts.prompt = string(data)
+	}
+	if strings.TrimSpace(opts.prompt) == "" {
+		return nil, fmt.Errorf("missing prompt\n%s", askUsage)
+	}
+
+	return opts, nil
+}
+
+// runAsk sends the prompt to the agent and prints the answer to stdout. Tool
+// calls that need confirmation are declined unless --yes was given, since
+// there is nobody to ask.
+func runAsk(ctx context.Context, messageBus bus.MessageBus, sessionStorage storage.SessionStorage, opts *askOptions) error {
+	chatID := opts.session
+	if chatID == "" {
+		chatID = fmt.Sprintf("ask-%d", time.Now().UnixNano())
+		defer func() {
+			if err := sessionStorage.ClearSession(context.WithoutCancel(ctx), chatID); err != nil {
+				logger.Warn("Failed to discard the chat", "chat_id", chatID, "error", err)
+			}
+		}()
+	}
+
+	request := &bus.Message{
+		ID:        fmt.Sprintf("ask-%d", time.Now().UnixNano()),
+		Channel:   bus.ChannelCLI,
+		ChatID:    chatID,
+		Content:   opts.prompt,
+		Timestamp: time.Now(),
+	}
+
+	replies := make(chan *bus.Message, 8)
+	handlerID, err := messageBus.Subscribe(bus.ChannelCLI, func(ctx context.Context, msg *bus.Message) error {
+		if msg.ReplyTo() != request.ID || msg.IsControl() {
+			return nil
+		}
+		select {
+		case replies <- msg:
+		default:
+			logger.Warn("Dropping reply nobody waits for", "reply_to", request.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to replies: %w", err)
+	}
+	defer messageBus.Unsubscribe(bus.ChannelCLI, handlerID)
+
+	if err := messageBus.Publish(ctx, bus.ChannelCLI, request); err != nil {
+		return fmt.Errorf("failed to send the prompt: %w", err)
+	}
+
+	timeout := time.NewTimer(opts.timeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case reply := <-replies:
+			if isConfirmation(reply) {
+				logger.Info("Answering tool confirmation", "approved", opts.yes, "prompt", reply.Content)
+				if err := messageBus.Publish(ctx, bus.ChannelCLI, confirmationAnswer(request, opts.yes)); err != nil {
+					return fmt.Errorf("failed to answer the confirmation: %w", err)
+				}
+				continue
+			}
+			if reply.ErrorCode() != "" {
+				return fmt.Errorf("%s", reply.Content)
+			}
+
+			fmt.Println(strings.TrimRight(reply.Content, "\n"))
+			return nil
+
+		case <-timeout.C:
+			return fmt.Errorf("no answer after %s", opts.timeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// isConfirmation reports whether reply asks to allow a tool call rather than
+// answering the prompt.
+func isConfirmation(reply *bus.Message) bool {
+	for _, row := range reply.Buttons() {
+		for _, button := range row {
+			if strings.HasPrefix(button.Data, "confirm:") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func confirmationAnswer(request *bus.Message, approved bool) *bus.Message {
+	data := "confirm:no"
+	if approved {
+		data = "confirm:yes"
+	}
+
+	return &bus.Message{
+		ID:        fmt.Sprintf("ask-%d", time.Now().UnixNano()),
+		Channel:   bus.ChannelCLI,
+		ChatID:    request.ChatID,
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{bus.MetadataCallback: &bus.Callback{Data: data}},
+	}
+}
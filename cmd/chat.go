@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/communication/cli"
+	"github.com/wjffsx/miniclaw_go/internal/config"
+)
+
+// chatLogFile keeps logs off the terminal while chatting.
+const chatLogFile = "miniclaw.log"
+
+func openChatLog(cfg *config.Config) (*os.File, error) {
+	if err := os.MkdirAll(cfg.Storage.BasePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(cfg.Storage.BasePath, chatLogFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return file, nil
+}
+
+// runChat talks to the agent from the terminal until the user exits.
+func runChat(ctx context.Context, messageBus bus.MessageBus, cfg *config.Config) error {
+	repl := cli.NewCLI(messageBus, ctx)
+	repl.SetChatMode(true)
+	repl.SetHistoryFile(filepath.Join(cfg.Storage.BasePath, "cli_history"))
+	registerChatCommands(repl)
+
+	handler := cli.NewHandler(repl)
+	if _, err := messageBus.Subscribe(bus.ChannelCLI, handler.HandleMessage); err != nil {
+		return fmt.Errorf("failed to subscribe CLI handler: %w", err)
+	}
+
+	return repl.Start()
+}
+
+func registerChatCommands(repl *cli.CLI) {
+	repl.RegisterCommand("model", cli.Command{
+		Name:        "model",
+		Description: "Show the available models or switch to another one",
+		Handler:     cmdModel,
+		Usage:       "/model [name]",
+	})
+
+	repl.RegisterCommand("clear", cli.Command{
+		Name:        "clear",
+		Description: "Clear the conversation history",
+		Handler: func(args []string) error {
+			agentService.ClearChatHistory(repl.GetChatID())
+			fmt.Println("Conversation cleared.")
+			return nil
+		},
+		Usage: "/clear",
+	})
+
+	repl.RegisterCommand("tools", cli.Command{
+		Name:        "tools",
+		Description: "List the tools the agent can use",
+		Handler:     cmdTools,
+		Usage:       "/tools",
+	})
+}
+
+func cmdModel(args []string) error {
+	manager := agentService.GetLLMManager()
+	if manager == nil {
+		return fmt.Errorf("LLM is not configured")
+	}
+
+	if len(args) > 0 {
+		if err := manager.SwitchModel(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to %s\n", args[0])
+		return nil
+	}
+
+	current := manager.GetCurrentModel()
+	models := manager.ListModels()
+	sort.Strings(models)
+	for _, name := range models {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, name)
+	}
+	return nil
+}
+
+func cmdTools(args []string) error {
+	registered := agentService.GetToolRegistry().List()
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name() < registered[j].Name() })
+
+	for _, tool := range registered {
+		fmt.Printf("  %-24s %s\n", tool.Name(), tool.Description())
+	}
+	fmt.Printf("%d tools\n", len(registered))
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const deadLettersUsage = `Usage:
+  miniclaw deadletters list
+  miniclaw deadletters show <id>
+  miniclaw deadletters replay [--key <api-key>] <id>
+  miniclaw deadletters delete <id>`
+
+// runDeadLettersCommand inspects the messages the bus gave up on. Replaying
+// goes through the admin API, since the message has to be handled by the
+// running instance.
+func runDeadLettersCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", deadLettersUsage)
+	}
+
+	flags := flag.NewFlagSet("deadletters "+args[0], flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "config file")
+	apiKey := flags.String("key", os.Getenv("MINICLAW_API_KEY"), "API key with operator access, used by replay")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewFileConfigManager(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	store := bus.NewDeadLetterStore(storage.NewFileStorage(cfg.Storage.BasePath))
+	ctx := context.Background()
+
+	if args[0] != "list" && flags.NArg() != 1 {
+		return fmt.Errorf("%s needs a dead letter id\n%s", args[0], deadLettersUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		letters, err := store.List(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tFAILED\tCHANNEL\tCHAT\tATTEMPTS\tMESSAGE\tERROR")
+		for _, letter := range letters {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", letter.ID, letter.FailedAt.Format(time.RFC3339), letter.Channel,
+				letter.ChatID, letter.Attempts, logging.Preview(letter.Content, 40), logging.Preview(letter.Error, 60))
+		}
+		return w.Flush()
+
+	case "show":
+		letter, err := store.Get(ctx, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(letter, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+
+	case "replay":
+		if !cfg.API.Enabled {
+			return fmt.Errorf("replay needs the admin API of the running instance, enable api in %s", *configPath)
+		}
+		if err := replayDeadLetter(cfg, *apiKey, flags.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Printf("Replayed dead letter %s\n", flags.Arg(0))
+
+	case "delete":
+		if err := store.Delete(ctx, flags.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted dead letter %s\n", flags.Arg(0))
+
+	default:
+		return fmt.Errorf("unknown subcommand: %s\n%s", args[0], deadLettersUsage)
+	}
+
+	return nil
+}
+
+func replayDeadLetter(cfg *config.Config, apiKey, id string) error {
+	client := newAdminClient(cfg, apiKey)
+	if err := client.call(http.MethodPost, "/api/deadletters/"+url.PathEscape(id)+"/replay", nil, nil); err != nil {
+		return fmt.Errorf("failed to replay dead letter %s: %w", id, err)
+	}
+	return nil
+}
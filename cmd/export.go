@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/export"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const exportUsage = `Usage:
+  miniclaw export [--output conversation.html] <chat-id>
+  miniclaw export --share [--ttl 24h] <chat-id>`
+
+func runExportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "config file")
+	output := flags.String("output", "", "write the HTML page to this file instead of stdout")
+	share := flags.Bool("share", false, "create an expiring share link served by the admin API")
+	ttl := flags.Duration("ttl", export.DefaultShareTTL, "how long the share link stays valid")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("export needs a chat id\n%s", exportUsage)
+	}
+	chatID := flags.Arg(0)
+
+	configMgr, err := config.NewFileConfigManager(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	fileStorage := storage.NewFileStorage(cfg.Storage.BasePath)
+	exporter, err := export.NewExporter(&export.Config{
+		SessionStorage: storage.NewFileSystemSessionStorage(cfg.Storage.BasePath + "/sessions"),
+		Storage:        fileStorage,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	page, err := exporter.Export(ctx, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to export %s: %w", chatID, err)
+	}
+
+	if *share {
+		link, err := export.NewShareStore(fileStorage).Create(ctx, chatID, *ttl)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Share link: http://%s:%d/share/%s\n", cfg.API.Host, cfg.API.Port, link.Token)
+		fmt.Printf("Expires: %s\n", link.ExpiresAt.Format(time.RFC3339))
+		if !cfg.API.Enabled {
+			fmt.Println("The admin API is disabled, enable it to serve the link.")
+		}
+		return nil
+	}
+
+	if *output == "" {
+		_, err := os.Stdout.Write(page)
+		return err
+	}
+
+	if err := os.WriteFile(*output, page, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *output, err)
+	}
+	fmt.Printf("Exported %s to %s\n", chatID, *output)
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const fsckUsage = `Usage:
+  miniclaw fsck [--repair]`
+
+// runFsckCommand checks the data directory for files damaged by a crash.
+// Stop MiniClaw before repairing, since it rewrites files in place.
+func runFsckCommand(args []string) error {
+	flags := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "config file")
+	repair := flags.Bool("repair", false, "drop invalid lines and delete leftover temporary files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("fsck takes no arguments\n%s", fsckUsage)
+	}
+
+	configMgr, err := config.NewFileConfigManager(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	report, err := storage.Check(context.Background(), cfg.Storage.BasePath, *repair)
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range report.Problems {
+		status := ""
+		if problem.Repaired {
+			status = " (repaired)"
+		}
+		fmt.Printf("%s: %s%s\n", problem.Path, problem.Problem, status)
+	}
+	fmt.Printf("Checked %d files in %s, %d problem(s)\n", report.Files, cfg.Storage.BasePath, len(report.Problems))
+
+	if remaining := len(report.Unrepaired()); remaining > 0 {
+		if !*repair {
+			return fmt.Errorf("%d problem(s) found, run with --repair to fix what can be fixed", remaining)
+		}
+		return fmt.Errorf("%d problem(s) could not be repaired", remaining)
+	}
+	return nil
+}
@@ -2,26 +2,44 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/wjffsx/miniclaw_go/internal/agent"
+	"github.com/wjffsx/miniclaw_go/internal/api"
+	"github.com/wjffsx/miniclaw_go/internal/auth"
 	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/chaos"
+	"github.com/wjffsx/miniclaw_go/internal/communication/discord"
+	"github.com/wjffsx/miniclaw_go/internal/communication/email"
 	"github.com/wjffsx/miniclaw_go/internal/communication/telegram"
 	"github.com/wjffsx/miniclaw_go/internal/communication/websocket"
 	"github.com/wjffsx/miniclaw_go/internal/config"
+	agentcontext "github.com/wjffsx/miniclaw_go/internal/context"
+	"github.com/wjffsx/miniclaw_go/internal/export"
 	"github.com/wjffsx/miniclaw_go/internal/filetools"
+	"github.com/wjffsx/miniclaw_go/internal/intent"
 	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 	"github.com/wjffsx/miniclaw_go/internal/mcp"
 	"github.com/wjffsx/miniclaw_go/internal/memory"
+	"github.com/wjffsx/miniclaw_go/internal/plugins"
 	"github.com/wjffsx/miniclaw_go/internal/scheduler"
 	"github.com/wjffsx/miniclaw_go/internal/search"
 	"github.com/wjffsx/miniclaw_go/internal/skills"
+	"github.com/wjffsx/miniclaw_go/internal/speech"
 	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/templates"
 	"github.com/wjffsx/miniclaw_go/internal/tools"
+	"github.com/wjffsx/miniclaw_go/internal/tracing"
+	"github.com/wjffsx/miniclaw_go/internal/webhook"
+	"github.com/wjffsx/miniclaw_go/internal/workspace"
 )
 
 const (
@@ -29,100 +47,461 @@ const (
 )
 
 var (
-	telegramBot     *telegram.Bot
-	websocketServer *websocket.Server
-	agentService    *agent.Agent
-	skillWatcher    *skills.SkillFileWatcher
-	mcpManager      *mcp.MCPManager
-	taskManager     *scheduler.TaskManager
+	telegramBot      *telegram.Bot
+	discordBot       *discord.Bot
+	emailClient      *email.Client
+	websocketServer  *websocket.Server
+	agentService     *agent.Agent
+	skillWatcher     *skills.SkillFileWatcher
+	mcpManager       *mcp.MCPManager
+	pluginLoader     *plugins.Loader
+	taskManager      *scheduler.TaskManager
+	workspaceWatcher *workspace.Watcher
+	apiServer        *api.Server
+	faultInjector    *chaos.Injector
+	deadLetters      *bus.DeadLetterStore
 )
 
+var logger = logging.For("main")
+
+// subcommands are operator commands that work on the data directory, or
+// on the admin API while MiniClaw runs, instead of starting it.
+var subcommands = map[string]func(args []string) error{
+	"apikey":      runAPIKeyCommand,
+	"export":      runExportCommand,
+	"deadletters": runDeadLettersCommand,
+	"transcripts": runTranscriptsCommand,
+	"fsck":        runFsckCommand,
+	"tasks":       runTasksCommand,
+	"sessions":    runSessionsCommand,
+	"skills":      runSkillsCommand,
+	"tools":       runToolsCommand,
+}
+
 func main() {
-	log.Printf("MiniClaw Go v%s starting...", version)
-	log.Println("========================================")
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	// Registered first so it runs after the other deferred cleanups.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	opts := &runOptions{configPath: defaultConfigPath}
+	var ask *askOptions
+	chatMode := len(os.Args) > 1 && os.Args[1] == "chat"
+	stdioMode := len(os.Args) > 1 && os.Args[1] == "stdio"
+	if len(os.Args) > 1 && (os.Args[1] == "run" || chatMode || stdioMode || os.Args[1] == "ask") {
+		var err error
+		if os.Args[1] == "ask" {
+			if ask, err = parseAskFlags(os.Args[2:], os.Stdin); err == nil {
+				opts = ask.runOptions
+			}
+		} else {
+			opts, err = parseRunFlags(os.Args[2:])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	configMgr, err := config.NewFileConfigManager("./configs/config.yaml")
+	configMgr, err := config.NewFileConfigManager(opts.configPath)
 	if err != nil {
-		log.Fatalf("Failed to initialize config manager: %v", err)
+		fatal("Failed to initialize config manager", err)
 	}
 
 	cfg := configMgr.GetConfig()
-	log.Printf("Configuration loaded successfully")
-	log.Printf("Telegram: %v", cfg.Telegram.Enabled)
-	log.Printf("WebSocket: %v", cfg.WebSocket.Enabled)
-	log.Printf("LLM Provider: %s", cfg.LLM.Provider)
+	generated := !configMgr.Exists()
+	opts.apply(cfg, generated)
+	if ask != nil || stdioMode {
+		agentOnly(cfg)
+	}
 
-	messageBus := bus.NewInMemoryMessageBus(ctx)
-	messageBus.Start()
-	defer messageBus.Close()
-	log.Println("Message bus started")
+	var logOutput io.Writer = os.Stderr
+	if chatMode || ask != nil {
+		logFile, err := openChatLog(cfg)
+		if err != nil {
+			fatal("Failed to open log file", err)
+		}
+		defer logFile.Close()
+		logOutput = logFile
+	}
+	if err := logging.Setup(&logging.Config{
+		Level:   cfg.Logging.Level,
+		Format:  cfg.Logging.Format,
+		Modules: cfg.Logging.Modules,
+	}, logOutput); err != nil {
+		fatal("Failed to configure logging", err)
+	}
+	logger.Info("MiniClaw Go starting", "version", version)
 
-	sessionStorage := storage.NewFileSystemSessionStorage(cfg.Storage.BasePath + "/sessions")
-	memoryStorage := storage.NewFileSystemMemoryStorage(cfg.Storage.BasePath + "/memory")
-	fileStorage := storage.NewFileStorage(cfg.Storage.BasePath)
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\nFix %s and start again.\n", err, configMgr.Path())
+		os.Exit(1)
+	}
 
-	log.Printf("Storage initialized at: %s", cfg.Storage.BasePath)
+	// Embedded runs do not leave a config file behind.
+	if generated && ask == nil && !stdioMode {
+		if err := configMgr.Save(); err != nil {
+			logger.Error("Failed to write config file", "error", err)
+		} else {
+			logger.Info("No config file found, wrote the current settings for later customization", "path", configMgr.Path())
+		}
+	}
+	if opts.chaos {
+		cfg.Chaos.Enabled = true
+	}
+	configMgr.SetOverrides(func(reloaded *config.Config) {
+		opts.apply(reloaded, false)
+		if opts.chaos {
+			reloaded.Chaos.Enabled = true
+		}
+	})
+	logger.Info("Configuration loaded",
+		"telegram", cfg.Telegram.Enabled,
+		"discord", cfg.Discord.Enabled,
+		"email", cfg.Email.Enabled,
+		"websocket", cfg.WebSocket.Enabled,
+		"llm_provider", cfg.LLM.Provider,
+		"log_level", cfg.Logging.Level)
+
+	if cfg.Tracing.Enabled {
+		tracer, err := tracing.NewTracer(&tracing.Config{
+			Endpoint:    cfg.Tracing.Endpoint,
+			Headers:     cfg.Tracing.Headers,
+			ServiceName: cfg.Tracing.ServiceName,
+		})
+		if err != nil {
+			fatal("Failed to configure tracing", err)
+		}
+		tracer.Start(context.WithoutCancel(ctx))
+		tracing.SetTracer(tracer)
+		defer func() {
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tracer.Shutdown(flushCtx); err != nil {
+				logger.Warn("Failed to export the last spans", "error", err)
+			}
+		}()
+		logger.Info("Tracing enabled", "endpoint", cfg.Tracing.Endpoint)
+	}
+
+	// The bus outlives ctx so replies to conversations still in progress at
+	// shutdown are delivered; it stops when it is closed.
+	inMemoryBus := bus.NewInMemoryMessageBus(context.WithoutCancel(ctx), logging.For("bus"))
+	inMemoryBus.Start()
+	defer inMemoryBus.Close()
+	logger.Info("Message bus started")
+
+	var messageBus bus.MessageBus = inMemoryBus
+	var sessionStorage storage.SessionStorage = storage.NewFileSystemSessionStorage(cfg.Storage.BasePath + "/sessions")
+	var memoryStorage storage.MemoryStorage = storage.NewFileSystemMemoryStorage(cfg.Storage.BasePath + "/memory")
+	var fileStorage storage.Storage = storage.NewFileStorage(cfg.Storage.BasePath)
+
+	if cfg.Storage.Backend == "s3" {
+		s3Storage, err := storage.NewS3Storage(&storage.S3Config{
+			Endpoint:        cfg.Storage.S3.Endpoint,
+			Region:          cfg.Storage.S3.Region,
+			Bucket:          cfg.Storage.S3.Bucket,
+			Prefix:          cfg.Storage.S3.Prefix,
+			AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+			SessionToken:    cfg.Storage.S3.SessionToken,
+			PathStyle:       cfg.Storage.S3.PathStyle,
+		})
+		if err != nil {
+			fatal("Failed to configure S3 storage", err)
+		}
+		// Same layout as on disk, so a data directory can be copied into the
+		// bucket as it is.
+		sessionStorage = storage.NewObjectSessionStorage(s3Storage, "sessions")
+		memoryStorage = storage.NewObjectMemoryStorage(s3Storage, "memory")
+		fileStorage = s3Storage
+	}
+
+	if cfg.Chaos.Enabled {
+		injector, err := chaos.NewInjector(&chaos.Config{
+			Seed:                cfg.Chaos.Seed,
+			LLMTimeout:          cfg.Chaos.LLMTimeout,
+			ToolError:           cfg.Chaos.ToolError,
+			BusDelay:            cfg.Chaos.BusDelay,
+			MaxBusDelay:         time.Duration(cfg.Chaos.MaxBusDelay) * time.Millisecond,
+			StorageWriteFailure: cfg.Chaos.StorageWriteFailure,
+		})
+		if err != nil {
+			fatal("Failed to configure fault injection", err)
+		}
+		faultInjector = injector
+		messageBus = chaos.NewMessageBus(messageBus, injector)
+		sessionStorage = chaos.NewSessionStorage(sessionStorage, injector)
+		fileStorage = chaos.NewStorage(fileStorage, injector)
+	}
 
-	if err := initializeCommunication(ctx, messageBus, cfg); err != nil {
-		log.Fatalf("Failed to initialize communication: %v", err)
+	// Cross-cutting concerns for every channel. Recover comes first so a
+	// panic anywhere in the chain becomes an ordinary handler error.
+	busLogger := logging.For("bus")
+	messageBus = bus.NewMiddlewareBus(messageBus, bus.Recover(busLogger), bus.Tracing(), bus.Logging(busLogger))
+
+	var sessionCache *storage.CachedSessionStorage
+	if cfg.Storage.SessionCache.Enabled {
+		sessionCache = storage.NewCachedSessionStorage(sessionStorage, &storage.SessionCacheConfig{
+			MaxSessions:   cfg.Storage.SessionCache.MaxSessions,
+			FlushInterval: time.Duration(cfg.Storage.SessionCache.FlushInterval) * time.Second,
+		})
+		sessionCache.Start(ctx)
+		sessionStorage = sessionCache
+	}
+
+	if cfg.Storage.Backend == "s3" {
+		logger.Info("Storage initialized", "backend", "s3", "bucket", cfg.Storage.S3.Bucket, "prefix", cfg.Storage.S3.Prefix)
+	} else {
+		logger.Info("Storage initialized", "path", cfg.Storage.BasePath)
+	}
+
+	if cfg.Bus.DeadLetters {
+		deadLetters = bus.NewDeadLetterStore(fileStorage)
+	}
+	inMemoryBus.SetRetryPolicy(&bus.RetryPolicy{
+		MaxAttempts:    cfg.Bus.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.Bus.InitialBackoff) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.Bus.MaxBackoff) * time.Millisecond,
+	}, deadLetters)
+
+	if err := initializeCommunication(ctx, messageBus, cfg, fileStorage); err != nil {
+		fatal("Failed to initialize communication", err)
 	}
 
 	if err := initializeAgent(ctx, messageBus, cfg, sessionStorage, memoryStorage, fileStorage); err != nil {
-		log.Fatalf("Failed to initialize agent: %v", err)
+		fatal("Failed to initialize agent", err)
+	}
+
+	if cfg.API.Enabled {
+		if err := initializeAPI(ctx, messageBus, cfg, sessionStorage, fileStorage); err != nil {
+			logger.Error("Failed to start API server", "error", err)
+		}
 	}
 
-	log.Println("========================================")
-	log.Println("MiniClaw Go is ready!")
-	log.Println("Press Ctrl+C to stop")
+	if ask == nil {
+		configMgr.AddWatcher(newConfigReloader(cfg))
+		if err := configMgr.Watch(ctx); err != nil {
+			logger.Warn("Config changes will not be picked up until restart", "error", err)
+		}
+	}
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	<-sigCh
-	log.Println("Shutting down...")
+	// ask and stdio fail with an exit code, read once they are done.
+	chatDone := make(chan struct{})
+	var embeddedErr error
+	if ask != nil {
+		go func() {
+			defer close(chatDone)
+			embeddedErr = runAsk(ctx, messageBus, sessionStorage, ask)
+		}()
+	} else if stdioMode {
+		go func() {
+			defer close(chatDone)
+			embeddedErr = runStdio(ctx, messageBus)
+		}()
+	} else if chatMode {
+		go func() {
+			defer close(chatDone)
+			if err := runChat(ctx, messageBus, cfg); err != nil {
+				logger.Error("Chat stopped", "error", err)
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}()
+	} else {
+		logger.Info("MiniClaw Go is ready, press Ctrl+C to stop")
+	}
+
+	select {
+	case <-sigCh:
+		if ask != nil {
+			exitCode = 1
+		}
+	case <-chatDone:
+		if embeddedErr != nil {
+			logger.Error("Stopped with an error", "error", embeddedErr)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", embeddedErr)
+			exitCode = 1
+		}
+	}
+	logger.Info("Shutting down")
 
 	cancel()
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Conversations get the drain timeout, the channels and storage the rest.
+	drainTimeout := time.Duration(cfg.Agent.ShutdownTimeout) * time.Second
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), drainTimeout+10*time.Second)
 	defer shutdownCancel()
 
-	if err := gracefulShutdown(shutdownCtx, messageBus); err != nil {
-		log.Printf("Error during shutdown: %v", err)
+	if err := gracefulShutdown(shutdownCtx, messageBus, drainTimeout); err != nil {
+		logger.Error("Error during shutdown", "error", err)
 	}
 
-	log.Println("MiniClaw Go stopped gracefully")
+	if sessionCache != nil {
+		if err := sessionCache.Flush(shutdownCtx); err != nil {
+			logger.Error("Failed to save session messages", "error", err)
+		}
+	}
+
+	logger.Info("MiniClaw Go stopped gracefully")
+}
+
+func fatal(msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
 
-func initializeCommunication(ctx context.Context, messageBus bus.MessageBus, cfg *config.Config) error {
+func initializeCommunication(ctx context.Context, messageBus bus.MessageBus, cfg *config.Config, fileStorage storage.Storage) error {
 	if cfg.Telegram.Enabled {
-		log.Println("Initializing Telegram bot...")
+		logger.Info("Initializing Telegram bot")
 
 		tgCfg := &telegram.Config{
-			Token: cfg.Telegram.Token,
+			Token:       cfg.Telegram.Token,
+			Storage:     fileStorage,
+			UploadDir:   cfg.Telegram.UploadDir,
+			MaxFileSize: cfg.Telegram.MaxFileSize,
+
+			StreamResponses: cfg.Telegram.StreamResponses,
+			StreamInterval:  time.Duration(cfg.Telegram.StreamInterval) * time.Millisecond,
+
+			Access: telegram.AccessConfig{
+				AllowedUsers: cfg.Telegram.AllowedUsers,
+				AllowedChats: cfg.Telegram.AllowedChats,
+				Admins:       cfg.Telegram.Admins,
+			},
+
+			GroupTriggers: telegram.TriggerConfig{
+				Mention:  cfg.Telegram.GroupMention,
+				Reply:    cfg.Telegram.GroupReply,
+				Prefixes: cfg.Telegram.GroupPrefixes,
+			},
+
+			Transcriber:  newTranscriber(cfg),
+			Synthesizer:  newSynthesizer(cfg),
+			VoiceReplies: cfg.Speech.VoiceReplies,
+
+			Logger: logging.For("telegram"),
 		}
 
 		telegramBot = telegram.NewBot(tgCfg, messageBus, ctx)
+		registerTelegramCommands(telegramBot)
 
 		handler := telegram.NewHandler(telegramBot)
 
 		if _, err := messageBus.Subscribe(bus.ChannelTelegram, handler.HandleMessage); err != nil {
-			log.Printf("Failed to subscribe Telegram handler: %v", err)
+			logger.Error("Failed to subscribe Telegram handler", "error", err)
 		}
 
 		if err := telegramBot.Start(); err != nil {
-			log.Printf("Failed to start Telegram bot: %v", err)
+			logger.Error("Failed to start Telegram bot", "error", err)
+		}
+	}
+
+	if cfg.Discord.Enabled {
+		logger.Info("Initializing Discord bot")
+
+		discordBot = discord.NewBot(&discord.Config{
+			Token:          cfg.Discord.Token,
+			RequireMention: cfg.Discord.RequireMention,
+
+			StreamResponses: cfg.Discord.StreamResponses,
+			StreamInterval:  time.Duration(cfg.Discord.StreamInterval) * time.Millisecond,
+
+			Access: discord.AccessConfig{
+				AllowedUsers:    cfg.Discord.AllowedUsers,
+				AllowedGuilds:   cfg.Discord.AllowedGuilds,
+				AllowedChannels: cfg.Discord.AllowedChannels,
+				Admins:          cfg.Discord.Admins,
+			},
+
+			Logger: logging.For("discord"),
+		}, messageBus, ctx)
+
+		handler := discord.NewHandler(discordBot)
+
+		if _, err := messageBus.Subscribe(bus.ChannelDiscord, handler.HandleMessage); err != nil {
+			logger.Error("Failed to subscribe Discord handler", "error", err)
+		}
+
+		if err := discordBot.Start(); err != nil {
+			logger.Error("Failed to start Discord bot", "error", err)
+		}
+	}
+
+	if cfg.Email.Enabled {
+		logger.Info("Initializing email channel", "imap", cfg.Email.IMAPHost, "smtp", cfg.Email.SMTPHost)
+
+		emailClient = email.NewClient(&email.Config{
+			IMAPAddr:     net.JoinHostPort(cfg.Email.IMAPHost, strconv.Itoa(cfg.Email.IMAPPort)),
+			SMTPAddr:     net.JoinHostPort(cfg.Email.SMTPHost, strconv.Itoa(cfg.Email.SMTPPort)),
+			Username:     cfg.Email.Username,
+			Password:     cfg.Email.Password,
+			Address:      cfg.Email.Address,
+			Mailbox:      cfg.Email.Mailbox,
+			PollInterval: time.Duration(cfg.Email.PollInterval) * time.Second,
+
+			Access: email.AccessConfig{
+				AllowedSenders: cfg.Email.AllowedSenders,
+				Admins:         cfg.Email.Admins,
+				AuthServID:     cfg.Email.AuthServID,
+			},
+
+			Logger: logging.For("email"),
+		}, messageBus, ctx)
+
+		handler := email.NewHandler(emailClient)
+
+		if _, err := messageBus.Subscribe(bus.ChannelEmail, handler.HandleMessage); err != nil {
+			logger.Error("Failed to subscribe email handler", "error", err)
+		}
+
+		if err := emailClient.Start(); err != nil {
+			logger.Error("Failed to start email channel", "error", err)
 		}
 	}
 
 	if cfg.WebSocket.Enabled {
-		log.Printf("Initializing WebSocket server on %s:%d...", cfg.WebSocket.Host, cfg.WebSocket.Port)
+		logger.Info("Initializing WebSocket server", "host", cfg.WebSocket.Host, "port", cfg.WebSocket.Port)
 
 		wsCfg := &websocket.Config{
-			Port:       cfg.WebSocket.Port,
-			MaxClients: 10,
+			Host:           cfg.WebSocket.Host,
+			Port:           cfg.WebSocket.Port,
+			MaxClients:     cfg.WebSocket.MaxClients,
+			AllowedOrigins: cfg.WebSocket.AllowedOrigins,
+			TLSCertFile:    cfg.WebSocket.TLSCert,
+			TLSKeyFile:     cfg.WebSocket.TLSKey,
+			PathPrefix:     cfg.WebSocket.PathPrefix,
+			TrustedProxies: cfg.WebSocket.TrustedProxies,
+			UI:             cfg.WebSocket.UI,
+			MaxMessageSize: cfg.WebSocket.MaxMessageSize,
+			MaxPayloadSize: cfg.WebSocket.MaxPayloadSize,
+			Logger:         logging.For("websocket"),
+		}
+
+		if cfg.WebSocket.RequireAuth {
+			authenticator, err := newAuthenticator(ctx, cfg, fileStorage)
+			if err != nil {
+				return fmt.Errorf("failed to initialize WebSocket auth: %w", err)
+			}
+			wsCfg.Auth = authenticator
 		}
 
 		websocketServer = websocket.NewServer(wsCfg, messageBus, ctx)
@@ -130,88 +509,306 @@ func initializeCommunication(ctx context.Context, messageBus bus.MessageBus, cfg
 		handler := websocket.NewHandler(websocketServer)
 
 		if _, err := messageBus.Subscribe(bus.ChannelWebSocket, handler.HandleMessage); err != nil {
-			log.Printf("Failed to subscribe WebSocket handler: %v", err)
+			logger.Error("Failed to subscribe WebSocket handler", "error", err)
 		}
 
 		if err := websocketServer.Start(cfg.WebSocket.Port); err != nil {
-			log.Printf("Failed to start WebSocket server: %v", err)
+			logger.Error("Failed to start WebSocket server", "error", err)
 		}
 	}
 
 	return nil
 }
 
+func initializeAPI(ctx context.Context, messageBus bus.MessageBus, cfg *config.Config, sessionStorage storage.SessionStorage, fileStorage storage.Storage) error {
+	logger.Info("Initializing API server", "host", cfg.API.Host, "port", cfg.API.Port)
+
+	authenticator, err := newAuthenticator(ctx, cfg, fileStorage)
+	if err != nil {
+		return fmt.Errorf("failed to initialize API auth: %w", err)
+	}
+
+	exporter, err := export.NewExporter(&export.Config{
+		SessionStorage: sessionStorage,
+		Storage:        fileStorage,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize exporter: %w", err)
+	}
+
+	apiConfig := &api.Config{
+		Host:           cfg.API.Host,
+		Port:           cfg.API.Port,
+		Agent:          agentService,
+		SessionStorage: sessionStorage,
+		Auth:           authenticator,
+		Exporter:       exporter,
+		Shares:         export.NewShareStore(fileStorage),
+		MessageBus:     messageBus,
+		DeadLetters:    deadLetters,
+	}
+
+	if len(cfg.Webhooks.Hooks) > 0 {
+		hooks := make([]webhook.Hook, 0, len(cfg.Webhooks.Hooks))
+		for _, hook := range cfg.Webhooks.Hooks {
+			hooks = append(hooks, webhook.Hook{
+				Name:     hook.Name,
+				Secret:   hook.Secret,
+				Template: hook.Template,
+				Prompt:   hook.Prompt,
+				Skills:   hook.Skills,
+				Channel:  hook.Channel,
+				ChatID:   hook.ChatID,
+			})
+		}
+
+		receiver, err := webhook.NewReceiver(&webhook.Config{
+			Hooks:       hooks,
+			MessageBus:  messageBus,
+			MaxBodySize: cfg.Webhooks.MaxBodySize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize webhooks: %w", err)
+		}
+		apiConfig.Webhooks = receiver
+		logger.Info("Webhooks enabled", "hooks", len(hooks))
+	}
+
+	server, err := api.NewServer(apiConfig)
+	if err != nil {
+		return err
+	}
+
+	if err := server.Start(); err != nil {
+		return err
+	}
+
+	apiServer = server
+	return nil
+}
+
+func newFastPath(cfg *config.Config) *intent.Router {
+	if !cfg.Agent.FastPath.Enabled {
+		return nil
+	}
+
+	handlers := []intent.Handler{
+		intent.NewMathHandler(),
+		intent.NewUnitHandler(),
+		intent.NewDateHandler(),
+	}
+	if cfg.Agent.FastPath.Currency {
+		rates := intent.NewHTTPRatesProvider(cfg.Agent.FastPath.RatesURL, time.Duration(cfg.Agent.FastPath.RatesTTL)*time.Minute)
+		handlers = append(handlers, intent.NewCurrencyHandler(rates))
+	}
+
+	logger.Info("Fast-path answers enabled", "intents", len(handlers))
+	return intent.NewRouter(handlers...)
+}
+
 func initializeAgent(ctx context.Context, messageBus bus.MessageBus, cfg *config.Config, sessionStorage storage.SessionStorage, memoryStorage storage.MemoryStorage, fileStorage storage.Storage) error {
-	log.Println("Initializing agent service...")
+	logger.Info("Initializing agent service")
 
 	toolRegistry := tools.NewToolRegistry()
 
 	getTimeTool := tools.NewGetTimeTool()
 	if err := toolRegistry.Register(getTimeTool); err != nil {
-		log.Printf("Failed to register get_time tool: %v", err)
+		logger.Error("Failed to register tool", "tool", "get_time", "error", err)
 	}
 
 	echoTool := tools.NewEchoTool()
 	if err := toolRegistry.Register(echoTool); err != nil {
-		log.Printf("Failed to register echo tool: %v", err)
+		logger.Error("Failed to register tool", "tool", "echo", "error", err)
 	}
 
 	calculateTool := tools.NewCalculateTool()
 	if err := toolRegistry.Register(calculateTool); err != nil {
-		log.Printf("Failed to register calculate tool: %v", err)
+		logger.Error("Failed to register tool", "tool", "calculate", "error", err)
+	}
+
+	memoryIndexed := false
+	if cfg.Memory.Embeddings.Enabled {
+		memoryIndex, err := initializeMemoryIndex(ctx, cfg, memoryStorage)
+		if err != nil {
+			logger.Error("Failed to initialize memory index", "error", err)
+		} else {
+			for _, memTool := range tools.NewMemoryIndexTools(memoryIndex) {
+				if err := toolRegistry.Register(memTool); err != nil {
+					logger.Error("Failed to register tool", "tool", memTool.Name(), "error", err)
+				}
+			}
+			// Memory written by add_memory or consolidation is indexed as
+			// it is saved.
+			memoryStorage = tools.NewIndexedMemoryStorage(memoryStorage, memoryIndex)
+			memoryIndexed = true
+		}
 	}
 
 	memoryManager := memory.NewManager(memoryStorage)
 	memoryTools := memory.NewMemoryTools(memoryManager)
 	for _, memTool := range memoryTools {
 		if err := toolRegistry.Register(memTool); err != nil {
-			log.Printf("Failed to register %s tool: %v", memTool.Name(), err)
+			logger.Error("Failed to register tool", "tool", memTool.Name(), "error", err)
 		}
 	}
 
-	fileTools := filetools.NewFileTools(fileStorage)
+	var toolStorage storage.Storage = fileStorage
+	if cfg.Workspace.Enabled {
+		logger.Info("Initializing workspace watcher")
+		watcher, err := workspace.NewWatcher(&workspace.WatcherConfig{
+			Directory:      cfg.Workspace.Directory,
+			MaxChanges:     cfg.Workspace.MaxChanges,
+			IgnorePatterns: cfg.Workspace.Ignore,
+		})
+		if err != nil {
+			logger.Error("Failed to create workspace watcher", "error", err)
+		} else if err := watcher.Start(); err != nil {
+			logger.Error("Failed to start workspace watcher", "error", err)
+		} else {
+			workspaceWatcher = watcher
+			toolStorage = workspace.NewTrackedStorage(fileStorage, cfg.Storage.BasePath, watcher)
+			if err := toolRegistry.Register(workspace.NewRecentChangesTool(watcher)); err != nil {
+				logger.Error("Failed to register tool", "tool", "recent_changes", "error", err)
+			}
+		}
+	}
+
+	fileTools := filetools.NewFileTools(toolStorage)
 	for _, fileTool := range fileTools {
 		if err := toolRegistry.Register(fileTool); err != nil {
-			log.Printf("Failed to register %s tool: %v", fileTool.Name(), err)
+			logger.Error("Failed to register tool", "tool", fileTool.Name(), "error", err)
+		}
+	}
+
+	var execTool *tools.ExecTool
+	if cfg.Tools.Exec.Enabled {
+		var err error
+		execTool, err = tools.NewExecTool(&tools.ExecConfig{
+			BasePath:  cfg.Storage.BasePath,
+			Allow:     cfg.Tools.Exec.Allow,
+			Deny:      cfg.Tools.Exec.Deny,
+			Timeout:   time.Duration(cfg.Tools.Exec.Timeout) * time.Second,
+			MaxOutput: cfg.Tools.Exec.MaxOutput,
+			Env:       cfg.Tools.Exec.Env,
+		})
+		if err != nil {
+			logger.Error("Failed to create exec tool", "error", err)
+		} else if err := toolRegistry.Register(execTool); err != nil {
+			logger.Error("Failed to register tool", "tool", execTool.Name(), "error", err)
+		}
+	}
+
+	httpConfig := &tools.HTTPConfig{
+		Allow:           cfg.Tools.HTTP.Allow,
+		MaxResponseSize: cfg.Tools.HTTP.MaxResponseSize,
+		MaxRedirects:    cfg.Tools.HTTP.MaxRedirects,
+		Timeout:         time.Duration(cfg.Tools.HTTP.Timeout) * time.Second,
+		AllowPrivate:    cfg.Tools.HTTP.AllowPrivate,
+	}
+	if cfg.Tools.HTTP.Enabled {
+		httpTool, err := tools.NewHTTPRequestTool(httpConfig)
+		if err != nil {
+			logger.Error("Failed to create http tool", "error", err)
+		} else if err := toolRegistry.Register(httpTool); err != nil {
+			logger.Error("Failed to register tool", "tool", httpTool.Name(), "error", err)
 		}
 	}
 
-	if cfg.Search.BraveAPIKey != "" {
-		searchConfig := &search.SearchConfig{
-			APIKey: cfg.Search.BraveAPIKey,
+	if cfg.Tools.FetchPage.Enabled {
+		fetchTool, err := tools.NewFetchPageTool(&tools.FetchPageConfig{
+			HTTP:      httpConfig,
+			MaxLength: cfg.Tools.FetchPage.MaxLength,
+			CacheTTL:  time.Duration(cfg.Tools.FetchPage.CacheTTL) * time.Second,
+		})
+		if err != nil {
+			logger.Error("Failed to create fetch_page tool", "error", err)
+		} else if err := toolRegistry.Register(fetchTool); err != nil {
+			logger.Error("Failed to register tool", "tool", fetchTool.Name(), "error", err)
 		}
-		searchClient := search.NewBraveSearchClient(searchConfig)
-		webSearchTool := search.NewWebSearchTool(searchClient)
+	}
+
+	if searchProvider := newSearchProvider(cfg); searchProvider != nil {
+		webSearchTool := search.NewWebSearchTool(searchProvider)
 		if err := toolRegistry.Register(webSearchTool); err != nil {
-			log.Printf("Failed to register web_search tool: %v", err)
+			logger.Error("Failed to register tool", "tool", "web_search", "error", err)
+		}
+		if newsProvider := search.NewsProvider(searchProvider); newsProvider != nil {
+			if err := toolRegistry.Register(search.NewNewsSearchTool(newsProvider)); err != nil {
+				logger.Error("Failed to register tool", "tool", "news_search", "error", err)
+			}
 		}
 	}
 
-	log.Printf("Registered %d tools", len(toolRegistry.List()))
+	logger.Info("Registered tools", "count", len(toolRegistry.List()))
 
 	var skillRegistry *skills.SkillRegistry
 	var skillConfig *skills.SkillConfig
 
 	if cfg.Skills.Enabled {
-		log.Println("Initializing skills system...")
+		logger.Info("Initializing skills system")
 		skillRegistry = skills.NewSkillRegistry(fileStorage)
+		// Skill scripts run through the exec tool, so they are only offered
+		// when it is enabled.
+		if execTool != nil {
+			skillRegistry.SetScriptTools(toolRegistry, execTool)
+		}
+
+		var bundledInstaller *skills.BundledInstaller
+		if cfg.Skills.Bundled.Enabled {
+			bundledInstaller = skills.NewBundledInstaller(fileStorage, cfg.Skills.Directory, cfg.Skills.Bundled.Disabled)
+			report, err := bundledInstaller.Install(ctx)
+			if err != nil {
+				logger.Error("Failed to install bundled skills", "error", err)
+			} else {
+				logger.Info("Bundled skills installed", "installed", len(report.Installed), "upgraded", len(report.Upgraded), "conflicts", len(report.Conflicts))
+			}
+		}
 
 		if err := skillRegistry.LoadFromDirectory(ctx, cfg.Skills.Directory); err != nil {
-			log.Printf("Failed to load skills from directory: %v", err)
+			logger.Error("Failed to load skills from directory", "error", err)
 		} else {
-			log.Printf("Loaded %d skills", skillRegistry.Count())
+			logger.Info("Loaded skills", "count", skillRegistry.Count())
+		}
+
+		if bundledInstaller != nil {
+			bundledInstaller.ApplyDisabled(skillRegistry)
+		}
+
+		if len(cfg.Skills.Packs.Sources) > 0 {
+			if err := skillRegistry.SetCacheDir(cfg.Storage.BasePath + "/skill-packs"); err != nil {
+				logger.Error("Failed to set skill pack cache directory", "error", err)
+			}
+			sources := make([]skills.SourceConfig, 0, len(cfg.Skills.Packs.Sources))
+			for _, source := range cfg.Skills.Packs.Sources {
+				sources = append(sources, skills.SourceConfig(source))
+			}
+			sourceManager, err := skills.NewSourceManager(skillRegistry, &skills.SourceManagerConfig{
+				Sources:         sources,
+				RefreshInterval: time.Duration(cfg.Skills.Packs.RefreshInterval) * time.Second,
+			})
+			if err != nil {
+				logger.Error("Failed to create skill pack manager", "error", err)
+			} else {
+				// Downloading can take a while, so packs are loaded in the
+				// background rather than holding up startup.
+				go func() {
+					if err := sourceManager.LoadAll(ctx); err != nil {
+						logger.Error("Failed to load skill packs", "error", err)
+					}
+					sourceManager.Start(ctx)
+				}()
+			}
 		}
 
 		if cfg.Skills.AutoReload {
 			watcher, err := skills.NewSkillFileWatcher(skillRegistry, skills.NewSkillParser(fileStorage))
 			if err != nil {
-				log.Printf("Failed to create skill file watcher: %v", err)
+				logger.Error("Failed to create skill file watcher", "error", err)
 			} else {
 				skillWatcher = watcher
 				if err := skillWatcher.WatchDirectory(cfg.Skills.Directory); err != nil {
-					log.Printf("Failed to watch skills directory: %v", err)
+					logger.Error("Failed to watch skills directory", "error", err)
 				}
-				log.Println("Skill file watcher started")
 			}
 		}
 
@@ -228,7 +825,7 @@ func initializeAgent(ctx context.Context, messageBus bus.MessageBus, cfg *config
 
 	var mcpManager *mcp.MCPManager
 	if cfg.MCP.Enabled {
-		log.Println("Initializing MCP manager...")
+		logger.Info("Initializing MCP manager")
 		mcpManager = mcp.NewMCPManager(toolRegistry)
 
 		for _, clientConfig := range cfg.MCP.Clients {
@@ -239,11 +836,20 @@ func initializeAgent(ctx context.Context, messageBus bus.MessageBus, cfg *config
 				Transport: clientConfig.Transport,
 				Headers:   clientConfig.Headers,
 				Timeout:   clientConfig.Timeout,
+				Command:   clientConfig.Command,
+				Args:      clientConfig.Args,
+				Env:       clientConfig.Env,
+				WorkDir:   clientConfig.WorkDir,
+
+				Fixture:     clientConfig.Fixture,
+				FixtureMode: clientConfig.FixtureMode,
+
+				Logger: logging.For("mcp"),
 			}
 
 			mcpClient, err := mcp.NewClient(mcpClientConfig)
 			if err != nil {
-				log.Printf("Failed to create MCP client %s: %v", clientConfig.Name, err)
+				logger.Error("Failed to create MCP client", "client", clientConfig.Name, "error", err)
 				continue
 			}
 
@@ -254,74 +860,389 @@ func initializeAgent(ctx context.Context, messageBus bus.MessageBus, cfg *config
 			}
 
 			if err := mcpManager.AddClient(mcpClient, adapterConfig); err != nil {
-				log.Printf("Failed to add MCP client %s: %v", clientConfig.Name, err)
+				logger.Error("Failed to add MCP client", "client", clientConfig.Name, "error", err)
 				continue
 			}
 
-			log.Printf("Added MCP client: %s", clientConfig.Name)
+			logger.Info("Added MCP client", "client", clientConfig.Name)
 		}
 
 		if err := mcpManager.ConnectAll(ctx); err != nil {
-			log.Printf("Failed to connect MCP clients: %v", err)
+			logger.Error("Failed to connect MCP clients", "error", err)
 		} else {
-			log.Printf("MCP manager initialized with %d clients", len(cfg.MCP.Clients))
+			logger.Info("MCP manager initialized", "clients", len(cfg.MCP.Clients))
+		}
+
+		if cfg.MCP.HealthCheck.Interval > 0 {
+			mcpManager.StartHealthChecks(&mcp.HealthConfig{
+				Interval:   time.Duration(cfg.MCP.HealthCheck.Interval) * time.Second,
+				Timeout:    time.Duration(cfg.MCP.HealthCheck.Timeout) * time.Second,
+				MaxBackoff: time.Duration(cfg.MCP.HealthCheck.MaxBackoff) * time.Second,
+			})
+		}
+	}
+
+	if cfg.Plugins.Enabled {
+		logger.Info("Loading tool plugins", "dir", cfg.Plugins.Directory)
+		pluginLoader = plugins.NewLoader(&plugins.Config{
+			Directory:    cfg.Plugins.Directory,
+			StartTimeout: time.Duration(cfg.Plugins.StartTimeout) * time.Second,
+			Logger:       logging.For("plugins"),
+		}, toolRegistry)
+
+		if err := pluginLoader.LoadAll(ctx); err != nil {
+			logger.Error("Failed to load tool plugins", "error", err)
+		} else if cfg.Plugins.AutoReload {
+			if err := pluginLoader.Watch(); err != nil {
+				logger.Error("Failed to watch plugins directory", "error", err)
+			}
 		}
 	}
 
 	var taskManager *scheduler.TaskManager
 	if cfg.Scheduler.Enabled {
-		log.Println("Initializing task scheduler...")
+		logger.Info("Initializing task scheduler")
 		sched := scheduler.NewScheduler(&scheduler.SchedulerConfig{
 			TickInterval: time.Duration(cfg.Scheduler.TickInterval) * time.Second,
+			Workers:      cfg.Scheduler.Workers,
+			Logger:       logging.For("scheduler"),
 		})
 
-		taskManager = scheduler.NewTaskManager(sched, &scheduler.TaskManagerConfig{
-			TasksFile: cfg.Scheduler.TasksFile,
-		})
+		taskManagerConfig := &scheduler.TaskManagerConfig{
+			TasksFile:        cfg.Scheduler.TasksFile,
+			HistoryRetention: time.Duration(cfg.Scheduler.HistoryRetention) * 24 * time.Hour,
+			HistoryMaxRuns:   cfg.Scheduler.HistoryMaxRuns,
+		}
+		if cfg.Scheduler.HistoryDir != "" {
+			taskManagerConfig.History = scheduler.NewFileTaskHistoryStore(cfg.Scheduler.HistoryDir)
+		}
+		taskManager = scheduler.NewTaskManager(sched, taskManagerConfig)
+
+		for _, taskTool := range scheduler.NewSchedulerTools(taskManager) {
+			if err := toolRegistry.Register(taskTool); err != nil {
+				logger.Error("Failed to register tool", "tool", taskTool.Name(), "error", err)
+			}
+		}
 
 		if cfg.Scheduler.AutoStart {
 			if err := sched.Start(); err != nil {
-				log.Printf("Failed to start scheduler: %v", err)
-			} else {
-				log.Println("Task scheduler started")
+				logger.Error("Failed to start scheduler", "error", err)
 			}
+		}
+	}
+
+	llmModels, defaultModel := newLLMModels(cfg)
 
-			if err := taskManager.Start(); err != nil {
-				log.Printf("Failed to start task manager: %v", err)
+	agentConfig := &agent.Config{
+		LLMModels:      llmModels,
+		DefaultModel:   defaultModel,
+		SessionStorage: sessionStorage,
+		MemoryStorage:  memoryStorage,
+		Storage:        fileStorage,
+		ToolRegistry:   toolRegistry,
+		SkillRegistry:  skillRegistry,
+		SkillConfig:    skillConfig,
+		MCPManager:     mcpManager,
+		TaskManager:    taskManager,
+		ShowWork:       cfg.Agent.ShowWork,
+		LogThinking:    cfg.Agent.LogThinking,
+		RetryDelay:     time.Duration(cfg.Agent.RetryDelay) * time.Second,
+		DeadLetters:    deadLetters,
+
+		MaxConcurrentChats: cfg.Agent.MaxConcurrentChats,
+		HistoryTokens:      cfg.Agent.HistoryTokens,
+		SummarizeHistory:   cfg.Agent.SummarizeHistory,
+		MaxSessions:        cfg.Agent.MaxSessions,
+		SessionIdleTTL:     time.Duration(cfg.Agent.SessionIdleTTL) * time.Second,
+		MemoryIndexed:      memoryIndexed,
+		FastPath:           newFastPath(cfg),
+		Logger:             logging.For("agent"),
+		Chaos:              faultInjector,
+		ToolPolicies:       newToolPolicies(cfg),
+		Quotas:             newQuotas(cfg),
+		SubAgents:          newSubAgents(cfg),
+		MaxSubAgentDepth:   cfg.Agent.MaxSubAgentDepth,
+		Channels:           enabledChannels(cfg),
+		ContextSections: agentcontext.Sections{
+			NoMemory:        !cfg.Agent.Context.Memory,
+			MemoryTokens:    cfg.Agent.Context.MemoryTokens,
+			SummarizeMemory: cfg.Agent.Context.SummarizeMemory,
+			NoDailyNotes:    !cfg.Agent.Context.DailyNotes,
+			DailyNoteDays:   cfg.Agent.Context.DailyNoteDays,
+			DailyNoteTokens: cfg.Agent.Context.DailyNoteTokens,
+			ToolDetail:      cfg.Agent.Context.ToolDetail,
+		},
+		LLMRouting: &llm.RoutingConfig{
+			Policy: cfg.LLM.Routing.Policy,
+			Models: cfg.LLM.Routing.Models,
+		},
+	}
+
+	if cfg.Agent.MemoryConsolidation.Enabled {
+		agentConfig.MemoryConsolidation = &agent.ConsolidationConfig{
+			MaxAge:  time.Duration(cfg.Agent.MemoryConsolidation.MaxAge) * 24 * time.Hour,
+			MaxSize: cfg.Agent.MemoryConsolidation.MaxSize,
+			DryRun:  cfg.Agent.MemoryConsolidation.DryRun,
+		}
+	}
+
+	if cfg.Agent.Journal.Enabled {
+		agentConfig.Journal = &agent.JournalConfig{Summarize: cfg.Agent.Journal.Summarize}
+	}
+
+	if cfg.Agent.Transcripts.Enabled {
+		agentConfig.Transcripts = agent.NewTranscriptStore(fileStorage)
+		agentConfig.TranscriptRetention = time.Duration(cfg.Agent.Transcripts.Retention) * 24 * time.Hour
+	}
+
+	if cfg.LLM.Budget.DailyLimit > 0 || cfg.LLM.Budget.ChatDailyLimit > 0 {
+		agentConfig.LLMBudget = &llm.BudgetConfig{
+			DailyLimit:     cfg.LLM.Budget.DailyLimit,
+			ChatDailyLimit: cfg.LLM.Budget.ChatDailyLimit,
+			Action:         cfg.LLM.Budget.Action,
+			DowngradeModel: cfg.LLM.Budget.DowngradeModel,
+		}
+	}
+
+	if len(cfg.LLM.RateLimits) > 0 {
+		agentConfig.LLMRateLimits = make(map[string]llm.RateLimitConfig, len(cfg.LLM.RateLimits))
+		for _, limit := range cfg.LLM.RateLimits {
+			agentConfig.LLMRateLimits[limit.Provider] = llm.RateLimitConfig{
+				RequestsPerMinute: limit.RequestsPerMinute,
+				BackgroundShare:   limit.BackgroundShare,
 			}
 		}
 	}
 
+	if workspaceWatcher != nil && cfg.Workspace.IncludeInContext {
+		agentConfig.Workspace = workspaceWatcher
+	}
+
+	if cfg.Templates.Enabled {
+		templateRegistry := templates.NewRegistry()
+		if err := templateRegistry.LoadFromFile(cfg.Templates.File); err != nil {
+			logger.Error("Failed to load conversation templates", "error", err)
+		} else {
+			logger.Info("Loaded conversation templates", "count", templateRegistry.Count())
+		}
+		agentConfig.Templates = templateRegistry
+	}
+
+	var err error
+	agentService, err = agent.NewAgent(agentConfig, messageBus, ctx)
+	if err != nil {
+		return err
+	}
+
+	if diff, err := agentService.RecordToolSnapshot(ctx); err != nil {
+		logger.Error("Failed to record tool snapshot", "error", err)
+	} else if !diff.Empty() {
+		logger.Info("Tool schema changed", "diff", diff.String())
+	}
+
+	if err := agentService.Start(); err != nil {
+		return err
+	}
+
+	// Tasks are loaded once the agent has registered its task actions.
+	if taskManager != nil && cfg.Scheduler.AutoStart {
+		if err := taskManager.Start(); err != nil {
+			logger.Error("Failed to start task manager", "error", err)
+		}
+
+		if err := scheduleMemoryConsolidation(taskManager, cfg.Agent.MemoryConsolidation); err != nil {
+			logger.Error("Failed to schedule memory consolidation", "error", err)
+		}
+	} else if cfg.Agent.MemoryConsolidation.Enabled {
+		logger.Warn("Memory consolidation needs scheduler.enabled and scheduler.auto_start, it will not run")
+	}
+
+	return nil
+}
+
+// newLLMModels returns the configured models and the name of the default
+// one. A config without llm.models describes a single model called
+// "default".
+// newSearchProvider returns the provider web_search uses, wrapped in a
+// fallback chain when tools.web_search.fallback is set, or nil when web
+// search is not configured. A brave_api_key in the search section enables
+// Brave on its own.
+func newSearchProvider(cfg *config.Config) search.SearchProvider {
+	webSearch := cfg.Tools.WebSearch
+	braveAPIKey := cfg.Search.BraveAPIKey
+	if braveAPIKey == "" {
+		braveAPIKey = webSearch.APIKey
+	}
+	options := search.SearchConfig{
+		Country:    webSearch.Country,
+		Language:   webSearch.Language,
+		SafeSearch: webSearch.SafeSearch,
+	}
+
+	if !webSearch.Enabled {
+		if cfg.Search.BraveAPIKey == "" {
+			return nil
+		}
+		options.APIKey = braveAPIKey
+		return search.NewBraveSearchClient(&options)
+	}
+
+	var providers []search.SearchProvider
+	for _, name := range append([]string{webSearch.Provider}, webSearch.Fallback...) {
+		providerConfig := options
+		var provider search.SearchProvider
+		var err error
+
+		switch name {
+		case "", "brave":
+			if braveAPIKey == "" {
+				err = fmt.Errorf("brave needs an API key")
+				break
+			}
+			providerConfig.APIKey = braveAPIKey
+			provider = search.NewBraveSearchClient(&providerConfig)
+		case "searxng":
+			providerConfig.BaseURL = webSearch.SearxNG.URL
+			provider, err = search.NewSearxNGClient(&providerConfig)
+		case "duckduckgo":
+			provider = search.NewDuckDuckGoClient(&providerConfig)
+		case "google":
+			providerConfig.APIKey = webSearch.Google.APIKey
+			providerConfig.EngineID = webSearch.Google.EngineID
+			provider, err = search.NewGoogleSearchClient(&providerConfig)
+		default:
+			err = fmt.Errorf("unknown provider")
+		}
+
+		if err != nil {
+			logger.Warn("Skipping search provider", "provider", name, "error", err)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+
+	switch len(providers) {
+	case 0:
+		logger.Warn("Web search is enabled but no search provider is usable")
+		return nil
+	case 1:
+		return providers[0]
+	}
+	return search.NewFallbackProvider(providers...)
+}
+
+// speechConfig fills in the OpenAI key from the LLM settings when the
+// backend has none of its own.
+func speechConfig(cfg *config.Config, backend config.SpeechBackendConfig) *speech.Config {
+	apiKey := backend.APIKey
+	if apiKey == "" && backend.Provider == "openai" && cfg.LLM.Provider == "openai" {
+		apiKey = cfg.LLM.APIKey
+	}
+
+	return &speech.Config{
+		BaseURL:  backend.BaseURL,
+		APIKey:   apiKey,
+		Model:    backend.Model,
+		Voice:    backend.Voice,
+		Language: backend.Language,
+		Timeout:  time.Duration(backend.Timeout) * time.Second,
+	}
+}
+
+func newTranscriber(cfg *config.Config) speech.Transcriber {
+	backend := cfg.Speech.STT
+	var transcriber speech.Transcriber
+	var err error
+
+	switch backend.Provider {
+	case "":
+		return nil
+	case "openai":
+		transcriber, err = speech.NewOpenAITranscriber(speechConfig(cfg, backend))
+	case "whisper.cpp":
+		transcriber, err = speech.NewWhisperCppTranscriber(speechConfig(cfg, backend))
+	default:
+		err = fmt.Errorf("unknown provider")
+	}
+
+	if err != nil {
+		logger.Warn("Voice messages will not be transcribed", "provider", backend.Provider, "error", err)
+		return nil
+	}
+	return transcriber
+}
+
+func newSynthesizer(cfg *config.Config) speech.Synthesizer {
+	backend := cfg.Speech.TTS
+	if backend.Provider != "openai" {
+		return nil
+	}
+
+	synthesizer, err := speech.NewOpenAISynthesizer(speechConfig(cfg, backend))
+	if err != nil {
+		logger.Warn("Voice replies are disabled", "provider", backend.Provider, "error", err)
+		return nil
+	}
+	return synthesizer
+}
+
+func newLLMModels(cfg *config.Config) ([]*llm.ModelConfig, string) {
 	llmModels := make([]*llm.ModelConfig, 0)
 
 	if len(cfg.LLM.Models) > 0 {
 		for _, modelConfig := range cfg.LLM.Models {
 			llmModels = append(llmModels, &llm.ModelConfig{
-				Name:        modelConfig.Name,
-				Provider:    modelConfig.Provider,
-				APIKey:      modelConfig.APIKey,
-				Model:       modelConfig.Model,
-				MaxTokens:   modelConfig.MaxTokens,
-				Temperature: modelConfig.Temperature,
+				Name:           modelConfig.Name,
+				Provider:       modelConfig.Provider,
+				APIKey:         modelConfig.APIKey,
+				Model:          modelConfig.Model,
+				BaseURL:        modelConfig.BaseURL,
+				Organization:   modelConfig.Organization,
+				Project:        modelConfig.Project,
+				Deployment:     modelConfig.Deployment,
+				APIVersion:     modelConfig.APIVersion,
+				MaxTokens:      modelConfig.MaxTokens,
+				ContextWindow:  modelConfig.ContextWindow,
+				ThinkingBudget: modelConfig.ThinkingBudget,
+				Headers:        modelConfig.Headers,
+				Vision:         modelConfig.Vision,
+				Temperature:    modelConfig.Temperature,
+				Cost:           modelConfig.Cost,
+				InputPrice:     modelConfig.InputPrice,
+				OutputPrice:    modelConfig.OutputPrice,
+				RateLimit:      modelConfig.RateLimit,
 				LocalModel: llm.LocalModelConfig{
-					Enabled: modelConfig.LocalModel.Enabled,
-					Path:    modelConfig.LocalModel.Path,
-					Type:    modelConfig.LocalModel.Type,
+					Enabled:   modelConfig.LocalModel.Enabled,
+					Path:      modelConfig.LocalModel.Path,
+					Type:      modelConfig.LocalModel.Type,
+					KeepAlive: modelConfig.LocalModel.KeepAlive,
+					AutoPull:  modelConfig.LocalModel.AutoPull,
 				},
 			})
 		}
 	} else {
 		llmModels = append(llmModels, &llm.ModelConfig{
-			Name:        "default",
-			Provider:    cfg.LLM.Provider,
-			APIKey:      cfg.LLM.APIKey,
-			Model:       cfg.LLM.Model,
-			MaxTokens:   cfg.LLM.MaxTokens,
-			Temperature: cfg.LLM.Temperature,
+			Name:           "default",
+			Provider:       cfg.LLM.Provider,
+			APIKey:         cfg.LLM.APIKey,
+			Model:          cfg.LLM.Model,
+			BaseURL:        cfg.LLM.BaseURL,
+			Organization:   cfg.LLM.Organization,
+			Project:        cfg.LLM.Project,
+			Deployment:     cfg.LLM.Deployment,
+			APIVersion:     cfg.LLM.APIVersion,
+			MaxTokens:      cfg.LLM.MaxTokens,
+			ContextWindow:  cfg.LLM.ContextWindow,
+			ThinkingBudget: cfg.LLM.ThinkingBudget,
+			Headers:        cfg.LLM.Headers,
+			Vision:         cfg.LLM.Vision,
+			Temperature:    cfg.LLM.Temperature,
 			LocalModel: llm.LocalModelConfig{
-				Enabled: cfg.LLM.LocalModel.Enabled,
-				Path:    cfg.LLM.LocalModel.Path,
-				Type:    cfg.LLM.LocalModel.Type,
+				Enabled:   cfg.LLM.LocalModel.Enabled,
+				Path:      cfg.LLM.LocalModel.Path,
+				Type:      cfg.LLM.LocalModel.Type,
+				KeepAlive: cfg.LLM.LocalModel.KeepAlive,
+				AutoPull:  cfg.LLM.LocalModel.AutoPull,
 			},
 		})
 	}
@@ -330,45 +1251,171 @@ func initializeAgent(ctx context.Context, messageBus bus.MessageBus, cfg *config
 	if defaultModel == "" {
 		defaultModel = "default"
 	}
+	return llmModels, defaultModel
+}
 
-	agentConfig := &agent.Config{
-		LLMModels:      llmModels,
-		DefaultModel:   defaultModel,
-		SessionStorage: sessionStorage,
-		MemoryStorage:  memoryStorage,
-		Storage:        fileStorage,
-		ToolRegistry:   toolRegistry,
-		SkillRegistry:  skillRegistry,
-		SkillConfig:    skillConfig,
-		MCPManager:     mcpManager,
-		TaskManager:    taskManager,
+func newAuthenticator(ctx context.Context, cfg *config.Config, fileStorage storage.Storage) (*auth.Authenticator, error) {
+	authCfg := &auth.Config{
+		Tokens:  make([]auth.TokenConfig, 0, len(cfg.Auth.Tokens)),
+		Basic:   make([]auth.BasicUserConfig, 0, len(cfg.Auth.Basic)),
+		APIKeys: auth.NewAPIKeyStore(fileStorage),
 	}
 
-	var err error
-	agentService, err = agent.NewAgent(agentConfig, messageBus, ctx)
+	for _, token := range cfg.Auth.Tokens {
+		authCfg.Tokens = append(authCfg.Tokens, auth.TokenConfig(token))
+	}
+	for _, user := range cfg.Auth.Basic {
+		authCfg.Basic = append(authCfg.Basic, auth.BasicUserConfig(user))
+	}
+	if cfg.Auth.OIDC.Issuer != "" {
+		oidcCfg := auth.OIDCConfig(cfg.Auth.OIDC)
+		authCfg.OIDC = &oidcCfg
+	}
+	if cfg.Auth.JWT.Secret != "" {
+		jwtCfg := auth.JWTConfig(cfg.Auth.JWT)
+		authCfg.JWT = &jwtCfg
+	}
+
+	return auth.NewAuthenticator(ctx, authCfg)
+}
+
+func newQuotas(cfg *config.Config) *agent.QuotaConfig {
+	quotas := cfg.Agent.Quotas
+	return &agent.QuotaConfig{
+		Chat: agent.QuotaLimits{
+			MessagesPerMinute: quotas.Chat.MessagesPerMinute,
+			LLMCallsPerHour:   quotas.Chat.LLMCallsPerHour,
+		},
+		User: agent.QuotaLimits{
+			MessagesPerMinute: quotas.User.MessagesPerMinute,
+			LLMCallsPerHour:   quotas.User.LLMCallsPerHour,
+		},
+	}
+}
+
+func enabledChannels(cfg *config.Config) []string {
+	var channels []string
+	if cfg.Telegram.Enabled {
+		channels = append(channels, bus.ChannelTelegram)
+	}
+	if cfg.Discord.Enabled {
+		channels = append(channels, bus.ChannelDiscord)
+	}
+	if cfg.Email.Enabled {
+		channels = append(channels, bus.ChannelEmail)
+	}
+	if cfg.WebSocket.Enabled {
+		channels = append(channels, bus.ChannelWebSocket)
+	}
+	return channels
+}
+
+func newSubAgents(cfg *config.Config) []agent.SubAgentConfig {
+	subAgents := make([]agent.SubAgentConfig, 0, len(cfg.Agent.SubAgents))
+	for _, sub := range cfg.Agent.SubAgents {
+		subAgents = append(subAgents, agent.SubAgentConfig{
+			Name:          sub.Name,
+			Description:   sub.Description,
+			SystemPrompt:  sub.SystemPrompt,
+			Model:         sub.Model,
+			Tools:         sub.Tools,
+			MaxIterations: sub.MaxIterations,
+		})
+	}
+	return subAgents
+}
+
+func newToolPolicies(cfg *config.Config) *tools.PolicyConfig {
+	policies := &tools.PolicyConfig{
+		DefaultTimeout: time.Duration(cfg.Tools.DefaultTimeout) * time.Second,
+		Tools:          make(map[string]tools.PolicyOverride, len(cfg.Tools.Policies)),
+	}
+
+	for name, policy := range cfg.Tools.Policies {
+		policies.Tools[name] = tools.PolicyOverride{
+			Timeout:              time.Duration(policy.Timeout) * time.Second,
+			Retries:              policy.Retries,
+			MaxConcurrent:        policy.MaxConcurrent,
+			RequiresConfirmation: policy.RequiresConfirmation,
+			AdminOnly:            policy.AdminOnly,
+			CacheTTL:             time.Duration(policy.CacheTTL) * time.Second,
+		}
+	}
+
+	return policies
+}
+
+func initializeMemoryIndex(ctx context.Context, cfg *config.Config, memoryStorage storage.MemoryStorage) (*tools.MemoryIndex, error) {
+	embedder, err := llm.NewEmbedder(&llm.EmbeddingConfig{
+		Provider: cfg.Memory.Embeddings.Provider,
+		APIKey:   cfg.Memory.Embeddings.APIKey,
+		Model:    cfg.Memory.Embeddings.Model,
+		BaseURL:  cfg.Memory.Embeddings.BaseURL,
+	})
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to create embedder: %w", err)
 	}
 
-	if err := agentService.Start(); err != nil {
-		return err
+	memoryIndex := tools.NewMemoryIndex(embedder, storage.NewFileSystemVectorStore(cfg.Storage.BasePath))
+
+	go func() {
+		indexed, err := memoryIndex.Reindex(ctx, memoryStorage)
+		if err != nil {
+			logger.Error("Failed to index memory", "error", err)
+			return
+		}
+		logger.Info("Memory index ready", "model", embedder.GetModel(), "new_chunks", indexed)
+	}()
+
+	if fileMemory, ok := memoryStorage.(*storage.FileSystemMemoryStorage); ok {
+		if err := memoryIndex.WatchMemoryFile(ctx, fileMemory.MemoryFile(), fileMemory); err != nil {
+			logger.Warn("Failed to watch memory file, edits outside the agent will not be indexed", "error", err)
+		}
 	}
 
-	return nil
+	return memoryIndex, nil
 }
 
-func gracefulShutdown(ctx context.Context, messageBus bus.MessageBus) error {
-	log.Println("Performing graceful shutdown...")
+func gracefulShutdown(ctx context.Context, messageBus bus.MessageBus, drainTimeout time.Duration) error {
+	logger.Info("Performing graceful shutdown")
+
+	// Let conversations in progress finish while the channels can still
+	// deliver their replies.
+	if agentService != nil {
+		drainCtx, cancel := context.WithTimeout(ctx, drainTimeout)
+		if err := agentService.Stop(drainCtx); err != nil {
+			logger.Error("Error stopping agent", "error", err)
+		}
+		cancel()
+	}
+
+	if apiServer != nil {
+		if err := apiServer.Stop(ctx); err != nil {
+			logger.Error("Error stopping API server", "error", err)
+		}
+	}
 
 	if telegramBot != nil {
 		if err := telegramBot.Stop(); err != nil {
-			log.Printf("Error stopping Telegram bot: %v", err)
+			logger.Error("Error stopping Telegram bot", "error", err)
+		}
+	}
+
+	if discordBot != nil && discordBot.IsRunning() {
+		if err := discordBot.Stop(); err != nil {
+			logger.Error("Error stopping Discord bot", "error", err)
+		}
+	}
+
+	if emailClient != nil && emailClient.IsRunning() {
+		if err := emailClient.Stop(); err != nil {
+			logger.Error("Error stopping email channel", "error", err)
 		}
 	}
 
 	if websocketServer != nil {
 		if err := websocketServer.Stop(); err != nil {
-			log.Printf("Error stopping WebSocket server: %v", err)
+			logger.Error("Error stopping WebSocket server", "error", err)
 		}
 	}
 
@@ -376,21 +1423,23 @@ func gracefulShutdown(ctx context.Context, messageBus bus.MessageBus) error {
 		skillWatcher.Stop()
 	}
 
+	if workspaceWatcher != nil {
+		workspaceWatcher.Stop()
+	}
+
 	if mcpManager != nil {
 		if err := mcpManager.Close(); err != nil {
-			log.Printf("Error closing MCP manager: %v", err)
+			logger.Error("Error closing MCP manager", "error", err)
 		}
 	}
 
-	if taskManager != nil {
-		if err := taskManager.Stop(); err != nil {
-			log.Printf("Error stopping task manager: %v", err)
-		}
+	if pluginLoader != nil {
+		pluginLoader.Close()
 	}
 
-	if agentService != nil {
-		if err := agentService.Stop(); err != nil {
-			log.Printf("Error stopping agent: %v", err)
+	if taskManager != nil {
+		if err := taskManager.Stop(); err != nil {
+			logger.Error("Error stopping task manager", "error", err)
 		}
 	}
 
@@ -401,3 +1450,31 @@ func gracefulShutdown(ctx context.Context, messageBus bus.MessageBus) error {
 		return nil
 	}
 }
+
+// scheduleMemoryConsolidation keeps the consolidation task in line with the
+// config. Call it after the task manager loaded the saved tasks, so an
+// unchanged task keeps its state, such as being disabled by hand.
+func scheduleMemoryConsolidation(taskManager *scheduler.TaskManager, settings config.MemoryConsolidationConfig) error {
+	task, exists := taskManager.GetTask(agent.MemoryConsolidationTaskID)
+	if exists && settings.Enabled && task.CronExpr == settings.Schedule {
+		return nil
+	}
+	if exists {
+		if err := taskManager.RemoveTask(agent.MemoryConsolidationTaskID); err != nil {
+			return fmt.Errorf("failed to remove the old task: %w", err)
+		}
+	}
+	if !settings.Enabled {
+		return nil
+	}
+
+	return taskManager.AddActionTask(&scheduler.TaskConfig{
+		ID:          agent.MemoryConsolidationTaskID,
+		Name:        "Memory consolidation",
+		Description: "Dedupe, merge and expire the entries of MEMORY.md",
+		CronExpr:    settings.Schedule,
+		Enabled:     true,
+		Action:      &scheduler.Action{Type: scheduler.ActionConsolidateMemory},
+		Misfire:     scheduler.MisfireRunOnce,
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+// configReloader applies the settings that can change while running when the
+// config file is edited, and warns about the ones that need a restart.
+type configReloader struct {
+	mu      sync.Mutex
+	current *config.Config
+}
+
+func newConfigReloader(cfg *config.Config) *configReloader {
+	return &configReloader{current: cfg}
+}
+
+func (r *configReloader) OnConfigChange(cfg *config.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var levels, selection, model, tick, quotas bool
+	restart := make([]string, 0)
+	for _, change := range config.Changes(r.current, cfg) {
+		switch {
+		case change == "logging.level" || change == "logging.modules":
+			levels = true
+		case change == "skills.selection.method" || change == "skills.selection.threshold":
+			selection = true
+		case change == "llm.default_model":
+			model = true
+		case change == "scheduler.tick_interval":
+			tick = true
+		case strings.HasPrefix(change, "agent.quotas."):
+			quotas = true
+		default:
+			restart = append(restart, change)
+		}
+	}
+	r.current = cfg
+
+	if levels {
+		if err := logging.SetLevels(cfg.Logging.Level, cfg.Logging.Modules); err != nil {
+			logger.Error("Failed to apply log levels", "error", err)
+		} else {
+			logger.Info("Applied log levels", "level", cfg.Logging.Level)
+		}
+	}
+
+	if selection && agentService != nil && agentService.GetSkillSelector() != nil {
+		selector := agentService.GetSkillSelector()
+		selectionConfig := *selector.GetConfig()
+		selectionConfig.Method = cfg.Skills.Selection.Method
+		selectionConfig.Threshold = cfg.Skills.Selection.Threshold
+		selector.SetConfig(&selectionConfig)
+		logger.Info("Applied skill selection settings", "method", selectionConfig.Method, "threshold", selectionConfig.Threshold)
+	}
+
+	if model && agentService != nil && agentService.GetLLMManager() != nil {
+		defaultModel := cfg.LLM.DefaultModel
+		if defaultModel == "" {
+			defaultModel = "default"
+		}
+		if err := agentService.GetLLMManager().SwitchModel(defaultModel); err != nil {
+			logger.Error("Failed to switch default model", "model", defaultModel, "error", err)
+		}
+	}
+
+	if tick && agentService != nil && agentService.GetTaskManager() != nil {
+		interval := time.Duration(cfg.Scheduler.TickInterval) * time.Second
+		if err := agentService.GetTaskManager().GetScheduler().SetTickInterval(interval); err != nil {
+			logger.Error("Failed to change scheduler tick interval", "error", err)
+		}
+	}
+
+	if quotas && agentService != nil {
+		agentService.SetQuotas(newQuotas(cfg))
+		logger.Info("Applied agent quotas")
+	}
+
+	if len(restart) > 0 {
+		logger.Warn("Some config changes take effect after a restart", "settings", strings.Join(restart, ", "))
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/wjffsx/miniclaw_go/internal/config"
+)
+
+const defaultConfigPath = "./configs/config.yaml"
+
+type runOptions struct {
+	configPath    string
+	openAIKey     string
+	anthropicKey  string
+	ollamaModel   string
+	model         string
+	telegramToken string
+	dataDir       string
+	port          int
+	chaos         bool
+}
+
+func parseRunFlags(args []string) (*runOptions, error) {
+	opts := &runOptions{}
+
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	opts.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+func (opts *runOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&opts.configPath, "config", defaultConfigPath, "config file, generated from the flags below if it does not exist")
+	flags.StringVar(&opts.openAIKey, "openai-key", "", "use OpenAI with this API key")
+	flags.StringVar(&opts.anthropicKey, "anthropic-key", "", "use Anthropic with this API key")
+	flags.StringVar(&opts.ollamaModel, "ollama-model", "", "use this model from a local Ollama server")
+	flags.StringVar(&opts.model, "model", "", "model name for the selected provider")
+	flags.StringVar(&opts.telegramToken, "telegram-token", "", "enable the Telegram bot with this token")
+	flags.StringVar(&opts.dataDir, "data", "", "storage directory (default ./data)")
+	flags.IntVar(&opts.port, "port", 0, "WebSocket port (default 18789)")
+	flags.BoolVar(&opts.chaos, "chaos", false, "inject random faults for resilience testing (not saved to the config)")
+}
+
+func (opts *runOptions) check() error {
+	providers := 0
+	for _, value := range []string{opts.openAIKey, opts.anthropicKey, opts.ollamaModel} {
+		if value != "" {
+			providers++
+		}
+	}
+	if providers > 1 {
+		return fmt.Errorf("only one of --openai-key, --anthropic-key and --ollama-model can be set")
+	}
+	return nil
+}
+
+func (opts *runOptions) apply(cfg *config.Config, generated bool) {
+	if generated {
+		cfg.Telegram.Enabled = false
+	}
+
+	switch {
+	case opts.openAIKey != "":
+		cfg.LLM.Provider = "openai"
+		cfg.LLM.APIKey = opts.openAIKey
+		cfg.LLM.Model = "gpt-4o"
+	case opts.anthropicKey != "":
+		cfg.LLM.Provider = "anthropic"
+		cfg.LLM.APIKey = opts.anthropicKey
+		cfg.LLM.Model = "claude-sonnet-4-5"
+	case opts.ollamaModel != "":
+		cfg.LLM.Provider = "ollama"
+		cfg.LLM.APIKey = ""
+		cfg.LLM.Model = opts.ollamaModel
+	}
+	if opts.model != "" {
+		cfg.LLM.Model = opts.model
+	}
+
+	if opts.telegramToken != "" {
+		cfg.Telegram.Enabled = true
+		cfg.Telegram.Token = opts.telegramToken
+	}
+
+	if opts.dataDir != "" {
+		cfg.Storage.BasePath = opts.dataDir
+		cfg.Skills.Directory = filepath.Join(opts.dataDir, "skills")
+		cfg.Plugins.Directory = filepath.Join(opts.dataDir, "plugins")
+		cfg.Scheduler.TasksFile = filepath.Join(opts.dataDir, "tasks.json")
+	}
+
+	if opts.port > 0 {
+		cfg.WebSocket.Port = opts.port
+	}
+}
+
+// agentOnly keeps a run to the agent and its tools, for ask and stdio. The
+// channels, the admin API and the scheduler, whose tasks belong to the
+// long-running instance, stay off, and failures are reported instead of
+// retried later.
+func agentOnly(cfg *config.Config) {
+	cfg.Telegram.Enabled = false
+	cfg.Discord.Enabled = false
+	cfg.Email.Enabled = false
+	cfg.WebSocket.Enabled = false
+	cfg.API.Enabled = false
+	cfg.Scheduler.Enabled = false
+	cfg.Agent.RetryDelay = 0
+}
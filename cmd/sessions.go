@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/export"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const sessionsUsage = `Usage:
+  miniclaw sessions list
+  miniclaw sessions clear <chat-id>
+  miniclaw sessions export [--format json|html] [--output <file>] <chat-id>`
+
+// runSessionsCommand lists, clears and exports the conversations in the data
+// directory. Clearing goes through the admin API while MiniClaw runs, so the
+// history it keeps in memory is cleared too.
+func runSessionsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", sessionsUsage)
+	}
+
+	flags := flag.NewFlagSet("sessions "+args[0], flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "config file")
+	apiKey := flags.String("key", os.Getenv("MINICLAW_API_KEY"), "API key with operator access, used while MiniClaw runs")
+	format := flags.String("format", "json", "export format: json or html")
+	output := flags.String("output", "", "write the export to this file instead of stdout")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewFileConfigManager(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	sessionStorage := storage.NewFileSystemSessionStorage(cfg.Storage.BasePath + "/sessions")
+	ctx := context.Background()
+
+	if args[0] != "list" && flags.NArg() != 1 {
+		return fmt.Errorf("%s needs a chat id\n%s", args[0], sessionsUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		chatIDs, err := sessionStorage.ListSessions(ctx)
+		if err != nil {
+			return err
+		}
+		for _, chatID := range chatIDs {
+			fmt.Println(chatID)
+		}
+
+	case "clear":
+		chatID := flags.Arg(0)
+		if client := newAdminClient(cfg, *apiKey); client.running() {
+			err = client.call(http.MethodDelete, "/api/sessions/"+url.PathEscape(chatID), nil, nil)
+		} else {
+			err = sessionStorage.ClearSession(ctx, chatID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to clear %s: %w", chatID, err)
+		}
+		fmt.Printf("Cleared session %s\n", chatID)
+
+	case "export":
+		data, err := exportSession(ctx, cfg, sessionStorage, flags.Arg(0), *format)
+		if err != nil {
+			return err
+		}
+
+		if *output == "" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", *output, err)
+		}
+		fmt.Printf("Exported %s to %s\n", flags.Arg(0), *output)
+
+	default:
+		return fmt.Errorf("unknown subcommand: %s\n%s", args[0], sessionsUsage)
+	}
+
+	return nil
+}
+
+func exportSession(ctx context.Context, cfg *config.Config, sessionStorage storage.SessionStorage, chatID, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		messages, err := sessionStorage.GetMessages(ctx, chatID, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", chatID, err)
+		}
+		if len(messages) == 0 {
+			return nil, fmt.Errorf("session %s has no messages", chatID)
+		}
+
+		data, err := json.MarshalIndent(messages, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+
+	case "html":
+		exporter, err := export.NewExporter(&export.Config{
+			SessionStorage: sessionStorage,
+			Storage:        storage.NewFileStorage(cfg.Storage.BasePath),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		page, err := exporter.Export(ctx, chatID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", chatID, err)
+		}
+		return page, nil
+
+	default:
+		return nil, fmt.Errorf("unknown export format %q, expected json or html", format)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/skills"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const skillsUsage = `Usage:
+  miniclaw skills list
+  miniclaw skills validate [<file>...]`
+
+// runSkillsCommand lists and checks skills. While MiniClaw runs the list
+// comes from its registry, which includes skill packs; otherwise it is read
+// from the skills directory. Without files, validate checks the whole
+// directory.
+func runSkillsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", skillsUsage)
+	}
+
+	flags := flag.NewFlagSet("skills "+args[0], flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "config file")
+	apiKey := flags.String("key", os.Getenv("MINICLAW_API_KEY"), "API key with viewer access, used while MiniClaw runs")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewFileConfigManager(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	parser := skills.NewSkillParser(storage.NewFileStorage(cfg.Storage.BasePath))
+	ctx := context.Background()
+
+	switch args[0] {
+	case "list":
+		if flags.NArg() != 0 {
+			return fmt.Errorf("list takes no arguments\n%s", skillsUsage)
+		}
+
+		var list []*skills.Skill
+		if client := newAdminClient(cfg, *apiKey); client.running() {
+			if err := client.call(http.MethodGet, "/api/skills", nil, &list); err != nil {
+				return err
+			}
+		} else {
+			var problems []*skills.SkillFileError
+			if list, problems, err = parser.CheckDirectory(ctx, cfg.Skills.Directory); err != nil {
+				return err
+			}
+			if len(problems) > 0 {
+				fmt.Fprintf(os.Stderr, "Skipped %d invalid skill file(s), run miniclaw skills validate for details\n", len(problems))
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCATEGORY\tENABLED\tDESCRIPTION")
+		for _, skill := range list {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", skill.Name, skill.Category, skill.Enabled, logging.Preview(skill.Description, 60))
+		}
+		return w.Flush()
+
+	case "validate":
+		var valid []*skills.Skill
+		var problems []*skills.SkillFileError
+		if flags.NArg() == 0 {
+			if valid, problems, err = parser.CheckDirectory(ctx, cfg.Skills.Directory); err != nil {
+				return err
+			}
+		}
+		for _, file := range flags.Args() {
+			path, err := filepath.Abs(file)
+			if err != nil {
+				return err
+			}
+			skill, err := parser.Parse(ctx, path)
+			if err != nil {
+				problems = append(problems, &skills.SkillFileError{Path: file, Err: err})
+				continue
+			}
+			valid = append(valid, skill)
+		}
+
+		for _, skill := range valid {
+			fmt.Printf("ok  %s (%s)\n", skill.Path, skill.Name)
+		}
+		for _, problem := range problems {
+			fmt.Printf("bad %s\n", problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d of %d skill file(s) are invalid", len(problems), len(valid)+len(problems))
+		}
+
+	default:
+		return fmt.Errorf("unknown subcommand: %s\n%s", args[0], skillsUsage)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/communication/stdio"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+// runStdio serves the JSON-RPC protocol on stdin and stdout until stdin is
+// closed. Logs stay on stderr.
+func runStdio(ctx context.Context, messageBus bus.MessageBus) error {
+	server := stdio.NewServer(&stdio.Config{
+		Reader: os.Stdin,
+		Writer: os.Stdout,
+		Logger: logging.For("stdio"),
+	}, messageBus, ctx)
+
+	handler := stdio.NewHandler(server)
+	if _, err := messageBus.Subscribe(bus.ChannelStdio, handler.HandleMessage); err != nil {
+		return fmt.Errorf("failed to subscribe stdio handler: %w", err)
+	}
+
+	return server.Run()
+}
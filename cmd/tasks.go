@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/scheduler"
+)
+
+const tasksUsage = `Usage:
+  miniclaw tasks list
+  miniclaw tasks add [--name <name>] [--channel telegram] [--misfire skip] <chat-id> <schedule> <prompt>
+  miniclaw tasks rm <id>
+
+The schedule is a cron expression such as "0 9 * * 1-5", a macro such as
+"@daily" or a delay such as "in 2 hours". When the task runs, the prompt is
+handled like a message from the chat and the answer is sent there.`
+
+// taskRow is a task as listed, from the admin API or the tasks file.
+type taskRow struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	Status   string     `json:"status"`
+	Enabled  bool       `json:"enabled"`
+	ChatID   string     `json:"chat_id"`
+	NextRun  *time.Time `json:"next_run"`
+}
+
+// runTasksCommand manages scheduled tasks. While MiniClaw runs with the
+// admin API enabled the changes go through it; otherwise the tasks file is
+// edited, which a running instance without the API would overwrite.
+func runTasksCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", tasksUsage)
+	}
+
+	flags := flag.NewFlagSet("tasks "+args[0], flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "config file")
+	apiKey := flags.String("key", os.Getenv("MINICLAW_API_KEY"), "API key with operator access, used while MiniClaw runs")
+	name := flags.String("name", "", "short name for the task (defaults to the prompt)")
+	channel := flags.String("channel", "", "channel of the chat (defaults to telegram)")
+	misfire := flags.String("misfire", "", "what to do with runs missed while MiniClaw was down: skip, run_once or run_all")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewFileConfigManager(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+	client := newAdminClient(cfg, *apiKey)
+	tasksFile := cfg.Scheduler.TasksFile
+
+	switch args[0] {
+	case "list":
+		if flags.NArg() != 0 {
+			return fmt.Errorf("list takes no arguments\n%s", tasksUsage)
+		}
+
+		var rows []taskRow
+		if client.running() {
+			if err := client.call(http.MethodGet, "/api/tasks", nil, &rows); err != nil {
+				return err
+			}
+		} else if rows, err = readTaskRows(tasksFile); err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSCHEDULE\tSTATUS\tCHAT\tNEXT RUN")
+		for _, row := range rows {
+			status := row.Status
+			if !row.Enabled {
+				status = "paused"
+			}
+			nextRun := "-"
+			if row.NextRun != nil && row.Enabled {
+				nextRun = row.NextRun.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", row.ID, logging.Preview(row.Name, 40), row.Schedule, status, row.ChatID, nextRun)
+		}
+		return w.Flush()
+
+	case "add":
+		if flags.NArg() != 3 {
+			return fmt.Errorf("add needs a chat id, a schedule and a prompt\n%s", tasksUsage)
+		}
+		task := &scheduler.PromptTask{
+			Name:     *name,
+			ChatID:   flags.Arg(0),
+			Schedule: flags.Arg(1),
+			Prompt:   flags.Arg(2),
+			Channel:  *channel,
+			Misfire:  *misfire,
+		}
+
+		if client.running() {
+			var row taskRow
+			if err := client.call(http.MethodPost, "/api/tasks", task, &row); err != nil {
+				return fmt.Errorf("failed to add task: %w", err)
+			}
+			if row.NextRun == nil {
+				fmt.Printf("Added task %s\n", row.ID)
+				return nil
+			}
+			fmt.Printf("Added task %s, next run at %s\n", row.ID, row.NextRun.Format(time.RFC3339))
+			return nil
+		}
+
+		taskConfig, err := task.TaskConfig(time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to add task: %w", err)
+		}
+		configs, err := scheduler.ReadTasksFile(tasksFile)
+		if err != nil {
+			return err
+		}
+		if err := scheduler.WriteTasksFile(tasksFile, append(configs, *taskConfig)); err != nil {
+			return err
+		}
+		fmt.Printf("Added task %s to %s, it is scheduled when MiniClaw starts\n", taskConfig.ID, tasksFile)
+
+	case "rm":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("rm needs a task id\n%s", tasksUsage)
+		}
+		taskID := flags.Arg(0)
+
+		if client.running() {
+			if err := client.call(http.MethodDelete, "/api/tasks/"+url.PathEscape(taskID), nil, nil); err != nil {
+				return fmt.Errorf("failed to remove task %s: %w", taskID, err)
+			}
+			fmt.Printf("Removed task %s\n", taskID)
+			return nil
+		}
+
+		configs, err := scheduler.ReadTasksFile(tasksFile)
+		if err != nil {
+			return err
+		}
+		kept := configs[:0]
+		for _, taskConfig := range configs {
+			if taskConfig.ID != taskID {
+				kept = append(kept, taskConfig)
+			}
+		}
+		if len(kept) == len(configs) {
+			return fmt.Errorf("task %s not found in %s", taskID, tasksFile)
+		}
+		if err := scheduler.WriteTasksFile(tasksFile, kept); err != nil {
+			return err
+		}
+		fmt.Printf("Removed task %s\n", taskID)
+
+	default:
+		return fmt.Errorf("unknown subcommand: %s\n%s", args[0], tasksUsage)
+	}
+
+	return nil
+}
+
+// readTaskRows lists the saved tasks of a stopped instance. The next run of
+// a recurring task is only known once it is scheduled again.
+func readTaskRows(tasksFile string) ([]taskRow, error) {
+	configs, err := scheduler.ReadTasksFile(tasksFile)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]taskRow, 0, len(configs))
+	for _, taskConfig := range configs {
+		row := taskRow{
+			ID:       taskConfig.ID,
+			Name:     taskConfig.Name,
+			Schedule: taskConfig.CronExpr,
+			Status:   "stopped",
+			Enabled:  taskConfig.Enabled,
+		}
+		if !taskConfig.RunAt.IsZero() {
+			runAt := taskConfig.RunAt
+			row.Schedule = "once at " + runAt.Format(time.RFC3339)
+			row.NextRun = &runAt
+		}
+		if taskConfig.Action != nil {
+			row.ChatID = taskConfig.Action.ChatID
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/communication/telegram"
+	"github.com/wjffsx/miniclaw_go/internal/scheduler"
+)
+
+// registerTelegramCommands adds the commands the bot answers without asking
+// the LLM. They run after the agent is initialized, so agentService is set.
+func registerTelegramCommands(bot *telegram.Bot) {
+	bot.RegisterCommand(telegram.Command{
+		Name:        "clear",
+		Description: "Clear the conversation history",
+		Handler: func(ctx context.Context, chatID string, args []string) (string, error) {
+			if agentService == nil {
+				return "", fmt.Errorf("agent is not ready")
+			}
+			agentService.ClearChatHistory(chatID)
+			return "Conversation cleared.", nil
+		},
+	})
+
+	bot.RegisterCommand(telegram.Command{
+		Name:        "model",
+		Description: "Show the available models or switch to another one",
+		Handler:     telegramModelCommand,
+		AdminOnly:   true,
+	})
+
+	bot.RegisterCommand(telegram.Command{
+		Name:        "tasks",
+		Description: "List the scheduled tasks of this chat",
+		Handler:     telegramTasksCommand,
+	})
+}
+
+func telegramModelCommand(ctx context.Context, chatID string, args []string) (string, error) {
+	if agentService == nil || agentService.GetLLMManager() == nil {
+		return "", fmt.Errorf("LLM is not configured")
+	}
+	manager := agentService.GetLLMManager()
+
+	if len(args) > 0 {
+		if err := manager.SwitchModel(args[0]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Switched to %s.", args[0]), nil
+	}
+
+	current := manager.GetCurrentModel()
+	models := manager.ListModels()
+	sort.Strings(models)
+
+	var builder strings.Builder
+	builder.WriteString("**Models**")
+	for _, name := range models {
+		if name == current {
+			fmt.Fprintf(&builder, "\n• `%s` (current)", name)
+		} else {
+			fmt.Fprintf(&builder, "\n• `%s`", name)
+		}
+	}
+	return builder.String(), nil
+}
+
+func telegramTasksCommand(ctx context.Context, chatID string, args []string) (string, error) {
+	if agentService == nil || agentService.GetTaskManager() == nil {
+		return "", fmt.Errorf("the scheduler is not enabled")
+	}
+
+	var owned []*scheduler.Task
+	for _, task := range agentService.GetTaskManager().ListTasks() {
+		if task.Action != nil && task.Action.ChatID == chatID {
+			owned = append(owned, task)
+		}
+	}
+	if len(owned) == 0 {
+		return "No scheduled tasks.", nil
+	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].NextRun.Before(owned[j].NextRun) })
+
+	var builder strings.Builder
+	builder.WriteString("**Scheduled tasks**")
+	for _, task := range owned {
+		next := "paused"
+		if task.Enabled {
+			next = "next " + task.NextRun.Format(time.RFC822)
+		}
+		schedule := "once"
+		if !task.OneOff() {
+			schedule = scheduler.DescribeCronExpression(task.CronExpr)
+		}
+		fmt.Fprintf(&builder, "\n• %s: %s (%s)", task.Name, schedule, next)
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const toolsUsage = `Usage:
+  miniclaw tools list`
+
+// runToolsCommand lists the tools the model can call. The tools depend on
+// the config and on the connected MCP servers, so they are asked from the
+// running instance.
+func runToolsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", toolsUsage)
+	}
+
+	flags := flag.NewFlagSet("tools "+args[0], flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "config file")
+	apiKey := flags.String("key", os.Getenv("MINICLAW_API_KEY"), "API key with viewer access")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewFileConfigManager(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	switch args[0] {
+	case "list":
+		if flags.NArg() != 0 {
+			return fmt.Errorf("list takes no arguments\n%s", toolsUsage)
+		}
+
+		client := newAdminClient(cfg, *apiKey)
+		if !client.running() {
+			return fmt.Errorf("listing tools needs the admin API of the running instance, start MiniClaw with api enabled in %s", *configPath)
+		}
+
+		var schemas []tools.ToolSchema
+		if err := client.call(http.MethodGet, "/api/tools", nil, &schemas); err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tDESCRIPTION")
+		for _, schema := range schemas {
+			fmt.Fprintf(w, "%s\t%s\n", schema.Name, logging.Preview(schema.Description, 80))
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown subcommand: %s\n%s", args[0], toolsUsage)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/agent"
+	"github.com/wjffsx/miniclaw_go/internal/config"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const transcriptsUsage = `Usage:
+  miniclaw transcripts list [--chat <chat-id>] [--limit 20]
+  miniclaw transcripts show [--full] [--json] <id>
+  miniclaw transcripts replay [--model <name>] [--max-iterations 10] [--json] <id>`
+
+// runTranscriptsCommand inspects the recorded ReAct loops. Replays call the
+// model again but not the tools, so they are safe to run next to a live
+// instance.
+func runTranscriptsCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", transcriptsUsage)
+	}
+
+	flags := flag.NewFlagSet("transcripts "+args[0], flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath, "config file")
+	chatID := flags.String("chat", "", "only list transcripts of this chat")
+	limit := flags.Int("limit", 20, "how many of the latest transcripts to list")
+	full := flags.Bool("full", false, "also print the system prompt and the whole history")
+	asJSON := flags.Bool("json", false, "print the transcript as JSON")
+	model := flags.String("model", "", "model to replay with, by name in llm.models (defaults to the default model)")
+	maxIterations := flags.Int("max-iterations", 10, "iterations the replay may take")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewFileConfigManager(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	store := agent.NewTranscriptStore(storage.NewFileStorage(cfg.Storage.BasePath))
+	ctx := context.Background()
+
+	if args[0] != "list" && flags.NArg() != 1 {
+		return fmt.Errorf("%s needs a transcript id\n%s", args[0], transcriptsUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		ids, err := store.List(ctx)
+		if err != nil {
+			return err
+		}
+
+		var transcripts []*agent.Transcript
+		for i := len(ids) - 1; i >= 0 && len(transcripts) < *limit; i-- {
+			transcript, err := store.Get(ctx, ids[i])
+			if err != nil {
+				return err
+			}
+			if *chatID == "" || transcript.ChatID == *chatID {
+				transcripts = append(transcripts, transcript)
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCHANNEL\tCHAT\tMODEL\tITERATIONS\tTOOLS\tTOKENS\tDURATION\tMESSAGE")
+		for i := len(transcripts) - 1; i >= 0; i-- {
+			t := transcripts[i]
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n", t.ID, t.Channel, t.ChatID, t.Model, len(t.Iterations),
+				len(t.ToolCalls()), t.Usage().TotalTokens, formatMillis(t.Duration), logging.Preview(lastUserMessage(t), 40))
+		}
+		return w.Flush()
+
+	case "show":
+		transcript, err := store.Get(ctx, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(transcript)
+		}
+		printTranscript(os.Stdout, transcript, *full)
+
+	case "replay":
+		original, err := store.Get(ctx, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		llmModels, defaultModel := newLLMModels(cfg)
+		manager, err := llm.NewMultiModelManager(llmModels, defaultModel)
+		if err != nil {
+			return fmt.Errorf("failed to create LLM manager: %w", err)
+		}
+		if *model == "" {
+			*model = defaultModel
+		}
+
+		replay := agent.ReplayTranscript(llm.WithModel(ctx, *model), manager, original, *maxIterations)
+		if *asJSON {
+			return printJSON(replay)
+		}
+		printComparison(os.Stdout, original, replay)
+
+	default:
+		return fmt.Errorf("unknown subcommand: %s\n%s", args[0], transcriptsUsage)
+	}
+
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func printTranscript(w io.Writer, t *agent.Transcript, full bool) {
+	fmt.Fprintf(w, "Transcript %s\n", t.ID)
+	fmt.Fprintf(w, "Chat:     %s (%s), message %s\n", t.ChatID, t.Channel, t.MessageID)
+	fmt.Fprintf(w, "Model:    %s\n", t.Model)
+	fmt.Fprintf(w, "Started:  %s, took %s, %d tokens\n", t.StartedAt.Local().Format(time.RFC3339), formatMillis(t.Duration), t.Usage().TotalTokens)
+	if len(t.Skills) > 0 {
+		fmt.Fprintf(w, "Skills:   %s\n", strings.Join(t.Skills, ", "))
+	}
+
+	if full {
+		fmt.Fprintf(w, "\n== System prompt ==\n%s\n", t.SystemPrompt)
+		fmt.Fprintln(w, "\n== Messages ==")
+		for _, msg := range t.Messages {
+			fmt.Fprintf(w, "[%s] %s\n", msg.Role, msg.Content)
+		}
+	} else {
+		fmt.Fprintf(w, "\n== Message ==\n%s\n", lastUserMessage(t))
+	}
+
+	printIterations(w, t)
+
+	if t.Error != "" {
+		fmt.Fprintf(w, "\n== Error ==\n%s\n", t.Error)
+	} else {
+		fmt.Fprintf(w, "\n== Response ==\n%s\n", t.Response)
+	}
+}
+
+func printIterations(w io.Writer, t *agent.Transcript) {
+	for i, iteration := range t.Iterations {
+		fmt.Fprintf(w, "\n== Iteration %d (%s, %d tokens) ==\n", i+1, formatMillis(iteration.Duration), iteration.Usage.TotalTokens)
+		if iteration.Error != "" {
+			fmt.Fprintf(w, "Error: %s\n", iteration.Error)
+			continue
+		}
+		fmt.Fprintln(w, iteration.Response)
+
+		for _, call := range iteration.ToolCalls {
+			input, _ := json.Marshal(call.Input)
+			fmt.Fprintf(w, "  -> %s %s (%s)\n", call.Name, input, formatMillis(call.Duration))
+			if call.Error != "" {
+				fmt.Fprintf(w, "     error: %s\n", call.Error)
+			} else {
+				fmt.Fprintf(w, "     %s\n", logging.Preview(call.Result, 200))
+			}
+		}
+	}
+}
+
+func printComparison(w io.Writer, original, replay *agent.Transcript) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tORIGINAL\tREPLAY")
+	fmt.Fprintf(tw, "Model\t%s\t%s\n", original.Model, replay.Model)
+	fmt.Fprintf(tw, "Iterations\t%d\t%d\n", len(original.Iterations), len(replay.Iterations))
+	fmt.Fprintf(tw, "Tools\t%s\t%s\n", toolNames(original), toolNames(replay))
+	fmt.Fprintf(tw, "Tokens\t%d\t%d\n", original.Usage().TotalTokens, replay.Usage().TotalTokens)
+	fmt.Fprintf(tw, "Duration\t%s\t%s\n", formatMillis(original.Duration), formatMillis(replay.Duration))
+	tw.Flush()
+
+	printIterations(w, replay)
+
+	fmt.Fprintf(w, "\n== Original response ==\n%s\n", original.Response)
+	if replay.Error != "" {
+		fmt.Fprintf(w, "\n== Replay error ==\n%s\n", replay.Error)
+	} else {
+		fmt.Fprintf(w, "\n== Replay response ==\n%s\n", replay.Response)
+	}
+}
+
+func toolNames(t *agent.Transcript) string {
+	var names []string
+	for _, call := range t.ToolCalls() {
+		names = append(names, call.Name)
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+func lastUserMessage(t *agent.Transcript) string {
+	for i := len(t.Messages) - 1; i >= 0; i-- {
+		if t.Messages[i].Role == llm.RoleUser {
+			return t.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+func formatMillis(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+// isAdmin reports whether the sender of msg may use admin-only tools and
+// commands. Only the local CLI and stdio channels are trusted without a user;
+// anywhere else admin rights come from the channel's admins list.
+func isAdmin(msg *bus.Message) bool {
+	if user, ok := msg.User(); ok {
+		return user.Admin
+	}
+	return msg.Channel == bus.ChannelCLI || msg.Channel == bus.ChannelStdio
+}
+
+func (a *Agent) authorizeToolCall(ctx context.Context, name string) bool {
+	msg := requestMessageFromContext(ctx)
+	if msg == nil || isAdmin(msg) {
+		return true
+	}
+
+	a.logger.Warn("Refused admin-only tool", "chat_id", msg.ChatID, "tool", name)
+	return false
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestAgentRestrictsAdminOnlyToolsAndCommands(t *testing.T) {
+	ctx := context.Background()
+	messageBus := &flakyBus{published: make(chan *bus.Message, 4)}
+
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(t.TempDir()),
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	messageOn := func(channel string, user *bus.User) *bus.Message {
+		msg := &bus.Message{ID: "m", Channel: channel, ChatID: "chat", Content: "/tools snapshot"}
+		if user != nil {
+			msg.Metadata = map[string]interface{}{bus.MetadataUser: *user}
+		}
+		return msg
+	}
+	message := func(user *bus.User) *bus.Message {
+		return messageOn(bus.ChannelTelegram, user)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		allowed bool
+	}{
+		{"no conversation", ctx, true},
+		{"no user", withRequestMessage(ctx, message(nil)), false},
+		{"cli", withRequestMessage(ctx, messageOn(bus.ChannelCLI, nil)), true},
+		{"stdio", withRequestMessage(ctx, messageOn(bus.ChannelStdio, nil)), true},
+		{"admin", withRequestMessage(ctx, message(&bus.User{ID: "telegram:1", Admin: true})), true},
+		{"user", withRequestMessage(ctx, message(&bus.User{ID: "telegram:2"})), false},
+	}
+	for _, tt := range tests {
+		if allowed := agent.authorizeToolCall(tt.ctx, "exec_command"); allowed != tt.allowed {
+			t.Errorf("%s: expected allowed=%v, got %v", tt.name, tt.allowed, allowed)
+		}
+	}
+
+	if err := agent.HandleMessage(ctx, message(&bus.User{ID: "telegram:2"})); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+	if reply := <-messageBus.published; reply.Content != "Only an admin can record tool snapshots." {
+		t.Errorf("Expected the snapshot to be refused, got %s", reply.Content)
+	}
+}
@@ -3,18 +3,27 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/chaos"
 	agentcontext "github.com/wjffsx/miniclaw_go/internal/context"
+	"github.com/wjffsx/miniclaw_go/internal/intent"
 	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 	"github.com/wjffsx/miniclaw_go/internal/mcp"
 	"github.com/wjffsx/miniclaw_go/internal/scheduler"
 	"github.com/wjffsx/miniclaw_go/internal/skills"
 	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/templates"
 	"github.com/wjffsx/miniclaw_go/internal/tools"
+	"github.com/wjffsx/miniclaw_go/internal/tracing"
+	"github.com/wjffsx/miniclaw_go/internal/workspace"
 )
 
 type Agent struct {
@@ -23,18 +32,63 @@ type Agent struct {
 	toolExecutor   *tools.ToolExecutor
 	contextBuilder *agentcontext.Builder
 	skillSelector  *skills.SkillSelector
+	skillRegistry  *skills.SkillRegistry
+	toolRegistry   *tools.ToolRegistry
+	toolSnapshots  *tools.ToolSnapshotStore
+	templates      *templates.Registry
+	fastPath       *intent.Router
 	mcpManager     *mcp.MCPManager
 	taskManager    *scheduler.TaskManager
 	sessionStorage storage.SessionStorage
 	memoryStorage  storage.MemoryStorage
+	storage        storage.Storage
 	ctx            context.Context
+	mu             sync.RWMutex
+	chatLocks      *chatLocker
+	requests       *requestTracker
+	conversations  chan struct{}
 	chatHistory    map[string][]llm.Message
+	sessions       *sessionCache
+	chatTemplates  map[string]*templates.Template
+	templatesMu    sync.Mutex
+	showWork       map[string]bool
+	lastExchanges  map[string]*exchange
+	confirmations  map[string]chan bool
+	quotas         *quotas
+	pages          map[string]*pagedResponse
+	skillMemoryMu  sync.Mutex
+	forkMu         sync.Mutex
+	dailyNoteMu    sync.Mutex
 	maxIterations  int
+	retryDelay     time.Duration
+	deadLetters    *bus.DeadLetterStore
+	historyTokens  int
+	logger         *slog.Logger
+
+	summarizeHistory    bool
+	missingToolWarnings map[string]bool
+
+	defaultShowWork bool
+	logThinking     bool
+
+	subAgents        []SubAgentConfig
+	maxSubAgentDepth int
+
+	channels []string
+
+	transcripts         *TranscriptStore
+	transcriptRetention time.Duration
+
+	journal       *JournalConfig
+	consolidation *ConsolidationConfig
 }
 
 type Config struct {
 	LLMModels      []*llm.ModelConfig
 	DefaultModel   string
+	LLMRouting     *llm.RoutingConfig
+	LLMBudget      *llm.BudgetConfig
+	LLMRateLimits  map[string]llm.RateLimitConfig
 	SessionStorage storage.SessionStorage
 	MemoryStorage  storage.MemoryStorage
 	Storage        storage.Storage
@@ -43,7 +97,44 @@ type Config struct {
 	SkillConfig    *skills.SkillConfig
 	MCPManager     *mcp.MCPManager
 	TaskManager    *scheduler.TaskManager
+	Workspace      *workspace.Watcher
+	Templates      *templates.Registry
 	MaxIterations  int
+	ShowWork       bool
+	LogThinking    bool
+	RetryDelay     time.Duration
+	// DeadLetters keeps messages that still fail after the delayed retry.
+	DeadLetters *bus.DeadLetterStore
+
+	MaxConcurrentChats int
+	HistoryTokens      int
+	MaxSessions        int
+	SessionIdleTTL     time.Duration
+	SummarizeHistory   bool
+	MemoryIndexed      bool
+	FastPath           *intent.Router
+	Logger             *slog.Logger
+	Chaos              *chaos.Injector
+	ToolPolicies       *tools.PolicyConfig
+	Quotas             *QuotaConfig
+	SubAgents          []SubAgentConfig
+	// MaxSubAgentDepth is how deeply sub-agents may delegate to other
+	// sub-agents. Zero uses the default of 1, where only the agent itself
+	// can delegate.
+	MaxSubAgentDepth int
+	// Channels are the channels with a running client, which the
+	// send_message tool can deliver to.
+	Channels []string
+	// Transcripts records every ReAct loop for debugging when set. They are
+	// deleted after TranscriptRetention, or kept if it is zero.
+	Transcripts         *TranscriptStore
+	TranscriptRetention time.Duration
+	// ContextSections limits what goes into the system prompt.
+	ContextSections agentcontext.Sections
+	// Journal logs every answered message in the daily note when set.
+	Journal *JournalConfig
+	// MemoryConsolidation configures the consolidate_memory task action.
+	MemoryConsolidation *ConsolidationConfig
 }
 
 func NewAgent(config *Config, messageBus bus.MessageBus, ctx context.Context) (*Agent, error) {
@@ -51,19 +142,50 @@ func NewAgent(config *Config, messageBus bus.MessageBus, ctx context.Context) (*
 		return nil, fmt.Errorf("config cannot be nil")
 	}
 
+	logger := logging.Or(config.Logger, "agent")
+
 	llmManager, err := llm.NewMultiModelManager(config.LLMModels, config.DefaultModel)
 	if err != nil {
-		log.Printf("Warning: failed to create LLM manager: %v", err)
-		log.Println("Agent will run without LLM support")
+		logger.Warn("Failed to create LLM manager, agent will run without LLM support", "error", err)
 		llmManager = nil
+	} else {
+		if err := llmManager.SetRouting(config.LLMRouting); err != nil {
+			logger.Warn("Failed to configure LLM routing", "error", err)
+		}
+		if err := llmManager.SetBudget(config.LLMBudget); err != nil {
+			logger.Warn("Failed to configure LLM budget", "error", err)
+		}
+		if len(config.LLMRateLimits) > 0 {
+			llmManager.SetRateLimits(config.LLMRateLimits)
+		}
+		if config.Storage != nil {
+			if err := llmManager.SetCostStorage(ctx, config.Storage); err != nil {
+				logger.Warn("Failed to load LLM cost reports", "error", err)
+			}
+		}
 	}
 
 	toolExecutor := tools.NewToolExecutor(config.ToolRegistry)
+	toolExecutor.SetPolicies(config.ToolPolicies)
 
-	contextBuilder := agentcontext.NewBuilder(&agentcontext.Config{
+	if config.Chaos != nil {
+		toolExecutor.SetWrapper(config.Chaos.WrapTool)
+		if llmManager != nil {
+			llmManager.WrapProviders(config.Chaos.WrapProvider)
+		}
+	}
+
+	contextConfig := &agentcontext.Config{
 		Storage:       config.Storage,
 		MemoryStorage: config.MemoryStorage,
-	})
+		Workspace:     config.Workspace,
+		MemoryIndexed: config.MemoryIndexed,
+		Sections:      config.ContextSections,
+	}
+	if llmManager != nil {
+		contextConfig.Summarize = newMemorySummarizer(llmManager)
+	}
+	contextBuilder := agentcontext.NewBuilder(contextConfig)
 
 	var skillSelector *skills.SkillSelector
 	if config.SkillRegistry != nil {
@@ -76,7 +198,7 @@ func NewAgent(config *Config, messageBus bus.MessageBus, ctx context.Context) (*
 			selectionConfig = &config.SkillConfig.Selection
 		}
 		skillSelector = skills.NewSkillSelector(config.SkillRegistry, nil, selectionConfig)
-		log.Printf("Skill selector initialized with method: %s", selectionConfig.Method)
+		logger.Info("Skill selector initialized", "method", selectionConfig.Method)
 	}
 
 	maxIterations := config.MaxIterations
@@ -84,55 +206,251 @@ func NewAgent(config *Config, messageBus bus.MessageBus, ctx context.Context) (*
 		maxIterations = 10
 	}
 
-	return &Agent{
+	maxSubAgentDepth := config.MaxSubAgentDepth
+	if maxSubAgentDepth <= 0 {
+		maxSubAgentDepth = defaultSubAgentDepth
+	}
+
+	var conversations chan struct{}
+	if config.MaxConcurrentChats > 0 {
+		conversations = make(chan struct{}, config.MaxConcurrentChats)
+	}
+
+	agent := &Agent{
 		messageBus:     messageBus,
 		llmManager:     llmManager,
 		toolExecutor:   toolExecutor,
 		contextBuilder: contextBuilder,
 		skillSelector:  skillSelector,
+		skillRegistry:  config.SkillRegistry,
+		toolRegistry:   config.ToolRegistry,
+		templates:      config.Templates,
+		fastPath:       config.FastPath,
 		mcpManager:     config.MCPManager,
 		taskManager:    config.TaskManager,
 		sessionStorage: config.SessionStorage,
 		memoryStorage:  config.MemoryStorage,
+		storage:        config.Storage,
 		ctx:            ctx,
+		chatLocks:      newChatLocker(),
+		requests:       newRequestTracker(),
+		conversations:  conversations,
 		chatHistory:    make(map[string][]llm.Message),
+		sessions:       newSessionCache(config.MaxSessions, config.SessionIdleTTL),
+		chatTemplates:  make(map[string]*templates.Template),
+		showWork:       make(map[string]bool),
+		lastExchanges:  make(map[string]*exchange),
+		confirmations:  make(map[string]chan bool),
+		quotas:         newQuotas(config.Quotas),
+		pages:          make(map[string]*pagedResponse),
 		maxIterations:  maxIterations,
-	}, nil
+		retryDelay:     config.RetryDelay,
+		deadLetters:    config.DeadLetters,
+		historyTokens:  config.HistoryTokens,
+		logger:         logger,
+
+		defaultShowWork:  config.ShowWork,
+		logThinking:      config.LogThinking,
+		summarizeHistory: config.SummarizeHistory,
+
+		subAgents:        config.SubAgents,
+		maxSubAgentDepth: maxSubAgentDepth,
+
+		channels: config.Channels,
+
+		transcripts:         config.Transcripts,
+		transcriptRetention: config.TranscriptRetention,
+
+		journal:       config.Journal,
+		consolidation: config.MemoryConsolidation,
+
+		missingToolWarnings: make(map[string]bool),
+	}
+
+	toolExecutor.SetConfirmer(agent.confirmToolCall)
+	toolExecutor.SetAuthorizer(agent.authorizeToolCall)
+
+	if config.TaskManager != nil {
+		agent.registerTaskActions(config.TaskManager)
+	}
+
+	if config.Storage != nil {
+		agent.toolSnapshots = tools.NewToolSnapshotStore(config.Storage)
+		agent.loadChatTemplates(ctx)
+	}
+
+	if config.ToolRegistry != nil {
+		if err := config.ToolRegistry.Register(NewSummarizeConversationTool(agent)); err != nil {
+			logger.Error("Failed to register tool", "tool", "summarize_conversation", "error", err)
+		}
+		if config.MemoryStorage != nil {
+			if err := config.ToolRegistry.Register(NewSetPreferenceTool(agent)); err != nil {
+				logger.Error("Failed to register tool", "tool", "set_preference", "error", err)
+			}
+		}
+		if llmManager != nil {
+			if err := config.ToolRegistry.Register(NewBudgetStatusTool(agent)); err != nil {
+				logger.Error("Failed to register tool", "tool", "budget_status", "error", err)
+			}
+		}
+		if len(config.SubAgents) > 0 {
+			if err := config.ToolRegistry.Register(NewSpawnSubAgentTool(agent)); err != nil {
+				logger.Error("Failed to register tool", "tool", spawnSubAgentTool, "error", err)
+			}
+		}
+		if len(config.Channels) > 0 {
+			if err := config.ToolRegistry.Register(NewSendMessageTool(agent)); err != nil {
+				logger.Error("Failed to register tool", "tool", sendMessageTool, "error", err)
+			}
+		}
+	}
+
+	return agent, nil
 }
 
 func (a *Agent) Start() error {
 	if a.llmManager != nil {
-		log.Printf("Starting agent with LLM provider: %s, model: %s", a.llmManager.GetProvider(), a.llmManager.GetModel())
+		a.logger.Info("Starting agent", "provider", a.llmManager.GetProvider(), "model", a.llmManager.GetModel())
 	} else {
-		log.Println("Starting agent without LLM support")
+		a.logger.Info("Starting agent without LLM support")
 	}
 
-	if _, err := a.messageBus.Subscribe(bus.ChannelCLI, a.HandleMessage); err != nil {
+	if _, err := a.messageBus.Subscribe(bus.ChannelCLI, a.handleWithRetry); err != nil {
 		return fmt.Errorf("failed to subscribe to CLI channel: %w", err)
 	}
 
-	if _, err := a.messageBus.Subscribe(bus.ChannelTelegram, a.HandleMessage); err != nil {
+	if _, err := a.messageBus.Subscribe(bus.ChannelTelegram, a.handleWithRetry); err != nil {
 		return fmt.Errorf("failed to subscribe to Telegram channel: %w", err)
 	}
 
-	if _, err := a.messageBus.Subscribe(bus.ChannelWebSocket, a.HandleMessage); err != nil {
+	if _, err := a.messageBus.Subscribe(bus.ChannelDiscord, a.handleWithRetry); err != nil {
+		return fmt.Errorf("failed to subscribe to Discord channel: %w", err)
+	}
+
+	if _, err := a.messageBus.Subscribe(bus.ChannelEmail, a.handleWithRetry); err != nil {
+		return fmt.Errorf("failed to subscribe to email channel: %w", err)
+	}
+
+	if _, err := a.messageBus.Subscribe(bus.ChannelWebSocket, a.handleWithRetry); err != nil {
 		return fmt.Errorf("failed to subscribe to WebSocket channel: %w", err)
 	}
 
+	if _, err := a.messageBus.Subscribe(bus.ChannelStdio, a.handleWithRetry); err != nil {
+		return fmt.Errorf("failed to subscribe to stdio channel: %w", err)
+	}
+
+	if a.sessions.idleTTL > 0 {
+		go a.sweepSessions()
+	}
+
+	if a.transcripts != nil && a.transcriptRetention > 0 {
+		go a.pruneTranscripts()
+	}
+
 	return nil
 }
 
-func (a *Agent) Stop() error {
-	log.Println("Stopping agent...")
+// Stop lets the conversations in progress finish until ctx is done, then
+// cancels the rest and tells their chats to send their message again. New
+// messages arriving meanwhile get the same request. Call it while the
+// channels and the bus are still running, so the replies are delivered.
+func (a *Agent) Stop(ctx context.Context) error {
+	a.logger.Info("Stopping agent", "in_flight", a.requests.count())
+
+	if interrupted := a.requests.drain(ctx); interrupted > 0 {
+		return fmt.Errorf("interrupted %d conversation(s) that did not finish in time", interrupted)
+	}
 	return nil
 }
 
-func (a *Agent) HandleMessage(ctx context.Context, msg *bus.Message) error {
+func (a *Agent) HandleMessage(ctx context.Context, msg *bus.Message) (err error) {
 	if msg == nil {
 		return fmt.Errorf("message cannot be nil")
 	}
 
-	log.Printf("Agent received message from %s: %s", msg.Channel, msg.Content)
+	if msg.IsPartial() || msg.IsReply() {
+		return nil
+	}
+
+	// A model the sender asked for, unless a retry already picked another.
+	if _, pinned := llm.ModelFromContext(ctx); !pinned && msg.Model() != "" && a.llmManager != nil {
+		if _, err := a.llmManager.GetModelConfig(msg.Model()); err != nil {
+			a.logger.Warn("Ignoring unknown model", "chat_id", msg.ChatID, "model", msg.Model())
+		} else {
+			ctx = llm.WithModel(ctx, msg.Model())
+		}
+	}
+
+	ctx, span := tracing.Start(tracing.WithTraceID(ctx, bus.TraceID(ctx)), "agent.handle_message",
+		tracing.String("channel", msg.Channel), tracing.String("chat_id", msg.ChatID), tracing.String("message_id", msg.ID))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if reaction := msg.Reaction(); reaction != nil {
+		return a.handleReaction(ctx, msg, reaction)
+	}
+
+	if callback := msg.Callback(); callback != nil && (callback.Data == confirmYes || callback.Data == confirmNo) {
+		a.resolveConfirmation(msg.ChatID, callback.Data == confirmYes)
+		return nil
+	}
+
+	if callback := msg.Callback(); callback != nil && callback.Data == pageMore {
+		return a.sendNextPage(ctx, msg)
+	}
+
+	if isMoreCommand(msg.Content) && a.hasMorePages(msg.ChatID) {
+		return a.sendNextPage(ctx, msg)
+	}
+
+	if approved, ok := parseConfirmationReply(msg.Content); ok && a.resolveConfirmation(msg.ChatID, approved) {
+		return nil
+	}
+
+	var quotaErr *QuotaError
+	if err := a.quotas.reserve(ctx, msg, quotaMessages); errors.As(err, &quotaErr) {
+		return a.throttle(ctx, msg, quotaErr)
+	}
+
+	release, err := a.acquireChat(ctx, msg.ChatID)
+	if err != nil {
+		return fmt.Errorf("failed to acquire conversation: %w", err)
+	}
+	defer release()
+
+	a.clearPages(msg.ChatID)
+
+	a.logger.Info("Message received", "channel", msg.Channel, "chat_id", msg.ChatID, "preview", logging.Preview(msg.Content, 80))
+
+	if isNewCommand(msg.Content) {
+		return a.handleNewCommand(ctx, msg)
+	}
+
+	if isShowWorkCommand(msg.Content) {
+		return a.handleShowWorkCommand(ctx, msg)
+	}
+
+	if isSummarizeCommand(msg.Content) {
+		return a.handleSummarizeCommand(ctx, msg)
+	}
+
+	if isToolsCommand(msg.Content) {
+		return a.handleToolsCommand(ctx, msg)
+	}
+
+	if isPrefsCommand(msg.Content) {
+		return a.handlePrefsCommand(ctx, msg)
+	}
+
+	if isForkCommand(msg.Content) {
+		return a.handleForkCommand(ctx, msg)
+	}
+
+	if answered, err := a.answerFastPath(ctx, msg); answered {
+		return err
+	}
 
 	if a.llmManager == nil {
 		responseMsg := &bus.Message{
@@ -141,67 +459,160 @@ func (a *Agent) HandleMessage(ctx context.Context, msg *bus.Message) error {
 			ChatID:  msg.ChatID,
 			Content: "LLM is not configured. Please set up your API key in the configuration.",
 		}
-		return a.messageBus.Publish(ctx, msg.Channel, responseMsg)
+		return a.publishReply(ctx, msg, responseMsg)
+	}
+
+	content := msg.Content
+	var images []llm.ContentPart
+	if attachments := msg.Attachments(); len(attachments) > 0 {
+		content = describeAttachments(content, attachments)
+		images = a.imageParts(ctx, msg.ChatID, attachments)
 	}
 
-	messages := a.getChatHistory(msg.ChatID)
+	history := a.fitHistory(ctx, msg.ChatID, a.getChatHistory(msg.ChatID), llm.EstimateTokens(content))
 
-	messages = append(messages, llm.Message{
+	userMessage := llm.Message{
 		Role:    llm.RoleUser,
-		Content: msg.Content,
-	})
+		Content: content,
+	}
+	// Images are only sent with the message they came with; the history
+	// keeps the descriptions with their paths.
+	request := userMessage
+	request.Parts = images
+	messages := append(append(make([]llm.Message, 0, len(history)+2), history...), request)
 
-	response, err := a.runReActLoop(ctx, messages, msg.Content)
+	responseID := fmt.Sprintf("agent-%s", msg.ID)
+
+	loopCtx := tools.WithChannel(withRequestMessage(ctx, msg), msg.Channel)
+	if msg.WantsStreaming() {
+		loopCtx = withResponseStream(loopCtx, &responseStream{agent: a, request: msg, responseID: responseID})
+	}
+
+	var transcript *Transcript
+	if a.transcripts != nil {
+		transcript = newTranscript(msg.ChatID)
+		transcript.MessageID = msg.ID
+		transcript.Channel = msg.Channel
+		loopCtx = withTranscript(loopCtx, transcript)
+	}
+
+	response, toolCalls, err := a.runReActLoop(loopCtx, msg.ChatID, messages, content)
+	if transcript != nil {
+		transcript.finish(response, err)
+		a.saveTranscript(ctx, transcript)
+	}
+	if errors.As(err, &quotaErr) {
+		return a.throttle(ctx, msg, quotaErr)
+	}
+	if errors.Is(err, llm.ErrBudgetExceeded) {
+		return a.reply(ctx, msg, "The daily usage budget has been reached, so I can't answer until it resets tomorrow.\n\n"+
+			formatBudgetStatus(a.llmManager.BudgetStatus(msg.ChatID)))
+	}
 	if err != nil {
 		return fmt.Errorf("failed to run ReAct loop: %w", err)
 	}
 
-	log.Printf("Final LLM response: %s", response)
+	a.logger.Debug("Final LLM response", "chat_id", msg.ChatID, "content", response)
 
-	messages = append(messages, llm.Message{
+	a.appendChatHistory(msg.ChatID, history, userMessage, llm.Message{
 		Role:    llm.RoleAssistant,
 		Content: response,
 	})
-
-	a.setChatHistory(msg.ChatID, messages)
+	a.recordToolCalls(ctx, msg, responseID, toolCalls)
 
 	responseMsg := &bus.Message{
-		ID:      fmt.Sprintf("agent-%s", msg.ID),
+		ID:      responseID,
 		Channel: msg.Channel,
 		ChatID:  msg.ChatID,
 		Content: response,
 	}
 
-	if err := a.messageBus.Publish(ctx, msg.Channel, responseMsg); err != nil {
+	if len(toolCalls) > 0 && a.IsShowWorkEnabled(msg.ChatID) {
+		responseMsg.Metadata = map[string]interface{}{
+			bus.MetadataToolUses: buildToolUses(toolCalls),
+		}
+	}
+
+	a.setLastExchange(msg.ChatID, &exchange{
+		request:    msg,
+		responseID: responseMsg.ID,
+		model:      a.currentModel(ctx),
+	})
+
+	if err := a.publishResponse(ctx, msg, responseMsg); err != nil {
 		return fmt.Errorf("failed to publish response: %w", err)
 	}
 
+	if a.journal != nil {
+		a.journalTurn(ctx, msg, content, response, toolCalls)
+	}
+
 	return nil
 }
 
-func (a *Agent) runReActLoop(ctx context.Context, messages []llm.Message, userMessage string) (string, error) {
-	toolSchemas := a.toolExecutor.GetSchemas()
+func (a *Agent) runReActLoop(ctx context.Context, chatID string, messages []llm.Message, userMessage string) (string, []tools.ToolCall, error) {
+	ctx = tools.WithChatID(ctx, chatID)
+	ctx = llm.WithChatID(ctx, chatID)
+
+	template := a.GetChatTemplate(chatID)
+	selectedSkills := a.getTemplateSkills(template)
+	if msg := requestMessageFromContext(ctx); msg != nil {
+		selectedSkills = mergeSkills(selectedSkills, a.namedSkills(msg.Skills(), "message "+msg.ID))
+	}
+
+	if a.skillSelector != nil {
+		matchedSkills, err := a.skillSelector.Select(ctx, userMessage)
+		if err != nil {
+			a.logger.Warn("Failed to select skills", "chat_id", chatID, "error", err)
+		} else {
+			selectedSkills = mergeSkills(selectedSkills, matchedSkills)
+		}
+	}
+
+	ctx = tools.WithTools(ctx, a.skillMemoryTools(selectedSkills)...)
+	toolSchemas := a.toolExecutor.SchemasFor(ctx)
+	a.warnMissingTools(selectedSkills, toolSchemas)
 
 	agentContext, err := a.contextBuilder.Build(ctx, toolSchemas)
 	if err != nil {
-		log.Printf("Failed to build context: %v", err)
+		a.logger.Error("Failed to build context", "chat_id", chatID, "error", err)
+	}
+
+	if template != nil && template.SystemPrompt != "" {
+		agentContext.ConversationTemplate = fmt.Sprintf("## Conversation Template: %s\n\n%s", template.Name, template.SystemPrompt)
+	}
+
+	if msg := requestMessageFromContext(ctx); msg != nil {
+		agentContext.ChannelGuidance = channelGuidance(msg.Capabilities())
+	}
+
+	if len(selectedSkills) > 0 {
+		a.logger.Debug("Selected skills", "chat_id", chatID, "skills", getSkillNames(selectedSkills))
+		agentContext.Skills = a.buildSkillContext(ctx, selectedSkills)
 	}
 
 	systemPrompt := agentContext.BuildSystemPrompt(toolSchemas)
 
-	if a.skillSelector != nil {
-		selectedSkills, err := a.skillSelector.Select(ctx, userMessage)
-		if err != nil {
-			log.Printf("Failed to select skills: %v", err)
-		} else if len(selectedSkills) > 0 {
-			log.Printf("Selected %d skills: %v", len(selectedSkills), getSkillNames(selectedSkills))
-			skillContext := a.buildSkillContext(selectedSkills)
-			systemPrompt += "\n\n" + skillContext
-		}
+	if transcript := transcriptFromContext(ctx); transcript != nil {
+		transcript.Model = a.currentModel(ctx)
+		transcript.SystemPrompt = systemPrompt
+		transcript.Skills = getSkillNames(selectedSkills)
+		transcript.Messages = messages
 	}
 
-	for iteration := 0; iteration < a.maxIterations; iteration++ {
-		log.Printf("ReAct iteration %d/%d", iteration+1, a.maxIterations)
+	return a.iterate(ctx, chatID, systemPrompt, messages, a.maxIterations, nil)
+}
+
+// iterate runs the ReAct loop with a fixed system prompt until the model
+// gives a final answer or maxIterations is reached. If allowed is not nil,
+// only the tools in it may be called.
+func (a *Agent) iterate(ctx context.Context, chatID string, systemPrompt string, messages []llm.Message, maxIterations int, allowed map[string]bool) (string, []tools.ToolCall, error) {
+	usedTools := make([]tools.ToolCall, 0)
+	transcript := transcriptFromContext(ctx)
+
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		a.logger.Debug("ReAct iteration", "chat_id", chatID, "iteration", iteration+1, "max", maxIterations)
+		iterationCtx, span := tracing.Start(ctx, "agent.iteration", tracing.Int("iteration", iteration+1))
 
 		llmMessages := make([]llm.Message, 0, len(messages)+1)
 		llmMessages = append(llmMessages, llm.Message{
@@ -210,41 +621,80 @@ func (a *Agent) runReActLoop(ctx context.Context, messages []llm.Message, userMe
 		})
 		llmMessages = append(llmMessages, messages...)
 
-		response, err := a.llmManager.Complete(ctx, llmMessages)
+		started := time.Now()
+		response, err := a.complete(iterationCtx, llmMessages)
 		if err != nil {
-			return "", fmt.Errorf("failed to complete LLM request: %w", err)
+			if transcript != nil {
+				transcript.Iterations = append(transcript.Iterations, Iteration{Duration: time.Since(started).Milliseconds(), Error: err.Error()})
+			}
+			span.RecordError(err)
+			span.End()
+			return "", usedTools, fmt.Errorf("failed to complete LLM request: %w", err)
 		}
 
-		log.Printf("LLM response: %s", response.Content)
-
-		toolCalls, isFinal := a.parseResponse(response.Content)
-		if isFinal {
-			return response.Content, nil
+		var recorded *Iteration
+		if transcript != nil {
+			transcript.Iterations = append(transcript.Iterations, Iteration{
+				Response: response.Content,
+				Usage:    response.Usage,
+				Duration: time.Since(started).Milliseconds(),
+			})
+			recorded = &transcript.Iterations[len(transcript.Iterations)-1]
 		}
 
-		if len(toolCalls) == 0 {
-			return response.Content, nil
+		a.logger.Debug("LLM response", "chat_id", chatID, "content", response.Content)
+
+		toolCalls, isFinal := a.parseResponse(response.Content)
+		if isFinal || len(toolCalls) == 0 {
+			span.SetAttributes(tracing.Bool("final", true))
+			span.End()
+			return response.Content, usedTools, nil
 		}
 
 		toolResults := make([]tools.ToolCall, 0, len(toolCalls))
 		for _, call := range toolCalls {
-			log.Printf("Executing tool: %s with params: %v", call.Name, call.Input)
+			a.logger.Info("Executing tool", "chat_id", chatID, "tool", call.Name, "params", call.Input)
+
+			if allowed != nil && !allowed[call.Name] {
+				toolResults = append(toolResults, tools.ToolCall{
+					Name:  call.Name,
+					Input: call.Input,
+					Error: fmt.Sprintf("tool '%s' is not available", call.Name),
+				})
+				continue
+			}
+
+			a.publishToolEvent(ctx, bus.ToolStarted, tools.ToolCall{Name: call.Name, Input: call.Input})
 
-			result, err := a.toolExecutor.Execute(ctx, call.Name, call.Input)
+			started := time.Now()
+			result, err := a.toolExecutor.Execute(iterationCtx, call.Name, call.Input)
 			if err != nil {
-				log.Printf("Tool execution error: %v", err)
+				a.logger.Warn("Tool execution error", "chat_id", chatID, "tool", call.Name, "error", err)
+				if result == nil {
+					result = &tools.ToolCall{Name: call.Name, Input: call.Input}
+				}
 				result.Error = err.Error()
 			}
+			result.Duration = time.Since(started).Milliseconds()
+
+			a.publishToolEvent(ctx, bus.ToolFinished, *result)
 
 			toolResults = append(toolResults, *result)
-			log.Printf("Tool result: %s", result.Result)
+			a.logger.Debug("Tool result", "chat_id", chatID, "tool", call.Name, "result", result.Result)
+		}
+		if recorded != nil {
+			recorded.ToolCalls = toolResults
 		}
+		span.SetAttributes(tracing.Int("tool_calls", len(toolCalls)))
+		span.End()
 
 		toolResultsJSON, err := json.MarshalIndent(toolResults, "", "  ")
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal tool results: %w", err)
+			return "", usedTools, fmt.Errorf("failed to marshal tool results: %w", err)
 		}
 
+		usedTools = append(usedTools, toolResults...)
+
 		observation := fmt.Sprintf("Tool execution results:\n%s", string(toolResultsJSON))
 		messages = append(messages, llm.Message{
 			Role:    llm.RoleAssistant,
@@ -256,10 +706,10 @@ func (a *Agent) runReActLoop(ctx context.Context, messages []llm.Message, userMe
 		})
 	}
 
-	return "", fmt.Errorf("max iterations (%d) reached without final answer", a.maxIterations)
+	return "", usedTools, fmt.Errorf("max iterations (%d) reached without final answer", maxIterations)
 }
 
-func (a *Agent) buildSkillContext(selectedSkills []*skills.Skill) string {
+func (a *Agent) buildSkillContext(ctx context.Context, selectedSkills []*skills.Skill) string {
 	var builder strings.Builder
 
 	builder.WriteString("## Active Skills\n\n")
@@ -275,6 +725,16 @@ func (a *Agent) buildSkillContext(selectedSkills []*skills.Skill) string {
 			builder.WriteString(fmt.Sprintf("**Tags**: %v\n", skill.Tags))
 		}
 		builder.WriteString(fmt.Sprintf("**Instructions**:\n%s\n\n", skill.Content))
+		if len(skill.Scripts) > 0 {
+			names := make([]string, 0, len(skill.Scripts))
+			for _, script := range skill.Scripts {
+				names = append(names, skills.ScriptToolName(skill.Name, script.Name))
+			}
+			builder.WriteString(fmt.Sprintf("**Script tools**: %s\n\n", strings.Join(names, ", ")))
+		}
+		if memory := a.skillMemoryContext(ctx, skill); memory != "" {
+			builder.WriteString(fmt.Sprintf("**Skill memory** (%s):\n%s\n", skill.Memory, memory))
+		}
 	}
 
 	builder.WriteString("Use these skills as guidelines when responding to the user. Adapt your approach based on the specific requirements of each skill.\n")
@@ -282,6 +742,80 @@ func (a *Agent) buildSkillContext(selectedSkills []*skills.Skill) string {
 	return builder.String()
 }
 
+func (a *Agent) getTemplateSkills(template *templates.Template) []*skills.Skill {
+	if template == nil {
+		return nil
+	}
+	return a.namedSkills(template.Skills, "template "+template.Name)
+}
+
+// namedSkills looks up skills by name, logging the ones that do not exist
+// along with who asked for them.
+func (a *Agent) namedSkills(names []string, source string) []*skills.Skill {
+	if len(names) == 0 || a.skillRegistry == nil {
+		return nil
+	}
+
+	named := make([]*skills.Skill, 0, len(names))
+	for _, name := range names {
+		skill, ok := a.skillRegistry.GetByName(name)
+		if !ok {
+			a.logger.Warn("Unknown skill requested", "source", source, "skill", name)
+			continue
+		}
+		named = append(named, skill)
+	}
+
+	return named
+}
+
+// warnMissingTools logs, once per skill and set of tools, when a selected
+// skill requires tools that are not registered.
+func (a *Agent) warnMissingTools(selectedSkills []*skills.Skill, toolSchemas []tools.ToolSchema) {
+	registered := make(map[string]bool, len(toolSchemas))
+	for _, schema := range toolSchemas {
+		registered[schema.Name] = true
+	}
+
+	for _, skill := range selectedSkills {
+		var missing []string
+		for _, name := range skill.Requires {
+			if !registered[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		key := skill.ID + ":" + strings.Join(missing, ",")
+		a.mu.Lock()
+		warned := a.missingToolWarnings[key]
+		a.missingToolWarnings[key] = true
+		a.mu.Unlock()
+
+		if !warned {
+			a.logger.Warn("Selected skill requires tools that are not registered", "skill", skill.Name, "missing", strings.Join(missing, ", "))
+		}
+	}
+}
+
+func mergeSkills(base, extra []*skills.Skill) []*skills.Skill {
+	seen := make(map[string]bool, len(base))
+	for _, skill := range base {
+		seen[skill.ID] = true
+	}
+
+	for _, skill := range extra {
+		if !seen[skill.ID] {
+			seen[skill.ID] = true
+			base = append(base, skill)
+		}
+	}
+
+	return base
+}
+
 func getSkillNames(skills []*skills.Skill) []string {
 	names := make([]string, 0, len(skills))
 	for _, skill := range skills {
@@ -291,6 +825,17 @@ func getSkillNames(skills []*skills.Skill) []string {
 }
 
 func (a *Agent) parseResponse(content string) ([]tools.ToolCall, bool) {
+	toolCalls, isFinal, err := parseReActResponse(content)
+	if err != nil {
+		a.logger.Debug("Failed to parse LLM response as JSON", "error", err)
+	}
+	return toolCalls, isFinal
+}
+
+// parseReActResponse returns the tool calls the model asked for, or reports
+// that content is the final answer. Content that is not JSON is taken as a
+// final answer in plain text.
+func parseReActResponse(content string) ([]tools.ToolCall, bool, error) {
 	var response struct {
 		Thought     string           `json:"thought"`
 		ToolCalls   []tools.ToolCall `json:"tool_calls"`
@@ -298,29 +843,32 @@ func (a *Agent) parseResponse(content string) ([]tools.ToolCall, bool) {
 	}
 
 	if err := json.Unmarshal([]byte(content), &response); err != nil {
-		log.Printf("Failed to parse LLM response as JSON: %v", err)
-		return nil, true
+		return nil, true, err
 	}
 
 	if response.FinalAnswer != "" {
-		return nil, true
+		return nil, true, nil
 	}
 
 	if len(response.ToolCalls) > 0 {
-		return response.ToolCalls, false
+		return response.ToolCalls, false, nil
 	}
 
-	return nil, true
+	return nil, true, nil
 }
 
 func (a *Agent) getChatHistory(chatID string) []llm.Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	if history, ok := a.chatHistory[chatID]; ok {
+		a.sessions.touch(chatID, time.Now())
 		return history
 	}
 
-	messages, err := a.sessionStorage.GetMessages(context.Background(), chatID, 50)
+	messages, err := a.sessionStorage.GetMessages(context.Background(), chatID, 0)
 	if err != nil {
-		log.Printf("Failed to load messages for %s: %v", chatID, err)
+		a.logger.Error("Failed to load messages", "chat_id", chatID, "error", err)
 		return []llm.Message{}
 	}
 
@@ -332,8 +880,12 @@ func (a *Agent) getChatHistory(chatID string) []llm.Message {
 		})
 	}
 
-	a.chatHistory[chatID] = llmMessages
-	return llmMessages
+	history := loadHistory(llmMessages)
+	if len(messages) > 0 {
+		a.sessions.reloaded++
+	}
+	a.storeHistory(chatID, history)
+	return history
 }
 
 func (a *Agent) GetChatHistory(chatID string) []llm.Message {
@@ -341,7 +893,191 @@ func (a *Agent) GetChatHistory(chatID string) []llm.Message {
 }
 
 func (a *Agent) ClearChatHistory(chatID string) {
-	a.chatHistory[chatID] = []llm.Message{}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.storeHistory(chatID, []llm.Message{})
+}
+
+// chatTemplatesFile maps each chat to the name of the template its
+// conversation was started from.
+const chatTemplatesFile = "templates/chats.json"
+
+func (a *Agent) GetChatTemplate(chatID string) *templates.Template {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.chatTemplates[chatID]
+}
+
+// setChatTemplate records the template a conversation was started from and
+// saves the assignments so that they survive a restart.
+func (a *Agent) setChatTemplate(chatID string, template *templates.Template) {
+	a.templatesMu.Lock()
+	defer a.templatesMu.Unlock()
+
+	a.mu.Lock()
+	current := a.chatTemplates[chatID]
+	if template == nil {
+		delete(a.chatTemplates, chatID)
+	} else {
+		a.chatTemplates[chatID] = template
+	}
+	names := make(map[string]string, len(a.chatTemplates))
+	for id, t := range a.chatTemplates {
+		names[id] = t.Name
+	}
+	a.mu.Unlock()
+
+	if current == template || a.storage == nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(names, "", "  ")
+	if err != nil {
+		a.logger.Error("Failed to marshal chat templates", "error", err)
+		return
+	}
+	if err := a.storage.WriteFile(a.ctx, chatTemplatesFile, data); err != nil {
+		a.logger.Error("Failed to save chat templates", "chat_id", chatID, "error", err)
+	}
+}
+
+func (a *Agent) loadChatTemplates(ctx context.Context) {
+	if a.templates == nil {
+		return
+	}
+
+	exists, err := a.storage.FileExists(ctx, chatTemplatesFile)
+	if err != nil || !exists {
+		return
+	}
+
+	data, err := a.storage.ReadFile(ctx, chatTemplatesFile)
+	if err != nil {
+		a.logger.Error("Failed to read chat templates", "error", err)
+		return
+	}
+
+	var names map[string]string
+	if err := json.Unmarshal(data, &names); err != nil {
+		a.logger.Error("Failed to parse chat templates", "error", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for chatID, name := range names {
+		template, ok := a.templates.Get(name)
+		if !ok {
+			a.logger.Warn("Conversation template no longer exists", "chat_id", chatID, "template", name)
+			continue
+		}
+		a.chatTemplates[chatID] = template
+	}
+}
+
+// startConversation clears the chat's history, in memory and in session
+// storage, and sets the template it starts from, if any.
+func (a *Agent) startConversation(ctx context.Context, chatID string, template *templates.Template) {
+	a.ClearChatHistory(chatID)
+	if a.sessionStorage != nil {
+		if err := a.sessionStorage.ClearSession(ctx, chatID); err != nil {
+			a.logger.Error("Failed to clear session", "chat_id", chatID, "error", err)
+		}
+	}
+	a.setChatTemplate(chatID, template)
+}
+
+func describeAttachments(content string, attachments []bus.Attachment) string {
+	var builder strings.Builder
+	builder.WriteString(content)
+
+	for _, attachment := range attachments {
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+
+		if attachment.Path == "" {
+			fmt.Fprintf(&builder, "[Attached %s %s could not be saved: %s]", attachment.Type, attachment.FileName, attachment.Error)
+			continue
+		}
+
+		fmt.Fprintf(&builder, "[Attached %s saved to %s", attachment.Type, attachment.Path)
+		if attachment.MimeType != "" {
+			fmt.Fprintf(&builder, " (%s, %d bytes)", attachment.MimeType, attachment.Size)
+		} else {
+			fmt.Fprintf(&builder, " (%d bytes)", attachment.Size)
+		}
+		builder.WriteString("]")
+	}
+
+	return builder.String()
+}
+
+func isNewCommand(content string) bool {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return false
+	}
+
+	command, _, _ := strings.Cut(fields[0], "@")
+	return command == "/new"
+}
+
+func (a *Agent) handleNewCommand(ctx context.Context, msg *bus.Message) error {
+	fields := strings.Fields(msg.Content)
+
+	var response string
+	if len(fields) < 2 {
+		a.startConversation(ctx, msg.ChatID, nil)
+		response = "Started a new conversation."
+		if list := a.listTemplates(); list != "" {
+			response += "\n\nAvailable templates:\n" + list
+		}
+	} else if a.templates == nil {
+		response = "No conversation templates are configured."
+	} else if template, ok := a.templates.Get(fields[1]); !ok {
+		response = fmt.Sprintf("Unknown template: %s", fields[1])
+		if list := a.listTemplates(); list != "" {
+			response += "\n\nAvailable templates:\n" + list
+		}
+	} else {
+		a.startConversation(ctx, msg.ChatID, template)
+		a.logger.Info("Started conversation from template", "chat_id", msg.ChatID, "template", template.Name)
+
+		response = fmt.Sprintf("Started a new conversation from template %s.", template.Name)
+		if template.Description != "" {
+			response += "\n" + template.Description
+		}
+		if template.FirstMessage != "" {
+			response += "\n\nSuggested first message:\n" + template.FirstMessage
+		}
+	}
+
+	responseMsg := &bus.Message{
+		ID:      fmt.Sprintf("agent-%s", msg.ID),
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: response,
+	}
+
+	return a.publishReply(ctx, msg, responseMsg)
+}
+
+func (a *Agent) listTemplates() string {
+	if a.templates == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	for _, template := range a.templates.List() {
+		builder.WriteString(fmt.Sprintf("- %s", template.Name))
+		if template.Description != "" {
+			builder.WriteString(fmt.Sprintf(": %s", template.Description))
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
 }
 
 func (a *Agent) SetMaxIterations(maxIterations int) {
@@ -368,12 +1104,30 @@ func (a *Agent) GetTaskManager() *scheduler.TaskManager {
 	return a.taskManager
 }
 
-func (a *Agent) setChatHistory(chatID string, messages []llm.Message) {
-	a.chatHistory[chatID] = messages
+func (a *Agent) GetLLMManager() *llm.MultiModelManager {
+	return a.llmManager
+}
 
-	for _, msg := range messages {
+func (a *Agent) GetToolRegistry() *tools.ToolRegistry {
+	return a.toolRegistry
+}
+
+func (a *Agent) GetSkillRegistry() *skills.SkillRegistry {
+	return a.skillRegistry
+}
+
+// appendChatHistory makes history followed by added the resident history of
+// a chat and saves only the added messages, since history is already stored.
+func (a *Agent) appendChatHistory(chatID string, history []llm.Message, added ...llm.Message) {
+	messages := append(append(make([]llm.Message, 0, len(history)+len(added)), history...), added...)
+
+	a.mu.Lock()
+	a.storeHistory(chatID, messages)
+	a.mu.Unlock()
+
+	for _, msg := range added {
 		if err := a.sessionStorage.SaveMessage(context.Background(), chatID, string(msg.Role), msg.Content); err != nil {
-			log.Printf("Failed to save message for %s: %v", chatID, err)
+			a.logger.Error("Failed to save message", "chat_id", chatID, "error", err)
 		}
 	}
 }
@@ -1,7 +1,10 @@
 package agent
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,11 +14,12 @@ import (
 	"github.com/wjffsx/miniclaw_go/internal/scheduler"
 	"github.com/wjffsx/miniclaw_go/internal/skills"
 	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/templates"
 	"github.com/wjffsx/miniclaw_go/internal/tools"
 )
 
 func TestNewAgent(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -51,7 +55,7 @@ func TestNewAgent(t *testing.T) {
 }
 
 func TestNewAgentNilConfig(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	_, err := NewAgent(nil, messageBus, ctx)
@@ -61,7 +65,7 @@ func TestNewAgentNilConfig(t *testing.T) {
 }
 
 func TestAgentProcessMessage(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -95,7 +99,7 @@ func TestAgentProcessMessage(t *testing.T) {
 }
 
 func TestAgentProcessMessageNil(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -124,7 +128,7 @@ func TestAgentProcessMessageNil(t *testing.T) {
 }
 
 func TestAgentGetChatHistory(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -153,7 +157,7 @@ func TestAgentGetChatHistory(t *testing.T) {
 }
 
 func TestAgentClearChatHistory(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -184,7 +188,7 @@ func TestAgentClearChatHistory(t *testing.T) {
 }
 
 func TestAgentSetMaxIterations(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -214,7 +218,7 @@ func TestAgentSetMaxIterations(t *testing.T) {
 }
 
 func TestAgentGetMaxIterations(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -243,7 +247,7 @@ func TestAgentGetMaxIterations(t *testing.T) {
 }
 
 func TestAgentGetToolExecutor(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -272,7 +276,7 @@ func TestAgentGetToolExecutor(t *testing.T) {
 }
 
 func TestAgentGetSkillSelector(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -301,7 +305,7 @@ func TestAgentGetSkillSelector(t *testing.T) {
 }
 
 func TestAgentGetMCPManager(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -330,7 +334,7 @@ func TestAgentGetMCPManager(t *testing.T) {
 }
 
 func TestAgentGetTaskManager(t *testing.T) {
-	messageBus := bus.NewInMemoryMessageBus(context.Background())
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
 	ctx := context.Background()
 
 	config := &Config{
@@ -357,3 +361,172 @@ func TestAgentGetTaskManager(t *testing.T) {
 		t.Error("Expected taskManager to be set")
 	}
 }
+
+func TestAgentNewCommandWithTemplate(t *testing.T) {
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
+	ctx := context.Background()
+
+	templateRegistry := templates.NewRegistry()
+	templateRegistry.Register(&templates.Template{
+		Name:         "weekly-review",
+		SystemPrompt: "Review the week.",
+		FirstMessage: "Let's review my week.",
+	})
+
+	dir := t.TempDir()
+	sessionStorage := storage.NewFileSystemSessionStorage(dir)
+	config := &Config{
+		LLMModels:      []*llm.ModelConfig{},
+		DefaultModel:   "default",
+		SessionStorage: sessionStorage,
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        storage.NewFileStorage(dir),
+		ToolRegistry:   tools.NewToolRegistry(),
+		SkillRegistry:  skills.NewSkillRegistry(nil),
+		SkillConfig:    &skills.SkillConfig{},
+		MCPManager:     mcp.NewMCPManager(nil),
+		TaskManager:    scheduler.NewTaskManager(scheduler.NewScheduler(&scheduler.SchedulerConfig{TickInterval: 1 * time.Second}), nil),
+		Templates:      templateRegistry,
+		MaxIterations:  10,
+	}
+
+	agent, err := NewAgent(config, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	err = agent.HandleMessage(ctx, &bus.Message{
+		Channel: bus.ChannelCLI,
+		ChatID:  "test-chat",
+		Content: "/new unknown",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if agent.GetChatTemplate("test-chat") != nil {
+		t.Error("Expected no template for unknown name")
+	}
+
+	err = agent.HandleMessage(ctx, &bus.Message{
+		Channel: bus.ChannelCLI,
+		ChatID:  "test-chat",
+		Content: "/new@miniclaw_bot weekly-review",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	template := agent.GetChatTemplate("test-chat")
+	if template == nil || template.Name != "weekly-review" {
+		t.Fatalf("Expected weekly-review template, got %v", template)
+	}
+
+	restarted, err := NewAgent(config, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	if template := restarted.GetChatTemplate("test-chat"); template == nil || template.Name != "weekly-review" {
+		t.Errorf("Expected the template to survive a restart, got %v", template)
+	}
+
+	sessionStorage.SaveMessage(ctx, "test-chat", "user", "An old question")
+
+	err = agent.HandleMessage(ctx, &bus.Message{
+		Channel: bus.ChannelCLI,
+		ChatID:  "test-chat",
+		Content: "/new",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if agent.GetChatTemplate("test-chat") != nil {
+		t.Error("Expected template to be cleared by plain /new")
+	}
+	if messages, _ := sessionStorage.GetMessages(ctx, "test-chat", 0); len(messages) != 0 {
+		t.Errorf("Expected /new to clear the stored session, got %+v", messages)
+	}
+}
+
+func TestDescribeAttachments(t *testing.T) {
+	content := describeAttachments("what is this?", []bus.Attachment{
+		{Type: bus.AttachmentPhoto, Path: "uploads/telegram/1/5_photo.jpg", MimeType: "image/jpeg", Size: 2048},
+		{Type: bus.AttachmentDocument, FileName: "big.zip", Error: "file exceeds maximum size of 10 bytes"},
+	})
+
+	expected := "what is this?\n" +
+		"[Attached photo saved to uploads/telegram/1/5_photo.jpg (image/jpeg, 2048 bytes)]\n" +
+		"[Attached document big.zip could not be saved: file exceeds maximum size of 10 bytes]"
+
+	if content != expected {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+}
+
+func TestAgentMarksRepliesAndIgnoresThem(t *testing.T) {
+	ctx := context.Background()
+	messageBus := &flakyBus{published: make(chan *bus.Message, 4)}
+
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(""),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(""),
+		Storage:        storage.NewFileStorage(""),
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	request := &bus.Message{
+		ID:       "websocket-1",
+		Channel:  bus.ChannelWebSocket,
+		ChatID:   "chat",
+		Content:  "/showwork on",
+		Metadata: map[string]interface{}{bus.MetadataConnection: "conn-7"},
+	}
+	if err := agent.HandleMessage(ctx, request); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	response := <-messageBus.published
+	if response.ReplyTo() != "websocket-1" || response.Connection() != "conn-7" {
+		t.Errorf("Expected response to point back at the request, got %+v", response.Metadata)
+	}
+
+	response.Content = "/showwork off"
+	if err := agent.HandleMessage(ctx, response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !agent.IsShowWorkEnabled("chat") || len(messageBus.published) != 0 {
+		t.Error("Expected the agent to ignore its own replies")
+	}
+}
+
+func TestAgentWarnsAboutMissingSkillTools(t *testing.T) {
+	var logs bytes.Buffer
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(t.TempDir()),
+		ToolRegistry:   tools.NewToolRegistry(),
+		Logger:         slog.New(slog.NewTextHandler(&logs, nil)),
+	}, bus.NewInMemoryMessageBus(context.Background(), nil), context.Background())
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	skill := skills.NewSkill("researcher", "Research topics", "research")
+	skill.Requires = []string{"echo", "web_search"}
+	schemas := []tools.ToolSchema{{Name: "echo"}}
+
+	agent.warnMissingTools([]*skills.Skill{skill}, schemas)
+	agent.warnMissingTools([]*skills.Skill{skill}, schemas)
+
+	if count := strings.Count(logs.String(), "requires tools that are not registered"); count != 1 {
+		t.Errorf("Expected one warning, got %d:\n%s", count, logs.String())
+	}
+	if !strings.Contains(logs.String(), "missing=web_search") {
+		t.Errorf("Expected the missing tool to be named, got %s", logs.String())
+	}
+}
@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func NewBudgetStatusTool(a *Agent) tools.Tool {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"additionalProperties": false
+	}`)
+
+	return tools.NewBaseTool(
+		"budget_status",
+		"Show how much LLM usage has cost today in this conversation and overall, and the configured daily budgets",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			if a.llmManager == nil {
+				return "", fmt.Errorf("LLM is not configured")
+			}
+
+			chatID, _ := tools.ChatIDFromContext(ctx)
+			return formatBudgetStatus(a.llmManager.BudgetStatus(chatID)), nil
+		},
+	)
+}
+
+func formatBudgetStatus(status *llm.BudgetStatus) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Usage on %s:\n", status.Day)
+	fmt.Fprintf(&builder, "- This chat: $%.4f", status.ChatSpent)
+	if status.ChatDailyLimit > 0 {
+		fmt.Fprintf(&builder, " of $%.2f per chat", status.ChatDailyLimit)
+	}
+	fmt.Fprintf(&builder, "\n- All chats: $%.4f", status.Spent)
+	if status.DailyLimit > 0 {
+		fmt.Fprintf(&builder, " of $%.2f", status.DailyLimit)
+	}
+
+	switch {
+	case status.DailyLimit == 0 && status.ChatDailyLimit == 0:
+		builder.WriteString("\nNo daily budget is configured.")
+	case status.Exceeded && status.Action == llm.BudgetActionDowngrade:
+		builder.WriteString("\nThe daily budget is used up, so replies use a cheaper model until tomorrow.")
+	case status.Exceeded:
+		builder.WriteString("\nThe daily budget is used up, so requests are rejected until tomorrow.")
+	}
+
+	return builder.String()
+}
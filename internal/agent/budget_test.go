@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestBudgetExceededReply(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"hello"}}],"usage":{"prompt_tokens":900000,"completion_tokens":100000,"total_tokens":1000000}}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	registry := tools.NewToolRegistry()
+	agent, err := NewAgent(&Config{
+		LLMModels: []*llm.ModelConfig{
+			{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL, InputPrice: 1, OutputPrice: 2},
+		},
+		DefaultModel:   "main",
+		LLMBudget:      &llm.BudgetConfig{ChatDailyLimit: 1},
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   registry,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	send := func(id string) string {
+		if err := agent.HandleMessage(ctx, &bus.Message{ID: id, Channel: bus.ChannelTelegram, ChatID: "chat", Content: "hi"}); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+		return (<-messageBus.published).Content
+	}
+
+	if reply := send("1"); reply != "hello" {
+		t.Fatalf("Expected a normal reply within budget, got %s", reply)
+	}
+
+	reply := send("2")
+	if !strings.Contains(reply, "budget has been reached") || !strings.Contains(reply, "This chat: $1.1000 of $1.00") {
+		t.Errorf("Expected budget reply, got %s", reply)
+	}
+
+	status, ok := registry.Get("budget_status")
+	if !ok {
+		t.Fatal("Expected budget_status tool to be registered")
+	}
+	result, err := status.Execute(tools.WithChatID(ctx, "other"), nil)
+	if err != nil || !strings.Contains(result, "This chat: $0.0000") || !strings.Contains(result, "All chats: $1.1000") {
+		t.Errorf("Unexpected budget status %q (%v)", result, err)
+	}
+}
@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/scheduler"
+)
+
+// MemoryConsolidationTaskID is the scheduled task that consolidates memory.
+const MemoryConsolidationTaskID = "memory-consolidation"
+
+// consolidationLog keeps the diff of every consolidation, dry runs included.
+const consolidationLog = "memory/consolidation.log"
+
+const consolidationPrompt = `You maintain the long-term memory file of a personal assistant. Rewrite it so that:
+- duplicate entries are removed and related facts about the same subject are merged into one entry,
+- entries that are outdated or only mattered until a date that has passed are dropped%s,
+- the result is at most %d characters%s.
+Keep every fact that is still useful, keep the dates of the entries you keep, and keep the Markdown structure of the file.
+Today is %s. Respond with the new file content only, without code fences or comments.`
+
+// ConsolidationConfig controls the memory consolidation task.
+type ConsolidationConfig struct {
+	// MaxAge is how old an entry may get before it is dropped; zero keeps
+	// entries until they are outdated.
+	MaxAge time.Duration
+	// MaxSize is the size MEMORY.md is kept under, in characters; zero
+	// leaves the size to the model.
+	MaxSize int
+	// DryRun logs what would change without saving it.
+	DryRun bool
+}
+
+// ConsolidationResult describes what a consolidation changed.
+type ConsolidationResult struct {
+	Before  string
+	After   string
+	Diff    string
+	Applied bool
+}
+
+// ConsolidateMemory asks the LLM to tidy MEMORY.md and saves the result
+// unless the config asks for a dry run. The diff is appended to the
+// consolidation log either way.
+func (a *Agent) ConsolidateMemory(ctx context.Context) (*ConsolidationResult, error) {
+	if a.llmManager == nil {
+		return nil, fmt.Errorf("LLM is not configured")
+	}
+	if a.memoryStorage == nil {
+		return nil, fmt.Errorf("memory storage is not configured")
+	}
+	config := a.consolidation
+	if config == nil {
+		config = &ConsolidationConfig{}
+	}
+
+	before, err := a.memoryStorage.GetMemory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory: %w", err)
+	}
+	result := &ConsolidationResult{Before: before, After: before}
+	if strings.TrimSpace(before) == "" {
+		return result, nil
+	}
+
+	after, err := a.rewriteMemory(ctx, before, config)
+	if err != nil {
+		return nil, err
+	}
+	if config.MaxSize > 0 && len([]rune(after)) > config.MaxSize {
+		return nil, fmt.Errorf("consolidated memory is still %d characters, over the limit of %d", len([]rune(after)), config.MaxSize)
+	}
+
+	result.After = after
+	result.Diff = lineDiff(before, after)
+	if result.Diff == "" {
+		a.logger.Info("Memory consolidation changed nothing")
+		return result, nil
+	}
+
+	if !config.DryRun {
+		// Entries added while the model was working would be lost.
+		current, err := a.memoryStorage.GetMemory(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read memory: %w", err)
+		}
+		if current != before {
+			return nil, fmt.Errorf("memory changed during consolidation, leaving it for the next run")
+		}
+
+		if err := a.memoryStorage.SetMemory(ctx, after); err != nil {
+			return nil, fmt.Errorf("failed to save memory: %w", err)
+		}
+		result.Applied = true
+	}
+
+	a.logger.Info("Consolidated memory", "dry_run", config.DryRun, "before", len([]rune(before)), "after", len([]rune(after)))
+	a.logConsolidation(ctx, result)
+	return result, nil
+}
+
+func (a *Agent) rewriteMemory(ctx context.Context, memory string, config *ConsolidationConfig) (string, error) {
+	maxAge := ""
+	if config.MaxAge > 0 {
+		maxAge = fmt.Sprintf(", as are dated entries older than %d days", int(config.MaxAge.Hours()/24))
+	}
+	maxSize, sizeNote := config.MaxSize, ", dropping the least useful entries first if needed"
+	if maxSize <= 0 {
+		maxSize, sizeNote = len([]rune(memory)), ""
+	}
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: fmt.Sprintf(consolidationPrompt, maxAge, maxSize, sizeNote, time.Now().Format("2006-01-02"))},
+		{Role: llm.RoleUser, Content: memory},
+	}
+
+	// One more try if the model overshoots the size, which it often does
+	// with long files.
+	var rewritten string
+	for attempt := 0; attempt < 2; attempt++ {
+		response, err := a.llmManager.Complete(ctx, messages)
+		if err != nil {
+			return "", fmt.Errorf("failed to consolidate memory: %w", err)
+		}
+
+		rewritten = strings.TrimSpace(stripCodeFence(response.Content))
+		if rewritten == "" {
+			return "", fmt.Errorf("failed to consolidate memory: the model returned nothing")
+		}
+		if config.MaxSize <= 0 || len([]rune(rewritten)) <= config.MaxSize {
+			break
+		}
+
+		messages = append(messages,
+			llm.Message{Role: llm.RoleAssistant, Content: response.Content},
+			llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("That is %d characters. Shorten it to at most %d.", len([]rune(rewritten)), config.MaxSize)},
+		)
+	}
+
+	return rewritten + "\n", nil
+}
+
+func (a *Agent) logConsolidation(ctx context.Context, result *ConsolidationResult) {
+	if a.storage == nil {
+		return
+	}
+
+	status := "applied"
+	if !result.Applied {
+		status = "dry run"
+	}
+	entry := fmt.Sprintf("## %s (%s)\n\n%s\n", time.Now().Format("2006-01-02 15:04"), status, result.Diff)
+
+	var log []byte
+	if exists, err := a.storage.FileExists(ctx, consolidationLog); err == nil && exists {
+		if log, err = a.storage.ReadFile(ctx, consolidationLog); err != nil {
+			a.logger.Warn("Failed to read consolidation log", "error", err)
+			return
+		}
+		log = append(log, '\n')
+	}
+
+	if err := a.storage.WriteFile(ctx, consolidationLog, append(log, entry...)); err != nil {
+		a.logger.Warn("Failed to write consolidation log", "error", err)
+	}
+}
+
+func (a *Agent) newConsolidateMemoryTask(action *scheduler.Action) (scheduler.TaskFunc, error) {
+	return func(ctx context.Context) error {
+		result, err := a.ConsolidateMemory(ctx)
+		if err != nil {
+			return err
+		}
+
+		if result.Diff == "" {
+			scheduler.ReportOutput(ctx, "Memory is already consolidated.")
+		} else {
+			scheduler.ReportOutput(ctx, result.Diff)
+		}
+		return nil
+	}, nil
+}
+
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") {
+		return text
+	}
+	_, body, _ := strings.Cut(text, "\n")
+	return strings.TrimSuffix(body, "```")
+}
+
+// lineDiff lists the lines removed from before with "-" and the lines added
+// in after with "+", in the order they appear. Blank lines are left out.
+func lineDiff(before, after string) string {
+	a := strings.Split(strings.TrimRight(before, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(after, "\n"), "\n")
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			if strings.TrimSpace(a[i]) != "" {
+				fmt.Fprintf(&diff, "- %s\n", a[i])
+			}
+			i++
+		default:
+			if strings.TrimSpace(b[j]) != "" {
+				fmt.Fprintf(&diff, "+ %s\n", b[j])
+			}
+			j++
+		}
+	}
+	return strings.TrimRight(diff.String(), "\n")
+}
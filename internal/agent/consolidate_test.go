@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const unconsolidatedMemory = `# Memory
+
+- [2024-01-03] The user lives in Berlin
+- [2024-02-10] The user lives in Berlin, Germany
+- [2024-03-01] The user has a dentist appointment on 2024-03-05
+- [2024-05-20] The user prefers tea
+`
+
+func newConsolidationTestAgent(t *testing.T, config *ConsolidationConfig, replies ...string) (*Agent, storage.MemoryStorage, storage.Storage) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		reply := replies[0]
+		if len(replies) > 1 {
+			replies = replies[1:]
+		}
+		mu.Unlock()
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, reply)
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	memoryStorage := storage.NewFileSystemMemoryStorage(dir)
+	memoryStorage.SetMemory(ctx, unconsolidatedMemory)
+
+	agent, err := NewAgent(&Config{
+		LLMModels:           []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:        "main",
+		SessionStorage:      storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:       memoryStorage,
+		Storage:             fileStorage,
+		MemoryConsolidation: config,
+	}, &flakyBus{published: make(chan *bus.Message, 1)}, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	return agent, memoryStorage, fileStorage
+}
+
+const consolidatedMemory = "```markdown\n# Memory\n\n- [2024-02-10] The user lives in Berlin, Germany\n- [2024-05-20] The user prefers tea\n```"
+
+func TestConsolidateMemory(t *testing.T) {
+	ctx := context.Background()
+	agent, memoryStorage, fileStorage := newConsolidationTestAgent(t, &ConsolidationConfig{MaxSize: 200}, consolidatedMemory)
+
+	result, err := agent.ConsolidateMemory(ctx)
+	if err != nil {
+		t.Fatalf("Failed to consolidate memory: %v", err)
+	}
+	if !result.Applied {
+		t.Error("Expected the consolidation to be saved")
+	}
+
+	memory, _ := memoryStorage.GetMemory(ctx)
+	if memory != "# Memory\n\n- [2024-02-10] The user lives in Berlin, Germany\n- [2024-05-20] The user prefers tea\n" {
+		t.Errorf("Expected the consolidated memory without the code fence, got %q", memory)
+	}
+
+	want := "- - [2024-01-03] The user lives in Berlin\n- - [2024-03-01] The user has a dentist appointment on 2024-03-05"
+	if result.Diff != want {
+		t.Errorf("Expected diff\n%s\ngot\n%s", want, result.Diff)
+	}
+
+	log, err := fileStorage.ReadFile(ctx, consolidationLog)
+	if err != nil || !strings.Contains(string(log), "(applied)") || !strings.Contains(string(log), want) {
+		t.Errorf("Expected the diff in the consolidation log, got %q, %v", log, err)
+	}
+}
+
+func TestConsolidateMemoryDryRun(t *testing.T) {
+	ctx := context.Background()
+	agent, memoryStorage, fileStorage := newConsolidationTestAgent(t, &ConsolidationConfig{DryRun: true}, consolidatedMemory)
+
+	result, err := agent.ConsolidateMemory(ctx)
+	if err != nil {
+		t.Fatalf("Failed to consolidate memory: %v", err)
+	}
+	if result.Applied || result.Diff == "" {
+		t.Errorf("Expected a diff that was not applied, got %+v", result)
+	}
+	if memory, _ := memoryStorage.GetMemory(ctx); memory != unconsolidatedMemory {
+		t.Errorf("Expected memory to be left alone in a dry run, got %q", memory)
+	}
+	if log, _ := fileStorage.ReadFile(ctx, consolidationLog); !strings.Contains(string(log), "(dry run)") {
+		t.Errorf("Expected the dry run in the consolidation log, got %q", log)
+	}
+}
+
+func TestConsolidateMemoryOverMaxSize(t *testing.T) {
+	ctx := context.Background()
+	agent, memoryStorage, _ := newConsolidationTestAgent(t, &ConsolidationConfig{MaxSize: 20}, consolidatedMemory)
+
+	if _, err := agent.ConsolidateMemory(ctx); err == nil || !strings.Contains(err.Error(), "over the limit") {
+		t.Errorf("Expected a result over the size limit to be rejected, got %v", err)
+	}
+	if memory, _ := memoryStorage.GetMemory(ctx); memory != unconsolidatedMemory {
+		t.Errorf("Expected memory to be left alone, got %q", memory)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	diff := lineDiff("a\nb\nc\n", "a\nB\nc\nd\n")
+	if diff != "- b\n+ B\n+ d" {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+	if diff := lineDiff("a\n\nb", "a\nb\n"); diff != "" {
+		t.Errorf("Expected blank lines to be ignored, got %q", diff)
+	}
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const (
+	// abortGrace is how long requests cancelled at shutdown get to tell
+	// their chats before the bus closes.
+	abortGrace = 5 * time.Second
+
+	shuttingDownReply = "I'm restarting right now and can't start on this. Please send your message again in a minute."
+	interruptedReply  = "Sorry, I was restarting and couldn't finish answering. Please send your message again."
+)
+
+// requestTracker keeps track of the messages being handled, so Stop can let
+// them finish before the channels and the bus shut down.
+type requestTracker struct {
+	mu       sync.Mutex
+	nextID   int
+	active   map[int]context.CancelFunc
+	draining bool
+	aborted  bool
+	// idle is closed when the last active request finishes while draining.
+	idle chan struct{}
+}
+
+func newRequestTracker() *requestTracker {
+	return &requestTracker{active: make(map[int]context.CancelFunc)}
+}
+
+// begin registers a request and returns a context that is cancelled if it is
+// aborted at shutdown, and a function to call when it is done. It returns
+// false once draining has started.
+func (t *requestTracker) begin(ctx context.Context) (context.Context, func(), bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return ctx, func() {}, false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	id := t.nextID
+	t.nextID++
+	t.active[id] = cancel
+
+	return ctx, func() {
+		cancel()
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.active, id)
+		if len(t.active) == 0 && t.idle != nil {
+			close(t.idle)
+			t.idle = nil
+		}
+	}, true
+}
+
+func (t *requestTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+func (t *requestTracker) isDraining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
+
+func (t *requestTracker) wasAborted() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.aborted
+}
+
+// drain stops new requests from starting and waits for the active ones to
+// finish. Those still running when ctx is done are cancelled and given
+// abortGrace to wind down. It returns how many had to be cancelled.
+func (t *requestTracker) drain(ctx context.Context) int {
+	t.mu.Lock()
+	t.draining = true
+	if len(t.active) == 0 {
+		t.mu.Unlock()
+		return 0
+	}
+	idle := make(chan struct{})
+	t.idle = idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	t.aborted = true
+	interrupted := len(t.active)
+	for _, cancel := range t.active {
+		cancel()
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-time.After(abortGrace):
+	}
+	return interrupted
+}
+
+// startsConversation reports whether msg would start new work, as opposed to
+// answering a confirmation or paging through a reply already in progress.
+func (a *Agent) startsConversation(msg *bus.Message) bool {
+	if msg.IsControl() || msg.IsReply() {
+		return false
+	}
+
+	a.mu.RLock()
+	_, confirming := a.confirmations[msg.ChatID]
+	a.mu.RUnlock()
+	return !confirming && !isMoreCommand(msg.Content)
+}
@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func newDrainTestAgent(t *testing.T, handler http.HandlerFunc) (*Agent, *flakyBus) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels: []*llm.ModelConfig{
+			{Name: "default", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL},
+		},
+		DefaultModel:   "default",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+		RetryDelay:     time.Hour,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	return agent, messageBus
+}
+
+func waitInFlight(t *testing.T, agent *Agent, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for agent.requests.count() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d request(s) in flight, got %d", n, agent.requests.count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStopWaitsForConversations(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	agent, messageBus := newDrainTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "Finished answer"}}]}`)
+	})
+
+	go agent.handleWithRetry(ctx, &bus.Message{ID: "1", Channel: bus.ChannelTelegram, ChatID: "chat", Content: "hi"})
+	waitInFlight(t, agent, 1)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		stopped <- agent.Stop(stopCtx)
+	}()
+
+	for !agent.requests.isDraining() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	agent.handleWithRetry(ctx, &bus.Message{ID: "2", Channel: bus.ChannelTelegram, ChatID: "other", Content: "hello?"})
+	if reply := <-messageBus.published; reply.Content != shuttingDownReply || reply.ChatID != "other" {
+		t.Errorf("Expected new conversations to be turned away while draining, got %+v", reply)
+	}
+
+	close(release)
+	if reply := <-messageBus.published; reply.Content != "Finished answer" {
+		t.Errorf("Expected the conversation in progress to finish, got %q", reply.Content)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Expected a clean stop, got %v", err)
+	}
+}
+
+func TestStopAbortsConversationsAtDeadline(t *testing.T) {
+	ctx := context.Background()
+	hang := make(chan struct{})
+	agent, messageBus := newDrainTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-hang:
+		}
+	})
+	t.Cleanup(func() { close(hang) })
+
+	go agent.handleWithRetry(ctx, &bus.Message{ID: "1", Channel: bus.ChannelTelegram, ChatID: "chat", Content: "hi"})
+	waitInFlight(t, agent, 1)
+
+	stopCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := agent.Stop(stopCtx); err == nil {
+		t.Error("Expected Stop to report the interrupted conversation")
+	}
+
+	reply := <-messageBus.published
+	if reply.Content != interruptedReply || reply.ChatID != "chat" {
+		t.Errorf("Expected the chat to be told to resend, got %+v", reply)
+	}
+	if len(messageBus.published) != 0 {
+		t.Errorf("Expected no error reply or retry notice, got %+v", <-messageBus.published)
+	}
+}
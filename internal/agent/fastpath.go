@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+)
+
+func (a *Agent) answerFastPath(ctx context.Context, msg *bus.Message) (bool, error) {
+	if a.fastPath == nil || len(msg.Attachments()) > 0 {
+		return false, nil
+	}
+
+	answer, handler, ok := a.fastPath.Answer(ctx, msg.Content)
+	if !ok {
+		return false, nil
+	}
+
+	a.logger.Info("Answered message via fast path", "chat_id", msg.ChatID, "message_id", msg.ID, "handler", handler)
+
+	a.appendChatHistory(msg.ChatID, a.getChatHistory(msg.ChatID),
+		llm.Message{Role: llm.RoleUser, Content: msg.Content},
+		llm.Message{Role: llm.RoleAssistant, Content: answer},
+	)
+
+	return true, a.reply(ctx, msg, answer)
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/intent"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestFastPathSkipsLLM(t *testing.T) {
+	ctx := context.Background()
+
+	var llmCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		llmCalls.Add(1)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"from the model"}}]}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+		FastPath:       intent.NewRouter(intent.NewMathHandler(), intent.NewUnitHandler()),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	send := func(content string) string {
+		if err := agent.HandleMessage(ctx, &bus.Message{ID: "m", Channel: bus.ChannelTelegram, ChatID: "chat", Content: content}); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+		return (<-messageBus.published).Content
+	}
+
+	if reply := send("What is 5 miles in km?"); reply != "5 mi = 8.04672 km" {
+		t.Errorf("Unexpected fast-path reply %q", reply)
+	}
+	if llmCalls.Load() != 0 {
+		t.Errorf("Expected no LLM calls for a conversion, got %d", llmCalls.Load())
+	}
+
+	history := agent.GetChatHistory("chat")
+	if len(history) != 2 || history[1].Content != "5 mi = 8.04672 km" {
+		t.Errorf("Expected the fast-path exchange in the history, got %+v", history)
+	}
+
+	if reply := send("5 miles in parsecs"); reply != "from the model" {
+		t.Errorf("Expected unparsed conversion to reach the model, got %q", reply)
+	}
+	if llmCalls.Load() == 0 {
+		t.Error("Expected the model to be called when the fast path does not apply")
+	}
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	agentcontext "github.com/wjffsx/miniclaw_go/internal/context"
+)
+
+const lineageKeyPrefix = "session_lineage:"
+
+// ErrNothingToFork is returned when forking a session without history.
+var ErrNothingToFork = errors.New("session has no messages to fork")
+
+// SessionLineage records which session a forked session was copied from and
+// which sessions were forked from it.
+type SessionLineage struct {
+	Parent   string    `json:"parent,omitempty"`
+	ForkedAt time.Time `json:"forked_at,omitempty"`
+	// Messages is how many messages were copied from the parent.
+	Messages int      `json:"messages,omitempty"`
+	Children []string `json:"children,omitempty"`
+}
+
+func (a *Agent) GetSessionLineage(ctx context.Context, chatID string) (*SessionLineage, error) {
+	lineage := &SessionLineage{}
+	if a.memoryStorage == nil {
+		return lineage, nil
+	}
+
+	data, err := a.memoryStorage.GetConfig(ctx, lineageKeyPrefix+chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session lineage: %w", err)
+	}
+	if data == "" {
+		return lineage, nil
+	}
+	if err := json.Unmarshal([]byte(data), lineage); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session lineage: %w", err)
+	}
+	return lineage, nil
+}
+
+func (a *Agent) saveSessionLineage(ctx context.Context, chatID string, lineage *SessionLineage) error {
+	data, err := json.Marshal(lineage)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session lineage: %w", err)
+	}
+	if err := a.memoryStorage.SetConfig(ctx, lineageKeyPrefix+chatID, string(data)); err != nil {
+		return fmt.Errorf("failed to save session lineage: %w", err)
+	}
+	return nil
+}
+
+// ForkSession copies the current history of a chat, along with its template
+// and preferences, into a new session and returns the new session's ID. The
+// original session is left as it is.
+func (a *Agent) ForkSession(ctx context.Context, chatID string) (string, error) {
+	history := a.getChatHistory(chatID)
+	if len(history) == 0 {
+		return "", ErrNothingToFork
+	}
+
+	a.forkMu.Lock()
+	defer a.forkMu.Unlock()
+
+	parent, err := a.GetSessionLineage(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+
+	forkID, err := a.newForkID(ctx, chatID, len(parent.Children)+1)
+	if err != nil {
+		return "", err
+	}
+
+	a.appendChatHistory(forkID, nil, history...)
+	if template := a.GetChatTemplate(chatID); template != nil {
+		a.setChatTemplate(forkID, template)
+	}
+
+	if a.memoryStorage == nil {
+		return forkID, nil
+	}
+
+	prefs, err := agentcontext.LoadPreferences(ctx, a.memoryStorage, chatID)
+	if err != nil {
+		a.logger.Warn("Failed to copy preferences to forked session", "chat_id", chatID, "fork", forkID, "error", err)
+	} else if !prefs.Empty() {
+		if err := agentcontext.SavePreferences(ctx, a.memoryStorage, forkID, prefs); err != nil {
+			a.logger.Warn("Failed to copy preferences to forked session", "chat_id", chatID, "fork", forkID, "error", err)
+		}
+	}
+
+	child := &SessionLineage{Parent: chatID, ForkedAt: time.Now().UTC(), Messages: len(history)}
+	if err := a.saveSessionLineage(ctx, forkID, child); err != nil {
+		return "", err
+	}
+	parent.Children = append(parent.Children, forkID)
+	if err := a.saveSessionLineage(ctx, chatID, parent); err != nil {
+		return "", err
+	}
+
+	a.logger.Info("Forked session", "chat_id", chatID, "fork", forkID, "messages", len(history))
+	return forkID, nil
+}
+
+// newForkID returns the first unused ID of the form <chat>_fork<n>, starting
+// at n. Keeping the parent's ID as a prefix keeps forks in the same
+// namespace, e.g. of an authenticated WebSocket user.
+func (a *Agent) newForkID(ctx context.Context, chatID string, n int) (string, error) {
+	for ; ; n++ {
+		forkID := fmt.Sprintf("%s_fork%d", chatID, n)
+
+		a.mu.RLock()
+		_, resident := a.chatHistory[forkID]
+		a.mu.RUnlock()
+		if resident {
+			continue
+		}
+
+		messages, err := a.sessionStorage.GetMessages(ctx, forkID, 1)
+		if err != nil {
+			return "", fmt.Errorf("failed to check session %s: %w", forkID, err)
+		}
+		if len(messages) == 0 {
+			return forkID, nil
+		}
+	}
+}
+
+func isForkCommand(content string) bool {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return false
+	}
+
+	command, _, _ := strings.Cut(fields[0], "@")
+	return command == "/fork"
+}
+
+func (a *Agent) handleForkCommand(ctx context.Context, msg *bus.Message) error {
+	forkID, err := a.ForkSession(ctx, msg.ChatID)
+	if errors.Is(err, ErrNothingToFork) {
+		return a.reply(ctx, msg, "There is nothing to fork yet.")
+	}
+	if err != nil {
+		a.logger.Error("Failed to fork session", "chat_id", msg.ChatID, "error", err)
+		return a.reply(ctx, msg, "Sorry, I couldn't fork this conversation.")
+	}
+
+	return a.reply(ctx, msg, fmt.Sprintf("Forked this conversation into session %s. This conversation is unchanged; continue in %s to explore a different direction.", forkID, forkID))
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func TestForkCommand(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	sessionStorage := storage.NewFileSystemSessionStorage(dir)
+	sessionStorage.SaveMessage(ctx, "chat", "user", "Plan a trip to Rome")
+	sessionStorage.SaveMessage(ctx, "chat", "assistant", "Here is a plan")
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		SessionStorage: sessionStorage,
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	if err := agent.SetPreference(ctx, "chat", "language", "Italian"); err != nil {
+		t.Fatalf("Failed to set preference: %v", err)
+	}
+
+	if err := agent.HandleMessage(ctx, &bus.Message{ID: "1", Channel: bus.ChannelCLI, ChatID: "chat", Content: "/fork"}); err != nil {
+		t.Fatalf("Failed to handle /fork: %v", err)
+	}
+	if reply := <-messageBus.published; !strings.Contains(reply.Content, "session chat_fork1") {
+		t.Errorf("Expected the new session ID in the reply, got %q", reply.Content)
+	}
+
+	forked, _ := sessionStorage.GetMessages(ctx, "chat_fork1", 0)
+	if len(forked) != 2 || forked[0].Content != "Plan a trip to Rome" {
+		t.Errorf("Expected the history to be copied, got %+v", forked)
+	}
+	if prefs, _ := agent.GetPreferences(ctx, "chat_fork1"); prefs.Language != "Italian" {
+		t.Errorf("Expected preferences to be copied, got %+v", prefs)
+	}
+
+	// The fork diverges without touching the original.
+	sessionStorage.SaveMessage(ctx, "chat_fork1", "user", "Make it Paris instead")
+	if original, _ := sessionStorage.GetMessages(ctx, "chat", 0); len(original) != 2 {
+		t.Errorf("Expected the original session to be unchanged, got %+v", original)
+	}
+
+	second, err := agent.ForkSession(ctx, "chat")
+	if err != nil || second != "chat_fork2" {
+		t.Fatalf("Expected a second fork with the next ID, got %q (%v)", second, err)
+	}
+
+	lineage, err := agent.GetSessionLineage(ctx, "chat")
+	if err != nil || strings.Join(lineage.Children, ",") != "chat_fork1,chat_fork2" {
+		t.Errorf("Expected both forks as children, got %+v (%v)", lineage, err)
+	}
+	child, _ := agent.GetSessionLineage(ctx, "chat_fork2")
+	if child.Parent != "chat" || child.Messages != 2 || child.ForkedAt.IsZero() {
+		t.Errorf("Expected the fork to record its parent, got %+v", child)
+	}
+
+	if err := agent.HandleMessage(ctx, &bus.Message{ID: "2", Channel: bus.ChannelCLI, ChatID: "empty", Content: "/fork"}); err != nil {
+		t.Fatalf("Failed to handle /fork: %v", err)
+	}
+	if reply := <-messageBus.published; !strings.Contains(reply.Content, "nothing to fork") {
+		t.Errorf("Expected a reply for an empty conversation, got %q", reply.Content)
+	}
+}
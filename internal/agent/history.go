@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	agentcontext "github.com/wjffsx/miniclaw_go/internal/context"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+)
+
+const (
+	maxHistoryMessages   = 50
+	historySummaryPrefix = "[Conversation summary covering "
+	historySummaryHeader = historySummaryPrefix + "%d earlier messages]"
+)
+
+const historySummaryPrompt = `You condense the earlier part of a chat between a user and an assistant so the conversation can continue without it.
+Keep facts about the user, decisions, preferences, open tasks and anything the assistant promised to do.
+If a previous summary is given, merge it with the new messages. Respond with the summary only, in a few short paragraphs.`
+
+func isHistorySummary(msg llm.Message) bool {
+	return msg.Role == llm.RoleSystem && strings.HasPrefix(msg.Content, historySummaryPrefix)
+}
+
+func summaryCoverage(content string) int {
+	var covered int
+	if _, err := fmt.Sscanf(content, historySummaryHeader, &covered); err != nil {
+		return 0
+	}
+	return covered
+}
+
+func loadHistory(stored []llm.Message) []llm.Message {
+	var summary *llm.Message
+	covered := 0
+	messages := make([]llm.Message, 0, len(stored))
+
+	for i := range stored {
+		if isHistorySummary(stored[i]) {
+			summary = &stored[i]
+			covered = summaryCoverage(stored[i].Content)
+			continue
+		}
+		messages = append(messages, stored[i])
+	}
+
+	if covered > len(messages) {
+		covered = len(messages)
+	}
+	messages = messages[covered:]
+
+	if len(messages) > maxHistoryMessages {
+		messages = messages[len(messages)-maxHistoryMessages:]
+	}
+
+	if summary == nil {
+		return messages
+	}
+	return append([]llm.Message{*summary}, messages...)
+}
+
+func (a *Agent) historyBudget() int {
+	if a.historyTokens > 0 {
+		return a.historyTokens
+	}
+
+	if a.llmManager == nil {
+		return 0
+	}
+
+	window := a.llmManager.ContextWindow()
+	available := window - a.llmManager.MaxTokens()
+	if available <= 0 {
+		available = window
+	}
+
+	return available / 2
+}
+
+func (a *Agent) fitHistory(ctx context.Context, chatID string, messages []llm.Message, reserve int) []llm.Message {
+	budget := a.historyBudget() - reserve
+	if budget <= 0 || llm.EstimateMessagesTokens(messages) <= budget {
+		return messages
+	}
+
+	var summary []llm.Message
+	rest := messages
+	if len(rest) > 0 && isHistorySummary(rest[0]) {
+		summary = rest[:1]
+		rest = rest[1:]
+	}
+
+	used := llm.EstimateMessagesTokens(summary)
+	dropped := 0
+	for dropped < len(rest) && used+llm.EstimateMessagesTokens(rest[dropped:]) > budget {
+		dropped++
+	}
+	for dropped < len(rest) && rest[dropped].Role != llm.RoleUser {
+		dropped++
+	}
+
+	kept := rest[dropped:]
+	fitted := append(append([]llm.Message{}, summary...), kept...)
+
+	if a.summarizeHistory && a.llmManager != nil {
+		if summaryMessage, err := a.summarizeHistoryMessages(ctx, chatID, summary, rest[:dropped], len(kept)); err != nil {
+			a.logger.Warn("Failed to summarize history", "chat_id", chatID, "error", err)
+		} else {
+			fitted = append([]llm.Message{summaryMessage}, kept...)
+		}
+	}
+
+	a.logger.Info("Trimmed history to fit token budget", "chat_id", chatID, "dropped", dropped, "budget", budget)
+
+	a.mu.Lock()
+	a.storeHistory(chatID, fitted)
+	a.mu.Unlock()
+
+	return fitted
+}
+
+func (a *Agent) summarizeHistoryMessages(ctx context.Context, chatID string, previous, dropped []llm.Message, kept int) (llm.Message, error) {
+	var transcript strings.Builder
+	if len(previous) > 0 {
+		_, text, _ := strings.Cut(previous[0].Content, "\n")
+		fmt.Fprintf(&transcript, "Previous summary:\n%s\n\n", strings.TrimSpace(text))
+	}
+
+	transcript.WriteString("Messages:\n\n")
+	for _, msg := range dropped {
+		fmt.Fprintf(&transcript, "%s: %s\n\n", msg.Role, msg.Content)
+	}
+
+	response, err := a.llmManager.Complete(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: historySummaryPrompt},
+		{Role: llm.RoleUser, Content: transcript.String()},
+	})
+	if err != nil {
+		return llm.Message{}, fmt.Errorf("failed to summarize history: %w", err)
+	}
+
+	stored, err := a.sessionStorage.GetMessages(ctx, chatID, 0)
+	if err != nil {
+		return llm.Message{}, fmt.Errorf("failed to load messages: %w", err)
+	}
+
+	total := 0
+	for _, msg := range stored {
+		if !isHistorySummary(llm.Message{Role: llm.MessageRole(msg.Role), Content: msg.Content}) {
+			total++
+		}
+	}
+
+	covered := total - kept
+	if covered < 0 {
+		covered = 0
+	}
+
+	summary := llm.Message{
+		Role:    llm.RoleSystem,
+		Content: fmt.Sprintf(historySummaryHeader, covered) + "\n" + strings.TrimSpace(response.Content),
+	}
+
+	if err := a.sessionStorage.SaveMessage(ctx, chatID, string(summary.Role), summary.Content); err != nil {
+		return llm.Message{}, fmt.Errorf("failed to save history summary: %w", err)
+	}
+
+	return summary, nil
+}
+
+const memorySummaryPrompt = `You condense an assistant's long-term memory about its user so it fits in a smaller space.
+Keep facts about the user, their preferences, people, projects and standing instructions; drop repetition and details that are unlikely to matter again.
+Respond with the condensed memory only, as short Markdown bullet points, in at most %d words.`
+
+// newMemorySummarizer condenses memory that is over its token budget in the
+// system prompt.
+func newMemorySummarizer(llmManager *llm.MultiModelManager) agentcontext.Summarizer {
+	return func(ctx context.Context, text string, maxTokens int) (string, error) {
+		// Words are about three quarters of a token.
+		response, err := llmManager.Complete(ctx, []llm.Message{
+			{Role: llm.RoleSystem, Content: fmt.Sprintf(memorySummaryPrompt, maxTokens*3/4)},
+			{Role: llm.RoleUser, Content: text},
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize memory: %w", err)
+		}
+		return strings.TrimSpace(response.Content), nil
+	}
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func TestLoadHistoryWithSummary(t *testing.T) {
+	stored := []llm.Message{
+		{Role: llm.RoleUser, Content: "one"},
+		{Role: llm.RoleAssistant, Content: "two"},
+		{Role: llm.RoleSystem, Content: fmt.Sprintf(historySummaryHeader, 2) + "\nEarlier talk"},
+		{Role: llm.RoleUser, Content: "three"},
+		{Role: llm.RoleAssistant, Content: "four"},
+	}
+
+	history := loadHistory(stored)
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(history))
+	}
+
+	if !isHistorySummary(history[0]) || history[1].Content != "three" {
+		t.Errorf("Expected summary followed by uncovered messages, got %+v", history)
+	}
+}
+
+func TestFitHistoryDropsOldestMessages(t *testing.T) {
+	a := &Agent{
+		chatHistory:   make(map[string][]llm.Message),
+		sessions:      newSessionCache(0, 0),
+		historyTokens: 30,
+		logger:        logging.For("agent"),
+	}
+
+	messages := make([]llm.Message, 0)
+	for i := 0; i < 10; i++ {
+		messages = append(messages,
+			llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("question %d", i)},
+			llm.Message{Role: llm.RoleAssistant, Content: fmt.Sprintf("answer %d", i)},
+		)
+	}
+
+	fitted := a.fitHistory(context.Background(), "chat", messages, 0)
+	if llm.EstimateMessagesTokens(fitted) > 30 {
+		t.Errorf("Expected history to fit budget, got %d tokens", llm.EstimateMessagesTokens(fitted))
+	}
+
+	if len(fitted) == 0 || fitted[0].Role != llm.RoleUser {
+		t.Fatalf("Expected history to start with a user message, got %+v", fitted)
+	}
+
+	if fitted[len(fitted)-1].Content != "answer 9" {
+		t.Errorf("Expected most recent message to be kept, got %s", fitted[len(fitted)-1].Content)
+	}
+}
+
+func TestFitHistorySummarizesDroppedMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"User likes tea"}}]}`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	sessionStorage := storage.NewFileSystemSessionStorage(t.TempDir())
+
+	a, err := NewAgent(&Config{
+		LLMModels: []*llm.ModelConfig{
+			{Name: "default", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL},
+		},
+		DefaultModel:     "default",
+		SessionStorage:   sessionStorage,
+		HistoryTokens:    40,
+		SummarizeHistory: true,
+	}, bus.NewInMemoryMessageBus(ctx, nil), ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	messages := make([]llm.Message, 0)
+	for i := 0; i < 6; i++ {
+		messages = append(messages,
+			llm.Message{Role: llm.RoleUser, Content: strings.Repeat("tea ", 10)},
+			llm.Message{Role: llm.RoleAssistant, Content: fmt.Sprintf("noted %d", i)},
+		)
+	}
+	a.appendChatHistory("chat", nil, messages...)
+
+	fitted := a.fitHistory(ctx, "chat", a.getChatHistory("chat"), 0)
+	if !isHistorySummary(fitted[0]) || !strings.Contains(fitted[0].Content, "User likes tea") {
+		t.Fatalf("Expected summary as first message, got %+v", fitted[0])
+	}
+
+	covered := summaryCoverage(fitted[0].Content)
+	if covered+len(fitted)-1 != len(messages) {
+		t.Errorf("Expected summary to cover dropped messages, covered %d with %d kept", covered, len(fitted)-1)
+	}
+
+	a.mu.Lock()
+	delete(a.chatHistory, "chat")
+	a.mu.Unlock()
+
+	reloaded := a.getChatHistory("chat")
+	if len(reloaded) != len(fitted) || !isHistorySummary(reloaded[0]) {
+		t.Errorf("Expected reloaded history to match fitted history, got %d messages", len(reloaded))
+	}
+}
+
+func TestAppendChatHistorySavesOnlyNewMessages(t *testing.T) {
+	ctx := context.Background()
+	sessionStorage := storage.NewCachedSessionStorage(storage.NewFileSystemSessionStorage(t.TempDir()), nil)
+	a := &Agent{
+		chatHistory:    make(map[string][]llm.Message),
+		sessions:       newSessionCache(0, 0),
+		sessionStorage: sessionStorage,
+		historyTokens:  30,
+		logger:         logging.For("agent"),
+	}
+
+	// The history is trimmed to the token budget on most turns, which must
+	// not cause earlier messages to be saved again.
+	const turns = 12
+	for i := 0; i < turns; i++ {
+		history := a.fitHistory(ctx, "chat", a.getChatHistory("chat"), 0)
+		a.appendChatHistory("chat", history,
+			llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("question %d", i)},
+			llm.Message{Role: llm.RoleAssistant, Content: fmt.Sprintf("answer %d", i)},
+		)
+	}
+
+	if err := sessionStorage.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	stored, err := sessionStorage.GetMessages(ctx, "chat", 0)
+	if err != nil {
+		t.Fatalf("Failed to load messages: %v", err)
+	}
+	if len(stored) != 2*turns {
+		t.Fatalf("Expected %d stored messages, got %d", 2*turns, len(stored))
+	}
+	for i := 0; i < turns; i++ {
+		if stored[2*i].Content != fmt.Sprintf("question %d", i) || stored[2*i+1].Content != fmt.Sprintf("answer %d", i) {
+			t.Errorf("Expected turn %d to be stored once and in order, got %+v", i, stored[2*i:2*i+2])
+		}
+	}
+}
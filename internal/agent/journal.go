@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+// maxJournalInput caps how much of the request and response is sent to the
+// LLM for a journal entry.
+const maxJournalInput = 2000
+
+const journalPrompt = `You keep an activity log of a user's conversations with an assistant.
+Summarize the exchange in one short sentence that says what was asked and what was done or answered, so it can be found again days later.
+Respond with the sentence only.`
+
+// JournalConfig appends a line about every answered message to the day's
+// daily note, so the notes double as an activity log.
+type JournalConfig struct {
+	// Summarize asks the LLM for a one-sentence entry. Without it the entry
+	// quotes the start of the request and the response.
+	Summarize bool
+}
+
+func (a *Agent) journalTurn(ctx context.Context, msg *bus.Message, request, response string, toolCalls []tools.ToolCall) {
+	if a.memoryStorage == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	response = finalAnswer(response)
+
+	entry := ""
+	if a.journal.Summarize && a.llmManager != nil {
+		summary, err := a.summarizeTurn(ctx, request, response)
+		if err != nil {
+			a.logger.Warn("Failed to summarize turn for the journal", "chat_id", msg.ChatID, "error", err)
+		}
+		entry = summary
+	}
+	if entry == "" {
+		entry = fmt.Sprintf("%q → %q", logging.Preview(request, 80), logging.Preview(response, 120))
+	}
+
+	if names := toolNames(toolCalls); len(names) > 0 {
+		entry += " (tools: " + strings.Join(names, ", ") + ")"
+	}
+
+	now := time.Now()
+	line := fmt.Sprintf("- %s [%s %s] %s", now.Format("15:04"), msg.Channel, msg.ChatID, entry)
+
+	err := a.updateDailyNote(ctx, now, func(note string) string {
+		switch {
+		case note == "":
+		case isJournalLine(lastLine(note)):
+			note = strings.TrimRight(note, "\n") + "\n"
+		default:
+			note = strings.TrimRight(note, "\n") + "\n\n"
+		}
+		return note + line + "\n"
+	})
+	if err != nil {
+		a.logger.Warn("Failed to journal turn", "chat_id", msg.ChatID, "error", err)
+	}
+}
+
+func (a *Agent) summarizeTurn(ctx context.Context, request, response string) (string, error) {
+	resp, err := a.llmManager.Complete(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: journalPrompt},
+		{Role: llm.RoleUser, Content: fmt.Sprintf("User: %s\n\nAssistant: %s",
+			logging.Preview(request, maxJournalInput), logging.Preview(response, maxJournalInput))},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to complete LLM request: %w", err)
+	}
+
+	summary, _, _ := strings.Cut(strings.TrimSpace(resp.Content), "\n")
+	return strings.TrimSpace(summary), nil
+}
+
+func toolNames(toolCalls []tools.ToolCall) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, call := range toolCalls {
+		if !seen[call.Name] {
+			seen[call.Name] = true
+			names = append(names, call.Name)
+		}
+	}
+	return names
+}
+
+func lastLine(text string) string {
+	text = strings.TrimRight(text, "\n")
+	return text[strings.LastIndex(text, "\n")+1:]
+}
+
+func isJournalLine(line string) bool {
+	return strings.HasPrefix(line, "- ") && len(line) > 8 && line[4] == ':' && line[7] == ' ' && line[8] == '['
+}
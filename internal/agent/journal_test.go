@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func newJournalTestAgent(t *testing.T, journal *JournalConfig) (*Agent, *flakyBus, storage.MemoryStorage) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reply := `{"thought": "easy", "final_answer": "Paris is the capital of France."}`
+		if strings.Contains(string(body), "activity log") {
+			reply = "Asked for the capital of France, answered Paris."
+		}
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, reply)
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+	memoryStorage := storage.NewFileSystemMemoryStorage(dir)
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  memoryStorage,
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+		Journal:        journal,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	return agent, messageBus, memoryStorage
+}
+
+func TestJournalSummarizesTurns(t *testing.T) {
+	ctx := context.Background()
+	agent, messageBus, memoryStorage := newJournalTestAgent(t, &JournalConfig{Summarize: true})
+
+	today := time.Now().Format("2006-01-02")
+	memoryStorage.SetDailyNote(ctx, today, "# Plans\n\nWater the plants.")
+
+	for i := 1; i <= 2; i++ {
+		msg := &bus.Message{ID: fmt.Sprintf("m%d", i), Channel: bus.ChannelTelegram, ChatID: "42", Content: "What is the capital of France?"}
+		if err := agent.HandleMessage(ctx, msg); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+		<-messageBus.published
+	}
+
+	note, err := memoryStorage.GetDailyNote(ctx, today)
+	if err != nil {
+		t.Fatalf("Failed to read daily note: %v", err)
+	}
+	if !strings.HasPrefix(note, "# Plans\n\nWater the plants.\n\n- ") {
+		t.Errorf("Expected the journal to start after the existing note, got %q", note)
+	}
+	if count := strings.Count(note, "[telegram 42] Asked for the capital of France, answered Paris.\n"); count != 2 {
+		t.Errorf("Expected two consecutive journal lines, got %q", note)
+	}
+	if strings.Contains(note, "answered Paris.\n\n-") {
+		t.Errorf("Expected no blank line between journal lines, got %q", note)
+	}
+}
+
+func TestJournalWithoutSummary(t *testing.T) {
+	ctx := context.Background()
+	agent, messageBus, memoryStorage := newJournalTestAgent(t, &JournalConfig{})
+
+	if err := agent.HandleMessage(ctx, &bus.Message{ID: "m1", Channel: bus.ChannelCLI, ChatID: "cli", Content: "Capital of France?"}); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+	<-messageBus.published
+
+	note, _ := memoryStorage.GetDailyNote(ctx, time.Now().Format("2006-01-02"))
+	if !strings.Contains(note, `[cli cli] "Capital of France?" → "Paris is the capital of France."`) {
+		t.Errorf("Expected the request and response to be quoted, got %q", note)
+	}
+}
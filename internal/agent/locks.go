@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+type chatLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+type chatLocker struct {
+	mu    sync.Mutex
+	locks map[string]*chatLock
+}
+
+func newChatLocker() *chatLocker {
+	return &chatLocker{
+		locks: make(map[string]*chatLock),
+	}
+}
+
+func (l *chatLocker) Lock(chatID string) func() {
+	l.mu.Lock()
+	lock, ok := l.locks[chatID]
+	if !ok {
+		lock = &chatLock{}
+		l.locks[chatID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, chatID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Busy reports whether a message for the chat is being handled or waiting.
+func (l *chatLocker) Busy(chatID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.locks[chatID]
+	return ok
+}
+
+func (l *chatLocker) Active() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
+
+func (a *Agent) acquireChat(ctx context.Context, chatID string) (func(), error) {
+	unlockChat := a.chatLocks.Lock(chatID)
+
+	if a.conversations == nil {
+		return unlockChat, nil
+	}
+
+	select {
+	case a.conversations <- struct{}{}:
+	case <-ctx.Done():
+		unlockChat()
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-a.conversations
+		unlockChat()
+	}, nil
+}
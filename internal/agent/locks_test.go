@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+type slowBus struct {
+	mu        sync.Mutex
+	active    map[string]int
+	total     int
+	maxChat   int
+	maxActive int
+}
+
+func (b *slowBus) Publish(ctx context.Context, channel string, msg *bus.Message) error {
+	b.mu.Lock()
+	b.active[msg.ChatID]++
+	b.total++
+	if b.active[msg.ChatID] > b.maxChat {
+		b.maxChat = b.active[msg.ChatID]
+	}
+	if b.total > b.maxActive {
+		b.maxActive = b.total
+	}
+	b.mu.Unlock()
+
+	time.Sleep(30 * time.Millisecond)
+
+	b.mu.Lock()
+	b.active[msg.ChatID]--
+	b.total--
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *slowBus) Subscribe(channel string, handler bus.MessageHandler) (string, error) {
+	return "", nil
+}
+
+func (b *slowBus) Unsubscribe(channel string, handlerID string) error {
+	return nil
+}
+
+func (b *slowBus) Close() error {
+	return nil
+}
+
+func runConcurrentMessages(t *testing.T, maxConcurrentChats int, chatIDs []string) *slowBus {
+	ctx := context.Background()
+	messageBus := &slowBus{active: make(map[string]int)}
+
+	agent, err := NewAgent(&Config{
+		LLMModels:          []*llm.ModelConfig{},
+		SessionStorage:     storage.NewFileSystemSessionStorage(t.TempDir()),
+		MemoryStorage:      storage.NewFileSystemMemoryStorage(t.TempDir()),
+		ToolRegistry:       tools.NewToolRegistry(),
+		MaxConcurrentChats: maxConcurrentChats,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i, chatID := range chatIDs {
+		wg.Add(1)
+		go func(i int, chatID string) {
+			defer wg.Done()
+			agent.HandleMessage(ctx, &bus.Message{
+				ID:      fmt.Sprintf("msg-%d", i),
+				Channel: bus.ChannelCLI,
+				ChatID:  chatID,
+				Content: "hello",
+			})
+		}(i, chatID)
+	}
+	wg.Wait()
+
+	if agent.chatLocks.Active() != 0 {
+		t.Errorf("Expected chat locks to be released, got %d", agent.chatLocks.Active())
+	}
+
+	return messageBus
+}
+
+func TestAgentSerializesMessagesPerChat(t *testing.T) {
+	messageBus := runConcurrentMessages(t, 0, []string{"a", "a", "a", "b", "b", "b"})
+
+	if messageBus.maxChat != 1 {
+		t.Errorf("Expected messages for a chat to be handled serially, got %d concurrent", messageBus.maxChat)
+	}
+
+	if messageBus.maxActive < 2 {
+		t.Errorf("Expected different chats to run in parallel, got %d concurrent", messageBus.maxActive)
+	}
+}
+
+func TestAgentMaxConcurrentChats(t *testing.T) {
+	messageBus := runConcurrentMessages(t, 1, []string{"a", "b", "c", "d"})
+
+	if messageBus.maxActive != 1 {
+		t.Errorf("Expected at most 1 concurrent conversation, got %d", messageBus.maxActive)
+	}
+}
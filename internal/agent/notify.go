@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const sendMessageTool = "send_message"
+
+type sendMessage struct {
+	*tools.BaseTool
+}
+
+// Policy makes the tool admin-only, since it can write to any chat, not just
+// the one the request came from.
+func (t *sendMessage) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{AdminOnly: true}
+}
+
+func NewSendMessageTool(a *Agent) tools.Tool {
+	channels, _ := json.Marshal(a.channels)
+	params := json.RawMessage(fmt.Sprintf(`{
+		"type": "object",
+		"properties": {
+			"channel": {
+				"type": "string",
+				"enum": %s,
+				"description": "Channel to send on. Defaults to the channel of the current conversation"
+			},
+			"chat_id": {
+				"type": "string",
+				"description": "Chat to send to: a Telegram chat ID, a Discord chat ID, a WebSocket chat ID, or an email chat ID or address"
+			},
+			"text": {
+				"type": "string",
+				"description": "Message to send"
+			}
+		},
+		"required": ["chat_id", "text"],
+		"additionalProperties": false
+	}`, channels))
+
+	return &sendMessage{tools.NewBaseTool(
+		sendMessageTool,
+		"Send a message to a chat outside the current conversation, for example to notify the user from a scheduled task. An email address as chat_id on the email channel starts a new email thread",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			channel, _ := params["channel"].(string)
+			chatID, _ := params["chat_id"].(string)
+			text, _ := params["text"].(string)
+
+			if channel == "" {
+				channel, _ = tools.ChannelFromContext(ctx)
+			}
+			if !slices.Contains(a.channels, channel) {
+				return "", &tools.ToolError{
+					Code:    "UNKNOWN_CHANNEL",
+					Message: fmt.Sprintf("channel %q is not available, use one of %v", channel, a.channels),
+				}
+			}
+			if chatID == "" || text == "" {
+				return "", &tools.ToolError{Code: "INVALID_PARAMS", Message: "chat_id and text are required"}
+			}
+
+			if err := a.sendMessage(ctx, channel, chatID, text); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Sent the message to %s chat %s.", channel, chatID), nil
+		},
+	)}
+}
+
+// sendMessage delivers text to a chat the agent was not asked from. It is
+// marked as a reply to itself, since channels only send out replies, and
+// carries no connection so the WebSocket server delivers it to every client
+// in the chat.
+func (a *Agent) sendMessage(ctx context.Context, channel, chatID, text string) error {
+	msg := &bus.Message{
+		ID:        fmt.Sprintf("notify-%d", time.Now().UnixNano()),
+		Channel:   channel,
+		ChatID:    chatID,
+		Content:   text,
+		Timestamp: time.Now(),
+	}
+	msg.Metadata = map[string]interface{}{bus.MetadataReplyTo: msg.ID}
+
+	if err := a.messageBus.Publish(ctx, channel, msg); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	a.logger.Info("Sent message", "channel", channel, "chat_id", chatID, "chars", len(text))
+	return nil
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestSendMessageTool(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		Storage:        storage.NewFileStorage(dir),
+		ToolRegistry:   tools.NewToolRegistry(),
+		Channels:       []string{bus.ChannelTelegram, bus.ChannelEmail},
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	executor := agent.GetToolExecutor()
+
+	// Scheduled tasks run without a user and default to their own channel.
+	taskCtx := tools.WithChannel(ctx, bus.ChannelTelegram)
+	call, err := executor.Execute(taskCtx, sendMessageTool, map[string]interface{}{"chat_id": "42", "text": "Backup finished"})
+	if err != nil || call.Error != "" {
+		t.Fatalf("Failed to send message: %v %s", err, call.Error)
+	}
+
+	msg := <-messageBus.published
+	if msg.Channel != bus.ChannelTelegram || msg.ChatID != "42" || msg.Content != "Backup finished" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	if !msg.IsReply() || msg.Connection() != "" {
+		t.Errorf("Expected an outgoing message without a connection, got %v", msg.Metadata)
+	}
+
+	call, _ = executor.Execute(ctx, sendMessageTool, map[string]interface{}{"channel": bus.ChannelDiscord, "chat_id": "1", "text": "hi"})
+	if !strings.Contains(call.Error, "not available") {
+		t.Errorf("Expected a disabled channel to be refused, got %q", call.Error)
+	}
+
+	userCtx := withRequestMessage(ctx, &bus.Message{
+		ID:       "1",
+		Channel:  bus.ChannelTelegram,
+		ChatID:   "7",
+		Metadata: map[string]interface{}{bus.MetadataUser: bus.User{ID: "telegram:7"}},
+	})
+	call, _ = executor.Execute(userCtx, sendMessageTool, map[string]interface{}{"channel": bus.ChannelEmail, "chat_id": "boss@example.com", "text": "hi"})
+	if call.Error == "" {
+		t.Error("Expected non-admin users to be refused")
+	}
+	select {
+	case msg := <-messageBus.published:
+		t.Errorf("Expected nothing to be sent, got %+v", msg)
+	default:
+	}
+}
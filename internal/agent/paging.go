@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const (
+	pageMore = "page:more"
+	// pageFooterReserve leaves room for the "(1/3) ..." footer.
+	pageFooterReserve = 64
+)
+
+type pagedResponse struct {
+	pages      []string
+	next       int
+	responseID string
+	metadata   map[string]interface{}
+	buttons    bool
+}
+
+func isMoreCommand(content string) bool {
+	switch strings.ToLower(strings.TrimSpace(content)) {
+	case "more", "/more":
+		return true
+	}
+	return false
+}
+
+// channelGuidance tells the model about the limits of the channel the reply
+// will be shown on.
+func channelGuidance(caps bus.Capabilities) string {
+	var lines []string
+	if caps.MaxMessageLength > 0 {
+		lines = append(lines, fmt.Sprintf("Messages on this channel hold at most %d characters. Prefer answers that fit in one message; "+
+			"longer answers are split into pages the user has to ask for.", caps.MaxMessageLength))
+	}
+	if caps.LineWidth > 0 {
+		lines = append(lines, fmt.Sprintf("The display is %d columns wide, so keep code lines and tables narrower than that.", caps.LineWidth))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "## Channel\n\n" + strings.Join(lines, "\n")
+}
+
+// publishResponse sends the response, or its first page when it is too long
+// for the channel. The rest is sent when the user asks for more.
+func (a *Agent) publishResponse(ctx context.Context, msg *bus.Message, response *bus.Message) error {
+	caps := msg.Capabilities()
+	if caps.MaxMessageLength <= pageFooterReserve {
+		return a.publishReply(ctx, msg, response)
+	}
+
+	pages := bus.SplitText(response.Content, caps.MaxMessageLength-pageFooterReserve)
+	if len(pages) == 1 {
+		return a.publishReply(ctx, msg, response)
+	}
+
+	a.logger.Info("Paging long response", "chat_id", msg.ChatID, "pages", len(pages))
+
+	a.mu.Lock()
+	a.pages[msg.ChatID] = &pagedResponse{
+		pages:      pages,
+		responseID: response.ID,
+		metadata:   response.Metadata,
+		buttons:    caps.Buttons,
+	}
+	a.mu.Unlock()
+
+	return a.sendNextPage(ctx, msg)
+}
+
+func (a *Agent) hasMorePages(chatID string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	_, ok := a.pages[chatID]
+	return ok
+}
+
+func (a *Agent) clearPages(chatID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.pages, chatID)
+}
+
+func (a *Agent) sendNextPage(ctx context.Context, msg *bus.Message) error {
+	a.mu.Lock()
+	paged, ok := a.pages[msg.ChatID]
+	if !ok {
+		a.mu.Unlock()
+		return a.reply(ctx, msg, "There is nothing more to show.")
+	}
+
+	index := paged.next
+	paged.next++
+	last := paged.next == len(paged.pages)
+	if last {
+		delete(a.pages, msg.ChatID)
+	}
+	a.mu.Unlock()
+
+	page := &bus.Message{
+		ID:      paged.responseID,
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: paged.pages[index],
+	}
+	if index > 0 {
+		page.ID = fmt.Sprintf("%s-page-%d", paged.responseID, index+1)
+	}
+
+	if last {
+		page.Metadata = paged.metadata
+	} else {
+		page.Content += fmt.Sprintf("\n\n(%d/%d) Reply \"more\" for the rest.", index+1, len(paged.pages))
+		if paged.buttons {
+			page.Metadata = map[string]interface{}{
+				bus.MetadataButtons: [][]bus.Button{{{Text: "Send more", Data: pageMore}}},
+			}
+		}
+	}
+
+	return a.publishReply(ctx, msg, page)
+}
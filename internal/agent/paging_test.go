@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestLongResponseIsPaged(t *testing.T) {
+	ctx := context.Background()
+	answer := strings.Repeat("This sentence is part of a long answer. ", 250)
+	server := newModelServer(answer)
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	send := func(msg *bus.Message) *bus.Message {
+		if err := agent.HandleMessage(ctx, msg); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+		return <-messageBus.published
+	}
+
+	first := send(&bus.Message{ID: "m1", Channel: bus.ChannelTelegram, ChatID: "chat", Content: "explain"})
+	if utf8.RuneCountInString(first.Content) > 4096 || !strings.Contains(first.Content, "(1/3)") {
+		t.Fatalf("Expected first of three pages within the Telegram limit, got %d characters", utf8.RuneCountInString(first.Content))
+	}
+	if buttons := first.Buttons(); len(buttons) != 1 || buttons[0][0].Data != pageMore {
+		t.Errorf("Expected a send more button, got %+v", buttons)
+	}
+	if first.ID != "agent-m1" {
+		t.Errorf("Expected first page to use the response ID, got %s", first.ID)
+	}
+
+	second := send(&bus.Message{ID: "m2", Channel: bus.ChannelTelegram, ChatID: "chat", Content: "more"})
+	if !strings.Contains(second.Content, "(2/3)") {
+		t.Errorf("Expected second page, got %q", second.Content[len(second.Content)-40:])
+	}
+
+	third := send(&bus.Message{
+		ID:       "cb",
+		Channel:  bus.ChannelTelegram,
+		ChatID:   "chat",
+		Metadata: map[string]interface{}{bus.MetadataCallback: &bus.Callback{Data: pageMore}},
+	})
+	if strings.Contains(third.Content, "more\" for the rest") || len(third.Buttons()) != 0 {
+		t.Errorf("Expected last page without a footer, got %q", third.Content)
+	}
+	if agent.hasMorePages("chat") {
+		t.Error("Expected pages to be cleared after the last one")
+	}
+
+	short := send(&bus.Message{ID: "m3", Channel: bus.ChannelWebSocket, ChatID: "ws", Content: "explain"})
+	if short.Content != answer {
+		t.Error("Expected unlimited channel to receive the whole answer")
+	}
+}
+
+func TestChannelGuidance(t *testing.T) {
+	if guidance := channelGuidance(bus.ChannelCapabilities(bus.ChannelWebSocket)); guidance != "" {
+		t.Errorf("Expected no guidance for unlimited channel, got %q", guidance)
+	}
+
+	guidance := channelGuidance(bus.Capabilities{MaxMessageLength: 4096, LineWidth: 60})
+	if !strings.Contains(guidance, "4096 characters") || !strings.Contains(guidance, "60 columns") {
+		t.Errorf("Unexpected guidance %q", guidance)
+	}
+}
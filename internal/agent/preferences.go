@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	agentcontext "github.com/wjffsx/miniclaw_go/internal/context"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const prefsCommandUsage = "Usage: /prefs | /prefs set <language|verbosity|units|code_style> <value> | /prefs unset <name> | /prefs clear"
+
+func (a *Agent) GetPreferences(ctx context.Context, chatID string) (*agentcontext.Preferences, error) {
+	if a.memoryStorage == nil {
+		return nil, fmt.Errorf("memory storage is not configured")
+	}
+	return agentcontext.LoadPreferences(ctx, a.memoryStorage, chatID)
+}
+
+func (a *Agent) SetPreference(ctx context.Context, chatID, name, value string) error {
+	prefs, err := a.GetPreferences(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	if err := prefs.Set(name, value); err != nil {
+		return err
+	}
+
+	return agentcontext.SavePreferences(ctx, a.memoryStorage, chatID, prefs)
+}
+
+func NewSetPreferenceTool(a *Agent) tools.Tool {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {
+				"type": "string",
+				"enum": ["language", "verbosity", "units", "code_style"],
+				"description": "Preference to set"
+			},
+			"value": {
+				"type": "string",
+				"description": "New value. verbosity: concise, normal or detailed. units: metric or imperial"
+			},
+			"reason": {
+				"type": "string",
+				"description": "Why you think the user wants this, shown when asking them to confirm"
+			}
+		},
+		"required": ["name", "value"],
+		"additionalProperties": false
+	}`)
+
+	return tools.NewBaseTool(
+		"set_preference",
+		"Remember a language or formatting preference for this conversation after the user has shown it. The user is asked to confirm before it is saved",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			chatID, ok := tools.ChatIDFromContext(ctx)
+			msg := requestMessageFromContext(ctx)
+			if !ok || msg == nil {
+				return "", &tools.ToolError{
+					Code:    "NO_CONVERSATION",
+					Message: "no active conversation to set preferences for",
+				}
+			}
+
+			name, _ := params["name"].(string)
+			value, _ := params["value"].(string)
+			reason, _ := params["reason"].(string)
+
+			if err := (&agentcontext.Preferences{}).Set(name, value); err != nil {
+				return "", &tools.ToolError{Code: "INVALID_PREFERENCE", Message: err.Error()}
+			}
+
+			prompt := fmt.Sprintf("Remember %s = %q for this conversation?", name, value)
+			if reason != "" {
+				prompt += "\n" + reason
+			}
+
+			approved, err := a.requestConfirmation(ctx, msg, prompt)
+			if err != nil {
+				return "", err
+			}
+			if !approved {
+				return fmt.Sprintf("The user declined to save %s = %q. Do not ask again.", name, value), nil
+			}
+
+			if err := a.SetPreference(ctx, chatID, name, value); err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("Saved preference %s = %q.", name, value), nil
+		},
+	)
+}
+
+func isPrefsCommand(content string) bool {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return false
+	}
+
+	command, _, _ := strings.Cut(fields[0], "@")
+	return command == "/prefs"
+}
+
+func (a *Agent) handlePrefsCommand(ctx context.Context, msg *bus.Message) error {
+	fields := strings.Fields(msg.Content)
+
+	if len(fields) == 1 {
+		prefs, err := a.GetPreferences(ctx, msg.ChatID)
+		if err != nil {
+			return a.reply(ctx, msg, fmt.Sprintf("Failed to load preferences: %v", err))
+		}
+		if prefs.Empty() {
+			return a.reply(ctx, msg, "No preferences set.\n"+prefsCommandUsage)
+		}
+		return a.reply(ctx, msg, "Preferences for this conversation:\n"+prefs.String())
+	}
+
+	switch fields[1] {
+	case "set":
+		if len(fields) < 4 {
+			return a.reply(ctx, msg, prefsCommandUsage)
+		}
+		value := strings.Join(fields[3:], " ")
+		if err := a.SetPreference(ctx, msg.ChatID, fields[2], value); err != nil {
+			return a.reply(ctx, msg, fmt.Sprintf("Failed to set preference: %v", err))
+		}
+		return a.reply(ctx, msg, fmt.Sprintf("Preference %s set to %q.", fields[2], value))
+
+	case "unset":
+		if len(fields) != 3 {
+			return a.reply(ctx, msg, prefsCommandUsage)
+		}
+		if err := a.SetPreference(ctx, msg.ChatID, fields[2], ""); err != nil {
+			return a.reply(ctx, msg, fmt.Sprintf("Failed to unset preference: %v", err))
+		}
+		return a.reply(ctx, msg, fmt.Sprintf("Preference %s cleared.", fields[2]))
+
+	case "clear":
+		if a.memoryStorage == nil {
+			return a.reply(ctx, msg, "Failed to clear preferences: memory storage is not configured")
+		}
+		if err := agentcontext.SavePreferences(ctx, a.memoryStorage, msg.ChatID, &agentcontext.Preferences{}); err != nil {
+			return a.reply(ctx, msg, fmt.Sprintf("Failed to clear preferences: %v", err))
+		}
+		return a.reply(ctx, msg, "All preferences cleared.")
+	}
+
+	return a.reply(ctx, msg, prefsCommandUsage)
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestPreferences(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var systemPrompts []string
+	replies := []string{
+		`{"thought": "They write in French", "tool_calls": [{"name": "set_preference", "input": {"name": "language", "value": "French"}}]}`,
+		`{"thought": "done", "final_answer": "D'accord"}`,
+		"Bonjour",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		mu.Lock()
+		systemPrompts = append(systemPrompts, req.Messages[0].Content)
+		reply := replies[0]
+		if len(replies) > 1 {
+			replies = replies[1:]
+		}
+		mu.Unlock()
+
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, reply)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+	memoryStorage := storage.NewFileSystemMemoryStorage(dir)
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  memoryStorage,
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	send := func(content string) {
+		if err := agent.HandleMessage(ctx, &bus.Message{ID: "m", Channel: bus.ChannelTelegram, ChatID: "chat", Content: content}); err != nil {
+			t.Fatalf("Failed to handle %q: %v", content, err)
+		}
+	}
+
+	send("/prefs set units kelvin")
+	if reply := <-messageBus.published; !strings.Contains(reply.Content, "invalid units") {
+		t.Errorf("Expected invalid value to be rejected, got %s", reply.Content)
+	}
+
+	send("/prefs set units Imperial")
+	<-messageBus.published
+
+	done := make(chan struct{})
+	go func() {
+		send("Salut, ça va ?")
+		close(done)
+	}()
+
+	prompt := <-messageBus.published
+	if len(prompt.Buttons()) != 1 || !strings.Contains(prompt.Content, "language") {
+		t.Fatalf("Expected inferred preference to be confirmed, got %+v", prompt)
+	}
+	agent.HandleMessage(ctx, &bus.Message{
+		ID:       "callback",
+		Channel:  bus.ChannelTelegram,
+		ChatID:   "chat",
+		Metadata: map[string]interface{}{bus.MetadataCallback: &bus.Callback{Data: confirmYes}},
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected conversation to finish after confirming")
+	}
+	<-messageBus.published
+
+	restarted, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  memoryStorage,
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	if err := restarted.HandleMessage(ctx, &bus.Message{ID: "m2", Channel: bus.ChannelTelegram, ChatID: "chat", Content: "Et demain ?"}); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+	<-messageBus.published
+
+	mu.Lock()
+	last := systemPrompts[len(systemPrompts)-1]
+	mu.Unlock()
+	if !strings.Contains(last, "- language: French") || !strings.Contains(last, "- units: imperial") {
+		t.Errorf("Expected preferences in the system prompt after restart, got:\n%s", last)
+	}
+
+	memory, _ := memoryStorage.GetMemory(ctx)
+	if strings.Contains(memory, "French") {
+		t.Error("Expected preferences to stay out of global memory")
+	}
+
+	if err := restarted.HandleMessage(ctx, &bus.Message{ID: "m3", Channel: bus.ChannelTelegram, ChatID: "other", Content: "/prefs"}); err != nil {
+		t.Fatalf("Failed to handle /prefs: %v", err)
+	}
+	if reply := <-messageBus.published; !strings.HasPrefix(reply.Content, "No preferences set.") {
+		t.Errorf("Expected preferences to be per chat, got %s", reply.Content)
+	}
+}
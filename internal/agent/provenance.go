@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/export"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const (
+	maxProvenanceInput  = 60
+	maxProvenanceOutput = 120
+	redactedValue       = "[redacted]"
+)
+
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|api[_-]?key|auth|credential|cookie|private[_-]?key)`)
+
+var sensitiveAssignmentPattern = regexp.MustCompile(`(?i)\b(password|passwd|secret|token|api[_-]?key|authorization)(["']?\s*[:=]\s*["']?)[^\s"',}]+`)
+
+var sensitiveValuePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`),
+	regexp.MustCompile(`\b(sk|pk|ghp|gho|xox[abp])[-_][A-Za-z0-9_-]{8,}`),
+}
+
+func buildToolUses(calls []tools.ToolCall) []bus.ToolUse {
+	toolUses := make([]bus.ToolUse, 0, len(calls))
+	for _, call := range calls {
+		toolUses = append(toolUses, bus.ToolUse{
+			Name:     call.Name,
+			Input:    summarizeToolInput(call.Input),
+			Output:   truncateProvenance(redactSecrets(call.Result), maxProvenanceOutput),
+			Error:    truncateProvenance(redactSecrets(call.Error), maxProvenanceOutput),
+			Duration: call.Duration,
+		})
+	}
+	return toolUses
+}
+
+// publishToolEvent tells the chat a tool call started or finished, when the
+// request asked for tool events. Inputs and results are redacted like the
+// tool uses shown with the reply.
+func (a *Agent) publishToolEvent(ctx context.Context, phase string, call tools.ToolCall) {
+	request := requestMessageFromContext(ctx)
+	if request == nil || !request.WantsToolEvents() {
+		return
+	}
+
+	err := a.publishReply(ctx, request, &bus.Message{
+		ID:      fmt.Sprintf("agent-%s", request.ID),
+		Channel: request.Channel,
+		ChatID:  request.ChatID,
+		Metadata: map[string]interface{}{
+			bus.MetadataToolEvent: &bus.ToolEvent{Phase: phase, Tool: buildToolUses([]tools.ToolCall{call})[0]},
+		},
+	})
+	if err != nil {
+		a.logger.Warn("Failed to publish tool event", "chat_id", request.ChatID, "tool", call.Name, "error", err)
+	}
+}
+
+func (a *Agent) recordToolCalls(ctx context.Context, msg *bus.Message, responseID string, calls []tools.ToolCall) {
+	if a.storage == nil || len(calls) == 0 {
+		return
+	}
+
+	record := &export.ToolCallRecord{
+		ResponseID: responseID,
+		Request:    truncateProvenance(redactSecrets(msg.Content), maxProvenanceOutput),
+		Timestamp:  time.Now(),
+		Tools:      buildToolUses(calls),
+	}
+	if err := export.SaveToolCalls(ctx, a.storage, msg.ChatID, record); err != nil {
+		a.logger.Warn("Failed to record tool calls", "chat_id", msg.ChatID, "error", err)
+	}
+}
+
+func summarizeToolInput(input map[string]interface{}) string {
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := redactedValue
+		if !sensitiveKeyPattern.MatchString(key) {
+			value = truncateProvenance(redactSecrets(fmt.Sprintf("%v", input[key])), maxProvenanceInput)
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func redactSecrets(text string) string {
+	for _, pattern := range sensitiveValuePatterns {
+		text = pattern.ReplaceAllString(text, redactedValue)
+	}
+	return sensitiveAssignmentPattern.ReplaceAllString(text, "${1}${2}"+redactedValue)
+}
+
+func truncateProvenance(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-3]) + "..."
+}
+
+func isShowWorkCommand(content string) bool {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return false
+	}
+
+	command, _, _ := strings.Cut(fields[0], "@")
+	return command == "/showwork"
+}
+
+func (a *Agent) handleShowWorkCommand(ctx context.Context, msg *bus.Message) error {
+	fields := strings.Fields(msg.Content)
+
+	var response string
+	switch {
+	case len(fields) < 2:
+		state := "off"
+		if a.IsShowWorkEnabled(msg.ChatID) {
+			state = "on"
+		}
+		response = fmt.Sprintf("Show your work is %s. Use /showwork on or /showwork off to change it.", state)
+	case strings.EqualFold(fields[1], "on"):
+		a.SetShowWork(msg.ChatID, true)
+		response = "Show your work enabled. Replies will list the tools that were used."
+	case strings.EqualFold(fields[1], "off"):
+		a.SetShowWork(msg.ChatID, false)
+		response = "Show your work disabled."
+	default:
+		response = "Usage: /showwork [on|off]"
+	}
+
+	return a.publishReply(ctx, msg, &bus.Message{
+		ID:      fmt.Sprintf("agent-%s", msg.ID),
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: response,
+	})
+}
+
+func (a *Agent) SetShowWork(chatID string, enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.showWork[chatID] = enabled
+}
+
+func (a *Agent) IsShowWorkEnabled(chatID string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if enabled, ok := a.showWork[chatID]; ok {
+		return enabled
+	}
+	return a.defaultShowWork
+}
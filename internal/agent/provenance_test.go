@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestBuildToolUsesRedactsSecrets(t *testing.T) {
+	toolUses := buildToolUses([]tools.ToolCall{
+		{
+			Name: "http_request",
+			Input: map[string]interface{}{
+				"url":     "https://example.com/api",
+				"api_key": "super-secret",
+			},
+			Result:   "Authorization: Bearer abc.def.ghi token=xyz123 ok",
+			Duration: 12,
+		},
+		{
+			Name:  "read_file",
+			Input: map[string]interface{}{"path": strings.Repeat("a", 100)},
+			Error: "file not found",
+		},
+	})
+
+	if len(toolUses) != 2 {
+		t.Fatalf("Expected 2 tool uses, got %d", len(toolUses))
+	}
+
+	if toolUses[0].Input != "api_key=[redacted], url=https://example.com/api" {
+		t.Errorf("Unexpected input summary: %s", toolUses[0].Input)
+	}
+
+	if strings.Contains(toolUses[0].Output, "abc.def.ghi") || strings.Contains(toolUses[0].Output, "xyz123") {
+		t.Errorf("Expected secrets to be redacted, got %s", toolUses[0].Output)
+	}
+
+	if len([]rune(toolUses[1].Input)) != len("path=")+maxProvenanceInput {
+		t.Errorf("Expected long input to be truncated, got %s", toolUses[1].Input)
+	}
+
+	if toolUses[1].Error != "file not found" {
+		t.Errorf("Expected error to be kept, got %s", toolUses[1].Error)
+	}
+}
+
+func TestAgentShowWorkCommand(t *testing.T) {
+	ctx := context.Background()
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(""),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(""),
+		Storage:        storage.NewFileStorage(""),
+		ToolRegistry:   tools.NewToolRegistry(),
+		ShowWork:       true,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	if !agent.IsShowWorkEnabled("chat") {
+		t.Error("Expected show work to follow the configured default")
+	}
+
+	err = agent.HandleMessage(ctx, &bus.Message{
+		Channel: bus.ChannelCLI,
+		ChatID:  "chat",
+		Content: "/showwork off",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if agent.IsShowWorkEnabled("chat") {
+		t.Error("Expected show work to be disabled for chat")
+	}
+
+	if !agent.IsShowWorkEnabled("other") {
+		t.Error("Expected other chats to keep the default")
+	}
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+)
+
+// QuotaLimits caps how much one chat or one user may use the agent. Zero
+// disables a limit.
+type QuotaLimits struct {
+	MessagesPerMinute int
+	LLMCallsPerHour   int
+}
+
+// QuotaConfig sets the limits applied to each chat and to each authenticated
+// user across their chats. Admins, the CLI and scheduled tasks are not limited.
+type QuotaConfig struct {
+	Chat QuotaLimits
+	User QuotaLimits
+}
+
+// QuotaError is returned when a chat or user has used up a quota.
+type QuotaError struct {
+	Quota      string
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s quota exceeded, retry in %s", e.Quota, e.RetryAfter.Round(time.Second))
+}
+
+const (
+	quotaMessages = "messages"
+	quotaLLMCalls = "llm_calls"
+)
+
+type quotas struct {
+	mu        sync.Mutex
+	config    QuotaConfig
+	limiters  map[string]*llm.RateLimiter
+	throttled map[string]bool
+}
+
+func newQuotas(config *QuotaConfig) *quotas {
+	q := &quotas{}
+	q.set(config)
+	return q
+}
+
+func (q *quotas) set(config *QuotaConfig) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.config = QuotaConfig{}
+	if config != nil {
+		q.config = *config
+	}
+	q.limiters = make(map[string]*llm.RateLimiter)
+	q.throttled = make(map[string]bool)
+}
+
+// SetQuotas replaces the quotas, starting every count afresh.
+func (a *Agent) SetQuotas(config *QuotaConfig) {
+	a.quotas.set(config)
+}
+
+// reserve counts one use of quota for the sender of msg, checking the user
+// limit before the chat limit.
+func (q *quotas) reserve(ctx context.Context, msg *bus.Message, quota string) error {
+	if msg == nil || quotaExempt(ctx, msg) {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if user, ok := msg.User(); ok {
+		if err := q.reserveLocked("user:"+user.ID, quota, q.config.User); err != nil {
+			return err
+		}
+	}
+	if err := q.reserveLocked("chat:"+msg.Channel+":"+msg.ChatID, quota, q.config.Chat); err != nil {
+		return err
+	}
+
+	delete(q.throttled, refusalKey(msg, quota))
+	return nil
+}
+
+func (q *quotas) reserveLocked(owner, quota string, limits QuotaLimits) error {
+	limit, window := limits.MessagesPerMinute, time.Minute
+	if quota == quotaLLMCalls {
+		limit, window = limits.LLMCallsPerHour, time.Hour
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	key := owner + ":" + quota
+	limiter, ok := q.limiters[key]
+	if !ok {
+		limiter = llm.NewRateLimiter(limit, window)
+		q.limiters[key] = limiter
+	}
+
+	retryAfter, ok := limiter.Reserve()
+	if !ok {
+		return &QuotaError{Quota: quota, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// firstRefusal reports whether this is the first time the chat is refused
+// since the quota last allowed it. Only that refusal is answered, so a noisy
+// chat does not get a reply to every message it sends while throttled.
+func (q *quotas) firstRefusal(msg *bus.Message, quota string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := refusalKey(msg, quota)
+	if q.throttled[key] {
+		return false
+	}
+	q.throttled[key] = true
+	return true
+}
+
+func refusalKey(msg *bus.Message, quota string) string {
+	return msg.Channel + ":" + msg.ChatID + ":" + quota
+}
+
+func quotaExempt(ctx context.Context, msg *bus.Message) bool {
+	if msg.Channel == bus.ChannelCLI || msg.Channel == bus.ChannelStdio || llm.PriorityFromContext(ctx) < llm.PriorityInteractive {
+		return true
+	}
+	user, ok := msg.User()
+	return ok && user.Admin
+}
+
+// throttle answers a message refused by a quota.
+func (a *Agent) throttle(ctx context.Context, msg *bus.Message, quotaErr *QuotaError) error {
+	a.logger.Info("Quota exceeded", "channel", msg.Channel, "chat_id", msg.ChatID, "quota", quotaErr.Quota, "retry_after", quotaErr.RetryAfter.Round(time.Second))
+	if !a.quotas.firstRefusal(msg, quotaErr.Quota) {
+		return nil
+	}
+
+	wait := formatWait(quotaErr.RetryAfter)
+	if quotaErr.Quota == quotaLLMCalls {
+		return a.reply(ctx, msg, fmt.Sprintf("I've answered a lot of requests here in the last hour. Please give me a break and try again in %s.", wait))
+	}
+	return a.reply(ctx, msg, fmt.Sprintf("You're sending messages faster than I can keep up with. Please wait %s and try again.", wait))
+}
+
+func formatWait(d time.Duration) string {
+	seconds := int((d + time.Second - 1) / time.Second)
+	switch {
+	case seconds <= 1:
+		return "a second"
+	case seconds < 60:
+		return fmt.Sprintf("%d seconds", seconds)
+	}
+
+	minutes := (seconds + 59) / 60
+	if minutes == 1 {
+		return "a minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestAgentThrottlesChatsOverMessageQuota(t *testing.T) {
+	ctx := context.Background()
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(t.TempDir()),
+		ToolRegistry:   tools.NewToolRegistry(),
+		Quotas:         &QuotaConfig{Chat: QuotaLimits{MessagesPerMinute: 2}},
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	send := func(channel, chatID string, user *bus.User) {
+		msg := &bus.Message{ID: "m", Channel: channel, ChatID: chatID, Content: "hello"}
+		if user != nil {
+			msg.Metadata = map[string]interface{}{bus.MetadataUser: *user}
+		}
+		if err := agent.HandleMessage(ctx, msg); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+	}
+	replies := func() []string {
+		var contents []string
+		for {
+			select {
+			case msg := <-messageBus.published:
+				contents = append(contents, msg.Content)
+			default:
+				return contents
+			}
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		send(bus.ChannelTelegram, "group", nil)
+	}
+	got := replies()
+	if len(got) != 3 || !strings.Contains(got[2], "Please wait a minute") {
+		t.Fatalf("Expected two answers and a single throttle reply, got %q", got)
+	}
+
+	send(bus.ChannelTelegram, "other", nil)
+	send(bus.ChannelCLI, "group", nil)
+	send(bus.ChannelTelegram, "group", &bus.User{ID: "telegram:1", Admin: true})
+	if got := replies(); len(got) != 3 || strings.Contains(strings.Join(got, "\n"), "Please wait") {
+		t.Errorf("Expected other chats, the CLI and admins not to be throttled, got %q", got)
+	}
+
+	agent.SetQuotas(nil)
+	send(bus.ChannelTelegram, "group", nil)
+	if got := replies(); len(got) != 1 || strings.Contains(got[0], "Please wait") {
+		t.Errorf("Expected no quota after clearing it, got %q", got)
+	}
+}
+
+func TestAgentThrottlesUsersOverLLMCallQuota(t *testing.T) {
+	ctx := context.Background()
+	model := newModelServer("hi")
+	defer model.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "alpha", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: model.URL}},
+		DefaultModel:   "alpha",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+		Quotas:         &QuotaConfig{User: QuotaLimits{LLMCallsPerHour: 1}},
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	user := bus.User{ID: "ws:alice"}
+	for _, chatID := range []string{"first", "second"} {
+		err := agent.HandleMessage(ctx, &bus.Message{
+			ID:       chatID,
+			Channel:  bus.ChannelWebSocket,
+			ChatID:   chatID,
+			Content:  "hello",
+			Metadata: map[string]interface{}{bus.MetadataUser: user},
+		})
+		if err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+	}
+
+	if reply := <-messageBus.published; reply.Content != "hi" {
+		t.Errorf("Expected the first message to be answered, got %s", reply.Content)
+	}
+	if reply := <-messageBus.published; !strings.Contains(reply.Content, "try again in 60 minutes") {
+		t.Errorf("Expected the user to be throttled in another chat, got %s", reply.Content)
+	}
+}
+
+func TestFormatWait(t *testing.T) {
+	tests := map[time.Duration]string{
+		300 * time.Millisecond:   "a second",
+		12500 * time.Millisecond: "13 seconds",
+		59900 * time.Millisecond: "a minute",
+		61 * time.Second:         "2 minutes",
+		time.Hour:                "60 minutes",
+	}
+	for d, expected := range tests {
+		if got := formatWait(d); got != expected {
+			t.Errorf("formatWait(%s) = %q, expected %q", d, got, expected)
+		}
+	}
+}
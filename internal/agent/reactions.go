@@ -0,0 +1,272 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+)
+
+// Reactions must come from the fixed set of emoji Telegram allows.
+const (
+	reactionThumbsUp   = "👍"
+	reactionThumbsDown = "👎"
+	reactionRetry      = "🤔"
+	reactionCancel     = "🙈"
+	feedbackLogDir     = "feedback"
+	confirmYes         = "confirm:yes"
+	confirmNo          = "confirm:no"
+)
+
+const confirmationTimeout = 5 * time.Minute
+
+type exchange struct {
+	request    *bus.Message
+	responseID string
+	model      string
+}
+
+type feedbackRecord struct {
+	ResponseID string    `json:"response_id"`
+	Channel    string    `json:"channel"`
+	ChatID     string    `json:"chat_id"`
+	UserID     string    `json:"user_id,omitempty"`
+	Rating     string    `json:"rating"`
+	Model      string    `json:"model,omitempty"`
+	Request    string    `json:"request,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+func (a *Agent) handleReaction(ctx context.Context, msg *bus.Message, reaction *bus.Reaction) error {
+	switch reaction.Emoji {
+	case reactionThumbsUp:
+		a.recordFeedback(ctx, msg, reaction, "positive")
+	case reactionThumbsDown:
+		a.recordFeedback(ctx, msg, reaction, "negative")
+	case reactionRetry:
+		return a.retryWithAnotherModel(ctx, msg, reaction)
+	case reactionCancel:
+		if a.resolveConfirmation(msg.ChatID, false) {
+			return a.reply(ctx, msg, "Cancelled.")
+		}
+	}
+
+	return nil
+}
+
+func (a *Agent) recordFeedback(ctx context.Context, msg *bus.Message, reaction *bus.Reaction, rating string) {
+	record := &feedbackRecord{
+		ResponseID: reaction.MessageID,
+		Channel:    msg.Channel,
+		ChatID:     msg.ChatID,
+		UserID:     reaction.UserID,
+		Rating:     rating,
+		Timestamp:  time.Now(),
+	}
+
+	if last := a.getLastExchange(msg.ChatID); last != nil && last.responseID == reaction.MessageID {
+		record.Model = last.model
+		record.Request = last.request.Content
+	}
+
+	a.logger.Info("Recorded feedback", "chat_id", msg.ChatID, "message_id", reaction.MessageID, "rating", rating)
+
+	if a.storage == nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		a.logger.Error("Failed to marshal feedback", "message_id", reaction.MessageID, "error", err)
+		return
+	}
+
+	filePath := path.Join(feedbackLogDir, record.Timestamp.Format("2006-01-02"), reaction.MessageID+".json")
+	if err := a.storage.WriteFile(ctx, filePath, data); err != nil {
+		a.logger.Error("Failed to store feedback", "message_id", reaction.MessageID, "error", err)
+	}
+}
+
+func (a *Agent) retryWithAnotherModel(ctx context.Context, msg *bus.Message, reaction *bus.Reaction) error {
+	last := a.getLastExchange(msg.ChatID)
+	if last == nil || last.responseID != reaction.MessageID {
+		return a.reply(ctx, msg, "I can only retry my latest reply.")
+	}
+
+	if a.llmManager == nil {
+		return nil
+	}
+
+	model, ok := a.alternateModel(last.model)
+	if !ok {
+		return a.reply(ctx, msg, "There is no other model configured to retry with.")
+	}
+
+	a.dropLastExchange(msg.ChatID)
+
+	retry := &bus.Message{
+		ID:       last.request.ID + "-retry",
+		Channel:  last.request.Channel,
+		ChatID:   last.request.ChatID,
+		Content:  last.request.Content,
+		Metadata: last.request.Metadata,
+	}
+
+	a.logger.Info("Retrying with another model", "chat_id", msg.ChatID, "message_id", last.request.ID, "model", model)
+	return a.HandleMessage(llm.WithModel(ctx, model), retry)
+}
+
+func (a *Agent) alternateModel(current string) (string, bool) {
+	models := a.llmManager.ListModels()
+	sort.Strings(models)
+
+	for _, model := range models {
+		if model != current {
+			return model, true
+		}
+	}
+
+	return "", false
+}
+
+func (a *Agent) currentModel(ctx context.Context) string {
+	if model, ok := llm.ModelFromContext(ctx); ok {
+		return model
+	}
+	if a.llmManager == nil {
+		return ""
+	}
+	return a.llmManager.GetCurrentModel()
+}
+
+func (a *Agent) getLastExchange(chatID string) *exchange {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastExchanges[chatID]
+}
+
+func (a *Agent) setLastExchange(chatID string, last *exchange) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastExchanges[chatID] = last
+}
+
+func (a *Agent) dropLastExchange(chatID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.lastExchanges, chatID)
+
+	history := a.chatHistory[chatID]
+	n := len(history)
+	if n >= 2 && history[n-2].Role == llm.RoleUser && history[n-1].Role == llm.RoleAssistant {
+		a.storeHistory(chatID, history[:n-2:n-2])
+	}
+}
+
+type requestMessageKey struct{}
+
+func withRequestMessage(ctx context.Context, msg *bus.Message) context.Context {
+	return context.WithValue(ctx, requestMessageKey{}, msg)
+}
+
+func requestMessageFromContext(ctx context.Context) *bus.Message {
+	msg, _ := ctx.Value(requestMessageKey{}).(*bus.Message)
+	return msg
+}
+
+func (a *Agent) requestConfirmation(ctx context.Context, msg *bus.Message, prompt string) (bool, error) {
+	answer := make(chan bool, 1)
+
+	a.mu.Lock()
+	if _, pending := a.confirmations[msg.ChatID]; pending {
+		a.mu.Unlock()
+		return false, fmt.Errorf("a confirmation is already pending for chat %s", msg.ChatID)
+	}
+	a.confirmations[msg.ChatID] = answer
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		delete(a.confirmations, msg.ChatID)
+		a.mu.Unlock()
+	}()
+
+	if err := a.publishReply(ctx, msg, &bus.Message{
+		ID:      fmt.Sprintf("agent-confirm-%s", msg.ID),
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: prompt,
+		Metadata: map[string]interface{}{
+			bus.MetadataButtons: [][]bus.Button{{
+				{Text: "Yes", Data: confirmYes},
+				{Text: "No", Data: confirmNo},
+			}},
+		},
+	}); err != nil {
+		return false, fmt.Errorf("failed to publish confirmation: %w", err)
+	}
+
+	timer := time.NewTimer(confirmationTimeout)
+	defer timer.Stop()
+
+	select {
+	case approved := <-answer:
+		return approved, nil
+	case <-timer.C:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (a *Agent) confirmToolCall(ctx context.Context, name string, params map[string]interface{}) (bool, error) {
+	msg := requestMessageFromContext(ctx)
+	if msg == nil {
+		return false, fmt.Errorf("no conversation to ask for confirmation")
+	}
+
+	prompt := fmt.Sprintf("Allow the assistant to run %s?", name)
+	if input, err := json.Marshal(params); err == nil && len(params) > 0 {
+		prompt += "\n" + truncateProvenance(string(input), 500)
+	}
+	prompt += "\nReply yes or no."
+
+	a.logger.Info("Waiting for tool confirmation", "chat_id", msg.ChatID, "tool", name)
+	return a.requestConfirmation(ctx, msg, prompt)
+}
+
+// parseConfirmationReply lets channels without buttons answer a pending
+// confirmation by typing yes or no.
+func parseConfirmationReply(content string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(content)) {
+	case "yes", "y":
+		return true, true
+	case "no", "n":
+		return false, true
+	}
+	return false, false
+}
+
+func (a *Agent) resolveConfirmation(chatID string, approved bool) bool {
+	a.mu.RLock()
+	answer, ok := a.confirmations[chatID]
+	a.mu.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	select {
+	case answer <- approved:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func newModelServer(reply string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, reply)
+	}))
+}
+
+func TestAgentReactions(t *testing.T) {
+	ctx := context.Background()
+	alpha := newModelServer("from alpha")
+	defer alpha.Close()
+	beta := newModelServer("from beta")
+	defer beta.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels: []*llm.ModelConfig{
+			{Name: "alpha", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: alpha.URL},
+			{Name: "beta", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: beta.URL},
+		},
+		DefaultModel:   "alpha",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	react := func(emoji, messageID string) {
+		err := agent.HandleMessage(ctx, &bus.Message{
+			ID:       "reaction-" + emoji,
+			Channel:  bus.ChannelTelegram,
+			ChatID:   "chat",
+			Metadata: map[string]interface{}{bus.MetadataReaction: &bus.Reaction{Emoji: emoji, MessageID: messageID}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to handle reaction %s: %v", emoji, err)
+		}
+	}
+
+	if err := agent.HandleMessage(ctx, &bus.Message{ID: "msg-1", Channel: bus.ChannelTelegram, ChatID: "chat", Content: "hi"}); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+	if reply := <-messageBus.published; reply.Content != "from alpha" {
+		t.Fatalf("Expected reply from default model, got %s", reply.Content)
+	}
+
+	react(reactionRetry, "agent-msg-1")
+	reply := <-messageBus.published
+	if reply.Content != "from beta" {
+		t.Fatalf("Expected retried reply from another model, got %s", reply.Content)
+	}
+
+	if history := agent.GetChatHistory("chat"); len(history) != 2 || history[1].Content != "from beta" {
+		t.Errorf("Expected retried exchange to replace the original, got %+v", history)
+	}
+
+	react(reactionThumbsUp, reply.ID)
+	files, _ := fileStorage.ListFiles(ctx, feedbackLogDir)
+	if len(files) != 1 {
+		t.Fatalf("Expected one feedback record, got %v", files)
+	}
+	data, _ := fileStorage.ReadFile(ctx, files[0])
+	if !strings.Contains(string(data), `"rating": "positive"`) || !strings.Contains(string(data), `"model": "beta"`) {
+		t.Errorf("Unexpected feedback record: %s", data)
+	}
+
+	react(reactionRetry, "agent-unknown")
+	if reply := <-messageBus.published; !strings.Contains(reply.Content, "latest reply") {
+		t.Errorf("Expected refusal to retry an old reply, got %s", reply.Content)
+	}
+}
+
+func TestCancelConfirmationWithReaction(t *testing.T) {
+	ctx := context.Background()
+	messageBus := &flakyBus{published: make(chan *bus.Message, 4)}
+
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(t.TempDir()),
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	result := make(chan bool, 1)
+	go func() {
+		approved, _ := agent.requestConfirmation(ctx, &bus.Message{ID: "m", Channel: bus.ChannelTelegram, ChatID: "chat"}, "Delete it?")
+		result <- approved
+	}()
+
+	prompt := <-messageBus.published
+	if len(prompt.Buttons()) != 1 {
+		t.Fatalf("Expected confirmation buttons, got %+v", prompt.Metadata)
+	}
+
+	agent.HandleMessage(ctx, &bus.Message{
+		ID:       "reaction",
+		Channel:  bus.ChannelTelegram,
+		ChatID:   "chat",
+		Metadata: map[string]interface{}{bus.MetadataReaction: &bus.Reaction{Emoji: reactionCancel, MessageID: prompt.ID}},
+	})
+
+	select {
+	case approved := <-result:
+		if approved {
+			t.Error("Expected confirmation to be cancelled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected confirmation to be resolved")
+	}
+
+	if reply := <-messageBus.published; reply.Content != "Cancelled." {
+		t.Errorf("Expected cancellation reply, got %s", reply.Content)
+	}
+}
+
+func TestConfirmToolCallWithTextReply(t *testing.T) {
+	ctx := context.Background()
+	messageBus := &flakyBus{published: make(chan *bus.Message, 4)}
+
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(t.TempDir()),
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	if _, err := agent.confirmToolCall(ctx, "delete_file", nil); err == nil {
+		t.Error("Expected confirmation outside a conversation to fail")
+	}
+
+	result := make(chan bool, 1)
+	go func() {
+		msgCtx := withRequestMessage(ctx, &bus.Message{ID: "m", Channel: bus.ChannelCLI, ChatID: "chat"})
+		approved, _ := agent.confirmToolCall(msgCtx, "delete_file", map[string]interface{}{"path": "notes.txt"})
+		result <- approved
+	}()
+
+	prompt := <-messageBus.published
+	if !strings.Contains(prompt.Content, "delete_file") || !strings.Contains(prompt.Content, "notes.txt") {
+		t.Errorf("Expected prompt to name the tool and its input, got %s", prompt.Content)
+	}
+
+	if err := agent.HandleMessage(ctx, &bus.Message{ID: "reply", Channel: bus.ChannelCLI, ChatID: "chat", Content: " Yes "}); err != nil {
+		t.Fatalf("Failed to handle reply: %v", err)
+	}
+
+	select {
+	case approved := <-result:
+		if !approved {
+			t.Error("Expected typed yes to approve the tool call")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected confirmation to be resolved")
+	}
+}
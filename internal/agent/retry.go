@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const (
+	metadataRetryAttempt = "retry_attempt"
+	failureLogDir        = "failures"
+)
+
+type failureRecord struct {
+	Reference string    `json:"reference"`
+	MessageID string    `json:"message_id"`
+	Channel   string    `json:"channel"`
+	ChatID    string    `json:"chat_id"`
+	Content   string    `json:"content"`
+	Error     string    `json:"error"`
+	Attempt   int       `json:"attempt"`
+	Retrying  bool      `json:"retrying"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (a *Agent) handleWithRetry(ctx context.Context, msg *bus.Message) error {
+	ctx, finish, ok := a.requests.begin(ctx)
+	if !ok {
+		if a.startsConversation(msg) {
+			return a.reply(ctx, msg, shuttingDownReply)
+		}
+		return a.HandleMessage(ctx, msg)
+	}
+	defer finish()
+
+	err := a.HandleMessage(ctx, msg)
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() != nil && a.requests.wasAborted() {
+		a.logger.Warn("Conversation interrupted by shutdown", "channel", msg.Channel, "chat_id", msg.ChatID, "message_id", msg.ID)
+		return a.publishReply(context.WithoutCancel(ctx), msg, &bus.Message{
+			ID:       fmt.Sprintf("agent-%s", msg.ID),
+			Channel:  msg.Channel,
+			ChatID:   msg.ChatID,
+			Content:  interruptedReply,
+			Metadata: map[string]interface{}{bus.MetadataErrorCode: bus.ErrorInterrupted},
+		})
+	}
+
+	// Failures the bus is going to retry are not worth bothering the chat
+	// with yet.
+	if busAttempt, busRetrying := bus.DeliveryAttempt(ctx); busRetrying {
+		a.logger.Warn("Failed to handle message, retrying", "channel", msg.Channel, "chat_id", msg.ChatID, "message_id", msg.ID, "bus_attempt", busAttempt, "error", err)
+		return err
+	}
+
+	attempt := retryAttempt(msg)
+	retrying := a.retryDelay > 0 && attempt == 0
+	reference := newErrorReference()
+
+	a.logger.Error("Failed to handle message", "channel", msg.Channel, "chat_id", msg.ChatID, "message_id", msg.ID, "ref", reference, "attempt", attempt+1, "error", err)
+	a.recordFailure(ctx, &failureRecord{
+		Reference: reference,
+		MessageID: msg.ID,
+		Channel:   msg.Channel,
+		ChatID:    msg.ChatID,
+		Content:   msg.Content,
+		Error:     err.Error(),
+		Attempt:   attempt + 1,
+		Retrying:  retrying,
+		Timestamp: time.Now(),
+	})
+
+	response := fmt.Sprintf("Sorry, something went wrong while handling your message. Please try again later. (ref: %s)", reference)
+	if retrying {
+		response = fmt.Sprintf("Sorry, something went wrong while handling your message. I'll try again in %s. (ref: %s)", a.retryDelay, reference)
+	}
+
+	if err := a.publishReply(ctx, msg, &bus.Message{
+		ID:       fmt.Sprintf("agent-error-%s", msg.ID),
+		Channel:  msg.Channel,
+		ChatID:   msg.ChatID,
+		Content:  response,
+		Metadata: map[string]interface{}{bus.MetadataErrorCode: bus.ErrorInternal},
+	}); err != nil {
+		a.logger.Error("Failed to publish error reply", "ref", reference, "error", err)
+	}
+
+	if retrying {
+		a.scheduleRetry(msg, reference)
+		return nil
+	}
+
+	// Passed on so the bus keeps the message as a dead letter.
+	return err
+}
+
+func (a *Agent) scheduleRetry(msg *bus.Message, reference string) {
+	retry := &bus.Message{
+		ID:       msg.ID,
+		Channel:  msg.Channel,
+		ChatID:   msg.ChatID,
+		Content:  msg.Content,
+		Metadata: make(map[string]interface{}, len(msg.Metadata)+1),
+	}
+	for key, value := range msg.Metadata {
+		retry.Metadata[key] = value
+	}
+	retry.Metadata[metadataRetryAttempt] = retryAttempt(msg) + 1
+
+	go func() {
+		timer := time.NewTimer(a.retryDelay)
+		defer timer.Stop()
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		a.logger.Info("Retrying message", "channel", retry.Channel, "message_id", retry.ID, "ref", reference)
+		if err := a.handleWithRetry(a.ctx, retry); err != nil && a.deadLetters != nil {
+			letter := bus.NewDeadLetter(retry, err, retryAttempt(retry)+1)
+			if err := a.deadLetters.Add(context.WithoutCancel(a.ctx), letter); err != nil {
+				a.logger.Error("Failed to store dead letter", "ref", reference, "error", err)
+			}
+		}
+	}()
+}
+
+func (a *Agent) recordFailure(ctx context.Context, record *failureRecord) {
+	if a.storage == nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		a.logger.Error("Failed to marshal failure record", "ref", record.Reference, "error", err)
+		return
+	}
+
+	filePath := path.Join(failureLogDir, record.Timestamp.Format("2006-01-02"), record.Reference+".json")
+	if err := a.storage.WriteFile(ctx, filePath, data); err != nil {
+		a.logger.Error("Failed to store failure record", "ref", record.Reference, "error", err)
+	}
+}
+
+func retryAttempt(msg *bus.Message) int {
+	if msg.Metadata == nil {
+		return 0
+	}
+
+	attempt, _ := msg.Metadata[metadataRetryAttempt].(int)
+	return attempt
+}
+
+func newErrorReference() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return "ERR-" + hex.EncodeToString(b)
+}
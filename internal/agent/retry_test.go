@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+type flakyBus struct {
+	mu        sync.Mutex
+	failures  int
+	published chan *bus.Message
+}
+
+func (b *flakyBus) Publish(ctx context.Context, channel string, msg *bus.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures > 0 {
+		b.failures--
+		return errors.New("publish failed")
+	}
+
+	b.published <- msg
+	return nil
+}
+
+func (b *flakyBus) Subscribe(channel string, handler bus.MessageHandler) (string, error) {
+	return "", nil
+}
+
+func (b *flakyBus) Unsubscribe(channel string, handlerID string) error {
+	return nil
+}
+
+func (b *flakyBus) Close() error {
+	return nil
+}
+
+func TestAgentRetriesFailedMessage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := &flakyBus{failures: 1, published: make(chan *bus.Message, 4)}
+	dir := t.TempDir()
+
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        storage.NewFileStorage(dir),
+		ToolRegistry:   tools.NewToolRegistry(),
+		RetryDelay:     10 * time.Millisecond,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	err = agent.handleWithRetry(ctx, &bus.Message{
+		ID:      "msg-1",
+		Channel: bus.ChannelCLI,
+		ChatID:  "chat",
+		Content: "hello",
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be handled, got %v", err)
+	}
+
+	var replies []*bus.Message
+	for len(replies) < 2 {
+		select {
+		case msg := <-messageBus.published:
+			replies = append(replies, msg)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected error reply and retried response, got %d messages", len(replies))
+		}
+	}
+
+	if !strings.Contains(replies[0].Content, "ref: ERR-") || !strings.Contains(replies[0].Content, "try again in") {
+		t.Errorf("Unexpected error reply: %s", replies[0].Content)
+	}
+
+	if !strings.Contains(replies[1].Content, "LLM is not configured") {
+		t.Errorf("Expected retried response, got %s", replies[1].Content)
+	}
+
+	files, err := storage.NewFileStorage(dir).ListFiles(ctx, failureLogDir)
+	if err != nil || len(files) == 0 {
+		t.Errorf("Expected failure record to be stored, got %v (%v)", files, err)
+	}
+}
+
+func TestAgentRetriesOnlyOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := &flakyBus{failures: 2, published: make(chan *bus.Message, 4)}
+
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(t.TempDir()),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(t.TempDir()),
+		ToolRegistry:   tools.NewToolRegistry(),
+		RetryDelay:     10 * time.Millisecond,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	err = agent.handleWithRetry(ctx, &bus.Message{
+		ID:       "msg-1",
+		Channel:  bus.ChannelCLI,
+		ChatID:   "chat",
+		Content:  "hello",
+		Metadata: map[string]interface{}{metadataRetryAttempt: 1},
+	})
+	if err == nil {
+		t.Error("Expected the error to be passed on once there are no retries left")
+	}
+
+	select {
+	case msg := <-messageBus.published:
+		if strings.Contains(msg.Content, "try again in") {
+			t.Errorf("Expected no further retry, got %s", msg.Content)
+		}
+	case <-time.After(time.Second):
+	}
+}
+
+func TestAgentDeadLettersFailedRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The reply and the error notice fail, then the retried reply fails too.
+	messageBus := &flakyBus{failures: 3, published: make(chan *bus.Message, 4)}
+	dir := t.TempDir()
+	deadLetters := bus.NewDeadLetterStore(storage.NewFileStorage(dir))
+
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{},
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		ToolRegistry:   tools.NewToolRegistry(),
+		RetryDelay:     10 * time.Millisecond,
+		DeadLetters:    deadLetters,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	if err := agent.handleWithRetry(ctx, &bus.Message{ID: "msg-1", Channel: bus.ChannelCLI, ChatID: "chat", Content: "hello"}); err != nil {
+		t.Fatalf("Expected the first failure to be retried by the agent, got %v", err)
+	}
+
+	var letters []*bus.DeadLetter
+	deadline := time.Now().Add(5 * time.Second)
+	for len(letters) == 0 && time.Now().Before(deadline) {
+		letters, _ = deadLetters.List(ctx)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(letters) != 1 || letters[0].MessageID != "msg-1" || letters[0].Content != "hello" || letters[0].Attempts != 2 {
+		t.Fatalf("Expected the message to be dead-lettered after the retry, got %+v", letters)
+	}
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+)
+
+const maxSessionSweepInterval = time.Minute
+
+type SessionStats struct {
+	Resident   int   `json:"resident"`
+	Limit      int   `json:"limit,omitempty"`
+	Hibernated int64 `json:"hibernated"`
+	Reloaded   int64 `json:"reloaded"`
+}
+
+// sessionCache tracks when each resident chat was last used so idle chats can
+// be hibernated. Hibernated chats are reloaded from session storage on their
+// next message.
+type sessionCache struct {
+	maxSessions int
+	idleTTL     time.Duration
+	order       *list.List
+	entries     map[string]*list.Element
+	hibernated  int64
+	reloaded    int64
+}
+
+type sessionEntry struct {
+	chatID   string
+	lastUsed time.Time
+}
+
+func newSessionCache(maxSessions int, idleTTL time.Duration) *sessionCache {
+	return &sessionCache{
+		maxSessions: maxSessions,
+		idleTTL:     idleTTL,
+		order:       list.New(),
+		entries:     make(map[string]*list.Element),
+	}
+}
+
+func (c *sessionCache) touch(chatID string, now time.Time) {
+	if element, ok := c.entries[chatID]; ok {
+		element.Value.(*sessionEntry).lastUsed = now
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[chatID] = c.order.PushFront(&sessionEntry{chatID: chatID, lastUsed: now})
+}
+
+func (c *sessionCache) remove(chatID string) {
+	if element, ok := c.entries[chatID]; ok {
+		c.order.Remove(element)
+		delete(c.entries, chatID)
+	}
+}
+
+// storeHistory replaces the resident history of a chat. The caller must hold
+// a.mu.
+func (a *Agent) storeHistory(chatID string, history []llm.Message) {
+	a.chatHistory[chatID] = history
+	a.sessions.touch(chatID, time.Now())
+
+	if a.sessions.maxSessions <= 0 {
+		return
+	}
+
+	// Evict least recently used chats, skipping any that are being handled
+	// right now since their history is still in use.
+	element := a.sessions.order.Back()
+	for len(a.sessions.entries) > a.sessions.maxSessions && element != nil {
+		previous := element.Prev()
+		if candidate := element.Value.(*sessionEntry).chatID; !a.chatLocks.Busy(candidate) {
+			a.hibernate(candidate)
+		}
+		element = previous
+	}
+}
+
+// hibernate drops the in-memory state of a chat. The caller must hold a.mu.
+func (a *Agent) hibernate(chatID string) {
+	delete(a.chatHistory, chatID)
+	delete(a.lastExchanges, chatID)
+	delete(a.pages, chatID)
+	a.sessions.remove(chatID)
+	a.sessions.hibernated++
+
+	a.logger.Debug("Hibernated session", "chat_id", chatID)
+}
+
+func (a *Agent) hibernateIdleSessions(now time.Time) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.sessions.idleTTL <= 0 {
+		return 0
+	}
+
+	count := 0
+	element := a.sessions.order.Back()
+	for element != nil {
+		previous := element.Prev()
+		entry := element.Value.(*sessionEntry)
+		if now.Sub(entry.lastUsed) < a.sessions.idleTTL {
+			break
+		}
+		if !a.chatLocks.Busy(entry.chatID) {
+			a.hibernate(entry.chatID)
+			count++
+		}
+		element = previous
+	}
+	return count
+}
+
+func (a *Agent) sweepSessions() {
+	interval := a.sessions.idleTTL / 2
+	if interval > maxSessionSweepInterval {
+		interval = maxSessionSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if count := a.hibernateIdleSessions(now); count > 0 {
+				a.logger.Info("Hibernated idle sessions", "count", count, "resident", a.SessionStats().Resident)
+			}
+		}
+	}
+}
+
+func (a *Agent) SessionStats() SessionStats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return SessionStats{
+		Resident:   len(a.chatHistory),
+		Limit:      a.sessions.maxSessions,
+		Hibernated: a.sessions.hibernated,
+		Reloaded:   a.sessions.reloaded,
+	}
+}
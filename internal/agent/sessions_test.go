@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func newSessionTestAgent(t *testing.T, maxSessions int, idleTTL time.Duration) (*Agent, *flakyBus, storage.SessionStorage) {
+	t.Helper()
+	ctx := context.Background()
+	server := newModelServer("ok")
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	sessionStorage := storage.NewFileSystemSessionStorage(dir)
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:   "main",
+		SessionStorage: sessionStorage,
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+		MaxSessions:    maxSessions,
+		SessionIdleTTL: idleTTL,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	return agent, messageBus, sessionStorage
+}
+
+func sendToChat(t *testing.T, agent *Agent, messageBus *flakyBus, chatID, content string) {
+	t.Helper()
+	msg := &bus.Message{ID: chatID + "-" + content, Channel: bus.ChannelWebSocket, ChatID: chatID, Content: content}
+	if err := agent.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+	<-messageBus.published
+}
+
+func TestSessionLimitHibernatesLeastRecentlyUsed(t *testing.T) {
+	agent, messageBus, sessionStorage := newSessionTestAgent(t, 2, 0)
+
+	sendToChat(t, agent, messageBus, "a", "first")
+	sendToChat(t, agent, messageBus, "b", "first")
+	sendToChat(t, agent, messageBus, "a", "second")
+	sendToChat(t, agent, messageBus, "c", "first")
+
+	stats := agent.SessionStats()
+	if stats.Resident != 2 || stats.Hibernated != 1 {
+		t.Fatalf("Expected 2 resident and 1 hibernated session, got %+v", stats)
+	}
+	if _, ok := agent.chatHistory["b"]; ok {
+		t.Error("Expected least recently used chat b to be hibernated")
+	}
+
+	sendToChat(t, agent, messageBus, "b", "second")
+
+	stats = agent.SessionStats()
+	if stats.Reloaded != 1 {
+		t.Errorf("Expected hibernated chat to be reloaded once, got %+v", stats)
+	}
+	if history := agent.chatHistory["b"]; len(history) != 4 {
+		t.Errorf("Expected reloaded history with both exchanges, got %d messages", len(history))
+	}
+
+	saved, err := sessionStorage.GetMessages(context.Background(), "b", 0)
+	if err != nil {
+		t.Fatalf("Failed to read session: %v", err)
+	}
+	if len(saved) != 4 {
+		t.Errorf("Expected 4 saved messages without duplicates, got %d", len(saved))
+	}
+}
+
+func TestIdleSessionsAreHibernated(t *testing.T) {
+	agent, messageBus, _ := newSessionTestAgent(t, 0, time.Hour)
+
+	sendToChat(t, agent, messageBus, "idle", "hello")
+	sendToChat(t, agent, messageBus, "busy", "hello")
+
+	if count := agent.hibernateIdleSessions(time.Now()); count != 0 {
+		t.Errorf("Expected no recently used session to be hibernated, got %d", count)
+	}
+
+	unlock := agent.chatLocks.Lock("busy")
+	count := agent.hibernateIdleSessions(time.Now().Add(2 * time.Hour))
+	unlock()
+
+	if count != 1 {
+		t.Errorf("Expected only the idle chat to be hibernated, got %d", count)
+	}
+	if _, ok := agent.chatHistory["busy"]; !ok {
+		t.Error("Expected chat being handled to stay resident")
+	}
+	if stats := agent.SessionStats(); stats.Resident != 1 || stats.Hibernated != 1 {
+		t.Errorf("Expected 1 resident and 1 hibernated session, got %+v", stats)
+	}
+}
@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/skills"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const (
+	skillMemoryDir        = "memory/skills"
+	maxSkillMemoryContext = 2000
+)
+
+func skillMemoryPath(namespace string) string {
+	return fmt.Sprintf("%s/%s.json", skillMemoryDir, namespace)
+}
+
+func (a *Agent) loadSkillMemory(ctx context.Context, namespace string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	exists, err := a.storage.FileExists(ctx, skillMemoryPath(namespace))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check skill memory: %w", err)
+	}
+	if !exists {
+		return entries, nil
+	}
+
+	data, err := a.storage.ReadFile(ctx, skillMemoryPath(namespace))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read skill memory: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse skill memory: %w", err)
+	}
+	return entries, nil
+}
+
+func (a *Agent) setSkillMemory(ctx context.Context, namespace, key, value string) error {
+	a.skillMemoryMu.Lock()
+	defer a.skillMemoryMu.Unlock()
+
+	entries, err := a.loadSkillMemory(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	if value == "" {
+		delete(entries, key)
+	} else {
+		entries[key] = value
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal skill memory: %w", err)
+	}
+	if err := a.storage.WriteFile(ctx, skillMemoryPath(namespace), data); err != nil {
+		return fmt.Errorf("failed to write skill memory: %w", err)
+	}
+	return nil
+}
+
+func formatSkillMemory(entries map[string]string) string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&builder, "- %s: %s\n", key, entries[key])
+	}
+	return builder.String()
+}
+
+// skillMemoryContext lists what the skill has remembered so far, for the
+// skill's section of the system prompt.
+func (a *Agent) skillMemoryContext(ctx context.Context, skill *skills.Skill) string {
+	if skill.Memory == "" || a.storage == nil {
+		return ""
+	}
+
+	entries, err := a.loadSkillMemory(ctx, skill.Memory)
+	if err != nil {
+		a.logger.Warn("Failed to load skill memory", "skill", skill.Name, "namespace", skill.Memory, "error", err)
+		return ""
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+
+	return truncateProvenance(formatSkillMemory(entries), maxSkillMemoryContext)
+}
+
+func skillMemoryToolSuffix(namespace string) string {
+	return strings.ReplaceAll(namespace, "-", "_")
+}
+
+// skillMemoryTools returns the memory_get and memory_set variants for the
+// memory areas of the selected skills. They are only offered for the
+// request the skills are active in.
+func (a *Agent) skillMemoryTools(selected []*skills.Skill) []tools.Tool {
+	if a.storage == nil {
+		return nil
+	}
+
+	var scoped []tools.Tool
+	seen := make(map[string]bool)
+	for _, skill := range selected {
+		if skill.Memory == "" || seen[skill.Memory] {
+			continue
+		}
+		seen[skill.Memory] = true
+		scoped = append(scoped, a.newSkillMemoryGetTool(skill), a.newSkillMemorySetTool(skill))
+	}
+	return scoped
+}
+
+func (a *Agent) newSkillMemoryGetTool(skill *skills.Skill) tools.Tool {
+	namespace := skill.Memory
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"key": {
+				"type": "string",
+				"description": "Entry to read. Omit to list all entries"
+			}
+		},
+		"additionalProperties": false
+	}`)
+
+	return tools.NewBaseTool(
+		"memory_get_"+skillMemoryToolSuffix(namespace),
+		fmt.Sprintf("Read the private memory of the %s skill. It is separate from the global memory", skill.Name),
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			entries, err := a.loadSkillMemory(ctx, namespace)
+			if err != nil {
+				return "", err
+			}
+
+			key, _ := params["key"].(string)
+			if key == "" {
+				if len(entries) == 0 {
+					return "No entries.", nil
+				}
+				return formatSkillMemory(entries), nil
+			}
+
+			value, ok := entries[key]
+			if !ok {
+				return fmt.Sprintf("No entry named %q.", key), nil
+			}
+			return value, nil
+		},
+	)
+}
+
+func (a *Agent) newSkillMemorySetTool(skill *skills.Skill) tools.Tool {
+	namespace := skill.Memory
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"key": {
+				"type": "string",
+				"description": "Entry to write"
+			},
+			"value": {
+				"type": "string",
+				"description": "New value. An empty value removes the entry"
+			}
+		},
+		"required": ["key", "value"],
+		"additionalProperties": false
+	}`)
+
+	return tools.NewBaseTool(
+		"memory_set_"+skillMemoryToolSuffix(namespace),
+		fmt.Sprintf("Save an entry in the private memory of the %s skill. It is only visible while the skill is active", skill.Name),
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			key, _ := params["key"].(string)
+			value, _ := params["value"].(string)
+			if strings.TrimSpace(key) == "" {
+				return "", &tools.ToolError{Code: "INVALID_KEY", Message: "key cannot be empty"}
+			}
+
+			if err := a.setSkillMemory(ctx, namespace, key, value); err != nil {
+				return "", err
+			}
+			if value == "" {
+				return fmt.Sprintf("Removed %q.", key), nil
+			}
+			return fmt.Sprintf("Saved %q.", key), nil
+		},
+	)
+}
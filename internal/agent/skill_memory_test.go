@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/skills"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestSkillMemory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+
+	agent, err := NewAgent(&Config{
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, &flakyBus{published: make(chan *bus.Message, 1)}, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	jobs := &skills.Skill{Name: "job search", Description: "Track applications", Memory: "job-search"}
+	planner := &skills.Skill{Name: "planner", Description: "Plan things"}
+
+	if scoped := agent.skillMemoryTools([]*skills.Skill{planner}); len(scoped) != 0 {
+		t.Errorf("Expected no memory tools for skills without a memory area, got %d", len(scoped))
+	}
+
+	skillCtx := tools.WithTools(ctx, agent.skillMemoryTools([]*skills.Skill{jobs, planner})...)
+	call, err := agent.toolExecutor.Execute(skillCtx, "memory_set_job_search", map[string]interface{}{"key": "Acme", "value": "applied 2026-10-01"})
+	if err != nil || call.Error != "" {
+		t.Fatalf("Failed to set skill memory: %+v, %v", call, err)
+	}
+
+	call, _ = agent.toolExecutor.Execute(skillCtx, "memory_get_job_search", map[string]interface{}{"key": "Acme"})
+	if call.Result != "applied 2026-10-01" {
+		t.Errorf("Expected stored value, got %+v", call)
+	}
+
+	if call, _ := agent.toolExecutor.Execute(ctx, "memory_get_job_search", nil); call != nil && call.Error == "" {
+		t.Error("Expected memory tools to be unavailable when the skill is not active")
+	}
+
+	global, _ := agent.memoryStorage.GetMemory(ctx)
+	if strings.Contains(global, "Acme") {
+		t.Error("Expected skill memory to stay out of the global memory")
+	}
+
+	skillContext := agent.buildSkillContext(ctx, []*skills.Skill{jobs})
+	if !strings.Contains(skillContext, "- Acme: applied 2026-10-01") {
+		t.Errorf("Expected skill memory in the skill context, got:\n%s", skillContext)
+	}
+	if strings.Contains(agent.buildSkillContext(ctx, []*skills.Skill{planner}), "Acme") {
+		t.Error("Expected skill memory to be hidden from other skills")
+	}
+
+	agent.toolExecutor.Execute(skillCtx, "memory_set_job_search", map[string]interface{}{"key": "Acme", "value": ""})
+	if call, _ := agent.toolExecutor.Execute(skillCtx, "memory_get_job_search", nil); call.Result != "No entries." {
+		t.Errorf("Expected entry to be removed, got %+v", call)
+	}
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+)
+
+const streamPublishInterval = 300 * time.Millisecond
+
+type responseStream struct {
+	agent       *Agent
+	request     *bus.Message
+	responseID  string
+	lastPublish time.Time
+	sequence    int
+}
+
+type responseStreamKey struct{}
+
+func withResponseStream(ctx context.Context, stream *responseStream) context.Context {
+	return context.WithValue(ctx, responseStreamKey{}, stream)
+}
+
+func responseStreamFromContext(ctx context.Context) *responseStream {
+	stream, _ := ctx.Value(responseStreamKey{}).(*responseStream)
+	return stream
+}
+
+func (a *Agent) complete(ctx context.Context, messages []llm.Message) (*llm.CompletionResponse, error) {
+	if err := a.quotas.reserve(ctx, requestMessageFromContext(ctx), quotaLLMCalls); err != nil {
+		return nil, err
+	}
+
+	stream := responseStreamFromContext(ctx)
+	if stream == nil {
+		resp, err := a.llmManager.Complete(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+		a.logModelThinking(ctx, resp.Thinking)
+		return resp, nil
+	}
+
+	// Reasoning arrives apart from the answer and is never published to the
+	// chat.
+	var content, thinking strings.Builder
+	ctx = llm.WithThinkingHandler(ctx, func(chunk string) {
+		thinking.WriteString(chunk)
+	})
+	err := a.llmManager.StreamComplete(ctx, messages, func(chunk string) error {
+		content.WriteString(chunk)
+		stream.update(ctx, content.String())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a.logModelThinking(ctx, thinking.String())
+	return &llm.CompletionResponse{Content: content.String(), Thinking: thinking.String()}, nil
+}
+
+func (a *Agent) logModelThinking(ctx context.Context, thinking string) {
+	if !a.logThinking || thinking == "" {
+		return
+	}
+
+	chatID := ""
+	if msg := requestMessageFromContext(ctx); msg != nil {
+		chatID = msg.ChatID
+	}
+	a.logger.Info("Model reasoning", "chat_id", chatID, "thinking", thinking)
+}
+
+func (s *responseStream) update(ctx context.Context, text string) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || strings.HasPrefix(trimmed, "{") {
+		return
+	}
+
+	if time.Since(s.lastPublish) < streamPublishInterval {
+		return
+	}
+	s.lastPublish = time.Now()
+	s.sequence++
+
+	err := s.agent.publishReply(ctx, s.request, &bus.Message{
+		ID:      s.responseID,
+		Channel: s.request.Channel,
+		ChatID:  s.request.ChatID,
+		Content: text,
+		Metadata: map[string]interface{}{
+			bus.MetadataPartial:  true,
+			bus.MetadataSequence: s.sequence,
+		},
+	})
+	if err != nil {
+		s.agent.logger.Warn("Failed to publish partial response", "chat_id", s.request.ChatID, "error", err)
+	}
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestStreamingResponse(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, chunk := range []string{"Hel", "lo"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels: []*llm.ModelConfig{
+			{Name: "default", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL},
+		},
+		DefaultModel:   "default",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	err = agent.HandleMessage(ctx, &bus.Message{
+		ID:       "msg-1",
+		Channel:  bus.ChannelTelegram,
+		ChatID:   "chat",
+		Content:  "hi",
+		Metadata: map[string]interface{}{bus.MetadataStreaming: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+
+	first := <-messageBus.published
+	if !first.IsPartial() || first.Content != "Hel" || first.ID != "agent-msg-1" {
+		t.Errorf("Expected partial update for the first chunk, got %+v", first)
+	}
+
+	final := <-messageBus.published
+	if final.IsPartial() || final.Content != "Hello" || final.ID != first.ID {
+		t.Errorf("Expected final response with the same ID, got %+v", final)
+	}
+
+	if err := agent.HandleMessage(ctx, first); err != nil || len(messageBus.published) != 0 {
+		t.Error("Expected partial updates to be ignored by the agent")
+	}
+}
+
+func TestToolEvents(t *testing.T) {
+	ctx := context.Background()
+
+	replies := []string{
+		`{"thought": "Echo it", "tool_calls": [{"name": "echo", "input": {"message": "tick"}}]}`,
+		`{"thought": "done", "final_answer": "tick"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := replies[0]
+		replies = replies[1:]
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, reply)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	registry := tools.NewToolRegistry()
+	registry.Register(tools.NewEchoTool())
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   registry,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	err = agent.HandleMessage(ctx, &bus.Message{
+		ID:       "msg-1",
+		Channel:  bus.ChannelWebSocket,
+		ChatID:   "chat",
+		Content:  "say tick",
+		Metadata: map[string]interface{}{bus.MetadataToolEvents: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+
+	for _, phase := range []string{bus.ToolStarted, bus.ToolFinished} {
+		msg := <-messageBus.published
+		event := msg.ToolEvent()
+		if event == nil || event.Phase != phase || event.Tool.Name != "echo" || msg.ID != "agent-msg-1" {
+			t.Fatalf("Expected %s event for echo, got %+v", phase, msg)
+		}
+		if phase == bus.ToolFinished && event.Tool.Output == "" {
+			t.Errorf("Expected finished event to carry the result, got %+v", event.Tool)
+		}
+	}
+	if final := <-messageBus.published; final.ToolEvent() != nil || !strings.Contains(final.Content, "tick") {
+		t.Errorf("Expected final response after the tool events, got %+v", final)
+	}
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	agentcontext "github.com/wjffsx/miniclaw_go/internal/context"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const (
+	spawnSubAgentTool         = "spawn_subagent"
+	defaultSubAgentIterations = 5
+	defaultSubAgentDepth      = 1
+)
+
+// SubAgentConfig defines a named sub-agent the model can delegate a task to.
+type SubAgentConfig struct {
+	Name         string
+	Description  string
+	SystemPrompt string
+	// Model is the name of the model to use. Empty uses the agent's model.
+	Model string
+	// Tools lists the tools the sub-agent may call. Empty allows all of them.
+	Tools []string
+	// MaxIterations limits the sub-agent's ReAct loop. Zero uses the default.
+	MaxIterations int
+}
+
+type subAgentDepthKey struct{}
+
+func subAgentDepth(ctx context.Context) int {
+	depth, _ := ctx.Value(subAgentDepthKey{}).(int)
+	return depth
+}
+
+func NewSpawnSubAgentTool(a *Agent) tools.Tool {
+	names := make([]string, 0, len(a.subAgents))
+	var description strings.Builder
+	description.WriteString("Delegate a self-contained task, such as summarizing or researching something, to a sub-agent. " +
+		"The sub-agent does not see this conversation, so describe the task completely. It returns the sub-agent's answer. Sub-agents:")
+	for _, sub := range a.subAgents {
+		names = append(names, sub.Name)
+		fmt.Fprintf(&description, "\n- %s: %s", sub.Name, sub.Description)
+	}
+
+	namesJSON, _ := json.Marshal(names)
+	params := json.RawMessage(fmt.Sprintf(`{
+		"type": "object",
+		"properties": {
+			"agent": {
+				"type": "string",
+				"enum": %s,
+				"description": "The sub-agent to delegate to"
+			},
+			"task": {
+				"type": "string",
+				"description": "The task, with everything the sub-agent needs to know"
+			}
+		},
+		"required": ["agent", "task"]
+	}`, namesJSON))
+
+	return tools.NewBaseTool(
+		spawnSubAgentTool,
+		description.String(),
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			name, _ := params["agent"].(string)
+			task, _ := params["task"].(string)
+			if strings.TrimSpace(task) == "" {
+				return "", fmt.Errorf("task is required")
+			}
+			return a.runSubAgent(ctx, name, task)
+		},
+	)
+}
+
+// runSubAgent runs a sub-agent's own ReAct loop on task and returns its
+// answer. Its partial responses are not streamed to the chat.
+func (a *Agent) runSubAgent(ctx context.Context, name string, task string) (string, error) {
+	sub := a.findSubAgent(name)
+	if sub == nil {
+		return "", fmt.Errorf("unknown sub-agent: %s", name)
+	}
+	if a.llmManager == nil {
+		return "", fmt.Errorf("LLM is not configured")
+	}
+
+	depth := subAgentDepth(ctx) + 1
+	if depth > a.maxSubAgentDepth {
+		return "", fmt.Errorf("sub-agents cannot be nested more than %d level(s) deep", a.maxSubAgentDepth)
+	}
+
+	ctx = context.WithValue(ctx, subAgentDepthKey{}, depth)
+	ctx = withResponseStream(ctx, nil)
+	ctx = withTranscript(ctx, nil)
+	if sub.Model != "" {
+		ctx = llm.WithModel(ctx, sub.Model)
+	}
+
+	allowed := a.subAgentTools(ctx, sub, depth)
+	schemas := make([]tools.ToolSchema, 0, len(allowed))
+	for _, schema := range a.toolExecutor.SchemasFor(ctx) {
+		if allowed[schema.Name] {
+			schemas = append(schemas, schema)
+		}
+	}
+
+	systemPrompt := (&agentcontext.Context{SystemPrompt: sub.SystemPrompt}).BuildSystemPrompt(schemas)
+
+	maxIterations := sub.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultSubAgentIterations
+	}
+
+	chatID, _ := tools.ChatIDFromContext(ctx)
+	a.logger.Info("Running sub-agent", "chat_id", chatID, "sub_agent", sub.Name, "depth", depth, "task", logging.Preview(task, 80))
+
+	response, toolCalls, err := a.iterate(ctx, chatID, systemPrompt, []llm.Message{{
+		Role:    llm.RoleUser,
+		Content: task,
+	}}, maxIterations, allowed)
+	if err != nil {
+		return "", fmt.Errorf("sub-agent %s failed: %w", sub.Name, err)
+	}
+
+	a.logger.Info("Sub-agent finished", "chat_id", chatID, "sub_agent", sub.Name, "tool_calls", len(toolCalls))
+	return finalAnswer(response), nil
+}
+
+func (a *Agent) findSubAgent(name string) *SubAgentConfig {
+	for i := range a.subAgents {
+		if a.subAgents[i].Name == name {
+			return &a.subAgents[i]
+		}
+	}
+	return nil
+}
+
+// subAgentTools returns the tools a sub-agent at depth may call. It can only
+// delegate further while that stays within the depth limit.
+func (a *Agent) subAgentTools(ctx context.Context, sub *SubAgentConfig, depth int) map[string]bool {
+	allowed := make(map[string]bool)
+	if len(sub.Tools) > 0 {
+		for _, name := range sub.Tools {
+			allowed[name] = true
+		}
+	} else {
+		for _, schema := range a.toolExecutor.SchemasFor(ctx) {
+			allowed[schema.Name] = true
+		}
+	}
+
+	if depth >= a.maxSubAgentDepth {
+		delete(allowed, spawnSubAgentTool)
+	}
+	return allowed
+}
+
+// finalAnswer extracts the final answer from a response in the ReAct JSON
+// format, or returns the response as it is.
+func finalAnswer(content string) string {
+	var response struct {
+		FinalAnswer string `json:"final_answer"`
+	}
+	if err := json.Unmarshal([]byte(content), &response); err == nil && response.FinalAnswer != "" {
+		return response.FinalAnswer
+	}
+	return content
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestSpawnSubAgent(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var subPrompts, observations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model    string        `json:"model"`
+			Messages []llm.Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		last := req.Messages[len(req.Messages)-1].Content
+
+		var content string
+		switch {
+		case req.Model == "gpt-main" && strings.HasPrefix(last, "Tool execution results"):
+			content = `{"thought": "done", "final_answer": "Summary received"}`
+		case req.Model == "gpt-main":
+			content = `{"thought": "delegate", "tool_calls": [{"name": "spawn_subagent", "input": {"agent": "summarizer", "task": "Summarize the repo"}}]}`
+		case strings.HasPrefix(last, "Tool execution results"):
+			mu.Lock()
+			observations = append(observations, last)
+			mu.Unlock()
+			content = `{"thought": "done", "final_answer": "It is a chat bot"}`
+		default:
+			mu.Lock()
+			subPrompts = append(subPrompts, req.Messages[0].Content)
+			mu.Unlock()
+			content = `{"thought": "look", "tool_calls": [{"name": "echo", "input": {}}, {"name": "delete_everything", "input": {}}]}`
+		}
+		fmt.Fprintf(w, `{"choices": [{"message": {"role": "assistant", "content": %q}}]}`, content)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	registry := tools.NewToolRegistry()
+	noParams := json.RawMessage(`{"type": "object", "properties": {}}`)
+	registry.Register(tools.NewBaseTool("echo", "Echo", noParams, func(ctx context.Context, params map[string]interface{}) (string, error) {
+		return "echoed", nil
+	}))
+	deleted := false
+	registry.Register(tools.NewBaseTool("delete_everything", "Delete everything", noParams, func(ctx context.Context, params map[string]interface{}) (string, error) {
+		deleted = true
+		return "deleted", nil
+	}))
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels: []*llm.ModelConfig{
+			{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-main", BaseURL: server.URL},
+			{Name: "cheap", Provider: "openai", APIKey: "key", Model: "gpt-cheap", BaseURL: server.URL},
+		},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   registry,
+		SubAgents: []SubAgentConfig{{
+			Name:         "summarizer",
+			Description:  "Summarizes things",
+			SystemPrompt: "You write short summaries.",
+			Model:        "cheap",
+			Tools:        []string{"echo", "spawn_subagent"},
+		}},
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	if err := agent.HandleMessage(ctx, &bus.Message{ID: "msg-1", Channel: bus.ChannelTelegram, ChatID: "chat", Content: "What is this repo?"}); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+
+	response := <-messageBus.published
+	if !strings.Contains(response.Content, "Summary received") {
+		t.Errorf("Expected the agent's final answer, got %q", response.Content)
+	}
+
+	if len(subPrompts) != 1 {
+		t.Fatalf("Expected the sub-agent to run once on its own model, got %d runs", len(subPrompts))
+	}
+	if !strings.HasPrefix(subPrompts[0], "You write short summaries.") || !strings.Contains(subPrompts[0], "**echo**") {
+		t.Errorf("Expected the sub-agent's prompt with its tools, got %q", subPrompts[0])
+	}
+	if strings.Contains(subPrompts[0], "spawn_subagent") || strings.Contains(subPrompts[0], "delete_everything") {
+		t.Errorf("Expected only the sub-agent's tools within the depth limit, got %q", subPrompts[0])
+	}
+	if deleted || !strings.Contains(observations[0], "tool 'delete_everything' is not available") || !strings.Contains(observations[0], "echoed") {
+		t.Errorf("Expected tools outside the subset to be refused, got %q", observations[0])
+	}
+
+	history := agent.GetChatHistory("chat")
+	if len(history) != 2 {
+		t.Errorf("Expected only the user's exchange in the history, got %+v", history)
+	}
+}
+
+func TestSubAgentLimits(t *testing.T) {
+	agent := &Agent{
+		llmManager:       &llm.MultiModelManager{},
+		subAgents:        []SubAgentConfig{{Name: "researcher", SystemPrompt: "Research."}},
+		maxSubAgentDepth: 1,
+	}
+
+	ctx := context.WithValue(context.Background(), subAgentDepthKey{}, 1)
+	if _, err := agent.runSubAgent(ctx, "researcher", "dig"); err == nil || !strings.Contains(err.Error(), "nested") {
+		t.Errorf("Expected the depth limit to stop nested sub-agents, got %v", err)
+	}
+	if _, err := agent.runSubAgent(context.Background(), "writer", "write"); err == nil || !strings.Contains(err.Error(), "unknown sub-agent") {
+		t.Errorf("Expected an unknown sub-agent error, got %v", err)
+	}
+
+	if got := finalAnswer(`{"thought": "x", "final_answer": "42"}`); got != "42" {
+		t.Errorf("Expected the final answer to be extracted, got %q", got)
+	}
+}
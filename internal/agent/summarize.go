@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const maxSummaryMessages = 200
+
+const summaryPrompt = `You summarize chat conversations between a user and an assistant.
+Respond in Markdown with exactly these sections:
+
+## Summary
+## Decisions
+## Action Items
+## Open Questions
+
+Use short bullet points. Include owners and dates for action items when they were mentioned. Write "None" for empty sections.`
+
+func NewSummarizeConversationTool(a *Agent) tools.Tool {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"hours": {
+				"type": "number",
+				"description": "Only summarize messages from the last N hours (default: the whole conversation)"
+			},
+			"save_to_daily_note": {
+				"type": "boolean",
+				"description": "Append the summary to today's daily note"
+			}
+		},
+		"additionalProperties": false
+	}`)
+
+	return tools.NewBaseTool(
+		"summarize_conversation",
+		"Summarize the current conversation into decisions, action items and open questions",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			chatID, ok := tools.ChatIDFromContext(ctx)
+			if !ok {
+				return "", &tools.ToolError{
+					Code:    "NO_CONVERSATION",
+					Message: "no active conversation to summarize",
+				}
+			}
+
+			var since time.Time
+			if hours, ok := params["hours"].(float64); ok && hours > 0 {
+				since = time.Now().Add(-time.Duration(hours * float64(time.Hour)))
+			}
+
+			save, _ := params["save_to_daily_note"].(bool)
+
+			return a.SummarizeConversation(ctx, chatID, since, save)
+		},
+	)
+}
+
+func (a *Agent) SummarizeConversation(ctx context.Context, chatID string, since time.Time, saveToDailyNote bool) (string, error) {
+	if a.llmManager == nil {
+		return "", fmt.Errorf("LLM is not configured")
+	}
+
+	stored, err := a.sessionStorage.GetMessages(ctx, chatID, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to load conversation: %w", err)
+	}
+
+	var messages []storage.Message
+	for _, msg := range stored {
+		if !since.IsZero() && msg.Timestamp < since.Unix() {
+			continue
+		}
+		if msg.Role != string(llm.RoleUser) && msg.Role != string(llm.RoleAssistant) {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	if len(messages) == 0 {
+		return "There are no messages to summarize.", nil
+	}
+
+	if len(messages) > maxSummaryMessages {
+		a.logger.Info("Summarizing the latest messages only", "chat_id", chatID, "count", len(messages), "max", maxSummaryMessages)
+		messages = messages[len(messages)-maxSummaryMessages:]
+	}
+
+	var transcript strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&transcript, "%s: %s\n\n", msg.Role, msg.Content)
+	}
+	text := strings.TrimSpace(transcript.String())
+
+	response, err := a.llmManager.Complete(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: summaryPrompt},
+		{Role: llm.RoleUser, Content: "Summarize this conversation:\n\n" + text},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+
+	summary := strings.TrimSpace(response.Content)
+
+	if saveToDailyNote {
+		if err := a.appendDailyNote(ctx, chatID, summary); err != nil {
+			return "", err
+		}
+		summary += "\n\n(Saved to today's daily note.)"
+	}
+
+	return summary, nil
+}
+
+func (a *Agent) appendDailyNote(ctx context.Context, chatID, summary string) error {
+	if a.memoryStorage == nil {
+		return fmt.Errorf("memory storage is not configured")
+	}
+
+	now := time.Now()
+	return a.updateDailyNote(ctx, now, func(note string) string {
+		if note != "" {
+			note = strings.TrimRight(note, "\n") + "\n\n"
+		}
+		return note + fmt.Sprintf("### Conversation summary (%s, %s)\n\n%s\n", chatID, now.Format("15:04"), summary)
+	})
+}
+
+// updateDailyNote rewrites the note of the given day. Conversations append to
+// it concurrently, so the read and the write must not interleave.
+func (a *Agent) updateDailyNote(ctx context.Context, day time.Time, update func(note string) string) error {
+	a.dailyNoteMu.Lock()
+	defer a.dailyNoteMu.Unlock()
+
+	date := day.Format("2006-01-02")
+	note, err := a.memoryStorage.GetDailyNote(ctx, date)
+	if err != nil {
+		return fmt.Errorf("failed to read daily note: %w", err)
+	}
+
+	if err := a.memoryStorage.SetDailyNote(ctx, date, update(note)); err != nil {
+		return fmt.Errorf("failed to save daily note: %w", err)
+	}
+
+	return nil
+}
+
+func isSummarizeCommand(content string) bool {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return false
+	}
+
+	command, _, _ := strings.Cut(fields[0], "@")
+	return command == "/summarize"
+}
+
+func (a *Agent) handleSummarizeCommand(ctx context.Context, msg *bus.Message) error {
+	var since time.Time
+	save := false
+
+	for _, arg := range strings.Fields(msg.Content)[1:] {
+		if strings.EqualFold(arg, "save") {
+			save = true
+			continue
+		}
+
+		hours, err := strconv.ParseFloat(strings.TrimSuffix(arg, "h"), 64)
+		if err != nil || hours <= 0 {
+			return a.reply(ctx, msg, "Usage: /summarize [hours] [save]")
+		}
+		since = time.Now().Add(-time.Duration(hours * float64(time.Hour)))
+	}
+
+	summary, err := a.SummarizeConversation(ctx, msg.ChatID, since, save)
+	if err != nil {
+		return err
+	}
+
+	return a.reply(ctx, msg, summary)
+}
+
+func (a *Agent) reply(ctx context.Context, msg *bus.Message, content string) error {
+	return a.publishReply(ctx, msg, &bus.Message{
+		ID:      fmt.Sprintf("agent-%s", msg.ID),
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: content,
+	})
+}
+
+// publishReply sends response back on the channel request came in on, marked
+// as a reply so the channel can route it to the right connection and the
+// agent does not pick it up as a new message.
+func (a *Agent) publishReply(ctx context.Context, request *bus.Message, response *bus.Message) error {
+	response.SetReplyTo(request)
+	return a.messageBus.Publish(ctx, request.Channel, response)
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestSummarizeConversationTool(t *testing.T) {
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[len(req.Messages)-1].Content
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"## Summary\n- Planned the launch"}}]}`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	sessionStorage := storage.NewFileSystemSessionStorage(dir)
+	memoryStorage := storage.NewFileSystemMemoryStorage(dir)
+	toolRegistry := tools.NewToolRegistry()
+
+	sessionStorage.SaveMessage(ctx, "chat", "user", "Let's launch on Friday")
+	sessionStorage.SaveMessage(ctx, "chat", "assistant", "Agreed, Friday it is")
+
+	_, err := NewAgent(&Config{
+		LLMModels: []*llm.ModelConfig{
+			{Name: "default", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL},
+		},
+		DefaultModel:   "default",
+		SessionStorage: sessionStorage,
+		MemoryStorage:  memoryStorage,
+		ToolRegistry:   toolRegistry,
+	}, bus.NewInMemoryMessageBus(ctx, nil), ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	tool, ok := toolRegistry.Get("summarize_conversation")
+	if !ok {
+		t.Fatal("Expected summarize_conversation tool to be registered")
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{}); err == nil {
+		t.Error("Expected error without a conversation in context")
+	}
+
+	result, err := tool.Execute(tools.WithChatID(ctx, "chat"), map[string]interface{}{
+		"hours":              1.0,
+		"save_to_daily_note": true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(result, "Planned the launch") {
+		t.Errorf("Expected summary in result, got %s", result)
+	}
+
+	if !strings.Contains(prompt, "user: Let's launch on Friday") {
+		t.Errorf("Expected transcript in prompt, got %s", prompt)
+	}
+
+	note, _ := memoryStorage.GetDailyNote(ctx, time.Now().Format("2006-01-02"))
+	if !strings.Contains(note, "### Conversation summary (chat") || !strings.Contains(note, "Planned the launch") {
+		t.Errorf("Expected summary in daily note, got %s", note)
+	}
+}
+
+func TestSummarizeKeepsLatestMessagesWhole(t *testing.T) {
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[len(req.Messages)-1].Content
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"## Summary"}}]}`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	sessionStorage := storage.NewFileSystemSessionStorage(t.TempDir())
+	sessionStorage.SaveMessage(ctx, "chat", "user", "oldest message")
+	for i := 0; i < maxSummaryMessages; i++ {
+		sessionStorage.SaveMessage(ctx, "chat", "assistant", fmt.Sprintf("paragraph %d\n\nmore of %d", i, i))
+	}
+
+	agent, err := NewAgent(&Config{
+		LLMModels: []*llm.ModelConfig{
+			{Name: "default", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL},
+		},
+		DefaultModel:   "default",
+		SessionStorage: sessionStorage,
+	}, bus.NewInMemoryMessageBus(ctx, nil), ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	if _, err := agent.SummarizeConversation(ctx, "chat", time.Time{}, false); err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+
+	if strings.Contains(prompt, "oldest message") {
+		t.Error("Expected the oldest message to be left out")
+	}
+	if !strings.Contains(prompt, "assistant: paragraph 0\n\nmore of 0") {
+		t.Error("Expected the oldest kept message to be whole")
+	}
+}
+
+func TestIsSummarizeCommand(t *testing.T) {
+	if !isSummarizeCommand("/summarize 24 save") || !isSummarizeCommand("/summarize@bot") {
+		t.Error("Expected summarize command to be recognized")
+	}
+
+	if isSummarizeCommand("please /summarize") {
+		t.Error("Expected command only at the start of the message")
+	}
+}
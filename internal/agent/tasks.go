@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/scheduler"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func (a *Agent) registerTaskActions(taskManager *scheduler.TaskManager) {
+	taskManager.RegisterAction(scheduler.ActionAgentPrompt, a.newPromptTask)
+	taskManager.RegisterAction(scheduler.ActionToolCall, a.newToolCallTask)
+	taskManager.RegisterAction(scheduler.ActionConsolidateMemory, a.newConsolidateMemoryTask)
+}
+
+// newPromptTask sends the prompt to the agent as if the user had written it
+// in the chat, so the answer is delivered there.
+func (a *Agent) newPromptTask(action *scheduler.Action) (scheduler.TaskFunc, error) {
+	channel := action.Channel
+	if channel == "" {
+		channel = bus.ChannelTelegram
+	}
+
+	switch channel {
+	case bus.ChannelTelegram, bus.ChannelDiscord, bus.ChannelEmail, bus.ChannelWebSocket, bus.ChannelCLI:
+	default:
+		return nil, fmt.Errorf("unknown channel %q", channel)
+	}
+
+	return func(ctx context.Context) error {
+		err := a.HandleMessage(ctx, &bus.Message{
+			ID:        fmt.Sprintf("task-%d", time.Now().UnixNano()),
+			Channel:   channel,
+			ChatID:    action.ChatID,
+			Content:   action.Prompt,
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		// Keep the answer in the task history.
+		if history := a.getChatHistory(action.ChatID); len(history) > 0 && history[len(history)-1].Role == llm.RoleAssistant {
+			scheduler.ReportOutput(ctx, history[len(history)-1].Content)
+		}
+		return nil
+	}, nil
+}
+
+func (a *Agent) newToolCallTask(action *scheduler.Action) (scheduler.TaskFunc, error) {
+	return func(ctx context.Context) error {
+		if action.ChatID != "" {
+			ctx = tools.WithChatID(ctx, action.ChatID)
+		}
+
+		call, err := a.toolExecutor.Execute(ctx, action.Tool, action.Params)
+		if err != nil {
+			return fmt.Errorf("failed to run %s: %w", action.Tool, err)
+		}
+		if call.Error != "" {
+			return fmt.Errorf("%s failed: %s", action.Tool, call.Error)
+		}
+
+		a.logger.Info("Scheduled tool call completed", "tool", action.Tool, "preview", logging.Preview(call.Result, 80))
+		scheduler.ReportOutput(ctx, call.Result)
+		return nil
+	}, nil
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/scheduler"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestScheduledTaskActions(t *testing.T) {
+	ctx := context.Background()
+	server := newModelServer("Time for standup")
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	registry := tools.NewToolRegistry()
+	registry.Register(tools.NewEchoTool())
+
+	taskManager := scheduler.NewTaskManager(scheduler.NewScheduler(&scheduler.SchedulerConfig{TickInterval: time.Hour}),
+		&scheduler.TaskManagerConfig{TasksFile: filepath.Join(dir, "tasks.json")})
+	messageBus := &flakyBus{published: make(chan *bus.Message, 4)}
+	_, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   registry,
+		TaskManager:    taskManager,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	err = taskManager.AddActionTask(&scheduler.TaskConfig{
+		ID:       "standup",
+		Name:     "Standup",
+		CronExpr: "0 9 * * *",
+		Enabled:  true,
+		Action:   &scheduler.Action{Type: scheduler.ActionAgentPrompt, Channel: bus.ChannelWebSocket, ChatID: "chat", Prompt: "Remind me about standup"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add prompt task: %v", err)
+	}
+
+	task, _ := taskManager.GetTask("standup")
+	if err := task.Handler(ctx); err != nil {
+		t.Fatalf("Prompt task failed: %v", err)
+	}
+	reply := <-messageBus.published
+	if reply.Channel != bus.ChannelWebSocket || reply.ChatID != "chat" || reply.Content != "Time for standup" {
+		t.Errorf("Expected answer in the task's chat, got %+v", reply)
+	}
+
+	err = taskManager.AddActionTask(&scheduler.TaskConfig{
+		ID:       "echo",
+		Name:     "Echo",
+		CronExpr: "0 9 * * *",
+		Enabled:  true,
+		Action:   &scheduler.Action{Type: scheduler.ActionToolCall, Tool: "echo", Params: map[string]interface{}{"message": "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add tool task: %v", err)
+	}
+	task, _ = taskManager.GetTask("echo")
+	if err := task.Handler(ctx); err != nil {
+		t.Errorf("Tool task failed: %v", err)
+	}
+
+	taskManager.AddActionTask(&scheduler.TaskConfig{
+		ID:       "missing",
+		Name:     "Missing",
+		CronExpr: "0 9 * * *",
+		Enabled:  true,
+		Action:   &scheduler.Action{Type: scheduler.ActionToolCall, Tool: "no_such_tool"},
+	})
+	task, _ = taskManager.GetTask("missing")
+	if err := task.Handler(ctx); err == nil {
+		t.Error("Expected task calling an unknown tool to fail")
+	}
+
+	err = taskManager.AddActionTask(&scheduler.TaskConfig{
+		ID:       "bad-channel",
+		Name:     "Bad",
+		CronExpr: "0 9 * * *",
+		Action:   &scheduler.Action{Type: scheduler.ActionAgentPrompt, Channel: "fax", ChatID: "chat", Prompt: "hi"},
+	})
+	if err == nil {
+		t.Error("Expected unknown channel to be rejected")
+	}
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const toolsCommandUsage = "Usage: /tools diff [version] | /tools snapshot"
+
+func (a *Agent) RecordToolSnapshot(ctx context.Context) (*tools.ToolDiff, error) {
+	if a.toolRegistry == nil || a.toolSnapshots == nil {
+		return nil, fmt.Errorf("tool snapshots are not available")
+	}
+
+	return a.toolSnapshots.Record(ctx, tools.NewToolSnapshot(a.toolRegistry))
+}
+
+func (a *Agent) DiffTools(ctx context.Context, version int) (*tools.ToolDiff, error) {
+	if a.toolRegistry == nil || a.toolSnapshots == nil {
+		return nil, fmt.Errorf("tool snapshots are not available")
+	}
+
+	var snapshot *tools.ToolSnapshot
+	var err error
+	if version > 0 {
+		snapshot, err = a.toolSnapshots.Load(ctx, version)
+	} else {
+		snapshot, err = a.toolSnapshots.Latest(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tools.DiffToolSnapshots(snapshot, tools.NewToolSnapshot(a.toolRegistry)), nil
+}
+
+func isToolsCommand(content string) bool {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return false
+	}
+
+	command, _, _ := strings.Cut(fields[0], "@")
+	return command == "/tools"
+}
+
+func (a *Agent) handleToolsCommand(ctx context.Context, msg *bus.Message) error {
+	fields := strings.Fields(msg.Content)
+	if len(fields) < 2 {
+		return a.reply(ctx, msg, toolsCommandUsage)
+	}
+
+	switch fields[1] {
+	case "diff":
+		version := 0
+		if len(fields) > 2 {
+			v, err := strconv.Atoi(strings.TrimPrefix(fields[2], "v"))
+			if err != nil || v <= 0 {
+				return a.reply(ctx, msg, toolsCommandUsage)
+			}
+			version = v
+		}
+
+		diff, err := a.DiffTools(ctx, version)
+		if err != nil {
+			return a.reply(ctx, msg, fmt.Sprintf("Failed to diff tools: %v", err))
+		}
+		if diff.Empty() {
+			return a.reply(ctx, msg, fmt.Sprintf("No tool schema changes since v%d.", diff.FromVersion))
+		}
+		return a.reply(ctx, msg, diff.String())
+
+	case "snapshot":
+		if !isAdmin(msg) {
+			return a.reply(ctx, msg, "Only an admin can record tool snapshots.")
+		}
+
+		diff, err := a.RecordToolSnapshot(ctx)
+		if err != nil {
+			return a.reply(ctx, msg, fmt.Sprintf("Failed to record tool snapshot: %v", err))
+		}
+		if diff.Empty() && diff.FromVersion == diff.ToVersion {
+			return a.reply(ctx, msg, fmt.Sprintf("Tool schemas unchanged, snapshot v%d is current.", diff.ToVersion))
+		}
+		return a.reply(ctx, msg, fmt.Sprintf("Recorded tool snapshot v%d.\n%s", diff.ToVersion, diff))
+	}
+
+	return a.reply(ctx, msg, toolsCommandUsage)
+}
@@ -0,0 +1,300 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const (
+	transcriptDir = "transcripts"
+	// transcriptIDTime starts transcript IDs, so they sort by time and old
+	// ones can be pruned without reading them.
+	transcriptIDTime = "20060102-150405"
+
+	transcriptPruneInterval = time.Hour
+)
+
+var ErrTranscriptNotFound = errors.New("transcript not found")
+
+// Transcript records one run of the ReAct loop for a message: what the model
+// was given, what it answered in each iteration and what the tools returned.
+// The request of iteration n is the system prompt and Messages, followed by
+// the response and tool results of the iterations before it.
+type Transcript struct {
+	ID           string        `json:"id"`
+	MessageID    string        `json:"message_id,omitempty"`
+	Channel      string        `json:"channel,omitempty"`
+	ChatID       string        `json:"chat_id"`
+	Model        string        `json:"model"`
+	StartedAt    time.Time     `json:"started_at"`
+	Duration     int64         `json:"duration_ms"`
+	SystemPrompt string        `json:"system_prompt"`
+	Skills       []string      `json:"skills,omitempty"`
+	Messages     []llm.Message `json:"messages"`
+	Iterations   []Iteration   `json:"iterations"`
+	Response     string        `json:"response,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+type Iteration struct {
+	Response  string           `json:"response"`
+	Usage     llm.Usage        `json:"usage"`
+	Duration  int64            `json:"duration_ms"`
+	ToolCalls []tools.ToolCall `json:"tool_calls,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+func newTranscript(chatID string) *Transcript {
+	id := make([]byte, 3)
+	rand.Read(id)
+
+	now := time.Now().UTC()
+	return &Transcript{
+		ID:        now.Format(transcriptIDTime) + "-" + hex.EncodeToString(id),
+		ChatID:    chatID,
+		StartedAt: now,
+	}
+}
+
+// Usage adds up the tokens of all iterations.
+func (t *Transcript) Usage() llm.Usage {
+	var total llm.Usage
+	for _, iteration := range t.Iterations {
+		total.PromptTokens += iteration.Usage.PromptTokens
+		total.CompletionTokens += iteration.Usage.CompletionTokens
+		total.TotalTokens += iteration.Usage.TotalTokens
+	}
+	return total
+}
+
+// ToolCalls returns the tool calls of all iterations in order.
+func (t *Transcript) ToolCalls() []tools.ToolCall {
+	var calls []tools.ToolCall
+	for _, iteration := range t.Iterations {
+		calls = append(calls, iteration.ToolCalls...)
+	}
+	return calls
+}
+
+func (t *Transcript) finish(response string, err error) {
+	t.Duration = time.Since(t.StartedAt).Milliseconds()
+	t.Response = response
+	if err != nil {
+		t.Error = err.Error()
+	}
+}
+
+type transcriptKey struct{}
+
+func withTranscript(ctx context.Context, transcript *Transcript) context.Context {
+	return context.WithValue(ctx, transcriptKey{}, transcript)
+}
+
+func transcriptFromContext(ctx context.Context) *Transcript {
+	transcript, _ := ctx.Value(transcriptKey{}).(*Transcript)
+	return transcript
+}
+
+// TranscriptStore keeps transcripts as one JSON file each.
+type TranscriptStore struct {
+	storage storage.Storage
+}
+
+func NewTranscriptStore(storage storage.Storage) *TranscriptStore {
+	return &TranscriptStore{
+		storage: storage,
+	}
+}
+
+func (s *TranscriptStore) Add(ctx context.Context, transcript *Transcript) error {
+	data, err := json.MarshalIndent(transcript, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+
+	if err := s.storage.WriteFile(ctx, transcriptPath(transcript.ID), data); err != nil {
+		return fmt.Errorf("failed to store transcript %s: %w", transcript.ID, err)
+	}
+	return nil
+}
+
+func (s *TranscriptStore) Get(ctx context.Context, id string) (*Transcript, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("failed to read transcript %s: %w", id, ErrTranscriptNotFound)
+	}
+
+	exists, err := s.storage.FileExists(ctx, transcriptPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript %s: %w", id, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("failed to read transcript %s: %w", id, ErrTranscriptNotFound)
+	}
+
+	data, err := s.storage.ReadFile(ctx, transcriptPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript %s: %w", id, err)
+	}
+
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript %s: %w", id, err)
+	}
+	return &transcript, nil
+}
+
+// List returns the IDs of the stored transcripts, oldest first.
+func (s *TranscriptStore) List(ctx context.Context) ([]string, error) {
+	files, err := s.storage.ListFiles(ctx, transcriptDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcripts: %w", err)
+	}
+
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		name := path.Base(strings.ReplaceAll(file, "\\", "/"))
+		if strings.HasSuffix(name, ".json") {
+			ids = append(ids, strings.TrimSuffix(name, ".json"))
+		}
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Prune deletes the transcripts started before the given time and returns
+// how many it deleted.
+func (s *TranscriptStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	ids, err := s.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := before.UTC().Format(transcriptIDTime)
+	deleted := 0
+	for _, id := range ids {
+		if id >= cutoff {
+			break
+		}
+		if err := s.storage.DeleteFile(ctx, transcriptPath(id)); err != nil {
+			return deleted, fmt.Errorf("failed to delete transcript %s: %w", id, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func transcriptPath(id string) string {
+	return path.Join(transcriptDir, id+".json")
+}
+
+func (a *Agent) saveTranscript(ctx context.Context, transcript *Transcript) {
+	if err := a.transcripts.Add(context.WithoutCancel(ctx), transcript); err != nil {
+		a.logger.Error("Failed to save transcript", "chat_id", transcript.ChatID, "error", err)
+		return
+	}
+	a.logger.Debug("Saved transcript", "chat_id", transcript.ChatID, "transcript", transcript.ID)
+}
+
+func (a *Agent) pruneTranscripts() {
+	ticker := time.NewTicker(transcriptPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := a.transcripts.Prune(a.ctx, time.Now().Add(-a.transcriptRetention)); err != nil {
+			a.logger.Warn("Failed to prune transcripts", "error", err)
+		} else if count > 0 {
+			a.logger.Info("Pruned old transcripts", "count", count)
+		}
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Completer is the part of the LLM manager a replay needs.
+type Completer interface {
+	Complete(ctx context.Context, messages []llm.Message) (*llm.CompletionResponse, error)
+}
+
+// ReplayTranscript runs the ReAct loop of a transcript again with the given
+// completer, for comparing how another model or prompt handles the same
+// request. Tools are not run: a call the original run also made gets the
+// result recorded for it, any other call gets an error saying so.
+func ReplayTranscript(ctx context.Context, completer Completer, original *Transcript, maxIterations int) *Transcript {
+	replay := newTranscript(original.ChatID)
+	replay.MessageID = original.MessageID
+	replay.Channel = original.Channel
+	replay.SystemPrompt = original.SystemPrompt
+	replay.Skills = original.Skills
+	replay.Messages = original.Messages
+	if model, ok := llm.ModelFromContext(ctx); ok {
+		replay.Model = model
+	}
+
+	recorded := make(map[string][]tools.ToolCall)
+	for _, call := range original.ToolCalls() {
+		key := toolCallKey(call)
+		recorded[key] = append(recorded[key], call)
+	}
+
+	messages := append([]llm.Message{}, original.Messages...)
+	for i := 0; i < maxIterations; i++ {
+		started := time.Now()
+		resp, err := completer.Complete(ctx, append([]llm.Message{{Role: llm.RoleSystem, Content: original.SystemPrompt}}, messages...))
+		if err != nil {
+			replay.Iterations = append(replay.Iterations, Iteration{Duration: time.Since(started).Milliseconds(), Error: err.Error()})
+			replay.finish("", fmt.Errorf("failed to complete LLM request: %w", err))
+			return replay
+		}
+
+		iteration := Iteration{Response: resp.Content, Usage: resp.Usage, Duration: time.Since(started).Milliseconds()}
+		calls, isFinal, _ := parseReActResponse(resp.Content)
+		if isFinal || len(calls) == 0 {
+			replay.Iterations = append(replay.Iterations, iteration)
+			replay.finish(resp.Content, nil)
+			return replay
+		}
+
+		for _, call := range calls {
+			key := toolCallKey(call)
+			if previous := recorded[key]; len(previous) > 0 {
+				call.Result, call.Error = previous[0].Result, previous[0].Error
+				recorded[key] = previous[1:]
+			} else {
+				call.Error = "no result was recorded for this call, tools are not run in a replay"
+			}
+			iteration.ToolCalls = append(iteration.ToolCalls, call)
+		}
+		replay.Iterations = append(replay.Iterations, iteration)
+
+		results, _ := json.MarshalIndent(iteration.ToolCalls, "", "  ")
+		messages = append(messages,
+			llm.Message{Role: llm.RoleAssistant, Content: resp.Content},
+			llm.Message{Role: llm.RoleUser, Content: "Tool execution results:\n" + string(results)},
+		)
+	}
+
+	replay.finish("", fmt.Errorf("max iterations (%d) reached without final answer", maxIterations))
+	return replay
+}
+
+func toolCallKey(call tools.ToolCall) string {
+	input, _ := json.Marshal(call.Input)
+	return call.Name + " " + string(input)
+}
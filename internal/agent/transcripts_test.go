@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestTranscriptRecording(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	replies := []string{
+		`{"thought": "Check the time", "tool_calls": [{"name": "echo", "input": {"message": "tick"}}]}`,
+		`{"thought": "done", "final_answer": "It is tick o'clock"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		reply := replies[0]
+		replies = replies[1:]
+		mu.Unlock()
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`, reply)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+
+	registry := tools.NewToolRegistry()
+	registry.Register(tools.NewEchoTool())
+	store := NewTranscriptStore(fileStorage)
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL}},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   registry,
+		Transcripts:    store,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	if err := agent.HandleMessage(ctx, &bus.Message{ID: "m1", Channel: bus.ChannelTelegram, ChatID: "chat", Content: "What time is it?"}); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+	<-messageBus.published
+
+	ids, err := store.List(ctx)
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("Expected one transcript, got %v, %v", ids, err)
+	}
+	transcript, err := store.Get(ctx, ids[0])
+	if err != nil {
+		t.Fatalf("Failed to read transcript: %v", err)
+	}
+
+	if transcript.MessageID != "m1" || transcript.ChatID != "chat" || transcript.Model != "main" {
+		t.Errorf("Unexpected transcript header %+v", transcript)
+	}
+	if !strings.Contains(transcript.SystemPrompt, "You are helpful.") || lastMessage(transcript.Messages) != "What time is it?" {
+		t.Errorf("Expected the request in the transcript, got %q", transcript.Messages)
+	}
+	if len(transcript.Iterations) != 2 || transcript.Usage().TotalTokens != 30 {
+		t.Fatalf("Expected two iterations of 15 tokens, got %+v", transcript.Iterations)
+	}
+	calls := transcript.Iterations[0].ToolCalls
+	if len(calls) != 1 || calls[0].Name != "echo" || !strings.Contains(calls[0].Result, "tick") {
+		t.Errorf("Expected the echo call with its result, got %+v", calls)
+	}
+	if !strings.Contains(transcript.Response, "tick o'clock") {
+		t.Errorf("Expected the final response, got %q", transcript.Response)
+	}
+
+	// The replayed model calls the recorded tool and one that was not
+	// recorded, then answers differently.
+	completer := &scriptedCompleter{replies: []string{
+		`{"tool_calls": [{"name": "echo", "input": {"message": "tick"}}, {"name": "exec_command", "input": {"command": "date"}}]}`,
+		`{"final_answer": "tick"}`,
+	}}
+	replay := ReplayTranscript(llm.WithModel(ctx, "other"), completer, transcript, 5)
+
+	if replay.Model != "other" || replay.Response != `{"final_answer": "tick"}` || replay.Error != "" {
+		t.Errorf("Unexpected replay %+v", replay)
+	}
+	replayed := replay.ToolCalls()
+	if len(replayed) != 2 || replayed[0].Result != calls[0].Result || !strings.Contains(replayed[1].Error, "not run in a replay") {
+		t.Errorf("Expected the recorded result and an error for the new call, got %+v", replayed)
+	}
+	if first := completer.requests[0]; first[0].Role != llm.RoleSystem || first[0].Content != transcript.SystemPrompt {
+		t.Errorf("Expected the recorded system prompt to be replayed, got %+v", first[0])
+	}
+}
+
+func TestTranscriptPrune(t *testing.T) {
+	ctx := context.Background()
+	store := NewTranscriptStore(storage.NewFileStorage(t.TempDir()))
+
+	old := newTranscript("chat")
+	old.ID = time.Now().Add(-48*time.Hour).UTC().Format(transcriptIDTime) + "-000000"
+	recent := newTranscript("chat")
+	for _, transcript := range []*Transcript{old, recent} {
+		if err := store.Add(ctx, transcript); err != nil {
+			t.Fatalf("Failed to add transcript: %v", err)
+		}
+	}
+
+	if count, err := store.Prune(ctx, time.Now().Add(-24*time.Hour)); err != nil || count != 1 {
+		t.Fatalf("Expected one transcript to be pruned, got %d, %v", count, err)
+	}
+	if ids, _ := store.List(ctx); len(ids) != 1 || ids[0] != recent.ID {
+		t.Errorf("Expected the recent transcript to be kept, got %v", ids)
+	}
+	if _, err := store.Get(ctx, "../secrets"); err == nil {
+		t.Error("Expected an invalid ID to be rejected")
+	}
+}
+
+type scriptedCompleter struct {
+	replies  []string
+	requests [][]llm.Message
+}
+
+func (c *scriptedCompleter) Complete(ctx context.Context, messages []llm.Message) (*llm.CompletionResponse, error) {
+	c.requests = append(c.requests, messages)
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return &llm.CompletionResponse{Content: reply}, nil
+}
+
+func lastMessage(messages []llm.Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[len(messages)-1].Content
+}
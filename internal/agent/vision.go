@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+)
+
+// maxImageBytes is the largest image sent to the model, the limit of the
+// Anthropic API. Larger images are only described by their path.
+const maxImageBytes = 5 * 1024 * 1024
+
+// imageMediaTypes are the image formats both OpenAI and Anthropic accept.
+var imageMediaTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// imageParts reads the photos and image documents attached to a message, so
+// models with vision can look at them. Models without it get only the
+// attachment descriptions, since the manager leaves images out for them.
+func (a *Agent) imageParts(ctx context.Context, chatID string, attachments []bus.Attachment) []llm.ContentPart {
+	if a.storage == nil {
+		return nil
+	}
+
+	var parts []llm.ContentPart
+	for _, attachment := range attachments {
+		if attachment.Path == "" || !isImageAttachment(attachment) {
+			continue
+		}
+		if attachment.Size > maxImageBytes {
+			a.logger.Info("Image too large to show the model", "chat_id", chatID, "path", attachment.Path, "bytes", attachment.Size)
+			continue
+		}
+
+		data, err := a.storage.ReadFile(ctx, attachment.Path)
+		if err != nil {
+			a.logger.Warn("Failed to read image", "chat_id", chatID, "path", attachment.Path, "error", err)
+			continue
+		}
+		if len(data) > maxImageBytes {
+			continue
+		}
+
+		mediaType := http.DetectContentType(data)
+		if !imageMediaTypes[mediaType] {
+			a.logger.Info("Unsupported image format", "chat_id", chatID, "path", attachment.Path, "media_type", mediaType)
+			continue
+		}
+		parts = append(parts, llm.ImageDataPart(mediaType, data))
+	}
+
+	return parts
+}
+
+func isImageAttachment(attachment bus.Attachment) bool {
+	return attachment.Type == bus.AttachmentPhoto || strings.HasPrefix(attachment.MimeType, "image/")
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestPhotoSentToVisionModel(t *testing.T) {
+	ctx := context.Background()
+
+	requests := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- string(body)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"{\"final_answer\": \"A red bicycle.\"}"}}]}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileStorage := storage.NewFileStorage(dir)
+	fileStorage.WriteFile(ctx, "config/SOUL.md", []byte("You are helpful."))
+	fileStorage.WriteFile(ctx, "config/USER.md", []byte("User"))
+	fileStorage.WriteFile(ctx, "uploads/chat/photo.jpg", []byte("\xff\xd8\xff\xe0 jpeg"))
+	fileStorage.WriteFile(ctx, "uploads/chat/notes.txt", []byte("not an image"))
+
+	messageBus := &flakyBus{published: make(chan *bus.Message, 10)}
+	agent, err := NewAgent(&Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "main", Provider: "openai", APIKey: "key", Model: "gpt-4o", BaseURL: server.URL, Vision: true}},
+		DefaultModel:   "main",
+		SessionStorage: storage.NewFileSystemSessionStorage(dir),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(dir),
+		Storage:        fileStorage,
+		ToolRegistry:   tools.NewToolRegistry(),
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	msg := &bus.Message{ID: "m1", Channel: bus.ChannelTelegram, ChatID: "chat", Content: "What is this?", Metadata: map[string]interface{}{
+		bus.MetadataAttachments: []bus.Attachment{
+			{Type: bus.AttachmentPhoto, MimeType: "image/jpeg", Size: 9, Path: "uploads/chat/photo.jpg"},
+			{Type: bus.AttachmentDocument, MimeType: "text/plain", Size: 12, Path: "uploads/chat/notes.txt"},
+		},
+	}}
+	if err := agent.HandleMessage(ctx, msg); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+	<-messageBus.published
+
+	request := <-requests
+	if strings.Count(request, `"image_url"`) != 2 || !strings.Contains(request, `"url":"data:image/jpeg;base64,/9j/4CBqcGVn"`) {
+		t.Errorf("Expected only the photo as an image, got %s", request)
+	}
+
+	for _, message := range agent.getChatHistory("chat") {
+		if message.HasImages() {
+			t.Errorf("Expected the history without images, got %+v", message)
+		}
+	}
+}
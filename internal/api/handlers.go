@@ -0,0 +1,605 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/agent"
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/export"
+	"github.com/wjffsx/miniclaw_go/internal/scheduler"
+	"github.com/wjffsx/miniclaw_go/internal/skills"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const defaultMessageLimit = 50
+
+type sessionView struct {
+	ChatID   string            `json:"chat_id"`
+	Parent   string            `json:"parent,omitempty"`
+	Children []string          `json:"children,omitempty"`
+	Messages []storage.Message `json:"messages,omitempty"`
+}
+
+type taskView struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Schedule    string     `json:"schedule"`
+	Misfire     string     `json:"misfire,omitempty"`
+	Status      string     `json:"status"`
+	Enabled     bool       `json:"enabled"`
+	RunCount    int        `json:"run_count"`
+	ErrorCount  int        `json:"error_count"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+	Action      string     `json:"action,omitempty"`
+	ChatID      string     `json:"chat_id,omitempty"`
+}
+
+type mcpClientView struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Connected bool   `json:"connected"`
+	ToolCount int    `json:"tool_count"`
+	Error     string `json:"error,omitempty"`
+	Failures  int    `json:"failures,omitempty"`
+}
+
+type modelView struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Current  bool   `json:"current"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := map[string]interface{}{}
+
+	if llmManager := s.config.Agent.GetLLMManager(); llmManager != nil {
+		status["model"] = llmManager.GetCurrentModel()
+	}
+	if taskManager := s.config.Agent.GetTaskManager(); taskManager != nil {
+		status["tasks"] = len(taskManager.ListTasks())
+	}
+	if toolRegistry := s.config.Agent.GetToolRegistry(); toolRegistry != nil {
+		status["tools"] = len(toolRegistry.List())
+	}
+	if skillRegistry := s.config.Agent.GetSkillRegistry(); skillRegistry != nil {
+		status["skills"] = skillRegistry.CountAll()
+	}
+	status["sessions"] = s.config.Agent.SessionStats()
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	if s.config.SessionStorage == nil {
+		writeError(w, http.StatusServiceUnavailable, "session storage is not configured")
+		return
+	}
+
+	chatIDs, err := s.config.SessionStorage.ListSessions(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sort.Strings(chatIDs)
+
+	sessions := make([]sessionView, 0, len(chatIDs))
+	for _, chatID := range chatIDs {
+		sessions = append(sessions, sessionView{ChatID: chatID})
+	}
+
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	if s.config.SessionStorage == nil {
+		writeError(w, http.StatusServiceUnavailable, "session storage is not configured")
+		return
+	}
+
+	limit := defaultMessageLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+
+	chatID := r.PathValue("id")
+	messages, err := s.config.SessionStorage.GetMessages(r.Context(), chatID, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	lineage, err := s.config.Agent.GetSessionLineage(r.Context(), chatID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sessionView{ChatID: chatID, Parent: lineage.Parent, Children: lineage.Children, Messages: messages})
+}
+
+func (s *Server) handleForkSession(w http.ResponseWriter, r *http.Request) {
+	chatID := r.PathValue("id")
+
+	forkID, err := s.config.Agent.ForkSession(r.Context(), chatID)
+	if errors.Is(err, agent.ErrNothingToFork) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sessionView{ChatID: forkID, Parent: chatID})
+}
+
+func (s *Server) handleClearSession(w http.ResponseWriter, r *http.Request) {
+	chatID := r.PathValue("id")
+
+	s.config.Agent.ClearChatHistory(chatID)
+
+	if s.config.SessionStorage != nil {
+		if err := s.config.SessionStorage.ClearSession(r.Context(), chatID); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	taskManager := s.config.Agent.GetTaskManager()
+	if taskManager == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not enabled")
+		return
+	}
+
+	tasks := taskManager.ListTasks()
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+
+	views := make([]taskView, 0, len(tasks))
+	for _, task := range tasks {
+		views = append(views, newTaskView(task))
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+func newTaskView(task *scheduler.Task) taskView {
+	view := taskView{
+		ID:          task.ID,
+		Name:        task.Name,
+		Description: task.Description,
+		Schedule:    task.CronExpr,
+		Misfire:     task.Misfire,
+		Status:      string(task.Status),
+		Enabled:     task.Enabled,
+		RunCount:    task.RunCount,
+		ErrorCount:  task.ErrorCount,
+	}
+	if task.OneOff() {
+		view.Schedule = "once at " + task.RunAt.Format(time.RFC3339)
+	}
+	if task.LastError != nil {
+		view.LastError = task.LastError.Error()
+	}
+	if task.Action != nil {
+		view.Action = task.Action.Type
+		view.ChatID = task.Action.ChatID
+	}
+	if !task.LastRun.IsZero() {
+		lastRun := task.LastRun
+		view.LastRun = &lastRun
+	}
+	if !task.NextRun.IsZero() {
+		nextRun := task.NextRun
+		view.NextRun = &nextRun
+	}
+	return view
+}
+
+// handleAddTask schedules an agent_prompt task, described by a
+// scheduler.PromptTask.
+func (s *Server) handleAddTask(w http.ResponseWriter, r *http.Request) {
+	taskManager := s.config.Agent.GetTaskManager()
+	if taskManager == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not enabled")
+		return
+	}
+
+	var body scheduler.PromptTask
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	config, err := body.TaskConfig(time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := taskManager.AddActionTask(config); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	task, _ := taskManager.GetTask(config.ID)
+	writeJSON(w, http.StatusCreated, newTaskView(task))
+}
+
+func (s *Server) handleRemoveTask(w http.ResponseWriter, r *http.Request) {
+	taskManager := s.config.Agent.GetTaskManager()
+	if taskManager == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not enabled")
+		return
+	}
+
+	taskID := r.PathValue("id")
+	if _, ok := taskManager.GetTask(taskID); !ok {
+		writeError(w, http.StatusNotFound, "task not found")
+		return
+	}
+
+	if err := taskManager.RemoveTask(taskID); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleTaskHistory(w http.ResponseWriter, r *http.Request) {
+	taskManager := s.config.Agent.GetTaskManager()
+	if taskManager == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not enabled")
+		return
+	}
+
+	limit := defaultMessageLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = parsed
+	}
+
+	runs, err := taskManager.GetTaskHistory(r.PathValue("id"), limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *Server) handleRunTask(w http.ResponseWriter, r *http.Request) {
+	taskManager := s.config.Agent.GetTaskManager()
+	if taskManager == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not enabled")
+		return
+	}
+
+	taskID := r.PathValue("id")
+	if _, ok := taskManager.GetTask(taskID); !ok {
+		writeError(w, http.StatusNotFound, "task not found")
+		return
+	}
+
+	if err := taskManager.TriggerTask(taskID); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "triggered", "id": taskID})
+}
+
+func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
+	toolRegistry := s.config.Agent.GetToolRegistry()
+	if toolRegistry == nil {
+		writeJSON(w, http.StatusOK, []tools.ToolSchema{})
+		return
+	}
+
+	schemas := toolRegistry.GetSchemas()
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Name < schemas[j].Name })
+
+	writeJSON(w, http.StatusOK, schemas)
+}
+
+func (s *Server) handleListSkills(w http.ResponseWriter, r *http.Request) {
+	skillRegistry := s.config.Agent.GetSkillRegistry()
+	if skillRegistry == nil {
+		writeJSON(w, http.StatusOK, []*skills.Skill{})
+		return
+	}
+
+	list := skillRegistry.ListAll()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleListMCPClients(w http.ResponseWriter, r *http.Request) {
+	views := make([]mcpClientView, 0)
+
+	if mcpManager := s.config.Agent.GetMCPManager(); mcpManager != nil {
+		for _, status := range mcpManager.ListClients() {
+			views = append(views, mcpClientView{
+				Name:      status.Name,
+				State:     string(status.State),
+				Connected: status.Connected,
+				ToolCount: status.ToolCount,
+				Error:     status.Error,
+				Failures:  status.Failures,
+			})
+		}
+		sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) handleListModels(w http.ResponseWriter, r *http.Request) {
+	llmManager := s.config.Agent.GetLLMManager()
+	if llmManager == nil {
+		writeError(w, http.StatusServiceUnavailable, "no LLM manager configured")
+		return
+	}
+
+	current := llmManager.GetCurrentModel()
+	names := llmManager.ListModels()
+	sort.Strings(names)
+
+	views := make([]modelView, 0, len(names))
+	for _, name := range names {
+		modelConfig, err := llmManager.GetModelConfig(name)
+		if err != nil {
+			continue
+		}
+		views = append(views, modelView{
+			Name:     name,
+			Provider: modelConfig.Provider,
+			Model:    modelConfig.Model,
+			Current:  name == current,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) handleSwitchModel(w http.ResponseWriter, r *http.Request) {
+	llmManager := s.config.Agent.GetLLMManager()
+	if llmManager == nil {
+		writeError(w, http.StatusServiceUnavailable, "no LLM manager configured")
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil || body.Name == "" {
+		writeError(w, http.StatusBadRequest, `request body must be {"name": "<model>"}`)
+		return
+	}
+
+	if err := llmManager.SwitchModel(body.Name); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"current": body.Name})
+}
+
+type shareView struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ChatID    string    `json:"chat_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (s *Server) handleExportSession(w http.ResponseWriter, r *http.Request) {
+	s.writeExport(w, r, r.PathValue("id"), true)
+}
+
+func (s *Server) handleShareSession(w http.ResponseWriter, r *http.Request) {
+	if s.config.Exporter == nil || s.config.Shares == nil {
+		writeError(w, http.StatusServiceUnavailable, "sharing is not configured")
+		return
+	}
+
+	var body struct {
+		TTL string `json:"ttl"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, `request body must be {"ttl": "<duration>"}`)
+		return
+	}
+
+	var ttl time.Duration
+	if body.TTL != "" {
+		parsed, err := time.ParseDuration(body.TTL)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "ttl must be a positive duration such as 24h")
+			return
+		}
+		ttl = parsed
+	}
+
+	chatID := r.PathValue("id")
+	if _, err := s.config.Exporter.Export(r.Context(), chatID); err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	share, err := s.config.Shares.Create(r.Context(), chatID, ttl)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	writeJSON(w, http.StatusCreated, shareView{
+		Token:     share.Token,
+		URL:       fmt.Sprintf("%s://%s/share/%s", scheme, r.Host, share.Token),
+		ChatID:    share.ChatID,
+		ExpiresAt: share.ExpiresAt,
+	})
+}
+
+func (s *Server) handleSharedSession(w http.ResponseWriter, r *http.Request) {
+	if s.config.Shares == nil {
+		writeError(w, http.StatusNotFound, export.ErrShareNotFound.Error())
+		return
+	}
+
+	share, err := s.config.Shares.Resolve(r.Context(), r.PathValue("token"))
+	switch {
+	case errors.Is(err, export.ErrShareNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, export.ErrShareExpired):
+		writeError(w, http.StatusGone, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeExport(w, r, share.ChatID, false)
+}
+
+func (s *Server) writeExport(w http.ResponseWriter, r *http.Request, chatID string, download bool) {
+	if s.config.Exporter == nil {
+		writeError(w, http.StatusServiceUnavailable, "export is not configured")
+		return
+	}
+
+	page, err := s.config.Exporter.Export(r.Context(), chatID)
+	if err != nil {
+		writeExportError(w, err)
+		return
+	}
+
+	if download {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "conversation-" + chatID + ".html"}))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src data:; style-src 'unsafe-inline'")
+	w.WriteHeader(http.StatusOK)
+	w.Write(page)
+}
+
+func writeExportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, export.ErrEmptySession):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, export.ErrInvalidChatID):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (s *Server) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if s.config.DeadLetters == nil {
+		writeError(w, http.StatusServiceUnavailable, "dead-letter queue is not enabled")
+		return
+	}
+
+	letters, err := s.config.DeadLetters.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, letters)
+}
+
+func (s *Server) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if s.config.DeadLetters == nil {
+		writeError(w, http.StatusServiceUnavailable, "dead-letter queue is not enabled")
+		return
+	}
+
+	letter, err := s.config.DeadLetters.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, letter)
+}
+
+func (s *Server) handleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if s.config.DeadLetters == nil || s.config.MessageBus == nil {
+		writeError(w, http.StatusServiceUnavailable, "dead-letter queue is not enabled")
+		return
+	}
+
+	letter, err := s.config.DeadLetters.Replay(r.Context(), s.config.MessageBus, r.PathValue("id"))
+	if err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	logger.Info("Replayed dead letter", "id", letter.ID, "channel", letter.Channel, "chat_id", letter.ChatID)
+	writeJSON(w, http.StatusAccepted, letter)
+}
+
+func (s *Server) handleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if s.config.DeadLetters == nil {
+		writeError(w, http.StatusServiceUnavailable, "dead-letter queue is not enabled")
+		return
+	}
+
+	if err := s.config.DeadLetters.Delete(r.Context(), r.PathValue("id")); err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeDeadLetterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, bus.ErrDeadLetterNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/agent"
+	"github.com/wjffsx/miniclaw_go/internal/auth"
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/export"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+var logger = logging.For("api")
+
+type Config struct {
+	Host           string
+	Port           int
+	Agent          *agent.Agent
+	SessionStorage storage.SessionStorage
+	Auth           *auth.Authenticator
+	Exporter       *export.Exporter
+	Shares         *export.ShareStore
+	MessageBus     bus.MessageBus
+	DeadLetters    *bus.DeadLetterStore
+	// Webhooks handles POST /hooks/{name}. The hooks check their own
+	// secrets, so the route is not behind the API's authentication.
+	Webhooks http.Handler
+}
+
+type Server struct {
+	config *Config
+	server *http.Server
+	mu     sync.Mutex
+}
+
+func NewServer(config *Config) (*Server, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	if config.Agent == nil {
+		return nil, fmt.Errorf("agent cannot be nil")
+	}
+
+	return &Server{config: config}, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// API keys and JWTs carry scopes: metrics:read only reaches the
+	// statistics, anything that exposes or changes conversations needs admin.
+	stats := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, s.protect(auth.RoleViewer, auth.ScopeMetricsRead, handler))
+	}
+	read := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, s.protect(auth.RoleViewer, auth.ScopeAdmin, handler))
+	}
+	write := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, s.protect(auth.RoleOperator, auth.ScopeAdmin, handler))
+	}
+	admin := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, s.protect(auth.RoleAdmin, auth.ScopeAdmin, handler))
+	}
+
+	stats("GET /api/status", s.handleStatus)
+
+	read("GET /api/sessions", s.handleListSessions)
+	read("GET /api/sessions/{id}", s.handleGetSession)
+	write("DELETE /api/sessions/{id}", s.handleClearSession)
+	write("POST /api/sessions/{id}/fork", s.handleForkSession)
+	read("GET /api/sessions/{id}/export", s.handleExportSession)
+	write("POST /api/sessions/{id}/share", s.handleShareSession)
+	mux.HandleFunc("GET /share/{token}", s.handleSharedSession)
+
+	read("GET /api/tasks", s.handleListTasks)
+	write("POST /api/tasks", s.handleAddTask)
+	write("DELETE /api/tasks/{id}", s.handleRemoveTask)
+	read("GET /api/tasks/{id}/history", s.handleTaskHistory)
+	write("POST /api/tasks/{id}/run", s.handleRunTask)
+
+	read("GET /api/tools", s.handleListTools)
+	read("GET /api/skills", s.handleListSkills)
+	read("GET /api/mcp", s.handleListMCPClients)
+
+	stats("GET /api/models", s.handleListModels)
+	write("PUT /api/models/current", s.handleSwitchModel)
+
+	read("GET /api/deadletters", s.handleListDeadLetters)
+	read("GET /api/deadletters/{id}", s.handleGetDeadLetter)
+	write("POST /api/deadletters/{id}/replay", s.handleReplayDeadLetter)
+	write("DELETE /api/deadletters/{id}", s.handleDeleteDeadLetter)
+
+	if s.config.Auth != nil && s.config.Auth.APIKeys() != nil {
+		admin("GET /api/apikeys", s.handleListAPIKeys)
+		admin("POST /api/apikeys", s.handleCreateAPIKey)
+		admin("POST /api/apikeys/{id}/rotate", s.handleRotateAPIKey)
+		admin("DELETE /api/apikeys/{id}", s.handleRevokeAPIKey)
+	}
+
+	if s.config.Webhooks != nil {
+		mux.Handle("POST /hooks/{name}", s.config.Webhooks)
+	}
+
+	if s.config.Auth != nil && s.config.Auth.OIDC() != nil {
+		oidc := s.config.Auth.OIDC()
+		mux.Handle("GET /auth/login", oidc.LoginHandler())
+		mux.Handle("GET /auth/callback", oidc.CallbackHandler())
+		mux.Handle("/auth/logout", oidc.LogoutHandler())
+	}
+
+	return mux
+}
+
+func (s *Server) protect(role auth.Role, scope auth.Scope, handler http.HandlerFunc) http.Handler {
+	if s.config.Auth == nil {
+		return handler
+	}
+	return s.config.Auth.Require(role, scope, handler)
+}
+
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		return fmt.Errorf("server already started")
+	}
+
+	addr := net.JoinHostPort(s.config.Host, fmt.Sprintf("%d", s.config.Port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	if s.config.Auth == nil || !s.config.Auth.Enabled() {
+		if ip := net.ParseIP(s.config.Host); ip == nil || !ip.IsLoopback() {
+			logger.Warn("API server has no authentication configured", "addr", addr)
+		}
+	}
+
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	logger.Info("API server listening", "addr", addr)
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("API server error", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	server := s.server
+	s.server = nil
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	logger.Info("Stopping API server")
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to stop API server: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("Failed to write API response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
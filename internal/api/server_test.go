@@ -0,0 +1,569 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/agent"
+	"github.com/wjffsx/miniclaw_go/internal/auth"
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/export"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/scheduler"
+	"github.com/wjffsx/miniclaw_go/internal/skills"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func newTestServer(t *testing.T, authenticator *auth.Authenticator) (*Server, storage.SessionStorage) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	toolRegistry := tools.NewToolRegistry()
+	if err := toolRegistry.Register(tools.NewGetTimeTool()); err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+
+	skillRegistry := skills.NewSkillRegistry(storage.NewFileStorage(t.TempDir()))
+	if err := skillRegistry.Register(skills.NewSkill("weather", "Check the weather", "utility")); err != nil {
+		t.Fatalf("failed to register skill: %v", err)
+	}
+
+	sessionStorage := storage.NewFileSystemSessionStorage(t.TempDir())
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+
+	a, err := agent.NewAgent(&agent.Config{
+		LLMModels: []*llm.ModelConfig{
+			{Name: "small", Provider: "ollama", Model: "llama3.2", BaseURL: "http://127.0.0.1:1"},
+			{Name: "large", Provider: "ollama", Model: "llama3.1:70b", BaseURL: "http://127.0.0.1:1"},
+		},
+		DefaultModel:   "small",
+		SessionStorage: sessionStorage,
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(t.TempDir()),
+		ToolRegistry:   toolRegistry,
+		SkillRegistry:  skillRegistry,
+	}, messageBus, ctx)
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	exporter, err := export.NewExporter(&export.Config{SessionStorage: sessionStorage})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+
+	server, err := NewServer(&Config{
+		Agent:          a,
+		SessionStorage: sessionStorage,
+		Auth:           authenticator,
+		Exporter:       exporter,
+		Shares:         export.NewShareStore(storage.NewFileStorage(t.TempDir())),
+		MessageBus:     messageBus,
+		DeadLetters:    bus.NewDeadLetterStore(storage.NewFileStorage(t.TempDir())),
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	return server, sessionStorage
+}
+
+func doRequest(t *testing.T, handler http.Handler, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSessionsEndpoints(t *testing.T) {
+	server, sessionStorage := newTestServer(t, nil)
+	handler := server.Handler()
+	ctx := context.Background()
+
+	for _, content := range []string{"hello", "how are you"} {
+		if err := sessionStorage.SaveMessage(ctx, "chat-1", "user", content); err != nil {
+			t.Fatalf("failed to save message: %v", err)
+		}
+	}
+
+	rec := doRequest(t, handler, http.MethodGet, "/api/sessions", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list sessions returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var sessions []sessionView
+	if err := json.Unmarshal(rec.Body.Bytes(), &sessions); err != nil {
+		t.Fatalf("failed to decode sessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ChatID != "chat-1" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/sessions/chat-1?limit=1", "", nil)
+	var session sessionView
+	if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+		t.Fatalf("failed to decode session: %v", err)
+	}
+	if len(session.Messages) != 1 || session.Messages[0].Content != "how are you" {
+		t.Fatalf("expected the latest message only, got %+v", session.Messages)
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/sessions/chat-1?limit=abc", "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit returned %d, want 400", rec.Code)
+	}
+
+	rec = doRequest(t, handler, http.MethodPost, "/api/sessions/chat-1/fork", "", nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("fork session returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var fork sessionView
+	if err := json.Unmarshal(rec.Body.Bytes(), &fork); err != nil {
+		t.Fatalf("failed to decode fork: %v", err)
+	}
+	if fork.ChatID != "chat-1_fork1" || fork.Parent != "chat-1" {
+		t.Fatalf("unexpected fork: %+v", fork)
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/sessions/chat-1_fork1", "", nil)
+	session = sessionView{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+		t.Fatalf("failed to decode session: %v", err)
+	}
+	if session.Parent != "chat-1" || len(session.Messages) != 2 || session.Messages[0].Content != "hello" {
+		t.Errorf("expected the fork to hold the copied history, got %+v", session)
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/sessions/chat-1", "", nil)
+	session = sessionView{}
+	json.Unmarshal(rec.Body.Bytes(), &session)
+	if len(session.Children) != 1 || session.Children[0] != "chat-1_fork1" || len(session.Messages) != 2 {
+		t.Errorf("expected the original to list its fork and keep its messages, got %+v", session)
+	}
+
+	if rec := doRequest(t, handler, http.MethodPost, "/api/sessions/empty/fork", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("forking an empty session returned %d, want 404", rec.Code)
+	}
+
+	rec = doRequest(t, handler, http.MethodDelete, "/api/sessions/chat-1", "", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("clear session returned %d: %s", rec.Code, rec.Body.String())
+	}
+
+	messages, err := sessionStorage.GetMessages(ctx, "chat-1", 10)
+	if err != nil {
+		t.Fatalf("failed to read messages: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("expected session to be cleared, got %d messages", len(messages))
+	}
+}
+
+func TestRegistryEndpoints(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	handler := server.Handler()
+
+	rec := doRequest(t, handler, http.MethodGet, "/api/tools", "", nil)
+	var schemas []tools.ToolSchema
+	if err := json.Unmarshal(rec.Body.Bytes(), &schemas); err != nil {
+		t.Fatalf("failed to decode tools: %v", err)
+	}
+	found := false
+	for _, schema := range schemas {
+		found = found || schema.Name == "get_time"
+	}
+	if !found {
+		t.Errorf("expected get_time in tools, got %+v", schemas)
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/skills", "", nil)
+	var list []skills.Skill
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode skills: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "weather" {
+		t.Errorf("unexpected skills: %+v", list)
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/mcp", "", nil)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("expected no MCP clients, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/tasks", "", nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("tasks without scheduler returned %d, want 503", rec.Code)
+	}
+}
+
+func TestTaskEndpoints(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	taskManager := scheduler.NewTaskManager(scheduler.NewScheduler(nil), &scheduler.TaskManagerConfig{
+		TasksFile: t.TempDir() + "/tasks.json",
+	})
+	a, err := agent.NewAgent(&agent.Config{
+		LLMModels:      []*llm.ModelConfig{{Name: "small", Provider: "ollama", Model: "llama3.2", BaseURL: "http://127.0.0.1:1"}},
+		DefaultModel:   "small",
+		SessionStorage: storage.NewFileSystemSessionStorage(t.TempDir()),
+		MemoryStorage:  storage.NewFileSystemMemoryStorage(t.TempDir()),
+		TaskManager:    taskManager,
+	}, bus.NewInMemoryMessageBus(ctx, nil), ctx)
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	server, err := NewServer(&Config{Agent: a})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	handler := server.Handler()
+
+	rec := doRequest(t, handler, http.MethodPost, "/api/tasks", `{"schedule": "0 9 * * 1-5", "prompt": "Plan my day", "chat_id": "chat-1"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add task returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var created taskView
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode task: %v", err)
+	}
+	if created.Name != "Plan my day" || created.Schedule != "0 9 * * 1-5" || created.ChatID != "chat-1" || created.Action != scheduler.ActionAgentPrompt || created.NextRun == nil {
+		t.Errorf("unexpected task: %+v", created)
+	}
+
+	rec = doRequest(t, handler, http.MethodPost, "/api/tasks", `{"schedule": "someday", "prompt": "Plan my day", "chat_id": "chat-1"}`, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid schedule returned %d, want 400", rec.Code)
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/tasks", "", nil)
+	var tasks []taskView
+	if err := json.Unmarshal(rec.Body.Bytes(), &tasks); err != nil || len(tasks) != 1 || tasks[0].ID != created.ID {
+		t.Errorf("expected the new task to be listed, got %s", rec.Body.String())
+	}
+
+	if rec := doRequest(t, handler, http.MethodDelete, "/api/tasks/"+created.ID, "", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("remove task returned %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, handler, http.MethodDelete, "/api/tasks/"+created.ID, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("removing a missing task returned %d, want 404", rec.Code)
+	}
+}
+
+func TestSwitchModel(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	handler := server.Handler()
+
+	rec := doRequest(t, handler, http.MethodPut, "/api/models/current", `{"name": "large"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("switch model returned %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/models", "", nil)
+	var models []modelView
+	if err := json.Unmarshal(rec.Body.Bytes(), &models); err != nil {
+		t.Fatalf("failed to decode models: %v", err)
+	}
+	if len(models) != 2 || models[0].Name != "large" || !models[0].Current || models[1].Current {
+		t.Errorf("expected large to be current, got %+v", models)
+	}
+
+	rec = doRequest(t, handler, http.MethodPut, "/api/models/current", `{"name": "missing"}`, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown model returned %d, want 404", rec.Code)
+	}
+
+	rec = doRequest(t, handler, http.MethodPut, "/api/models/current", `{}`, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("empty body returned %d, want 400", rec.Code)
+	}
+}
+
+func TestEndpointsRequireRoles(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(context.Background(), &auth.Config{
+		Tokens: []auth.TokenConfig{
+			{Name: "dashboard", Token: "viewer-token", Roles: []string{"viewer"}},
+			{Name: "ops", Token: "operator-token", Roles: []string{"operator"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+
+	server, _ := newTestServer(t, authenticator)
+	handler := server.Handler()
+
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header http.Header
+		want   int
+	}{
+		{"anonymous read", http.MethodGet, "/api/tools", "", nil, http.StatusUnauthorized},
+		{"viewer read", http.MethodGet, "/api/tools", "", bearer("viewer-token"), http.StatusOK},
+		{"viewer write", http.MethodPut, "/api/models/current", `{"name": "large"}`, bearer("viewer-token"), http.StatusForbidden},
+		{"operator write", http.MethodPut, "/api/models/current", `{"name": "large"}`, bearer("operator-token"), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, handler, tt.method, tt.path, tt.body, tt.header)
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	ctx := context.Background()
+	store := auth.NewAPIKeyStore(storage.NewFileStorage(t.TempDir()))
+	_, metricsKey, _ := store.Create(ctx, "grafana", []auth.Scope{auth.ScopeMetricsRead})
+	_, adminKey, _ := store.Create(ctx, "ops", []auth.Scope{auth.ScopeAdmin})
+
+	authenticator, err := auth.NewAuthenticator(ctx, &auth.Config{APIKeys: store})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+
+	server, sessionStorage := newTestServer(t, authenticator)
+	handler := server.Handler()
+	if err := sessionStorage.SaveMessage(ctx, "chat-1", "user", "my password is hunter2"); err != nil {
+		t.Fatalf("failed to save message: %v", err)
+	}
+
+	key := func(secret string) http.Header {
+		return http.Header{"X-Api-Key": {secret}}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		want   int
+	}{
+		{"metrics status", http.MethodGet, "/api/status", key(metricsKey), http.StatusOK},
+		{"metrics models", http.MethodGet, "/api/models", key(metricsKey), http.StatusOK},
+		{"metrics session", http.MethodGet, "/api/sessions/chat-1", key(metricsKey), http.StatusForbidden},
+		{"metrics export", http.MethodGet, "/api/sessions/chat-1/export", key(metricsKey), http.StatusForbidden},
+		{"metrics dead letters", http.MethodGet, "/api/deadletters", key(metricsKey), http.StatusForbidden},
+		{"metrics api keys", http.MethodGet, "/api/apikeys", key(metricsKey), http.StatusForbidden},
+		{"admin export", http.MethodGet, "/api/sessions/chat-1/export", key(adminKey), http.StatusOK},
+		{"admin api keys", http.MethodGet, "/api/apikeys", key(adminKey), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, handler, tt.method, tt.path, "", tt.header)
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIKeyEndpoints(t *testing.T) {
+	ctx := context.Background()
+	store := auth.NewAPIKeyStore(storage.NewFileStorage(t.TempDir()))
+	_, adminKey, _ := store.Create(ctx, "ops", []auth.Scope{auth.ScopeAdmin})
+
+	authenticator, err := auth.NewAuthenticator(ctx, &auth.Config{APIKeys: store})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+
+	server, _ := newTestServer(t, authenticator)
+	handler := server.Handler()
+	admin := http.Header{"X-Api-Key": {adminKey}}
+
+	rec := doRequest(t, handler, http.MethodPost, "/api/apikeys", `{"name": "grafana", "scopes": ["metrics:read"]}`, admin)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create api key returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var created apiKeyView
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode api key: %v", err)
+	}
+	if created.Key == "" || created.Name != "grafana" {
+		t.Fatalf("unexpected api key: %+v", created)
+	}
+
+	status := func(secret string) int {
+		return doRequest(t, handler, http.MethodGet, "/api/status", "", http.Header{"X-Api-Key": {secret}}).Code
+	}
+	if code := status(created.Key); code != http.StatusOK {
+		t.Errorf("expected the new key to work, got %d", code)
+	}
+
+	rec = doRequest(t, handler, http.MethodPost, "/api/apikeys/"+created.ID+"/rotate", "", admin)
+	var rotated apiKeyView
+	if err := json.Unmarshal(rec.Body.Bytes(), &rotated); err != nil || rotated.Key == "" || rotated.Key == created.Key {
+		t.Fatalf("unexpected rotated key: %d %s", rec.Code, rec.Body.String())
+	}
+	if code := status(created.Key); code != http.StatusUnauthorized {
+		t.Errorf("expected the old secret to be rejected after rotation, got %d", code)
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/api/apikeys", "", admin)
+	if strings.Contains(rec.Body.String(), rotated.Key) || strings.Contains(rec.Body.String(), `"hash"`) {
+		t.Errorf("expected listed keys to leave out secrets: %s", rec.Body.String())
+	}
+
+	if rec := doRequest(t, handler, http.MethodDelete, "/api/apikeys/"+created.ID, "", admin); rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke api key returned %d: %s", rec.Code, rec.Body.String())
+	}
+	if code := status(rotated.Key); code != http.StatusUnauthorized {
+		t.Errorf("expected the revoked key to be rejected, got %d", code)
+	}
+
+	if rec := doRequest(t, handler, http.MethodPost, "/api/apikeys/nope/rotate", "", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("expected unknown key to return 404, got %d", rec.Code)
+	}
+	if rec := doRequest(t, handler, http.MethodPost, "/api/apikeys", `{"name": "x", "scopes": ["root"]}`, admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected unknown scope to return 400, got %d", rec.Code)
+	}
+}
+
+func TestDeadLetterEndpoints(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	handler := server.Handler()
+	ctx := context.Background()
+
+	received := make(chan *bus.Message, 1)
+	server.config.MessageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+	server.config.MessageBus.(*bus.InMemoryMessageBus).Start()
+	t.Cleanup(func() { server.config.MessageBus.Close() })
+
+	letter := bus.NewDeadLetter(&bus.Message{ID: "msg-1", Channel: bus.ChannelTelegram, ChatID: "chat-1", Content: "hello"}, errors.New("provider down"), 3)
+	if err := server.config.DeadLetters.Add(ctx, letter); err != nil {
+		t.Fatalf("failed to add dead letter: %v", err)
+	}
+
+	rec := doRequest(t, handler, http.MethodGet, "/api/deadletters", "", nil)
+	var letters []bus.DeadLetter
+	if err := json.Unmarshal(rec.Body.Bytes(), &letters); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("list dead letters returned %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(letters) != 1 || letters[0].ID != letter.ID || letters[0].Error != "provider down" {
+		t.Errorf("unexpected dead letters: %+v", letters)
+	}
+
+	if rec := doRequest(t, handler, http.MethodGet, "/api/deadletters/nope", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown dead letter, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, handler, http.MethodPost, "/api/deadletters/"+letter.ID+"/replay", "", nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("replay returned %d: %s", rec.Code, rec.Body.String())
+	}
+	select {
+	case msg := <-received:
+		if msg.ID != "msg-1" || msg.ChatID != "chat-1" || msg.Content != "hello" {
+			t.Errorf("unexpected replayed message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the replayed message")
+	}
+
+	if rec := doRequest(t, handler, http.MethodGet, "/api/deadletters/"+letter.ID, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected the replayed letter to be gone, got %d", rec.Code)
+	}
+	if rec := doRequest(t, handler, http.MethodDelete, "/api/deadletters/"+letter.ID, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting a replayed letter, got %d", rec.Code)
+	}
+}
+
+func TestExportAndShareSession(t *testing.T) {
+	server, sessionStorage := newTestServer(t, nil)
+	handler := server.Handler()
+	ctx := context.Background()
+
+	sessionStorage.SaveMessage(ctx, "chat-1", "user", "Show me <b>code</b>")
+	sessionStorage.SaveMessage(ctx, "chat-1", "assistant", "```go\nfunc main() {}\n```")
+
+	rec := doRequest(t, handler, http.MethodGet, "/api/sessions/chat-1/export", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "conversation-chat-1.html") {
+		t.Errorf("Expected attachment disposition, got %q", rec.Header().Get("Content-Disposition"))
+	}
+	if !strings.Contains(rec.Body.String(), "&lt;b&gt;code&lt;/b&gt;") || !strings.Contains(rec.Body.String(), `<span class="keyword">func</span>`) {
+		t.Errorf("Unexpected export body %s", rec.Body.String())
+	}
+
+	if rec := doRequest(t, handler, http.MethodGet, "/api/sessions/missing/export", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an empty session, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, handler, http.MethodPost, "/api/sessions/chat-1/share", `{"ttl": "1h"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var share shareView
+	if err := json.NewDecoder(rec.Body).Decode(&share); err != nil {
+		t.Fatalf("Failed to decode share: %v", err)
+	}
+	if !strings.HasSuffix(share.URL, "/share/"+share.Token) || share.ChatID != "chat-1" {
+		t.Errorf("Unexpected share %+v", share)
+	}
+
+	rec = doRequest(t, handler, http.MethodGet, "/share/"+share.Token, "", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Conversation chat-1") {
+		t.Errorf("Expected shared page, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Disposition") != "" {
+		t.Error("Expected shared page to be displayed inline")
+	}
+
+	if rec := doRequest(t, handler, http.MethodGet, "/share/0123456789abcdef0123456789abcdef", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown share, got %d", rec.Code)
+	}
+	if rec := doRequest(t, handler, http.MethodPost, "/api/sessions/chat-1/share", `{"ttl": "-1h"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative ttl, got %d", rec.Code)
+	}
+}
+
+func TestWebhooksSkipAPIAuth(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(context.Background(), &auth.Config{
+		Tokens: []auth.TokenConfig{{Name: "ops", Token: "operator-token", Roles: []string{"operator"}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+
+	server, _ := newTestServer(t, authenticator)
+	server.config.Webhooks = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Hook", r.PathValue("name"))
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	rec := doRequest(t, server.Handler(), http.MethodPost, "/hooks/github", `{}`, nil)
+	if rec.Code != http.StatusAccepted || rec.Header().Get("X-Hook") != "github" {
+		t.Errorf("Expected the hook to handle the request itself, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const (
+	apiKeyPrefix = "mc_"
+	apiKeysFile  = "auth/api_keys.json"
+)
+
+type Scope string
+
+const (
+	ScopeChat        Scope = "chat"
+	ScopeAdmin       Scope = "admin"
+	ScopeMetricsRead Scope = "metrics:read"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+func ParseScope(value string) (Scope, error) {
+	scope := Scope(strings.ToLower(strings.TrimSpace(value)))
+	switch scope {
+	case ScopeChat, ScopeAdmin, ScopeMetricsRead:
+		return scope, nil
+	}
+	return "", fmt.Errorf("unknown scope: %s", value)
+}
+
+func ParseScopes(values []string) ([]Scope, error) {
+	scopes := make([]Scope, 0, len(values))
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		scope, err := ParseScope(value)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+func rolesForScopes(scopes []Scope) []Role {
+	for _, scope := range scopes {
+		if scope == ScopeAdmin {
+			return []Role{RoleAdmin}
+		}
+	}
+	for _, scope := range scopes {
+		if scope == ScopeMetricsRead {
+			return []Role{RoleViewer}
+		}
+	}
+	return nil
+}
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"hash"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+type APIKeyStore struct {
+	storage storage.Storage
+	mu      sync.Mutex
+}
+
+func NewAPIKeyStore(storage storage.Storage) *APIKeyStore {
+	return &APIKeyStore{
+		storage: storage,
+	}
+}
+
+func (s *APIKeyStore) Create(ctx context.Context, name string, scopes []Scope) (*APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", fmt.Errorf("api key name cannot be empty")
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.load(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	id, err := randomHex(6)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &APIKey{
+		ID:        id,
+		Name:      name,
+		Prefix:    secret[:len(apiKeyPrefix)+6],
+		Hash:      hashAPIKey(secret),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	keys = append(keys, key)
+
+	if err := s.save(ctx, keys); err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+func (s *APIKeyStore) List(ctx context.Context) ([]*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(ctx)
+}
+
+func (s *APIKeyStore) Rotate(ctx context.Context, id string) (*APIKey, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.load(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := findAPIKey(keys, id)
+	if key == nil || key.Revoked() {
+		return nil, "", fmt.Errorf("failed to rotate api key %s: %w", id, ErrAPIKeyNotFound)
+	}
+
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	key.Prefix = secret[:len(apiKeyPrefix)+6]
+	key.Hash = hashAPIKey(secret)
+	key.RotatedAt = &now
+
+	if err := s.save(ctx, keys); err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+func (s *APIKeyStore) Revoke(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := findAPIKey(keys, id)
+	if key == nil {
+		return fmt.Errorf("failed to revoke api key %s: %w", id, ErrAPIKeyNotFound)
+	}
+	if key.Revoked() {
+		return nil
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+
+	return s.save(ctx, keys)
+}
+
+func (s *APIKeyStore) Name() string {
+	return "apikey"
+}
+
+// Keys are re-read on every request so that keys revoked or rotated from the
+// CLI take effect without restarting the server.
+func (s *APIKeyStore) Authenticate(r *http.Request) (*Identity, error) {
+	secret := apiKeyFromRequest(r)
+	if secret == "" {
+		return nil, ErrNoCredentials
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.load(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashAPIKey(secret)
+	for _, key := range keys {
+		if key.Revoked() || subtle.ConstantTimeCompare([]byte(hash), []byte(key.Hash)) != 1 {
+			continue
+		}
+
+		now := time.Now()
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+			key.LastUsedAt = &now
+			if err := s.save(r.Context(), keys); err != nil {
+				return nil, err
+			}
+		}
+
+		return &Identity{
+			Subject:  key.ID,
+			Name:     key.Name,
+			Provider: s.Name(),
+			Roles:    rolesForScopes(key.Scopes),
+			Scopes:   key.Scopes,
+		}, nil
+	}
+
+	return nil, ErrInvalidCredentials
+}
+
+func (s *APIKeyStore) load(ctx context.Context) ([]*APIKey, error) {
+	exists, err := s.storage.FileExists(ctx, apiKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check api keys: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	data, err := s.storage.ReadFile(ctx, apiKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api keys: %w", err)
+	}
+
+	var keys []*APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse api keys: %w", err)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+func (s *APIKeyStore) save(ctx context.Context, keys []*APIKey) error {
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal api keys: %w", err)
+	}
+
+	if err := s.storage.WriteFile(ctx, apiKeysFile, data); err != nil {
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+
+	return nil
+}
+
+func findAPIKey(keys []*APIKey, id string) *APIKey {
+	for _, key := range keys {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+
+	header := r.Header.Get("Authorization")
+	if len(header) >= 7 && strings.EqualFold(header[:7], "Bearer ") {
+		if token := strings.TrimSpace(header[7:]); strings.HasPrefix(token, apiKeyPrefix) {
+			return token
+		}
+	}
+
+	// Browsers cannot set headers on a WebSocket handshake.
+	return strings.TrimSpace(r.URL.Query().Get("api_key"))
+}
+
+func newAPIKeySecret() (string, error) {
+	value, err := randomHex(24)
+	if err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + value, nil
+}
+
+func hashAPIKey(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := NewAPIKeyStore(storage.NewFileStorage(t.TempDir()))
+
+	key, secret, err := store.Create(ctx, "web", []Scope{ScopeChat})
+	if err != nil {
+		t.Fatalf("Failed to create api key: %v", err)
+	}
+	if !strings.HasPrefix(secret, apiKeyPrefix) || strings.Contains(key.Hash, secret) {
+		t.Fatalf("Expected a prefixed secret stored only as a hash, got %q / %q", secret, key.Hash)
+	}
+
+	authenticate := func(secret string) (*Identity, error) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		return store.Authenticate(req)
+	}
+
+	identity, err := authenticate(secret)
+	if err != nil {
+		t.Fatalf("Expected key to authenticate, got %v", err)
+	}
+	if identity.Subject != key.ID || !identity.HasScope(ScopeChat) || identity.HasScope(ScopeAdmin) {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+
+	keys, _ := store.List(ctx)
+	if len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Errorf("Expected last use to be recorded, got %+v", keys)
+	}
+
+	_, rotated, err := store.Rotate(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("Failed to rotate api key: %v", err)
+	}
+	if _, err := authenticate(secret); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected old secret to stop working after rotation, got %v", err)
+	}
+	if _, err := authenticate(rotated); err != nil {
+		t.Errorf("Expected rotated secret to work, got %v", err)
+	}
+
+	if err := store.Revoke(ctx, key.ID); err != nil {
+		t.Fatalf("Failed to revoke api key: %v", err)
+	}
+	if _, err := authenticate(rotated); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected revoked key to be rejected, got %v", err)
+	}
+	if _, _, err := store.Rotate(ctx, key.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Expected revoked key to be unrotatable, got %v", err)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	ctx := context.Background()
+	store := NewAPIKeyStore(storage.NewFileStorage(t.TempDir()))
+
+	_, chatKey, _ := store.Create(ctx, "chat", []Scope{ScopeChat})
+	_, metricsKey, _ := store.Create(ctx, "grafana", []Scope{ScopeMetricsRead})
+	_, adminKey, _ := store.Create(ctx, "ops", []Scope{ScopeAdmin})
+
+	a, err := NewAuthenticator(ctx, &Config{
+		Tokens:  []TokenConfig{{Name: "ci", Token: "ci-token", Roles: []string{"viewer"}}},
+		APIKeys: store,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	handler := a.RequireScope(ScopeMetricsRead, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name     string
+		setup    func(r *http.Request)
+		expected int
+	}{
+		{"no credentials", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"chat key", func(r *http.Request) { r.Header.Set("X-API-Key", chatKey) }, http.StatusForbidden},
+		{"metrics key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+metricsKey) }, http.StatusOK},
+		{"admin key in query", func(r *http.Request) { r.URL.RawQuery = "api_key=" + adminKey }, http.StatusOK},
+		{"unknown key", func(r *http.Request) { r.Header.Set("X-API-Key", "mc_nope") }, http.StatusUnauthorized},
+		{"static token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer ci-token") }, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/metrics", nil)
+			tt.setup(req)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/status", nil)
+	req.Header.Set("X-API-Key", chatKey)
+	a.Middleware(RoleViewer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected chat-only key to be kept out of role protected routes, got %d", rec.Code)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	if _, err := ParseScopes([]string{"chat", "root"}); err == nil {
+		t.Error("Expected error for unknown scope")
+	}
+	if _, err := ParseScopes([]string{""}); err == nil {
+		t.Error("Expected error for empty scope list")
+	}
+}
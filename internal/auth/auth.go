@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+var logger = logging.For("auth")
+
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+var (
+	ErrNoCredentials      = errors.New("no credentials provided")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+func ParseRole(value string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role: %s", value)
+	}
+	return role, nil
+}
+
+func parseRoles(values []string) ([]Role, error) {
+	roles := make([]Role, 0, len(values))
+	for _, value := range values {
+		role, err := ParseRole(value)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+type Identity struct {
+	Subject  string
+	Name     string
+	Provider string
+	Roles    []Role
+	Scopes   []Scope
+}
+
+func (i *Identity) HasRole(required Role) bool {
+	for _, role := range i.Roles {
+		if roleRanks[role] >= roleRanks[required] {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Identity) HasScope(required Scope) bool {
+	if i.Scopes == nil {
+		switch {
+		case i.HasRole(RoleAdmin):
+			return true
+		case i.HasRole(RoleOperator):
+			return required == ScopeChat || required == ScopeMetricsRead
+		case i.HasRole(RoleViewer):
+			return required == ScopeMetricsRead
+		}
+		return false
+	}
+
+	for _, scope := range i.Scopes {
+		if scope == ScopeAdmin || scope == required {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the identity has role and, if it was granted
+// scopes, scope as well. Identities without scopes, such as basic auth users,
+// are only checked by role.
+func (i *Identity) Allows(role Role, scope Scope) bool {
+	return i.HasRole(role) && (i.Scopes == nil || i.HasScope(scope))
+}
+
+type Provider interface {
+	Name() string
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+type Config struct {
+	Tokens  []TokenConfig
+	Basic   []BasicUserConfig
+	OIDC    *OIDCConfig
+	JWT     *JWTConfig
+	APIKeys *APIKeyStore
+}
+
+type Authenticator struct {
+	providers []Provider
+	oidc      *OIDCProvider
+	apiKeys   *APIKeyStore
+}
+
+func NewAuthenticator(ctx context.Context, config *Config) (*Authenticator, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+
+	a := &Authenticator{providers: make([]Provider, 0)}
+
+	if config.APIKeys != nil {
+		a.providers = append(a.providers, config.APIKeys)
+		a.apiKeys = config.APIKeys
+	}
+
+	// Before static tokens, which reject any bearer token they do not know.
+	if config.JWT != nil && config.JWT.Secret != "" {
+		provider, err := NewJWTProvider(config.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.providers = append(a.providers, provider)
+	}
+
+	if len(config.Tokens) > 0 {
+		provider, err := NewTokenProvider(config.Tokens)
+		if err != nil {
+			return nil, err
+		}
+		a.providers = append(a.providers, provider)
+	}
+
+	if len(config.Basic) > 0 {
+		provider, err := NewBasicProvider(config.Basic)
+		if err != nil {
+			return nil, err
+		}
+		a.providers = append(a.providers, provider)
+	}
+
+	if config.OIDC != nil && config.OIDC.Issuer != "" {
+		provider, err := NewOIDCProvider(ctx, config.OIDC)
+		if err != nil {
+			return nil, err
+		}
+		a.providers = append(a.providers, provider)
+		a.oidc = provider
+	}
+
+	return a, nil
+}
+
+func (a *Authenticator) Enabled() bool {
+	return len(a.providers) > 0
+}
+
+func (a *Authenticator) OIDC() *OIDCProvider {
+	return a.oidc
+}
+
+func (a *Authenticator) APIKeys() *APIKeyStore {
+	return a.apiKeys
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	for _, provider := range a.providers {
+		identity, err := provider.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return identity, nil
+	}
+
+	return nil, ErrNoCredentials
+}
+
+func (a *Authenticator) Middleware(required Role, next http.Handler) http.Handler {
+	return a.require(func(identity *Identity) bool {
+		return identity.HasRole(required)
+	}, next)
+}
+
+func (a *Authenticator) RequireScope(required Scope, next http.Handler) http.Handler {
+	return a.require(func(identity *Identity) bool {
+		return identity.HasScope(required)
+	}, next)
+}
+
+func (a *Authenticator) Require(role Role, scope Scope, next http.Handler) http.Handler {
+	return a.require(func(identity *Identity) bool {
+		return identity.Allows(role, scope)
+	}, next)
+}
+
+func (a *Authenticator) require(allowed func(identity *Identity) bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity, err := a.Authenticate(r)
+		if err != nil {
+			if !errors.Is(err, ErrNoCredentials) {
+				logger.Warn("Authentication failed", "method", r.Method, "path", r.URL.Path, "error", err)
+			}
+			a.challenge(w, r)
+			return
+		}
+
+		if !allowed(identity) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+func (a *Authenticator) challenge(w http.ResponseWriter, r *http.Request) {
+	if a.oidc != nil && r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		a.oidc.redirectToLogin(w, r)
+		return
+	}
+
+	for _, provider := range a.providers {
+		if _, ok := provider.(*BasicProvider); ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="miniclaw"`)
+			break
+		}
+	}
+
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestAuthenticator(t *testing.T) *Authenticator {
+	hash := sha256.Sum256([]byte("secret"))
+
+	a, err := NewAuthenticator(context.Background(), &Config{
+		Tokens: []TokenConfig{
+			{Name: "ci", Token: "ci-token", Roles: []string{"viewer"}},
+			{Name: "ops", Token: "ops-token", Roles: []string{"admin"}},
+		},
+		Basic: []BasicUserConfig{
+			{Username: "alice", Password: "sha256:" + hex.EncodeToString(hash[:]), Roles: []string{"operator"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	return a
+}
+
+func TestMiddlewareRoles(t *testing.T) {
+	a := newTestAuthenticator(t)
+
+	var seen *Identity
+	handler := a.Middleware(RoleOperator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = IdentityFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		setup    func(r *http.Request)
+		expected int
+	}{
+		{"no credentials", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"wrong token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"insufficient role", func(r *http.Request) { r.Header.Set("Authorization", "Bearer ci-token") }, http.StatusForbidden},
+		{"admin token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer ops-token") }, http.StatusOK},
+		{"basic auth", func(r *http.Request) { r.SetBasicAuth("alice", "secret") }, http.StatusOK},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("alice", "guess") }, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/status", nil)
+			tt.setup(req)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+
+	if seen == nil || seen.Name != "alice" || seen.Provider != "basic" {
+		t.Errorf("Expected identity in request context, got %+v", seen)
+	}
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	a, err := NewAuthenticator(context.Background(), &Config{})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	a.Middleware(RoleAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+		ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected requests to pass without providers, got %d", rec.Code)
+	}
+}
+
+func TestInvalidRole(t *testing.T) {
+	_, err := NewAuthenticator(context.Background(), &Config{
+		Tokens: []TokenConfig{{Name: "bad", Token: "x", Roles: []string{"superuser"}}},
+	})
+	if err == nil {
+		t.Error("Expected error for unknown role")
+	}
+}
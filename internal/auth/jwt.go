@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jwtLeeway tolerates small clock differences with the token issuer.
+const jwtLeeway = time.Minute
+
+// JWTConfig accepts HS256 tokens signed with Secret, e.g. minted by the
+// application that embeds the chat widget.
+type JWTConfig struct {
+	Secret   string
+	Issuer   string
+	Audience string
+}
+
+type JWTProvider struct {
+	config *JWTConfig
+	secret []byte
+}
+
+func NewJWTProvider(config *JWTConfig) (*JWTProvider, error) {
+	if len(config.Secret) < 32 {
+		return nil, fmt.Errorf("JWT secret must be at least 32 characters")
+	}
+	return &JWTProvider{config: config, secret: []byte(config.Secret)}, nil
+}
+
+func (p *JWTProvider) Name() string {
+	return "jwt"
+}
+
+func (p *JWTProvider) Authenticate(r *http.Request) (*Identity, error) {
+	raw := jwtFromRequest(r)
+	if raw == "" {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := p.verify(raw, time.Now())
+	if err != nil {
+		logger.Debug("Rejected JWT", "error", err)
+		return nil, ErrInvalidCredentials
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	identity := &Identity{Subject: subject, Name: subject, Provider: p.Name()}
+	if name, ok := claims["name"].(string); ok && name != "" {
+		identity.Name = name
+	}
+
+	// Roles and scopes this server does not know, e.g. "openid", are ignored.
+	for _, value := range claimStrings(claims["roles"]) {
+		if role, err := ParseRole(value); err == nil {
+			identity.Roles = append(identity.Roles, role)
+		}
+	}
+	if value, ok := claims["scope"]; ok {
+		identity.Scopes = make([]Scope, 0)
+		for _, value := range claimStrings(value) {
+			if scope, err := ParseScope(value); err == nil {
+				identity.Scopes = append(identity.Scopes, scope)
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *JWTProvider) verify(raw string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported token algorithm: %s", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("token signature verification failed")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	expires, ok := claims["exp"].(float64)
+	if !ok || now.Add(-jwtLeeway).Unix() > int64(expires) {
+		return nil, fmt.Errorf("token has expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Unix() < int64(notBefore) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+
+	if p.config.Issuer != "" {
+		if issuer, _ := claims["iss"].(string); issuer != p.config.Issuer {
+			return nil, fmt.Errorf("unexpected token issuer: %s", issuer)
+		}
+	}
+	if p.config.Audience != "" && !hasAudience(claims["aud"], p.config.Audience) {
+		return nil, fmt.Errorf("token was not issued for this audience")
+	}
+
+	return claims, nil
+}
+
+func claimStrings(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// jwtFromRequest only picks up bearer tokens shaped like a JWT, so static
+// tokens and API keys sent the same way are left to their providers.
+func jwtFromRequest(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) >= 7 && strings.EqualFold(header[:7], "Bearer ") {
+		if token := strings.TrimSpace(header[7:]); strings.Count(token, ".") == 2 {
+			return token
+		}
+	}
+
+	// Browsers cannot set headers on a WebSocket handshake.
+	return strings.TrimSpace(r.URL.Query().Get("access_token"))
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func signTestJWT(t *testing.T, secret, alg string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWTProvider(t *testing.T) {
+	a, err := NewAuthenticator(context.Background(), &Config{
+		Tokens: []TokenConfig{{Name: "ci", Token: "ci-token", Roles: []string{"viewer"}}},
+		JWT:    &JWTConfig{Secret: testJWTSecret, Issuer: "app", Audience: "miniclaw"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	valid := map[string]interface{}{
+		"sub":   "user-42",
+		"name":  "Alice",
+		"iss":   "app",
+		"aud":   []interface{}{"miniclaw"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "openid chat",
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := make(map[string]interface{})
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[key] = value
+		return claims
+	}
+
+	req := httptest.NewRequest("GET", "/?access_token="+signTestJWT(t, testJWTSecret, "HS256", valid), nil)
+	identity, err := a.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Expected valid token to authenticate, got %v", err)
+	}
+	if identity.Subject != "user-42" || identity.Name != "Alice" || identity.Provider != "jwt" {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+	if !identity.HasScope(ScopeChat) || identity.HasScope(ScopeAdmin) {
+		t.Errorf("Expected only the chat scope, got %v", identity.Scopes)
+	}
+
+	rejected := map[string]string{
+		"expired":        signTestJWT(t, testJWTSecret, "HS256", with("exp", time.Now().Add(-time.Hour).Unix())),
+		"wrong secret":   signTestJWT(t, "another-secret-another-secret-xx", "HS256", valid),
+		"wrong alg":      signTestJWT(t, testJWTSecret, "none", valid),
+		"wrong issuer":   signTestJWT(t, testJWTSecret, "HS256", with("iss", "other")),
+		"wrong audience": signTestJWT(t, testJWTSecret, "HS256", with("aud", "other")),
+		"no subject":     signTestJWT(t, testJWTSecret, "HS256", with("sub", "")),
+	}
+	for name, token := range rejected {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: expected invalid credentials, got %v", name, err)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer ci-token")
+	if identity, err := a.Authenticate(req); err != nil || identity.Name != "ci" {
+		t.Errorf("Expected static tokens to keep working next to JWTs, got %v %v", identity, err)
+	}
+
+	if _, err := NewJWTProvider(&JWTConfig{Secret: "short"}); err == nil {
+		t.Error("Expected short secret to be rejected")
+	}
+}
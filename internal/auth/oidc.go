@@ -0,0 +1,537 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookieName = "miniclaw_session"
+	defaultRoleClaim  = "groups"
+	defaultSessionTTL = 12 * time.Hour
+	loginStateTTL     = 10 * time.Minute
+	oidcHTTPTimeout   = 10 * time.Second
+	maxOIDCResponse   = 1024 * 1024
+)
+
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	RoleClaim    string
+	RoleMapping  map[string]string
+	DefaultRole  string
+	SessionTTL   int
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type loginState struct {
+	nonce    string
+	returnTo string
+	expires  time.Time
+}
+
+type oidcSession struct {
+	identity *Identity
+	expires  time.Time
+}
+
+type OIDCProvider struct {
+	config      *OIDCConfig
+	client      *http.Client
+	discovery   oidcDiscovery
+	roleMapping map[string]Role
+	defaultRole Role
+	sessionTTL  time.Duration
+
+	mu       sync.Mutex
+	keys     map[string]*rsa.PublicKey
+	states   map[string]loginState
+	sessions map[string]oidcSession
+}
+
+func NewOIDCProvider(ctx context.Context, config *OIDCConfig) (*OIDCProvider, error) {
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC requires client_id and redirect_url")
+	}
+
+	p := &OIDCProvider{
+		config:      config,
+		client:      &http.Client{Timeout: oidcHTTPTimeout},
+		roleMapping: make(map[string]Role, len(config.RoleMapping)),
+		sessionTTL:  time.Duration(config.SessionTTL) * time.Second,
+		keys:        make(map[string]*rsa.PublicKey),
+		states:      make(map[string]loginState),
+		sessions:    make(map[string]oidcSession),
+	}
+
+	if p.sessionTTL <= 0 {
+		p.sessionTTL = defaultSessionTTL
+	}
+
+	for value, name := range config.RoleMapping {
+		role, err := ParseRole(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OIDC role mapping for %s: %w", value, err)
+		}
+		p.roleMapping[value] = role
+	}
+
+	if config.DefaultRole != "" {
+		role, err := ParseRole(config.DefaultRole)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OIDC default role: %w", err)
+		}
+		p.defaultRole = role
+	}
+
+	discoveryURL := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, discoveryURL, &p.discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+
+	if strings.TrimSuffix(p.discovery.Issuer, "/") != strings.TrimSuffix(config.Issuer, "/") {
+		return nil, fmt.Errorf("OIDC issuer mismatch: expected %s, got %s", config.Issuer, p.discovery.Issuer)
+	}
+
+	if p.discovery.AuthorizationEndpoint == "" || p.discovery.TokenEndpoint == "" || p.discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing required endpoints")
+	}
+
+	return p, nil
+}
+
+func (p *OIDCProvider) Name() string {
+	return "oidc"
+}
+
+func (p *OIDCProvider) Authenticate(r *http.Request) (*Identity, error) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, ErrNoCredentials
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	session, ok := p.sessions[cookie.Value]
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	if time.Now().After(session.expires) {
+		delete(p.sessions, cookie.Value)
+		return nil, ErrNoCredentials
+	}
+
+	return session.identity, nil
+}
+
+func (p *OIDCProvider) LoginHandler() http.Handler {
+	return http.HandlerFunc(p.redirectToLogin)
+}
+
+func (p *OIDCProvider) CallbackHandler() http.Handler {
+	return http.HandlerFunc(p.handleCallback)
+}
+
+func (p *OIDCProvider) LogoutHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(sessionCookieName); err == nil {
+			p.mu.Lock()
+			delete(p.sessions, cookie.Value)
+			p.mu.Unlock()
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+}
+
+func (p *OIDCProvider) redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	state, err := randomString()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	returnTo := r.URL.Query().Get("return_to")
+	if returnTo == "" || !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") {
+		returnTo = r.URL.RequestURI()
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	for key, pending := range p.states {
+		if now.After(pending.expires) {
+			delete(p.states, key)
+		}
+	}
+	p.states[state] = loginState{nonce: nonce, returnTo: returnTo, expires: now.Add(loginStateTTL)}
+	p.mu.Unlock()
+
+	scopes := p.config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.config.ClientID},
+		"redirect_uri":  {p.config.RedirectURL},
+		"scope":         {strings.Join(scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+
+	target := p.discovery.AuthorizationEndpoint
+	if strings.Contains(target, "?") {
+		target += "&" + query.Encode()
+	} else {
+		target += "?" + query.Encode()
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (p *OIDCProvider) handleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if errorCode := query.Get("error"); errorCode != "" {
+		logger.Warn("OIDC login failed", "error", errorCode, "description", query.Get("error_description"))
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+
+	stateValue := query.Get("state")
+
+	p.mu.Lock()
+	state, ok := p.states[stateValue]
+	delete(p.states, stateValue)
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(state.expires) {
+		http.Error(w, "invalid login state", http.StatusBadRequest)
+		return
+	}
+
+	identity, err := p.exchange(r.Context(), query.Get("code"), state.nonce)
+	if err != nil {
+		logger.Warn("OIDC login failed", "error", err)
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+
+	if !identity.HasRole(RoleViewer) {
+		logger.Warn("OIDC user has no mapped role", "subject", identity.Subject)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	sessionID, err := randomString()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	p.mu.Lock()
+	p.sessions[sessionID] = oidcSession{identity: identity, expires: time.Now().Add(p.sessionTTL)}
+	p.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		MaxAge:   int(p.sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(p.config.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, state.returnTo, http.StatusFound)
+}
+
+func (p *OIDCProvider) exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	if code == "" {
+		return nil, fmt.Errorf("missing authorization code")
+	}
+
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {p.config.RedirectURL},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.doJSON(req, &token); err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	claims, err := p.verifyIDToken(ctx, token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	name, _ := claims["email"].(string)
+	if preferred, ok := claims["preferred_username"].(string); ok && preferred != "" {
+		name = preferred
+	}
+	if name == "" {
+		name = subject
+	}
+
+	return &Identity{
+		Subject:  subject,
+		Name:     name,
+		Provider: "oidc",
+		Roles:    p.mapRoles(claims),
+	}, nil
+}
+
+func (p *OIDCProvider) mapRoles(claims map[string]interface{}) []Role {
+	claim := p.config.RoleClaim
+	if claim == "" {
+		claim = defaultRoleClaim
+	}
+
+	var values []string
+	switch value := claims[claim].(type) {
+	case string:
+		values = strings.Fields(value)
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	roles := make([]Role, 0)
+	for _, value := range values {
+		if role, ok := p.roleMapping[value]; ok {
+			roles = append(roles, role)
+		}
+	}
+
+	if len(roles) == 0 && p.defaultRole != "" {
+		roles = append(roles, p.defaultRole)
+	}
+
+	return roles
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, raw, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed id_token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid id_token header: %w", err)
+	}
+
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported id_token algorithm: %s", header.Alg)
+	}
+
+	key, err := p.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token signature: %w", err)
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("id_token signature verification failed: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid id_token claims: %w", err)
+	}
+
+	if issuer, _ := claims["iss"].(string); issuer != p.discovery.Issuer {
+		return nil, fmt.Errorf("unexpected id_token issuer: %s", issuer)
+	}
+
+	if !hasAudience(claims["aud"], p.config.ClientID) {
+		return nil, fmt.Errorf("id_token was not issued for this client")
+	}
+
+	expires, ok := claims["exp"].(float64)
+	if !ok || time.Now().Unix() > int64(expires) {
+		return nil, fmt.Errorf("id_token has expired")
+	}
+
+	if value, _ := claims["nonce"].(string); value != nonce {
+		return nil, fmt.Errorf("id_token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	key, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown id_token signing key: %s", kid)
+	}
+
+	return key, nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	return p.doJSON(req, v)
+}
+
+func (p *OIDCProvider) doJSON(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOIDCResponse))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func hasAudience(aud interface{}, clientID string) bool {
+	switch value := aud.(type) {
+	case string:
+		return value == clientID
+	case []interface{}:
+		for _, item := range value {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
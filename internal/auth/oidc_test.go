@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	nonce  string
+	groups []string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	issuer := &testIssuer{key: key, groups: []string{"miniclaw-admins"}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer":%q,"authorization_endpoint":%q,"token_endpoint":%q,"jwks_uri":%q}`,
+			issuer.server.URL, issuer.server.URL+"/authorize", issuer.server.URL+"/token", issuer.server.URL+"/jwks")
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"keys":[{"kty":"RSA","kid":"k1","n":%q,"e":%q}]}`,
+			base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "shh" || r.FormValue("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"a","id_token":%q}`, issuer.sign(t))
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	return issuer
+}
+
+func (i *testIssuer) sign(t *testing.T) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"k1"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":                i.server.URL,
+		"aud":                "client",
+		"sub":                "user-1",
+		"preferred_username": "bob",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              i.nonce,
+		"groups":             i.groups,
+	})
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(header + "." + payload))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+
+	a, err := NewAuthenticator(context.Background(), &Config{
+		OIDC: &OIDCConfig{
+			Issuer:       issuer.server.URL,
+			ClientID:     "client",
+			ClientSecret: "shh",
+			RedirectURL:  "http://localhost/auth/callback",
+			RoleMapping:  map[string]string{"miniclaw-admins": "admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	protected := a.Middleware(RoleAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := IdentityFromContext(r.Context())
+		fmt.Fprint(w, identity.Name)
+	}))
+
+	req := httptest.NewRequest("GET", "/dashboard", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Expected redirect to login, got %d", rec.Code)
+	}
+
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	if !strings.HasPrefix(location.String(), issuer.server.URL+"/authorize") {
+		t.Fatalf("Expected redirect to authorization endpoint, got %s", location)
+	}
+	issuer.nonce = location.Query().Get("nonce")
+
+	callback := httptest.NewRequest("GET", "/auth/callback?code=good-code&state="+location.Query().Get("state"), nil)
+	rec = httptest.NewRecorder()
+	a.OIDC().CallbackHandler().ServeHTTP(rec, callback)
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/dashboard" {
+		t.Fatalf("Expected redirect back to dashboard, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookieName {
+		t.Fatalf("Expected session cookie, got %+v", cookies)
+	}
+
+	req = httptest.NewRequest("GET", "/dashboard", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "bob" {
+		t.Errorf("Expected authenticated request, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	callback = httptest.NewRequest("GET", "/auth/callback?code=good-code&state="+location.Query().Get("state"), nil)
+	rec = httptest.NewRecorder()
+	a.OIDC().CallbackHandler().ServeHTTP(rec, callback)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected reused state to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestOIDCRejectsUnmappedUsers(t *testing.T) {
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+
+	provider, err := NewOIDCProvider(context.Background(), &OIDCConfig{
+		Issuer:      issuer.server.URL,
+		ClientID:    "client",
+		RedirectURL: "http://localhost/auth/callback",
+		RoleMapping: map[string]string{"miniclaw-admins": "admin"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	claims := map[string]interface{}{"groups": []interface{}{"everyone"}}
+	if roles := provider.mapRoles(claims); len(roles) != 0 {
+		t.Errorf("Expected no roles for unmapped groups, got %v", roles)
+	}
+
+	provider.defaultRole = RoleViewer
+	if roles := provider.mapRoles(claims); len(roles) != 1 || roles[0] != RoleViewer {
+		t.Errorf("Expected default role, got %v", roles)
+	}
+}
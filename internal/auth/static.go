@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const sha256PasswordPrefix = "sha256:"
+
+type TokenConfig struct {
+	Name  string
+	Token string
+	Roles []string
+}
+
+type BasicUserConfig struct {
+	Username string
+	Password string
+	Roles    []string
+}
+
+type staticCredential struct {
+	identity *Identity
+	secret   []byte
+}
+
+type TokenProvider struct {
+	tokens []staticCredential
+}
+
+func NewTokenProvider(tokens []TokenConfig) (*TokenProvider, error) {
+	p := &TokenProvider{tokens: make([]staticCredential, 0, len(tokens))}
+
+	for _, token := range tokens {
+		if token.Token == "" {
+			return nil, fmt.Errorf("token %s has no value", token.Name)
+		}
+
+		roles, err := parseRoles(token.Roles)
+		if err != nil {
+			return nil, fmt.Errorf("invalid roles for token %s: %w", token.Name, err)
+		}
+
+		hash := sha256.Sum256([]byte(token.Token))
+		p.tokens = append(p.tokens, staticCredential{
+			identity: &Identity{Subject: token.Name, Name: token.Name, Provider: "token", Roles: roles},
+			secret:   hash[:],
+		})
+	}
+
+	return p, nil
+}
+
+func (p *TokenProvider) Name() string {
+	return "token"
+}
+
+func (p *TokenProvider) Authenticate(r *http.Request) (*Identity, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return nil, ErrNoCredentials
+	}
+
+	hash := sha256.Sum256([]byte(strings.TrimSpace(header[7:])))
+	for _, token := range p.tokens {
+		if subtle.ConstantTimeCompare(hash[:], token.secret) == 1 {
+			return token.identity, nil
+		}
+	}
+
+	return nil, ErrInvalidCredentials
+}
+
+type BasicProvider struct {
+	users map[string]staticCredential
+}
+
+func NewBasicProvider(users []BasicUserConfig) (*BasicProvider, error) {
+	p := &BasicProvider{users: make(map[string]staticCredential, len(users))}
+
+	for _, user := range users {
+		if user.Username == "" || user.Password == "" {
+			return nil, fmt.Errorf("basic auth users need a username and password")
+		}
+
+		roles, err := parseRoles(user.Roles)
+		if err != nil {
+			return nil, fmt.Errorf("invalid roles for user %s: %w", user.Username, err)
+		}
+
+		secret, err := passwordHash(user.Password)
+		if err != nil {
+			return nil, fmt.Errorf("invalid password for user %s: %w", user.Username, err)
+		}
+
+		p.users[user.Username] = staticCredential{
+			identity: &Identity{Subject: user.Username, Name: user.Username, Provider: "basic", Roles: roles},
+			secret:   secret,
+		}
+	}
+
+	return p, nil
+}
+
+func (p *BasicProvider) Name() string {
+	return "basic"
+}
+
+func (p *BasicProvider) Authenticate(r *http.Request) (*Identity, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	user, ok := p.users[username]
+	hash := sha256.Sum256([]byte(password))
+	if !ok || subtle.ConstantTimeCompare(hash[:], user.secret) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user.identity, nil
+}
+
+func passwordHash(password string) ([]byte, error) {
+	if strings.HasPrefix(password, sha256PasswordPrefix) {
+		hash, err := hex.DecodeString(strings.TrimPrefix(password, sha256PasswordPrefix))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("expected %s followed by a hex encoded SHA-256 digest", sha256PasswordPrefix)
+		}
+		return hash, nil
+	}
+
+	hash := sha256.Sum256([]byte(password))
+	return hash[:], nil
+}
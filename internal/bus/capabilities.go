@@ -0,0 +1,132 @@
+package bus
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const MetadataCapabilities = "capabilities"
+
+// Capabilities describes how much a channel can show in a single message.
+type Capabilities struct {
+	// MaxMessageLength is counted in characters, 0 means unlimited.
+	MaxMessageLength int
+	// LineWidth is the display width in columns, 0 means unknown.
+	LineWidth int
+	Buttons   bool
+}
+
+var channelCapabilities = map[string]Capabilities{
+	ChannelTelegram:  {MaxMessageLength: 4096, Buttons: true},
+	ChannelDiscord:   {MaxMessageLength: 2000},
+	ChannelEmail:     {},
+	ChannelWebSocket: {},
+	ChannelCLI:       {LineWidth: 80},
+	ChannelStdio:     {},
+}
+
+func ChannelCapabilities(channel string) Capabilities {
+	return channelCapabilities[channel]
+}
+
+// Capabilities returns the channel defaults, narrowed by whatever the client
+// reported with the message.
+func (m *Message) Capabilities() Capabilities {
+	caps := ChannelCapabilities(m.Channel)
+	if m.Metadata == nil {
+		return caps
+	}
+
+	reported, ok := m.Metadata[MetadataCapabilities].(Capabilities)
+	if !ok {
+		return caps
+	}
+	if reported.MaxMessageLength > 0 && (caps.MaxMessageLength == 0 || reported.MaxMessageLength < caps.MaxMessageLength) {
+		caps.MaxMessageLength = reported.MaxMessageLength
+	}
+	if reported.LineWidth > 0 {
+		caps.LineWidth = reported.LineWidth
+	}
+	return caps
+}
+
+const fenceReserve = 16
+
+// SplitText breaks text into parts of at most limit characters, preferring
+// paragraph, line and word boundaries. Code blocks cut in two are closed at
+// the end of one part and reopened at the start of the next.
+func SplitText(text string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	fenced := strings.Contains(text, "```")
+	if fenced && limit > 2*fenceReserve {
+		limit -= fenceReserve
+	}
+
+	var parts []string
+	rest := text
+	for utf8.RuneCountInString(rest) > limit {
+		window := rest[:byteOffset(rest, limit)]
+		cut := len(window)
+		for _, sep := range []string{"\n\n", "\n", " "} {
+			if i := strings.LastIndex(window, sep); i > len(window)/2 {
+				cut = i + len(sep)
+				break
+			}
+		}
+
+		parts = append(parts, strings.TrimRight(rest[:cut], " \n"))
+		rest = strings.TrimLeft(rest[cut:], "\n")
+	}
+	if rest != "" {
+		parts = append(parts, rest)
+	}
+
+	if fenced {
+		balanceFences(parts)
+	}
+	return parts
+}
+
+func byteOffset(s string, runes int) int {
+	for i := range s {
+		if runes == 0 {
+			return i
+		}
+		runes--
+	}
+	return len(s)
+}
+
+func balanceFences(parts []string) {
+	open := ""
+	for i, part := range parts {
+		if open != "" {
+			part = open + "\n" + part
+		}
+
+		inCode := false
+		for _, line := range strings.Split(part, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if !strings.HasPrefix(trimmed, "```") {
+				continue
+			}
+			inCode = !inCode
+			if inCode {
+				open = trimmed
+				if len(open) > fenceReserve-5 {
+					open = "```"
+				}
+			}
+		}
+
+		if inCode {
+			part += "\n```"
+		} else {
+			open = ""
+		}
+		parts[i] = part
+	}
+}
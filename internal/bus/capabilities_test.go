@@ -0,0 +1,66 @@
+package bus
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText(t *testing.T) {
+	if parts := SplitText("short", 10); len(parts) != 1 || parts[0] != "short" {
+		t.Errorf("Expected short text untouched, got %q", parts)
+	}
+
+	text := strings.Repeat("word ", 10) + "\n\n" + strings.Repeat("next ", 10)
+	parts := SplitText(text, 60)
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "next") {
+		t.Errorf("Expected split at the paragraph break, got %q", parts)
+	}
+
+	parts = SplitText(strings.Repeat("你好", 50), 30)
+	for _, part := range parts {
+		if !utf8.ValidString(part) || utf8.RuneCountInString(part) > 30 {
+			t.Errorf("Expected valid parts of at most 30 characters, got %q", part)
+		}
+	}
+	if strings.Join(parts, "") != strings.Repeat("你好", 50) {
+		t.Error("Expected no characters to be lost")
+	}
+}
+
+func TestSplitTextBalancesCodeFences(t *testing.T) {
+	code := "```go\n" + strings.Repeat("fmt.Println(1)\n", 10) + "```"
+	parts := SplitText("Intro\n"+code, 80)
+	if len(parts) < 2 {
+		t.Fatalf("Expected several parts, got %q", parts)
+	}
+
+	for i, part := range parts {
+		if strings.Count(part, "```")%2 != 0 {
+			t.Errorf("Part %d has unbalanced fences: %q", i, part)
+		}
+		if utf8.RuneCountInString(part) > 80 {
+			t.Errorf("Part %d exceeds the limit: %d", i, utf8.RuneCountInString(part))
+		}
+	}
+	if !strings.HasPrefix(parts[1], "```go\n") {
+		t.Errorf("Expected code block to be reopened with its language, got %q", parts[1])
+	}
+}
+
+func TestMessageCapabilities(t *testing.T) {
+	msg := &Message{Channel: ChannelTelegram}
+	if caps := msg.Capabilities(); caps.MaxMessageLength != 4096 || !caps.Buttons {
+		t.Errorf("Unexpected Telegram defaults %+v", caps)
+	}
+
+	msg.Metadata = map[string]interface{}{MetadataCapabilities: Capabilities{MaxMessageLength: 10000, LineWidth: 40}}
+	if caps := msg.Capabilities(); caps.MaxMessageLength != 4096 || caps.LineWidth != 40 {
+		t.Errorf("Expected client not to raise the channel limit, got %+v", caps)
+	}
+
+	msg = &Message{Channel: ChannelWebSocket, Metadata: map[string]interface{}{MetadataCapabilities: Capabilities{MaxMessageLength: 500}}}
+	if caps := msg.Capabilities(); caps.MaxMessageLength != 500 {
+		t.Errorf("Expected client to lower an unlimited channel, got %+v", caps)
+	}
+}
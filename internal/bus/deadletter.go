@@ -0,0 +1,249 @@
+package bus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const deadLetterDir = "deadletters"
+
+// RetryPolicy controls how often the bus calls a failing handler again. The
+// first retry waits InitialBackoff, and each one after that twice as long as
+// the one before, up to MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns how long to wait after the given failed attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+type deliveryKey struct{}
+
+type delivery struct {
+	attempt int
+	final   bool
+}
+
+// DeliveryAttempt returns which attempt at handling a message ctx belongs to,
+// and whether the bus will try again if this one fails. Handlers can use it
+// to stay quiet about failures that are going to be retried.
+func DeliveryAttempt(ctx context.Context) (int, bool) {
+	d, ok := ctx.Value(deliveryKey{}).(delivery)
+	if !ok {
+		return 1, false
+	}
+	return d.attempt, !d.final
+}
+
+// DeadLetter is a message that could not be handled after all its attempts.
+// It keeps what is needed to publish the message again; other metadata, such
+// as the connection it arrived on, is dropped.
+type DeadLetter struct {
+	ID          string       `json:"id"`
+	MessageID   string       `json:"message_id"`
+	Channel     string       `json:"channel"`
+	ChatID      string       `json:"chat_id"`
+	Content     string       `json:"content"`
+	ReplyTo     string       `json:"reply_to,omitempty"`
+	User        *User        `json:"user,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	ToolUses    []ToolUse    `json:"tool_uses,omitempty"`
+	Buttons     [][]Button   `json:"buttons,omitempty"`
+	Error       string       `json:"error"`
+	Attempts    int          `json:"attempts"`
+	FailedAt    time.Time    `json:"failed_at"`
+}
+
+func NewDeadLetter(msg *Message, err error, attempts int) *DeadLetter {
+	id := make([]byte, 6)
+	rand.Read(id)
+
+	letter := &DeadLetter{
+		ID:          hex.EncodeToString(id),
+		MessageID:   msg.ID,
+		Channel:     msg.Channel,
+		ChatID:      msg.ChatID,
+		Content:     msg.Content,
+		ReplyTo:     msg.ReplyTo(),
+		Attachments: msg.Attachments(),
+		ToolUses:    msg.ToolUses(),
+		Buttons:     msg.Buttons(),
+		Error:       err.Error(),
+		Attempts:    attempts,
+		FailedAt:    time.Now().UTC(),
+	}
+	if user, ok := msg.User(); ok {
+		letter.User = &user
+	}
+	return letter
+}
+
+// Message rebuilds the message so it can be published again.
+func (d *DeadLetter) Message() *Message {
+	msg := &Message{
+		ID:       d.MessageID,
+		Channel:  d.Channel,
+		ChatID:   d.ChatID,
+		Content:  d.Content,
+		Metadata: make(map[string]interface{}),
+	}
+	if d.ReplyTo != "" {
+		msg.Metadata[MetadataReplyTo] = d.ReplyTo
+	}
+	if d.User != nil {
+		msg.Metadata[MetadataUser] = *d.User
+	}
+	if len(d.Attachments) > 0 {
+		msg.Metadata[MetadataAttachments] = d.Attachments
+	}
+	if len(d.ToolUses) > 0 {
+		msg.Metadata[MetadataToolUses] = d.ToolUses
+	}
+	if len(d.Buttons) > 0 {
+		msg.Metadata[MetadataButtons] = d.Buttons
+	}
+	return msg
+}
+
+// DeadLetterStore keeps dead letters as one JSON file each, so they survive
+// restarts and can be inspected and replayed later.
+type DeadLetterStore struct {
+	storage storage.Storage
+}
+
+func NewDeadLetterStore(storage storage.Storage) *DeadLetterStore {
+	return &DeadLetterStore{
+		storage: storage,
+	}
+}
+
+func (s *DeadLetterStore) Add(ctx context.Context, letter *DeadLetter) error {
+	data, err := json.MarshalIndent(letter, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	if err := s.storage.WriteFile(ctx, deadLetterPath(letter.ID), data); err != nil {
+		return fmt.Errorf("failed to store dead letter %s: %w", letter.ID, err)
+	}
+	return nil
+}
+
+func (s *DeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	if !validDeadLetterID(id) {
+		return nil, fmt.Errorf("failed to read dead letter %s: %w", id, ErrDeadLetterNotFound)
+	}
+
+	exists, err := s.storage.FileExists(ctx, deadLetterPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter %s: %w", id, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("failed to read dead letter %s: %w", id, ErrDeadLetterNotFound)
+	}
+
+	data, err := s.storage.ReadFile(ctx, deadLetterPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter %s: %w", id, err)
+	}
+
+	var letter DeadLetter
+	if err := json.Unmarshal(data, &letter); err != nil {
+		return nil, fmt.Errorf("failed to parse dead letter %s: %w", id, err)
+	}
+	return &letter, nil
+}
+
+// List returns the dead letters, oldest first.
+func (s *DeadLetterStore) List(ctx context.Context) ([]*DeadLetter, error) {
+	files, err := s.storage.ListFiles(ctx, deadLetterDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	letters := make([]*DeadLetter, 0, len(files))
+	for _, file := range files {
+		name := path.Base(strings.ReplaceAll(file, "\\", "/"))
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		letter, err := s.Get(ctx, strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].FailedAt.Before(letters[j].FailedAt)
+	})
+	return letters, nil
+}
+
+func (s *DeadLetterStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteFile(ctx, deadLetterPath(id)); err != nil {
+		return fmt.Errorf("failed to delete dead letter %s: %w", id, err)
+	}
+	return nil
+}
+
+// Replay publishes a dead letter on its channel again and removes it from the
+// store. If it fails again it comes back as a new dead letter.
+func (s *DeadLetterStore) Replay(ctx context.Context, messageBus MessageBus, id string) (*DeadLetter, error) {
+	letter, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := messageBus.Publish(ctx, letter.Channel, letter.Message()); err != nil {
+		return nil, fmt.Errorf("failed to replay dead letter %s: %w", id, err)
+	}
+
+	if err := s.storage.DeleteFile(ctx, deadLetterPath(id)); err != nil {
+		return nil, fmt.Errorf("failed to delete replayed dead letter %s: %w", id, err)
+	}
+	return letter, nil
+}
+
+func deadLetterPath(id string) string {
+	return path.Join(deadLetterDir, id+".json")
+}
+
+func validDeadLetterID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
@@ -0,0 +1,132 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range expected {
+		if got := policy.backoff(i + 1); got != want {
+			t.Errorf("Expected backoff %s after attempt %d, got %s", want, i+1, got)
+		}
+	}
+
+	var none *RetryPolicy
+	if none.attempts() != 1 {
+		t.Errorf("Expected a single attempt without a policy, got %d", none.attempts())
+	}
+}
+
+func TestInMemoryMessageBus_RetriesAndDeadLetters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewDeadLetterStore(storage.NewFileStorage(t.TempDir()))
+	messageBus := NewInMemoryMessageBus(ctx, nil)
+	messageBus.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, store)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	var mu sync.Mutex
+	var retrying []bool
+	done := make(chan struct{}, 3)
+	messageBus.Subscribe(ChannelTelegram, func(ctx context.Context, msg *Message) error {
+		_, willRetry := DeliveryAttempt(ctx)
+		mu.Lock()
+		retrying = append(retrying, willRetry)
+		mu.Unlock()
+		done <- struct{}{}
+		return errors.New("provider down")
+	})
+
+	msg := &Message{ID: "msg-1", ChatID: "chat", Content: "hello", Metadata: map[string]interface{}{
+		MetadataUser: User{ID: "42", Name: "alice", Admin: true},
+	}}
+	if err := messageBus.Publish(ctx, ChannelTelegram, msg); err != nil {
+		t.Fatalf("Failed to publish message: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected 3 attempts, got %d", i)
+		}
+	}
+
+	var letters []*DeadLetter
+	deadline := time.Now().Add(2 * time.Second)
+	for len(letters) == 0 && time.Now().Before(deadline) {
+		letters, _ = store.List(ctx)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	if len(retrying) != 3 || !retrying[0] || !retrying[1] || retrying[2] {
+		t.Errorf("Expected the last attempt to be marked final, got %v", retrying)
+	}
+	mu.Unlock()
+
+	if len(letters) != 1 {
+		t.Fatalf("Expected one dead letter, got %d", len(letters))
+	}
+	letter := letters[0]
+	if letter.MessageID != "msg-1" || letter.Attempts != 3 || letter.Error != "provider down" {
+		t.Errorf("Unexpected dead letter: %+v", letter)
+	}
+
+	replayed := letter.Message()
+	if user, ok := replayed.User(); !ok || user.Name != "alice" || !user.Admin {
+		t.Errorf("Expected the sender to survive storage, got %+v", user)
+	}
+}
+
+func TestDeadLetterStoreReplay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewDeadLetterStore(storage.NewFileStorage(t.TempDir()))
+	letter := NewDeadLetter(&Message{ID: "msg-1", Channel: ChannelCLI, ChatID: "chat", Content: "hello"}, errors.New("boom"), 1)
+	if err := store.Add(ctx, letter); err != nil {
+		t.Fatalf("Failed to add dead letter: %v", err)
+	}
+
+	messageBus := NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *Message, 1)
+	messageBus.Subscribe(ChannelCLI, func(ctx context.Context, msg *Message) error {
+		received <- msg
+		return nil
+	})
+
+	if _, err := store.Replay(ctx, messageBus, letter.ID); err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.ID != "msg-1" || msg.Content != "hello" {
+			t.Errorf("Unexpected replayed message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the replayed message")
+	}
+
+	if _, err := store.Get(ctx, letter.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Expected the replayed letter to be removed, got %v", err)
+	}
+	if _, err := store.Replay(ctx, messageBus, "../secrets"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Expected an invalid ID to be rejected, got %v", err)
+	}
+}
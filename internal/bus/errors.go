@@ -6,4 +6,5 @@ var (
 	ErrTimeout       = errors.New("message bus timeout")
 	ErrHandlerNotFound = errors.New("handler not found")
 	ErrClosed        = errors.New("message bus closed")
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
 )
\ No newline at end of file
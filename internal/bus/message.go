@@ -3,14 +3,57 @@ package bus
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 )
 
 const (
 	ChannelTelegram  = "telegram"
+	ChannelDiscord   = "discord"
+	ChannelEmail     = "email"
 	ChannelWebSocket = "websocket"
 	ChannelCLI       = "cli"
+	ChannelStdio     = "stdio"
+)
+
+const (
+	MetadataAttachments = "attachments"
+	MetadataToolUses    = "tool_uses"
+	MetadataButtons     = "buttons"
+	MetadataCallback    = "callback"
+	MetadataReaction    = "reaction"
+	MetadataPartial     = "partial"
+	MetadataSequence    = "sequence"
+	MetadataStreaming   = "streaming"
+	MetadataUser        = "user"
+	MetadataReplyTo     = "reply_to"
+	MetadataConnection  = "connection"
+	MetadataSkills      = "skills"
+	MetadataToolEvents  = "tool_events"
+	MetadataToolEvent   = "tool_event"
+	MetadataErrorCode   = "error_code"
+	MetadataModel       = "model"
+)
+
+// Error codes of replies that report a failure instead of answering.
+const (
+	ErrorInternal    = "internal_error"
+	ErrorInterrupted = "interrupted"
+)
+
+// Phases of a ToolEvent.
+const (
+	ToolStarted  = "started"
+	ToolFinished = "finished"
+)
+
+const (
+	AttachmentPhoto    = "photo"
+	AttachmentVoice    = "voice"
+	AttachmentDocument = "document"
 )
 
 type Message struct {
@@ -22,6 +65,252 @@ type Message struct {
 	Metadata  map[string]interface{}
 }
 
+type Attachment struct {
+	Type     string
+	FileID   string
+	FileName string
+	MimeType string
+	Size     int64
+	Path     string
+	Error    string
+}
+
+func (m *Message) Attachments() []Attachment {
+	if m.Metadata == nil {
+		return nil
+	}
+
+	attachments, _ := m.Metadata[MetadataAttachments].([]Attachment)
+	return attachments
+}
+
+// User identifies the authenticated sender of a message, independently of
+// the chat it was sent to. Only admins may run admin-only tools and commands.
+type User struct {
+	ID    string
+	Name  string
+	Admin bool
+}
+
+func (m *Message) User() (User, bool) {
+	if m.Metadata == nil {
+		return User{}, false
+	}
+
+	user, ok := m.Metadata[MetadataUser].(User)
+	return user, ok
+}
+
+type ToolUse struct {
+	Name     string `json:"name"`
+	Input    string `json:"input,omitempty"`
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms,omitempty"`
+}
+
+func (u ToolUse) Summary() string {
+	summary := u.Name
+	if u.Input != "" {
+		summary += "(" + u.Input + ")"
+	}
+
+	if u.Error != "" {
+		summary += " -> error: " + u.Error
+	} else if u.Output != "" {
+		summary += " -> " + u.Output
+	}
+
+	if u.Duration > 0 {
+		summary += fmt.Sprintf(" [%dms]", u.Duration)
+	}
+
+	return summary
+}
+
+func (m *Message) ToolUses() []ToolUse {
+	if m.Metadata == nil {
+		return nil
+	}
+
+	toolUses, _ := m.Metadata[MetadataToolUses].([]ToolUse)
+	return toolUses
+}
+
+// ToolEvent reports a tool call starting or finishing while the agent works
+// on a request that asked for them with MetadataToolEvents.
+type ToolEvent struct {
+	Phase string
+	Tool  ToolUse
+}
+
+func (m *Message) ToolEvent() *ToolEvent {
+	if m.Metadata == nil {
+		return nil
+	}
+
+	event, _ := m.Metadata[MetadataToolEvent].(*ToolEvent)
+	return event
+}
+
+func (m *Message) WantsToolEvents() bool {
+	if m.Metadata == nil {
+		return false
+	}
+
+	wants, _ := m.Metadata[MetadataToolEvents].(bool)
+	return wants
+}
+
+// ErrorCode is one of the Error constants for replies that report a
+// failure, or "".
+func (m *Message) ErrorCode() string {
+	if m.Metadata == nil {
+		return ""
+	}
+
+	code, _ := m.Metadata[MetadataErrorCode].(string)
+	return code
+}
+
+// Model is the model the sender asked to answer with, or "" for the
+// current one.
+func (m *Message) Model() string {
+	if m.Metadata == nil {
+		return ""
+	}
+
+	model, _ := m.Metadata[MetadataModel].(string)
+	return model
+}
+
+type Button struct {
+	Text string
+	Data string
+}
+
+type Callback struct {
+	ID        string
+	Data      string
+	MessageID string
+	UserID    string
+}
+
+func (m *Message) Buttons() [][]Button {
+	if m.Metadata == nil {
+		return nil
+	}
+
+	buttons, _ := m.Metadata[MetadataButtons].([][]Button)
+	return buttons
+}
+
+func (m *Message) Callback() *Callback {
+	if m.Metadata == nil {
+		return nil
+	}
+
+	callback, _ := m.Metadata[MetadataCallback].(*Callback)
+	return callback
+}
+
+type Reaction struct {
+	Emoji     string
+	MessageID string
+	UserID    string
+}
+
+func (m *Message) Reaction() *Reaction {
+	if m.Metadata == nil {
+		return nil
+	}
+
+	reaction, _ := m.Metadata[MetadataReaction].(*Reaction)
+	return reaction
+}
+
+func (m *Message) IsPartial() bool {
+	if m.Metadata == nil {
+		return false
+	}
+
+	partial, _ := m.Metadata[MetadataPartial].(bool)
+	return partial
+}
+
+// Sequence numbers the partial replies to a request from 1 in the order they
+// were produced, since the bus may deliver them out of order.
+func (m *Message) Sequence() int {
+	if m.Metadata == nil {
+		return 0
+	}
+
+	sequence, _ := m.Metadata[MetadataSequence].(int)
+	return sequence
+}
+
+func (m *Message) WantsStreaming() bool {
+	if m.Metadata == nil {
+		return false
+	}
+
+	streaming, _ := m.Metadata[MetadataStreaming].(bool)
+	return streaming
+}
+
+// SetReplyTo marks m as a reply to request. The connection the request came
+// in on is kept so the channel can route the reply back to it.
+func (m *Message) SetReplyTo(request *Message) {
+	if m.Metadata == nil {
+		m.Metadata = make(map[string]interface{})
+	}
+	m.Metadata[MetadataReplyTo] = request.ID
+	if connection := request.Connection(); connection != "" {
+		m.Metadata[MetadataConnection] = connection
+	}
+}
+
+// ReplyTo returns the ID of the message this one answers, or "" for
+// messages coming from users.
+func (m *Message) ReplyTo() string {
+	if m.Metadata == nil {
+		return ""
+	}
+
+	replyTo, _ := m.Metadata[MetadataReplyTo].(string)
+	return replyTo
+}
+
+func (m *Message) IsReply() bool {
+	return m.ReplyTo() != ""
+}
+
+// Connection identifies the client connection a message arrived on, for
+// channels where one chat can have several.
+func (m *Message) Connection() string {
+	if m.Metadata == nil {
+		return ""
+	}
+
+	connection, _ := m.Metadata[MetadataConnection].(string)
+	return connection
+}
+
+// Skills returns the names of the skills the sender asked the agent to use
+// for this message, on top of the ones it selects itself.
+func (m *Message) Skills() []string {
+	if m.Metadata == nil {
+		return nil
+	}
+
+	skills, _ := m.Metadata[MetadataSkills].([]string)
+	return skills
+}
+
+func (m *Message) IsControl() bool {
+	return m.Callback() != nil || m.Reaction() != nil || m.IsPartial() || m.ToolEvent() != nil
+}
+
 type MessageHandler func(ctx context.Context, msg *Message) error
 
 type MessageBus interface {
@@ -38,18 +327,33 @@ type InMemoryMessageBus struct {
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
+	logger      *slog.Logger
+	retryPolicy *RetryPolicy
+	deadLetters *DeadLetterStore
 }
 
-func NewInMemoryMessageBus(ctx context.Context) *InMemoryMessageBus {
+func NewInMemoryMessageBus(ctx context.Context, logger *slog.Logger) *InMemoryMessageBus {
 	busCtx, cancel := context.WithCancel(ctx)
 	return &InMemoryMessageBus{
 		subscribers: make(map[string]map[string]MessageHandler),
 		messageCh:   make(chan *Message, 100),
 		ctx:         busCtx,
 		cancel:      cancel,
+		logger:      logging.Or(logger, "bus"),
 	}
 }
 
+// SetRetryPolicy makes the bus call failing handlers again according to
+// policy, and keep the messages that still fail in deadLetters if it is not
+// nil.
+func (b *InMemoryMessageBus) SetRetryPolicy(policy *RetryPolicy, deadLetters *DeadLetterStore) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.retryPolicy = policy
+	b.deadLetters = deadLetters
+}
+
 func (b *InMemoryMessageBus) Start() {
 	b.wg.Add(1)
 	go b.processMessages()
@@ -65,6 +369,7 @@ func (b *InMemoryMessageBus) processMessages() {
 		case msg := <-b.messageCh:
 			b.mu.RLock()
 			handlers, ok := b.subscribers[msg.Channel]
+			policy, deadLetters := b.retryPolicy, b.deadLetters
 			b.mu.RUnlock()
 
 			if ok {
@@ -72,9 +377,7 @@ func (b *InMemoryMessageBus) processMessages() {
 					b.wg.Add(1)
 					go func(h MessageHandler) {
 						defer b.wg.Done()
-						if err := h(b.ctx, msg); err != nil {
-							fmt.Printf("Handler error: %v\n", err)
-						}
+						b.deliver(h, msg, policy, deadLetters)
 					}(handler)
 				}
 			}
@@ -82,6 +385,48 @@ func (b *InMemoryMessageBus) processMessages() {
 	}
 }
 
+func (b *InMemoryMessageBus) deliver(handler MessageHandler, msg *Message, policy *RetryPolicy, deadLetters *DeadLetterStore) {
+	attempts := policy.attempts()
+	// A streaming update is superseded by the next one, and a tool event is
+	// stale once the reply arrives, so neither is worth retrying or keeping.
+	if msg.IsPartial() || msg.ToolEvent() != nil {
+		attempts = 1
+		deadLetters = nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		final := attempt == attempts
+		err := handler(context.WithValue(b.ctx, deliveryKey{}, delivery{attempt: attempt, final: final}), msg)
+		if err == nil {
+			return
+		}
+
+		if !final {
+			delay := policy.backoff(attempt)
+			b.logger.Warn("Handler error, retrying", "channel", msg.Channel, "chat_id", msg.ChatID, "attempt", attempt, "retry_in", delay, "error", err)
+
+			select {
+			case <-time.After(delay):
+				continue
+			case <-b.ctx.Done():
+			}
+		}
+
+		b.logger.Error("Handler error", "channel", msg.Channel, "chat_id", msg.ChatID, "attempts", attempt, "error", err)
+		if deadLetters == nil {
+			return
+		}
+
+		letter := NewDeadLetter(msg, err, attempt)
+		if err := deadLetters.Add(context.WithoutCancel(b.ctx), letter); err != nil {
+			b.logger.Error("Failed to store dead letter", "channel", msg.Channel, "chat_id", msg.ChatID, "error", err)
+			return
+		}
+		b.logger.Warn("Moved message to the dead-letter queue", "id", letter.ID, "channel", msg.Channel, "chat_id", msg.ChatID)
+		return
+	}
+}
+
 func (b *InMemoryMessageBus) Publish(ctx context.Context, channel string, msg *Message) error {
 	msg.Channel = channel
 	msg.Timestamp = time.Now()
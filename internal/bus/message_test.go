@@ -1,7 +1,11 @@
 package bus
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"log/slog"
+	"strings"
 	"testing"
 	"time"
 )
@@ -10,7 +14,7 @@ func TestInMemoryMessageBus_PublishAndSubscribe(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	bus := NewInMemoryMessageBus(ctx)
+	bus := NewInMemoryMessageBus(ctx, nil)
 	bus.Start()
 	defer bus.Close()
 
@@ -58,7 +62,7 @@ func TestInMemoryMessageBus_Unsubscribe(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	bus := NewInMemoryMessageBus(ctx)
+	bus := NewInMemoryMessageBus(ctx, nil)
 	bus.Start()
 	defer bus.Close()
 
@@ -90,7 +94,7 @@ func TestInMemoryMessageBus_MultipleSubscribers(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	bus := NewInMemoryMessageBus(ctx)
+	bus := NewInMemoryMessageBus(ctx, nil)
 	bus.Start()
 	defer bus.Close()
 
@@ -154,7 +158,7 @@ func TestInMemoryMessageBus_DifferentChannels(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	bus := NewInMemoryMessageBus(ctx)
+	bus := NewInMemoryMessageBus(ctx, nil)
 	bus.Start()
 	defer bus.Close()
 
@@ -211,7 +215,7 @@ func TestMessage_Timestamp(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	bus := NewInMemoryMessageBus(ctx)
+	bus := NewInMemoryMessageBus(ctx, nil)
 	bus.Start()
 	defer bus.Close()
 
@@ -240,3 +244,33 @@ func TestMessage_Timestamp(t *testing.T) {
 
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestInMemoryMessageBus_LogsHandlerErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var output bytes.Buffer
+	bus := NewInMemoryMessageBus(ctx, slog.New(slog.NewTextHandler(&output, nil)))
+	bus.Start()
+
+	done := make(chan struct{})
+	bus.Subscribe(ChannelCLI, func(ctx context.Context, msg *Message) error {
+		defer close(done)
+		return errors.New("boom")
+	})
+
+	if err := bus.Publish(ctx, ChannelCLI, &Message{ID: "m", ChatID: "chat"}); err != nil {
+		t.Fatalf("Failed to publish message: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for handler")
+	}
+	bus.Close()
+
+	if !strings.Contains(output.String(), `msg="Handler error"`) || !strings.Contains(output.String(), "error=boom") {
+		t.Errorf("Expected handler error to be logged through the injected logger, got %q", output.String())
+	}
+}
@@ -0,0 +1,191 @@
+package bus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+const MetadataTraceID = "trace_id"
+
+// PublishFunc has the signature of MessageBus.Publish.
+type PublishFunc func(ctx context.Context, channel string, msg *Message) error
+
+// Middleware intercepts messages on their way through a bus. Publish wraps
+// every call to Publish and Handle every call to a subscribed handler; either
+// may be nil.
+type Middleware struct {
+	Publish func(next PublishFunc) PublishFunc
+	Handle  func(next MessageHandler) MessageHandler
+}
+
+// MiddlewareBus runs a chain of middleware around another bus, so concerns
+// like logging and tracing are handled once for every channel. Middleware
+// added first runs outermost.
+type MiddlewareBus struct {
+	MessageBus
+	mu         sync.RWMutex
+	middleware []Middleware
+}
+
+func NewMiddlewareBus(inner MessageBus, middleware ...Middleware) *MiddlewareBus {
+	return &MiddlewareBus{
+		MessageBus: inner,
+		middleware: middleware,
+	}
+}
+
+// Use appends middleware to the chain. It also applies to handlers that
+// subscribed earlier.
+func (b *MiddlewareBus) Use(middleware ...Middleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.middleware = append(b.middleware, middleware...)
+}
+
+func (b *MiddlewareBus) chain() []Middleware {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.middleware
+}
+
+func (b *MiddlewareBus) Publish(ctx context.Context, channel string, msg *Message) error {
+	publish := PublishFunc(b.MessageBus.Publish)
+
+	chain := b.chain()
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Publish != nil {
+			publish = chain[i].Publish(publish)
+		}
+	}
+	return publish(ctx, channel, msg)
+}
+
+func (b *MiddlewareBus) Subscribe(channel string, handler MessageHandler) (string, error) {
+	return b.MessageBus.Subscribe(channel, func(ctx context.Context, msg *Message) error {
+		handle := handler
+
+		chain := b.chain()
+		for i := len(chain) - 1; i >= 0; i-- {
+			if chain[i].Handle != nil {
+				handle = chain[i].Handle(handle)
+			}
+		}
+		return handle(ctx, msg)
+	})
+}
+
+// TraceID returns the ID that ties a message to the messages published while
+// handling it, or "" if it has none.
+func (m *Message) TraceID() string {
+	if m.Metadata == nil {
+		return ""
+	}
+
+	traceID, _ := m.Metadata[MetadataTraceID].(string)
+	return traceID
+}
+
+type traceIDKey struct{}
+
+// TraceID returns the trace ID of the message being handled in ctx.
+func TraceID(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// Tracing gives every published message a trace ID. Messages published while
+// handling another one, such as replies, inherit its ID, so one exchange can
+// be followed through the logs of every component.
+func Tracing() Middleware {
+	return Middleware{
+		Publish: func(next PublishFunc) PublishFunc {
+			return func(ctx context.Context, channel string, msg *Message) error {
+				if msg.TraceID() == "" {
+					traceID := TraceID(ctx)
+					if traceID == "" {
+						traceID = newTraceID()
+					}
+					if msg.Metadata == nil {
+						msg.Metadata = make(map[string]interface{})
+					}
+					msg.Metadata[MetadataTraceID] = traceID
+				}
+				return next(ctx, channel, msg)
+			}
+		},
+		Handle: func(next MessageHandler) MessageHandler {
+			return func(ctx context.Context, msg *Message) error {
+				if traceID := msg.TraceID(); traceID != "" {
+					ctx = context.WithValue(ctx, traceIDKey{}, traceID)
+				}
+				return next(ctx, msg)
+			}
+		},
+	}
+}
+
+// newTraceID returns 16 random bytes in hex, the size of an OpenTelemetry
+// trace ID, so spans can share it.
+func newTraceID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Logging logs every message published and handled at debug level. Streaming
+// updates are left out, there are too many of them.
+func Logging(logger *slog.Logger) Middleware {
+	logger = logging.Or(logger, "bus")
+
+	return Middleware{
+		Publish: func(next PublishFunc) PublishFunc {
+			return func(ctx context.Context, channel string, msg *Message) error {
+				err := next(ctx, channel, msg)
+				if !msg.IsPartial() {
+					logger.Debug("Published message", "channel", channel, "chat_id", msg.ChatID, "message_id", msg.ID, "trace_id", msg.TraceID(), "error", err)
+				}
+				return err
+			}
+		},
+		Handle: func(next MessageHandler) MessageHandler {
+			return func(ctx context.Context, msg *Message) error {
+				start := time.Now()
+				err := next(ctx, msg)
+				if !msg.IsPartial() {
+					logger.Debug("Handled message", "channel", msg.Channel, "chat_id", msg.ChatID, "message_id", msg.ID, "trace_id", msg.TraceID(), "duration", time.Since(start), "error", err)
+				}
+				return err
+			}
+		},
+	}
+}
+
+// Recover turns a panic in a handler into an error, so it is retried and
+// dead-lettered like any other failure instead of taking the process down.
+func Recover(logger *slog.Logger) Middleware {
+	logger = logging.Or(logger, "bus")
+
+	return Middleware{
+		Handle: func(next MessageHandler) MessageHandler {
+			return func(ctx context.Context, msg *Message) (err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						logger.Error("Handler panicked", "channel", msg.Channel, "chat_id", msg.ChatID, "message_id", msg.ID, "panic", r, "stack", string(debug.Stack()))
+						err = fmt.Errorf("handler panicked: %v", r)
+					}
+				}()
+				return next(ctx, msg)
+			}
+		},
+	}
+}
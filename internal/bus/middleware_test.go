@@ -0,0 +1,113 @@
+package bus
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareBusOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inner := NewInMemoryMessageBus(ctx, nil)
+	inner.Start()
+	defer inner.Close()
+
+	calls := make(chan string, 10)
+	record := func(name string) Middleware {
+		return Middleware{
+			Publish: func(next PublishFunc) PublishFunc {
+				return func(ctx context.Context, channel string, msg *Message) error {
+					calls <- "publish " + name
+					return next(ctx, channel, msg)
+				}
+			},
+			Handle: func(next MessageHandler) MessageHandler {
+				return func(ctx context.Context, msg *Message) error {
+					calls <- "handle " + name
+					return next(ctx, msg)
+				}
+			},
+		}
+	}
+
+	messageBus := NewMiddlewareBus(inner, record("first"))
+	messageBus.Subscribe(ChannelCLI, func(ctx context.Context, msg *Message) error {
+		calls <- "handler"
+		return nil
+	})
+	// Added after subscribing, and still applied.
+	messageBus.Use(record("second"))
+
+	if err := messageBus.Publish(ctx, ChannelCLI, &Message{ID: "1", Content: "hi"}); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	var got []string
+	for len(got) < 5 {
+		select {
+		case call := <-calls:
+			got = append(got, call)
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout, got %v", got)
+		}
+	}
+
+	expected := "publish first,publish second,handle first,handle second,handler"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("Expected %s, got %s", expected, strings.Join(got, ","))
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inner := NewInMemoryMessageBus(ctx, nil)
+	inner.Start()
+	defer inner.Close()
+
+	messageBus := NewMiddlewareBus(inner, Recover(nil), Tracing())
+
+	replies := make(chan *Message, 1)
+	messageBus.Subscribe(ChannelCLI, func(ctx context.Context, msg *Message) error {
+		if msg.IsReply() {
+			replies <- msg
+			return nil
+		}
+
+		reply := &Message{ID: "reply", ChatID: msg.ChatID, Content: "hello"}
+		reply.SetReplyTo(msg)
+		return messageBus.Publish(ctx, ChannelCLI, reply)
+	})
+
+	request := &Message{ID: "request", ChatID: "chat", Content: "hi"}
+	if err := messageBus.Publish(ctx, ChannelCLI, request); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if request.TraceID() == "" {
+		t.Fatal("Expected the request to get a trace ID")
+	}
+
+	select {
+	case reply := <-replies:
+		if reply.TraceID() != request.TraceID() {
+			t.Errorf("Expected the reply to inherit trace ID %s, got %q", request.TraceID(), reply.TraceID())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the reply")
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	handler := Recover(nil).Handle(func(ctx context.Context, msg *Message) error {
+		panic("nil map")
+	})
+
+	err := handler(context.Background(), &Message{ID: "1"})
+	if err == nil || !strings.Contains(err.Error(), "nil map") {
+		t.Errorf("Expected the panic as an error, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const (
+	FaultLLMTimeout   = "llm_timeout"
+	FaultToolError    = "tool_error"
+	FaultBusDelay     = "bus_delay"
+	FaultStorageWrite = "storage_write"
+)
+
+var ErrInjected = errors.New("injected fault")
+
+// Config holds the probability (0-1) of each fault.
+type Config struct {
+	Seed                int64
+	LLMTimeout          float64
+	ToolError           float64
+	BusDelay            float64
+	MaxBusDelay         time.Duration
+	StorageWriteFailure float64
+	Logger              *slog.Logger
+}
+
+type Injector struct {
+	mu     sync.Mutex
+	config Config
+	rand   *rand.Rand
+	counts map[string]int
+	logger *slog.Logger
+}
+
+func NewInjector(config *Config) (*Injector, error) {
+	if config == nil {
+		return nil, fmt.Errorf("chaos config is required")
+	}
+
+	for name, p := range map[string]float64{
+		FaultLLMTimeout:   config.LLMTimeout,
+		FaultToolError:    config.ToolError,
+		FaultBusDelay:     config.BusDelay,
+		FaultStorageWrite: config.StorageWriteFailure,
+	} {
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("probability for %s must be between 0 and 1, got %v", name, p)
+		}
+	}
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	injector := &Injector{
+		config: *config,
+		rand:   rand.New(rand.NewSource(seed)),
+		counts: make(map[string]int),
+		logger: logging.Or(config.Logger, "chaos"),
+	}
+	if injector.config.MaxBusDelay <= 0 {
+		injector.config.MaxBusDelay = 2 * time.Second
+	}
+
+	injector.logger.Warn("Fault injection enabled",
+		"seed", seed,
+		FaultLLMTimeout, config.LLMTimeout,
+		FaultToolError, config.ToolError,
+		FaultBusDelay, config.BusDelay,
+		FaultStorageWrite, config.StorageWriteFailure)
+
+	return injector, nil
+}
+
+// Counts returns how many faults of each kind have been injected.
+func (i *Injector) Counts() map[string]int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	counts := make(map[string]int, len(i.counts))
+	for kind, n := range i.counts {
+		counts[kind] = n
+	}
+	return counts
+}
+
+func (i *Injector) hit(kind string, p float64, attrs ...any) bool {
+	if p <= 0 {
+		return false
+	}
+
+	i.mu.Lock()
+	hit := i.rand.Float64() < p
+	if hit {
+		i.counts[kind]++
+	}
+	i.mu.Unlock()
+
+	if hit {
+		i.logger.Info("Injecting fault", append([]any{"fault", kind}, attrs...)...)
+	}
+	return hit
+}
+
+func (i *Injector) busDelay() time.Duration {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return time.Duration(i.rand.Int63n(int64(i.config.MaxBusDelay)) + 1)
+}
+
+func injected(kind string) error {
+	return fmt.Errorf("%w: %s", ErrInjected, kind)
+}
+
+// WrapProvider is passed to llm.MultiModelManager.WrapProviders.
+func (i *Injector) WrapProvider(name string, provider llm.LLMProvider) llm.LLMProvider {
+	return &faultyProvider{LLMProvider: provider, name: name, injector: i}
+}
+
+type faultyProvider struct {
+	llm.LLMProvider
+	name     string
+	injector *Injector
+}
+
+func (p *faultyProvider) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	if p.injector.hit(FaultLLMTimeout, p.injector.config.LLMTimeout, "model", p.name) {
+		return nil, fmt.Errorf("%w: %w", injected(FaultLLMTimeout), llm.ErrTimeout)
+	}
+	return p.LLMProvider.Complete(ctx, req)
+}
+
+func (p *faultyProvider) StreamComplete(ctx context.Context, req *llm.CompletionRequest, callback func(chunk string) error) error {
+	if p.injector.hit(FaultLLMTimeout, p.injector.config.LLMTimeout, "model", p.name) {
+		return fmt.Errorf("%w: %w", injected(FaultLLMTimeout), llm.ErrTimeout)
+	}
+	return p.LLMProvider.StreamComplete(ctx, req, callback)
+}
+
+// WrapTool is passed to tools.ToolExecutor.SetWrapper.
+func (i *Injector) WrapTool(tool tools.Tool) tools.Tool {
+	return &faultyTool{Tool: tool, injector: i}
+}
+
+type faultyTool struct {
+	tools.Tool
+	injector *Injector
+}
+
+func (t *faultyTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	if t.injector.hit(FaultToolError, t.injector.config.ToolError, "tool", t.Name()) {
+		return "", &tools.ToolError{
+			Code:    "INJECTED_FAULT",
+			Message: "tool failed by fault injection",
+			Err:     ErrInjected,
+		}
+	}
+	return t.Tool.Execute(ctx, params)
+}
+
+type faultyBus struct {
+	bus.MessageBus
+	injector *Injector
+}
+
+func NewMessageBus(inner bus.MessageBus, injector *Injector) bus.MessageBus {
+	return &faultyBus{MessageBus: inner, injector: injector}
+}
+
+func (b *faultyBus) Publish(ctx context.Context, channel string, msg *bus.Message) error {
+	if b.injector.hit(FaultBusDelay, b.injector.config.BusDelay, "channel", channel) {
+		timer := time.NewTimer(b.injector.busDelay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return b.MessageBus.Publish(ctx, channel, msg)
+}
+
+type faultyStorage struct {
+	storage.Storage
+	injector *Injector
+}
+
+func NewStorage(inner storage.Storage, injector *Injector) storage.Storage {
+	return &faultyStorage{Storage: inner, injector: injector}
+}
+
+func (s *faultyStorage) WriteFile(ctx context.Context, path string, data []byte) error {
+	if s.injector.hit(FaultStorageWrite, s.injector.config.StorageWriteFailure, "path", path) {
+		return injected(FaultStorageWrite)
+	}
+	return s.Storage.WriteFile(ctx, path, data)
+}
+
+type faultySessionStorage struct {
+	storage.SessionStorage
+	injector *Injector
+}
+
+func NewSessionStorage(inner storage.SessionStorage, injector *Injector) storage.SessionStorage {
+	return &faultySessionStorage{SessionStorage: inner, injector: injector}
+}
+
+func (s *faultySessionStorage) SaveMessage(ctx context.Context, chatID string, role string, content string) error {
+	if s.injector.hit(FaultStorageWrite, s.injector.config.StorageWriteFailure, "chat_id", chatID) {
+		return injected(FaultStorageWrite)
+	}
+	return s.SessionStorage.SaveMessage(ctx, chatID, role, content)
+}
@@ -0,0 +1,157 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+type stubProvider struct {
+	calls int
+}
+
+func (p *stubProvider) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.calls++
+	return &llm.CompletionResponse{Content: "ok"}, nil
+}
+
+func (p *stubProvider) StreamComplete(ctx context.Context, req *llm.CompletionRequest, callback func(chunk string) error) error {
+	p.calls++
+	return callback("ok")
+}
+
+func (p *stubProvider) GetModel() string {
+	return "stub"
+}
+
+func TestInjectorFaults(t *testing.T) {
+	injector, err := NewInjector(&Config{Seed: 1, LLMTimeout: 1, ToolError: 1, StorageWriteFailure: 1})
+	if err != nil {
+		t.Fatalf("Failed to create injector: %v", err)
+	}
+	ctx := context.Background()
+
+	provider := &stubProvider{}
+	_, err = injector.WrapProvider("stub", provider).Complete(ctx, &llm.CompletionRequest{})
+	if !errors.Is(err, ErrInjected) || !llm.IsRetryableError(err) {
+		t.Errorf("Expected retryable injected timeout, got %v", err)
+	}
+	if provider.calls != 0 {
+		t.Error("Expected provider not to be called")
+	}
+
+	registry := tools.NewToolRegistry()
+	registry.Register(tools.NewEchoTool())
+	executor := tools.NewToolExecutor(registry)
+	executor.SetWrapper(injector.WrapTool)
+
+	call, err := executor.Execute(ctx, "echo", map[string]interface{}{"message": "hi"})
+	if err != nil || call.Error == "" {
+		t.Errorf("Expected tool call to fail, got %+v, %v", call, err)
+	}
+
+	dir := t.TempDir()
+	files := NewStorage(storage.NewFileStorage(dir), injector)
+	if err := files.WriteFile(ctx, "a.txt", []byte("x")); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected write failure, got %v", err)
+	}
+	if exists, _ := files.FileExists(ctx, "a.txt"); exists {
+		t.Error("Expected failed write to leave no file")
+	}
+
+	sessions := NewSessionStorage(storage.NewFileSystemSessionStorage(dir+"/sessions"), injector)
+	if err := sessions.SaveMessage(ctx, "chat", "user", "hi"); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected session write failure, got %v", err)
+	}
+
+	counts := injector.Counts()
+	if counts[FaultLLMTimeout] != 1 || counts[FaultToolError] != 1 || counts[FaultStorageWrite] != 2 {
+		t.Errorf("Unexpected fault counts %v", counts)
+	}
+}
+
+func TestInjectorPassesThroughWithZeroProbability(t *testing.T) {
+	injector, err := NewInjector(&Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Failed to create injector: %v", err)
+	}
+	ctx := context.Background()
+
+	provider := &stubProvider{}
+	resp, err := injector.WrapProvider("stub", provider).Complete(ctx, &llm.CompletionRequest{})
+	if err != nil || resp.Content != "ok" {
+		t.Errorf("Expected provider response, got %v, %v", resp, err)
+	}
+
+	files := NewStorage(storage.NewFileStorage(t.TempDir()), injector)
+	if err := files.WriteFile(ctx, "a.txt", []byte("x")); err != nil {
+		t.Errorf("Expected write to succeed, got %v", err)
+	}
+	if len(injector.Counts()) != 0 {
+		t.Errorf("Expected no faults, got %v", injector.Counts())
+	}
+}
+
+func TestInjectorIsReproducibleWithSeed(t *testing.T) {
+	run := func() []bool {
+		injector, _ := NewInjector(&Config{Seed: 42, ToolError: 0.5})
+		hits := make([]bool, 20)
+		for i := range hits {
+			hits[i] = injector.hit(FaultToolError, 0.5)
+		}
+		return hits
+	}
+
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected identical fault sequence for the same seed, differs at %d", i)
+		}
+	}
+}
+
+func TestBusDelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inner := bus.NewInMemoryMessageBus(ctx, nil)
+	inner.Start()
+	defer inner.Close()
+
+	injector, _ := NewInjector(&Config{Seed: 1, BusDelay: 1, MaxBusDelay: 50 * time.Millisecond})
+	messageBus := NewMessageBus(inner, injector)
+
+	received := make(chan struct{}, 1)
+	messageBus.Subscribe(bus.ChannelCLI, func(ctx context.Context, msg *bus.Message) error {
+		received <- struct{}{}
+		return nil
+	})
+
+	if err := messageBus.Publish(ctx, bus.ChannelCLI, &bus.Message{Content: "hi"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("Expected delayed message to be delivered")
+	}
+
+	cancelled, stop := context.WithCancel(ctx)
+	stop()
+	injector.config.MaxBusDelay = time.Hour
+	if err := messageBus.Publish(cancelled, bus.ChannelCLI, &bus.Message{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected delayed publish to respect cancellation, got %v", err)
+	}
+}
+
+func TestNewInjectorRejectsInvalidProbability(t *testing.T) {
+	if _, err := NewInjector(&Config{ToolError: 1.5}); err == nil {
+		t.Error("Expected error for probability above 1")
+	}
+}
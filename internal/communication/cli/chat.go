@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const defaultReplyTimeout = 2 * time.Minute
+
+// SetChatMode turns the CLI into a chat REPL: plain lines are sent to the
+// agent, "/name" runs a registered command and any other slash command, such
+// as /new, is passed on to the agent. Each message waits for its reply, which
+// is streamed as it is generated.
+func (c *CLI) SetChatMode(enabled bool) {
+	c.chat = enabled
+}
+
+// SetHistoryFile keeps the line editor history in path between chat sessions.
+func (c *CLI) SetHistoryFile(path string) {
+	c.historyFile = path
+}
+
+func (c *CLI) SetOutput(out io.Writer) {
+	c.out = out
+}
+
+func (c *CLI) SetReplyTimeout(timeout time.Duration) {
+	c.replyTimeout = timeout
+}
+
+func (c *CLI) dispatchChat(input string) error {
+	if strings.HasPrefix(input, "/") {
+		firstLine, _, _ := strings.Cut(input, "\n")
+		args := strings.Fields(firstLine)
+		if cmd, ok := c.commands[strings.ToLower(strings.TrimPrefix(args[0], "/"))]; ok {
+			return cmd.Handler(args[1:])
+		}
+	}
+
+	return c.sendMessage(input)
+}
+
+func (c *CLI) expectReply(messageID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.awaiting = messageID
+	select {
+	case <-c.replied:
+	default:
+	}
+}
+
+func (c *CLI) waitForReply() {
+	select {
+	case <-c.replied:
+	case <-c.ctx.Done():
+	case <-time.After(c.replyTimeout):
+		c.expectReply("")
+		fmt.Fprintln(c.out, "No reply yet, it will be shown when it arrives.")
+	}
+}
+
+// showReply prints a reply in chat mode. Partial replies carry the whole text
+// so far, so only the part not printed yet is written. The bus does not keep
+// them in order, so those arriving late are dropped.
+func (c *CLI) showReply(msg *bus.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if msg.IsPartial() {
+		if msg.ID == c.finished || (c.streamID == msg.ID && strings.HasPrefix(c.streamed, msg.Content)) {
+			return
+		}
+		if c.streamID != msg.ID {
+			c.streamID, c.streamed = msg.ID, ""
+		}
+		c.printAfter(c.streamed, msg.Content)
+		c.streamed = msg.Content
+		return
+	}
+
+	var reply strings.Builder
+	if c.streamID == msg.ID && c.streamed != "" {
+		c.printAfter(c.streamed, msg.Content)
+		reply.WriteString("\n")
+	} else {
+		reply.WriteString(RenderResponse(WrapText(msg.Content, c.Capabilities().LineWidth), c.color) + "\n")
+	}
+	c.streamID, c.streamed = "", ""
+	c.finished = msg.ID
+
+	if toolUses := msg.ToolUses(); len(toolUses) > 0 {
+		reply.WriteString(RenderToolUses(toolUses, c.color) + "\n")
+	}
+
+	switch {
+	case c.awaiting != "" && msg.ReplyTo() == c.awaiting:
+		fmt.Fprint(c.out, reply.String())
+		c.awaiting = ""
+		select {
+		case c.replied <- struct{}{}:
+		default:
+		}
+	case c.awaiting == "":
+		// Replies nobody is waiting for, such as scheduled task results,
+		// arrive while the prompt is shown.
+		c.printAbovePrompt(reply.String())
+	default:
+		fmt.Fprint(c.out, reply.String())
+	}
+}
+
+// printAbovePrompt shows text that arrives while the user may be typing,
+// keeping the prompt and their input below it.
+func (c *CLI) printAbovePrompt(text string) {
+	if editor, ok := c.reader.(*Editor); ok {
+		editor.PrintAbove(text)
+		return
+	}
+	fmt.Fprint(c.out, text+Prompt)
+}
+
+// printAfter writes what text adds to printed. When the agent starts over,
+// e.g. after a tool call, the new text goes on a fresh line.
+func (c *CLI) printAfter(printed, text string) {
+	if rest, ok := strings.CutPrefix(text, printed); ok {
+		fmt.Fprint(c.out, rest)
+		return
+	}
+	fmt.Fprint(c.out, "\n"+text)
+}
+
+func (c *CLI) loadHistory() {
+	editor, ok := c.reader.(*Editor)
+	if !ok || c.historyFile == "" {
+		return
+	}
+	if err := editor.History().Load(c.historyFile); err != nil {
+		logger.Warn("Failed to load CLI history", "path", c.historyFile, "error", err)
+	}
+}
+
+func (c *CLI) saveHistory() {
+	editor, ok := c.reader.(*Editor)
+	if !ok || c.historyFile == "" {
+		return
+	}
+	if err := editor.History().Save(c.historyFile); err != nil {
+		logger.Warn("Failed to save CLI history", "path", c.historyFile, "error", err)
+	}
+}
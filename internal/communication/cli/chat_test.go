@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestChatModeStreamsReplies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *bus.Message, 4)
+	messageBus.Subscribe(bus.ChannelCLI, func(ctx context.Context, msg *bus.Message) error {
+		if msg.IsReply() {
+			return nil
+		}
+		received <- msg
+
+		for i, content := range []string{"Hel", "Hello"} {
+			reply := &bus.Message{ID: "agent-" + msg.ID, Channel: bus.ChannelCLI, ChatID: msg.ChatID, Content: content}
+			if i == 0 {
+				reply.Metadata = map[string]interface{}{bus.MetadataPartial: true}
+			}
+			reply.SetReplyTo(msg)
+			messageBus.Publish(ctx, bus.ChannelCLI, reply)
+		}
+		return nil
+	})
+
+	var out bytes.Buffer
+	repl := NewCLI(messageBus, ctx)
+	repl.SetChatMode(true)
+	repl.SetOutput(&out)
+	repl.SetReplyTimeout(2 * time.Second)
+	repl.SetReader(NewScannerReader(strings.NewReader("hi there\n/new\n/ping a b\n/exit\nnever sent\n"), io.Discard))
+
+	var pinged []string
+	repl.RegisterCommand("ping", Command{Name: "ping", Handler: func(args []string) error {
+		pinged = args
+		return nil
+	}})
+	messageBus.Subscribe(bus.ChannelCLI, NewHandler(repl).HandleMessage)
+
+	if err := repl.Start(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, expected := range []string{"hi there", "/new"} {
+		select {
+		case msg := <-received:
+			if msg.Content != expected || !msg.WantsStreaming() {
+				t.Errorf("Expected streaming request %q, got %q", expected, msg.Content)
+			}
+		default:
+			t.Errorf("Expected %q to be sent to the agent", expected)
+		}
+	}
+	if len(received) != 0 {
+		t.Errorf("Expected input after /exit to be ignored, got %d more messages", len(received))
+	}
+
+	if strings.Join(pinged, " ") != "a b" {
+		t.Errorf("Expected /ping to run the registered command, got %v", pinged)
+	}
+	if got := out.String(); got != "Hello\nHello\n" {
+		t.Errorf("Expected each streamed reply once, got %q", got)
+	}
+}
+
+func TestHistorySaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	history := NewHistory(10)
+	history.Add("first")
+	history.Add("multi\nline")
+	if err := history.Save(path); err != nil {
+		t.Fatalf("Failed to save history: %v", err)
+	}
+
+	loaded := NewHistory(10)
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if loaded.Len() != 2 || loaded.Get(1) != "multi\nline" {
+		t.Errorf("Expected saved entries back, got %d entries", loaded.Len())
+	}
+
+	if err := NewHistory(10).Load(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("Expected missing history file to be ignored, got %v", err)
+	}
+}
@@ -1,11 +1,15 @@
 package cli
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/wjffsx/miniclaw_go/internal/bus"
 )
@@ -15,11 +19,25 @@ const (
 )
 
 type CLI struct {
-	scanner    *bufio.Scanner
+	reader     LineReader
+	color      bool
 	messageBus bus.MessageBus
 	ctx        context.Context
 	commands   map[string]Command
 	chatID     string
+	out        io.Writer
+	exiting    bool
+
+	chat         bool
+	historyFile  string
+	replyTimeout time.Duration
+	replied      chan struct{}
+
+	mu       sync.Mutex
+	awaiting string
+	streamID string
+	streamed string
+	finished string
 }
 
 type Command struct {
@@ -31,11 +49,16 @@ type Command struct {
 
 func NewCLI(messageBus bus.MessageBus, ctx context.Context) *CLI {
 	cli := &CLI{
-		scanner:    bufio.NewScanner(os.Stdin),
+		reader:     NewLineReader(os.Stdin, os.Stdout),
+		color:      isTerminal(int(os.Stdout.Fd())) && os.Getenv("NO_COLOR") == "",
 		messageBus: messageBus,
 		ctx:        ctx,
 		commands:   make(map[string]Command),
 		chatID:     "cli",
+		out:        os.Stdout,
+
+		replyTimeout: defaultReplyTimeout,
+		replied:      make(chan struct{}, 1),
 	}
 
 	cli.registerCommands()
@@ -52,7 +75,7 @@ func (c *CLI) registerCommands() {
 
 	c.commands["send"] = Command{
 		Name:        "send",
-		Description: "Send a message to the agent",
+		Description: "Send a message to the agent (use 'send <<EOF' for multi-line input)",
 		Handler:     c.cmdSend,
 		Usage:       "send <message>",
 	}
@@ -80,8 +103,16 @@ func (c *CLI) registerCommands() {
 }
 
 func (c *CLI) Start() error {
-	fmt.Println("MiniClaw CLI")
-	fmt.Println("Type 'help' for available commands")
+	if c.chat {
+		c.loadHistory()
+		defer c.saveHistory()
+
+		fmt.Println("MiniClaw chat")
+		fmt.Println("Type a message to talk to the agent, /help for commands or /exit to quit")
+	} else {
+		fmt.Println("MiniClaw CLI")
+		fmt.Println("Type 'help' for available commands")
+	}
 	fmt.Println()
 
 	for {
@@ -90,41 +121,121 @@ func (c *CLI) Start() error {
 			fmt.Println("CLI stopped")
 			return nil
 		default:
-			fmt.Print(Prompt)
-			if !c.scanner.Scan() {
+			input, err := c.readInput()
+			if errors.Is(err, ErrInterrupted) {
+				continue
+			}
+			if err != nil {
+				if err != io.EOF {
+					fmt.Printf("Error: %v\n", err)
+				}
 				fmt.Println()
 				return nil
 			}
 
-			line := strings.TrimSpace(c.scanner.Text())
-			if line == "" {
+			if strings.TrimSpace(input) == "" {
 				continue
 			}
 
-			args := strings.Fields(line)
-			if len(args) == 0 {
-				continue
+			if err := c.dispatch(input); err != nil {
+				fmt.Printf("Error: %v\n", err)
 			}
-
-			cmdName := strings.ToLower(args[0])
-			cmd, ok := c.commands[cmdName]
-			if !ok {
-				fmt.Printf("Unknown command: %s\n", cmdName)
-				fmt.Println("Type 'help' for available commands")
-				continue
+			if c.exiting {
+				return nil
 			}
+		}
+	}
+}
 
-			if err := cmd.Handler(args[1:]); err != nil {
-				fmt.Printf("Error: %v\n", err)
-			}
+func (c *CLI) readInput() (string, error) {
+	line, err := c.reader.ReadLine(Prompt)
+	if err != nil {
+		return "", err
+	}
+
+	prefix, tag, ok := parseHeredoc(line)
+	if !ok {
+		return line, nil
+	}
+
+	lines := make([]string, 0)
+	for {
+		next, err := c.reader.ReadLine(ContinuationPrompt)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(next) == tag {
+			break
 		}
+		lines = append(lines, next)
+	}
+
+	body := strings.Join(lines, "\n")
+	if prefix == "" {
+		return body, nil
 	}
+	return prefix + "\n" + body, nil
+}
+
+// heredocPattern matches a <<WORD that ends the line and starts a word, so
+// expressions like 1<<3 are sent as they are.
+var heredocPattern = regexp.MustCompile(`(?:^|\s)<<([A-Za-z_][A-Za-z0-9_]*)\s*$`)
+
+func parseHeredoc(line string) (string, string, bool) {
+	match := heredocPattern.FindStringSubmatchIndex(line)
+	if match == nil {
+		return "", "", false
+	}
+
+	return strings.TrimSpace(line[:match[0]]), line[match[2]:match[3]], true
+}
+
+func (c *CLI) dispatch(input string) error {
+	input = strings.TrimSpace(input)
+	if c.chat {
+		return c.dispatchChat(input)
+	}
+	firstLine, rest, multiline := strings.Cut(input, "\n")
+
+	args := strings.Fields(firstLine)
+	if len(args) == 0 {
+		return c.sendMessage(rest)
+	}
+
+	cmdName := strings.ToLower(args[0])
+	cmd, ok := c.commands[cmdName]
+	if !ok {
+		if multiline {
+			return c.sendMessage(input)
+		}
+		fmt.Printf("Unknown command: %s\n", cmdName)
+		fmt.Println("Type 'help' for available commands")
+		return nil
+	}
+
+	if cmdName == "send" {
+		message := strings.TrimSpace(strings.TrimPrefix(input, args[0]))
+		if message == "" {
+			return fmt.Errorf("usage: send <message>")
+		}
+		return c.sendMessage(message)
+	}
+
+	return cmd.Handler(strings.Fields(input)[1:])
 }
 
 func (c *CLI) Stop() error {
 	return nil
 }
 
+func (c *CLI) SetReader(reader LineReader) {
+	c.reader = reader
+}
+
+func (c *CLI) SetColor(color bool) {
+	c.color = color
+}
+
 func (c *CLI) RegisterCommand(name string, cmd Command) {
 	c.commands[name] = cmd
 }
@@ -201,12 +312,17 @@ func (c *CLI) cmdHelp(args []string) error {
 		return nil
 	}
 
+	prefix := ""
+	if c.chat {
+		prefix = "/"
+	}
+
 	fmt.Println("Available commands:")
 	for _, cmd := range c.commands {
-		fmt.Printf("  %-15s - %s\n", cmd.Name, cmd.Description)
+		fmt.Printf("  %-15s - %s\n", prefix+cmd.Name, cmd.Description)
 	}
 	fmt.Println()
-	fmt.Println("Use 'help <command>' for more information about a command")
+	fmt.Printf("Use '%shelp <command>' for more information about a command\n", prefix)
 	return nil
 }
 
@@ -215,19 +331,46 @@ func (c *CLI) cmdSend(args []string) error {
 		return fmt.Errorf("usage: send <message>")
 	}
 
-	message := strings.Join(args, " ")
+	return c.sendMessage(strings.Join(args, " "))
+}
+
+// Capabilities reports the current terminal width so replies fit it. Output
+// that is not a terminal is not wrapped.
+func (c *CLI) Capabilities() bus.Capabilities {
+	caps := bus.ChannelCapabilities(bus.ChannelCLI)
+	caps.LineWidth = 0
+	if fd := int(os.Stdout.Fd()); isTerminal(fd) {
+		caps.LineWidth = terminalWidth(fd)
+	}
+	return caps
+}
 
+func (c *CLI) sendMessage(message string) error {
 	msg := &bus.Message{
-		ID:      fmt.Sprintf("cli-%d", 0),
+		ID:      fmt.Sprintf("cli-%d", time.Now().UnixNano()),
 		Channel: bus.ChannelCLI,
 		ChatID:  c.chatID,
 		Content: message,
+		Metadata: map[string]interface{}{
+			bus.MetadataCapabilities: c.Capabilities(),
+		},
+	}
+
+	if c.chat {
+		msg.Metadata[bus.MetadataStreaming] = true
+		c.expectReply(msg.ID)
 	}
 
 	if err := c.messageBus.Publish(c.ctx, bus.ChannelCLI, msg); err != nil {
+		c.expectReply("")
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
+	if c.chat {
+		c.waitForReply()
+		return nil
+	}
+
 	fmt.Printf("Message sent: %s\n", message)
 	return nil
 }
@@ -240,5 +383,6 @@ func (c *CLI) cmdConfig(args []string) error {
 
 func (c *CLI) cmdExit(args []string) error {
 	fmt.Println("Exiting...")
+	c.exiting = true
 	return nil
 }
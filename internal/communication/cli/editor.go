@@ -0,0 +1,535 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ContinuationPrompt = "...> "
+	defaultHistorySize = 500
+	maxScannerLine     = 1024 * 1024
+	tabWidth           = 4
+)
+
+var ErrInterrupted = errors.New("interrupted")
+
+type LineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+func NewLineReader(in *os.File, out *os.File) LineReader {
+	if isTerminal(int(in.Fd())) && isTerminal(int(out.Fd())) {
+		return NewEditor(in, out, int(in.Fd()))
+	}
+	return NewScannerReader(in, out)
+}
+
+type ScannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func NewScannerReader(in io.Reader, out io.Writer) *ScannerReader {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScannerLine)
+
+	return &ScannerReader{
+		scanner: scanner,
+		out:     out,
+	}
+}
+
+func (r *ScannerReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+
+	return r.scanner.Text(), nil
+}
+
+type History struct {
+	entries []string
+	max     int
+}
+
+func NewHistory(max int) *History {
+	if max <= 0 {
+		max = defaultHistorySize
+	}
+
+	return &History{
+		entries: make([]string, 0),
+		max:     max,
+	}
+}
+
+func (h *History) Add(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > h.max {
+		h.entries = h.entries[len(h.entries)-h.max:]
+	}
+}
+
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+func (h *History) Get(index int) string {
+	if index < 0 || index >= len(h.entries) {
+		return ""
+	}
+	return h.entries[index]
+}
+
+// Load adds the entries saved in path. A missing file is not an error.
+func (h *History) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// Entries are quoted, so pasted multi-line input stays one entry.
+		if entry, err := strconv.Unquote(line); err == nil {
+			h.Add(entry)
+		}
+	}
+	return nil
+}
+
+func (h *History) Save(path string) error {
+	var builder strings.Builder
+	for _, entry := range h.entries {
+		builder.WriteString(strconv.Quote(entry))
+		builder.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(builder.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+type Editor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	fd      int
+	history *History
+
+	mu           sync.Mutex
+	reading      bool
+	buf          []rune
+	pos          int
+	prompt       string
+	cursorRow    int
+	pasting      bool
+	historyIndex int
+	pending      string
+}
+
+func NewEditor(in io.Reader, out io.Writer, fd int) *Editor {
+	return &Editor{
+		in:      bufio.NewReader(in),
+		out:     out,
+		fd:      fd,
+		history: NewHistory(defaultHistorySize),
+	}
+}
+
+func (e *Editor) History() *History {
+	return e.history
+}
+
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.fd >= 0 {
+		state, err := makeRaw(e.fd)
+		if err != nil {
+			return "", fmt.Errorf("failed to enter raw mode: %w", err)
+		}
+		defer restoreTerminal(e.fd, state)
+	}
+
+	fmt.Fprint(e.out, "\x1b[?2004h")
+	defer fmt.Fprint(e.out, "\x1b[?2004l")
+
+	e.mu.Lock()
+	e.reading = true
+	e.buf = e.buf[:0]
+	e.pos = 0
+	e.prompt = prompt
+	e.cursorRow = 0
+	e.pasting = false
+	e.historyIndex = e.history.Len()
+	e.pending = ""
+	e.refresh()
+	e.mu.Unlock()
+
+	defer func() {
+		e.mu.Lock()
+		e.reading = false
+		e.mu.Unlock()
+	}()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		e.mu.Lock()
+		line, done, err := e.handleRune(r)
+		e.mu.Unlock()
+		if done {
+			return line, err
+		}
+	}
+}
+
+// PrintAbove writes text, such as a reply that nobody waited for, above the
+// line being edited and draws the prompt and the line again below it.
+func (e *Editor) PrintAbove(text string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.reading {
+		fmt.Fprint(e.out, text)
+		return
+	}
+
+	if e.cursorRow > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dA", e.cursorRow)
+	}
+	fmt.Fprint(e.out, "\r\x1b[J"+strings.ReplaceAll(text, "\n", "\r\n"))
+	if !strings.HasSuffix(text, "\n") {
+		fmt.Fprint(e.out, "\r\n")
+	}
+	e.cursorRow = 0
+	e.refresh()
+}
+
+func (e *Editor) handleRune(r rune) (string, bool, error) {
+	switch r {
+	case '\r', '\n':
+		if e.pasting {
+			e.insert('\n')
+			return "", false, nil
+		}
+		e.moveToEnd()
+		fmt.Fprint(e.out, "\r\n")
+		line := string(e.buf)
+		e.history.Add(line)
+		return line, true, nil
+	case 3:
+		e.moveToEnd()
+		fmt.Fprint(e.out, "^C\r\n")
+		return "", true, ErrInterrupted
+	case 4:
+		if len(e.buf) == 0 {
+			fmt.Fprint(e.out, "\r\n")
+			return "", true, io.EOF
+		}
+		e.deleteAt(e.pos)
+	case 127, 8:
+		if e.pos > 0 {
+			e.deleteAt(e.pos - 1)
+			e.pos--
+		}
+	case 1:
+		e.pos = e.lineStart()
+	case 5:
+		e.pos = e.lineEnd()
+	case 2:
+		if e.pos > 0 {
+			e.pos--
+		}
+	case 6:
+		if e.pos < len(e.buf) {
+			e.pos++
+		}
+	case 11:
+		e.buf = append(e.buf[:e.pos], e.buf[e.lineEnd():]...)
+	case 21:
+		start := e.lineStart()
+		e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+		e.pos = start
+	case 23:
+		e.deleteWord()
+	case 12:
+		fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		e.cursorRow = 0
+	case 16:
+		e.historyMove(-1)
+	case 14:
+		e.historyMove(1)
+	case 27:
+		switch e.readEscape() {
+		case "[A", "OA":
+			if e.cursorLine() > 0 {
+				e.moveVertical(-1)
+			} else {
+				e.historyMove(-1)
+			}
+		case "[B", "OB":
+			if e.cursorLine() < strings.Count(string(e.buf), "\n") {
+				e.moveVertical(1)
+			} else {
+				e.historyMove(1)
+			}
+		case "[C", "OC":
+			if e.pos < len(e.buf) {
+				e.pos++
+			}
+		case "[D", "OD":
+			if e.pos > 0 {
+				e.pos--
+			}
+		case "[H", "OH", "[1~", "[7~":
+			e.pos = e.lineStart()
+		case "[F", "OF", "[4~", "[8~":
+			e.pos = e.lineEnd()
+		case "[3~":
+			if e.pos < len(e.buf) {
+				e.deleteAt(e.pos)
+			}
+		case "[200~":
+			e.pasting = true
+			return "", false, nil
+		case "[201~":
+			e.pasting = false
+		}
+	case '\t':
+		// Pasted tabs are kept, code and Makefiles need them.
+		if e.pasting {
+			e.insert('\t')
+			return "", false, nil
+		}
+		for i := 0; i < tabWidth; i++ {
+			e.insert(' ')
+		}
+	default:
+		if r < 32 {
+			return "", false, nil
+		}
+		e.insert(r)
+	}
+
+	if !e.pasting {
+		e.refresh()
+	}
+	return "", false, nil
+}
+
+// readEscape reads the rest of an escape sequence. Terminals send a
+// sequence at once, so an escape with nothing after it is a lone ESC key.
+func (e *Editor) readEscape() string {
+	if e.in.Buffered() == 0 {
+		return ""
+	}
+
+	first, _, err := e.in.ReadRune()
+	if err != nil {
+		return ""
+	}
+
+	if first != '[' && first != 'O' {
+		return string(first)
+	}
+
+	seq := []rune{first}
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return string(seq)
+		}
+		seq = append(seq, r)
+		if (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || r == '~' {
+			return string(seq)
+		}
+	}
+}
+
+func (e *Editor) insert(r rune) {
+	e.buf = append(e.buf, 0)
+	copy(e.buf[e.pos+1:], e.buf[e.pos:])
+	e.buf[e.pos] = r
+	e.pos++
+}
+
+func (e *Editor) deleteAt(index int) {
+	e.buf = append(e.buf[:index], e.buf[index+1:]...)
+}
+
+func (e *Editor) deleteWord() {
+	start := e.pos
+	for start > 0 && e.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && e.buf[start-1] != ' ' && e.buf[start-1] != '\n' {
+		start--
+	}
+	e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+	e.pos = start
+}
+
+func (e *Editor) lineStart() int {
+	i := e.pos
+	for i > 0 && e.buf[i-1] != '\n' {
+		i--
+	}
+	return i
+}
+
+func (e *Editor) lineEnd() int {
+	i := e.pos
+	for i < len(e.buf) && e.buf[i] != '\n' {
+		i++
+	}
+	return i
+}
+
+func (e *Editor) cursorLine() int {
+	return strings.Count(string(e.buf[:e.pos]), "\n")
+}
+
+func (e *Editor) moveVertical(delta int) {
+	col := e.pos - e.lineStart()
+
+	if delta < 0 {
+		e.pos = e.lineStart() - 1
+		e.pos = e.lineStart()
+	} else {
+		e.pos = e.lineEnd() + 1
+	}
+
+	end := e.lineEnd()
+	if e.pos+col < end {
+		e.pos += col
+	} else {
+		e.pos = end
+	}
+}
+
+func (e *Editor) moveToEnd() {
+	e.pos = len(e.buf)
+	e.refresh()
+}
+
+func (e *Editor) historyMove(delta int) {
+	next := e.historyIndex + delta
+	if next < 0 || next > e.history.Len() {
+		return
+	}
+
+	if e.historyIndex == e.history.Len() {
+		e.pending = string(e.buf)
+	}
+
+	if next == e.history.Len() {
+		e.buf = []rune(e.pending)
+	} else {
+		e.buf = []rune(e.history.Get(next))
+	}
+	e.pos = len(e.buf)
+	e.historyIndex = next
+}
+
+func (e *Editor) refresh() {
+	width := 80
+	if e.fd >= 0 {
+		width = terminalWidth(e.fd)
+	}
+
+	var out strings.Builder
+	if e.cursorRow > 0 {
+		fmt.Fprintf(&out, "\x1b[%dA", e.cursorRow)
+	}
+	out.WriteString("\r\x1b[J")
+
+	lines := strings.Split(string(e.buf), "\n")
+	cursorLine := e.cursorLine()
+	cursorCol := displayWidth(e.buf[e.lineStart():e.pos])
+
+	rows := 0
+	cursorRow, cursorScreenCol := 0, 0
+	for i, line := range lines {
+		prefix := e.prompt
+		if i > 0 {
+			prefix = ContinuationPrompt
+			out.WriteString("\r\n")
+		}
+		out.WriteString(prefix)
+		out.WriteString(strings.ReplaceAll(line, "\t", strings.Repeat(" ", tabWidth)))
+
+		length := len([]rune(prefix)) + displayWidth([]rune(line))
+		if i == cursorLine {
+			col := len([]rune(prefix)) + cursorCol
+			cursorRow = rows + col/width
+			cursorScreenCol = col % width
+		}
+
+		if i < len(lines)-1 {
+			if length > 0 {
+				rows += (length - 1) / width
+			}
+			rows++
+			continue
+		}
+
+		if length > 0 && length%width == 0 {
+			out.WriteString("\r\n")
+		}
+		rows += length / width
+	}
+
+	if up := rows - cursorRow; up > 0 {
+		fmt.Fprintf(&out, "\x1b[%dA", up)
+	}
+	out.WriteString("\r")
+	if cursorScreenCol > 0 {
+		fmt.Fprintf(&out, "\x1b[%dC", cursorScreenCol)
+	}
+
+	e.cursorRow = cursorRow
+	fmt.Fprint(e.out, out.String())
+}
+
+// displayWidth is the number of columns runes take, with tabs shown as
+// spaces.
+func displayWidth(runes []rune) int {
+	width := 0
+	for _, r := range runes {
+		if r == '\t' {
+			width += tabWidth
+		} else {
+			width++
+		}
+	}
+	return width
+}
@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestEditorReadLine(t *testing.T) {
+	editor := NewEditor(strings.NewReader("hello\r"), &bytes.Buffer{}, -1)
+
+	line, err := editor.ReadLine(Prompt)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if line != "hello" {
+		t.Errorf("Expected 'hello', got '%s'", line)
+	}
+}
+
+func TestEditorEditing(t *testing.T) {
+	input := "helo\x1b[Dl\x1b[F!\x7f?\x01>\r"
+	editor := NewEditor(strings.NewReader(input), &bytes.Buffer{}, -1)
+
+	line, err := editor.ReadLine(Prompt)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if line != ">hello?" {
+		t.Errorf("Expected '>hello?', got '%s'", line)
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	input := "first\rsecond\r\x1b[A\x1b[A\r"
+	editor := NewEditor(strings.NewReader(input), &bytes.Buffer{}, -1)
+
+	for _, expected := range []string{"first", "second", "first"} {
+		line, err := editor.ReadLine(Prompt)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if line != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, line)
+		}
+	}
+
+	if editor.History().Len() != 3 {
+		t.Errorf("Expected 3 history entries, got %d", editor.History().Len())
+	}
+}
+
+func TestEditorBracketedPaste(t *testing.T) {
+	input := "\x1b[200~func main() {\r\tfmt.Println(1)\r}\x1b[201~\r"
+	editor := NewEditor(strings.NewReader(input), &bytes.Buffer{}, -1)
+
+	line, err := editor.ReadLine(Prompt)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "func main() {\n\tfmt.Println(1)\n}"
+	if line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+}
+
+func TestEditorLoneEscape(t *testing.T) {
+	in, writer := io.Pipe()
+	editor := NewEditor(in, &bytes.Buffer{}, -1)
+
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := editor.ReadLine(Prompt)
+		lines <- line
+	}()
+
+	writer.Write([]byte("ab\x1b"))
+	writer.Write([]byte("c\r"))
+
+	select {
+	case line := <-lines:
+		if line != "abc" {
+			t.Errorf("Expected a lone ESC to be ignored, got %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a lone ESC not to wait for an escape sequence")
+	}
+}
+
+func TestEditorPrintAbove(t *testing.T) {
+	in, writer := io.Pipe()
+	out := &safeBuffer{}
+	editor := NewEditor(in, out, -1)
+
+	done := make(chan struct{})
+	go func() {
+		editor.ReadLine(Prompt)
+		close(done)
+	}()
+
+	writer.Write([]byte("draft"))
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), "draft") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	editor.PrintAbove("Reminder: stand-up\n")
+	output := out.String()
+	after := output[strings.LastIndex(output, "Reminder: stand-up"):]
+	if !strings.Contains(after, Prompt+"draft") {
+		t.Errorf("Expected the prompt and input to be drawn again after the text, got %q", after)
+	}
+
+	writer.Write([]byte("\r"))
+	<-done
+}
+
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestEditorInterruptAndEOF(t *testing.T) {
+	editor := NewEditor(strings.NewReader("abc\x03\x04"), &bytes.Buffer{}, -1)
+
+	if _, err := editor.ReadLine(Prompt); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Expected ErrInterrupted, got %v", err)
+	}
+
+	if _, err := editor.ReadLine(Prompt); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	history := NewHistory(2)
+	history.Add("a")
+	history.Add("a")
+	history.Add("b")
+	history.Add("c")
+
+	if history.Len() != 2 {
+		t.Fatalf("Expected 2 entries, got %d", history.Len())
+	}
+
+	if history.Get(0) != "b" || history.Get(1) != "c" {
+		t.Errorf("Expected [b c], got [%s %s]", history.Get(0), history.Get(1))
+	}
+}
+
+func TestParseHeredoc(t *testing.T) {
+	prefix, tag, ok := parseHeredoc("send <<EOF")
+	if !ok || prefix != "send" || tag != "EOF" {
+		t.Errorf("Unexpected heredoc parse: %q %q %v", prefix, tag, ok)
+	}
+
+	for _, line := range []string{"send hello", "send 1<<3", "send a <<b c", "x<<EOF"} {
+		if _, _, ok := parseHeredoc(line); ok {
+			t.Errorf("Expected no heredoc for %q", line)
+		}
+	}
+}
+
+func TestCLIHeredocSend(t *testing.T) {
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan string, 1)
+	messageBus.Subscribe(bus.ChannelCLI, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg.Content
+		return nil
+	})
+
+	cli := NewCLI(messageBus, context.Background())
+	cli.SetReader(NewScannerReader(strings.NewReader("send <<EOF\nline one\n  line two\nEOF\n"), io.Discard))
+
+	input, err := cli.readInput()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := cli.dispatch(input); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	select {
+	case content := <-received:
+		if content != "line one\n  line two" {
+			t.Errorf("Expected multi-line message, got %q", content)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected message to be published")
+	}
+}
@@ -2,12 +2,13 @@ package cli
 
 import (
 	"context"
-	"fmt"
-	"log"
 
 	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 )
 
+var logger = logging.For("cli")
+
 type Handler struct {
 	cli *CLI
 }
@@ -19,12 +20,28 @@ func NewHandler(cli *CLI) *Handler {
 }
 
 func (h *Handler) HandleMessage(ctx context.Context, msg *bus.Message) error {
-	if msg.Channel != bus.ChannelCLI {
+	// The messages typed here come through the bus too; only the replies to
+	// them are shown.
+	if msg.Channel != bus.ChannelCLI || !msg.IsReply() {
 		return nil
 	}
 
-	log.Printf("CLI received response: %.40s...", msg.Content)
+	if h.cli.chat {
+		h.cli.showReply(msg)
+		return nil
+	}
+
+	if msg.IsPartial() {
+		return nil
+	}
+
+	logger.Debug("CLI received response", "preview", logging.Preview(msg.Content, 40))
+
+	response := RenderResponse(WrapText(msg.Content, h.cli.Capabilities().LineWidth), h.cli.color)
+	if toolUses := msg.ToolUses(); len(toolUses) > 0 {
+		response += "\n" + RenderToolUses(toolUses, h.cli.color)
+	}
 
-	fmt.Printf("\nResponse: %s\n%s", msg.Content, Prompt)
+	h.cli.printAbovePrompt("\nResponse: " + response + "\n")
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorKeyword = "\x1b[35m"
+	colorString  = "\x1b[32m"
+	colorComment = "\x1b[90m"
+	colorNumber  = "\x1b[36m"
+	colorFence   = "\x1b[2m"
+)
+
+var languageAliases = map[string]string{
+	"golang":     "go",
+	"py":         "python",
+	"python3":    "python",
+	"js":         "javascript",
+	"ts":         "javascript",
+	"typescript": "javascript",
+	"jsx":        "javascript",
+	"tsx":        "javascript",
+	"bash":       "shell",
+	"sh":         "shell",
+	"zsh":        "shell",
+	"console":    "shell",
+	"yml":        "yaml",
+	"rs":         "rust",
+}
+
+var languageKeywords = map[string][]string{
+	"go": {"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func", "go",
+		"goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "type",
+		"var", "nil", "true", "false", "error", "string", "int", "bool"},
+	"python": {"and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del", "elif", "else",
+		"except", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda", "None", "not", "or",
+		"pass", "raise", "return", "True", "False", "try", "while", "with", "yield"},
+	"javascript": {"async", "await", "break", "case", "catch", "class", "const", "continue", "default", "delete",
+		"else", "export", "extends", "false", "finally", "for", "function", "if", "import", "in", "instanceof", "let",
+		"new", "null", "return", "switch", "this", "throw", "true", "try", "typeof", "undefined", "var", "while",
+		"interface", "type"},
+	"shell": {"if", "then", "else", "elif", "fi", "for", "while", "do", "done", "case", "esac", "function", "in",
+		"export", "local", "return", "echo", "cd", "sudo"},
+	"rust": {"as", "break", "const", "continue", "crate", "else", "enum", "false", "fn", "for", "if", "impl", "in",
+		"let", "loop", "match", "mod", "move", "mut", "pub", "ref", "return", "self", "Self", "static", "struct",
+		"trait", "true", "type", "unsafe", "use", "where", "while"},
+	"yaml": {"true", "false", "null"},
+	"json": {"true", "false", "null"},
+}
+
+func RenderResponse(text string, color bool) string {
+	if !color {
+		return text
+	}
+
+	var builder strings.Builder
+	inCode := false
+	language := ""
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				inCode = false
+			} else {
+				inCode = true
+				language = normalizeLanguage(strings.TrimPrefix(trimmed, "```"))
+			}
+			builder.WriteString(colorFence + line + colorReset)
+			continue
+		}
+
+		if inCode {
+			builder.WriteString(HighlightLine(line, language))
+		} else {
+			builder.WriteString(line)
+		}
+	}
+
+	return builder.String()
+}
+
+// WrapText breaks prose lines longer than width at word boundaries. Code
+// blocks are left alone so they can still be copied.
+func WrapText(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	wrapped := make([]string, 0, len(lines))
+	inCode := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		if inCode || utf8.RuneCountInString(line) <= width {
+			wrapped = append(wrapped, line)
+			continue
+		}
+
+		current := ""
+		for _, word := range strings.Fields(line) {
+			if current != "" && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width {
+				wrapped = append(wrapped, current)
+				current = ""
+			}
+			if current != "" {
+				current += " "
+			}
+			current += word
+		}
+		wrapped = append(wrapped, current)
+	}
+
+	return strings.Join(wrapped, "\n")
+}
+
+func RenderToolUses(toolUses []bus.ToolUse, color bool) string {
+	var builder strings.Builder
+	builder.WriteString("\nTools used:")
+	for i, toolUse := range toolUses {
+		fmt.Fprintf(&builder, "\n  %d. %s", i+1, toolUse.Summary())
+	}
+
+	if !color {
+		return builder.String()
+	}
+	return colorComment + builder.String() + colorReset
+}
+
+func normalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if alias, ok := languageAliases[language]; ok {
+		return alias
+	}
+	return language
+}
+
+func commentPrefix(language string) string {
+	switch language {
+	case "python", "shell", "yaml", "ruby", "toml":
+		return "#"
+	case "sql", "lua":
+		return "--"
+	default:
+		return "//"
+	}
+}
+
+func HighlightLine(line, language string) string {
+	keywords, ok := languageKeywords[language]
+	if !ok {
+		return line
+	}
+
+	keywordSet := make(map[string]bool, len(keywords))
+	for _, keyword := range keywords {
+		keywordSet[keyword] = true
+	}
+
+	comment := commentPrefix(language)
+	runes := []rune(line)
+
+	var builder strings.Builder
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case strings.HasPrefix(string(runes[i:]), comment):
+			builder.WriteString(colorComment + string(runes[i:]) + colorReset)
+			return builder.String()
+
+		case r == '"' || r == '\'' || r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				if runes[end] == '\\' && r != '`' {
+					end++
+				}
+				end++
+			}
+			if end < len(runes) {
+				end++
+			} else {
+				end = len(runes)
+			}
+			builder.WriteString(colorString + string(runes[i:end]) + colorReset)
+			i = end
+
+		case unicode.IsDigit(r) && (i == 0 || !isIdentRune(runes[i-1])):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.' || runes[end] == 'x' || runes[end] == '_') {
+				end++
+			}
+			builder.WriteString(colorNumber + string(runes[i:end]) + colorReset)
+			i = end
+
+		case isIdentRune(r):
+			end := i
+			for end < len(runes) && isIdentRune(runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			if keywordSet[word] {
+				builder.WriteString(colorKeyword + word + colorReset)
+			} else {
+				builder.WriteString(word)
+			}
+			i = end
+
+		default:
+			builder.WriteRune(r)
+			i++
+		}
+	}
+
+	return builder.String()
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestRenderResponseNoColor(t *testing.T) {
+	text := "Here:\n```go\nfunc main() {}\n```"
+
+	if RenderResponse(text, false) != text {
+		t.Error("Expected text to be unchanged without color")
+	}
+}
+
+func TestRenderResponseHighlightsCode(t *testing.T) {
+	text := "Here:\n```go\nreturn \"x\" // done\n```\nreturn"
+	rendered := RenderResponse(text, true)
+
+	if !strings.Contains(rendered, colorKeyword+"return"+colorReset) {
+		t.Error("Expected keyword to be highlighted")
+	}
+
+	if !strings.Contains(rendered, colorString+"\"x\""+colorReset) {
+		t.Error("Expected string to be highlighted")
+	}
+
+	if !strings.Contains(rendered, colorComment+"// done"+colorReset) {
+		t.Error("Expected comment to be highlighted")
+	}
+
+	if !strings.HasSuffix(rendered, "\nreturn") {
+		t.Error("Expected text outside code blocks to be left alone")
+	}
+}
+
+func TestHighlightLineUnknownLanguage(t *testing.T) {
+	if HighlightLine("return 1", "brainfuck") != "return 1" {
+		t.Error("Expected unknown language to be left alone")
+	}
+}
+
+func TestHighlightLineAlias(t *testing.T) {
+	rendered := HighlightLine("def f(): # note", normalizeLanguage("py"))
+
+	if !strings.Contains(rendered, colorKeyword+"def"+colorReset) {
+		t.Error("Expected python keyword to be highlighted")
+	}
+
+	if !strings.Contains(rendered, colorComment+"# note"+colorReset) {
+		t.Error("Expected python comment to be highlighted")
+	}
+}
+
+func TestRenderToolUses(t *testing.T) {
+	output := RenderToolUses([]bus.ToolUse{
+		{Name: "read_file", Input: "path=notes.txt", Output: "hello", Duration: 3},
+		{Name: "web_search", Error: "timeout"},
+	}, false)
+
+	expected := "\nTools used:\n  1. read_file(path=notes.txt) -> hello [3ms]\n  2. web_search -> error: timeout"
+	if output != expected {
+		t.Errorf("Unexpected output:\n%q", output)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	text := "one two three four five six\n```\na very long line of code that must not wrap\n```"
+	wrapped := WrapText(text, 10)
+
+	expected := "one two\nthree four\nfive six\n```\na very long line of code that must not wrap\n```"
+	if wrapped != expected {
+		t.Errorf("Unexpected wrapping:\n%s", wrapped)
+	}
+
+	if WrapText(text, 0) != text {
+		t.Error("Expected no wrapping without a width")
+	}
+}
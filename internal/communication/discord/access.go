@@ -0,0 +1,67 @@
+package discord
+
+import (
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+// AccessConfig limits who may talk to the bot, by Discord user, server
+// (guild) and channel ID. Admins are allowed too. With all lists empty
+// everyone is allowed, but only admins may use admin-only tools and commands.
+type AccessConfig struct {
+	AllowedUsers    []string
+	AllowedGuilds   []string
+	AllowedChannels []string
+	Admins          []string
+}
+
+type accessList struct {
+	users    map[string]bool
+	guilds   map[string]bool
+	channels map[string]bool
+	admins   map[string]bool
+}
+
+func newAccessList(cfg AccessConfig) *accessList {
+	if len(cfg.AllowedUsers) == 0 && len(cfg.AllowedGuilds) == 0 && len(cfg.AllowedChannels) == 0 && len(cfg.Admins) == 0 {
+		return nil
+	}
+
+	return &accessList{
+		users:    idSet(cfg.AllowedUsers),
+		guilds:   idSet(cfg.AllowedGuilds),
+		channels: idSet(cfg.AllowedChannels),
+		admins:   idSet(cfg.Admins),
+	}
+}
+
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// authorize returns the bus user for the author of a message, or false if
+// they may not use the bot. Without an access list everyone may, but nobody
+// is an admin.
+func (a *accessList) authorize(message *Message) (*bus.User, bool) {
+	if message.Author == nil {
+		return nil, a == nil
+	}
+
+	author := message.Author.ID
+	admin := false
+	if a != nil {
+		if !a.users[author] && !a.admins[author] && !a.channels[message.ChannelID] && (message.GuildID == "" || !a.guilds[message.GuildID]) {
+			return nil, false
+		}
+		admin = a.admins[author]
+	}
+
+	return &bus.User{
+		ID:    "discord:" + author,
+		Name:  message.Author.DisplayName(),
+		Admin: admin,
+	}, true
+}
@@ -0,0 +1,45 @@
+package discord
+
+import "testing"
+
+func TestAccessListAuthorize(t *testing.T) {
+	if user, ok := newAccessList(AccessConfig{}).authorize(&Message{}); !ok || user != nil {
+		t.Errorf("Expected everyone to be allowed without an access list, got %v, %v", user, ok)
+	}
+	if user, ok := newAccessList(AccessConfig{}).authorize(&Message{Author: &User{ID: "30"}}); !ok || user.Admin {
+		t.Errorf("Expected no admins without an access list, got %+v, %v", user, ok)
+	}
+
+	access := newAccessList(AccessConfig{
+		AllowedUsers:    []string{"10"},
+		AllowedGuilds:   []string{"g1"},
+		AllowedChannels: []string{"c1"},
+		Admins:          []string{"1"},
+	})
+
+	tests := []struct {
+		name    string
+		message Message
+		allowed bool
+		admin   bool
+	}{
+		{"admin", Message{Author: &User{ID: "1", Username: "root"}, ChannelID: "dm"}, true, true},
+		{"allowed user", Message{Author: &User{ID: "10"}, ChannelID: "dm"}, true, false},
+		{"member of allowed guild", Message{Author: &User{ID: "20"}, GuildID: "g1", ChannelID: "c9"}, true, false},
+		{"in allowed channel", Message{Author: &User{ID: "20"}, GuildID: "g2", ChannelID: "c1"}, true, false},
+		{"stranger", Message{Author: &User{ID: "30"}, GuildID: "g2", ChannelID: "c2"}, false, false},
+		{"no author", Message{GuildID: "g1", ChannelID: "c1"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, ok := access.authorize(&tt.message)
+			if ok != tt.allowed {
+				t.Fatalf("Expected allowed=%v, got %v", tt.allowed, ok)
+			}
+			if ok && user.Admin != tt.admin {
+				t.Errorf("Expected admin=%v, got %+v", tt.admin, user)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+const (
+	defaultAPIURL     = "https://discord.com/api/v10"
+	defaultGatewayURL = "wss://gateway.discord.gg/?v=10&encoding=json"
+	maxMessageLength  = 2000
+	maxRateLimitWaits = 3
+	userAgent         = "DiscordBot (https://github.com/wjffsx/miniclaw_go, 0.1.0)"
+
+	// directMessages takes the place of the server ID in the chat ID of a
+	// direct message.
+	directMessages = "dm"
+)
+
+type Message struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id,omitempty"`
+	Author    *User  `json:"author,omitempty"`
+	Content   string `json:"content"`
+	Mentions  []User `json:"mentions,omitempty"`
+}
+
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name,omitempty"`
+	Bot        bool   `json:"bot,omitempty"`
+}
+
+func (u *User) DisplayName() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+type CreateMessageRequest struct {
+	Content          string            `json:"content"`
+	MessageReference *MessageReference `json:"message_reference,omitempty"`
+	AllowedMentions  *AllowedMentions  `json:"allowed_mentions,omitempty"`
+}
+
+type MessageReference struct {
+	MessageID       string `json:"message_id"`
+	FailIfNotExists bool   `json:"fail_if_not_exists"`
+}
+
+type AllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// noMentions keeps replies from pinging @everyone, roles or users just
+// because the model wrote their name.
+var noMentions = &AllowedMentions{Parse: []string{}}
+
+type APIError struct {
+	Code       int     `json:"code"`
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after,omitempty"`
+}
+
+type Config struct {
+	Token string
+	// APIURL and GatewayURL default to Discord's; they are settable for tests.
+	APIURL     string
+	GatewayURL string
+
+	// RequireMention makes the bot ignore messages in server channels that
+	// do not mention it. Direct messages are always answered.
+	RequireMention bool
+
+	StreamResponses bool
+	StreamInterval  time.Duration
+
+	Access AccessConfig
+
+	Logger *slog.Logger
+}
+
+type Bot struct {
+	token          string
+	apiURL         string
+	gatewayURL     string
+	requireMention bool
+	httpClient     *http.Client
+	messageBus     bus.MessageBus
+	ctx            context.Context
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+	mu             sync.RWMutex
+	started        bool
+	access         *accessList
+
+	// Filled in from the gateway's READY event.
+	userID    string
+	sessionID string
+	resumeURL string
+	sequence  int64
+
+	streamResponses bool
+	streamInterval  time.Duration
+	streams         map[string]*streamState
+	streamsMu       sync.Mutex
+
+	logger *slog.Logger
+}
+
+func NewBot(cfg *Config, messageBus bus.MessageBus, ctx context.Context) *Bot {
+	botCtx, cancel := context.WithCancel(ctx)
+
+	apiURL := defaultAPIURL
+	if cfg.APIURL != "" {
+		apiURL = strings.TrimSuffix(cfg.APIURL, "/")
+	}
+
+	gatewayURL := defaultGatewayURL
+	if cfg.GatewayURL != "" {
+		gatewayURL = cfg.GatewayURL
+	}
+
+	streamInterval := defaultStreamInterval
+	if cfg.StreamInterval > 0 {
+		streamInterval = cfg.StreamInterval
+	}
+
+	return &Bot{
+		token:          cfg.Token,
+		apiURL:         apiURL,
+		gatewayURL:     gatewayURL,
+		requireMention: cfg.RequireMention,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		messageBus:     messageBus,
+		ctx:            botCtx,
+		cancel:         cancel,
+		access:         newAccessList(cfg.Access),
+
+		streamResponses: cfg.StreamResponses,
+		streamInterval:  streamInterval,
+		streams:         make(map[string]*streamState),
+
+		logger: logging.Or(cfg.Logger, "discord"),
+	}
+}
+
+func (b *Bot) Start() error {
+	if b.token == "" {
+		return fmt.Errorf("discord bot token is required")
+	}
+
+	b.mu.Lock()
+	if b.started {
+		b.mu.Unlock()
+		return fmt.Errorf("bot already started")
+	}
+	b.started = true
+	b.mu.Unlock()
+
+	b.logger.Info("Starting Discord bot")
+	if b.access == nil {
+		b.logger.Warn("Discord bot answers everyone, set allowed users, guilds or channels to restrict it")
+	}
+
+	b.wg.Add(1)
+	go b.runGateway()
+
+	return nil
+}
+
+func (b *Bot) Stop() error {
+	b.mu.Lock()
+	if !b.started {
+		b.mu.Unlock()
+		return fmt.Errorf("bot not started")
+	}
+	b.started = false
+	b.mu.Unlock()
+
+	b.logger.Info("Stopping Discord bot")
+	b.cancel()
+	b.wg.Wait()
+	return nil
+}
+
+func (b *Bot) IsRunning() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.started
+}
+
+// ChatID returns the bus chat ID of a Discord channel, "<guild>-<channel>"
+// for server channels and "dm-<channel>" for direct messages. Every channel
+// is its own conversation, shared by everyone in it.
+func ChatID(guildID, channelID string) string {
+	if guildID == "" {
+		guildID = directMessages
+	}
+	return guildID + "-" + channelID
+}
+
+func channelID(chatID string) string {
+	return chatID[strings.LastIndex(chatID, "-")+1:]
+}
+
+func (b *Bot) handleMessage(message *Message) {
+	if message.Author == nil || message.Author.Bot || b.messageBus == nil {
+		return
+	}
+
+	b.mu.RLock()
+	botID := b.userID
+	b.mu.RUnlock()
+
+	content := message.Content
+	if message.GuildID != "" {
+		mentioned := botID != "" && mentions(message, botID)
+		if b.requireMention && !mentioned {
+			return
+		}
+		if mentioned {
+			content = stripMention(content, botID)
+		}
+	}
+	if content == "" {
+		return
+	}
+
+	chatID := ChatID(message.GuildID, message.ChannelID)
+
+	user, ok := b.access.authorize(message)
+	if !ok {
+		b.logger.Warn("Ignoring message from a sender who is not allowed", "chat_id", chatID, "user_id", message.Author.ID)
+		return
+	}
+
+	b.logger.Info("Message received", "chat_id", chatID, "preview", logging.Preview(content, 40))
+
+	msg := &bus.Message{
+		ID:      "discord-" + message.ID,
+		Channel: bus.ChannelDiscord,
+		ChatID:  chatID,
+		Content: content,
+	}
+
+	if b.streamResponses || user != nil {
+		msg.Metadata = make(map[string]interface{})
+	}
+	if user != nil {
+		msg.Metadata[bus.MetadataUser] = *user
+	}
+	if b.streamResponses {
+		msg.Metadata[bus.MetadataStreaming] = true
+	}
+
+	if err := b.messageBus.Publish(b.ctx, bus.ChannelDiscord, msg); err != nil {
+		b.logger.Error("Failed to publish message to bus", "chat_id", chatID, "error", err)
+	}
+}
+
+func mentions(message *Message, userID string) bool {
+	for _, user := range message.Mentions {
+		if user.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func stripMention(content, userID string) string {
+	content = strings.ReplaceAll(content, "<@"+userID+">", "")
+	content = strings.ReplaceAll(content, "<@!"+userID+">", "")
+	return strings.TrimSpace(content)
+}
+
+func (b *Bot) SendMessage(chatID, text string) error {
+	_, err := b.sendText(chatID, text, "")
+	return err
+}
+
+// SendResponse sends the reply to msg, finishing its streamed message if
+// there is one. In server channels the first part quotes the message it
+// answers, so it is clear who it is for.
+func (b *Bot) SendResponse(msg *bus.Message, text string) error {
+	if handled, err := b.finishStream(msg, text); handled {
+		return err
+	}
+
+	// Messages the agent sends on its own answer no Discord message.
+	replyTo, ok := strings.CutPrefix(msg.ReplyTo(), "discord-")
+	if !ok || strings.HasPrefix(msg.ChatID, directMessages+"-") {
+		replyTo = ""
+	}
+
+	_, err := b.sendText(msg.ChatID, text, replyTo)
+	return err
+}
+
+func (b *Bot) sendText(chatID, text, replyTo string) ([]string, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	parts := bus.SplitText(text, maxMessageLength)
+	messageIDs := make([]string, 0, len(parts))
+
+	for i, part := range parts {
+		req := CreateMessageRequest{Content: part, AllowedMentions: noMentions}
+		if i == 0 && replyTo != "" {
+			req.MessageReference = &MessageReference{MessageID: replyTo}
+		}
+
+		var sent Message
+		if err := b.request(http.MethodPost, "/channels/"+channelID(chatID)+"/messages", req, &sent); err != nil {
+			return messageIDs, fmt.Errorf("failed to send message: %w", err)
+		}
+		messageIDs = append(messageIDs, sent.ID)
+	}
+
+	return messageIDs, nil
+}
+
+func (b *Bot) editText(chatID, messageID, text string) error {
+	req := CreateMessageRequest{Content: text, AllowedMentions: noMentions}
+	if err := b.request(http.MethodPatch, "/channels/"+channelID(chatID)+"/messages/"+messageID, req, nil); err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+	return nil
+}
+
+// request calls the REST API, waiting out rate limits a few times before
+// giving up.
+func (b *Bot) request(method, path string, payload, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(b.ctx, method, b.apiURL+path, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bot "+b.token)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+
+		resp, err := b.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if result == nil || len(data) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(data, result); err != nil {
+				return fmt.Errorf("failed to decode response: %w", err)
+			}
+			return nil
+		}
+
+		var apiErr APIError
+		json.Unmarshal(data, &apiErr)
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitWaits {
+			wait := time.Duration(apiErr.RetryAfter * float64(time.Second))
+			b.logger.Debug("Rate limited, waiting", "path", path, "wait", wait)
+			select {
+			case <-time.After(wait):
+				continue
+			case <-b.ctx.Done():
+				return b.ctx.Err()
+			}
+		}
+
+		if apiErr.Message != "" {
+			return fmt.Errorf("API error %d: %s", apiErr.Code, apiErr.Message)
+		}
+		return fmt.Errorf("API returned %s", resp.Status)
+	}
+}
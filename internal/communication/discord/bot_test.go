@@ -0,0 +1,180 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+type sentRequest struct {
+	method string
+	path   string
+	auth   string
+	body   CreateMessageRequest
+}
+
+// fakeAPI records REST calls and answers them like Discord, rate limiting
+// the first one.
+type fakeAPI struct {
+	mu        sync.Mutex
+	requests  []sentRequest
+	rateLimit bool
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.rateLimit {
+		f.rateLimit = false
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(APIError{Message: "You are being rate limited.", RetryAfter: 0.01})
+		return
+	}
+
+	var body CreateMessageRequest
+	json.NewDecoder(r.Body).Decode(&body)
+	f.requests = append(f.requests, sentRequest{method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization"), body: body})
+	json.NewEncoder(w).Encode(Message{ID: "sent"})
+}
+
+func (f *fakeAPI) sent() []sentRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentRequest(nil), f.requests...)
+}
+
+func TestChatID(t *testing.T) {
+	if chatID := ChatID("guild", "channel"); chatID != "guild-channel" || channelID(chatID) != "channel" {
+		t.Errorf("Unexpected server chat ID %q", chatID)
+	}
+	if chatID := ChatID("", "123"); chatID != "dm-123" || channelID(chatID) != "123" {
+		t.Errorf("Unexpected direct message chat ID %q", chatID)
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *bus.Message, 4)
+	messageBus.Subscribe(bus.ChannelDiscord, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	bot := NewBot(&Config{Token: "token", RequireMention: true, Access: AccessConfig{Admins: []string{"1"}}}, messageBus, ctx)
+	bot.userID = "99"
+
+	author := &User{ID: "1", Username: "alice"}
+	bot.handleMessage(&Message{ID: "1", GuildID: "g", ChannelID: "c", Author: author, Content: "not for the bot"})
+	bot.handleMessage(&Message{ID: "2", GuildID: "g", ChannelID: "c", Author: &User{ID: "2", Bot: true}, Content: "<@99> hi", Mentions: []User{{ID: "99"}}})
+	bot.handleMessage(&Message{ID: "3", GuildID: "g", ChannelID: "c", Author: &User{ID: "3"}, Content: "<@99> hi", Mentions: []User{{ID: "99"}}})
+	bot.handleMessage(&Message{ID: "4", GuildID: "g", ChannelID: "c", Author: author, Content: "<@99> what's up?", Mentions: []User{{ID: "99"}}})
+	bot.handleMessage(&Message{ID: "5", ChannelID: "d", Author: author, Content: "hello"})
+
+	expected := map[string][2]string{
+		"discord-4": {"g-c", "what's up?"},
+		"discord-5": {"dm-d", "hello"},
+	}
+	for range expected {
+		select {
+		case msg := <-received:
+			want, found := expected[msg.ID]
+			user, ok := msg.User()
+			if !found || msg.ChatID != want[0] || msg.Content != want[1] || !ok || user.ID != "discord:1" {
+				t.Errorf("Unexpected message %s %s %q %+v", msg.ID, msg.ChatID, msg.Content, user)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the mention and the direct message to be published")
+		}
+	}
+
+	select {
+	case msg := <-received:
+		t.Errorf("Expected no more messages, got %q", msg.Content)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSendResponseSplitsLongReplies(t *testing.T) {
+	api := &fakeAPI{rateLimit: true}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	bot := NewBot(&Config{Token: "token", APIURL: server.URL}, nil, context.Background())
+
+	request := &bus.Message{ID: "discord-42", ChatID: "g-c"}
+	reply := &bus.Message{ID: "reply", ChatID: "g-c"}
+	reply.SetReplyTo(request)
+
+	if err := bot.SendResponse(reply, strings.Repeat("word ", 900)); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+
+	sent := api.sent()
+	if len(sent) != 3 {
+		t.Fatalf("Expected the reply in 3 messages, got %d", len(sent))
+	}
+	for i, req := range sent {
+		if req.method != http.MethodPost || req.path != "/channels/c/messages" || req.auth != "Bot token" {
+			t.Errorf("Unexpected request %+v", req)
+		}
+		if len([]rune(req.body.Content)) > maxMessageLength {
+			t.Errorf("Message %d is %d characters long", i, len([]rune(req.body.Content)))
+		}
+		if (i == 0) != (req.body.MessageReference != nil) {
+			t.Errorf("Expected only the first message to quote the request, message %d has %+v", i, req.body.MessageReference)
+		}
+	}
+	if sent[0].body.MessageReference.MessageID != "42" {
+		t.Errorf("Expected a reference to message 42, got %+v", sent[0].body.MessageReference)
+	}
+}
+
+func TestStreamedResponse(t *testing.T) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	bot := NewBot(&Config{Token: "token", APIURL: server.URL, StreamInterval: time.Millisecond}, nil, context.Background())
+
+	partial := func(content string, sequence int) *bus.Message {
+		return &bus.Message{ID: "reply", ChatID: "dm-d", Content: content, Metadata: map[string]interface{}{bus.MetadataPartial: true, bus.MetadataSequence: sequence}}
+	}
+	if err := bot.UpdateStream(partial("Hel", 1)); err != nil {
+		t.Fatalf("Failed to start stream: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := bot.UpdateStream(partial("Hello", 2)); err != nil {
+		t.Fatalf("Failed to update stream: %v", err)
+	}
+	if err := bot.SendResponse(&bus.Message{ID: "reply", ChatID: "dm-d"}, "Hello there"); err != nil {
+		t.Fatalf("Failed to finish stream: %v", err)
+	}
+	if err := bot.UpdateStream(partial("Hello th", 3)); err != nil {
+		t.Fatalf("Failed to update stream: %v", err)
+	}
+
+	sent := api.sent()
+	if len(sent) != 3 {
+		t.Fatalf("Expected a message and two edits, got %+v", sent)
+	}
+	if sent[0].method != http.MethodPost || sent[0].body.Content != "Hel"+streamCursor {
+		t.Errorf("Unexpected first message %+v", sent[0])
+	}
+	if last := sent[2]; last.method != http.MethodPatch || last.path != "/channels/d/messages/sent" || last.body.Content != "Hello there" {
+		t.Errorf("Expected the final text as an edit, got %+v", last)
+	}
+}
@@ -0,0 +1,306 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Gateway opcodes, see https://discord.com/developers/docs/topics/opcodes-and-status-codes.
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opResume         = 6
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatAck   = 11
+)
+
+// The bot needs server and direct messages, and their text.
+const intents = 1<<0 | 1<<9 | 1<<12 | 1<<15
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 2 * time.Minute
+)
+
+// fatalCloseCodes are the ones reconnecting does not fix, such as a wrong
+// token or intents the bot is not allowed to use.
+var fatalCloseCodes = []int{4004, 4010, 4011, 4012, 4013, 4014}
+
+type gatewayPayload struct {
+	Op       int             `json:"op"`
+	Data     json.RawMessage `json:"d,omitempty"`
+	Sequence *int64          `json:"s,omitempty"`
+	Type     string          `json:"t,omitempty"`
+}
+
+type helloEvent struct {
+	HeartbeatInterval int `json:"heartbeat_interval"`
+}
+
+type readyEvent struct {
+	SessionID        string `json:"session_id"`
+	ResumeGatewayURL string `json:"resume_gateway_url"`
+	User             User   `json:"user"`
+}
+
+type identifyData struct {
+	Token      string             `json:"token"`
+	Intents    int                `json:"intents"`
+	Properties identifyProperties `json:"properties"`
+}
+
+type identifyProperties struct {
+	OS      string `json:"os"`
+	Browser string `json:"browser"`
+	Device  string `json:"device"`
+}
+
+type resumeData struct {
+	Token     string `json:"token"`
+	SessionID string `json:"session_id"`
+	Sequence  int64  `json:"seq"`
+}
+
+var errReconnect = errors.New("gateway asked to reconnect")
+
+func (b *Bot) runGateway() {
+	defer b.wg.Done()
+
+	delay := minReconnectDelay
+	for {
+		connected := time.Now()
+		err := b.connect()
+		if b.ctx.Err() != nil {
+			return
+		}
+
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) && isFatal(closeErr.Code) {
+			b.logger.Error("Discord closed the gateway connection, not reconnecting", "code", closeErr.Code, "reason", closeErr.Text)
+			return
+		}
+
+		// A connection that lasted a while was healthy, so start over
+		// with a short delay.
+		if time.Since(connected) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		b.logger.Warn("Discord gateway disconnected, reconnecting", "error", err, "delay", delay)
+
+		select {
+		case <-time.After(delay):
+		case <-b.ctx.Done():
+			return
+		}
+		delay = min(2*delay, maxReconnectDelay)
+	}
+}
+
+func isFatal(code int) bool {
+	for _, fatal := range fatalCloseCodes {
+		if code == fatal {
+			return true
+		}
+	}
+	return false
+}
+
+// connect runs one gateway session until the connection drops, resuming the
+// previous session if there is one.
+func (b *Bot) connect() error {
+	b.mu.RLock()
+	url, sessionID, sequence := b.gatewayURL, b.sessionID, b.sequence
+	if sessionID != "" && b.resumeURL != "" {
+		url = b.resumeURL + "/?v=10&encoding=json"
+	}
+	b.mu.RUnlock()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(b.ctx, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var writeMu sync.Mutex
+	send := func(op int, data interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(gatewayPayload{Op: op, Data: raw})
+	}
+
+	var hello helloEvent
+	if err := readPayload(conn, opHello, &hello); err != nil {
+		return err
+	}
+
+	if sessionID != "" {
+		err = send(opResume, resumeData{Token: b.token, SessionID: sessionID, Sequence: sequence})
+	} else {
+		err = send(opIdentify, identifyData{
+			Token:   b.token,
+			Intents: intents,
+			Properties: identifyProperties{
+				OS:      runtime.GOOS,
+				Browser: "miniclaw",
+				Device:  "miniclaw",
+			},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to identify: %w", err)
+	}
+
+	acks := make(chan struct{}, 1)
+	heartbeat := func() error {
+		b.mu.RLock()
+		seq := b.sequence
+		b.mu.RUnlock()
+
+		if seq == 0 {
+			return send(opHeartbeat, nil)
+		}
+		return send(opHeartbeat, seq)
+	}
+	go b.heartbeat(ctx, conn, time.Duration(hello.HeartbeatInterval)*time.Millisecond, heartbeat, acks)
+
+	for {
+		var payload gatewayPayload
+		if err := conn.ReadJSON(&payload); err != nil {
+			return err
+		}
+
+		if payload.Sequence != nil {
+			b.mu.Lock()
+			b.sequence = *payload.Sequence
+			b.mu.Unlock()
+		}
+
+		switch payload.Op {
+		case opDispatch:
+			b.dispatch(payload.Type, payload.Data)
+		case opHeartbeat:
+			if err := heartbeat(); err != nil {
+				return fmt.Errorf("failed to send heartbeat: %w", err)
+			}
+		case opHeartbeatAck:
+			select {
+			case acks <- struct{}{}:
+			default:
+			}
+		case opReconnect:
+			return errReconnect
+		case opInvalidSession:
+			var resumable bool
+			json.Unmarshal(payload.Data, &resumable)
+			if !resumable {
+				b.mu.Lock()
+				b.sessionID, b.resumeURL, b.sequence = "", "", 0
+				b.mu.Unlock()
+			}
+			return fmt.Errorf("invalid session (resumable: %v)", resumable)
+		}
+	}
+}
+
+// heartbeat keeps the connection alive and closes it when Discord stops
+// acknowledging, so a dead connection is noticed and replaced.
+func (b *Bot) heartbeat(ctx context.Context, conn *websocket.Conn, interval time.Duration, beat func() error, acks <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+
+	// The first beat is jittered as Discord asks, so reconnecting bots do
+	// not all beat at once.
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(interval))))
+	defer timer.Stop()
+
+	acked := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-acks:
+			acked = true
+		case <-timer.C:
+			if !acked {
+				b.logger.Warn("Discord stopped acknowledging heartbeats, reconnecting")
+				conn.Close()
+				return
+			}
+			if err := beat(); err != nil {
+				return
+			}
+			acked = false
+			timer.Reset(interval)
+		}
+	}
+}
+
+func (b *Bot) dispatch(event string, data json.RawMessage) {
+	switch event {
+	case "READY":
+		var ready readyEvent
+		if err := json.Unmarshal(data, &ready); err != nil {
+			b.logger.Warn("Failed to decode READY event", "error", err)
+			return
+		}
+
+		b.mu.Lock()
+		b.userID = ready.User.ID
+		b.sessionID = ready.SessionID
+		b.resumeURL = ready.ResumeGatewayURL
+		b.mu.Unlock()
+
+		b.logger.Info("Connected to Discord", "user", ready.User.Username)
+	case "RESUMED":
+		b.logger.Info("Resumed Discord session")
+	case "MESSAGE_CREATE":
+		var message Message
+		if err := json.Unmarshal(data, &message); err != nil {
+			b.logger.Warn("Failed to decode message", "error", err)
+			return
+		}
+		b.handleMessage(&message)
+	}
+}
+
+func readPayload(conn *websocket.Conn, op int, data interface{}) error {
+	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var payload gatewayPayload
+	if err := conn.ReadJSON(&payload); err != nil {
+		return fmt.Errorf("failed to read gateway payload: %w", err)
+	}
+	if payload.Op != op {
+		return fmt.Errorf("expected gateway opcode %d, got %d", op, payload.Op)
+	}
+	if err := json.Unmarshal(payload.Data, data); err != nil {
+		return fmt.Errorf("failed to decode gateway payload: %w", err)
+	}
+	return nil
+}
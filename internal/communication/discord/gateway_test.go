@@ -0,0 +1,105 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestGatewayDeliversMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *bus.Message, 1)
+	messageBus.Subscribe(bus.ChannelDiscord, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	identified := make(chan identifyData, 1)
+	upgrader := websocket.Upgrader{}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		send := func(op int, event string, seq int64, data interface{}) {
+			raw, _ := json.Marshal(data)
+			conn.WriteJSON(gatewayPayload{Op: op, Type: event, Sequence: &seq, Data: raw})
+		}
+		send(opHello, "", 0, helloEvent{HeartbeatInterval: 60000})
+
+		var payload gatewayPayload
+		if err := conn.ReadJSON(&payload); err != nil || payload.Op != opIdentify {
+			return
+		}
+		var identify identifyData
+		json.Unmarshal(payload.Data, &identify)
+		identified <- identify
+
+		send(opDispatch, "READY", 1, readyEvent{SessionID: "session", User: User{ID: "99", Username: "miniclaw"}})
+		send(opDispatch, "MESSAGE_CREATE", 2, Message{
+			ID:        "123",
+			GuildID:   "g",
+			ChannelID: "c",
+			Author:    &User{ID: "1", Username: "alice"},
+			Content:   "<@!99> ping",
+			Mentions:  []User{{ID: "99"}},
+		})
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer gateway.Close()
+
+	bot := NewBot(&Config{
+		Token:          "token",
+		GatewayURL:     "ws" + strings.TrimPrefix(gateway.URL, "http"),
+		RequireMention: true,
+	}, messageBus, ctx)
+	if err := bot.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	defer bot.Stop()
+
+	select {
+	case identify := <-identified:
+		if identify.Token != "token" || identify.Intents != intents {
+			t.Errorf("Unexpected identify payload %+v", identify)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the bot to identify")
+	}
+
+	select {
+	case msg := <-received:
+		if msg.ID != "discord-123" || msg.ChatID != "g-c" || msg.Content != "ping" {
+			t.Errorf("Unexpected message %s %s %q", msg.ID, msg.ChatID, msg.Content)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the message to reach the bus")
+	}
+
+	bot.mu.RLock()
+	sessionID, sequence := bot.sessionID, bot.sequence
+	bot.mu.RUnlock()
+	if sessionID != "session" || sequence != 2 {
+		t.Errorf("Expected the session to be kept for resuming, got %q at %d", sessionID, sequence)
+	}
+}
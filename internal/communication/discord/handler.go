@@ -0,0 +1,58 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+type Handler struct {
+	bot *Bot
+}
+
+func NewHandler(bot *Bot) *Handler {
+	return &Handler{
+		bot: bot,
+	}
+}
+
+func (h *Handler) HandleMessage(ctx context.Context, msg *bus.Message) error {
+	if msg.Channel != bus.ChannelDiscord || !msg.IsReply() {
+		return nil
+	}
+
+	if msg.IsPartial() {
+		return h.bot.UpdateStream(msg)
+	}
+
+	if msg.IsControl() {
+		return nil
+	}
+
+	h.bot.logger.Debug("Sending message", "chat_id", msg.ChatID, "preview", logging.Preview(msg.Content, 40))
+
+	content := msg.Content
+	if toolUses := msg.ToolUses(); len(toolUses) > 0 {
+		content += formatToolUses(toolUses)
+	}
+
+	if err := h.bot.SendResponse(msg, content); err != nil {
+		h.bot.logger.Error("Failed to send message", "chat_id", msg.ChatID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func formatToolUses(toolUses []bus.ToolUse) string {
+	var builder strings.Builder
+	builder.WriteString("\n\n**Tools used**\n```\n")
+	for i, toolUse := range toolUses {
+		fmt.Fprintf(&builder, "%d. %s\n", i+1, strings.ReplaceAll(toolUse.Summary(), "```", "'''"))
+	}
+	builder.WriteString("```")
+	return builder.String()
+}
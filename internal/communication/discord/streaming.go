@@ -0,0 +1,154 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const (
+	defaultStreamInterval = 1500 * time.Millisecond
+	streamTTL             = 10 * time.Minute
+	streamCursor          = " ▌"
+)
+
+type streamState struct {
+	mu        sync.Mutex
+	chatID    string
+	messageID string
+	text      string
+	sequence  int
+	shown     string
+	lastEdit  time.Time
+	created   time.Time
+	scheduled bool
+	done      bool
+}
+
+func (b *Bot) UpdateStream(msg *bus.Message) error {
+	stream := b.stream(msg.ID, msg.ChatID, true)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.done || msg.Sequence() <= stream.sequence {
+		return nil
+	}
+	stream.text = msg.Content
+	stream.sequence = msg.Sequence()
+
+	if stream.messageID == "" {
+		messageIDs, err := b.sendText(stream.chatID, streamPreview(stream.text), "")
+		if err != nil {
+			return fmt.Errorf("failed to start streamed message: %w", err)
+		}
+
+		stream.messageID = messageIDs[0]
+		stream.shown = stream.text
+		stream.lastEdit = time.Now()
+		return nil
+	}
+
+	wait := b.streamInterval - time.Since(stream.lastEdit)
+	if wait <= 0 {
+		return b.flushStream(stream)
+	}
+
+	if !stream.scheduled {
+		stream.scheduled = true
+		time.AfterFunc(wait, func() {
+			stream.mu.Lock()
+			defer stream.mu.Unlock()
+
+			stream.scheduled = false
+			if stream.done {
+				return
+			}
+			if err := b.flushStream(stream); err != nil {
+				b.logger.Warn("Failed to update streamed message", "error", err)
+			}
+		})
+	}
+
+	return nil
+}
+
+func (b *Bot) flushStream(stream *streamState) error {
+	if stream.text == stream.shown {
+		return nil
+	}
+
+	err := b.editText(stream.chatID, stream.messageID, streamPreview(stream.text))
+	stream.lastEdit = time.Now()
+	if err != nil {
+		return err
+	}
+
+	stream.shown = stream.text
+	return nil
+}
+
+// finishStream replaces the streamed message with the final text, sending
+// whatever does not fit as further messages.
+func (b *Bot) finishStream(msg *bus.Message, text string) (bool, error) {
+	// The stream is kept as done even when nothing was streamed, so partials
+	// delivered after the final message are ignored.
+	stream := b.stream(msg.ID, msg.ChatID, true)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.done || stream.messageID == "" {
+		stream.done = true
+		return false, nil
+	}
+	stream.done = true
+
+	parts := bus.SplitText(text, maxMessageLength)
+	if err := b.editText(stream.chatID, stream.messageID, parts[0]); err != nil {
+		return true, fmt.Errorf("failed to finalize streamed message: %w", err)
+	}
+
+	for _, part := range parts[1:] {
+		if _, err := b.sendText(stream.chatID, part, ""); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func (b *Bot) stream(id, chatID string, create bool) *streamState {
+	b.streamsMu.Lock()
+	defer b.streamsMu.Unlock()
+
+	stream, ok := b.streams[id]
+	if !ok && create {
+		for key, existing := range b.streams {
+			if time.Since(existing.created) > streamTTL {
+				delete(b.streams, key)
+			}
+		}
+
+		stream = &streamState{
+			chatID:  chatID,
+			created: time.Now(),
+		}
+		b.streams[id] = stream
+	}
+
+	return stream
+}
+
+// streamPreview cuts text to what fits in one message, with a cursor to
+// show more is coming.
+func streamPreview(text string) string {
+	limit := maxMessageLength - utf8.RuneCountInString(streamCursor)
+	if utf8.RuneCountInString(text) > limit {
+		text = string([]rune(text)[:limit])
+	}
+	return strings.TrimRight(text, " \n") + streamCursor
+}
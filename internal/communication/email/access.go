@@ -0,0 +1,117 @@
+package email
+
+import (
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+// AccessConfig limits who may email the assistant, by address or by domain
+// ("@example.com"). Admins are allowed too. With both lists empty everyone
+// is allowed, but nobody may use admin-only tools and commands.
+//
+// The From header is easy to forge, so only rely on this when the mail
+// server rejects mail that fails SPF and DKIM checks. Admins only get admin
+// rights for mail that AuthServID, the authserv-id the receiving mail server
+// writes into its Authentication-Results header, reports as passing DKIM,
+// DMARC or SPF for their address; without it nobody is an admin by email.
+type AccessConfig struct {
+	AllowedSenders []string
+	Admins         []string
+	AuthServID     string
+}
+
+type accessList struct {
+	senders    map[string]bool
+	admins     map[string]bool
+	authServID string
+}
+
+func newAccessList(cfg AccessConfig) *accessList {
+	if len(cfg.AllowedSenders) == 0 && len(cfg.Admins) == 0 {
+		return nil
+	}
+
+	return &accessList{
+		senders:    addressSet(cfg.AllowedSenders),
+		admins:     addressSet(cfg.Admins),
+		authServID: strings.ToLower(strings.TrimSpace(cfg.AuthServID)),
+	}
+}
+
+func addressSet(addresses []string) map[string]bool {
+	set := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		set[strings.ToLower(strings.TrimSpace(address))] = true
+	}
+	return set
+}
+
+// authorize returns the bus user for a sender, or false if the sender may
+// not use the assistant. Without an access list everyone may, but nobody is
+// an admin.
+func (a *accessList) authorize(email *Email) (*bus.User, bool) {
+	from := email.From
+	address := strings.ToLower(from.Address)
+	domain := address[strings.LastIndex(address, "@")+1:]
+
+	admin := false
+	if a != nil {
+		if !a.senders[address] && !a.senders["@"+domain] && !a.admins[address] {
+			return nil, false
+		}
+		admin = a.admins[address] && a.authServID != "" && verifiedSender(email.AuthResults, a.authServID, address)
+	}
+
+	name := from.Name
+	if name == "" {
+		name = address
+	}
+	return &bus.User{
+		ID:    "email:" + address,
+		Name:  name,
+		Admin: admin,
+	}, true
+}
+
+// verifiedSender reports whether the Authentication-Results header added by
+// the server authServID shows that address really sent the email. Only the
+// topmost header is used, since that is the one the receiving server added;
+// headers further down may come from the sender.
+func verifiedSender(results, authServID, address string) bool {
+	clauses := strings.Split(results, ";")
+	if fields := strings.Fields(clauses[0]); len(fields) == 0 || !strings.EqualFold(fields[0], authServID) {
+		return false
+	}
+
+	domain := address[strings.LastIndex(address, "@")+1:]
+	for _, clause := range clauses[1:] {
+		fields := strings.Fields(strings.ToLower(clause))
+		if len(fields) == 0 {
+			continue
+		}
+
+		properties := make(map[string]string)
+		for _, field := range fields[1:] {
+			if key, value, ok := strings.Cut(field, "="); ok {
+				properties[key] = strings.Trim(value, `"<>`)
+			}
+		}
+
+		switch fields[0] {
+		case "dkim=pass":
+			if properties["header.d"] == domain {
+				return true
+			}
+		case "dmarc=pass":
+			if properties["header.from"] == domain {
+				return true
+			}
+		case "spf=pass":
+			if mailFrom := properties["smtp.mailfrom"]; mailFrom == address || mailFrom == domain {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package email
+
+import (
+	"net/mail"
+	"testing"
+)
+
+func TestAdminsNeedAuthenticatedMail(t *testing.T) {
+	access := newAccessList(AccessConfig{Admins: []string{"boss@example.com"}, AuthServID: "mx.example.com"})
+
+	tests := []struct {
+		name    string
+		results string
+		admin   bool
+	}{
+		{"no results", "", false},
+		{"dkim pass", "mx.example.com; dkim=pass header.d=example.com header.s=mail; spf=none", true},
+		{"dmarc pass", "mx.example.com 1; dmarc=pass (p=reject) header.from=example.com", true},
+		{"spf pass", "mx.example.com; spf=pass smtp.mailfrom=boss@example.com", true},
+		{"dkim fail", "mx.example.com; dkim=fail header.d=example.com", false},
+		{"other domain", "mx.example.com; dkim=pass header.d=attacker.example", false},
+		{"other server", "mx.attacker.example; dkim=pass header.d=example.com", false},
+	}
+
+	for _, tt := range tests {
+		email := &Email{From: &mail.Address{Address: "Boss@example.com"}, AuthResults: tt.results}
+		user, ok := access.authorize(email)
+		if !ok {
+			t.Fatalf("%s: expected admins to be allowed", tt.name)
+		}
+		if user.Admin != tt.admin {
+			t.Errorf("%s: expected admin %v, got %v", tt.name, tt.admin, user.Admin)
+		}
+	}
+
+	unverified := newAccessList(AccessConfig{Admins: []string{"boss@example.com"}})
+	email := &Email{From: &mail.Address{Address: "boss@example.com"}, AuthResults: "mx.example.com; dkim=pass header.d=example.com"}
+	if user, ok := unverified.authorize(email); !ok || user.Admin {
+		t.Errorf("Expected no admin rights without a trusted authserv-id, got %+v", user)
+	}
+}
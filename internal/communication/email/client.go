@@ -0,0 +1,489 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+const (
+	defaultMailbox      = "INBOX"
+	defaultPollInterval = time.Minute
+	maxThreads          = 1000
+	maxSubjectLength    = 60
+)
+
+type Config struct {
+	// IMAPAddr and SMTPAddr are host:port. IMAP always uses TLS; SMTP uses
+	// TLS on port 465 and STARTTLS elsewhere when the server offers it.
+	IMAPAddr string
+	SMTPAddr string
+	Username string
+	Password string
+	// Address is the assistant's address, used as the sender of replies.
+	// It defaults to Username.
+	Address      string
+	Mailbox      string
+	PollInterval time.Duration
+
+	Access AccessConfig
+
+	Logger *slog.Logger
+}
+
+// thread is what a reply to a conversation needs: who to send it to and
+// which message it answers.
+type thread struct {
+	to         *mail.Address
+	subject    string
+	lastID     string
+	references []string
+	updated    time.Time
+}
+
+// Client polls an IMAP mailbox for new emails, publishes them on the bus
+// with one chat per email thread, and sends the replies over SMTP.
+type Client struct {
+	imapAddr     string
+	imapTLS      bool
+	smtpAddr     string
+	username     string
+	password     string
+	address      *mail.Address
+	mailbox      string
+	pollInterval time.Duration
+	access       *accessList
+	messageBus   bus.MessageBus
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	mu           sync.RWMutex
+	started      bool
+
+	threads   map[string]*thread
+	threadsMu sync.Mutex
+
+	logger *slog.Logger
+}
+
+func NewClient(cfg *Config, messageBus bus.MessageBus, ctx context.Context) *Client {
+	clientCtx, cancel := context.WithCancel(ctx)
+
+	address := cfg.Address
+	if address == "" {
+		address = cfg.Username
+	}
+
+	mailbox := defaultMailbox
+	if cfg.Mailbox != "" {
+		mailbox = cfg.Mailbox
+	}
+
+	pollInterval := defaultPollInterval
+	if cfg.PollInterval > 0 {
+		pollInterval = cfg.PollInterval
+	}
+
+	return &Client{
+		imapAddr:     cfg.IMAPAddr,
+		imapTLS:      true,
+		smtpAddr:     cfg.SMTPAddr,
+		username:     cfg.Username,
+		password:     cfg.Password,
+		address:      &mail.Address{Address: address},
+		mailbox:      mailbox,
+		pollInterval: pollInterval,
+		access:       newAccessList(cfg.Access),
+		messageBus:   messageBus,
+		ctx:          clientCtx,
+		cancel:       cancel,
+		threads:      make(map[string]*thread),
+
+		logger: logging.Or(cfg.Logger, "email"),
+	}
+}
+
+func (c *Client) Start() error {
+	if c.imapAddr == "" || c.smtpAddr == "" || c.address.Address == "" {
+		return fmt.Errorf("email channel needs an IMAP server, an SMTP server and an address")
+	}
+
+	c.mu.Lock()
+	if c.started {
+		c.mu.Unlock()
+		return fmt.Errorf("email channel already started")
+	}
+	c.started = true
+	c.mu.Unlock()
+
+	c.logger.Info("Starting email channel", "address", c.address.Address, "mailbox", c.mailbox, "interval", c.pollInterval)
+	if c.access == nil {
+		c.logger.Warn("Email channel answers every sender, set allowed senders to restrict it")
+	}
+
+	c.wg.Add(1)
+	go c.run()
+
+	return nil
+}
+
+func (c *Client) Stop() error {
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return fmt.Errorf("email channel not started")
+	}
+	c.started = false
+	c.mu.Unlock()
+
+	c.logger.Info("Stopping email channel")
+	c.cancel()
+	c.wg.Wait()
+	return nil
+}
+
+func (c *Client) IsRunning() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.started
+}
+
+func (c *Client) run() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.poll(); err != nil {
+			c.logger.Error("Failed to check mailbox", "error", err)
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll publishes the unseen emails in the mailbox and marks them seen.
+// Emails that cannot be parsed are marked seen too, so they are not tried
+// again on every poll.
+func (c *Client) poll() error {
+	conn, err := dialIMAP(c.imapAddr, c.imapTLS)
+	if err != nil {
+		return err
+	}
+	defer conn.logout()
+
+	if err := conn.login(c.username, c.password); err != nil {
+		return err
+	}
+	if err := conn.selectMailbox(c.mailbox); err != nil {
+		return err
+	}
+
+	uids, err := conn.searchUnseen()
+	if err != nil {
+		return err
+	}
+
+	for _, uid := range uids {
+		if c.ctx.Err() != nil {
+			return nil
+		}
+
+		raw, err := conn.fetch(uid)
+		if err != nil {
+			return err
+		}
+
+		if email, err := parseEmail(raw); err != nil {
+			c.logger.Warn("Skipping email that cannot be parsed", "uid", uid, "error", err)
+		} else {
+			c.handleEmail(email)
+		}
+
+		if err := conn.markSeen(uid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) handleEmail(email *Email) {
+	sender := strings.ToLower(email.From.Address)
+	if email.AutoReply || sender == strings.ToLower(c.address.Address) {
+		c.logger.Debug("Ignoring automatic email", "from", sender, "subject", email.Subject)
+		return
+	}
+
+	chatID := email.ChatID()
+
+	user, ok := c.access.authorize(email)
+	if !ok {
+		c.logger.Warn("Ignoring email from a sender who is not allowed", "chat_id", chatID, "from", sender)
+		return
+	}
+
+	if email.Body == "" {
+		return
+	}
+
+	// The subject of the first email is often the task itself.
+	isNew := c.trackThread(chatID, email)
+	content := email.Body
+	if isNew && email.Subject != "" {
+		content = "Subject: " + email.Subject + "\n\n" + content
+	}
+
+	c.logger.Info("Email received", "chat_id", chatID, "from", sender, "preview", logging.Preview(email.Subject, 40))
+
+	msg := &bus.Message{
+		ID:      fmt.Sprintf("email-%d", time.Now().UnixNano()),
+		Channel: bus.ChannelEmail,
+		ChatID:  chatID,
+		Content: content,
+	}
+	if user != nil {
+		msg.Metadata = map[string]interface{}{
+			bus.MetadataUser: *user,
+		}
+	}
+
+	if err := c.messageBus.Publish(c.ctx, bus.ChannelEmail, msg); err != nil {
+		c.logger.Error("Failed to publish message to bus", "chat_id", chatID, "error", err)
+	}
+}
+
+// trackThread remembers where replies in the email's thread go and reports
+// whether the thread is new.
+func (c *Client) trackThread(chatID string, email *Email) bool {
+	c.threadsMu.Lock()
+	defer c.threadsMu.Unlock()
+
+	existing, found := c.threads[chatID]
+	if !found && len(c.threads) >= maxThreads {
+		c.evictOldestThread()
+	}
+
+	references := email.References
+	if email.MessageID != "" {
+		references = append(references, email.MessageID)
+	}
+
+	subject := email.Subject
+	if found && subject == "" {
+		subject = existing.subject
+	}
+
+	c.threads[chatID] = &thread{
+		to:         email.From,
+		subject:    subject,
+		lastID:     email.MessageID,
+		references: references,
+		updated:    time.Now(),
+	}
+	return !found && email.InReplyTo == ""
+}
+
+func (c *Client) evictOldestThread() {
+	var oldest string
+	for chatID, t := range c.threads {
+		if oldest == "" || t.updated.Before(c.threads[oldest].updated) {
+			oldest = chatID
+		}
+	}
+	delete(c.threads, oldest)
+}
+
+// SendResponse emails text to the sender of the latest email in the chat's
+// thread, as a reply to it. A chat ID that is an email address starts a new
+// thread with that address instead.
+func (c *Client) SendResponse(chatID, text string) error {
+	c.threadsMu.Lock()
+	t, ok := c.threads[chatID]
+	if !ok {
+		c.threadsMu.Unlock()
+		if to, err := mail.ParseAddress(chatID); err == nil {
+			return c.sendNew(to, text)
+		}
+		return fmt.Errorf("no email thread for chat %s", chatID)
+	}
+	reply := *t
+	c.threadsMu.Unlock()
+
+	messageID := c.newMessageID()
+	data, err := c.compose(&reply, messageID, text)
+	if err != nil {
+		return err
+	}
+
+	if err := c.send(reply.to.Address, data); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	// Later replies follow this one in the thread.
+	c.threadsMu.Lock()
+	if t, ok := c.threads[chatID]; ok && t.lastID == reply.lastID {
+		t.lastID = messageID
+		t.references = append(t.references, messageID)
+	}
+	c.threadsMu.Unlock()
+	return nil
+}
+
+// sendNew starts a thread with to, using the first line of text as the
+// subject. Answers to it continue in the chat they would get as replies.
+func (c *Client) sendNew(to *mail.Address, text string) error {
+	subject, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if runes := []rune(subject); len(runes) > maxSubjectLength {
+		subject = string(runes[:maxSubjectLength]) + "..."
+	}
+
+	t := &thread{to: to, subject: subject}
+	messageID := c.newMessageID()
+	data, err := c.compose(t, messageID, text)
+	if err != nil {
+		return err
+	}
+
+	if err := c.send(to.Address, data); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	t.lastID = messageID
+	t.references = []string{messageID}
+	t.updated = time.Now()
+
+	c.threadsMu.Lock()
+	if len(c.threads) >= maxThreads {
+		c.evictOldestThread()
+	}
+	c.threads[threadChatID(messageID)] = t
+	c.threadsMu.Unlock()
+	return nil
+}
+
+func (c *Client) compose(t *thread, messageID, text string) ([]byte, error) {
+	subject := t.subject
+	autoSubmitted := "auto-generated"
+	if t.lastID != "" {
+		autoSubmitted = "auto-replied"
+		if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+			subject = strings.TrimSpace("Re: " + subject)
+		}
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", c.address.String())
+	header("To", t.to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	if t.lastID != "" {
+		header("In-Reply-To", t.lastID)
+	}
+	if len(t.references) > 0 {
+		header("References", strings.Join(t.references, " "))
+	}
+	header("Auto-Submitted", autoSubmitted)
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	writer := quotedprintable.NewWriter(&buf)
+	if _, err := writer.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n"))); err != nil {
+		return nil, fmt.Errorf("failed to encode email: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode email: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Client) newMessageID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+
+	domain := "miniclaw"
+	if at := strings.LastIndex(c.address.Address, "@"); at >= 0 {
+		domain = c.address.Address[at+1:]
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+func (c *Client) send(to string, data []byte) error {
+	host, port, err := net.SplitHostPort(c.smtpAddr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address: %w", err)
+	}
+
+	var client *smtp.Client
+	if port == "465" {
+		conn, err := tls.Dial("tcp", c.smtpAddr, &tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+		if client, err = smtp.NewClient(conn, host); err != nil {
+			conn.Close()
+			return err
+		}
+	} else {
+		if client, err = smtp.Dial(c.smtpAddr); err != nil {
+			return err
+		}
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				client.Close()
+				return err
+			}
+		}
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("AUTH"); ok && c.password != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.username, c.password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(c.address.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,276 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+// fakeIMAP serves a mailbox of unseen messages to one client at a time.
+type fakeIMAP struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages map[uint32]string
+	seen     map[uint32]bool
+}
+
+func newFakeIMAP(t *testing.T, messages map[uint32]string) *fakeIMAP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeIMAP{listener: listener, messages: messages, seen: make(map[uint32]bool)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeIMAP) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, command, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+		s.mu.Lock()
+		switch {
+		case strings.HasPrefix(command, "LOGIN"):
+			if command != `LOGIN "assistant@example.com" "secret"` {
+				fmt.Fprintf(conn, "%s NO invalid credentials\r\n", tag)
+				s.mu.Unlock()
+				continue
+			}
+		case command == "UID SEARCH UNSEEN":
+			var uids []string
+			for uid := range s.messages {
+				if !s.seen[uid] {
+					uids = append(uids, fmt.Sprint(uid))
+				}
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+		case strings.HasPrefix(command, "UID FETCH"):
+			var uid uint32
+			fmt.Sscanf(command, "UID FETCH %d", &uid)
+			message := s.messages[uid]
+			fmt.Fprintf(conn, "* 1 FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid, len(message), message)
+		case strings.HasPrefix(command, "UID STORE"):
+			var uid uint32
+			fmt.Sscanf(command, "UID STORE %d", &uid)
+			s.seen[uid] = true
+		case command == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK LOGOUT completed\r\n", tag)
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func (s *fakeIMAP) isSeen(uid uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[uid]
+}
+
+// fakeSMTP accepts mail without authentication and hands over each message.
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			reader := bufio.NewReader(conn)
+			fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					break
+				}
+				switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+				case "EHLO", "HELO":
+					fmt.Fprint(conn, "250 localhost\r\n")
+				case "DATA":
+					fmt.Fprint(conn, "354 go ahead\r\n")
+					var data strings.Builder
+					for {
+						line, err := reader.ReadString('\n')
+						if err != nil || line == ".\r\n" {
+							break
+						}
+						data.WriteString(line)
+					}
+					messages <- data.String()
+					fmt.Fprint(conn, "250 queued\r\n")
+				case "QUIT":
+					fmt.Fprint(conn, "221 bye\r\n")
+				default:
+					fmt.Fprint(conn, "250 ok\r\n")
+				}
+			}
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestEmailRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	imap := newFakeIMAP(t, map[uint32]string{
+		7: "From: Alice <alice@example.com>\r\nMessage-ID: <task@example.com>\r\nSubject: Trip\r\n\r\nPlan a weekend in Lisbon.\r\n",
+		8: "From: stranger@spam.example\r\nMessage-ID: <spam@spam.example>\r\nSubject: Hi\r\n\r\nBuy now\r\n",
+		9: "From: alice@example.com\r\nMessage-ID: <ooo@example.com>\r\nAuto-Submitted: auto-replied\r\nSubject: Away\r\n\r\nOut of office\r\n",
+	})
+	smtpAddr, sent := fakeSMTP(t)
+
+	client := NewClient(&Config{
+		IMAPAddr: imap.listener.Addr().String(),
+		SMTPAddr: smtpAddr,
+		Username: "assistant@example.com",
+		Password: "secret",
+		Access:   AccessConfig{AllowedSenders: []string{"@example.com"}},
+	}, messageBus, ctx)
+	client.imapTLS = false
+
+	requests := make(chan *bus.Message, 4)
+	messageBus.Subscribe(bus.ChannelEmail, func(ctx context.Context, msg *bus.Message) error {
+		if !msg.IsReply() {
+			requests <- msg
+		}
+		return nil
+	})
+	messageBus.Subscribe(bus.ChannelEmail, NewHandler(client).HandleMessage)
+
+	if err := client.poll(); err != nil {
+		t.Fatalf("Failed to poll: %v", err)
+	}
+	for _, uid := range []uint32{7, 8, 9} {
+		if !imap.isSeen(uid) {
+			t.Errorf("Expected email %d to be marked seen", uid)
+		}
+	}
+
+	var request *bus.Message
+	select {
+	case request = <-requests:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the email to reach the bus")
+	}
+	if request.Content != "Subject: Trip\n\nPlan a weekend in Lisbon." {
+		t.Errorf("Unexpected content %q", request.Content)
+	}
+	if user, ok := request.User(); !ok || user.ID != "email:alice@example.com" {
+		t.Errorf("Expected Alice as the user, got %+v", user)
+	}
+	select {
+	case msg := <-requests:
+		t.Errorf("Expected the spam and the auto-reply to be ignored, got %q", msg.Content)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	reply := &bus.Message{ID: "reply", Channel: bus.ChannelEmail, ChatID: request.ChatID, Content: "Day 1: Alfama. Day 2: Belém."}
+	reply.SetReplyTo(request)
+	if err := messageBus.Publish(ctx, bus.ChannelEmail, reply); err != nil {
+		t.Fatalf("Failed to publish reply: %v", err)
+	}
+
+	select {
+	case data := <-sent:
+		for _, want := range []string{
+			"To: \"Alice\" <alice@example.com>",
+			"Subject: Re: Trip",
+			"In-Reply-To: <task@example.com>",
+			"References: <task@example.com>",
+			"Day 1: Alfama. Day 2: Bel=C3=A9m.",
+		} {
+			if !strings.Contains(data, want) {
+				t.Errorf("Expected %q in the reply, got:\n%s", want, data)
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the reply to be emailed")
+	}
+}
+
+func TestSendToAddressStartsThread(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	smtpAddr, sent := fakeSMTP(t)
+	client := NewClient(&Config{
+		SMTPAddr: smtpAddr,
+		Username: "assistant@example.com",
+		Password: "secret",
+	}, bus.NewInMemoryMessageBus(ctx, nil), ctx)
+
+	if err := client.SendResponse("bob@example.com", "Backup finished\n\nAll 3 disks are fine."); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+
+	data := <-sent
+	for _, want := range []string{"To: <bob@example.com>", "Subject: Backup finished\r\n", "Auto-Submitted: auto-generated"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("Expected %q in the email, got:\n%s", want, data)
+		}
+	}
+	if strings.Contains(data, "In-Reply-To") {
+		t.Errorf("Expected a new thread, got:\n%s", data)
+	}
+
+	// Bob's answer lands in a chat the client can reply to.
+	var messageID string
+	for _, line := range strings.Split(data, "\r\n") {
+		if id, ok := strings.CutPrefix(line, "Message-ID: "); ok {
+			messageID = id
+		}
+	}
+	answer := &Email{MessageID: "<answer@example.com>", InReplyTo: messageID, References: []string{messageID}}
+	client.threadsMu.Lock()
+	_, ok := client.threads[answer.ChatID()]
+	client.threadsMu.Unlock()
+	if !ok {
+		t.Error("Expected the answer's chat to be known")
+	}
+
+	if err := client.SendResponse("email-unknown", "hi"); err == nil {
+		t.Error("Expected an unknown chat to fail")
+	}
+}
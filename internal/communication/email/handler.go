@@ -0,0 +1,51 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+type Handler struct {
+	client *Client
+}
+
+func NewHandler(client *Client) *Handler {
+	return &Handler{
+		client: client,
+	}
+}
+
+// HandleMessage emails the agent's replies. Streamed updates are skipped,
+// an email is only sent once the answer is complete.
+func (h *Handler) HandleMessage(ctx context.Context, msg *bus.Message) error {
+	if msg.Channel != bus.ChannelEmail || !msg.IsReply() || msg.IsControl() {
+		return nil
+	}
+
+	h.client.logger.Debug("Sending email", "chat_id", msg.ChatID, "preview", logging.Preview(msg.Content, 40))
+
+	content := msg.Content
+	if toolUses := msg.ToolUses(); len(toolUses) > 0 {
+		content += formatToolUses(toolUses)
+	}
+
+	if err := h.client.SendResponse(msg.ChatID, content); err != nil {
+		h.client.logger.Error("Failed to send email", "chat_id", msg.ChatID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func formatToolUses(toolUses []bus.ToolUse) string {
+	var builder strings.Builder
+	builder.WriteString("\n\n--\nTools used:\n")
+	for i, toolUse := range toolUses {
+		fmt.Fprintf(&builder, "%d. %s\n", i+1, toolUse.Summary())
+	}
+	return builder.String()
+}
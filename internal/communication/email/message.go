@@ -0,0 +1,220 @@
+package email
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+const maxBodyLength = 100 * 1024
+
+// Email is an incoming message, reduced to what the agent and the reply
+// need.
+type Email struct {
+	MessageID  string
+	InReplyTo  string
+	References []string
+	From       *mail.Address
+	Subject    string
+	Body       string
+	// AutoReply is set for vacation notices, bounces and mailing list
+	// traffic, which must never be answered.
+	AutoReply bool
+	// AuthResults is the topmost Authentication-Results header.
+	AuthResults string
+}
+
+var wordDecoder = &mime.WordDecoder{}
+
+func parseEmail(raw []byte) (*Email, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email: %w", err)
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid From address: %w", err)
+	}
+
+	subject, err := wordDecoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	body, err := textBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	autoSubmitted := strings.ToLower(msg.Header.Get("Auto-Submitted"))
+	precedence := strings.ToLower(msg.Header.Get("Precedence"))
+
+	return &Email{
+		MessageID:  strings.TrimSpace(msg.Header.Get("Message-Id")),
+		InReplyTo:  strings.TrimSpace(msg.Header.Get("In-Reply-To")),
+		References: strings.Fields(msg.Header.Get("References")),
+		From:       from,
+		Subject:    strings.TrimSpace(subject),
+		Body:       stripQuoted(body),
+		AutoReply: (autoSubmitted != "" && autoSubmitted != "no") ||
+			precedence == "bulk" || precedence == "junk" || precedence == "list" ||
+			msg.Header.Get("List-Id") != "",
+		AuthResults: msg.Header.Get("Authentication-Results"),
+	}, nil
+}
+
+// textBody returns the plain text of a body, picking the text/plain part of
+// multipart messages and falling back to HTML with the tags removed.
+func textBody(contentType, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	body = decodeTransfer(encoding, body)
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		var html string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to read message part: %w", err)
+			}
+			if strings.HasPrefix(part.Header.Get("Content-Disposition"), "attachment") {
+				continue
+			}
+
+			text, err := textBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", err
+			}
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			if partType == "text/html" {
+				if html == "" {
+					html = text
+				}
+				continue
+			}
+			if text != "" {
+				return text, nil
+			}
+		}
+		return html, nil
+	}
+
+	if !strings.HasPrefix(mediaType, "text/") {
+		return "", nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxBodyLength))
+	if err != nil {
+		return "", fmt.Errorf("failed to read message body: %w", err)
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if mediaType == "text/html" {
+		text = htmlToText(text)
+	}
+	return text, nil
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &lineJoiner{r: body})
+	default:
+		return body
+	}
+}
+
+// lineJoiner drops the line breaks base64 bodies are wrapped with.
+type lineJoiner struct {
+	r io.Reader
+}
+
+func (l *lineJoiner) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
+
+var (
+	htmlBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+func htmlToText(html string) string {
+	text := htmlBreaks.ReplaceAllString(html, "\n")
+	text = htmlTags.ReplaceAllString(text, "")
+	for _, entity := range [][2]string{{"&nbsp;", " "}, {"&lt;", "<"}, {"&gt;", ">"}, {"&quot;", `"`}, {"&#39;", "'"}, {"&amp;", "&"}} {
+		text = strings.ReplaceAll(text, entity[0], entity[1])
+	}
+	return blankLines.ReplaceAllString(text, "\n\n")
+}
+
+var quoteHeader = regexp.MustCompile(`^On .+ wrote:$`)
+
+// stripQuoted removes the quoted previous messages and the signature from a
+// reply. The agent has the conversation in its history already.
+func stripQuoted(body string) string {
+	lines := strings.Split(body, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if quoteHeader.MatchString(trimmed) || trimmed == "-----Original Message-----" || line == "-- " {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// threadRoot returns the ID of the first message in the email's thread.
+func (e *Email) threadRoot() string {
+	if len(e.References) > 0 {
+		return e.References[0]
+	}
+	if e.InReplyTo != "" {
+		return e.InReplyTo
+	}
+	if e.MessageID != "" {
+		return e.MessageID
+	}
+	return e.From.Address + "\n" + e.Subject
+}
+
+// ChatID returns the chat ID shared by all emails of a thread. Message IDs
+// may contain any character, so they are hashed.
+func (e *Email) ChatID() string {
+	return threadChatID(e.threadRoot())
+}
+
+func threadChatID(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return "email-" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,86 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMultipartEmail(t *testing.T) {
+	raw := strings.ReplaceAll(`From: "Alice" <alice@example.com>
+To: assistant@example.com
+Subject: =?utf-8?q?Quarterly_r=C3=A9port?=
+Message-ID: <root@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Please summarize the attached numbers and draft a short r=C3=A9sum=C3=A9 =
+for the team.
+
+--=20
+Alice
+--b1
+Content-Type: text/html; charset=utf-8
+
+<p>Please summarize</p>
+--b1--
+`, "\n", "\r\n")
+
+	email, err := parseEmail([]byte(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	if email.From.Address != "alice@example.com" || email.From.Name != "Alice" {
+		t.Errorf("Unexpected sender %+v", email.From)
+	}
+	if email.Subject != "Quarterly réport" {
+		t.Errorf("Expected the decoded subject, got %q", email.Subject)
+	}
+	if email.Body != "Please summarize the attached numbers and draft a short résumé for the team." {
+		t.Errorf("Expected the plain text without the signature, got %q", email.Body)
+	}
+	if email.AutoReply {
+		t.Error("Expected a normal email")
+	}
+}
+
+func TestParseReplyKeepsThread(t *testing.T) {
+	first, _ := parseEmail([]byte("From: alice@example.com\r\nMessage-ID: <root@example.com>\r\nSubject: Plan\r\n\r\nMake a plan\r\n"))
+
+	reply, err := parseEmail([]byte(strings.ReplaceAll(`From: alice@example.com
+Message-ID: <second@example.com>
+In-Reply-To: <answer@miniclaw>
+References: <root@example.com> <answer@miniclaw>
+Subject: Re: Plan
+Content-Type: text/plain
+
+Looks good, add a budget.
+
+On Mon, 5 Oct 2026 at 10:00, Assistant <assistant@example.com> wrote:
+> Here is the plan
+`, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	if reply.Body != "Looks good, add a budget." {
+		t.Errorf("Expected the quoted message to be removed, got %q", reply.Body)
+	}
+	if reply.ChatID() != first.ChatID() || !strings.HasPrefix(first.ChatID(), "email-") {
+		t.Errorf("Expected the reply in the same chat, got %s and %s", first.ChatID(), reply.ChatID())
+	}
+}
+
+func TestParseAutoReply(t *testing.T) {
+	email, err := parseEmail([]byte("From: bob@example.com\r\nAuto-Submitted: auto-replied\r\nSubject: Out of office\r\n\r\nAway\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if !email.AutoReply {
+		t.Error("Expected the vacation notice to be recognized")
+	}
+}
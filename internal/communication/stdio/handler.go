@@ -0,0 +1,35 @@
+package stdio
+
+import (
+	"context"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+type Handler struct {
+	server *Server
+}
+
+func NewHandler(server *Server) *Handler {
+	return &Handler{
+		server: server,
+	}
+}
+
+func (h *Handler) HandleMessage(ctx context.Context, msg *bus.Message) error {
+	// Requests from the client go through the bus too; only the replies to
+	// them are written.
+	if msg.Channel != bus.ChannelStdio || !msg.IsReply() {
+		return nil
+	}
+
+	h.server.logger.Debug("Sending message", "chat_id", msg.ChatID, "preview", logging.Preview(msg.Content, 40))
+
+	if err := h.server.Deliver(msg); err != nil {
+		h.server.logger.Error("Failed to send message", "chat_id", msg.ChatID, "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,457 @@
+// Package stdio lets another program embed the agent as a subprocess. It
+// speaks JSON-RPC 2.0 over standard input and output, one message per line.
+package stdio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+// ProtocolVersion is returned by initialize, so clients can tell which
+// methods and notifications to expect.
+const ProtocolVersion = 1
+
+// Methods the client calls.
+const (
+	MethodInitialize  = "initialize"
+	MethodSendMessage = "send_message"
+	MethodConfirm     = "confirm"
+	MethodClear       = "clear"
+	MethodSetChat     = "set_chat"
+	MethodSwitchModel = "switch_model"
+)
+
+// Notifications sent while the agent works on a send_message request. Their
+// request_id is the id of that request.
+const (
+	NotifyChunk        = "chunk"
+	NotifyToolStarted  = "tool_started"
+	NotifyToolFinished = "tool_finished"
+	NotifyConfirmation = "confirmation"
+)
+
+// JSON-RPC error codes. Failed replies from the agent use CodeAgentError
+// with the bus.Error code in the data.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeAgentError     = -32000
+)
+
+const (
+	defaultChatID = "stdio"
+	// maxLineSize bounds a single request, mostly to catch a client that
+	// never sends a newline.
+	maxLineSize = 16 << 20
+)
+
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type Error struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    *ErrorData `json:"data,omitempty"`
+}
+
+type ErrorData struct {
+	Code string `json:"code"`
+}
+
+type SendMessageParams struct {
+	Content string `json:"content"`
+	// ChatID switches to another conversation before sending, like
+	// set_chat. Model picks the model for this message only.
+	ChatID string `json:"chat_id,omitempty"`
+	Model  string `json:"model,omitempty"`
+}
+
+type MessageResult struct {
+	ChatID  string        `json:"chat_id"`
+	Content string        `json:"content"`
+	Tools   []bus.ToolUse `json:"tools,omitempty"`
+}
+
+type SessionResult struct {
+	ProtocolVersion int    `json:"protocol_version,omitempty"`
+	ChatID          string `json:"chat_id"`
+	Model           string `json:"model,omitempty"`
+}
+
+type ConfirmParams struct {
+	Approved bool `json:"approved"`
+}
+
+type SetChatParams struct {
+	ChatID string `json:"chat_id"`
+}
+
+type SwitchModelParams struct {
+	Model string `json:"model"`
+}
+
+// ChunkParams carries the new text in Delta, or all of it in Content when
+// the text so far changed and the client should replace it.
+type ChunkParams struct {
+	RequestID json.RawMessage `json:"request_id"`
+	Delta     string          `json:"delta,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type ToolParams struct {
+	RequestID json.RawMessage `json:"request_id"`
+	Tool      bus.ToolUse     `json:"tool"`
+}
+
+// ConfirmationParams asks the client to allow a tool call, answered with
+// the confirm method.
+type ConfirmationParams struct {
+	RequestID json.RawMessage `json:"request_id"`
+	Prompt    string          `json:"prompt"`
+}
+
+type Config struct {
+	Reader io.Reader
+	Writer io.Writer
+	// ChatID is the conversation messages go to until the client picks
+	// another one, "stdio" by default.
+	ChatID string
+	Logger *slog.Logger
+}
+
+type pendingRequest struct {
+	id   json.RawMessage
+	sent string
+}
+
+type Server struct {
+	reader     io.Reader
+	writer     io.Writer
+	messageBus bus.MessageBus
+	ctx        context.Context
+	logger     *slog.Logger
+
+	mu      sync.Mutex
+	chatID  string
+	model   string
+	pending map[string]*pendingRequest
+
+	writeMu sync.Mutex
+}
+
+func NewServer(cfg *Config, messageBus bus.MessageBus, ctx context.Context) *Server {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	chatID := cfg.ChatID
+	if chatID == "" {
+		chatID = defaultChatID
+	}
+
+	return &Server{
+		reader:     cfg.Reader,
+		writer:     cfg.Writer,
+		messageBus: messageBus,
+		ctx:        ctx,
+		logger:     logging.Or(cfg.Logger, "stdio"),
+		chatID:     chatID,
+		pending:    make(map[string]*pendingRequest),
+	}
+}
+
+// Run handles requests until the input is closed. Answers to requests still
+// in progress are written when they arrive.
+func (s *Server) Run() error {
+	scanner := bufio.NewScanner(s.reader)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		s.handleLine(line)
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handleLine(line []byte) {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		s.respond(nil, nil, &Error{Code: CodeParseError, Message: fmt.Sprintf("invalid JSON: %v", err)})
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.respond(req.ID, nil, &Error{Code: CodeInvalidRequest, Message: `expected {"jsonrpc":"2.0","method":...}`})
+		return
+	}
+
+	result, rpcErr := s.call(&req)
+	if result == nil && rpcErr == nil {
+		return
+	}
+	if req.ID != nil {
+		s.respond(req.ID, result, rpcErr)
+	}
+}
+
+// call runs a method. A nil result and error means the answer comes later.
+func (s *Server) call(req *Request) (interface{}, *Error) {
+	switch req.Method {
+	case MethodInitialize:
+		session := s.session()
+		session.ProtocolVersion = ProtocolVersion
+		return session, nil
+
+	case MethodSendMessage:
+		var params SendMessageParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(params.Content) == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "content is empty"}
+		}
+		return nil, s.publish(req.ID, params.Content, params.ChatID, params.Model)
+
+	case MethodClear:
+		return nil, s.publish(req.ID, "/new", "", "")
+
+	case MethodConfirm:
+		var params ConfirmParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if err := s.confirm(params.Approved); err != nil {
+			return nil, err
+		}
+		return struct{}{}, nil
+
+	case MethodSetChat:
+		var params SetChatParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if params.ChatID == "" {
+			return nil, &Error{Code: CodeInvalidParams, Message: "chat_id is empty"}
+		}
+		s.mu.Lock()
+		s.chatID = params.ChatID
+		s.mu.Unlock()
+		return s.session(), nil
+
+	case MethodSwitchModel:
+		var params SwitchModelParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.model = params.Model
+		s.mu.Unlock()
+		return s.session(), nil
+
+	default:
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+}
+
+func decodeParams(req *Request, params interface{}) *Error {
+	if len(req.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	return nil
+}
+
+func (s *Server) session() *SessionResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &SessionResult{ChatID: s.chatID, Model: s.model}
+}
+
+// publish hands a message to the agent. The request is answered when the
+// reply arrives.
+func (s *Server) publish(id json.RawMessage, content, chatID, model string) *Error {
+	s.mu.Lock()
+	if chatID != "" {
+		s.chatID = chatID
+	}
+	chatID = s.chatID
+	if model == "" {
+		model = s.model
+	}
+
+	metadata := map[string]interface{}{
+		bus.MetadataStreaming:  true,
+		bus.MetadataToolEvents: true,
+	}
+	if model != "" {
+		metadata[bus.MetadataModel] = model
+	}
+
+	msg := &bus.Message{
+		ID:        fmt.Sprintf("stdio-%d", time.Now().UnixNano()),
+		Channel:   bus.ChannelStdio,
+		ChatID:    chatID,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata:  metadata,
+	}
+	s.pending[msg.ID] = &pendingRequest{id: id}
+	s.mu.Unlock()
+
+	s.logger.Info("Message received", "chat_id", chatID, "preview", logging.Preview(content, 40))
+
+	if err := s.messageBus.Publish(s.ctx, bus.ChannelStdio, msg); err != nil {
+		s.mu.Lock()
+		delete(s.pending, msg.ID)
+		s.mu.Unlock()
+
+		s.logger.Error("Failed to publish message to bus", "chat_id", chatID, "error", err)
+		return &Error{Code: CodeAgentError, Message: "failed to queue the message, please try again", Data: &ErrorData{Code: "publish_failed"}}
+	}
+	return nil
+}
+
+func (s *Server) confirm(approved bool) *Error {
+	data := "confirm:no"
+	if approved {
+		data = "confirm:yes"
+	}
+
+	s.mu.Lock()
+	chatID := s.chatID
+	s.mu.Unlock()
+
+	err := s.messageBus.Publish(s.ctx, bus.ChannelStdio, &bus.Message{
+		ID:        fmt.Sprintf("stdio-%d", time.Now().UnixNano()),
+		Channel:   bus.ChannelStdio,
+		ChatID:    chatID,
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{bus.MetadataCallback: &bus.Callback{Data: data}},
+	})
+	if err != nil {
+		return &Error{Code: CodeAgentError, Message: "failed to send the answer, please try again", Data: &ErrorData{Code: "publish_failed"}}
+	}
+	return nil
+}
+
+// Deliver writes a reply from the agent as a notification, or as the answer
+// to its request once it is final.
+func (s *Server) Deliver(msg *bus.Message) error {
+	s.mu.Lock()
+	request, ok := s.pending[msg.ReplyTo()]
+	if !ok {
+		s.mu.Unlock()
+		s.logger.Debug("Dropping reply to an answered request", "reply_to", msg.ReplyTo())
+		return nil
+	}
+
+	var notification *Notification
+	switch event := msg.ToolEvent(); {
+	case event != nil:
+		method := NotifyToolStarted
+		if event.Phase == bus.ToolFinished {
+			method = NotifyToolFinished
+		}
+		notification = &Notification{Method: method, Params: &ToolParams{RequestID: request.id, Tool: event.Tool}}
+
+	case msg.IsPartial():
+		if len(msg.Content) <= len(request.sent) {
+			s.mu.Unlock()
+			return nil
+		}
+		params := &ChunkParams{RequestID: request.id}
+		if strings.HasPrefix(msg.Content, request.sent) {
+			params.Delta = msg.Content[len(request.sent):]
+		} else {
+			params.Content = msg.Content
+		}
+		request.sent = msg.Content
+		notification = &Notification{Method: NotifyChunk, Params: params}
+
+	case isConfirmation(msg):
+		notification = &Notification{Method: NotifyConfirmation, Params: &ConfirmationParams{RequestID: request.id, Prompt: msg.Content}}
+
+	default:
+		delete(s.pending, msg.ReplyTo())
+	}
+	s.mu.Unlock()
+
+	if notification != nil {
+		notification.JSONRPC = "2.0"
+		return s.write(notification)
+	}
+
+	if request.id == nil {
+		return nil
+	}
+	if code := msg.ErrorCode(); code != "" {
+		return s.respond(request.id, nil, &Error{Code: CodeAgentError, Message: msg.Content, Data: &ErrorData{Code: code}})
+	}
+	return s.respond(request.id, &MessageResult{ChatID: msg.ChatID, Content: msg.Content, Tools: msg.ToolUses()}, nil)
+}
+
+// isConfirmation reports whether msg asks to allow a tool call rather than
+// answering the request.
+func isConfirmation(msg *bus.Message) bool {
+	for _, row := range msg.Buttons() {
+		for _, button := range row {
+			if strings.HasPrefix(button.Data, "confirm:") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Server) respond(id json.RawMessage, result interface{}, rpcErr *Error) error {
+	return s.write(&Response{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+}
+
+func (s *Server) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Warn("Failed to marshal message", "error", err)
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to stdout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package stdio
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+type harness struct {
+	t        *testing.T
+	ctx      context.Context
+	bus      *bus.InMemoryMessageBus
+	requests chan *bus.Message
+	input    *io.PipeWriter
+	output   *bufio.Scanner
+}
+
+func newHarness(t *testing.T) *harness {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	t.Cleanup(func() { messageBus.Close() })
+
+	inputReader, inputWriter := io.Pipe()
+	outputReader, outputWriter := io.Pipe()
+	t.Cleanup(func() {
+		inputWriter.Close()
+		outputReader.Close()
+	})
+
+	server := NewServer(&Config{Reader: inputReader, Writer: outputWriter}, messageBus, ctx)
+	go server.Run()
+
+	h := &harness{t: t, ctx: ctx, bus: messageBus, requests: make(chan *bus.Message, 8), input: inputWriter, output: bufio.NewScanner(outputReader)}
+	messageBus.Subscribe(bus.ChannelStdio, func(ctx context.Context, msg *bus.Message) error {
+		if !msg.IsReply() {
+			h.requests <- msg
+		}
+		return nil
+	})
+	messageBus.Subscribe(bus.ChannelStdio, NewHandler(server).HandleMessage)
+	return h
+}
+
+func (h *harness) send(line string) {
+	if _, err := io.WriteString(h.input, line+"\n"); err != nil {
+		h.t.Fatalf("Failed to write request: %v", err)
+	}
+}
+
+// next reads the next message the server wrote.
+func (h *harness) next() map[string]json.RawMessage {
+	lines := make(chan []byte, 1)
+	go func() {
+		if h.output.Scan() {
+			lines <- append([]byte(nil), h.output.Bytes()...)
+		}
+	}()
+
+	select {
+	case line := <-lines:
+		var msg map[string]json.RawMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			h.t.Fatalf("Server wrote invalid JSON %q: %v", line, err)
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		h.t.Fatal("Timed out waiting for the server")
+		return nil
+	}
+}
+
+func (h *harness) nextRequest() *bus.Message {
+	select {
+	case msg := <-h.requests:
+		return msg
+	case <-time.After(2 * time.Second):
+		h.t.Fatal("Timed out waiting for a message to the agent")
+		return nil
+	}
+}
+
+func (h *harness) reply(request *bus.Message, reply *bus.Message) {
+	reply.Channel = bus.ChannelStdio
+	reply.ChatID = request.ChatID
+	reply.SetReplyTo(request)
+	if err := h.bus.Publish(h.ctx, bus.ChannelStdio, reply); err != nil {
+		h.t.Fatalf("Failed to publish reply: %v", err)
+	}
+}
+
+func field(t *testing.T, msg map[string]json.RawMessage, name string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(msg[name], v); err != nil {
+		t.Fatalf("Failed to decode %s of %v: %v", name, msg, err)
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	h := newHarness(t)
+
+	h.send(`{"jsonrpc":"2.0","id":7,"method":"send_message","params":{"content":"What's the weather?","chat_id":"editor"}}`)
+	request := h.nextRequest()
+	if request.ChatID != "editor" || request.Content != "What's the weather?" || !request.WantsStreaming() || !request.WantsToolEvents() {
+		t.Fatalf("Unexpected message to the agent: %+v", request)
+	}
+
+	h.reply(request, &bus.Message{ID: "r", Metadata: map[string]interface{}{
+		bus.MetadataToolEvent: &bus.ToolEvent{Phase: bus.ToolStarted, Tool: bus.ToolUse{Name: "web_search"}},
+	}})
+	notification := h.next()
+	var method string
+	var tool ToolParams
+	field(t, notification, "method", &method)
+	field(t, notification, "params", &tool)
+	if method != NotifyToolStarted || tool.Tool.Name != "web_search" || string(tool.RequestID) != "7" {
+		t.Fatalf("Expected a tool_started notification for request 7, got %v", notification)
+	}
+
+	// The bus may deliver updates out of order, so each is awaited.
+	var chunks []string
+	for _, partial := range []string{"Sunny", "Sunny and warm"} {
+		h.reply(request, &bus.Message{ID: "r", Content: partial, Metadata: map[string]interface{}{bus.MetadataPartial: true}})
+		var chunk ChunkParams
+		field(t, h.next(), "params", &chunk)
+		chunks = append(chunks, chunk.Delta)
+	}
+	if chunks[0] != "Sunny" || chunks[1] != " and warm" {
+		t.Fatalf("Expected the streamed text as deltas, got %q", chunks)
+	}
+
+	h.reply(request, &bus.Message{ID: "r", Content: "Sunny and warm."})
+	response := h.next()
+	var result MessageResult
+	field(t, response, "result", &result)
+	if string(response["id"]) != "7" || result.Content != "Sunny and warm." || result.ChatID != "editor" {
+		t.Fatalf("Expected the answer as the result of request 7, got %v", response)
+	}
+}
+
+func TestFailedReplyIsAnError(t *testing.T) {
+	h := newHarness(t)
+
+	h.send(`{"jsonrpc":"2.0","id":"a","method":"send_message","params":{"content":"hi"}}`)
+	request := h.nextRequest()
+	if request.ChatID != defaultChatID {
+		t.Fatalf("Expected the default chat, got %s", request.ChatID)
+	}
+
+	h.reply(request, &bus.Message{ID: "r", Content: "Sorry", Metadata: map[string]interface{}{bus.MetadataErrorCode: bus.ErrorInternal}})
+	var rpcErr Error
+	field(t, h.next(), "error", &rpcErr)
+	if rpcErr.Code != CodeAgentError || rpcErr.Data == nil || rpcErr.Data.Code != bus.ErrorInternal {
+		t.Fatalf("Expected an agent error with the error code, got %+v", rpcErr)
+	}
+}
+
+func TestConfirmation(t *testing.T) {
+	h := newHarness(t)
+
+	h.send(`{"jsonrpc":"2.0","id":1,"method":"send_message","params":{"content":"delete it"}}`)
+	request := h.nextRequest()
+
+	h.reply(request, &bus.Message{ID: "c", Content: "Allow the assistant to run delete_file?", Metadata: map[string]interface{}{
+		bus.MetadataButtons: [][]bus.Button{{{Text: "Yes", Data: "confirm:yes"}, {Text: "No", Data: "confirm:no"}}},
+	}})
+	var method string
+	field(t, h.next(), "method", &method)
+	if method != NotifyConfirmation {
+		t.Fatalf("Expected a confirmation notification, got %s", method)
+	}
+
+	h.send(`{"jsonrpc":"2.0","id":2,"method":"confirm","params":{"approved":true}}`)
+	answer := h.nextRequest()
+	if callback := answer.Callback(); callback == nil || callback.Data != "confirm:yes" || answer.ChatID != request.ChatID {
+		t.Fatalf("Expected the approval as a callback in the same chat, got %+v", answer)
+	}
+	if response := h.next(); string(response["id"]) != "2" || response["error"] != nil {
+		t.Fatalf("Expected confirm to succeed, got %v", response)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	h := newHarness(t)
+
+	tests := []struct {
+		line string
+		code int
+	}{
+		{`{not json`, CodeParseError},
+		{`{"id":1,"method":"send_message"}`, CodeInvalidRequest},
+		{`{"jsonrpc":"2.0","id":1,"method":"dance"}`, CodeMethodNotFound},
+		{`{"jsonrpc":"2.0","id":1,"method":"send_message","params":{"content":" "}}`, CodeInvalidParams},
+		{`{"jsonrpc":"2.0","id":1,"method":"set_chat","params":[]}`, CodeInvalidParams},
+	}
+
+	for _, tt := range tests {
+		h.send(tt.line)
+		var rpcErr Error
+		field(t, h.next(), "error", &rpcErr)
+		if rpcErr.Code != tt.code {
+			t.Errorf("%s: expected code %d, got %+v", tt.line, tt.code, rpcErr)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	h := newHarness(t)
+
+	h.send(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+	var session SessionResult
+	field(t, h.next(), "result", &session)
+	if session.ProtocolVersion != ProtocolVersion || session.ChatID != defaultChatID {
+		t.Fatalf("Unexpected initialize result: %+v", session)
+	}
+
+	h.send(`{"jsonrpc":"2.0","id":2,"method":"set_chat","params":{"chat_id":"work"}}`)
+	h.next()
+	h.send(`{"jsonrpc":"2.0","id":3,"method":"switch_model","params":{"model":"fast"}}`)
+	field(t, h.next(), "result", &session)
+	if session.ChatID != "work" || session.Model != "fast" {
+		t.Fatalf("Expected chat work and model fast, got %+v", session)
+	}
+
+	h.send(`{"jsonrpc":"2.0","id":4,"method":"clear"}`)
+	request := h.nextRequest()
+	if request.Content != "/new" || request.ChatID != "work" || request.Model() != "fast" {
+		t.Fatalf("Expected /new in chat work with model fast, got %+v", request)
+	}
+}
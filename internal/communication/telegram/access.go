@@ -0,0 +1,75 @@
+package telegram
+
+import (
+	"strconv"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+// AccessConfig limits who may talk to the bot. Admins are allowed too. With
+// all lists empty everyone is allowed, but only admins may use admin-only
+// tools and commands.
+type AccessConfig struct {
+	AllowedUsers []int64
+	AllowedChats []int64
+	Admins       []int64
+}
+
+type accessList struct {
+	users  map[int64]bool
+	chats  map[int64]bool
+	admins map[int64]bool
+}
+
+func newAccessList(cfg AccessConfig) *accessList {
+	if len(cfg.AllowedUsers) == 0 && len(cfg.AllowedChats) == 0 && len(cfg.Admins) == 0 {
+		return nil
+	}
+
+	return &accessList{
+		users:  idSet(cfg.AllowedUsers),
+		chats:  idSet(cfg.AllowedChats),
+		admins: idSet(cfg.Admins),
+	}
+}
+
+func idSet(ids []int64) map[int64]bool {
+	set := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// authorize returns the bus user for a sender, or false if the sender may
+// not use the bot. Without an access list everyone may, but nobody is an
+// admin. Senders are always needed with one, since only they can be checked.
+func (a *accessList) authorize(from *User, chatID int64) (*bus.User, bool) {
+	if from == nil {
+		return nil, a == nil
+	}
+	admin := false
+	if a != nil {
+		if !a.users[from.ID] && !a.admins[from.ID] && !a.chats[chatID] {
+			return nil, false
+		}
+		admin = a.admins[from.ID]
+	}
+
+	name := from.Username
+	if name == "" {
+		name = from.FirstName
+	}
+	return &bus.User{
+		ID:    "telegram:" + strconv.FormatInt(from.ID, 10),
+		Name:  name,
+		Admin: admin,
+	}, true
+}
+
+func senderID(from *User) string {
+	if from == nil {
+		return ""
+	}
+	return strconv.FormatInt(from.ID, 10)
+}
@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestAccessListAuthorize(t *testing.T) {
+	if user, ok := newAccessList(AccessConfig{}).authorize(nil, 1); !ok || user != nil {
+		t.Errorf("Expected everyone to be allowed without an access list, got %v, %v", user, ok)
+	}
+	if user, ok := newAccessList(AccessConfig{}).authorize(&User{ID: 30}, 30); !ok || user.Admin {
+		t.Errorf("Expected no admins without an access list, got %+v, %v", user, ok)
+	}
+
+	access := newAccessList(AccessConfig{
+		AllowedUsers: []int64{10},
+		AllowedChats: []int64{-100},
+		Admins:       []int64{1},
+	})
+
+	tests := []struct {
+		name    string
+		from    *User
+		chatID  int64
+		allowed bool
+		admin   bool
+	}{
+		{"admin", &User{ID: 1, Username: "root"}, 1, true, true},
+		{"allowed user", &User{ID: 10}, 10, true, false},
+		{"member of allowed chat", &User{ID: 20}, -100, true, false},
+		{"stranger", &User{ID: 30}, 30, false, false},
+		{"no sender", nil, -100, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, ok := access.authorize(tt.from, tt.chatID)
+			if ok != tt.allowed {
+				t.Fatalf("Expected allowed=%v, got %v", tt.allowed, ok)
+			}
+			if ok && user.Admin != tt.admin {
+				t.Errorf("Expected admin=%v, got %+v", tt.admin, user)
+			}
+		})
+	}
+}
+
+func TestBotIgnoresSendersNotAllowed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+
+	received := make(chan *bus.Message, 2)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	bot := NewBot(&Config{Token: "test-token", Access: AccessConfig{Admins: []int64{1}}}, messageBus, ctx)
+
+	send := func(userID int64, text string) {
+		bot.handleUpdate(&Update{
+			UpdateID: userID,
+			Message: &Message{
+				From: &User{ID: userID, FirstName: "Test"},
+				Chat: &Chat{ID: userID, Type: "private"},
+				Text: text,
+			},
+		})
+	}
+	send(2, "stranger")
+	send(1, "admin")
+
+	select {
+	case msg := <-received:
+		user, ok := msg.User()
+		if msg.Content != "admin" || !ok || !user.Admin || user.ID != "telegram:1" {
+			t.Errorf("Expected only the admin's message with its user, got %q %+v", msg.Content, user)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the admin's message to be published")
+	}
+
+	select {
+	case msg := <-received:
+		t.Errorf("Expected the stranger's message to be ignored, got %q", msg.Content)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBotWithoutAccessListHasNoAdmins(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+
+	received := make(chan *bus.Message, 1)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	bot := NewBot(&Config{Token: "test-token"}, messageBus, ctx)
+	bot.handleUpdate(&Update{
+		UpdateID: 1,
+		Message: &Message{
+			From: &User{ID: 2, FirstName: "Stranger"},
+			Chat: &Chat{ID: 2, Type: "private"},
+			Text: "delete everything",
+		},
+	})
+
+	select {
+	case msg := <-received:
+		if user, ok := msg.User(); !ok || user.Admin || user.ID != "telegram:2" {
+			t.Errorf("Expected the stranger to be a user without admin rights, got %+v", user)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message to be published")
+	}
+}
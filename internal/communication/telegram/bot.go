@@ -5,7 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -13,26 +13,40 @@ import (
 	"time"
 
 	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/speech"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
 )
 
 const (
 	defaultAPIURL       = "https://api.telegram.org/bot%s/%s"
+	defaultFileURL      = "https://api.telegram.org/file/bot%s/%s"
 	maxMessageLength    = 4096
 	defaultPollTimeout  = 30
 	defaultPollInterval = 3 * time.Second
 )
 
 type Update struct {
-	UpdateID int64    `json:"update_id"`
-	Message  *Message `json:"message,omitempty"`
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+
+	MessageReaction *MessageReactionUpdated `json:"message_reaction,omitempty"`
 }
 
 type Message struct {
-	MessageID int64  `json:"message_id"`
-	From      *User  `json:"from,omitempty"`
-	Chat      *Chat  `json:"chat"`
-	Date      int64  `json:"date"`
-	Text      string `json:"text,omitempty"`
+	MessageID int64       `json:"message_id"`
+	From      *User       `json:"from,omitempty"`
+	Chat      *Chat       `json:"chat"`
+	Date      int64       `json:"date"`
+	Text      string      `json:"text,omitempty"`
+	Caption   string      `json:"caption,omitempty"`
+	Photo     []PhotoSize `json:"photo,omitempty"`
+	Voice     *Voice      `json:"voice,omitempty"`
+	Document  *Document   `json:"document,omitempty"`
+
+	Entities       []MessageEntity `json:"entities,omitempty"`
+	ReplyToMessage *Message        `json:"reply_to_message,omitempty"`
 }
 
 type User struct {
@@ -52,9 +66,13 @@ type Chat struct {
 }
 
 type SendMessageRequest struct {
-	ChatID    string `json:"chat_id"`
-	Text      string `json:"text"`
-	ParseMode string `json:"parse_mode,omitempty"`
+	ChatID      string                `json:"chat_id"`
+	Text        string                `json:"text"`
+	ParseMode   string                `json:"parse_mode,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+
+	ReplyToMessageID         int64 `json:"reply_to_message_id,omitempty"`
+	AllowSendingWithoutReply bool  `json:"allow_sending_without_reply,omitempty"`
 }
 
 type APIResponse struct {
@@ -82,11 +100,60 @@ type Bot struct {
 	started      bool
 	pollTimeout  int
 	pollInterval time.Duration
+	fileURL      string
+	storage      storage.Storage
+	uploadDir    string
+	maxFileSize  int64
+	sent         *sentMessages
+	access       *accessList
+
+	streamResponses bool
+	streamInterval  time.Duration
+	streams         map[string]*streamState
+	streamsMu       sync.Mutex
+
+	typing   map[string]*typingState
+	typingMu sync.Mutex
+
+	commands   map[string]Command
+	commandsMu sync.RWMutex
+
+	// self is the bot's own user, known once getMe succeeded.
+	self             *User
+	groupTriggers    TriggerConfig
+	triggerOverrides *triggerStore
+
+	transcriber   speech.Transcriber
+	synthesizer   speech.Synthesizer
+	voiceRequests map[string]time.Time
+	voiceMu       sync.Mutex
+
+	logger *slog.Logger
 }
 
 type Config struct {
 	Token       string
 	PollTimeout int
+	Storage     storage.Storage
+	UploadDir   string
+	MaxFileSize int64
+
+	StreamResponses bool
+	StreamInterval  time.Duration
+
+	Access AccessConfig
+
+	// GroupTriggers decides which group messages are answered, unless a
+	// chat has its own set with /triggers.
+	GroupTriggers TriggerConfig
+
+	// Transcriber turns voice messages into text for the agent. With
+	// VoiceReplies, Synthesizer reads the answers to them aloud as well.
+	Transcriber  speech.Transcriber
+	Synthesizer  speech.Synthesizer
+	VoiceReplies bool
+
+	Logger *slog.Logger
 }
 
 func NewBot(cfg *Config, messageBus bus.MessageBus, ctx context.Context) *Bot {
@@ -97,23 +164,67 @@ func NewBot(cfg *Config, messageBus bus.MessageBus, ctx context.Context) *Bot {
 		pollTimeout = cfg.PollTimeout
 	}
 
-	return &Bot{
+	uploadDir := defaultUploadDir
+	if cfg.UploadDir != "" {
+		uploadDir = cfg.UploadDir
+	}
+
+	maxFileSize := int64(defaultMaxFileSize)
+	if cfg.MaxFileSize > 0 {
+		maxFileSize = cfg.MaxFileSize
+	}
+
+	streamInterval := defaultStreamInterval
+	if cfg.StreamInterval > 0 {
+		streamInterval = cfg.StreamInterval
+	}
+
+	bot := &Bot{
 		token:        cfg.Token,
 		apiURL:       fmt.Sprintf(defaultAPIURL, cfg.Token, "%s"),
 		updateOffset: 0,
 		httpClient: &http.Client{
 			Timeout: time.Duration(pollTimeout+5) * time.Second,
 		},
-		messageBus: messageBus,
-		ctx:        botCtx,
-		cancel:     cancel,
-		enabled:    cfg.Token != "",
+		messageBus:  messageBus,
+		ctx:         botCtx,
+		cancel:      cancel,
+		enabled:     cfg.Token != "",
+		fileURL:     fmt.Sprintf(defaultFileURL, cfg.Token, "%s"),
+		storage:     cfg.Storage,
+		uploadDir:   uploadDir,
+		maxFileSize: maxFileSize,
+		sent:        newSentMessages(maxTrackedMessages),
+		access:      newAccessList(cfg.Access),
+
+		streamResponses: cfg.StreamResponses,
+		streamInterval:  streamInterval,
+		streams:         make(map[string]*streamState),
+
+		typing: make(map[string]*typingState),
+
+		commands: make(map[string]Command),
+
+		groupTriggers:    cfg.GroupTriggers,
+		triggerOverrides: newTriggerStore(cfg.Storage),
+
+		transcriber:   cfg.Transcriber,
+		voiceRequests: make(map[string]time.Time),
+
+		logger: logging.Or(cfg.Logger, "telegram"),
 	}
+	if cfg.VoiceReplies {
+		bot.synthesizer = cfg.Synthesizer
+	}
+	bot.registerBuiltinCommands()
+	bot.registerTriggersCommand()
+
+	return bot
 }
 
 func (b *Bot) Start() error {
 	if !b.enabled {
-		log.Println("Telegram bot is disabled (no token configured)")
+		b.logger.Info("Telegram bot is disabled (no token configured)")
 		return nil
 	}
 
@@ -125,7 +236,10 @@ func (b *Bot) Start() error {
 	b.started = true
 	b.mu.Unlock()
 
-	log.Println("Starting Telegram bot...")
+	b.logger.Info("Starting Telegram bot")
+	if b.access == nil {
+		b.logger.Warn("Telegram bot answers everyone, set allowed users or chats to restrict it")
+	}
 
 	b.wg.Add(1)
 	go b.pollUpdates()
@@ -142,7 +256,7 @@ func (b *Bot) Stop() error {
 	b.started = false
 	b.mu.Unlock()
 
-	log.Println("Stopping Telegram bot...")
+	b.logger.Info("Stopping Telegram bot")
 	b.cancel()
 	b.wg.Wait()
 	return nil
@@ -151,16 +265,28 @@ func (b *Bot) Stop() error {
 func (b *Bot) pollUpdates() {
 	defer b.wg.Done()
 
-	log.Println("Telegram polling task started")
+	b.logger.Debug("Telegram polling task started")
+
+	if self, err := b.GetMe(); err != nil {
+		b.logger.Warn("Failed to look up the bot user, mentions and replies will not trigger answers in groups", "error", err)
+	} else {
+		b.mu.Lock()
+		b.self = self
+		b.mu.Unlock()
+	}
+
+	if err := b.SetMyCommands(); err != nil {
+		b.logger.Warn("Failed to register bot commands", "error", err)
+	}
 
 	for {
 		select {
 		case <-b.ctx.Done():
-			log.Println("Telegram polling task stopped")
+			b.logger.Debug("Telegram polling task stopped")
 			return
 		default:
 			if err := b.getUpdates(); err != nil {
-				log.Printf("Error getting updates: %v", err)
+				b.logger.Error("Error getting updates", "error", err)
 				time.Sleep(defaultPollInterval)
 			}
 		}
@@ -171,8 +297,9 @@ func (b *Bot) getUpdates() error {
 	params := url.Values{}
 	params.Add("offset", strconv.FormatInt(b.updateOffset, 10))
 	params.Add("timeout", strconv.Itoa(defaultPollTimeout))
+	params.Add("allowed_updates", allowedUpdates)
 
-	apiURL := fmt.Sprintf(b.apiURL, b.token, "getUpdates?"+params.Encode())
+	apiURL := b.methodURL("getUpdates?" + params.Encode())
 
 	resp, err := b.httpClient.Get(apiURL)
 	if err != nil {
@@ -214,84 +341,149 @@ func (b *Bot) getUpdates() error {
 		}
 		b.mu.Unlock()
 
-		messageMap, ok := updateMap["message"].(map[string]interface{})
-		if !ok {
+		if callbackMap, ok := updateMap["callback_query"].(map[string]interface{}); ok {
+			var callbackQuery CallbackQuery
+			if err := decodeMap(callbackMap, &callbackQuery); err != nil {
+				b.logger.Warn("Failed to decode callback query", "error", err)
+				continue
+			}
+
+			b.handleUpdate(&Update{
+				UpdateID:      int64(updateID),
+				CallbackQuery: &callbackQuery,
+			})
 			continue
 		}
 
-		text, ok := messageMap["text"].(string)
-		if !ok || text == "" {
+		if reactionMap, ok := updateMap["message_reaction"].(map[string]interface{}); ok {
+			var reaction MessageReactionUpdated
+			if err := decodeMap(reactionMap, &reaction); err != nil {
+				b.logger.Warn("Failed to decode message reaction", "error", err)
+				continue
+			}
+
+			b.handleUpdate(&Update{
+				UpdateID:        int64(updateID),
+				MessageReaction: &reaction,
+			})
 			continue
 		}
 
-		chatMap, ok := messageMap["chat"].(map[string]interface{})
+		messageMap, ok := updateMap["message"].(map[string]interface{})
 		if !ok {
 			continue
 		}
 
-		chatID, ok := chatMap["id"].(float64)
-		if !ok {
+		message, err := decodeMessage(messageMap)
+		if err != nil {
+			b.logger.Warn("Failed to decode message", "error", err)
 			continue
 		}
 
-		chatIDStr := fmt.Sprintf("%.0f", chatID)
-		log.Printf("Message from chat %s: %.40s...", chatIDStr, text)
+		b.handleUpdate(&Update{
+			UpdateID: int64(updateID),
+			Message:  message,
+		})
+	}
+
+	return nil
+}
+
+func (b *Bot) SendMessage(chatID, text string) error {
+	_, err := b.sendText(chatID, text, nil, 0)
+	return err
+}
 
-		msg := &bus.Message{
-			ID:      fmt.Sprintf("telegram-%d-%.0f", time.Now().UnixNano(), updateID),
-			Channel: bus.ChannelTelegram,
-			ChatID:  chatIDStr,
-			Content: text,
+func (b *Bot) SendResponse(msg *bus.Message, text string, keyboard *InlineKeyboardMarkup) error {
+	if keyboard != nil {
+		if err := keyboard.Validate(); err != nil {
+			return fmt.Errorf("invalid keyboard: %w", err)
 		}
+	}
+
+	b.stopTyping(msg.ReplyTo())
 
-		if err := b.messageBus.Publish(b.ctx, bus.ChannelTelegram, msg); err != nil {
-			log.Printf("Failed to publish message to bus: %v", err)
+	if handled, err := b.finishStream(msg, text, keyboard); handled {
+		if err == nil {
+			b.replyWithVoice(msg, text)
 		}
+		return err
 	}
 
-	return nil
+	messageIDs, err := b.sendText(msg.ChatID, text, keyboard, replyTarget(msg))
+	for _, messageID := range messageIDs {
+		b.sent.Track(msg.ChatID, messageID, msg.ID)
+	}
+	if err == nil {
+		b.replyWithVoice(msg, text)
+	}
+
+	return err
 }
 
-func (b *Bot) SendMessage(chatID, text string) error {
+// sendText sends text in as many messages as it takes, the first one
+// quoting replyTo unless it is 0.
+func (b *Bot) sendText(chatID, text string, keyboard *InlineKeyboardMarkup, replyTo int64) ([]int64, error) {
 	if !b.enabled {
-		return fmt.Errorf("telegram bot is disabled")
+		return nil, fmt.Errorf("telegram bot is disabled")
 	}
 
-	textLen := len(text)
-	offset := 0
+	if text == "" {
+		return nil, nil
+	}
 
-	for offset < textLen {
-		chunk := textLen - offset
-		if chunk > maxMessageLength {
-			chunk = maxMessageLength
-		}
+	return b.sendParts(chatID, formatParts(text, maxMessageLength), keyboard, replyTo)
+}
 
-		segment := text[offset : offset+chunk]
+func (b *Bot) sendParts(chatID string, parts []messagePart, keyboard *InlineKeyboardMarkup, replyTo int64) ([]int64, error) {
+	messageIDs := make([]int64, 0, len(parts))
 
+	for i, part := range parts {
 		req := SendMessageRequest{
 			ChatID:    chatID,
-			Text:      segment,
-			ParseMode: "Markdown",
+			Text:      part.formatted,
+			ParseMode: parseModeMarkdownV2,
 		}
 
-		if err := b.sendMessageRequest(req); err != nil {
-			log.Printf("Markdown send failed, retrying plain: %v", err)
+		if i == len(parts)-1 {
+			req.ReplyMarkup = keyboard
+		}
+		if i == 0 && replyTo != 0 {
+			req.ReplyToMessageID = replyTo
+			req.AllowSendingWithoutReply = true
+		}
+
+		messageID, err := b.sendMessageRequest(req)
+		if err != nil {
+			b.logger.Warn("MarkdownV2 send failed, retrying plain", "error", err)
+			req.Text = part.source
 			req.ParseMode = ""
-			if err := b.sendMessageRequest(req); err != nil {
-				return fmt.Errorf("failed to send message: %w", err)
+			if messageID, err = b.sendMessageRequest(req); err != nil {
+				return messageIDs, fmt.Errorf("failed to send message: %w", err)
 			}
 		}
+		messageIDs = append(messageIDs, messageID)
+	}
+
+	return messageIDs, nil
+}
 
-		offset += chunk
+func (b *Bot) sendMessageRequest(req SendMessageRequest) (int64, error) {
+	var sent Message
+	if err := b.callMethodResult("sendMessage", req, &sent); err != nil {
+		return 0, err
 	}
+	return sent.MessageID, nil
+}
 
-	return nil
+func (b *Bot) callMethod(method string, payload interface{}) error {
+	return b.callMethodResult(method, payload, nil)
 }
 
-func (b *Bot) sendMessageRequest(req SendMessageRequest) error {
-	apiURL := fmt.Sprintf(b.apiURL, b.token, "sendMessage")
+func (b *Bot) callMethodResult(method string, payload interface{}, result interface{}) error {
+	apiURL := b.methodURL(method)
 
-	jsonData, err := json.Marshal(req)
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
@@ -314,16 +506,24 @@ func (b *Bot) sendMessageRequest(req SendMessageRequest) error {
 		return fmt.Errorf("API returned not OK")
 	}
 
+	if resultMap, ok := apiResp.Result.(map[string]interface{}); ok && result != nil {
+		return decodeMap(resultMap, result)
+	}
+
 	return nil
 }
 
+func (b *Bot) methodURL(method string) string {
+	return fmt.Sprintf(b.apiURL, method)
+}
+
 func (b *Bot) SetToken(token string) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	b.token = token
 	b.enabled = token != ""
-	log.Printf("Telegram bot token updated (len=%d)", len(token))
+	b.logger.Info("Telegram bot token updated", "length", len(token))
 }
 
 func (b *Bot) IsRunning() bool {
@@ -361,7 +561,7 @@ func (b *Bot) GetUpdates(offset int64) ([]Update, error) {
 	params.Add("offset", strconv.FormatInt(offset, 10))
 	params.Add("timeout", strconv.Itoa(defaultPollTimeout))
 
-	apiURL := fmt.Sprintf(b.apiURL, b.token, "getUpdates?"+params.Encode())
+	apiURL := b.methodURL("getUpdates?" + params.Encode())
 
 	resp, err := b.httpClient.Get(apiURL)
 	if err != nil {
@@ -414,7 +614,7 @@ func (b *Bot) SetWebhook(webhookURL string) error {
 	params := url.Values{}
 	params.Add("url", webhookURL)
 
-	apiURL := fmt.Sprintf(b.apiURL, b.token, "setWebhook?"+params.Encode())
+	apiURL := b.methodURL("setWebhook?" + params.Encode())
 
 	resp, err := b.httpClient.Get(apiURL)
 	if err != nil {
@@ -442,7 +642,7 @@ func (b *Bot) DeleteWebhook() error {
 		return fmt.Errorf("telegram bot is disabled")
 	}
 
-	apiURL := fmt.Sprintf(b.apiURL, b.token, "deleteWebhook")
+	apiURL := b.methodURL("deleteWebhook")
 
 	resp, err := b.httpClient.Get(apiURL)
 	if err != nil {
@@ -470,7 +670,7 @@ func (b *Bot) GetMe() (*User, error) {
 		return nil, fmt.Errorf("telegram bot is disabled")
 	}
 
-	apiURL := fmt.Sprintf(b.apiURL, b.token, "getMe")
+	apiURL := b.methodURL("getMe")
 
 	resp, err := b.httpClient.Get(apiURL)
 	if err != nil {
@@ -513,7 +713,17 @@ func (b *Bot) GetMe() (*User, error) {
 }
 
 func (b *Bot) handleUpdate(update *Update) {
-	if update.Message == nil {
+	if update.CallbackQuery != nil {
+		b.handleCallbackQuery(update.UpdateID, update.CallbackQuery)
+		return
+	}
+
+	if update.MessageReaction != nil {
+		b.handleReaction(update.UpdateID, update.MessageReaction)
+		return
+	}
+
+	if update.Message == nil || update.Message.Chat == nil {
 		return
 	}
 
@@ -521,16 +731,66 @@ func (b *Bot) handleUpdate(update *Update) {
 		return
 	}
 
+	chatID := strconv.FormatInt(update.Message.Chat.ID, 10)
+
+	user, ok := b.access.authorize(update.Message.From, update.Message.Chat.ID)
+	if !ok {
+		b.logger.Warn("Ignoring message from a sender who is not allowed", "chat_id", chatID, "user_id", senderID(update.Message.From))
+		return
+	}
+
+	content := update.Message.Text
+	if content == "" {
+		content = update.Message.Caption
+	}
+
+	content, ok = b.triggered(chatID, update.Message, content)
+	if !ok {
+		b.logger.Debug("Ignoring group message without a trigger", "chat_id", chatID)
+		return
+	}
+
+	if b.runCommand(chatID, update.Message, user) {
+		return
+	}
+
+	attachments := b.downloadAttachments(b.ctx, chatID, update.Message)
+	content, attachments, transcribed := b.transcribeVoice(chatID, content, attachments)
+	if content == "" && len(attachments) == 0 {
+		return
+	}
+
+	b.logger.Info("Message received", "chat_id", chatID, "preview", logging.Preview(content, 40), "attachments", len(attachments))
+
 	msg := &bus.Message{
-		ID:      fmt.Sprintf("telegram-%d", update.UpdateID),
+		ID:      busMessageID(chatID, update.Message.MessageID),
 		Channel: bus.ChannelTelegram,
-		ChatID:  fmt.Sprintf("%.0f", float64(update.Message.Chat.ID)),
-		Content: update.Message.Text,
+		ChatID:  chatID,
+		Content: content,
+	}
+
+	if len(attachments) > 0 || b.streamResponses || user != nil {
+		msg.Metadata = make(map[string]interface{})
+	}
+	if user != nil {
+		msg.Metadata[bus.MetadataUser] = *user
+	}
+	if len(attachments) > 0 {
+		msg.Metadata[bus.MetadataAttachments] = attachments
+	}
+	if b.streamResponses {
+		msg.Metadata[bus.MetadataStreaming] = true
 	}
 
+	if transcribed && b.synthesizer != nil {
+		b.trackVoiceRequest(msg.ID)
+	}
 	if err := b.messageBus.Publish(b.ctx, bus.ChannelTelegram, msg); err != nil {
-		log.Printf("Failed to publish message to bus: %v", err)
+		b.logger.Error("Failed to publish message to bus", "chat_id", chatID, "error", err)
+		b.takeVoiceRequest(msg.ID)
+		return
 	}
+	b.startTyping(msg.ID, chatID)
 }
 
 func (b *Bot) poll() {
@@ -0,0 +1,142 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+// CommandFunc answers a command locally. args are the words after the
+// command and the returned text is sent back to the chat.
+type CommandFunc func(ctx context.Context, chatID string, args []string) (string, error)
+
+// Command is a slash command the bot answers itself instead of passing it
+// to the agent.
+type Command struct {
+	Name        string
+	Description string
+	Handler     CommandFunc
+	// AdminOnly refuses the command to senders who are not admins.
+	AdminOnly bool
+}
+
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+type SetMyCommandsRequest struct {
+	Commands []BotCommand `json:"commands"`
+}
+
+// RegisterCommand adds or replaces a command. Commands registered after the
+// bot started show up in the Telegram menu on the next start.
+func (b *Bot) RegisterCommand(cmd Command) {
+	b.commandsMu.Lock()
+	defer b.commandsMu.Unlock()
+	b.commands[strings.ToLower(cmd.Name)] = cmd
+}
+
+func (b *Bot) listCommands() []Command {
+	b.commandsMu.RLock()
+	defer b.commandsMu.RUnlock()
+
+	commands := make([]Command, 0, len(b.commands))
+	for _, cmd := range b.commands {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// SetMyCommands publishes the registered commands as the bot's command menu.
+func (b *Bot) SetMyCommands() error {
+	if !b.enabled {
+		return fmt.Errorf("telegram bot is disabled")
+	}
+
+	commands := b.listCommands()
+	req := SetMyCommandsRequest{Commands: make([]BotCommand, 0, len(commands))}
+	for _, cmd := range commands {
+		req.Commands = append(req.Commands, BotCommand{Command: cmd.Name, Description: cmd.Description})
+	}
+
+	if err := b.callMethod("setMyCommands", req); err != nil {
+		return fmt.Errorf("failed to set commands: %w", err)
+	}
+	return nil
+}
+
+func (b *Bot) registerBuiltinCommands() {
+	b.RegisterCommand(Command{
+		Name:        "start",
+		Description: "Start talking to the assistant",
+		Handler: func(ctx context.Context, chatID string, args []string) (string, error) {
+			return "Hi! Send me a message and I'll do my best to help.\n\n" + b.commandHelp(), nil
+		},
+	})
+
+	b.RegisterCommand(Command{
+		Name:        "help",
+		Description: "List the available commands",
+		Handler: func(ctx context.Context, chatID string, args []string) (string, error) {
+			return b.commandHelp(), nil
+		},
+	})
+}
+
+func (b *Bot) commandHelp() string {
+	var builder strings.Builder
+	builder.WriteString("**Commands**")
+	for _, cmd := range b.listCommands() {
+		fmt.Fprintf(&builder, "\n/%s - %s", cmd.Name, cmd.Description)
+	}
+	return builder.String()
+}
+
+// parseCommand splits "/name@bot args" into the lower-cased name and its
+// arguments. ok is false when text is not a command.
+func parseCommand(text string) (string, []string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") || len(fields[0]) == 1 {
+		return "", nil, false
+	}
+
+	name, _, _ := strings.Cut(fields[0][1:], "@")
+	return strings.ToLower(name), fields[1:], true
+}
+
+// runCommand answers message if it is a registered command and reports
+// whether it did. Unknown commands are left for the agent.
+func (b *Bot) runCommand(chatID string, message *Message, user *bus.User) bool {
+	name, args, ok := parseCommand(message.Text)
+	if !ok {
+		return false
+	}
+
+	b.commandsMu.RLock()
+	cmd, ok := b.commands[name]
+	b.commandsMu.RUnlock()
+	if !ok || cmd.Handler == nil {
+		return false
+	}
+
+	b.logger.Info("Command received", "chat_id", chatID, "command", name)
+
+	var reply string
+	var err error
+	if cmd.AdminOnly && (user == nil || !user.Admin) {
+		reply = fmt.Sprintf("/%s is only available to admins.", name)
+	} else if reply, err = cmd.Handler(b.ctx, chatID, args); err != nil {
+		b.logger.Warn("Command failed", "chat_id", chatID, "command", name, "error", err)
+		reply = fmt.Sprintf("/%s failed: %v", name, err)
+	}
+
+	if _, err := b.sendText(chatID, reply, nil, message.MessageID); err != nil {
+		b.logger.Error("Failed to answer command", "chat_id", chatID, "command", name, "error", err)
+	}
+	return true
+}
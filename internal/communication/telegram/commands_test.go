@@ -0,0 +1,132 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestParseCommand(t *testing.T) {
+	name, args, ok := parseCommand("/Model@miniclaw_bot gpt-4o  fast")
+	if !ok || name != "model" || len(args) != 2 || args[0] != "gpt-4o" {
+		t.Errorf("Unexpected parse: %q %v %v", name, args, ok)
+	}
+
+	for _, text := range []string{"hello", "/", " ", "a /clear"} {
+		if _, _, ok := parseCommand(text); ok {
+			t.Errorf("Expected %q not to be a command", text)
+		}
+	}
+}
+
+func TestCommandsAnsweredLocally(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+
+	received := make(chan *bus.Message, 1)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	server, calls := newRecordingServer(t)
+	bot := NewBot(&Config{Token: "test-token"}, messageBus, ctx)
+	bot.apiURL = server.URL + "/bot/%s"
+
+	var cleared string
+	bot.RegisterCommand(Command{
+		Name:        "clear",
+		Description: "Clear the conversation history",
+		Handler: func(ctx context.Context, chatID string, args []string) (string, error) {
+			cleared = chatID
+			return "Conversation cleared.", nil
+		},
+	})
+
+	bot.handleUpdate(&Update{UpdateID: 1, Message: &Message{MessageID: 5, Chat: &Chat{ID: 7}, Text: "/clear"}})
+	if cleared != "7" {
+		t.Errorf("Expected /clear to run for chat 7, got %q", cleared)
+	}
+	recorded := calls()
+	if len(recorded) != 1 || recorded[0].method != "sendMessage" || recorded[0].payload["text"] != `Conversation cleared\.` {
+		t.Fatalf("Expected the command reply, got %+v", recorded)
+	}
+
+	bot.handleUpdate(&Update{UpdateID: 2, Message: &Message{MessageID: 6, Chat: &Chat{ID: 7}, Text: "/help"}})
+	if help := calls()[1].payload["text"].(string); !strings.Contains(help, "/clear \\- Clear") || !strings.Contains(help, "/start") {
+		t.Errorf("Expected help to list the commands, got %q", help)
+	}
+
+	select {
+	case msg := <-received:
+		t.Fatalf("Expected commands not to reach the agent, got %q", msg.Content)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	bot.handleUpdate(&Update{UpdateID: 3, Message: &Message{MessageID: 7, Chat: &Chat{ID: 7}, Text: "/prefs"}})
+	select {
+	case msg := <-received:
+		if msg.Content != "/prefs" {
+			t.Errorf("Unexpected content: %q", msg.Content)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected unknown commands to be passed to the agent")
+	}
+}
+
+func TestAdminOnlyCommand(t *testing.T) {
+	server, calls := newRecordingServer(t)
+	bot := NewBot(&Config{Token: "test-token", Access: AccessConfig{AllowedChats: []int64{7}, Admins: []int64{1}}}, nil, context.Background())
+	bot.apiURL = server.URL + "/bot/%s"
+
+	ran := false
+	bot.RegisterCommand(Command{
+		Name:      "model",
+		AdminOnly: true,
+		Handler: func(ctx context.Context, chatID string, args []string) (string, error) {
+			ran = true
+			return "ok", nil
+		},
+	})
+
+	message := &Message{MessageID: 5, From: &User{ID: 2}, Chat: &Chat{ID: 7}, Text: "/model"}
+	user, _ := bot.access.authorize(message.From, 7)
+	if !bot.runCommand("7", message, user) || ran {
+		t.Fatal("Expected the command to be refused to a non-admin")
+	}
+	if text := calls()[0].payload["text"].(string); !strings.Contains(text, "only available to admins") {
+		t.Errorf("Unexpected reply: %q", text)
+	}
+
+	message.From = &User{ID: 1}
+	user, _ = bot.access.authorize(message.From, 7)
+	if bot.runCommand("7", message, user); !ran {
+		t.Error("Expected the command to run for an admin")
+	}
+}
+
+func TestSetMyCommands(t *testing.T) {
+	server, calls := newRecordingServer(t)
+	bot := NewBot(&Config{Token: "test-token"}, nil, context.Background())
+	bot.apiURL = server.URL + "/bot/%s"
+	bot.RegisterCommand(Command{Name: "tasks", Description: "List tasks"})
+
+	if err := bot.SetMyCommands(); err != nil {
+		t.Fatalf("Failed to set commands: %v", err)
+	}
+
+	recorded := calls()
+	commands, _ := recorded[0].payload["commands"].([]interface{})
+	if recorded[0].method != "setMyCommands" || len(commands) != 4 {
+		t.Fatalf("Expected the built-in commands and tasks to be registered, got %+v", recorded)
+	}
+	if first := commands[0].(map[string]interface{}); first["command"] != "help" {
+		t.Errorf("Expected commands sorted by name, got %v", commands)
+	}
+}
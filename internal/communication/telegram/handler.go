@@ -2,9 +2,11 @@ package telegram
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"strings"
 
 	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 )
 
 type Handler struct {
@@ -22,12 +24,40 @@ func (h *Handler) HandleMessage(ctx context.Context, msg *bus.Message) error {
 		return nil
 	}
 
-	log.Printf("Sending message to Telegram chat %s: %.40s...", msg.ChatID, msg.Content)
+	if msg.IsPartial() {
+		return h.bot.UpdateStream(msg)
+	}
+
+	if msg.IsControl() {
+		return nil
+	}
+
+	h.bot.logger.Debug("Sending message", "chat_id", msg.ChatID, "preview", logging.Preview(msg.Content, 40))
+
+	content := msg.Content
+	if toolUses := msg.ToolUses(); len(toolUses) > 0 {
+		content += formatToolUses(toolUses)
+	}
+
+	var keyboard *InlineKeyboardMarkup
+	if buttons := msg.Buttons(); len(buttons) > 0 {
+		keyboard = NewInlineKeyboard(buttons)
+	}
 
-	if err := h.bot.SendMessage(msg.ChatID, msg.Content); err != nil {
-		log.Printf("Failed to send message to Telegram: %v", err)
+	if err := h.bot.SendResponse(msg, content, keyboard); err != nil {
+		h.bot.logger.Error("Failed to send message", "chat_id", msg.ChatID, "error", err)
 		return err
 	}
 
 	return nil
 }
+
+func formatToolUses(toolUses []bus.ToolUse) string {
+	var builder strings.Builder
+	builder.WriteString("\n\n**Tools used**\n```\n")
+	for i, toolUse := range toolUses {
+		fmt.Fprintf(&builder, "%d. %s\n", i+1, strings.ReplaceAll(toolUse.Summary(), "```", "'''"))
+	}
+	builder.WriteString("```")
+	return builder.String()
+}
@@ -0,0 +1,166 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+const maxCallbackDataLength = 64
+
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    *User    `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+type AnswerCallbackQueryRequest struct {
+	CallbackQueryID string `json:"callback_query_id"`
+	Text            string `json:"text,omitempty"`
+	ShowAlert       bool   `json:"show_alert,omitempty"`
+}
+
+func NewInlineKeyboard(rows [][]bus.Button) *InlineKeyboardMarkup {
+	keyboard := &InlineKeyboardMarkup{
+		InlineKeyboard: make([][]InlineKeyboardButton, 0, len(rows)),
+	}
+
+	for _, row := range rows {
+		buttons := make([]InlineKeyboardButton, 0, len(row))
+		for _, button := range row {
+			data := button.Data
+			if data == "" {
+				data = button.Text
+			}
+			buttons = append(buttons, InlineKeyboardButton{
+				Text:         button.Text,
+				CallbackData: data,
+			})
+		}
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, buttons)
+	}
+
+	return keyboard
+}
+
+func (k *InlineKeyboardMarkup) Validate() error {
+	if len(k.InlineKeyboard) == 0 {
+		return fmt.Errorf("keyboard has no buttons")
+	}
+
+	for _, row := range k.InlineKeyboard {
+		for _, button := range row {
+			if button.Text == "" {
+				return fmt.Errorf("button text cannot be empty")
+			}
+			if button.CallbackData == "" && button.URL == "" {
+				return fmt.Errorf("button %q needs callback data or a URL", button.Text)
+			}
+			if len(button.CallbackData) > maxCallbackDataLength {
+				return fmt.Errorf("callback data for button %q exceeds %d bytes", button.Text, maxCallbackDataLength)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *Bot) SendMessageWithKeyboard(chatID, text string, keyboard *InlineKeyboardMarkup) error {
+	if keyboard == nil {
+		return b.SendMessage(chatID, text)
+	}
+
+	if err := keyboard.Validate(); err != nil {
+		return fmt.Errorf("invalid keyboard: %w", err)
+	}
+
+	_, err := b.sendText(chatID, text, keyboard, 0)
+	return err
+}
+
+func (b *Bot) AnswerCallbackQuery(callbackQueryID, text string) error {
+	if !b.enabled {
+		return fmt.Errorf("telegram bot is disabled")
+	}
+
+	req := AnswerCallbackQueryRequest{
+		CallbackQueryID: callbackQueryID,
+		Text:            text,
+	}
+
+	if err := b.callMethod("answerCallbackQuery", req); err != nil {
+		return fmt.Errorf("failed to answer callback query: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Bot) handleCallbackQuery(updateID int64, query *CallbackQuery) {
+	if err := b.AnswerCallbackQuery(query.ID, ""); err != nil {
+		b.logger.Warn("Failed to answer callback query", "query_id", query.ID, "error", err)
+	}
+
+	if b.messageBus == nil {
+		return
+	}
+
+	var chat int64
+	var messageID string
+	if query.Message != nil && query.Message.Chat != nil {
+		chat = query.Message.Chat.ID
+		messageID = strconv.FormatInt(query.Message.MessageID, 10)
+	} else if query.From != nil {
+		chat = query.From.ID
+	} else {
+		return
+	}
+	chatID := strconv.FormatInt(chat, 10)
+
+	user, ok := b.access.authorize(query.From, chat)
+	if !ok {
+		b.logger.Warn("Ignoring callback from a sender who is not allowed", "chat_id", chatID, "user_id", senderID(query.From))
+		return
+	}
+
+	callback := &bus.Callback{
+		ID:        query.ID,
+		Data:      query.Data,
+		MessageID: messageID,
+	}
+	if query.From != nil {
+		callback.UserID = strconv.FormatInt(query.From.ID, 10)
+	}
+
+	b.logger.Info("Callback received", "chat_id", chatID, "data", logging.Preview(query.Data, 40))
+
+	msg := &bus.Message{
+		ID:      fmt.Sprintf("telegram-%d-%d", time.Now().UnixNano(), updateID),
+		Channel: bus.ChannelTelegram,
+		ChatID:  chatID,
+		Content: query.Data,
+		Metadata: map[string]interface{}{
+			bus.MetadataCallback: callback,
+		},
+	}
+	if user != nil {
+		msg.Metadata[bus.MetadataUser] = *user
+	}
+
+	if err := b.messageBus.Publish(b.ctx, bus.ChannelTelegram, msg); err != nil {
+		b.logger.Error("Failed to publish callback to bus", "chat_id", chatID, "error", err)
+	}
+}
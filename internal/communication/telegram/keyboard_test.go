@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestSendMessageWithKeyboard(t *testing.T) {
+	requests := make(chan SendMessageRequest, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot/sendMessage" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+
+		var req SendMessageRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests <- req
+		fmt.Fprint(w, `{"ok":true,"result":{}}`)
+	}))
+	defer server.Close()
+
+	bot := NewBot(&Config{Token: "test-token"}, nil, context.Background())
+	bot.apiURL = server.URL + "/bot/%s"
+
+	keyboard := NewInlineKeyboard([][]bus.Button{
+		{{Text: "Yes", Data: "task:run"}, {Text: "No"}},
+	})
+
+	if err := bot.SendMessageWithKeyboard("123", "Run this task?", keyboard); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	req := <-requests
+	if req.ReplyMarkup == nil || len(req.ReplyMarkup.InlineKeyboard) != 1 {
+		t.Fatalf("Expected inline keyboard, got %+v", req.ReplyMarkup)
+	}
+
+	row := req.ReplyMarkup.InlineKeyboard[0]
+	if row[0].CallbackData != "task:run" || row[1].CallbackData != "No" {
+		t.Errorf("Unexpected callback data: %+v", row)
+	}
+}
+
+func TestInlineKeyboardValidate(t *testing.T) {
+	keyboard := NewInlineKeyboard([][]bus.Button{
+		{{Text: "Too long", Data: strings.Repeat("x", maxCallbackDataLength+1)}},
+	})
+	if err := keyboard.Validate(); err == nil {
+		t.Error("Expected error for oversized callback data")
+	}
+
+	if err := (&InlineKeyboardMarkup{}).Validate(); err == nil {
+		t.Error("Expected error for empty keyboard")
+	}
+}
+
+func TestBotHandleCallbackQuery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	answered := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req AnswerCallbackQueryRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		answered <- req.CallbackQueryID
+		fmt.Fprint(w, `{"ok":true,"result":true}`)
+	}))
+	defer server.Close()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *bus.Message, 1)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	bot := NewBot(&Config{Token: "test-token"}, messageBus, ctx)
+	bot.apiURL = server.URL + "/bot/%s"
+
+	bot.handleUpdate(&Update{
+		UpdateID: 3,
+		CallbackQuery: &CallbackQuery{
+			ID:      "cb-1",
+			From:    &User{ID: 42},
+			Message: &Message{MessageID: 9, Chat: &Chat{ID: 123456, Type: "private"}},
+			Data:    "task:run",
+		},
+	})
+
+	if id := <-answered; id != "cb-1" {
+		t.Errorf("Expected callback query to be answered, got %s", id)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.ChatID != "123456" || msg.Content != "task:run" {
+			t.Errorf("Unexpected message: %+v", msg)
+		}
+
+		callback := msg.Callback()
+		if callback == nil || callback.MessageID != "9" || callback.UserID != "42" {
+			t.Errorf("Unexpected callback metadata: %+v", callback)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected callback to be published")
+	}
+}
@@ -0,0 +1,289 @@
+package telegram
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const parseModeMarkdownV2 = "MarkdownV2"
+
+// markdownV2Special lists the characters MarkdownV2 rejects unless they are
+// escaped, outside of code.
+const markdownV2Special = "_*[]()~`>#+-=|{}.!\\"
+
+// messagePart is one message worth of a reply: the Markdown the LLM wrote and
+// the same text converted to MarkdownV2.
+type messagePart struct {
+	source    string
+	formatted string
+}
+
+// formatParts splits text at block boundaries and converts every part to
+// MarkdownV2. The limit is on the source text: Telegram counts characters
+// after parsing entities, so escapes and markers do not add to the length.
+func formatParts(text string, limit int) []messagePart {
+	sources := splitMarkdown(text, limit)
+	parts := make([]messagePart, 0, len(sources))
+	for _, source := range sources {
+		parts = append(parts, messagePart{source: source, formatted: formatMarkdownV2(source)})
+	}
+	return parts
+}
+
+// splitMarkdown packs paragraphs and whole code blocks into parts of at most
+// limit characters. Only a single block that is too long on its own is cut,
+// and then bus.SplitText closes and reopens any code block it cuts through.
+func splitMarkdown(text string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var parts []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, block := range markdownBlocks(text) {
+		size := utf8.RuneCountInString(block)
+		if size > limit {
+			flush()
+			parts = append(parts, bus.SplitText(block, limit)...)
+			continue
+		}
+
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+2+size > limit {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(block)
+	}
+	flush()
+
+	return parts
+}
+
+// markdownBlocks splits text on blank lines that are not inside a code block.
+func markdownBlocks(text string) []string {
+	var blocks []string
+	var lines []string
+	inCode := false
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		if !inCode && strings.TrimSpace(line) == "" {
+			if len(lines) > 0 {
+				blocks = append(blocks, strings.Join(lines, "\n"))
+				lines = nil
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > 0 {
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+
+	return blocks
+}
+
+// formatMarkdownV2 converts the Markdown LLMs usually write into Telegram's
+// MarkdownV2. Anything it does not recognize is escaped and shown literally,
+// so the result is always accepted by the API.
+func formatMarkdownV2(text string) string {
+	var builder strings.Builder
+	inCode := false
+
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				builder.WriteString("```")
+			} else {
+				builder.WriteString("```" + codeLanguage(strings.TrimPrefix(trimmed, "```")))
+			}
+			inCode = !inCode
+			continue
+		}
+
+		if inCode {
+			builder.WriteString(escapeCode(line))
+			continue
+		}
+
+		builder.WriteString(formatLine(line))
+	}
+
+	if inCode {
+		builder.WriteString("\n```")
+	}
+
+	return builder.String()
+}
+
+func formatLine(line string) string {
+	trimmed := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(trimmed)]
+
+	if heading := strings.TrimLeft(trimmed, "#"); len(heading) < len(trimmed) && len(trimmed)-len(heading) <= 6 && strings.HasPrefix(heading, " ") {
+		heading = strings.ReplaceAll(strings.TrimSpace(heading), "**", "")
+		if heading == "" {
+			return ""
+		}
+		return "*" + formatInline(heading) + "*"
+	}
+
+	if rest, ok := strings.CutPrefix(trimmed, "> "); ok {
+		return ">" + formatInline(rest)
+	}
+
+	for _, bullet := range []string{"- ", "* ", "+ "} {
+		if rest, ok := strings.CutPrefix(trimmed, bullet); ok {
+			return indent + "• " + formatInline(rest)
+		}
+	}
+
+	return indent + formatInline(trimmed)
+}
+
+// formatInline converts code spans, bold, italics, strikethrough and links.
+// Markers without a partner are escaped.
+func formatInline(text string) string {
+	var builder strings.Builder
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune(markdownV2Special, rune(rest[1])):
+			builder.WriteString("\\" + rest[1:2])
+			i += 2
+			continue
+
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end > 0 {
+				builder.WriteString("`" + escapeCode(rest[1:1+end]) + "`")
+				i += end + 2
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if inner, ok := delimited(rest, rest[:2]); ok {
+				builder.WriteString("*" + formatInline(inner) + "*")
+				i += len(inner) + 4
+				continue
+			}
+
+		case strings.HasPrefix(rest, "~~"):
+			if inner, ok := delimited(rest, "~~"); ok {
+				builder.WriteString("~" + formatInline(inner) + "~")
+				i += len(inner) + 4
+				continue
+			}
+
+		case rest[0] == '*' || rest[0] == '_':
+			if rest[0] == '_' && i > 0 && isWordByte(text[i-1]) {
+				break
+			}
+			if inner, ok := delimited(rest, rest[:1]); ok {
+				end := i + len(inner) + 2
+				if rest[0] == '_' && end < len(text) && isWordByte(text[end]) {
+					break
+				}
+				builder.WriteString("_" + formatInline(inner) + "_")
+				i = end
+				continue
+			}
+
+		case rest[0] == '[':
+			if label, target, ok := markdownLink(rest); ok {
+				builder.WriteString("[" + formatInline(label) + "](" + escapeLinkTarget(target) + ")")
+				i += len(label) + len(target) + 4
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		if strings.ContainsRune(markdownV2Special, r) {
+			builder.WriteByte('\\')
+		}
+		builder.WriteRune(r)
+		i += size
+	}
+
+	return builder.String()
+}
+
+// delimited returns the text between marker at the start of s and its next
+// occurrence, provided neither end touches whitespace.
+func delimited(s, marker string) (string, bool) {
+	body := s[len(marker):]
+	end := strings.Index(body, marker)
+	if end <= 0 {
+		return "", false
+	}
+
+	inner := body[:end]
+	first, _ := utf8.DecodeRuneInString(inner)
+	last, _ := utf8.DecodeLastRuneInString(inner)
+	if unicode.IsSpace(first) || unicode.IsSpace(last) {
+		return "", false
+	}
+	if len(marker) == 1 && strings.HasPrefix(body[end:], marker+marker) {
+		return "", false
+	}
+
+	return inner, true
+}
+
+// markdownLink parses [label](target) at the start of s.
+func markdownLink(s string) (string, string, bool) {
+	closeLabel := strings.Index(s, "](")
+	if closeLabel <= 1 || strings.ContainsAny(s[1:closeLabel], "[]\n") {
+		return "", "", false
+	}
+
+	rest := s[closeLabel+2:]
+	closeTarget := strings.IndexByte(rest, ')')
+	if closeTarget <= 0 || strings.ContainsAny(rest[:closeTarget], " \n") {
+		return "", "", false
+	}
+
+	return s[1:closeLabel], rest[:closeTarget], true
+}
+
+func codeLanguage(info string) string {
+	info = strings.TrimSpace(info)
+	for i, r := range info {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#' && r != '-' {
+			return info[:i]
+		}
+	}
+	return info
+}
+
+func escapeCode(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(text)
+}
+
+func escapeLinkTarget(target string) string {
+	return strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(target)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
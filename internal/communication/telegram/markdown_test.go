@@ -0,0 +1,75 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFormatMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain punctuation", "Done. Cost: $1.50 (approx)!", `Done\. Cost: $1\.50 \(approx\)\!`},
+		{"bold", "This is **important**.", `This is *important*\.`},
+		{"italic", "An *aside* and _another_", `An _aside_ and _another_`},
+		{"snake case", "call my_func_name now", `call my\_func\_name now`},
+		{"strikethrough", "~~old~~ new", `~old~ new`},
+		{"inline code", "run `ls -la | grep *.go`", "run `ls -la | grep *.go`"},
+		{"link", "see [the docs](https://example.com/a_b).", `see [the docs](https://example.com/a_b)\.`},
+		{"heading", "## **Summary**", `*Summary*`},
+		{"bullets", "- one\n  * two", "• one\n  • two"},
+		{"quote", "> quoted.", `>quoted\.`},
+		{"numbered", "1. first", `1\. first`},
+		{"unpaired marker", "2 * 3 = 6", `2 \* 3 \= 6`},
+		{"escaped marker", `a \* b`, `a \* b`},
+		{
+			"code block",
+			"Try:\n```python\nprint(\"a_b\") # `x`\n```\nok.",
+			"Try:\n```python\nprint(\"a_b\") # \\`x\\`\n```\nok\\.",
+		},
+		{"unclosed code block", "```\nx = 1", "```\nx = 1\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatMarkdownV2(tt.in); got != tt.want {
+				t.Errorf("formatMarkdownV2(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMarkdownKeepsCodeBlocksWhole(t *testing.T) {
+	code := "```go\n" + strings.Repeat("fmt.Println(1)\n\n", 17) + "```"
+	text := strings.Repeat("word ", 50) + "\n\n" + code + "\n\n" + strings.Repeat("after ", 10)
+
+	parts := splitMarkdown(text, 300)
+	if len(parts) != 3 {
+		t.Fatalf("Expected 3 parts, got %d: %q", len(parts), parts)
+	}
+	if parts[1] != code {
+		t.Errorf("Expected the code block as its own part, got %q", parts[1])
+	}
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) > 300 {
+			t.Errorf("Part exceeds limit: %d", utf8.RuneCountInString(part))
+		}
+	}
+}
+
+func TestSplitMarkdownCutsOversizedCodeBlock(t *testing.T) {
+	text := "```\n" + strings.Repeat("line of code\n", 100) + "```"
+
+	parts := splitMarkdown(text, 300)
+	if len(parts) < 2 {
+		t.Fatalf("Expected the block to be cut, got %d parts", len(parts))
+	}
+	for _, part := range parts {
+		if strings.Count(part, "```")%2 != 0 {
+			t.Errorf("Expected every part to have balanced fences, got %q", part)
+		}
+	}
+}
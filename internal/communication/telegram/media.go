@@ -0,0 +1,252 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const (
+	defaultUploadDir   = "uploads/telegram"
+	defaultMaxFileSize = 20 * 1024 * 1024
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+type PhotoSize struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+type Voice struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Duration     int    `json:"duration"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+type Document struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+type File struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileSize     int64  `json:"file_size,omitempty"`
+	FilePath     string `json:"file_path,omitempty"`
+}
+
+func decodeMessage(messageMap map[string]interface{}) (*Message, error) {
+	var message Message
+	if err := decodeMap(messageMap, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func decodeMap(source map[string]interface{}, target interface{}) error {
+	data, err := json.Marshal(source)
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to unmarshal update: %w", err)
+	}
+
+	return nil
+}
+
+func messageAttachments(message *Message) []bus.Attachment {
+	attachments := make([]bus.Attachment, 0)
+
+	if len(message.Photo) > 0 {
+		largest := message.Photo[0]
+		for _, photo := range message.Photo[1:] {
+			if photo.Width*photo.Height > largest.Width*largest.Height {
+				largest = photo
+			}
+		}
+
+		attachments = append(attachments, bus.Attachment{
+			Type:     bus.AttachmentPhoto,
+			FileID:   largest.FileID,
+			FileName: fmt.Sprintf("photo_%s.jpg", largest.FileUniqueID),
+			MimeType: "image/jpeg",
+			Size:     largest.FileSize,
+		})
+	}
+
+	if message.Voice != nil {
+		mimeType := message.Voice.MimeType
+		if mimeType == "" {
+			mimeType = "audio/ogg"
+		}
+
+		attachments = append(attachments, bus.Attachment{
+			Type:     bus.AttachmentVoice,
+			FileID:   message.Voice.FileID,
+			FileName: fmt.Sprintf("voice_%s.ogg", message.Voice.FileUniqueID),
+			MimeType: mimeType,
+			Size:     message.Voice.FileSize,
+		})
+	}
+
+	if message.Document != nil {
+		fileName := message.Document.FileName
+		if fileName == "" {
+			fileName = "document_" + message.Document.FileUniqueID
+		}
+
+		attachments = append(attachments, bus.Attachment{
+			Type:     bus.AttachmentDocument,
+			FileID:   message.Document.FileID,
+			FileName: fileName,
+			MimeType: message.Document.MimeType,
+			Size:     message.Document.FileSize,
+		})
+	}
+
+	return attachments
+}
+
+func (b *Bot) downloadAttachments(ctx context.Context, chatID string, message *Message) []bus.Attachment {
+	attachments := messageAttachments(message)
+
+	for i := range attachments {
+		attachment := &attachments[i]
+
+		if b.storage == nil {
+			attachment.Error = "file storage is not configured"
+			continue
+		}
+
+		if attachment.Size > b.maxFileSize {
+			attachment.Error = fmt.Sprintf("file exceeds maximum size of %d bytes", b.maxFileSize)
+			continue
+		}
+
+		data, err := b.DownloadFile(ctx, attachment.FileID)
+		if err != nil {
+			b.logger.Warn("Failed to download attachment", "chat_id", chatID, "type", attachment.Type, "error", err)
+			attachment.Error = err.Error()
+			continue
+		}
+
+		filePath := path.Join(b.uploadDir, chatID, fmt.Sprintf("%d_%s", message.MessageID, sanitizeFileName(attachment.FileName)))
+		if err := b.storage.WriteFile(ctx, filePath, data); err != nil {
+			b.logger.Warn("Failed to store attachment", "chat_id", chatID, "type", attachment.Type, "error", err)
+			attachment.Error = fmt.Sprintf("failed to store file: %v", err)
+			continue
+		}
+
+		attachment.Path = filePath
+		attachment.Size = int64(len(data))
+		b.logger.Info("Stored attachment", "chat_id", chatID, "type", attachment.Type, "path", filePath, "bytes", len(data))
+	}
+
+	return attachments
+}
+
+func (b *Bot) GetFile(ctx context.Context, fileID string) (*File, error) {
+	params := url.Values{}
+	params.Add("file_id", fileID)
+
+	apiURL := b.methodURL("getFile?" + params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		OK          bool   `json:"ok"`
+		Result      *File  `json:"result,omitempty"`
+		Description string `json:"description,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.OK || apiResp.Result == nil {
+		if apiResp.Description != "" {
+			return nil, fmt.Errorf("API error: %s", apiResp.Description)
+		}
+		return nil, fmt.Errorf("API returned not OK")
+	}
+
+	return apiResp.Result, nil
+}
+
+func (b *Bot) DownloadFile(ctx context.Context, fileID string) ([]byte, error) {
+	file, err := b.GetFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.FilePath == "" {
+		return nil, fmt.Errorf("file path not available")
+	}
+
+	if file.FileSize > b.maxFileSize {
+		return nil, fmt.Errorf("file exceeds maximum size of %d bytes", b.maxFileSize)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(b.fileURL, file.FilePath), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, b.maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if int64(len(data)) > b.maxFileSize {
+		return nil, fmt.Errorf("file exceeds maximum size of %d bytes", b.maxFileSize)
+	}
+
+	return data, nil
+}
+
+func sanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = unsafeFileNameChars.ReplaceAllString(name, "_")
+	name = strings.Trim(name, "._")
+	if name == "" {
+		return "file"
+	}
+	return name
+}
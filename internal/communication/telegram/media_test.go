@@ -0,0 +1,183 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func newMediaTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bot/getFile", func(w http.ResponseWriter, r *http.Request) {
+		fileID := r.URL.Query().Get("file_id")
+		if fileID == "missing" {
+			fmt.Fprint(w, `{"ok":false,"description":"Bad Request: invalid file_id"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"ok":true,"result":{"file_id":%q,"file_size":5,"file_path":"documents/%s.txt"}}`, fileID, fileID)
+	})
+	mux.HandleFunc("/file/documents/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBotHandleUpdateDocument(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := newMediaTestServer(t)
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *bus.Message, 1)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	fileStorage := storage.NewFileStorage(t.TempDir())
+
+	bot := NewBot(&Config{Token: "test-token", Storage: fileStorage}, messageBus, ctx)
+	bot.apiURL = server.URL + "/bot/%s"
+	bot.fileURL = server.URL + "/file/%s"
+
+	bot.handleUpdate(&Update{
+		UpdateID: 1,
+		Message: &Message{
+			MessageID: 42,
+			Chat:      &Chat{ID: 123456, Type: "private"},
+			Caption:   "please summarize",
+			Document: &Document{
+				FileID:       "doc1",
+				FileUniqueID: "unique1",
+				FileName:     "../notes file.txt",
+				MimeType:     "text/plain",
+				FileSize:     5,
+			},
+		},
+	})
+
+	var msg *bus.Message
+	select {
+	case msg = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected message to be published")
+	}
+
+	if msg.Content != "please summarize" {
+		t.Errorf("Expected caption as content, got %q", msg.Content)
+	}
+
+	attachments := msg.Attachments()
+	if len(attachments) != 1 {
+		t.Fatalf("Expected 1 attachment, got %d", len(attachments))
+	}
+
+	attachment := attachments[0]
+	if attachment.Type != bus.AttachmentDocument || attachment.Error != "" {
+		t.Errorf("Unexpected attachment: %+v", attachment)
+	}
+
+	expectedPath := "uploads/telegram/123456/42_notes_file.txt"
+	if attachment.Path != expectedPath {
+		t.Errorf("Expected path %s, got %s", expectedPath, attachment.Path)
+	}
+
+	data, err := fileStorage.ReadFile(ctx, expectedPath)
+	if err != nil {
+		t.Fatalf("Expected file to be stored: %v", err)
+	}
+
+	if string(data) != "hello" {
+		t.Errorf("Expected stored content 'hello', got %q", string(data))
+	}
+}
+
+func TestBotHandleUpdateDownloadError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := newMediaTestServer(t)
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *bus.Message, 1)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	bot := NewBot(&Config{Token: "test-token", Storage: storage.NewFileStorage(t.TempDir())}, messageBus, ctx)
+	bot.apiURL = server.URL + "/bot/%s"
+	bot.fileURL = server.URL + "/file/%s"
+
+	bot.handleUpdate(&Update{
+		UpdateID: 2,
+		Message: &Message{
+			MessageID: 7,
+			Chat:      &Chat{ID: 1, Type: "private"},
+			Voice:     &Voice{FileID: "missing", FileUniqueID: "v1", Duration: 3},
+		},
+	})
+
+	select {
+	case msg := <-received:
+		attachments := msg.Attachments()
+		if len(attachments) != 1 || attachments[0].Path != "" || attachments[0].Error == "" {
+			t.Errorf("Expected failed attachment, got %+v", attachments)
+		}
+		if attachments[0].MimeType != "audio/ogg" {
+			t.Errorf("Expected default voice mime type, got %s", attachments[0].MimeType)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected message to be published")
+	}
+}
+
+func TestMessageAttachmentsLargestPhoto(t *testing.T) {
+	attachments := messageAttachments(&Message{
+		Photo: []PhotoSize{
+			{FileID: "small", FileUniqueID: "s", Width: 90, Height: 90},
+			{FileID: "large", FileUniqueID: "l", Width: 1280, Height: 960},
+			{FileID: "medium", FileUniqueID: "m", Width: 320, Height: 240},
+		},
+	})
+
+	if len(attachments) != 1 {
+		t.Fatalf("Expected 1 attachment, got %d", len(attachments))
+	}
+
+	if attachments[0].FileID != "large" {
+		t.Errorf("Expected largest photo, got %s", attachments[0].FileID)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := map[string]string{
+		"report.pdf":         "report.pdf",
+		"../../etc/passwd":   "passwd",
+		"my file (1).docx":   "my_file_1_.docx",
+		"..\\windows\\x.txt": "x.txt",
+		"...":                "file",
+	}
+
+	for input, expected := range tests {
+		if got := sanitizeFileName(input); got != expected {
+			t.Errorf("sanitizeFileName(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const (
+	maxTrackedMessages = 1000
+	allowedUpdates     = `["message","callback_query","message_reaction"]`
+)
+
+type ReactionType struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji,omitempty"`
+}
+
+type MessageReactionUpdated struct {
+	Chat        *Chat          `json:"chat"`
+	MessageID   int64          `json:"message_id"`
+	User        *User          `json:"user,omitempty"`
+	Date        int64          `json:"date"`
+	OldReaction []ReactionType `json:"old_reaction"`
+	NewReaction []ReactionType `json:"new_reaction"`
+}
+
+type sentMessages struct {
+	mu    sync.Mutex
+	ids   map[string]string
+	order []string
+	max   int
+}
+
+func newSentMessages(max int) *sentMessages {
+	return &sentMessages{
+		ids: make(map[string]string),
+		max: max,
+	}
+}
+
+func (s *sentMessages) Track(chatID string, messageID int64, busID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := sentMessageKey(chatID, messageID)
+	if _, ok := s.ids[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	s.ids[key] = busID
+
+	for len(s.order) > s.max {
+		delete(s.ids, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func (s *sentMessages) Lookup(chatID string, messageID int64) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	busID, ok := s.ids[sentMessageKey(chatID, messageID)]
+	return busID, ok
+}
+
+func sentMessageKey(chatID string, messageID int64) string {
+	return chatID + ":" + strconv.FormatInt(messageID, 10)
+}
+
+func addedReactions(old, current []ReactionType) []string {
+	previous := make(map[string]bool, len(old))
+	for _, reaction := range old {
+		previous[reaction.Emoji] = true
+	}
+
+	added := make([]string, 0, len(current))
+	for _, reaction := range current {
+		if reaction.Type == "emoji" && !previous[reaction.Emoji] {
+			added = append(added, reaction.Emoji)
+		}
+	}
+
+	return added
+}
+
+func (b *Bot) handleReaction(updateID int64, reaction *MessageReactionUpdated) {
+	if b.messageBus == nil || reaction.Chat == nil {
+		return
+	}
+
+	chatID := strconv.FormatInt(reaction.Chat.ID, 10)
+
+	user, allowed := b.access.authorize(reaction.User, reaction.Chat.ID)
+	if !allowed {
+		return
+	}
+
+	busID, ok := b.sent.Lookup(chatID, reaction.MessageID)
+	if !ok {
+		return
+	}
+
+	var userID string
+	if reaction.User != nil {
+		userID = strconv.FormatInt(reaction.User.ID, 10)
+	}
+
+	for i, emoji := range addedReactions(reaction.OldReaction, reaction.NewReaction) {
+		b.logger.Info("Reaction received", "chat_id", chatID, "emoji", emoji, "message_id", busID)
+
+		msg := &bus.Message{
+			ID:      fmt.Sprintf("telegram-%d-%d-%d", time.Now().UnixNano(), updateID, i),
+			Channel: bus.ChannelTelegram,
+			ChatID:  chatID,
+			Metadata: map[string]interface{}{
+				bus.MetadataReaction: &bus.Reaction{
+					Emoji:     emoji,
+					MessageID: busID,
+					UserID:    userID,
+				},
+			},
+		}
+		if user != nil {
+			msg.Metadata[bus.MetadataUser] = *user
+		}
+
+		if err := b.messageBus.Publish(b.ctx, bus.ChannelTelegram, msg); err != nil {
+			b.logger.Error("Failed to publish reaction to bus", "chat_id", chatID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestSentMessagesEviction(t *testing.T) {
+	sent := newSentMessages(2)
+	sent.Track("chat", 1, "agent-1")
+	sent.Track("chat", 2, "agent-2")
+	sent.Track("chat", 3, "agent-3")
+
+	if _, ok := sent.Lookup("chat", 1); ok {
+		t.Error("Expected oldest message to be evicted")
+	}
+
+	if busID, ok := sent.Lookup("chat", 3); !ok || busID != "agent-3" {
+		t.Errorf("Expected agent-3, got %s", busID)
+	}
+}
+
+func TestAddedReactions(t *testing.T) {
+	added := addedReactions(
+		[]ReactionType{{Type: "emoji", Emoji: "👍"}},
+		[]ReactionType{{Type: "emoji", Emoji: "👍"}, {Type: "emoji", Emoji: "🤔"}, {Type: "custom_emoji"}},
+	)
+
+	if len(added) != 1 || added[0] != "🤔" {
+		t.Errorf("Expected only the new emoji, got %v", added)
+	}
+}
+
+func TestBotReactionOnResponse(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":42,"chat":{"id":7,"type":"private"}}}`)
+	}))
+	defer server.Close()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+
+	bot := NewBot(&Config{Token: "test-token"}, messageBus, ctx)
+	bot.apiURL = server.URL + "/bot/%s"
+
+	if err := bot.SendResponse(&bus.Message{ID: "agent-1", ChatID: "7"}, "Hello", nil); err != nil {
+		t.Fatalf("Failed to send response: %v", err)
+	}
+
+	reactions := make(chan *bus.Reaction, 1)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		if reaction := msg.Reaction(); reaction != nil {
+			reactions <- reaction
+		}
+		return nil
+	})
+
+	bot.handleUpdate(&Update{
+		UpdateID: 1,
+		MessageReaction: &MessageReactionUpdated{
+			Chat:        &Chat{ID: 7},
+			MessageID:   42,
+			User:        &User{ID: 9},
+			NewReaction: []ReactionType{{Type: "emoji", Emoji: "👎"}},
+		},
+	})
+
+	select {
+	case reaction := <-reactions:
+		if reaction.Emoji != "👎" || reaction.MessageID != "agent-1" || reaction.UserID != "9" {
+			t.Errorf("Unexpected reaction: %+v", reaction)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected reaction to be published")
+	}
+}
@@ -0,0 +1,195 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const (
+	defaultStreamInterval = 1500 * time.Millisecond
+	streamTTL             = 10 * time.Minute
+	streamCursor          = " ▌"
+)
+
+type EditMessageTextRequest struct {
+	ChatID      string                `json:"chat_id"`
+	MessageID   int64                 `json:"message_id"`
+	Text        string                `json:"text"`
+	ParseMode   string                `json:"parse_mode,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type streamState struct {
+	mu        sync.Mutex
+	chatID    string
+	messageID int64
+	text      string
+	sequence  int
+	shown     string
+	lastEdit  time.Time
+	created   time.Time
+	scheduled bool
+	done      bool
+}
+
+func (b *Bot) UpdateStream(msg *bus.Message) error {
+	stream := b.stream(msg.ID, msg.ChatID, true)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.done || msg.Sequence() <= stream.sequence {
+		return nil
+	}
+	stream.text = msg.Content
+	stream.sequence = msg.Sequence()
+
+	if stream.messageID == 0 {
+		req := SendMessageRequest{
+			ChatID: stream.chatID,
+			Text:   streamPreview(stream.text),
+		}
+		if replyTo := replyTarget(msg); replyTo != 0 {
+			req.ReplyToMessageID = replyTo
+			req.AllowSendingWithoutReply = true
+		}
+		messageID, err := b.sendMessageRequest(req)
+		if err != nil {
+			return fmt.Errorf("failed to start streamed message: %w", err)
+		}
+
+		stream.messageID = messageID
+		stream.shown = stream.text
+		stream.lastEdit = time.Now()
+		b.sent.Track(stream.chatID, messageID, msg.ID)
+		return nil
+	}
+
+	wait := b.streamInterval - time.Since(stream.lastEdit)
+	if wait <= 0 {
+		return b.flushStream(stream)
+	}
+
+	if !stream.scheduled {
+		stream.scheduled = true
+		time.AfterFunc(wait, func() {
+			stream.mu.Lock()
+			defer stream.mu.Unlock()
+
+			stream.scheduled = false
+			if stream.done {
+				return
+			}
+			if err := b.flushStream(stream); err != nil {
+				b.logger.Warn("Failed to update streamed message", "error", err)
+			}
+		})
+	}
+
+	return nil
+}
+
+func (b *Bot) flushStream(stream *streamState) error {
+	if stream.text == stream.shown {
+		return nil
+	}
+
+	err := b.callMethod("editMessageText", EditMessageTextRequest{
+		ChatID:    stream.chatID,
+		MessageID: stream.messageID,
+		Text:      streamPreview(stream.text),
+	})
+	stream.lastEdit = time.Now()
+	if err != nil {
+		return fmt.Errorf("failed to edit streamed message: %w", err)
+	}
+
+	stream.shown = stream.text
+	return nil
+}
+
+func (b *Bot) finishStream(msg *bus.Message, text string, keyboard *InlineKeyboardMarkup) (bool, error) {
+	// The stream is kept as done even when nothing was streamed, so partials
+	// delivered after the final message are ignored.
+	stream := b.stream(msg.ID, msg.ChatID, true)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.done || stream.messageID == 0 {
+		stream.done = true
+		return false, nil
+	}
+	stream.done = true
+
+	parts := formatParts(text, maxMessageLength)
+	first, rest := parts[0], parts[1:]
+
+	req := EditMessageTextRequest{
+		ChatID:    stream.chatID,
+		MessageID: stream.messageID,
+		Text:      first.formatted,
+		ParseMode: parseModeMarkdownV2,
+	}
+	if len(rest) == 0 {
+		req.ReplyMarkup = keyboard
+	}
+
+	if err := b.callMethod("editMessageText", req); err != nil {
+		b.logger.Warn("MarkdownV2 edit failed, retrying plain", "error", err)
+		req.Text = first.source
+		req.ParseMode = ""
+		if err := b.callMethod("editMessageText", req); err != nil {
+			return true, fmt.Errorf("failed to finalize streamed message: %w", err)
+		}
+	}
+
+	if len(rest) == 0 {
+		return true, nil
+	}
+
+	messageIDs, err := b.sendParts(stream.chatID, rest, keyboard, 0)
+	for _, messageID := range messageIDs {
+		b.sent.Track(stream.chatID, messageID, msg.ID)
+	}
+
+	return true, err
+}
+
+func (b *Bot) stream(id, chatID string, create bool) *streamState {
+	b.streamsMu.Lock()
+	defer b.streamsMu.Unlock()
+
+	stream, ok := b.streams[id]
+	if !ok && create {
+		for key, existing := range b.streams {
+			if time.Since(existing.created) > streamTTL {
+				delete(b.streams, key)
+			}
+		}
+
+		stream = &streamState{
+			chatID:  chatID,
+			created: time.Now(),
+		}
+		b.streams[id] = stream
+	}
+
+	return stream
+}
+
+func streamPreview(text string) string {
+	limit := maxMessageLength - len(streamCursor)
+	if len(text) > limit {
+		text = text[:limit]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	return strings.TrimRight(text, " \n") + streamCursor
+}
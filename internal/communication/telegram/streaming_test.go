@@ -0,0 +1,178 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+type recordedCall struct {
+	method  string
+	payload map[string]interface{}
+}
+
+func newRecordingServer(t *testing.T) (*httptest.Server, func() []recordedCall) {
+	var mu sync.Mutex
+	var calls []recordedCall
+	nextID := 100
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		calls = append(calls, recordedCall{method: method, payload: payload})
+		nextID++
+		fmt.Fprintf(w, `{"ok":true,"result":{"message_id":%d,"chat":{"id":7,"type":"private"}}}`, nextID)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []recordedCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedCall(nil), calls...)
+	}
+}
+
+func partial(id, text string, sequence int) *bus.Message {
+	return &bus.Message{
+		ID:       id,
+		Channel:  bus.ChannelTelegram,
+		ChatID:   "7",
+		Content:  text,
+		Metadata: map[string]interface{}{bus.MetadataPartial: true, bus.MetadataSequence: sequence},
+	}
+}
+
+func TestStreamedResponseEditing(t *testing.T) {
+	server, calls := newRecordingServer(t)
+
+	bot := NewBot(&Config{Token: "test-token", StreamInterval: 50 * time.Millisecond}, nil, context.Background())
+	bot.apiURL = server.URL + "/bot/%s"
+	handler := NewHandler(bot)
+	ctx := context.Background()
+
+	handler.HandleMessage(ctx, partial("agent-1", "Hel", 1))
+	handler.HandleMessage(ctx, partial("agent-1", "Hello wor", 3))
+	handler.HandleMessage(ctx, partial("agent-1", "Hello", 2))
+
+	time.Sleep(150 * time.Millisecond)
+
+	recorded := calls()
+	if len(recorded) != 2 || recorded[0].method != "sendMessage" || recorded[1].method != "editMessageText" {
+		t.Fatalf("Expected initial send and one throttled edit, got %+v", recorded)
+	}
+
+	if recorded[1].payload["text"] != "Hello wor"+streamCursor || recorded[1].payload["message_id"] != float64(101) {
+		t.Errorf("Unexpected edit payload: %v", recorded[1].payload)
+	}
+
+	if err := handler.HandleMessage(ctx, &bus.Message{ID: "agent-1", Channel: bus.ChannelTelegram, ChatID: "7", Content: "Hello *world*"}); err != nil {
+		t.Fatalf("Failed to finalize response: %v", err)
+	}
+
+	recorded = calls()
+	final := recorded[len(recorded)-1]
+	if len(recorded) != 3 || final.method != "editMessageText" {
+		t.Fatalf("Expected final edit instead of a new message, got %+v", recorded)
+	}
+	if final.payload["text"] != "Hello _world_" || final.payload["parse_mode"] != "MarkdownV2" {
+		t.Errorf("Unexpected final payload: %v", final.payload)
+	}
+
+	handler.HandleMessage(ctx, partial("agent-1", "Hello *world* again", 4))
+	if len(calls()) != 3 {
+		t.Error("Expected late partial updates to be ignored")
+	}
+
+	if busID, ok := bot.sent.Lookup("7", 101); !ok || busID != "agent-1" {
+		t.Errorf("Expected streamed message to be tracked for reactions, got %s", busID)
+	}
+}
+
+func TestStreamOrdering(t *testing.T) {
+	server, calls := newRecordingServer(t)
+
+	bot := NewBot(&Config{Token: "test-token", StreamInterval: time.Millisecond}, nil, context.Background())
+	bot.apiURL = server.URL + "/bot/%s"
+
+	bot.UpdateStream(partial("agent-3", "Let me look that up", 1))
+	time.Sleep(5 * time.Millisecond)
+	if err := bot.UpdateStream(partial("agent-3", "Found it", 2)); err != nil {
+		t.Fatalf("Failed to update stream: %v", err)
+	}
+	if recorded := calls(); len(recorded) != 2 || recorded[1].payload["text"] != "Found it"+streamCursor {
+		t.Fatalf("Expected a shorter text from a later iteration to be shown, got %+v", recorded)
+	}
+
+	// A reply that finished before its partials were delivered.
+	if err := bot.SendResponse(&bus.Message{ID: "agent-4", ChatID: "7"}, "Done", nil); err != nil {
+		t.Fatalf("Failed to send response: %v", err)
+	}
+	bot.UpdateStream(partial("agent-4", "Do", 1))
+	if recorded := calls(); len(recorded) != 3 || recorded[2].payload["text"] != "Done" {
+		t.Errorf("Expected a partial after the final message to be ignored, got %+v", recorded)
+	}
+}
+
+func TestStreamedResponseOverflow(t *testing.T) {
+	server, calls := newRecordingServer(t)
+
+	bot := NewBot(&Config{Token: "test-token"}, nil, context.Background())
+	bot.apiURL = server.URL + "/bot/%s"
+
+	if err := bot.UpdateStream(partial("agent-2", "Start", 1)); err != nil {
+		t.Fatalf("Failed to start stream: %v", err)
+	}
+
+	long := strings.Repeat("a", maxMessageLength+10)
+	if err := bot.SendResponse(&bus.Message{ID: "agent-2", ChatID: "7"}, long, nil); err != nil {
+		t.Fatalf("Failed to finalize: %v", err)
+	}
+
+	recorded := calls()
+	if len(recorded) != 3 || recorded[1].method != "editMessageText" || recorded[2].method != "sendMessage" {
+		t.Fatalf("Expected edit plus continuation message, got %d calls", len(recorded))
+	}
+
+	if recorded[2].payload["text"] != strings.Repeat("a", 10) {
+		t.Errorf("Expected continuation to hold the overflow, got %v", recorded[2].payload["text"])
+	}
+}
+
+func TestBotMarksStreamingRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+
+	received := make(chan *bus.Message, 1)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	bot := NewBot(&Config{Token: "test-token", StreamResponses: true}, messageBus, ctx)
+	bot.handleUpdate(&Update{UpdateID: 1, Message: &Message{Chat: &Chat{ID: 7}, Text: "hi"}})
+
+	select {
+	case msg := <-received:
+		if !msg.WantsStreaming() {
+			t.Error("Expected message to request streaming")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected message to be published")
+	}
+}
@@ -0,0 +1,279 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const triggersFile = "telegram/triggers.json"
+
+// TriggerConfig decides which group messages the bot answers. Private chats
+// are always answered. With nothing set every message is answered.
+type TriggerConfig struct {
+	// All answers every message, overriding the other triggers.
+	All bool `json:"all,omitempty"`
+	// Mention answers messages that @mention the bot.
+	Mention bool `json:"mention,omitempty"`
+	// Reply answers replies to the bot's own messages.
+	Reply bool `json:"reply,omitempty"`
+	// Prefixes answers messages starting with one of them, e.g. "!ai".
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+type MessageEntity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	User   *User  `json:"user,omitempty"`
+}
+
+func (t TriggerConfig) any() bool {
+	return t.All || t.Mention || t.Reply || len(t.Prefixes) > 0
+}
+
+func (t TriggerConfig) String() string {
+	if !t.any() || t.All {
+		return "every message"
+	}
+
+	var triggers []string
+	if t.Mention {
+		triggers = append(triggers, "mentions")
+	}
+	if t.Reply {
+		triggers = append(triggers, "replies")
+	}
+	for _, prefix := range t.Prefixes {
+		triggers = append(triggers, fmt.Sprintf("messages starting with %q", prefix))
+	}
+	return strings.Join(triggers, ", ")
+}
+
+// triggerStore keeps the per-chat overrides, loaded from storage on first
+// use and written back on every change.
+type triggerStore struct {
+	mu        sync.Mutex
+	storage   storage.Storage
+	loaded    bool
+	overrides map[string]TriggerConfig
+}
+
+func newTriggerStore(s storage.Storage) *triggerStore {
+	return &triggerStore{
+		storage:   s,
+		overrides: make(map[string]TriggerConfig),
+	}
+}
+
+func (s *triggerStore) load(ctx context.Context) error {
+	if s.loaded || s.storage == nil {
+		return nil
+	}
+
+	exists, err := s.storage.FileExists(ctx, triggersFile)
+	if err != nil {
+		return fmt.Errorf("failed to check trigger overrides: %w", err)
+	}
+	if exists {
+		data, err := s.storage.ReadFile(ctx, triggersFile)
+		if err != nil {
+			return fmt.Errorf("failed to read trigger overrides: %w", err)
+		}
+		if err := json.Unmarshal(data, &s.overrides); err != nil {
+			return fmt.Errorf("failed to parse trigger overrides: %w", err)
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// get returns the override for chatID, if there is one.
+func (s *triggerStore) get(ctx context.Context, chatID string) (TriggerConfig, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return TriggerConfig{}, false, err
+	}
+	triggers, ok := s.overrides[chatID]
+	return triggers, ok, nil
+}
+
+// set stores an override for chatID, or removes it when triggers is nil.
+func (s *triggerStore) set(ctx context.Context, chatID string, triggers *TriggerConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	if triggers == nil {
+		delete(s.overrides, chatID)
+	} else {
+		s.overrides[chatID] = *triggers
+	}
+
+	if s.storage == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.overrides, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trigger overrides: %w", err)
+	}
+	if err := s.storage.WriteFile(ctx, triggersFile, data); err != nil {
+		return fmt.Errorf("failed to write trigger overrides: %w", err)
+	}
+	return nil
+}
+
+func (b *Bot) chatTriggers(chatID string) TriggerConfig {
+	triggers, ok, err := b.triggerOverrides.get(b.ctx, chatID)
+	if err != nil {
+		b.logger.Warn("Failed to load trigger overrides", "error", err)
+	}
+	if !ok {
+		return b.groupTriggers
+	}
+	return triggers
+}
+
+// triggered reports whether the bot should answer message, and returns its
+// content without the mention or prefix that addressed the bot.
+func (b *Bot) triggered(chatID string, message *Message, content string) (string, bool) {
+	if !isGroup(message.Chat) {
+		return content, true
+	}
+
+	b.mu.RLock()
+	self := b.self
+	b.mu.RUnlock()
+
+	// Commands always reach the bot unless they name another one.
+	if _, _, ok := parseCommand(content); ok {
+		_, target, addressed := strings.Cut(strings.Fields(content)[0], "@")
+		if addressed && (self == nil || !strings.EqualFold(target, self.Username)) {
+			return "", false
+		}
+		return content, true
+	}
+
+	triggers := b.chatTriggers(chatID)
+	if !triggers.any() || triggers.All {
+		return content, true
+	}
+
+	if triggers.Mention && self != nil {
+		if stripped, ok := stripMention(content, message.Entities, self); ok {
+			return stripped, true
+		}
+	}
+
+	if triggers.Reply && self != nil && message.ReplyToMessage != nil &&
+		message.ReplyToMessage.From != nil && message.ReplyToMessage.From.ID == self.ID {
+		return content, true
+	}
+
+	for _, prefix := range triggers.Prefixes {
+		if len(content) >= len(prefix) && strings.EqualFold(content[:len(prefix)], prefix) {
+			return strings.TrimSpace(content[len(prefix):]), true
+		}
+	}
+
+	return "", false
+}
+
+// stripMention removes the mentions of self from content. Entity offsets are
+// in UTF-16 code units, so they are only used to find mentions by user ID;
+// @username mentions are matched on the text.
+func stripMention(content string, entities []MessageEntity, self *User) (string, bool) {
+	mentioned := false
+	for _, entity := range entities {
+		if entity.Type == "text_mention" && entity.User != nil && entity.User.ID == self.ID {
+			mentioned = true
+		}
+	}
+
+	if self.Username != "" {
+		handle := "@" + strings.ToLower(self.Username)
+		lower := strings.ToLower(content)
+		for {
+			i := strings.Index(lower, handle)
+			if i < 0 {
+				break
+			}
+			end := i + len(handle)
+			if end < len(lower) && isWordByte(lower[end]) {
+				break
+			}
+			content = content[:i] + content[end:]
+			lower = lower[:i] + lower[end:]
+			mentioned = true
+		}
+	}
+
+	return strings.Join(strings.Fields(content), " "), mentioned
+}
+
+func isGroup(chat *Chat) bool {
+	return chat != nil && (chat.Type == "group" || chat.Type == "supergroup")
+}
+
+func (b *Bot) registerTriggersCommand() {
+	b.RegisterCommand(Command{
+		Name:        "triggers",
+		Description: "Show or change when the bot answers in this group",
+		AdminOnly:   true,
+		Handler: func(ctx context.Context, chatID string, args []string) (string, error) {
+			if id, err := strconv.ParseInt(chatID, 10, 64); err == nil && id > 0 {
+				return "Triggers only apply to group chats.", nil
+			}
+
+			if len(args) == 0 {
+				return "This group gets answers to " + b.chatTriggers(chatID).String() + ".\n" + triggersUsage, nil
+			}
+
+			if len(args) == 1 && args[0] == "default" {
+				if err := b.triggerOverrides.set(ctx, chatID, nil); err != nil {
+					return "", err
+				}
+				return "Back to the default: answering " + b.groupTriggers.String() + ".", nil
+			}
+
+			triggers := parseTriggers(args)
+			if err := b.triggerOverrides.set(ctx, chatID, &triggers); err != nil {
+				return "", err
+			}
+			return "Answering " + triggers.String() + ".", nil
+		},
+	})
+}
+
+const triggersUsage = "Usage: /triggers [all | default | mention | reply | <prefix>...]"
+
+// parseTriggers reads words like "mention reply !ai"; anything that is not
+// a keyword is a prefix.
+func parseTriggers(args []string) TriggerConfig {
+	var triggers TriggerConfig
+	for _, arg := range args {
+		switch strings.ToLower(arg) {
+		case "all":
+			triggers.All = true
+		case "mention":
+			triggers.Mention = true
+		case "reply":
+			triggers.Reply = true
+		default:
+			triggers.Prefixes = append(triggers.Prefixes, arg)
+		}
+	}
+	return triggers
+}
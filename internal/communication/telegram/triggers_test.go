@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func groupMessage(text string) *Message {
+	return &Message{MessageID: 1, From: &User{ID: 2}, Chat: &Chat{ID: -100, Type: "supergroup"}, Text: text}
+}
+
+func TestGroupTriggers(t *testing.T) {
+	bot := NewBot(&Config{
+		Token:         "test-token",
+		GroupTriggers: TriggerConfig{Mention: true, Reply: true, Prefixes: []string{"!ai"}},
+	}, nil, context.Background())
+	bot.self = &User{ID: 99, Username: "MiniClawBot"}
+
+	reply := groupMessage("and tomorrow?")
+	reply.ReplyToMessage = &Message{From: &User{ID: 99}}
+
+	tests := []struct {
+		name    string
+		message *Message
+		content string
+		ok      bool
+	}{
+		{"chatter", groupMessage("lunch anyone?"), "", false},
+		{"mention", groupMessage("@miniclawbot what's the weather?"), "what's the weather?", true},
+		{"other bot", groupMessage("@miniclawbot2 hi"), "", false},
+		{"reply", reply, "and tomorrow?", true},
+		{"prefix", groupMessage("!AI translate this"), "translate this", true},
+		{"command", groupMessage("/help"), "/help", true},
+		{"command for us", groupMessage("/help@MiniClawBot"), "/help@MiniClawBot", true},
+		{"command for another bot", groupMessage("/help@OtherBot"), "", false},
+		{"private chat", &Message{Chat: &Chat{ID: 2, Type: "private"}, Text: "hi"}, "hi", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, ok := bot.triggered("-100", tt.message, tt.message.Text)
+			if ok != tt.ok || content != tt.content {
+				t.Errorf("triggered(%q) = %q, %v, want %q, %v", tt.message.Text, content, ok, tt.content, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTriggerOverridesPersist(t *testing.T) {
+	fileStorage := storage.NewFileStorage(t.TempDir())
+	ctx := context.Background()
+
+	bot := NewBot(&Config{Token: "test-token", Storage: fileStorage, GroupTriggers: TriggerConfig{Mention: true}}, nil, ctx)
+	if _, ok := bot.triggered("-100", groupMessage("hello"), "hello"); ok {
+		t.Fatal("Expected the default triggers to ignore plain messages")
+	}
+
+	command := bot.commands["triggers"]
+	if _, err := command.Handler(ctx, "-100", []string{"all"}); err != nil {
+		t.Fatalf("Failed to set triggers: %v", err)
+	}
+
+	restarted := NewBot(&Config{Token: "test-token", Storage: fileStorage, GroupTriggers: TriggerConfig{Mention: true}}, nil, ctx)
+	if _, ok := restarted.triggered("-100", groupMessage("hello"), "hello"); !ok {
+		t.Error("Expected the override to survive a restart")
+	}
+	if _, ok := restarted.triggered("-200", groupMessage("hello"), "hello"); ok {
+		t.Error("Expected other groups to keep the default")
+	}
+
+	if _, err := restarted.commands["triggers"].Handler(ctx, "-100", []string{"default"}); err != nil {
+		t.Fatalf("Failed to reset triggers: %v", err)
+	}
+	if _, ok := restarted.triggered("-100", groupMessage("hello"), "hello"); ok {
+		t.Error("Expected the default triggers after a reset")
+	}
+}
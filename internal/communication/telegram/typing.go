@@ -0,0 +1,103 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+const (
+	// Telegram shows a chat action for five seconds, so it is repeated a
+	// little more often while the agent works.
+	typingInterval = 4 * time.Second
+	maxTyping      = 3 * time.Minute
+)
+
+type ChatActionRequest struct {
+	ChatID string `json:"chat_id"`
+	Action string `json:"action"`
+}
+
+type typingState struct {
+	cancel context.CancelFunc
+}
+
+// startTyping shows "typing…" in the chat until the reply to requestID is
+// sent or maxTyping passes.
+func (b *Bot) startTyping(requestID, chatID string) {
+	ctx, cancel := context.WithTimeout(b.ctx, maxTyping)
+	state := &typingState{cancel: cancel}
+
+	b.typingMu.Lock()
+	if previous, ok := b.typing[requestID]; ok {
+		previous.cancel()
+	}
+	b.typing[requestID] = state
+	b.typingMu.Unlock()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() {
+			b.typingMu.Lock()
+			if b.typing[requestID] == state {
+				delete(b.typing, requestID)
+			}
+			b.typingMu.Unlock()
+			cancel()
+		}()
+
+		ticker := time.NewTicker(typingInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := b.callMethod("sendChatAction", ChatActionRequest{ChatID: chatID, Action: "typing"}); err != nil {
+				b.logger.Debug("Failed to send typing action", "chat_id", chatID, "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (b *Bot) stopTyping(requestID string) {
+	b.typingMu.Lock()
+	defer b.typingMu.Unlock()
+
+	if state, ok := b.typing[requestID]; ok {
+		state.cancel()
+		delete(b.typing, requestID)
+	}
+}
+
+// busMessageID is the bus message ID of a Telegram message.
+func busMessageID(chatID string, messageID int64) string {
+	return fmt.Sprintf("telegram-%s-%d", chatID, messageID)
+}
+
+// replyTarget is the Telegram message a reply answers, which it quotes in
+// group chats so it is clear whose question it is. It is 0 in private chats
+// and for messages the agent sends on its own.
+func replyTarget(msg *bus.Message) int64 {
+	if !strings.HasPrefix(msg.ChatID, "-") {
+		return 0
+	}
+
+	id, ok := strings.CutPrefix(msg.ReplyTo(), "telegram-"+msg.ChatID+"-")
+	if !ok {
+		return 0
+	}
+	messageID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return messageID
+}
@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestTypingUntilReply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+
+	received := make(chan *bus.Message, 1)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	server, calls := newRecordingServer(t)
+	bot := NewBot(&Config{Token: "test-token"}, messageBus, ctx)
+	bot.apiURL = server.URL + "/bot/%s"
+
+	bot.handleUpdate(&Update{UpdateID: 1, Message: &Message{MessageID: 42, Chat: &Chat{ID: 7}, Text: "hi"}})
+
+	var request *bus.Message
+	select {
+	case request = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected message to be published")
+	}
+	if request.ID != "telegram-7-42" {
+		t.Errorf("Expected the bus ID to name the Telegram message, got %s", request.ID)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(calls()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if recorded := calls(); len(recorded) == 0 || recorded[0].method != "sendChatAction" || recorded[0].payload["action"] != "typing" || recorded[0].payload["chat_id"] != "7" {
+		t.Fatalf("Expected a typing action while the agent works, got %+v", recorded)
+	}
+
+	reply := &bus.Message{ID: "agent-" + request.ID, Channel: bus.ChannelTelegram, ChatID: "7", Content: "hello"}
+	reply.SetReplyTo(request)
+	if err := bot.SendResponse(reply, reply.Content, nil); err != nil {
+		t.Fatalf("Failed to send reply: %v", err)
+	}
+
+	bot.typingMu.Lock()
+	typing := len(bot.typing)
+	bot.typingMu.Unlock()
+	if typing != 0 {
+		t.Error("Expected the typing action to stop once the reply is sent")
+	}
+	if recorded := calls(); recorded[len(recorded)-1].payload["reply_to_message_id"] != nil {
+		t.Errorf("Expected no quote in a private chat, got %v", recorded[len(recorded)-1].payload)
+	}
+}
+
+func TestRepliesQuoteTheQuestionInGroups(t *testing.T) {
+	server, calls := newRecordingServer(t)
+	bot := NewBot(&Config{Token: "test-token"}, nil, context.Background())
+	bot.apiURL = server.URL + "/bot/%s"
+
+	request := &bus.Message{ID: "telegram--1001-42", Channel: bus.ChannelTelegram, ChatID: "-1001"}
+	reply := &bus.Message{ID: "agent-1", Channel: bus.ChannelTelegram, ChatID: "-1001"}
+	reply.SetReplyTo(request)
+
+	if err := bot.SendResponse(reply, "answer", nil); err != nil {
+		t.Fatalf("Failed to send reply: %v", err)
+	}
+	sent := calls()[0].payload
+	if sent["reply_to_message_id"] != float64(42) || sent["allow_sending_without_reply"] != true {
+		t.Errorf("Expected the reply to quote the question, got %v", sent)
+	}
+
+	stream := &bus.Message{ID: "agent-2", Channel: bus.ChannelTelegram, ChatID: "-1001", Content: "Hel", Metadata: map[string]interface{}{bus.MetadataPartial: true, bus.MetadataSequence: 1}}
+	stream.SetReplyTo(request)
+	if err := bot.UpdateStream(stream); err != nil {
+		t.Fatalf("Failed to start stream: %v", err)
+	}
+	if sent := calls()[1].payload; sent["reply_to_message_id"] != float64(42) {
+		t.Errorf("Expected the streamed reply to quote the question, got %v", sent)
+	}
+
+	if err := bot.SendMessage("-1001", "notice"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	if sent := calls()[2].payload; sent["reply_to_message_id"] != nil {
+		t.Errorf("Expected messages the agent sends on its own not to quote anything, got %v", sent)
+	}
+}
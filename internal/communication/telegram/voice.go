@@ -0,0 +1,159 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"strconv"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/speech"
+)
+
+// voiceRequestTTL bounds how long a voice note waits for its reply before
+// it is forgotten.
+const voiceRequestTTL = 10 * time.Minute
+
+// transcribeVoice replaces stored voice notes with their transcript, which
+// becomes the message content. Voice notes that cannot be transcribed stay
+// attachments. It reports whether anything was transcribed.
+func (b *Bot) transcribeVoice(chatID, content string, attachments []bus.Attachment) (string, []bus.Attachment, bool) {
+	if b.transcriber == nil {
+		return content, attachments, false
+	}
+
+	transcribed := false
+	kept := attachments[:0]
+	for _, attachment := range attachments {
+		if attachment.Type != bus.AttachmentVoice || attachment.Path == "" {
+			kept = append(kept, attachment)
+			continue
+		}
+
+		text, err := b.transcribe(attachment)
+		if err != nil {
+			b.logger.Warn("Failed to transcribe voice message", "chat_id", chatID, "transcriber", b.transcriber.Name(), "error", err)
+			kept = append(kept, attachment)
+			continue
+		}
+		if text == "" {
+			kept = append(kept, attachment)
+			continue
+		}
+
+		b.logger.Info("Transcribed voice message", "chat_id", chatID, "chars", len(text))
+		if content != "" {
+			content += "\n"
+		}
+		content += text
+		transcribed = true
+	}
+
+	return content, kept, transcribed
+}
+
+func (b *Bot) transcribe(attachment bus.Attachment) (string, error) {
+	audio, err := b.storage.ReadFile(b.ctx, attachment.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read voice message: %w", err)
+	}
+	return b.transcriber.Transcribe(b.ctx, audio, attachment.FileName)
+}
+
+func (b *Bot) trackVoiceRequest(requestID string) {
+	b.voiceMu.Lock()
+	defer b.voiceMu.Unlock()
+
+	for id, received := range b.voiceRequests {
+		if time.Since(received) > voiceRequestTTL {
+			delete(b.voiceRequests, id)
+		}
+	}
+	b.voiceRequests[requestID] = time.Now()
+}
+
+func (b *Bot) takeVoiceRequest(requestID string) bool {
+	b.voiceMu.Lock()
+	defer b.voiceMu.Unlock()
+
+	_, ok := b.voiceRequests[requestID]
+	delete(b.voiceRequests, requestID)
+	return ok
+}
+
+// replyWithVoice reads the reply to a voice note aloud, after its text was
+// sent. Failures are only logged since the text already arrived.
+func (b *Bot) replyWithVoice(msg *bus.Message, text string) {
+	if !b.takeVoiceRequest(msg.ReplyTo()) || b.synthesizer == nil {
+		return
+	}
+
+	spoken := speech.SpeakableText(text)
+	if spoken == "" {
+		return
+	}
+
+	audio, err := b.synthesizer.Synthesize(b.ctx, spoken)
+	if err != nil {
+		b.logger.Warn("Failed to synthesize voice reply", "chat_id", msg.ChatID, "synthesizer", b.synthesizer.Name(), "error", err)
+		return
+	}
+
+	messageID, err := b.SendVoice(msg.ChatID, audio, replyTarget(msg))
+	if err != nil {
+		b.logger.Warn("Failed to send voice reply", "chat_id", msg.ChatID, "error", err)
+		return
+	}
+	b.sent.Track(msg.ChatID, messageID, msg.ID)
+}
+
+// SendVoice uploads OGG/Opus audio as a voice message, quoting replyTo
+// unless it is 0.
+func (b *Bot) SendVoice(chatID string, audio []byte, replyTo int64) (int64, error) {
+	if !b.enabled {
+		return 0, fmt.Errorf("telegram bot is disabled")
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("chat_id", chatID)
+	if replyTo != 0 {
+		writer.WriteField("reply_to_message_id", strconv.FormatInt(replyTo, 10))
+		writer.WriteField("allow_sending_without_reply", "true")
+	}
+	part, err := writer.CreateFormFile("voice", "reply.ogg")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create form: %w", err)
+	}
+	if _, err := part.Write(audio); err != nil {
+		return 0, fmt.Errorf("failed to write audio: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write form: %w", err)
+	}
+
+	resp, err := b.httpClient.Post(b.methodURL("sendVoice"), writer.FormDataContentType(), &body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send voice: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		OK          bool     `json:"ok"`
+		Result      *Message `json:"result,omitempty"`
+		Description string   `json:"description,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !apiResp.OK || apiResp.Result == nil {
+		if apiResp.Description != "" {
+			return 0, fmt.Errorf("API error: %s", apiResp.Description)
+		}
+		return 0, fmt.Errorf("API returned not OK")
+	}
+
+	return apiResp.Result.MessageID, nil
+}
@@ -0,0 +1,125 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+type fakeSpeech struct {
+	transcript string
+	spoken     chan string
+}
+
+func (f *fakeSpeech) Name() string {
+	return "fake"
+}
+
+func (f *fakeSpeech) Transcribe(ctx context.Context, audio []byte, fileName string) (string, error) {
+	if string(audio) != "hello" {
+		return "", fmt.Errorf("unexpected audio %q", audio)
+	}
+	return f.transcript, nil
+}
+
+func (f *fakeSpeech) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	f.spoken <- text
+	return []byte("OggS"), nil
+}
+
+func TestVoiceMessageTranscribedAndAnsweredAloud(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	voices := make(chan string, 1)
+	server := newMediaTestServer(t)
+	mux := server.Config.Handler.(*http.ServeMux)
+	mux.HandleFunc("/bot/sendVoice", func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("voice")
+		if err != nil {
+			t.Errorf("Expected a voice upload: %v", err)
+			return
+		}
+		audio, _ := io.ReadAll(file)
+		voices <- r.FormValue("chat_id") + ":" + string(audio)
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":300,"chat":{"id":1,"type":"private"}}}`)
+	})
+	mux.HandleFunc("/bot/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":200,"chat":{"id":1,"type":"private"}}}`)
+	})
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *bus.Message, 1)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	fake := &fakeSpeech{transcript: "what time is it?", spoken: make(chan string, 1)}
+	bot := NewBot(&Config{
+		Token:        "test-token",
+		Storage:      storage.NewFileStorage(t.TempDir()),
+		Transcriber:  fake,
+		Synthesizer:  fake,
+		VoiceReplies: true,
+	}, messageBus, ctx)
+	bot.apiURL = server.URL + "/bot/%s"
+	bot.fileURL = server.URL + "/file/%s"
+
+	bot.handleUpdate(&Update{UpdateID: 1, Message: &Message{
+		MessageID: 7,
+		Chat:      &Chat{ID: 1, Type: "private"},
+		Voice:     &Voice{FileID: "voice1", FileUniqueID: "v1", Duration: 2, FileSize: 5},
+	}})
+
+	var request *bus.Message
+	select {
+	case request = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected message to be published")
+	}
+	if request.Content != "what time is it?" || len(request.Attachments()) != 0 {
+		t.Fatalf("Expected the transcript instead of the voice note, got %q with %d attachments", request.Content, len(request.Attachments()))
+	}
+
+	reply := &bus.Message{ID: "agent-1", Channel: bus.ChannelTelegram, ChatID: "1", Content: "It is **noon**."}
+	reply.SetReplyTo(request)
+	if err := bot.SendResponse(reply, reply.Content, nil); err != nil {
+		t.Fatalf("Failed to send reply: %v", err)
+	}
+
+	if spoken := <-fake.spoken; spoken != "It is noon." {
+		t.Errorf("Expected the reply without formatting to be read aloud, got %q", spoken)
+	}
+	select {
+	case voice := <-voices:
+		if voice != "1:OggS" {
+			t.Errorf("Unexpected voice upload: %q", voice)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a voice reply")
+	}
+
+	if bot.takeVoiceRequest(request.ID) {
+		t.Error("Expected the voice request to be answered only once")
+	}
+}
+
+func TestVoiceMessageKeptWithoutTranscript(t *testing.T) {
+	bot := NewBot(&Config{Token: "test-token"}, nil, context.Background())
+	attachments := []bus.Attachment{{Type: bus.AttachmentVoice, Path: "uploads/voice.ogg"}}
+
+	content, kept, transcribed := bot.transcribeVoice("1", "", attachments)
+	if content != "" || len(kept) != 1 || transcribed {
+		t.Errorf("Expected voice notes to stay attachments without a transcriber, got %q %v %v", content, kept, transcribed)
+	}
+}
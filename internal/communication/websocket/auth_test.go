@@ -0,0 +1,222 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/wjffsx/miniclaw_go/internal/auth"
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func TestHandshakeRequiresChatScope(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := auth.NewAPIKeyStore(storage.NewFileStorage(t.TempDir()))
+	chatKey, chatSecret, _ := store.Create(ctx, "web", []auth.Scope{auth.ScopeChat})
+	_, metricsSecret, _ := store.Create(ctx, "grafana", []auth.Scope{auth.ScopeMetricsRead})
+
+	authenticator, err := auth.NewAuthenticator(ctx, &auth.Config{APIKeys: store})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *bus.Message, 4)
+	messageBus.Subscribe(bus.ChannelWebSocket, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	server := NewServer(&Config{Auth: authenticator}, messageBus, ctx)
+	go server.run()
+
+	httpServer := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	dial := func(secret string) (*websocket.Conn, *http.Response, error) {
+		header := http.Header{}
+		if secret != "" {
+			header.Set("X-API-Key", secret)
+		}
+		return websocket.DefaultDialer.Dial(url, header)
+	}
+
+	if _, resp, err := dial(""); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a key, got %v", resp)
+	}
+	if _, resp, err := dial(metricsSecret); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a key without the chat scope, got %v", resp)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?api_key="+chatSecret, nil)
+	if err != nil {
+		t.Fatalf("Expected chat key to connect, got %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(Message{Type: "message", Content: "hello"})
+	select {
+	case msg := <-received:
+		if msg.Content != "hello" {
+			t.Errorf("Unexpected message %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected message to reach the bus")
+	}
+
+	if err := store.Revoke(ctx, chatKey.ID); err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+
+	conn.WriteJSON(Message{Type: "message", Content: "after revoke"})
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("Expected connection to close after its key was revoked")
+	}
+
+	select {
+	case msg := <-received:
+		t.Errorf("Expected no messages from a revoked key, got %+v", msg)
+	default:
+	}
+}
+
+func TestAuthenticatedClientsGetStableChatIDs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := auth.NewAPIKeyStore(storage.NewFileStorage(t.TempDir()))
+	key, secret, _ := store.Create(ctx, "web", []auth.Scope{auth.ScopeChat})
+	authenticator, _ := auth.NewAuthenticator(ctx, &auth.Config{APIKeys: store})
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	received := make(chan *bus.Message, 4)
+	messageBus.Subscribe(bus.ChannelWebSocket, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	server := NewServer(&Config{Auth: authenticator}, messageBus, ctx)
+	go server.run()
+
+	httpServer := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "?api_key=" + secret
+
+	next := func() *bus.Message {
+		select {
+		case msg := <-received:
+			return msg
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected message to reach the bus")
+			return nil
+		}
+	}
+
+	base := "ws_apikey_" + key.ID
+	for i := 0; i < 2; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		conn.WriteJSON(Message{Type: "message", Content: "hello"})
+		msg := next()
+		conn.Close()
+
+		if msg.ChatID != base {
+			t.Errorf("Expected chat ID %s on every connection, got %s", base, msg.ChatID)
+		}
+		if user, ok := msg.User(); !ok || user.ID != "apikey:"+key.ID || user.Name != "web" {
+			t.Errorf("Expected user identity in metadata, got %+v", msg.Metadata)
+		}
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(Message{Type: "message", Content: "hi", ChatID: "ws_someone_else"})
+	if msg := next(); msg.ChatID != base+"~ws_someone_else" {
+		t.Errorf("Expected requested chat to be scoped to the user, got %s", msg.ChatID)
+	}
+	conn.WriteJSON(Message{Type: "message", Content: "again", ChatID: base + "~ws_someone_else"})
+	if msg := next(); msg.ChatID != base+"~ws_someone_else" {
+		t.Errorf("Expected scoped chat ID to be kept, got %s", msg.ChatID)
+	}
+}
+
+func TestChatIDsOfUsersWithSharedPrefix(t *testing.T) {
+	alice := &Client{user: &bus.User{ID: "alice"}}
+	admin := &Client{user: &bus.User{ID: "alice_admin"}}
+
+	adminChat := userChatID(admin.user)
+	if got := alice.resolveChatID(adminChat); got == adminChat {
+		t.Errorf("Expected alice to be kept out of alice_admin's chat %s", adminChat)
+	}
+	if got := alice.resolveChatID(adminChat + "~work"); strings.HasPrefix(got, adminChat+"~") {
+		t.Errorf("Expected alice to be kept out of alice_admin's chats, got %s", got)
+	}
+
+	work := alice.resolveChatID("work")
+	if work != "ws_alice~work" || alice.resolveChatID(work) != work {
+		t.Errorf("Expected alice's own chats to resolve to themselves, got %s", work)
+	}
+	if got := admin.resolveChatID(work); got == work {
+		t.Errorf("Expected alice_admin to be kept out of alice's chat %s", work)
+	}
+}
+
+func TestHandshakeLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := NewServer(&Config{MaxClients: 1, AllowedOrigins: []string{"https://app.example.com/"}}, nil, ctx)
+	go server.run()
+
+	httpServer := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	dial := func(origin string) (*websocket.Conn, *http.Response, error) {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		return websocket.DefaultDialer.Dial(url, header)
+	}
+
+	if _, resp, err := dial("https://evil.example.com"); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for an unknown origin, got %v", resp)
+	}
+
+	conn, _, err := dial("https://app.example.com")
+	if err != nil {
+		t.Fatalf("Expected allowed origin to connect, got %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for server.GetClientCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, resp, err := dial(""); err == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 beyond max clients, got %v", resp)
+	}
+}
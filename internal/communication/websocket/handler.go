@@ -2,9 +2,9 @@ package websocket
 
 import (
 	"context"
-	"log"
 
 	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 )
 
 type Handler struct {
@@ -18,14 +18,16 @@ func NewHandler(server *Server) *Handler {
 }
 
 func (h *Handler) HandleMessage(ctx context.Context, msg *bus.Message) error {
-	if msg.Channel != bus.ChannelWebSocket {
+	// Messages from the clients themselves go through the bus too; only the
+	// replies to them are sent out.
+	if msg.Channel != bus.ChannelWebSocket || !msg.IsReply() {
 		return nil
 	}
 
-	log.Printf("Sending message to WebSocket client %s: %.40s...", msg.ChatID, msg.Content)
+	h.server.logger.Debug("Sending message", "chat_id", msg.ChatID, "connection", msg.Connection(), "preview", logging.Preview(msg.Content, 40))
 
-	if err := h.server.SendToClient(msg.ChatID, msg.Content); err != nil {
-		log.Printf("Failed to send message to WebSocket: %v", err)
+	if err := h.server.Deliver(msg); err != nil {
+		h.server.logger.Error("Failed to send message", "chat_id", msg.ChatID, "error", err)
 		return err
 	}
 
@@ -0,0 +1,106 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func TestRepliesRouteToOriginatingClientAndChat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	defer messageBus.Close()
+
+	server := NewServer(nil, messageBus, ctx)
+	go server.run()
+	handler := NewHandler(server)
+
+	requests := make(chan *bus.Message, 4)
+	messageBus.Subscribe(bus.ChannelWebSocket, func(ctx context.Context, msg *bus.Message) error {
+		if !msg.IsReply() {
+			requests <- msg
+		}
+		return nil
+	})
+	messageBus.Subscribe(bus.ChannelWebSocket, handler.HandleMessage)
+
+	httpServer := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	dial := func() *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		return conn
+	}
+	nextRequest := func() *bus.Message {
+		select {
+		case msg := <-requests:
+			return msg
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected message to reach the bus")
+			return nil
+		}
+	}
+	read := func(conn *websocket.Conn) (Message, error) {
+		var msg Message
+		conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+		err := conn.ReadJSON(&msg)
+		return msg, err
+	}
+	reply := func(request *bus.Message, content string) {
+		response := &bus.Message{ID: "agent-" + request.ID, Channel: bus.ChannelWebSocket, ChatID: request.ChatID, Content: content}
+		response.SetReplyTo(request)
+		messageBus.Publish(ctx, bus.ChannelWebSocket, response)
+	}
+
+	first, second, other := dial(), dial(), dial()
+	defer first.Close()
+	defer second.Close()
+	defer other.Close()
+
+	first.WriteJSON(Message{Type: "message", Content: "hello", ChatID: "shared"})
+	request := nextRequest()
+	second.WriteJSON(Message{Type: "message", Content: "joining", ChatID: "shared"})
+	nextRequest()
+	other.WriteJSON(Message{Type: "message", Content: "elsewhere", ChatID: "private"})
+	nextRequest()
+
+	if request.Connection() == "" {
+		t.Fatalf("Expected connection ID in metadata, got %+v", request.Metadata)
+	}
+
+	reply(request, "hi both")
+	for name, conn := range map[string]*websocket.Conn{"first": first, "second": second} {
+		msg, err := read(conn)
+		if err != nil || msg.Content != "hi both" || msg.ChatID != "shared" {
+			t.Errorf("Expected %s client in the chat to get the reply, got %+v %v", name, msg, err)
+		}
+	}
+	if msg, err := read(other); err == nil {
+		t.Errorf("Expected client in another chat not to get the reply or echoes, got %+v", msg)
+	}
+
+	// A reply still reaches the client that asked after it moved on to
+	// another chat.
+	first.WriteJSON(Message{Type: "message", Content: "switching", ChatID: "private"})
+	nextRequest()
+	reply(request, "late answer")
+	if msg, err := read(first); err != nil || msg.Content != "late answer" {
+		t.Errorf("Expected originating client to get the late reply, got %+v %v", msg, err)
+	}
+	if msg, err := read(second); err != nil || msg.Content != "late answer" {
+		t.Errorf("Expected client still in the chat to get the late reply, got %+v %v", msg, err)
+	}
+}
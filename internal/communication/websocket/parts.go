@@ -0,0 +1,139 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultMaxMessageSize = 64 * 1024
+	defaultMaxPayloadSize = 1024 * 1024
+
+	// minClientMessageSize is the smallest message size a client may ask
+	// for, below which parts would be mostly overhead.
+	minClientMessageSize = 1024
+	// maxOpenPayloads bounds how many payloads a client may be sending in
+	// parts at once.
+	maxOpenPayloads = 4
+	// partOverhead is room left in a part for everything but its data.
+	partOverhead = 256
+)
+
+// A frame too large for one WebSocket message is sent as part frames, each
+// carrying a piece of the frame's JSON in data. The receiver joins the data
+// of the parts with the same id, in seq order starting at 0, and reads the
+// result as a frame once the part with final set arrives. Clients learn the
+// server's limits from the hello frame and announce their own with
+// max_message_size in theirs; the server only splits frames for clients that
+// did.
+
+type assembly struct {
+	next int
+	data strings.Builder
+}
+
+// assemble adds a part to its payload and returns the frame once all parts
+// arrived. ok is false when the part was rejected and reported.
+func (s *Server) assemble(client *Client, part *Message) (*Message, bool) {
+	if part.ID == "" {
+		s.sendError(client, ErrorInvalidPart, "part frames need an id")
+		return nil, false
+	}
+
+	payload, open := client.parts[part.ID]
+	if !open {
+		if part.Seq != 0 {
+			s.sendError(client, ErrorInvalidPart, fmt.Sprintf("payload %s does not start at part 0", part.ID))
+			return nil, false
+		}
+		if len(client.parts) >= maxOpenPayloads {
+			s.sendError(client, ErrorInvalidPart, fmt.Sprintf("too many payloads in progress, at most %d", maxOpenPayloads))
+			return nil, false
+		}
+		payload = &assembly{}
+		client.parts[part.ID] = payload
+	}
+
+	if part.Seq != payload.next {
+		delete(client.parts, part.ID)
+		s.sendError(client, ErrorInvalidPart, fmt.Sprintf("expected part %d of payload %s, got %d", payload.next, part.ID, part.Seq))
+		return nil, false
+	}
+	if payload.data.Len()+len(part.Data) > s.maxPayload {
+		delete(client.parts, part.ID)
+		s.sendError(client, ErrorPayloadTooLarge, fmt.Sprintf("payload %s exceeds %d bytes", part.ID, s.maxPayload))
+		return nil, false
+	}
+	payload.next++
+	payload.data.WriteString(part.Data)
+
+	if !part.Final {
+		return nil, true
+	}
+	delete(client.parts, part.ID)
+
+	var frame Message
+	if err := json.Unmarshal([]byte(payload.data.String()), &frame); err != nil {
+		s.sendError(client, ErrorInvalidJSON, fmt.Sprintf("payload %s: %v", part.ID, err))
+		return nil, false
+	}
+	if frame.Type == FramePart {
+		s.sendError(client, ErrorInvalidPart, "parts cannot be nested")
+		return nil, false
+	}
+	return &frame, true
+}
+
+// split breaks an encoded frame into part frames when it is larger than the
+// client accepts.
+func (c *Client) split(data []byte) [][]byte {
+	c.mu.Lock()
+	limit := c.maxMessageSize
+	if limit == 0 || len(data) <= limit {
+		c.mu.Unlock()
+		return [][]byte{data}
+	}
+	c.partSeq++
+	id := fmt.Sprintf("part-%d", c.partSeq)
+	c.mu.Unlock()
+
+	pieces := splitEscaped(string(data), limit-partOverhead)
+	parts := make([][]byte, 0, len(pieces))
+	for i, piece := range pieces {
+		part, err := json.Marshal(&Message{Type: FramePart, ID: id, Seq: i, Final: i == len(pieces)-1, Data: piece})
+		if err != nil {
+			return [][]byte{data}
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// splitEscaped cuts s at rune boundaries into pieces that take at most size
+// bytes once escaped as a JSON string.
+func splitEscaped(s string, size int) []string {
+	var pieces []string
+	start, used := 0, 0
+	for i, r := range s {
+		cost := escapedLen(r)
+		if used+cost > size && i > start {
+			pieces = append(pieces, s[start:i])
+			start, used = i, 0
+		}
+		used += cost
+	}
+	return append(pieces, s[start:])
+}
+
+// escapedLen is how many bytes encoding/json writes for r inside a string.
+func escapedLen(r rune) int {
+	switch {
+	case r == '"' || r == '\\' || r == '\n' || r == '\r' || r == '\t':
+		return 2
+	case r < 0x20 || r == '<' || r == '>' || r == '&' || r == '\u2028' || r == '\u2029' || r == utf8.RuneError:
+		return 6
+	}
+	return utf8.RuneLen(r)
+}
@@ -0,0 +1,102 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSplitEscaped(t *testing.T) {
+	text := strings.Repeat(`héllo "wörld" <b>&</b> 日本語`+"\n", 50)
+	pieces := splitEscaped(text, 100)
+	if len(pieces) < 2 {
+		t.Fatalf("Expected several pieces, got %d", len(pieces))
+	}
+	for _, piece := range pieces {
+		encoded, _ := json.Marshal(piece)
+		if len(encoded)-2 > 100 {
+			t.Errorf("Expected each piece to fit 100 bytes escaped, got %d", len(encoded)-2)
+		}
+	}
+	if strings.Join(pieces, "") != text {
+		t.Error("Expected pieces to join back into the text")
+	}
+}
+
+func TestPayloadsInParts(t *testing.T) {
+	h := newProtocolHarness(t, &Config{MaxMessageSize: 2048, MaxPayloadSize: 8192})
+	conn := h.dial("")
+
+	conn.WriteJSON(Message{Type: "hello", Version: 2, MaxMessageSize: 1024})
+	hello, err := read(conn)
+	if err != nil || hello.MaxMessageSize != 2048 || hello.MaxPayloadSize != 8192 {
+		t.Fatalf("Expected hello frame with the server's limits, got %+v %v", hello, err)
+	}
+
+	// A message larger than the read limit, sent in parts.
+	content := strings.Repeat("lorem ipsum ", 400)
+	frame, _ := json.Marshal(Message{Type: "message", Content: content})
+	pieces := splitEscaped(string(frame), 1500)
+	for i, piece := range pieces {
+		conn.WriteJSON(Message{Type: "part", ID: "p1", Seq: i, Final: i == len(pieces)-1, Data: piece})
+	}
+	request := h.nextRequest()
+	if request.Content != content {
+		t.Fatalf("Expected the reassembled message on the bus, got %d bytes", len(request.Content))
+	}
+
+	// A reply larger than the client accepts comes back in parts.
+	answer := strings.Repeat("dolor sit amet ", 300)
+	h.reply(request, answer, nil)
+	var data strings.Builder
+	for seq := 0; ; seq++ {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Expected part %d, got %v", seq, err)
+		}
+		if len(raw) > 1024 {
+			t.Errorf("Expected parts within the client's limit, got %d bytes", len(raw))
+		}
+		var part Message
+		json.Unmarshal(raw, &part)
+		if part.Type != "part" || part.Seq != seq {
+			t.Fatalf("Expected part %d, got %+v", seq, part)
+		}
+		data.WriteString(part.Data)
+		if part.Final {
+			break
+		}
+	}
+	var response Message
+	if err := json.Unmarshal([]byte(data.String()), &response); err != nil || response.Type != "response" || response.Content != answer {
+		t.Errorf("Expected the response once the parts are joined, got %+v %v", response, err)
+	}
+}
+
+func TestPartErrors(t *testing.T) {
+	h := newProtocolHarness(t, &Config{MaxMessageSize: 2048, MaxPayloadSize: 4096})
+	conn := h.dial("?version=2")
+
+	conn.WriteJSON(Message{Type: "part", ID: "p1", Seq: 0, Data: `{"type":`})
+	conn.WriteJSON(Message{Type: "part", ID: "p1", Seq: 2, Data: `"message"}`})
+	if msg, err := read(conn); err != nil || msg.Code != ErrorInvalidPart {
+		t.Errorf("Expected invalid_part for a missing part, got %+v %v", msg, err)
+	}
+
+	for seq := 0; seq < 3; seq++ {
+		conn.WriteJSON(Message{Type: "part", ID: "p2", Seq: seq, Data: strings.Repeat("x", 1500)})
+	}
+	if msg, err := read(conn); err != nil || msg.Code != ErrorPayloadTooLarge {
+		t.Errorf("Expected payload_too_large, got %+v %v", msg, err)
+	}
+
+	conn.WriteJSON(Message{Type: "message", Content: strings.Repeat("x", 4096)})
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseMessageTooBig {
+		t.Errorf("Expected the connection to close with 1009 for a message over the limit, got %v", err)
+	}
+}
@@ -0,0 +1,287 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+// Protocol versions. Version 1 clients send {"type":"message"} and only
+// receive final responses. Version 2 clients also get streamed chunks, tool
+// events, errors with codes, and can control their session. Clients opt in
+// with ?version=2 on the handshake or a hello frame.
+const (
+	ProtocolV1     = 1
+	ProtocolV2     = 2
+	LatestProtocol = ProtocolV2
+)
+
+// Frame types.
+const (
+	// Sent by clients.
+	FrameHello       = "hello"
+	FrameMessage     = "message"
+	FrameNew         = "new"
+	FrameSetChat     = "set_chat"
+	FrameClear       = "clear"
+	FrameSwitchModel = "switch_model"
+
+	// Sent by both; see parts.go.
+	FramePart = "part"
+
+	// Sent by the server.
+	FrameResponse     = "response"
+	FrameChunk        = "chunk"
+	FrameToolStarted  = "tool_started"
+	FrameToolFinished = "tool_finished"
+	FrameError        = "error"
+	FrameSession      = "session"
+)
+
+// Error codes of error frames, besides the bus.Error codes of failed
+// replies.
+const (
+	ErrorInvalidJSON        = "invalid_json"
+	ErrorUnknownType        = "unknown_type"
+	ErrorEmptyMessage       = "empty_message"
+	ErrorUnsupportedVersion = "unsupported_version"
+	ErrorUnauthorized       = "unauthorized"
+	ErrorPublishFailed      = "publish_failed"
+	ErrorInvalidPart        = "invalid_part"
+	ErrorPayloadTooLarge    = "payload_too_large"
+)
+
+// maxStreams bounds how many responses a client remembers. Finished ones
+// are kept so that chunks the bus delivers after the response are dropped.
+const maxStreams = 64
+
+type stream struct {
+	sent string
+	done bool
+}
+
+func handshakeVersion(r *http.Request) int {
+	version, err := strconv.Atoi(r.URL.Query().Get("version"))
+	if err != nil || version < ProtocolV1 {
+		return ProtocolV1
+	}
+	return min(version, LatestProtocol)
+}
+
+func (c *Client) protocol() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// handleFrame acts on a frame from the client. It returns false when the
+// connection should be closed.
+func (s *Server) handleFrame(client *Client, msg *Message) bool {
+	switch msg.Type {
+	case FrameHello:
+		if msg.Version < ProtocolV1 || msg.Version > LatestProtocol {
+			s.sendError(client, ErrorUnsupportedVersion, fmt.Sprintf("protocol version %d is not supported, the latest is %d", msg.Version, LatestProtocol))
+			return true
+		}
+		client.mu.Lock()
+		client.version = msg.Version
+		if msg.MaxMessageSize > 0 {
+			client.maxMessageSize = max(msg.MaxMessageSize, minClientMessageSize)
+		}
+		client.mu.Unlock()
+		s.sendSession(client, FrameHello)
+
+	case FramePart:
+		frame, ok := s.assemble(client, msg)
+		if !ok || frame == nil {
+			return true
+		}
+		return s.handleFrame(client, frame)
+
+	case FrameNew:
+		return s.publish(client, strings.TrimSpace("/new "+msg.Content), msg)
+
+	case FrameClear:
+		return s.publish(client, "/new", msg)
+
+	case FrameMessage:
+		if msg.Content == "" {
+			s.sendError(client, ErrorEmptyMessage, "message content is empty")
+			return true
+		}
+		return s.publish(client, msg.Content, msg)
+
+	case FrameSetChat:
+		if msg.ChatID == "" {
+			s.sendError(client, ErrorEmptyMessage, "chat_id is empty")
+			return true
+		}
+		client.mu.Lock()
+		client.chatID = client.resolveChatID(msg.ChatID)
+		client.mu.Unlock()
+		s.sendSession(client, FrameSession)
+
+	case FrameSwitchModel:
+		client.mu.Lock()
+		client.model = msg.Model
+		client.mu.Unlock()
+		s.sendSession(client, FrameSession)
+
+	default:
+		s.sendError(client, ErrorUnknownType, fmt.Sprintf("unknown frame type %q", msg.Type))
+	}
+	return true
+}
+
+func (s *Server) publish(client *Client, content string, msg *Message) bool {
+	if client.authRequest != nil {
+		if _, _, err := s.authorize(client.authRequest); err != nil {
+			s.logger.Warn("Closing WebSocket client", "chat_id", client.chatID, "error", err)
+			s.sendError(client, ErrorUnauthorized, err.Error())
+			return false
+		}
+	}
+
+	client.mu.Lock()
+	if msg.ChatID != "" {
+		client.chatID = client.resolveChatID(msg.ChatID)
+	}
+	chatID, version, model := client.chatID, client.version, client.model
+	client.mu.Unlock()
+
+	s.logger.Info("Message received", "chat_id", chatID, "preview", logging.Preview(content, 40))
+
+	metadata := map[string]interface{}{
+		bus.MetadataConnection: client.id,
+	}
+	if msg.MaxLength > 0 || msg.Width > 0 {
+		metadata[bus.MetadataCapabilities] = bus.Capabilities{MaxMessageLength: msg.MaxLength, LineWidth: msg.Width}
+	}
+	if client.user != nil {
+		metadata[bus.MetadataUser] = *client.user
+	}
+	if version >= ProtocolV2 {
+		metadata[bus.MetadataStreaming] = true
+		metadata[bus.MetadataToolEvents] = true
+	}
+	if model != "" {
+		metadata[bus.MetadataModel] = model
+	}
+
+	busMsg := &bus.Message{
+		ID:       fmt.Sprintf("websocket-%d", time.Now().UnixNano()),
+		Channel:  bus.ChannelWebSocket,
+		ChatID:   chatID,
+		Content:  content,
+		Metadata: metadata,
+	}
+	if err := s.messageBus.Publish(s.ctx, bus.ChannelWebSocket, busMsg); err != nil {
+		s.logger.Error("Failed to publish message to bus", "chat_id", chatID, "error", err)
+		s.sendError(client, ErrorPublishFailed, "failed to queue the message, please try again")
+	}
+	return true
+}
+
+// frame is what the client gets for a reply, or nil when its protocol
+// version has no frame for it.
+func (c *Client) frame(msg *bus.Message) *Message {
+	version := c.protocol()
+
+	if event := msg.ToolEvent(); event != nil {
+		if version < ProtocolV2 || c.finished(msg.ID) {
+			return nil
+		}
+		frameType := FrameToolStarted
+		if event.Phase == bus.ToolFinished {
+			frameType = FrameToolFinished
+		}
+		tool := event.Tool
+		return &Message{Type: frameType, ID: msg.ID, ChatID: msg.ChatID, Tool: &tool}
+	}
+
+	if msg.IsPartial() {
+		if version < ProtocolV2 {
+			return nil
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		s, ok := c.streams[msg.ID]
+		if s.done || ok && len(msg.Content) <= len(s.sent) {
+			return nil
+		}
+		c.track(msg.ID, stream{sent: msg.Content})
+		sent := s.sent
+		if !strings.HasPrefix(msg.Content, sent) {
+			// The text so far changed; the client replaces it.
+			return &Message{Type: FrameChunk, ID: msg.ID, ChatID: msg.ChatID, Content: msg.Content}
+		}
+		return &Message{Type: FrameChunk, ID: msg.ID, ChatID: msg.ChatID, Delta: msg.Content[len(sent):]}
+	}
+
+	if version >= ProtocolV2 {
+		c.mu.Lock()
+		c.track(msg.ID, stream{done: true})
+		c.mu.Unlock()
+
+		if code := msg.ErrorCode(); code != "" {
+			return &Message{Type: FrameError, ID: msg.ID, ChatID: msg.ChatID, Code: code, Content: msg.Content}
+		}
+		return &Message{Type: FrameResponse, ID: msg.ID, ChatID: msg.ChatID, Content: msg.Content, Tools: msg.ToolUses()}
+	}
+	return &Message{Type: FrameResponse, ChatID: msg.ChatID, Content: msg.Content, Tools: msg.ToolUses()}
+}
+
+func (c *Client) finished(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.streams[id].done
+}
+
+// track records the state of a response, forgetting finished ones when
+// there are too many. c.mu must be held.
+func (c *Client) track(id string, s stream) {
+	if _, ok := c.streams[id]; !ok && len(c.streams) >= maxStreams {
+		for other, old := range c.streams {
+			if old.done {
+				delete(c.streams, other)
+			}
+		}
+	}
+	c.streams[id] = s
+}
+
+func (s *Server) sendSession(client *Client, frameType string) {
+	client.mu.Lock()
+	frame := &Message{Type: frameType, Version: client.version, ChatID: client.chatID, Model: client.model, MaxMessageSize: s.readLimit, MaxPayloadSize: s.maxPayload}
+	client.mu.Unlock()
+	s.sendFrame(client, frame)
+}
+
+// sendError reports a problem with a frame. Version 1 clients are not told,
+// as before.
+func (s *Server) sendError(client *Client, code, message string) {
+	if client.protocol() < ProtocolV2 {
+		return
+	}
+	s.sendFrame(client, &Message{Type: FrameError, Code: code, Content: message})
+}
+
+func (s *Server) sendFrame(client *Client, frame *Message) {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		s.logger.Warn("Failed to marshal frame", "type", frame.Type, "error", err)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+		s.logger.Warn("Client send buffer full, dropping frame", "connection", client.id, "type", frame.Type)
+	}
+}
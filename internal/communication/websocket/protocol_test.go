@@ -0,0 +1,212 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+type protocolHarness struct {
+	t          *testing.T
+	ctx        context.Context
+	messageBus *bus.InMemoryMessageBus
+	requests   chan *bus.Message
+	url        string
+}
+
+func newProtocolHarness(t *testing.T, cfg *Config) *protocolHarness {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	t.Cleanup(func() { messageBus.Close() })
+
+	server := NewServer(cfg, messageBus, ctx)
+	go server.run()
+
+	h := &protocolHarness{t: t, ctx: ctx, messageBus: messageBus, requests: make(chan *bus.Message, 8)}
+	messageBus.Subscribe(bus.ChannelWebSocket, func(ctx context.Context, msg *bus.Message) error {
+		if !msg.IsReply() {
+			h.requests <- msg
+		}
+		return nil
+	})
+	messageBus.Subscribe(bus.ChannelWebSocket, NewHandler(server).HandleMessage)
+
+	httpServer := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	t.Cleanup(httpServer.Close)
+	h.url = "ws" + strings.TrimPrefix(httpServer.URL, "http")
+	return h
+}
+
+func (h *protocolHarness) dial(query string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(h.url+query, nil)
+	if err != nil {
+		h.t.Fatalf("Failed to connect: %v", err)
+	}
+	h.t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func (h *protocolHarness) nextRequest() *bus.Message {
+	select {
+	case msg := <-h.requests:
+		return msg
+	case <-time.After(2 * time.Second):
+		h.t.Fatal("Expected message to reach the bus")
+		return nil
+	}
+}
+
+func (h *protocolHarness) reply(request *bus.Message, content string, metadata map[string]interface{}) {
+	response := &bus.Message{ID: "agent-" + request.ID, Channel: bus.ChannelWebSocket, ChatID: request.ChatID, Content: content, Metadata: metadata}
+	response.SetReplyTo(request)
+	h.messageBus.Publish(h.ctx, bus.ChannelWebSocket, response)
+}
+
+func read(conn *websocket.Conn) (Message, error) {
+	var msg Message
+	conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	err := conn.ReadJSON(&msg)
+	return msg, err
+}
+
+func TestProtocolV1ClientsKeepOldBehaviour(t *testing.T) {
+	h := newProtocolHarness(t, nil)
+	conn := h.dial("")
+
+	conn.WriteJSON(Message{Type: "message", Content: ""})
+	conn.WriteJSON(Message{Type: "subscribe"})
+	conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+	conn.WriteJSON(Message{Type: "message", Content: "hello"})
+	request := h.nextRequest()
+	if request.WantsStreaming() || request.WantsToolEvents() {
+		t.Errorf("Expected version 1 request not to ask for chunks or tool events, got %+v", request.Metadata)
+	}
+
+	h.reply(request, "Hel", map[string]interface{}{bus.MetadataPartial: true})
+	h.reply(request, "", map[string]interface{}{bus.MetadataToolEvent: &bus.ToolEvent{Phase: bus.ToolStarted, Tool: bus.ToolUse{Name: "read_file"}}})
+	time.Sleep(50 * time.Millisecond)
+	h.reply(request, "Hello", nil)
+
+	// Error frames for the bad frames above, chunks or tool events would
+	// come first.
+	msg, err := read(conn)
+	if err != nil || msg.Type != FrameResponse || msg.Content != "Hello" || msg.ID != "" {
+		t.Errorf("Expected only the final response, got %+v %v", msg, err)
+	}
+}
+
+func TestProtocolV2Frames(t *testing.T) {
+	h := newProtocolHarness(t, nil)
+	conn := h.dial("?version=2")
+
+	conn.WriteJSON(Message{Type: "message", Content: "hello"})
+	request := h.nextRequest()
+	if !request.WantsStreaming() || !request.WantsToolEvents() {
+		t.Fatalf("Expected version 2 request to ask for chunks and tool events, got %+v", request.Metadata)
+	}
+
+	id := "agent-" + request.ID
+	for _, step := range []struct {
+		reply    string
+		metadata map[string]interface{}
+		want     Message
+	}{
+		{"Hel", map[string]interface{}{bus.MetadataPartial: true}, Message{Type: FrameChunk, Delta: "Hel"}},
+		{"Hello wor", map[string]interface{}{bus.MetadataPartial: true}, Message{Type: FrameChunk, Delta: "lo wor"}},
+		{"", map[string]interface{}{bus.MetadataToolEvent: &bus.ToolEvent{Phase: bus.ToolStarted, Tool: bus.ToolUse{Name: "read_file", Input: "notes.md"}}}, Message{Type: FrameToolStarted}},
+		{"", map[string]interface{}{bus.MetadataToolEvent: &bus.ToolEvent{Phase: bus.ToolFinished, Tool: bus.ToolUse{Name: "read_file", Input: "notes.md", Output: "ok"}}}, Message{Type: FrameToolFinished}},
+		{"Hello world", nil, Message{Type: FrameResponse, Content: "Hello world"}},
+	} {
+		h.reply(request, step.reply, step.metadata)
+		want := step.want
+		msg, err := read(conn)
+		if err != nil {
+			t.Fatalf("Expected %s frame, got %v", want.Type, err)
+		}
+		if msg.Type != want.Type || msg.ID != id || msg.Delta != want.Delta || msg.Content != want.Content {
+			t.Errorf("Expected %+v, got %+v", want, msg)
+		}
+		if strings.HasPrefix(msg.Type, "tool_") && (msg.Tool == nil || msg.Tool.Name != "read_file") {
+			t.Errorf("Expected tool in %s frame, got %+v", msg.Type, msg.Tool)
+		}
+	}
+
+	// A chunk the bus delivers after the response is dropped.
+	h.reply(request, "Hello world!", map[string]interface{}{bus.MetadataPartial: true})
+
+	conn.WriteJSON(Message{Type: "message", Content: "again"})
+	request = h.nextRequest()
+	h.reply(request, "Sorry, I encountered an error.", map[string]interface{}{bus.MetadataErrorCode: bus.ErrorInternal})
+	if msg, err := read(conn); err != nil || msg.Type != FrameError || msg.Code != bus.ErrorInternal || msg.ID != "agent-"+request.ID {
+		t.Errorf("Expected error frame for a failed reply, got %+v %v", msg, err)
+	}
+}
+
+func TestProtocolHelloAndSessionControl(t *testing.T) {
+	h := newProtocolHarness(t, nil)
+	conn := h.dial("")
+
+	conn.WriteJSON(Message{Type: "hello", Version: 3})
+	conn.WriteJSON(Message{Type: "hello", Version: 2})
+	if msg, err := read(conn); err != nil || msg.Type != FrameHello || msg.Version != 2 {
+		t.Fatalf("Expected hello frame for version 2, got %+v %v", msg, err)
+	}
+
+	conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+	if msg, err := read(conn); err != nil || msg.Type != FrameError || msg.Code != ErrorInvalidJSON {
+		t.Errorf("Expected invalid_json error, got %+v %v", msg, err)
+	}
+	conn.WriteJSON(Message{Type: "subscribe"})
+	if msg, err := read(conn); err != nil || msg.Code != ErrorUnknownType {
+		t.Errorf("Expected unknown_type error, got %+v %v", msg, err)
+	}
+	conn.WriteJSON(Message{Type: "message"})
+	if msg, err := read(conn); err != nil || msg.Code != ErrorEmptyMessage {
+		t.Errorf("Expected empty_message error, got %+v %v", msg, err)
+	}
+
+	conn.WriteJSON(Message{Type: "set_chat", ChatID: "work"})
+	if msg, err := read(conn); err != nil || msg.Type != FrameSession || msg.ChatID != "work" {
+		t.Errorf("Expected session frame for the new chat, got %+v %v", msg, err)
+	}
+	conn.WriteJSON(Message{Type: "switch_model", Model: "fast"})
+	if msg, err := read(conn); err != nil || msg.Type != FrameSession || msg.Model != "fast" || msg.ChatID != "work" {
+		t.Errorf("Expected session frame for the new model, got %+v %v", msg, err)
+	}
+
+	conn.WriteJSON(Message{Type: "message", Content: "hello"})
+	request := h.nextRequest()
+	if request.ChatID != "work" || request.Model() != "fast" {
+		t.Errorf("Expected request in the chosen chat and model, got %s %q", request.ChatID, request.Model())
+	}
+
+	conn.WriteJSON(Message{Type: "clear"})
+	if request := h.nextRequest(); request.Content != "/new" || request.ChatID != "work" {
+		t.Errorf("Expected clear to start a new conversation, got %+v", request)
+	}
+}
+
+func TestHandshakeVersion(t *testing.T) {
+	tests := map[string]int{
+		"":           ProtocolV1,
+		"?version=1": ProtocolV1,
+		"?version=2": ProtocolV2,
+		"?version=9": LatestProtocol,
+		"?version=x": ProtocolV1,
+	}
+	for query, want := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/ws"+query, nil)
+		if got := handshakeVersion(r); got != want {
+			t.Errorf("handshakeVersion(%q) = %d, want %d", query, got, want)
+		}
+	}
+}
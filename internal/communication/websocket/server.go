@@ -3,14 +3,22 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/wjffsx/miniclaw_go/internal/auth"
 	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 )
 
 const (
@@ -18,17 +26,9 @@ const (
 	writeWait         = 10 * time.Second
 	pongWait          = 60 * time.Second
 	pingPeriod        = (pongWait * 9) / 10
-	maxMessageSize    = 512
+	shutdownTimeout   = 5 * time.Second
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
 type WebSocketConn interface {
 	SetReadLimit(limit int64)
 	ReadMessage() (messageType int, p []byte, err error)
@@ -40,11 +40,26 @@ type WebSocketConn interface {
 }
 
 type Client struct {
-	conn   WebSocketConn
-	chatID string
-	send   chan []byte
-	server *Server
-	mu     sync.Mutex
+	id          string
+	conn        WebSocketConn
+	chatID      string
+	send        chan []byte
+	server      *Server
+	mu          sync.Mutex
+	authRequest *http.Request
+	user        *bus.User
+	version     int
+	model       string
+	// streams holds the text of each response streamed so far, to send
+	// chunks as deltas.
+	streams map[string]stream
+	// maxMessageSize is the largest message the client accepts, 0 for no
+	// limit; larger frames are sent in parts.
+	maxMessageSize int
+	partSeq        int
+	// parts holds the payloads the client is sending in parts. Only the
+	// read pump uses it.
+	parts map[string]*assembly
 }
 
 type Server struct {
@@ -53,28 +68,136 @@ type Server struct {
 	unregister chan *Client
 	broadcast  chan []byte
 	messageBus bus.MessageBus
+	auth       *auth.Authenticator
+	upgrader   websocket.Upgrader
+	maxClients int
+	origins    map[string]bool
+	host       string
+	certFile   string
+	keyFile    string
+	pathPrefix string
+	ui         bool
+	// readLimit and maxPayload are the largest message read from a client
+	// and the largest frame it may send in parts.
+	readLimit  int
+	maxPayload int
+	proxies    []*net.IPNet
+	httpServer *http.Server
+	listener   net.Listener
 	ctx        context.Context
 	cancel     context.CancelFunc
 	wg         sync.WaitGroup
 	mu         sync.RWMutex
 	started    bool
+	logger     *slog.Logger
+	nextConnID atomic.Uint64
 }
 
+// Message is a frame in either direction; see protocol.go for the types.
 type Message struct {
-	Type    string `json:"type"`
-	Content string `json:"content"`
-	ChatID  string `json:"chat_id,omitempty"`
+	Type    string        `json:"type"`
+	Content string        `json:"content"`
+	ChatID  string        `json:"chat_id,omitempty"`
+	Tools   []bus.ToolUse `json:"tools,omitempty"`
+	// MaxLength and Width let clients with small screens ask for paged,
+	// narrower replies.
+	MaxLength int `json:"max_length,omitempty"`
+	Width     int `json:"width,omitempty"`
+
+	Version int `json:"version,omitempty"`
+	// ID ties chunks, tool events and errors to the response they belong
+	// to.
+	ID    string       `json:"id,omitempty"`
+	Delta string       `json:"delta,omitempty"`
+	Tool  *bus.ToolUse `json:"tool,omitempty"`
+	Code  string       `json:"code,omitempty"`
+	Model string       `json:"model,omitempty"`
+
+	// Limits, announced in hello frames.
+	MaxMessageSize int `json:"max_message_size,omitempty"`
+	MaxPayloadSize int `json:"max_payload_size,omitempty"`
+
+	// Part frames.
+	Seq   int    `json:"seq,omitempty"`
+	Final bool   `json:"final,omitempty"`
+	Data  string `json:"data,omitempty"`
 }
 
 type Config struct {
+	// Host is the address to bind; empty binds every interface.
+	Host       string
 	Port       int
 	MaxClients int
+	// AllowedOrigins lists the browser origins that may connect, "*" allows
+	// any. When empty only same-origin pages and non-browser clients can.
+	AllowedOrigins []string
+	// TLSCertFile and TLSKeyFile serve wss:// directly when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// PathPrefix serves the endpoint under a path, such as "/ws" behind a
+	// reverse proxy that routes by path.
+	PathPrefix string
+	// TrustedProxies are the IPs or CIDRs of reverse proxies whose
+	// X-Forwarded-For and X-Forwarded-Host headers are believed.
+	TrustedProxies []string
+	// MaxMessageSize is the largest WebSocket message read from a client;
+	// larger ones close the connection with 1009. MaxPayloadSize bounds
+	// frames clients send in parts.
+	MaxMessageSize int
+	MaxPayloadSize int
+	// UI serves the built-in chat page at <path prefix>/ui.
+	UI     bool
+	Auth   *auth.Authenticator
+	Logger *slog.Logger
 }
 
 func NewServer(cfg *Config, messageBus bus.MessageBus, ctx context.Context) *Server {
 	serverCtx, cancel := context.WithCancel(ctx)
 
-	return &Server{
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	maxClients := defaultMaxClients
+	if cfg.MaxClients > 0 {
+		maxClients = cfg.MaxClients
+	}
+	maxMessageSize := defaultMaxMessageSize
+	if cfg.MaxMessageSize > 0 {
+		maxMessageSize = cfg.MaxMessageSize
+	}
+	maxPayloadSize := max(defaultMaxPayloadSize, maxMessageSize)
+	if cfg.MaxPayloadSize > 0 {
+		maxPayloadSize = cfg.MaxPayloadSize
+	}
+	origins := make(map[string]bool)
+	for _, origin := range cfg.AllowedOrigins {
+		origins[strings.ToLower(strings.TrimRight(origin, "/"))] = true
+	}
+	logger := logging.Or(cfg.Logger, "websocket")
+
+	var proxies []*net.IPNet
+	for _, proxy := range cfg.TrustedProxies {
+		network, err := parseNetwork(proxy)
+		if err != nil {
+			logger.Warn("Ignoring invalid trusted proxy", "proxy", proxy, "error", err)
+			continue
+		}
+		proxies = append(proxies, network)
+	}
+
+	s := &Server{
+		auth:       cfg.Auth,
+		maxClients: maxClients,
+		origins:    origins,
+		host:       cfg.Host,
+		certFile:   cfg.TLSCertFile,
+		keyFile:    cfg.TLSKeyFile,
+		pathPrefix: "/" + strings.Trim(cfg.PathPrefix, "/"),
+		ui:         cfg.UI,
+		readLimit:  maxMessageSize,
+		maxPayload: maxPayloadSize,
+		proxies:    proxies,
 		clients:    make(map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
@@ -82,30 +205,80 @@ func NewServer(cfg *Config, messageBus bus.MessageBus, ctx context.Context) *Ser
 		messageBus: messageBus,
 		ctx:        serverCtx,
 		cancel:     cancel,
+		logger:     logger,
 	}
+	s.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     s.checkOrigin,
+	}
+	return s
+}
+
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || s.origins["*"] || s.origins[strings.ToLower(origin)] {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, s.requestHost(r))
+}
+
+// Handler serves the WebSocket endpoint, and the chat UI when enabled,
+// under the path prefix.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(s.pathPrefix, s.handleWebSocket)
+	if s.pathPrefix != "/" {
+		mux.HandleFunc(s.pathPrefix+"/", s.handleWebSocket)
+	}
+	if s.ui {
+		mux.HandleFunc(s.uiPath(), s.handleUI)
+		mux.HandleFunc(s.uiPath()+"/", s.handleUI)
+	}
+	return mux
 }
 
 func (s *Server) Start(port int) error {
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.started {
-		s.mu.Unlock()
 		return fmt.Errorf("server already started")
 	}
-	s.started = true
-	s.mu.Unlock()
 
-	log.Printf("Starting WebSocket server on port %d...", port)
+	addr := net.JoinHostPort(s.host, fmt.Sprintf("%d", port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s.started = true
+	s.listener = listener
+	s.httpServer = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 
 	go s.run()
 
-	addr := fmt.Sprintf(":%d", port)
-	log.Printf("WebSocket server listening on %s", addr)
+	useTLS := s.certFile != "" && s.keyFile != ""
+	s.logger.Info("WebSocket server listening", "addr", addr, "path", s.pathPrefix, "tls", useTLS)
+	if s.ui {
+		s.logger.Info("Chat UI enabled", "path", s.uiPath())
+	}
 
+	server := s.httpServer
 	go func() {
-		mux := http.NewServeMux()
-		mux.HandleFunc("/", s.handleWebSocket)
-		if err := http.ListenAndServe(addr, mux); err != nil && err != http.ErrServerClosed {
-			log.Printf("WebSocket server error: %v", err)
+		var err error
+		if useTLS {
+			err = server.ServeTLS(listener, s.certFile, s.keyFile)
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			s.logger.Error("WebSocket server error", "error", err)
 		}
 	}()
 
@@ -119,14 +292,101 @@ func (s *Server) Stop() error {
 		return nil
 	}
 	s.started = false
+	server, listener := s.httpServer, s.listener
+	s.httpServer, s.listener = nil, nil
 	s.mu.Unlock()
 
-	log.Println("Stopping WebSocket server...")
+	s.logger.Info("Stopping WebSocket server")
+
+	// Shutdown does not wait for hijacked connections; cancelling the
+	// context closes them.
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		s.logger.Warn("Failed to shut down WebSocket server", "error", err)
+	}
+	// Shutdown misses the listener when Serve has not started yet.
+	listener.Close()
+
 	s.cancel()
 	s.wg.Wait()
 	return nil
 }
 
+func parseNetwork(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		return network, err
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP address or CIDR")
+	}
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func (s *Server) trusted(ip net.IP) bool {
+	for _, network := range s.proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) fromTrustedProxy(r *http.Request) bool {
+	if len(s.proxies) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && s.trusted(ip)
+}
+
+// clientIP is the address of the client. Behind trusted proxies it is the
+// last X-Forwarded-For entry that is not itself a trusted proxy.
+func (s *Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !s.fromTrustedProxy(r) {
+		return host
+	}
+
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+		host = ip.String()
+		if !s.trusted(ip) {
+			break
+		}
+	}
+	return host
+}
+
+// requestHost is the host the client connected to, as forwarded by a
+// trusted proxy.
+func (s *Server) requestHost(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" && s.fromTrustedProxy(r) {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	return r.Host
+}
+
 func (s *Server) run() {
 	s.wg.Add(1)
 	defer s.wg.Done()
@@ -134,13 +394,13 @@ func (s *Server) run() {
 	for {
 		select {
 		case <-s.ctx.Done():
-			log.Println("WebSocket server stopped")
+			s.logger.Info("WebSocket server stopped")
 			return
 		case client := <-s.register:
 			s.mu.Lock()
 			s.clients[client] = true
 			s.mu.Unlock()
-			log.Printf("Client connected: %s", client.chatID)
+			s.logger.Info("Client connected", "chat_id", client.chatID)
 
 		case client := <-s.unregister:
 			if _, ok := s.clients[client]; ok {
@@ -148,7 +408,7 @@ func (s *Server) run() {
 				delete(s.clients, client)
 				s.mu.Unlock()
 				close(client.send)
-				log.Printf("Client disconnected: %s", client.chatID)
+				s.logger.Info("Client disconnected", "chat_id", client.chatID)
 			}
 
 		case message := <-s.broadcast:
@@ -167,17 +427,56 @@ func (s *Server) run() {
 }
 
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	if count := s.GetClientCount(); count >= s.maxClients {
+		s.logger.Warn("WebSocket handshake rejected, too many clients", "remote_addr", s.clientIP(r), "clients", count)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	if !s.checkOrigin(r) {
+		s.logger.Warn("WebSocket handshake rejected, origin not allowed", "remote_addr", s.clientIP(r), "origin", r.Header.Get("Origin"))
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	var authRequest *http.Request
+	var user *bus.User
+	if s.auth != nil && s.auth.Enabled() {
+		identity, status, err := s.authorize(r)
+		if err != nil {
+			s.logger.Warn("WebSocket handshake rejected", "remote_addr", s.clientIP(r), "error", err)
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		authRequest = r.Clone(context.Background())
+		user = &bus.User{
+			ID:    identity.Provider + ":" + identity.Subject,
+			Name:  identity.Name,
+			Admin: identity.HasScope(auth.ScopeAdmin),
+		}
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		s.logger.Error("WebSocket upgrade error", "error", err)
 		return
 	}
 
 	client := &Client{
+		id:     s.newConnectionID(),
 		conn:   conn,
 		send:   make(chan []byte, 256),
 		server: s,
 		chatID: fmt.Sprintf("ws_%d", time.Now().UnixNano()),
+
+		authRequest: authRequest,
+		user:        user,
+		version:     handshakeVersion(r),
+		streams:     make(map[string]stream),
+		parts:       make(map[string]*assembly),
+	}
+	if user != nil {
+		client.chatID = userChatID(user)
 	}
 
 	s.register <- client
@@ -186,13 +485,58 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go s.readPump(client)
 }
 
+func (s *Server) newConnectionID() string {
+	return fmt.Sprintf("conn-%d", s.nextConnID.Add(1))
+}
+
+func (s *Server) authorize(r *http.Request) (*auth.Identity, int, error) {
+	identity, err := s.auth.Authenticate(r)
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	if !identity.HasScope(auth.ScopeChat) {
+		return nil, http.StatusForbidden, fmt.Errorf("%s lacks the %s scope", identity.Name, auth.ScopeChat)
+	}
+
+	return identity, http.StatusOK, nil
+}
+
+var unsafeChatIDChars = regexp.MustCompile(`[^A-Za-z0-9_.@-]+`)
+
+// userChatID gives an authenticated user the same chat on every connection.
+func userChatID(user *bus.User) string {
+	return "ws_" + unsafeChatIDChars.ReplaceAllString(user.ID, "_")
+}
+
+// subChatSeparator joins a user's chat ID and the name of one of their other
+// chats. userChatID never produces it, so no user's chat can be mistaken for a
+// sub-chat of another user whose ID is a prefix of theirs.
+const subChatSeparator = "~"
+
+// resolveChatID maps the chat_id a client asked for into its own namespace,
+// so authenticated users cannot read or write the chats of others. IDs the
+// server handed out before are used as they are.
+func (c *Client) resolveChatID(requested string) string {
+	if c.user == nil {
+		return requested
+	}
+
+	base := userChatID(c.user)
+	if requested == base {
+		return requested
+	}
+	name := strings.TrimPrefix(requested, base+subChatSeparator)
+	return base + subChatSeparator + unsafeChatIDChars.ReplaceAllString(name, "_")
+}
+
 func (s *Server) readPump(client *Client) {
 	defer func() {
 		s.unregister <- client
 		client.conn.Close()
 	}()
 
-	client.conn.SetReadLimit(maxMessageSize)
+	client.conn.SetReadLimit(int64(s.readLimit))
 	client.conn.SetReadDeadline(time.Now().Add(pongWait))
 	client.conn.SetPongHandler(func(string) error {
 		client.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -201,40 +545,32 @@ func (s *Server) readPump(client *Client) {
 
 	for {
 		_, message, err := client.conn.ReadMessage()
+		if errors.Is(err, websocket.ErrReadLimit) {
+			s.logger.Warn("Closing WebSocket client, message too large", "connection", client.id, "limit", s.readLimit)
+			break
+		}
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket read error: %v", err)
+				s.logger.Warn("WebSocket read error", "error", err)
 			}
 			break
 		}
 
 		var msg Message
 		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("Invalid JSON message: %v", err)
+			s.logger.Warn("Invalid JSON message", "error", err)
+			s.sendError(client, ErrorInvalidJSON, err.Error())
 			continue
 		}
 
-		if msg.Type == "message" && msg.Content != "" {
-			chatID := client.chatID
-			if msg.ChatID != "" {
-				chatID = msg.ChatID
-				client.mu.Lock()
-				client.chatID = chatID
-				client.mu.Unlock()
-			}
-
-			log.Printf("WS message from %s: %.40s...", chatID, msg.Content)
-
-			busMsg := &bus.Message{
-				ID:      fmt.Sprintf("websocket-%d", time.Now().UnixNano()),
-				Channel: bus.ChannelWebSocket,
-				ChatID:  chatID,
-				Content: msg.Content,
-			}
+		// Version 1 clients expect empty messages and unknown frames to be
+		// ignored.
+		if client.protocol() < ProtocolV2 && msg.Type != FrameHello && msg.Type != FrameNew && (msg.Type != FrameMessage || msg.Content == "") {
+			continue
+		}
 
-			if err := s.messageBus.Publish(s.ctx, bus.ChannelWebSocket, busMsg); err != nil {
-				log.Printf("Failed to publish message to bus: %v", err)
-			}
+		if !s.handleFrame(client, &msg) {
+			break
 		}
 	}
 }
@@ -257,9 +593,12 @@ func (s *Server) writePump(client *Client) {
 				return
 			}
 
-			if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("WebSocket write error: %v", err)
-				return
+			for _, part := range client.split(message) {
+				client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := client.conn.WriteMessage(websocket.TextMessage, part); err != nil {
+					s.logger.Warn("WebSocket write error", "error", err)
+					return
+				}
 			}
 
 		case <-ticker.C:
@@ -272,32 +611,58 @@ func (s *Server) writePump(client *Client) {
 }
 
 func (s *Server) SendToClient(chatID, text string) error {
+	return s.SendResponse(chatID, text, nil)
+}
+
+func (s *Server) SendResponse(chatID, text string, toolUses []bus.ToolUse) error {
+	return s.Deliver(&bus.Message{
+		Channel:  bus.ChannelWebSocket,
+		ChatID:   chatID,
+		Content:  text,
+		Metadata: map[string]interface{}{bus.MetadataToolUses: toolUses},
+	})
+}
+
+// Deliver sends a reply to the connection the request came in on and to
+// every other client currently in the same chat, so several tabs or devices
+// sharing a chat all see it.
+func (s *Server) Deliver(msg *bus.Message) error {
+	connection := msg.Connection()
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	delivered := 0
 	for client := range s.clients {
-		if client.chatID == chatID {
-			resp := Message{
-				Type:    "response",
-				Content: text,
-				ChatID:  chatID,
-			}
+		client.mu.Lock()
+		inChat := client.chatID == msg.ChatID
+		client.mu.Unlock()
+		if !inChat && (connection == "" || client.id != connection) {
+			continue
+		}
 
-			data, err := json.Marshal(resp)
-			if err != nil {
-				return fmt.Errorf("failed to marshal message: %w", err)
-			}
+		frame := client.frame(msg)
+		if frame == nil {
+			continue
+		}
+		data, err := json.Marshal(frame)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
 
-			select {
-			case client.send <- data:
-				return nil
-			default:
-				return fmt.Errorf("client send buffer full")
-			}
+		select {
+		case client.send <- data:
+			delivered++
+		default:
+			s.logger.Warn("Client send buffer full, dropping message", "connection", client.id, "chat_id", msg.ChatID)
 		}
 	}
 
-	return fmt.Errorf("client not found: %s", chatID)
+	// Chunks and tool events are only for clients that asked for them.
+	if delivered == 0 && !msg.IsPartial() && msg.ToolEvent() == nil {
+		return fmt.Errorf("client not found: %s", msg.ChatID)
+	}
+	return nil
 }
 
 func (s *Server) Broadcast(text string) error {
@@ -333,9 +698,13 @@ func (s *Server) IsRunning() bool {
 
 func NewClient(conn WebSocketConn, chatID string, server *Server) *Client {
 	return &Client{
-		conn:   conn,
-		chatID: chatID,
-		send:   make(chan []byte, 256),
-		server: server,
+		id:      server.newConnectionID(),
+		conn:    conn,
+		chatID:  chatID,
+		send:    make(chan []byte, 256),
+		server:  server,
+		version: ProtocolV1,
+		streams: make(map[string]stream),
+		parts:   make(map[string]*assembly),
 	}
 }
@@ -2,8 +2,15 @@ package websocket
 
 import (
 	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 func TestNewServer(t *testing.T) {
@@ -205,3 +212,140 @@ func (m *mockConn) SetPongHandler(h func(appData string) error) {}
 func (m *mockConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
 	return nil
 }
+
+func TestServerBindsHost(t *testing.T) {
+	server := NewServer(&Config{Host: "127.0.0.1"}, nil, context.Background())
+	if err := server.Start(8087); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp", "127.0.0.1:8087")
+	if err != nil {
+		t.Fatalf("Expected the server to listen on 127.0.0.1:8087: %v", err)
+	}
+	conn.Close()
+
+	other := NewServer(&Config{Host: "127.0.0.1"}, nil, context.Background())
+	if err := other.Start(8087); err == nil {
+		other.Stop()
+		t.Error("Expected an error when the address is in use")
+	}
+}
+
+func TestServerStopReleasesPort(t *testing.T) {
+	server := NewServer(&Config{Host: "127.0.0.1"}, nil, context.Background())
+	if err := server.Start(8088); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	server.Stop()
+
+	again := NewServer(&Config{Host: "127.0.0.1"}, nil, context.Background())
+	if err := again.Start(8088); err != nil {
+		t.Fatalf("Expected the port to be free after Stop, got %v", err)
+	}
+	again.Stop()
+}
+
+func TestServerPathPrefix(t *testing.T) {
+	server := NewServer(&Config{PathPrefix: "/ws/"}, nil, context.Background())
+	go server.run()
+	defer server.cancel()
+
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	base := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(base+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Expected to connect under the prefix: %v", err)
+	}
+	conn.Close()
+
+	if _, resp, err := websocket.DefaultDialer.Dial(base+"/", nil); err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 outside the prefix, got %v", resp)
+	}
+}
+
+func TestClientIPFromTrustedProxy(t *testing.T) {
+	server := NewServer(&Config{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}, nil, context.Background())
+
+	tests := []struct {
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{"203.0.113.7:5000", "", "203.0.113.7"},
+		{"203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"10.1.2.3:5000", "198.51.100.1", "198.51.100.1"},
+		{"192.168.1.1:5000", "1.2.3.4, 198.51.100.1, 10.0.0.5", "198.51.100.1"},
+		{"10.1.2.3:5000", "garbage", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := server.clientIP(r); got != tt.want {
+			t.Errorf("clientIP(%s, %q) = %s, want %s", tt.remote, tt.forwarded, got, tt.want)
+		}
+	}
+}
+
+func TestForwardedHostOriginCheck(t *testing.T) {
+	server := NewServer(&Config{TrustedProxies: []string{"10.0.0.1"}}, nil, context.Background())
+
+	r := httptest.NewRequest(http.MethodGet, "http://backend:18789/", nil)
+	r.Header.Set("Origin", "https://chat.example.com")
+	r.Header.Set("X-Forwarded-Host", "chat.example.com")
+
+	r.RemoteAddr = "203.0.113.7:5000"
+	if server.checkOrigin(r) {
+		t.Error("Expected X-Forwarded-Host from an untrusted client to be ignored")
+	}
+	r.RemoteAddr = "10.0.0.1:5000"
+	if !server.checkOrigin(r) {
+		t.Error("Expected X-Forwarded-Host from a trusted proxy to count as same origin")
+	}
+}
+
+func TestChatUI(t *testing.T) {
+	server := NewServer(&Config{PathPrefix: "/ws", UI: true}, nil, context.Background())
+	go server.run()
+	defer server.cancel()
+
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/ws/ui")
+	if err != nil {
+		t.Fatalf("Failed to get the UI: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || !strings.Contains(string(body), "<title>MiniClaw</title>") {
+		t.Errorf("Expected the chat page under the prefix, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if csp := resp.Header.Get("Content-Security-Policy"); !strings.Contains(csp, "connect-src 'self' ws://"+resp.Request.URL.Host) {
+		t.Errorf("Expected the page to be limited to this server, got %q", csp)
+	}
+
+	resp, err = http.Post(httpServer.URL+"/ws/ui/", "text/plain", nil)
+	if err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %v %v", resp, err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+"/ws?version=2", nil)
+	if err != nil {
+		t.Fatalf("Expected the endpoint to keep working next to the UI: %v", err)
+	}
+	conn.Close()
+
+	disabled := NewServer(&Config{}, nil, context.Background())
+	recorder := httptest.NewRecorder()
+	disabled.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ui", nil))
+	if recorder.Code == http.StatusOK {
+		t.Error("Expected no UI when it is disabled")
+	}
+}
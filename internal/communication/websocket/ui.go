@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// uiPage is a single-page chat client speaking protocol version 2.
+//
+//go:embed ui/index.html
+var uiPage []byte
+
+// uiPath is where the chat UI is served under the path prefix.
+func (s *Server) uiPath() string {
+	if s.pathPrefix == "/" {
+		return "/ui"
+	}
+	return s.pathPrefix + "/ui"
+}
+
+func (s *Server) handleUI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	host := s.requestHost(r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src data:; connect-src 'self' ws://"+host+" wss://"+host)
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(uiPage)
+	}
+}
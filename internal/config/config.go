@@ -11,62 +11,246 @@ import (
 
 type Config struct {
 	Telegram  TelegramConfig
+	Discord   DiscordConfig
+	Email     EmailConfig
 	WebSocket WebSocketConfig
+	API       APIConfig
 	LLM       LLMConfig
 	Storage   StorageConfig
 	Tools     ToolsConfig
 	Skills    SkillsConfig
 	MCP       MCPConfig
+	Plugins   PluginsConfig
 	Scheduler SchedulerConfig
 	Search    SearchConfig
 	Proxy     ProxyConfig
+	Workspace WorkspaceConfig
+	Templates TemplatesConfig
+	Agent     AgentConfig
+	Bus       BusConfig
+	Webhooks  WebhooksConfig
+	Auth      AuthConfig
+	Memory    MemoryConfig
+	Speech    SpeechConfig
+	Logging   LoggingConfig
+	Tracing   TracingConfig
+	Chaos     ChaosConfig
+	Secrets   SecretsConfig
 }
 
 type TelegramConfig struct {
-	Enabled bool
-	Token   string
-	Webhook string
+	Enabled     bool
+	Token       string
+	Webhook     string
+	UploadDir   string
+	MaxFileSize int64
+
+	StreamResponses bool
+	StreamInterval  int
+
+	AllowedUsers []int64
+	AllowedChats []int64
+	Admins       []int64
+
+	// In groups only messages that mention the bot, reply to it or start
+	// with one of the prefixes are answered. With none set, all are.
+	GroupMention  bool
+	GroupReply    bool
+	GroupPrefixes []string
+}
+
+type DiscordConfig struct {
+	Enabled        bool
+	Token          string
+	RequireMention bool
+
+	StreamResponses bool
+	StreamInterval  int
+
+	AllowedUsers    []string
+	AllowedGuilds   []string
+	AllowedChannels []string
+	Admins          []string
+}
+
+type EmailConfig struct {
+	Enabled      bool
+	IMAPHost     string
+	IMAPPort     int
+	SMTPHost     string
+	SMTPPort     int
+	Username     string
+	Password     string
+	Address      string
+	Mailbox      string
+	PollInterval int
+
+	AllowedSenders []string
+	Admins         []string
+	AuthServID     string
+}
+
+// WebhooksConfig lists the endpoints external systems can post events to, at
+// /hooks/<name> on the admin API. MaxBodySize is in bytes.
+type WebhooksConfig struct {
+	MaxBodySize int64
+	Hooks       []WebhookConfig
+}
+
+type WebhookConfig struct {
+	Name     string
+	Secret   string
+	Template string
+	Prompt   string
+	Skills   []string
+	Channel  string
+	ChatID   string
 }
 
 type WebSocketConfig struct {
+	Enabled        bool
+	Port           int
+	Host           string
+	RequireAuth    bool
+	MaxClients     int
+	AllowedOrigins []string
+	TLSCert        string
+	TLSKey         string
+	PathPrefix     string
+	TrustedProxies []string
+	UI             bool
+	MaxMessageSize int
+	MaxPayloadSize int
+}
+
+type APIConfig struct {
 	Enabled bool
-	Port    int
 	Host    string
+	Port    int
 }
 
 type LLMConfig struct {
 	Provider     string
 	APIKey       string
 	Model        string
+	BaseURL      string
+	Organization string
+	Project      string
+	Deployment   string
+	APIVersion   string
 	MaxTokens    int
 	Temperature  float64
 	LocalModel   LocalModelConfig
 	Models       []ModelConfig
 	DefaultModel string
+
+	ContextWindow  int
+	ThinkingBudget int
+	Headers        map[string]string
+	Vision         bool
+	Routing        RoutingConfig
+	Budget         BudgetConfig
+	RateLimits     []RateLimitConfig
+}
+
+type RateLimitConfig struct {
+	Provider          string
+	RequestsPerMinute int
+	BackgroundShare   float64
+}
+
+type RoutingConfig struct {
+	Policy string
+	Models []string
+}
+
+type BudgetConfig struct {
+	DailyLimit     float64
+	ChatDailyLimit float64
+	Action         string
+	DowngradeModel string
 }
 
 type ModelConfig struct {
-	Name        string
-	Provider    string
-	APIKey      string
-	Model       string
-	MaxTokens   int
-	Temperature float64
-	LocalModel  LocalModelConfig
+	Name         string
+	Provider     string
+	APIKey       string
+	Model        string
+	BaseURL      string
+	Organization string
+	Project      string
+	Deployment   string
+	APIVersion   string
+	MaxTokens    int
+	Temperature  float64
+	LocalModel   LocalModelConfig
+
+	ContextWindow  int
+	ThinkingBudget int
+	Headers        map[string]string
+	Vision         bool
+	Cost           float64
+	InputPrice     float64
+	OutputPrice    float64
+	RateLimit      int
 }
 
 type LocalModelConfig struct {
-	Enabled bool
-	Path    string
-	Type    string
+	Enabled   bool
+	Path      string
+	Type      string
+	KeepAlive string
+	AutoPull  bool
 }
 
 type StorageConfig struct {
 	BasePath string
+	// Backend is where memory, sessions and files are kept: "local" (the
+	// default) for BasePath on disk, or "s3" for an S3-compatible bucket.
+	Backend      string
+	S3           S3StorageConfig
+	SessionCache SessionCacheConfig
+}
+
+// S3StorageConfig configures the s3 storage backend. Endpoint is only needed
+// for services other than AWS, such as MinIO or Cloudflare R2.
+type S3StorageConfig struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	PathStyle       bool
+}
+
+// SessionCacheConfig controls the write-behind cache in front of session
+// storage.
+type SessionCacheConfig struct {
+	Enabled       bool
+	MaxSessions   int
+	FlushInterval int
 }
 
 type ToolsConfig struct {
 	WebSearch WebSearchConfig
+	Exec      ExecConfig
+	HTTP      HTTPRequestConfig
+	FetchPage FetchPageConfig
+
+	DefaultTimeout int
+	Policies       map[string]ToolPolicyConfig
+}
+
+type ToolPolicyConfig struct {
+	Timeout              int
+	Retries              int
+	MaxConcurrent        int
+	RequiresConfirmation *bool
+	AdminOnly            *bool
+	// CacheTTL is in seconds; -1 turns off a cache the tool declares.
+	CacheTTL int
 }
 
 type SkillsConfig struct {
@@ -75,6 +259,29 @@ type SkillsConfig struct {
 	AutoReload bool
 	MaxActive  int
 	Selection  SelectionConfig
+	Bundled    BundledSkillsConfig
+	Packs      SkillPacksConfig
+}
+
+type BundledSkillsConfig struct {
+	Enabled  bool
+	Disabled []string
+}
+
+// SkillPacksConfig lists skill packs to download from git repositories or
+// archive URLs. RefreshInterval is in seconds; zero downloads them only at
+// startup.
+type SkillPacksConfig struct {
+	RefreshInterval int
+	Sources         []SkillSourceConfig
+}
+
+type SkillSourceConfig struct {
+	Name     string
+	Git      string
+	Ref      string
+	URL      string
+	Disabled bool
 }
 
 type SelectionConfig struct {
@@ -83,8 +290,27 @@ type SelectionConfig struct {
 }
 
 type MCPConfig struct {
-	Enabled bool
-	Clients []MCPClientConfig
+	Enabled     bool
+	Clients     []MCPClientConfig
+	HealthCheck MCPHealthCheckConfig
+}
+
+// MCPHealthCheckConfig sets how often MCP servers are pinged and how long to
+// wait at most between reconnects, in seconds. An interval of 0 turns health
+// checks off.
+type MCPHealthCheckConfig struct {
+	Interval   int
+	Timeout    int
+	MaxBackoff int
+}
+
+// PluginsConfig loads tool plugins, executables built with pkg/plugin, from
+// Directory. StartTimeout is in seconds.
+type PluginsConfig struct {
+	Enabled      bool
+	Directory    string
+	AutoReload   bool
+	StartTimeout int
 }
 
 type MCPClientConfig struct {
@@ -94,13 +320,210 @@ type MCPClientConfig struct {
 	Transport string
 	Headers   map[string]string
 	Timeout   int
+	Command   string
+	Args      []string
+	Env       map[string]string
+	WorkDir   string
+
+	Fixture     string
+	FixtureMode string
+}
+
+type TemplatesConfig struct {
+	Enabled bool
+	File    string
+}
+
+type AgentConfig struct {
+	ShowWork           bool
+	LogThinking        bool
+	RetryDelay         int
+	MaxConcurrentChats int
+	HistoryTokens      int
+	SummarizeHistory   bool
+	MaxSessions        int
+	SessionIdleTTL     int
+	FastPath           FastPathConfig
+	Quotas             QuotasConfig
+	SubAgents          []SubAgentConfig
+	MaxSubAgentDepth   int
+	// ShutdownTimeout is how many seconds conversations in progress get to
+	// finish when MiniClaw shuts down.
+	ShutdownTimeout int
+	Transcripts     TranscriptsConfig
+	Context         ContextConfig
+	Journal         JournalConfig
+	// MemoryConsolidation schedules a task that tidies MEMORY.md with the
+	// LLM.
+	MemoryConsolidation MemoryConsolidationConfig
+}
+
+type MemoryConsolidationConfig struct {
+	Enabled bool
+	// Schedule is a cron expression.
+	Schedule string
+	// MaxAge is how many days old an entry may get; 0 keeps entries until
+	// they are outdated.
+	MaxAge int
+	// MaxSize is the size MEMORY.md is kept under, in characters; 0 means
+	// no limit.
+	MaxSize int
+	DryRun  bool
+}
+
+// JournalConfig appends a line about every answered message to the day's
+// daily note.
+type JournalConfig struct {
+	Enabled bool
+	// Summarize writes the line with an extra LLM call instead of quoting
+	// the message and the response.
+	Summarize bool
+}
+
+// ContextConfig controls which sections go into the system prompt and their
+// budgets in estimated tokens, where 0 means unlimited.
+type ContextConfig struct {
+	Memory          bool
+	MemoryTokens    int
+	SummarizeMemory bool
+	DailyNotes      bool
+	DailyNoteDays   int
+	DailyNoteTokens int
+	// ToolDetail is names, descriptions or full.
+	ToolDetail string
+}
+
+// TranscriptsConfig records every ReAct loop under
+// <storage.base_path>/transcripts for debugging and replays.
+type TranscriptsConfig struct {
+	Enabled bool
+	// Retention is how many days transcripts are kept; 0 keeps them.
+	Retention int
+}
+
+// SubAgentConfig defines a named sub-agent the agent can delegate tasks to
+// with the spawn_subagent tool.
+type SubAgentConfig struct {
+	Name          string
+	Description   string
+	SystemPrompt  string
+	Model         string
+	Tools         []string
+	MaxIterations int
+}
+
+type QuotasConfig struct {
+	Chat QuotaLimitsConfig
+	User QuotaLimitsConfig
+}
+
+type QuotaLimitsConfig struct {
+	MessagesPerMinute int
+	LLMCallsPerHour   int
+}
+
+type FastPathConfig struct {
+	Enabled  bool
+	Currency bool
+	RatesURL string
+	RatesTTL int
+}
+
+type MemoryConfig struct {
+	Embeddings EmbeddingsConfig
+}
+
+// SpeechConfig turns Telegram voice messages into text with STT and, with
+// VoiceReplies, answers them with a voice message read by TTS.
+type SpeechConfig struct {
+	STT          SpeechBackendConfig
+	TTS          SpeechBackendConfig
+	VoiceReplies bool
+}
+
+type SpeechBackendConfig struct {
+	// Provider is openai or, for STT only, whisper.cpp. Empty disables it.
+	Provider string
+	// BaseURL is an OpenAI-compatible API root or the whisper.cpp server.
+	BaseURL string
+	// APIKey defaults to llm.api_key when the LLM provider is openai.
+	APIKey   string
+	Model    string
+	Voice    string
+	Language string
+	// Timeout is in seconds.
+	Timeout int
+}
+
+type LoggingConfig struct {
+	Level   string
+	Format  string
+	Modules map[string]string
+}
+
+// TracingConfig exports spans to an OpenTelemetry collector over OTLP/HTTP.
+// Endpoint is the collector's base URL, e.g. http://localhost:4318.
+type TracingConfig struct {
+	Enabled     bool
+	Endpoint    string
+	Headers     map[string]string
+	ServiceName string
+}
+
+type EmbeddingsConfig struct {
+	Enabled  bool
+	Provider string
+	APIKey   string
+	Model    string
+	BaseURL  string
+}
+
+type AuthConfig struct {
+	Tokens []AuthTokenConfig
+	Basic  []AuthBasicUserConfig
+	OIDC   OIDCConfig
+	JWT    JWTConfig
+}
+
+type AuthTokenConfig struct {
+	Name  string
+	Token string
+	Roles []string
+}
+
+type AuthBasicUserConfig struct {
+	Username string
+	Password string
+	Roles    []string
+}
+
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	RoleClaim    string
+	RoleMapping  map[string]string
+	DefaultRole  string
+	SessionTTL   int
+}
+
+type JWTConfig struct {
+	Secret   string
+	Issuer   string
+	Audience string
 }
 
 type SchedulerConfig struct {
-	Enabled      bool
-	TasksFile    string
-	AutoStart    bool
-	TickInterval int
+	Enabled          bool
+	TasksFile        string
+	AutoStart        bool
+	TickInterval     int
+	Workers          int
+	HistoryDir       string
+	HistoryRetention int
+	HistoryMaxRuns   int
 }
 
 type SearchConfig struct {
@@ -108,9 +531,83 @@ type SearchConfig struct {
 }
 
 type WebSearchConfig struct {
-	Enabled  bool
-	APIKey   string
+	Enabled bool
+	// APIKey is the Brave Search API key.
+	APIKey string
+	// Provider is brave, searxng, duckduckgo or google.
 	Provider string
+	// Fallback lists the providers to try, in order, when Provider fails or
+	// is rate limited.
+	Fallback []string
+	SearxNG  SearxNGConfig
+	Google   GoogleSearchConfig
+	// Country, Language and SafeSearch are the defaults for searches that
+	// do not set their own.
+	Country    string
+	Language   string
+	SafeSearch string
+}
+
+type SearxNGConfig struct {
+	URL string
+}
+
+type GoogleSearchConfig struct {
+	APIKey string
+	// EngineID is the ID (cx) of the Programmable Search Engine.
+	EngineID string
+}
+
+type ExecConfig struct {
+	Enabled   bool
+	Allow     []string
+	Deny      []string
+	Timeout   int
+	MaxOutput int
+	Env       []string
+}
+
+type HTTPRequestConfig struct {
+	Enabled         bool
+	Allow           []string
+	MaxResponseSize int64
+	MaxRedirects    int
+	Timeout         int
+	AllowPrivate    bool
+}
+
+// FetchPageConfig enables fetch_page, which uses the URL allowlist and limits
+// of HTTPRequestConfig.
+type FetchPageConfig struct {
+	Enabled   bool
+	MaxLength int
+	// CacheTTL is in seconds; -1 turns the cache off.
+	CacheTTL int
+}
+
+// BusConfig controls how failing message handlers are retried. Backoffs are
+// in milliseconds.
+type BusConfig struct {
+	MaxAttempts    int
+	InitialBackoff int
+	MaxBackoff     int
+	DeadLetters    bool
+}
+
+type ChaosConfig struct {
+	Enabled             bool
+	Seed                int64
+	LLMTimeout          float64
+	ToolError           float64
+	BusDelay            float64
+	MaxBusDelay         int
+	StorageWriteFailure float64
+}
+
+// SecretsConfig names a file of NAME=value lines used, after the environment,
+// to fill in ${NAME} placeholders in the config.
+type SecretsConfig struct {
+	File string
 }
 
 type ProxyConfig struct {
@@ -121,6 +618,14 @@ type ProxyConfig struct {
 	Password string
 }
 
+type WorkspaceConfig struct {
+	Enabled          bool
+	Directory        string
+	MaxChanges       int
+	IncludeInContext bool
+	Ignore           []string
+}
+
 type ConfigManager interface {
 	GetConfig() *Config
 	Reload() error
@@ -138,6 +643,9 @@ type FileConfigManager struct {
 	config   *Config
 	path     string
 	watchers []ConfigWatcher
+
+	overrides func(config *Config)
+	secrets   SecretsProvider
 }
 
 type ConfigWatcher interface {
@@ -158,14 +666,17 @@ func NewFileConfigManager(path string) (*FileConfigManager, error) {
 }
 
 func (cm *FileConfigManager) Load() error {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-
 	config, err := cm.loadFromFile()
 	if err != nil {
 		return err
 	}
 
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.overrides != nil {
+		cm.overrides(config)
+	}
 	cm.config = config
 
 	for _, watcher := range cm.watchers {
@@ -190,18 +701,54 @@ func (cm *FileConfigManager) loadFromFile() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	secrets, err := cm.secretsFor(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := expandSecrets(config, secrets); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
 func (cm *FileConfigManager) getDefaultConfig() *Config {
 	return &Config{
 		Telegram: TelegramConfig{
-			Enabled: true,
+			Enabled:     true,
+			UploadDir:   "uploads/telegram",
+			MaxFileSize: 20 * 1024 * 1024,
+
+			GroupMention: true,
+			GroupReply:   true,
+		},
+		Discord: DiscordConfig{
+			Enabled:        false,
+			RequireMention: true,
+		},
+		Webhooks: WebhooksConfig{
+			MaxBodySize: 1024 * 1024,
+		},
+		Email: EmailConfig{
+			Enabled:      false,
+			IMAPPort:     993,
+			SMTPPort:     587,
+			Mailbox:      "INBOX",
+			PollInterval: 60,
 		},
 		WebSocket: WebSocketConfig{
-			Enabled: true,
-			Port:    18789,
-			Host:    "0.0.0.0",
+			Enabled:        true,
+			Port:           18789,
+			Host:           "0.0.0.0",
+			MaxClients:     10,
+			UI:             true,
+			MaxMessageSize: 64 * 1024,
+			MaxPayloadSize: 1024 * 1024,
+		},
+		API: APIConfig{
+			Enabled: false,
+			Host:    "127.0.0.1",
+			Port:    18790,
 		},
 		LLM: LLMConfig{
 			Provider:    "anthropic",
@@ -216,12 +763,37 @@ func (cm *FileConfigManager) getDefaultConfig() *Config {
 		},
 		Storage: StorageConfig{
 			BasePath: "./data",
+			Backend:  "local",
+			SessionCache: SessionCacheConfig{
+				Enabled:       true,
+				MaxSessions:   100,
+				FlushInterval: 5,
+			},
 		},
 		Tools: ToolsConfig{
 			WebSearch: WebSearchConfig{
 				Enabled:  false,
 				Provider: "brave",
 			},
+			Exec: ExecConfig{
+				Enabled:   false,
+				Allow:     []string{"git", "ls", "ps", "df", "du", "uptime", "date", "whoami", "wc", "head", "tail"},
+				Deny:      []string{"git push", "git config"},
+				Timeout:   30,
+				MaxOutput: 64 * 1024,
+			},
+			HTTP: HTTPRequestConfig{
+				Enabled:         false,
+				MaxResponseSize: 1024 * 1024,
+				MaxRedirects:    5,
+				Timeout:         30,
+			},
+			FetchPage: FetchPageConfig{
+				Enabled:   false,
+				MaxLength: 20000,
+				CacheTTL:  900,
+			},
+			DefaultTimeout: 120,
 		},
 		Skills: SkillsConfig{
 			Enabled:    true,
@@ -232,16 +804,37 @@ func (cm *FileConfigManager) getDefaultConfig() *Config {
 				Method:    "hybrid",
 				Threshold: 0.5,
 			},
+			Bundled: BundledSkillsConfig{
+				Enabled: true,
+			},
+			Packs: SkillPacksConfig{
+				RefreshInterval: 21600,
+			},
 		},
 		MCP: MCPConfig{
 			Enabled: false,
 			Clients: []MCPClientConfig{},
+			HealthCheck: MCPHealthCheckConfig{
+				Interval:   30,
+				Timeout:    10,
+				MaxBackoff: 300,
+			},
 		},
-		Scheduler: SchedulerConfig{
+		Plugins: PluginsConfig{
 			Enabled:      false,
-			TasksFile:    "./data/tasks.json",
-			AutoStart:    true,
-			TickInterval: 1,
+			Directory:    "./data/plugins",
+			AutoReload:   true,
+			StartTimeout: 10,
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:          false,
+			TasksFile:        "./data/tasks.json",
+			AutoStart:        true,
+			TickInterval:     1,
+			Workers:          4,
+			HistoryDir:       "./data/task_history",
+			HistoryRetention: 30,
+			HistoryMaxRuns:   100,
 		},
 		Search: SearchConfig{
 			BraveAPIKey: "",
@@ -249,6 +842,65 @@ func (cm *FileConfigManager) getDefaultConfig() *Config {
 		Proxy: ProxyConfig{
 			Enabled: false,
 		},
+		Workspace: WorkspaceConfig{
+			Enabled:          false,
+			Directory:        "./workspace",
+			MaxChanges:       100,
+			IncludeInContext: true,
+		},
+		Templates: TemplatesConfig{
+			Enabled: false,
+			File:    "./configs/templates.yaml",
+		},
+		Agent: AgentConfig{
+			ShowWork:        false,
+			RetryDelay:      30,
+			MaxSessions:     1000,
+			SessionIdleTTL:  3600,
+			ShutdownTimeout: 20,
+			Transcripts: TranscriptsConfig{
+				Retention: 7,
+			},
+			Journal: JournalConfig{
+				Summarize: true,
+			},
+			MemoryConsolidation: MemoryConsolidationConfig{
+				Schedule: "0 4 * * *",
+				MaxAge:   180,
+				MaxSize:  8000,
+			},
+			Context: ContextConfig{
+				Memory:          true,
+				MemoryTokens:    4000,
+				DailyNotes:      true,
+				DailyNoteDays:   7,
+				DailyNoteTokens: 2000,
+				ToolDetail:      "descriptions",
+			},
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			Enabled:     false,
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "miniclaw",
+		},
+		Bus: BusConfig{
+			MaxAttempts:    3,
+			InitialBackoff: 500,
+			MaxBackoff:     10000,
+			DeadLetters:    true,
+		},
+		Chaos: ChaosConfig{
+			Enabled:             false,
+			LLMTimeout:          0.1,
+			ToolError:           0.1,
+			BusDelay:            0.2,
+			MaxBusDelay:         2000,
+			StorageWriteFailure: 0.05,
+		},
 	}
 }
 
@@ -271,9 +923,27 @@ func (cm *FileConfigManager) Save() error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	data, err := yaml.Marshal(cm.config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(cm.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
 	return nil
 }
 
+func (cm *FileConfigManager) Exists() bool {
+	_, err := os.Stat(cm.path)
+	return err == nil
+}
+
+func (cm *FileConfigManager) Path() string {
+	return cm.path
+}
+
 func (cm *FileConfigManager) GetString(key string) (string, error) {
 	return "", nil
 }
@@ -298,6 +298,42 @@ func TestLoadFromFileInvalid(t *testing.T) {
 	}
 }
 
+func TestSaveGeneratedConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "configs", "config.yaml")
+
+	manager, err := NewFileConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if manager.Exists() {
+		t.Fatal("Expected config file not to exist yet")
+	}
+
+	manager.GetConfig().LLM.Provider = "openai"
+	manager.GetConfig().LLM.APIKey = "sk-test"
+	manager.GetConfig().Storage.BasePath = "./state"
+
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	reloaded, err := NewFileConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if !reloaded.Exists() {
+		t.Error("Expected config file to exist after saving")
+	}
+
+	config := reloaded.GetConfig()
+	if config.LLM.Provider != "openai" || config.LLM.APIKey != "sk-test" || config.Storage.BasePath != "./state" {
+		t.Errorf("Expected saved settings to round-trip, got %+v %+v", config.LLM, config.Storage)
+	}
+	if config.WebSocket.Port != 18789 {
+		t.Errorf("Expected defaults to be saved as well, got port %d", config.WebSocket.Port)
+	}
+}
+
 type mockConfigWatcher struct {
 	called bool
 }
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderPattern matches ${NAME} and ${NAME:-default}. $${ is an escaped,
+// literal ${.
+var placeholderPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// SecretsProvider resolves the ${NAME} placeholders in config values.
+type SecretsProvider interface {
+	Lookup(name string) (string, bool)
+}
+
+// EnvSecrets reads secrets from environment variables.
+type EnvSecrets struct{}
+
+func (EnvSecrets) Lookup(name string) (string, bool) {
+	return os.LookupEnv(name)
+}
+
+// FileSecrets reads secrets from a file of NAME=value lines, kept out of
+// version control next to the config.
+type FileSecrets struct {
+	values map[string]string
+}
+
+func NewFileSecrets(path string) (*FileSecrets, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid secrets file line %d: expected NAME=value", number)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	return &FileSecrets{values: values}, nil
+}
+
+func (s *FileSecrets) Lookup(name string) (string, bool) {
+	value, ok := s.values[name]
+	return value, ok
+}
+
+// ChainSecrets asks each provider in turn.
+type ChainSecrets []SecretsProvider
+
+func (c ChainSecrets) Lookup(name string) (string, bool) {
+	for _, provider := range c {
+		if value, ok := provider.Lookup(name); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// SetSecretsProvider replaces the environment as the source of ${NAME}
+// values. A secrets file named in the config is still consulted after it.
+func (cm *FileConfigManager) SetSecretsProvider(provider SecretsProvider) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.secrets = provider
+}
+
+func (cm *FileConfigManager) secretsFor(config *Config) (SecretsProvider, error) {
+	cm.mu.RLock()
+	var provider SecretsProvider = EnvSecrets{}
+	if cm.secrets != nil {
+		provider = cm.secrets
+	}
+	cm.mu.RUnlock()
+
+	if config.Secrets.File == "" {
+		return provider, nil
+	}
+
+	path, err := interpolate(config.Secrets.File, provider, nil)
+	if err != nil {
+		return nil, err
+	}
+	file, err := NewFileSecrets(path)
+	if err != nil {
+		return nil, err
+	}
+	return ChainSecrets{provider, file}, nil
+}
+
+// expandSecrets replaces the placeholders in every string of config,
+// including map values and list items. Placeholders without a value and
+// without a default are all reported at once.
+func expandSecrets(config *Config, provider SecretsProvider) error {
+	missing := make(map[string]bool)
+	expandValue(reflect.ValueOf(config).Elem(), provider, missing)
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("undefined variables in config: %s", strings.Join(names, ", "))
+}
+
+func expandValue(value reflect.Value, provider SecretsProvider, missing map[string]bool) {
+	switch value.Kind() {
+	case reflect.String:
+		expanded, _ := interpolate(value.String(), provider, missing)
+		value.SetString(expanded)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				expandValue(value.Field(i), provider, missing)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			expandValue(value.Index(i), provider, missing)
+		}
+	case reflect.Pointer:
+		if !value.IsNil() {
+			expandValue(value.Elem(), provider, missing)
+		}
+	case reflect.Map:
+		if value.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range value.MapKeys() {
+			expanded, _ := interpolate(value.MapIndex(key).String(), provider, missing)
+			value.SetMapIndex(key, reflect.ValueOf(expanded).Convert(value.Type().Elem()))
+		}
+	}
+}
+
+func interpolate(text string, provider SecretsProvider, missing map[string]bool) (string, error) {
+	if !strings.Contains(text, "${") {
+		return text, nil
+	}
+
+	var undefined []string
+	expanded := placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+
+		parts := placeholderPattern.FindStringSubmatch(match)
+		if value, ok := provider.Lookup(parts[1]); ok && value != "" {
+			return value
+		}
+		if strings.Contains(match, ":-") {
+			return parts[2]
+		}
+
+		undefined = append(undefined, parts[1])
+		if missing != nil {
+			missing[parts[1]] = true
+		}
+		return ""
+	})
+
+	if len(undefined) > 0 {
+		return expanded, fmt.Errorf("undefined variables: %s", strings.Join(undefined, ", "))
+	}
+	return expanded, nil
+}
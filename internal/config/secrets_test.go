@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type mapSecrets map[string]string
+
+func (m mapSecrets) Lookup(name string) (string, bool) {
+	value, ok := m[name]
+	return value, ok
+}
+
+func TestConfigInterpolatesSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretsPath := filepath.Join(dir, "secrets.env")
+	os.WriteFile(secretsPath, []byte("# kept out of git\nMCP_TOKEN=\"from-file\"\nexport LLM_KEY=overridden\n"), 0600)
+
+	configPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(configPath, []byte(`
+telegram:
+  token: "${TELEGRAM_TOKEN}"
+llm:
+  apikey: "${LLM_KEY}"
+  model: "${LLM_MODEL:-claude-sonnet-4-5}"
+mcp:
+  clients:
+    - name: "tools"
+      headers:
+        Authorization: "Bearer ${MCP_TOKEN}"
+      args: ["--price", "$${NOT_A_VARIABLE}"]
+secrets:
+  file: "${SECRETS_DIR}/secrets.env"
+`), 0600)
+
+	manager := &FileConfigManager{path: configPath}
+	manager.SetSecretsProvider(mapSecrets{"TELEGRAM_TOKEN": "123:abc", "LLM_KEY": "sk-env", "SECRETS_DIR": dir})
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	config := manager.GetConfig()
+	if config.Telegram.Token != "123:abc" || config.LLM.APIKey != "sk-env" || config.LLM.Model != "claude-sonnet-4-5" {
+		t.Errorf("Unexpected values: token %q, key %q, model %q", config.Telegram.Token, config.LLM.APIKey, config.LLM.Model)
+	}
+
+	client := config.MCP.Clients[0]
+	if client.Headers["Authorization"] != "Bearer from-file" {
+		t.Errorf("Expected header from the secrets file, got %q", client.Headers["Authorization"])
+	}
+	if client.Args[1] != "${NOT_A_VARIABLE}" {
+		t.Errorf("Expected escaped placeholder to be kept, got %q", client.Args[1])
+	}
+
+	manager.SetSecretsProvider(mapSecrets{"SECRETS_DIR": dir})
+	err := manager.Load()
+	if err == nil || !strings.Contains(err.Error(), "TELEGRAM_TOKEN") || strings.Contains(err.Error(), "LLM_MODEL") {
+		t.Errorf("Expected undefined variable without default to be reported, got %v", err)
+	}
+}
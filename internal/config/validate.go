@@ -0,0 +1,484 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+var (
+	llmProviders     = []string{"anthropic", "openai", "azure", "openrouter", "groq", "deepseek", "local", "ollama"}
+	visionProviders  = []string{"anthropic", "openai", "azure", "openrouter", "groq"}
+	mcpTransports    = []string{"http", "stdio", "sse", "streamable_http"}
+	channels         = []string{"telegram", "discord", "email", "websocket", "cli"}
+	safeSearchLevels = []string{"off", "moderate", "strict"}
+	searchProviders  = []string{"brave", "searxng", "duckduckgo", "google"}
+	sttProviders     = []string{"openai", "whisper.cpp"}
+
+	sourceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// Problem is one thing Validate found wrong, with the setting to fix.
+type Problem struct {
+	Setting string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Setting + ": " + p.Message
+}
+
+// ValidationError lists every problem found, so they can be fixed in one go.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "invalid config, %d problem(s) found:", len(e.Problems))
+	for _, problem := range e.Problems {
+		builder.WriteString("\n  - ")
+		builder.WriteString(problem.String())
+	}
+	return builder.String()
+}
+
+// Validate checks that the settings are consistent with each other, e.g. that
+// enabled features have what they need, and returns a *ValidationError if
+// they are not.
+func (c *Config) Validate() error {
+	var problems []Problem
+	add := func(setting, format string, args ...interface{}) {
+		problems = append(problems, Problem{Setting: setting, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Telegram.Enabled && (c.Telegram.Token == "" || c.Telegram.Token == "YOUR_TELEGRAM_BOT_TOKEN") {
+		add("telegram.token", "Telegram is enabled but no bot token is set; set the token or disable telegram")
+	}
+	if c.Discord.Enabled && (c.Discord.Token == "" || c.Discord.Token == "YOUR_DISCORD_BOT_TOKEN") {
+		add("discord.token", "Discord is enabled but no bot token is set; set the token or disable discord")
+	}
+	if c.Email.Enabled {
+		if c.Email.IMAPHost == "" || c.Email.SMTPHost == "" {
+			add("email.imap_host", "email is enabled but the IMAP or SMTP server is not set")
+		}
+		if c.Email.Username == "" {
+			add("email.username", "email is enabled but no username is set")
+		}
+		if !validPort(c.Email.IMAPPort) {
+			add("email.imap_port", "%d is not a valid port", c.Email.IMAPPort)
+		}
+		if !validPort(c.Email.SMTPPort) {
+			add("email.smtp_port", "%d is not a valid port", c.Email.SMTPPort)
+		}
+		if c.Email.PollInterval <= 0 {
+			add("email.poll_interval", "must be greater than 0")
+		}
+	}
+
+	if c.WebSocket.Enabled && !validPort(c.WebSocket.Port) {
+		add("websocket.port", "%d is not a valid port", c.WebSocket.Port)
+	}
+	if ws := c.WebSocket; ws.Enabled {
+		if (ws.TLSCert == "") != (ws.TLSKey == "") {
+			add("websocket.tls_cert", "tls_cert and tls_key must be set together")
+		}
+		for _, file := range []struct{ name, path string }{{"tls_cert", ws.TLSCert}, {"tls_key", ws.TLSKey}} {
+			if _, err := os.Stat(file.path); file.path != "" && err != nil {
+				add("websocket."+file.name, "%v", err)
+			}
+		}
+		if ws.PathPrefix != "" && !strings.HasPrefix(ws.PathPrefix, "/") {
+			add("websocket.path_prefix", "must start with /, got %q", ws.PathPrefix)
+		}
+		for _, proxy := range ws.TrustedProxies {
+			if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+				add("websocket.trusted_proxies", "%q is not an IP address or CIDR", proxy)
+			}
+		}
+		if ws.MaxMessageSize < 1024 {
+			add("websocket.max_message_size", "must be at least 1024 bytes, got %d", ws.MaxMessageSize)
+		}
+		if ws.MaxPayloadSize < ws.MaxMessageSize {
+			add("websocket.max_payload_size", "must be at least max_message_size (%d), got %d", ws.MaxMessageSize, ws.MaxPayloadSize)
+		}
+	}
+	if c.API.Enabled && !validPort(c.API.Port) {
+		add("api.port", "%d is not a valid port", c.API.Port)
+	}
+
+	if len(c.LLM.Models) == 0 {
+		if !contains(llmProviders, c.LLM.Provider) {
+			add("llm.provider", "unknown provider %q, expected one of %s", c.LLM.Provider, strings.Join(llmProviders, ", "))
+		}
+		if !validThinkingBudget(c.LLM.ThinkingBudget) {
+			add("llm.thinking_budget", "must be 0 or at least %d tokens", minThinkingBudget)
+		}
+		if c.LLM.Vision && !contains(visionProviders, c.LLM.Provider) {
+			add("llm.vision", "not supported by provider %q, expected one of %s", c.LLM.Provider, strings.Join(visionProviders, ", "))
+		}
+	} else {
+		names := make(map[string]bool)
+		for i, model := range c.LLM.Models {
+			setting := fmt.Sprintf("llm.models[%d]", i)
+			if model.Name == "" {
+				add(setting+".name", "model name is required")
+			} else if names[model.Name] {
+				add(setting+".name", "model %q is defined more than once", model.Name)
+			}
+			names[model.Name] = true
+
+			if !contains(llmProviders, model.Provider) {
+				add(setting+".provider", "unknown provider %q, expected one of %s", model.Provider, strings.Join(llmProviders, ", "))
+			}
+			if !validThinkingBudget(model.ThinkingBudget) {
+				add(setting+".thinking_budget", "must be 0 or at least %d tokens", minThinkingBudget)
+			}
+			if model.Vision && !contains(visionProviders, model.Provider) {
+				add(setting+".vision", "not supported by provider %q, expected one of %s", model.Provider, strings.Join(visionProviders, ", "))
+			}
+		}
+		if c.LLM.DefaultModel != "" && !names[c.LLM.DefaultModel] {
+			add("llm.default_model", "%q is not one of the models in llm.models", c.LLM.DefaultModel)
+		}
+	}
+
+	if c.MCP.Enabled {
+		for i, client := range c.MCP.Clients {
+			setting := fmt.Sprintf("mcp.clients[%d]", i)
+			if client.Name == "" {
+				add(setting+".name", "client name is required")
+			}
+
+			switch {
+			case client.Transport != "" && !contains(mcpTransports, client.Transport):
+				add(setting+".transport", "unknown transport %q, expected one of %s", client.Transport, strings.Join(mcpTransports, ", "))
+			case client.FixtureMode == "replay":
+			case client.Transport == "stdio" && client.Command == "":
+				add(setting+".command", "the stdio transport needs a command")
+			case client.Transport != "stdio" && client.Endpoint == "":
+				add(setting+".endpoint", "the %s transport needs an endpoint", orDefault(client.Transport, "http"))
+			}
+
+			switch client.FixtureMode {
+			case "", "record", "replay":
+				if client.FixtureMode != "" && client.Fixture == "" {
+					add(setting+".fixture", "fixture mode %s needs a fixture file", client.FixtureMode)
+				}
+			default:
+				add(setting+".fixture_mode", "unknown fixture mode %q, expected record or replay", client.FixtureMode)
+			}
+		}
+
+		for _, setting := range []struct {
+			name  string
+			value int
+		}{
+			{"interval", c.MCP.HealthCheck.Interval},
+			{"timeout", c.MCP.HealthCheck.Timeout},
+			{"max_backoff", c.MCP.HealthCheck.MaxBackoff},
+		} {
+			if setting.value < 0 {
+				add("mcp.health_check."+setting.name, "must not be negative, got %d", setting.value)
+			}
+		}
+	}
+
+	if c.Plugins.Enabled {
+		// A missing directory is created when the plugins are loaded.
+		switch info, err := os.Stat(c.Plugins.Directory); {
+		case c.Plugins.Directory == "":
+			add("plugins.directory", "plugins are enabled but no directory is set")
+		case err == nil && !info.IsDir():
+			add("plugins.directory", "%s is not a directory", c.Plugins.Directory)
+		}
+		if c.Plugins.StartTimeout < 0 {
+			add("plugins.start_timeout", "must not be negative, got %d", c.Plugins.StartTimeout)
+		}
+	}
+
+	switch c.Storage.Backend {
+	case "", "local":
+	case "s3":
+		for _, setting := range []struct {
+			name  string
+			value string
+		}{
+			{"bucket", c.Storage.S3.Bucket},
+			{"region", c.Storage.S3.Region},
+			{"access_key_id", c.Storage.S3.AccessKeyID},
+			{"secret_access_key", c.Storage.S3.SecretAccessKey},
+		} {
+			if setting.value == "" {
+				add("storage.s3."+setting.name, "is required for the s3 backend")
+			}
+		}
+	default:
+		add("storage.backend", "unknown backend %q, expected local or s3", c.Storage.Backend)
+	}
+
+	if c.Storage.SessionCache.Enabled {
+		if c.Storage.SessionCache.MaxSessions < 0 {
+			add("storage.session_cache.max_sessions", "must not be negative, got %d", c.Storage.SessionCache.MaxSessions)
+		}
+		if c.Storage.SessionCache.FlushInterval < 0 {
+			add("storage.session_cache.flush_interval", "must not be negative, got %d", c.Storage.SessionCache.FlushInterval)
+		}
+	}
+
+	for _, quota := range []struct {
+		owner  string
+		limits QuotaLimitsConfig
+	}{{"chat", c.Agent.Quotas.Chat}, {"user", c.Agent.Quotas.User}} {
+		if quota.limits.MessagesPerMinute < 0 {
+			add("agent.quotas."+quota.owner+".messages_per_minute", "must not be negative, got %d", quota.limits.MessagesPerMinute)
+		}
+		if quota.limits.LLMCallsPerHour < 0 {
+			add("agent.quotas."+quota.owner+".llm_calls_per_hour", "must not be negative, got %d", quota.limits.LLMCallsPerHour)
+		}
+	}
+
+	modelNames := map[string]bool{"default": len(c.LLM.Models) == 0}
+	for _, model := range c.LLM.Models {
+		modelNames[model.Name] = true
+	}
+	subAgentNames := make(map[string]bool)
+	for i, sub := range c.Agent.SubAgents {
+		setting := fmt.Sprintf("agent.sub_agents[%d]", i)
+		switch {
+		case sub.Name == "":
+			add(setting+".name", "every sub-agent needs a name")
+		case subAgentNames[sub.Name]:
+			add(setting+".name", "sub-agent %q is defined more than once", sub.Name)
+		}
+		subAgentNames[sub.Name] = true
+
+		if sub.SystemPrompt == "" {
+			add(setting+".system_prompt", "every sub-agent needs a system prompt")
+		}
+		if sub.Model != "" && !modelNames[sub.Model] {
+			add(setting+".model", "model %q is not defined in llm.models", sub.Model)
+		}
+		if sub.MaxIterations < 0 {
+			add(setting+".max_iterations", "must not be negative, got %d", sub.MaxIterations)
+		}
+	}
+	if c.Agent.ShutdownTimeout < 0 {
+		add("agent.shutdown_timeout", "must not be negative, got %d", c.Agent.ShutdownTimeout)
+	}
+	for _, setting := range []struct {
+		name  string
+		value int
+	}{
+		{"memory_tokens", c.Agent.Context.MemoryTokens},
+		{"daily_note_days", c.Agent.Context.DailyNoteDays},
+		{"daily_note_tokens", c.Agent.Context.DailyNoteTokens},
+	} {
+		if setting.value < 0 {
+			add("agent.context."+setting.name, "must not be negative, got %d", setting.value)
+		}
+	}
+	switch c.Agent.Context.ToolDetail {
+	case "", "names", "descriptions", "full":
+	default:
+		add("agent.context.tool_detail", "must be names, descriptions or full, got %q", c.Agent.Context.ToolDetail)
+	}
+	if consolidation := c.Agent.MemoryConsolidation; consolidation.Enabled {
+		if fields := len(strings.Fields(consolidation.Schedule)); fields != 5 && fields != 6 && !strings.HasPrefix(consolidation.Schedule, "@") {
+			add("agent.memory_consolidation.schedule", "must be a cron expression with 5 or 6 fields or a macro such as @daily, got %q", consolidation.Schedule)
+		}
+		if consolidation.MaxAge < 0 {
+			add("agent.memory_consolidation.max_age", "must not be negative, got %d", consolidation.MaxAge)
+		}
+		if consolidation.MaxSize < 0 {
+			add("agent.memory_consolidation.max_size", "must not be negative, got %d", consolidation.MaxSize)
+		}
+	}
+	if c.Agent.Transcripts.Retention < 0 {
+		add("agent.transcripts.retention", "must not be negative, got %d", c.Agent.Transcripts.Retention)
+	}
+	if c.Agent.MaxSubAgentDepth < 0 {
+		add("agent.max_sub_agent_depth", "must not be negative, got %d", c.Agent.MaxSubAgentDepth)
+	}
+
+	if c.Tracing.Enabled && !strings.HasPrefix(c.Tracing.Endpoint, "http://") && !strings.HasPrefix(c.Tracing.Endpoint, "https://") {
+		add("tracing.endpoint", "tracing is enabled but %q is not an http(s) URL of an OTLP collector", c.Tracing.Endpoint)
+	}
+
+	if c.Bus.MaxAttempts < 1 {
+		add("bus.max_attempts", "must be at least 1, got %d", c.Bus.MaxAttempts)
+	}
+	if c.Bus.InitialBackoff < 0 {
+		add("bus.initial_backoff", "must not be negative, got %d", c.Bus.InitialBackoff)
+	}
+	if c.Bus.MaxBackoff < c.Bus.InitialBackoff {
+		add("bus.max_backoff", "must be at least bus.initial_backoff (%d), got %d", c.Bus.InitialBackoff, c.Bus.MaxBackoff)
+	}
+
+	if c.Scheduler.Enabled && c.Scheduler.TickInterval <= 0 {
+		add("scheduler.tick_interval", "must be at least 1 second, got %d", c.Scheduler.TickInterval)
+	}
+	if c.Scheduler.Enabled && c.Scheduler.Workers <= 0 {
+		add("scheduler.workers", "must be at least 1, got %d", c.Scheduler.Workers)
+	}
+
+	if c.Skills.Enabled {
+		switch info, err := os.Stat(c.Skills.Directory); {
+		case c.Skills.Directory == "":
+			add("skills.directory", "skills are enabled but no directory is set")
+		case err == nil && !info.IsDir():
+			add("skills.directory", "%s is not a directory", c.Skills.Directory)
+		// The bundled skills are installed into the directory, creating it.
+		case os.IsNotExist(err) && !c.Skills.Bundled.Enabled:
+			add("skills.directory", "%s does not exist; create it or disable skills", c.Skills.Directory)
+		}
+
+		switch c.Skills.Selection.Method {
+		case "", "keyword", "llm", "hybrid":
+		default:
+			add("skills.selection.method", "unknown method %q, expected keyword, llm or hybrid", c.Skills.Selection.Method)
+		}
+
+		if c.Skills.Packs.RefreshInterval < 0 {
+			add("skills.packs.refresh_interval", "must not be negative, got %d", c.Skills.Packs.RefreshInterval)
+		}
+		names := make(map[string]bool)
+		for i, source := range c.Skills.Packs.Sources {
+			setting := fmt.Sprintf("skills.packs.sources[%d]", i)
+			if (source.Git == "") == (source.URL == "") {
+				add(setting, "set exactly one of git and url")
+			}
+			if source.Name == "" {
+				continue
+			}
+			if !sourceNamePattern.MatchString(source.Name) {
+				add(setting+".name", "%q may only contain letters, digits, '.', '_' and '-'", source.Name)
+			} else if names[source.Name] {
+				add(setting+".name", "skill pack %q is defined more than once", source.Name)
+			}
+			names[source.Name] = true
+		}
+	}
+
+	if len(c.Webhooks.Hooks) > 0 && !c.API.Enabled {
+		add("webhooks.hooks", "webhooks are served by the admin API; enable api or remove the hooks")
+	}
+	hooks := make(map[string]bool)
+	for i, hook := range c.Webhooks.Hooks {
+		setting := fmt.Sprintf("webhooks.hooks[%d]", i)
+		switch {
+		case !sourceNamePattern.MatchString(hook.Name):
+			add(setting+".name", "%q is not a valid name, use letters, digits, '.', '_' and '-'", hook.Name)
+		case hooks[hook.Name]:
+			add(setting+".name", "duplicate webhook %q", hook.Name)
+		}
+		hooks[hook.Name] = true
+
+		if !contains(channels, hook.Channel) {
+			add(setting+".channel", "unknown channel %q, expected one of %s", hook.Channel, strings.Join(channels, ", "))
+		}
+		if hook.ChatID == "" {
+			add(setting+".chat_id", "a chat to post the events to is required")
+		}
+	}
+
+	if webSearch := c.Tools.WebSearch; webSearch.Enabled {
+		providers := append([]string{orDefault(webSearch.Provider, "brave")}, webSearch.Fallback...)
+		for i, provider := range providers {
+			setting := "tools.web_search.provider"
+			if i > 0 {
+				setting = fmt.Sprintf("tools.web_search.fallback[%d]", i-1)
+			}
+
+			switch provider {
+			case "brave":
+				if (webSearch.APIKey == "" || webSearch.APIKey == "YOUR_BRAVE_SEARCH_API_KEY") && c.Search.BraveAPIKey == "" {
+					add(setting, "brave needs tools.web_search.api_key")
+				}
+			case "searxng":
+				if webSearch.SearxNG.URL == "" {
+					add(setting, "searxng needs tools.web_search.searxng.url")
+				}
+			case "google":
+				if webSearch.Google.APIKey == "" || webSearch.Google.EngineID == "" {
+					add(setting, "google needs tools.web_search.google.api_key and engine_id")
+				}
+			case "duckduckgo":
+			default:
+				add(setting, "unknown provider %q, expected one of %s", provider, strings.Join(searchProviders, ", "))
+			}
+		}
+	}
+	switch c.Speech.STT.Provider {
+	case "", "openai":
+	case "whisper.cpp":
+		if c.Speech.STT.BaseURL == "" {
+			add("speech.stt.base_url", "whisper.cpp needs the URL of its server")
+		}
+	default:
+		add("speech.stt.provider", "unknown provider %q, expected one of %s", c.Speech.STT.Provider, strings.Join(sttProviders, ", "))
+	}
+	if provider := c.Speech.TTS.Provider; provider != "" && provider != "openai" {
+		add("speech.tts.provider", "unknown provider %q, expected openai", provider)
+	}
+	if c.Speech.VoiceReplies && c.Speech.TTS.Provider == "" {
+		add("speech.voice_replies", "voice replies need a speech.tts provider")
+	}
+	if c.Tools.FetchPage.MaxLength < 0 {
+		add("tools.fetch_page.max_length", "must not be negative")
+	}
+	if safeSearch := c.Tools.WebSearch.SafeSearch; safeSearch != "" && !contains(safeSearchLevels, strings.ToLower(safeSearch)) {
+		add("tools.web_search.safesearch", "unknown level %q, expected off, moderate or strict", safeSearch)
+	}
+
+	if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+		add("logging.level", "%v", err)
+	}
+	for component, level := range c.Logging.Modules {
+		if _, err := logging.ParseLevel(level); err != nil {
+			add("logging.modules."+component, "%v", err)
+		}
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "", logging.FormatText, logging.FormatJSON:
+	default:
+		add("logging.format", "unknown format %q, expected text or json", c.Logging.Format)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// minThinkingBudget is the smallest reasoning budget the Anthropic API
+// accepts.
+const minThinkingBudget = 1024
+
+func validThinkingBudget(budget int) bool {
+	return budget == 0 || budget >= minThinkingBudget
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	manager := &FileConfigManager{}
+
+	config := manager.getDefaultConfig()
+	config.Telegram.Token = "123:abc"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected default config with a token to be valid, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.LLM.Provider = "gemini"
+	config.MCP.Enabled = true
+	config.MCP.Clients = []MCPClientConfig{
+		{Name: "files", Transport: "websocket", Endpoint: "ws://localhost"},
+		{Name: "shell", Transport: "stdio"},
+		{Name: "recorded", FixtureMode: "replay", Fixture: "testdata/recorded.json"},
+	}
+	config.Agent.Quotas.User.LLMCallsPerHour = -1
+	config.Scheduler.Enabled = true
+	config.Scheduler.TickInterval = 0
+	config.Skills.Directory = filepath.Join(t.TempDir(), "missing")
+	config.Skills.Bundled.Enabled = false
+
+	err := config.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+
+	settings := make([]string, 0, len(validationErr.Problems))
+	for _, problem := range validationErr.Problems {
+		settings = append(settings, problem.Setting)
+	}
+	expected := "telegram.token llm.provider mcp.clients[0].transport mcp.clients[1].command agent.quotas.user.llm_calls_per_hour scheduler.tick_interval skills.directory"
+	if got := strings.Join(settings, " "); got != expected {
+		t.Errorf("Expected problems with %s, got %s", expected, got)
+	}
+	if !strings.Contains(err.Error(), "7 problem(s)") || !strings.Contains(err.Error(), `unknown provider "gemini"`) {
+		t.Errorf("Expected every problem in the message, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.LLM.Models = []ModelConfig{{Name: "fast", Provider: "openai", Vision: true}, {Name: "fast", Provider: "ollama", ThinkingBudget: 100, Vision: true}}
+	config.LLM.DefaultModel = "smart"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "defined more than once") || !strings.Contains(err.Error(), "llm.default_model") || !strings.Contains(err.Error(), "llm.models[1].thinking_budget") ||
+		!strings.Contains(err.Error(), "llm.models[1].vision") || strings.Contains(err.Error(), "llm.models[0].vision") {
+		t.Errorf("Expected model list problems, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.LLM.Models = []ModelConfig{{Name: "main", Provider: "openai"}}
+	config.Agent.SubAgents = []SubAgentConfig{
+		{Name: "researcher", SystemPrompt: "Research.", Model: "main"},
+		{Name: "researcher", SystemPrompt: "Research.", Model: "default"},
+	}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "agent.sub_agents[1].name") || !strings.Contains(err.Error(), "agent.sub_agents[1].model") || strings.Contains(err.Error(), "agent.sub_agents[0]") {
+		t.Errorf("Expected problems with the second sub-agent only, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Agent.MemoryConsolidation.Enabled = true
+	config.Agent.MemoryConsolidation.Schedule = "daily"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "agent.memory_consolidation.schedule") {
+		t.Errorf("Expected an invalid consolidation schedule to be rejected, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Tools.WebSearch.SafeSearch = "high"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "tools.web_search.safesearch") {
+		t.Errorf("Expected an unknown safesearch level to be rejected, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Tools.WebSearch.Enabled = true
+	config.Tools.WebSearch.Provider = "searxng"
+	config.Tools.WebSearch.Fallback = []string{"duckduckgo", "bing"}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "tools.web_search.provider") || !strings.Contains(err.Error(), "tools.web_search.fallback[1]") || strings.Contains(err.Error(), "fallback[0]") {
+		t.Errorf("Expected the searxng URL and the unknown fallback to be reported, got %v", err)
+	}
+	config.Tools.WebSearch.SearxNG.URL = "http://localhost:8888"
+	config.Tools.WebSearch.Fallback = []string{"duckduckgo"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected searxng with a duckduckgo fallback to be valid, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Speech.STT.Provider = "whisper.cpp"
+	config.Speech.VoiceReplies = true
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "speech.stt.base_url") || !strings.Contains(err.Error(), "speech.voice_replies") {
+		t.Errorf("Expected the whisper.cpp URL and the missing TTS provider to be reported, got %v", err)
+	}
+	config.Speech.STT.BaseURL = "http://localhost:8080"
+	config.Speech.TTS.Provider = "openai"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected whisper.cpp with OpenAI voice replies to be valid, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Plugins.Enabled = true
+	config.Plugins.Directory = filepath.Join(t.TempDir(), "missing")
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected a plugins directory that does not exist yet to be valid, got %v", err)
+	}
+	config.Plugins.Directory = ""
+	config.Plugins.StartTimeout = -1
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "plugins.directory") || !strings.Contains(err.Error(), "plugins.start_timeout") {
+		t.Errorf("Expected plugins section problems, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Agent.Context.ToolDetail = "verbose"
+	config.Agent.Context.MemoryTokens = -1
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "agent.context.tool_detail") || !strings.Contains(err.Error(), "agent.context.memory_tokens") {
+		t.Errorf("Expected context section problems, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Storage.Backend = "s3"
+	config.Storage.S3 = S3StorageConfig{Bucket: "miniclaw", Region: "us-east-1", AccessKeyID: "key"}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "storage.s3.secret_access_key") || strings.Contains(err.Error(), "storage.s3.bucket") {
+		t.Errorf("Expected only the missing S3 secret key to be reported, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Bus.MaxAttempts = 0
+	config.Bus.MaxBackoff = 100
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "bus.max_attempts") || !strings.Contains(err.Error(), "bus.max_backoff") {
+		t.Errorf("Expected bus retry problems, got %v", err)
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Webhooks.Hooks = []WebhookConfig{
+		{Name: "github", Channel: "telegram", ChatID: "1"},
+		{Name: "github", Channel: "slack"},
+	}
+	err = config.Validate()
+	for _, setting := range []string{"webhooks.hooks:", "webhooks.hooks[1].name", "webhooks.hooks[1].channel", "webhooks.hooks[1].chat_id"} {
+		if err == nil || !strings.Contains(err.Error(), setting) {
+			t.Errorf("Expected a problem with %s, got %v", setting, err)
+		}
+	}
+
+	config = manager.getDefaultConfig()
+	config.Telegram.Enabled = false
+	config.Skills.Packs.Sources = []SkillSourceConfig{
+		{Name: "team", Git: "https://example.com/team/skills.git"},
+		{Name: "team", URL: "https://example.com/skills.zip"},
+		{Name: "bad/name", Git: "https://example.com/a.git", URL: "https://example.com/a.zip"},
+		{},
+	}
+	err = config.Validate()
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	settings = settings[:0]
+	for _, problem := range validationErr.Problems {
+		settings = append(settings, problem.Setting)
+	}
+	expected = "skills.packs.sources[1].name skills.packs.sources[2] skills.packs.sources[2].name skills.packs.sources[3]"
+	if got := strings.Join(settings, " "); got != expected {
+		t.Errorf("Expected problems with %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+// reloadDebounce lets editors finish writing before the file is read.
+const reloadDebounce = 500 * time.Millisecond
+
+var logger = logging.For("config")
+
+// SetOverrides registers changes, such as command line flags, that are applied
+// to every config read from the file after this.
+func (cm *FileConfigManager) SetOverrides(apply func(config *Config)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.overrides = apply
+}
+
+// Watch reloads the config file whenever it changes until ctx is done. A
+// config that fails to parse or validate is ignored and the current one kept.
+func (cm *FileConfigManager) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+
+	// Editors often replace the file instead of writing to it, so the
+	// directory is watched rather than the file.
+	path, err := filepath.Abs(cm.path)
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var timer *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				if timer == nil {
+					timer = time.AfterFunc(reloadDebounce, cm.reloadChanged)
+				} else {
+					timer.Reset(reloadDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("Config watcher error", "error", err)
+			}
+		}
+	}()
+
+	logger.Info("Watching config file for changes", "path", cm.path)
+	return nil
+}
+
+func (cm *FileConfigManager) reloadChanged() {
+	if err := cm.reloadValid(); err != nil {
+		logger.Warn("Ignoring invalid config change", "path", cm.path, "error", err)
+	}
+}
+
+func (cm *FileConfigManager) reloadValid() error {
+	config, err := cm.loadFromFile()
+	if err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	if cm.overrides != nil {
+		cm.overrides(config)
+	}
+	if err := config.Validate(); err != nil {
+		cm.mu.Unlock()
+		return err
+	}
+	if reflect.DeepEqual(config, cm.config) {
+		cm.mu.Unlock()
+		return nil
+	}
+	cm.config = config
+	watchers := append([]ConfigWatcher(nil), cm.watchers...)
+	cm.mu.Unlock()
+
+	logger.Info("Config reloaded", "path", cm.path)
+	for _, watcher := range watchers {
+		watcher.OnConfigChange(config)
+	}
+	return nil
+}
+
+// Changes lists the settings that differ between two configs as dotted paths
+// such as "llm.default_model". Lists and maps are compared as a whole.
+func Changes(before, after *Config) []string {
+	changes := make([]string, 0)
+	diffSettings(reflect.ValueOf(*before), reflect.ValueOf(*after), "", &changes)
+	return changes
+}
+
+func diffSettings(before, after reflect.Value, path string, changes *[]string) {
+	if before.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(before.Interface(), after.Interface()) {
+			*changes = append(*changes, path)
+		}
+		return
+	}
+
+	for i := 0; i < before.NumField(); i++ {
+		name := settingName(before.Type().Field(i).Name)
+		if path != "" {
+			name = path + "." + name
+		}
+		diffSettings(before.Field(i), after.Field(i), name, changes)
+	}
+}
+
+// settingName turns a field name into the key used in the example config,
+// e.g. DefaultModel into default_model and APIKey into api_key.
+func settingName(field string) string {
+	if field == "WebSocket" {
+		return "websocket"
+	}
+
+	runes := []rune(field)
+	var builder strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			builder.WriteByte('_')
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type reloadWatcher struct {
+	reloaded chan *Config
+}
+
+func (w *reloadWatcher) OnConfigChange(config *Config) {
+	w.reloaded <- config
+}
+
+func TestWatchReloadsValidChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("telegram:\n  enabled: false\nlogging:\n  level: info\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	manager, err := NewFileConfigManager(path)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	manager.SetOverrides(func(config *Config) { config.LLM.Model = "from-flag" })
+
+	watcher := &reloadWatcher{reloaded: make(chan *Config, 4)}
+	manager.AddWatcher(watcher)
+	if err := manager.Watch(ctx); err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+
+	os.WriteFile(path, []byte("telegram:\n  enabled: false\nlogging:\n  level: loud\n"), 0600)
+	select {
+	case <-watcher.reloaded:
+		t.Fatal("Expected invalid config to be ignored")
+	case <-time.After(2 * reloadDebounce):
+	}
+	if manager.GetConfig().Logging.Level != "info" {
+		t.Errorf("Expected current config to be kept, got level %q", manager.GetConfig().Logging.Level)
+	}
+
+	os.WriteFile(path, []byte("telegram:\n  enabled: false\nlogging:\n  level: debug\n"), 0600)
+	select {
+	case config := <-watcher.reloaded:
+		if config.Logging.Level != "debug" || config.LLM.Model != "from-flag" {
+			t.Errorf("Expected reloaded config with overrides, got level %q model %q", config.Logging.Level, config.LLM.Model)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected config to be reloaded")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	manager := &FileConfigManager{}
+	before := manager.getDefaultConfig()
+	after := manager.getDefaultConfig()
+
+	if changes := Changes(before, after); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+
+	after.LLM.DefaultModel = "fast"
+	after.LLM.APIKey = "secret"
+	after.WebSocket.Port++
+	after.Logging.Modules = map[string]string{"agent": "debug"}
+
+	expected := []string{"websocket.port", "llm.api_key", "llm.default_model", "logging.modules"}
+	if changes := Changes(before, after); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+}
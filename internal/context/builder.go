@@ -4,40 +4,92 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 	"github.com/wjffsx/miniclaw_go/internal/storage"
 	"github.com/wjffsx/miniclaw_go/internal/tools"
+	"github.com/wjffsx/miniclaw_go/internal/workspace"
 )
 
+var logger = logging.For("context")
+
 type Builder struct {
-	storage       storage.Storage
-	memoryStorage storage.MemoryStorage
+	storage        storage.Storage
+	memoryStorage  storage.MemoryStorage
+	workspace      *workspace.Watcher
+	workspaceLimit int
+	memoryIndexed  bool
+	sections       Sections
+	summarize      Summarizer
+	memorySummary  memorySummary
 }
 
 type Config struct {
-	Storage       storage.Storage
-	MemoryStorage storage.MemoryStorage
+	Storage        storage.Storage
+	MemoryStorage  storage.MemoryStorage
+	Workspace      *workspace.Watcher
+	WorkspaceLimit int
+	MemoryIndexed  bool
+	Sections       Sections
+	// Summarize is used to condense memory when Sections.SummarizeMemory
+	// is set.
+	Summarize Summarizer
 }
 
 func NewBuilder(config *Config) *Builder {
+	workspaceLimit := config.WorkspaceLimit
+	if workspaceLimit <= 0 {
+		workspaceLimit = 10
+	}
+
 	return &Builder{
-		storage:       config.Storage,
-		memoryStorage: config.MemoryStorage,
+		storage:        config.Storage,
+		memoryStorage:  config.MemoryStorage,
+		workspace:      config.Workspace,
+		workspaceLimit: workspaceLimit,
+		memoryIndexed:  config.MemoryIndexed,
+		sections:       config.Sections,
+		summarize:      config.Summarize,
 	}
 }
 
 type Context struct {
-	SystemPrompt string
-	Memory      string
-	DailyNotes  []string
-	Tools       []tools.ToolSchema
+	// SystemPrompt is SOUL.md, USER.md and AGENTS.md joined together.
+	SystemPrompt     string
+	Soul             string
+	User             string
+	Agents           string
+	Memory           string
+	DailyNotes       []string
+	Preferences      *Preferences
+	WorkspaceChanges string
+	Tools            []tools.ToolSchema
+
+	// Set by the agent once it knows them, and placed after the rest of the
+	// prompt unless a prompt template puts them somewhere else.
+	ConversationTemplate string
+	ChannelGuidance      string
+	Skills               string
+
+	Channel string
+	ChatID  string
+	Time    time.Time
+
+	template   *template.Template
+	toolDetail string
 }
 
 func (b *Builder) Build(ctx context.Context, toolSchemas []tools.ToolSchema) (*Context, error) {
 	result := &Context{
-		Tools: toolSchemas,
+		Tools:      toolSchemas,
+		Time:       time.Now(),
+		toolDetail: b.sections.ToolDetail,
 	}
+	result.Channel, _ = tools.ChannelFromContext(ctx)
+	result.ChatID, _ = tools.ChatIDFromContext(ctx)
 
 	if err := b.loadSystemPrompt(ctx, result); err != nil {
 		return nil, fmt.Errorf("failed to load system prompt: %w", err)
@@ -51,6 +103,20 @@ func (b *Builder) Build(ctx context.Context, toolSchemas []tools.ToolSchema) (*C
 		return nil, fmt.Errorf("failed to load daily notes: %w", err)
 	}
 
+	if chatID, ok := tools.ChatIDFromContext(ctx); ok && b.memoryStorage != nil {
+		prefs, err := LoadPreferences(ctx, b.memoryStorage, chatID)
+		if err != nil {
+			logger.Warn("Failed to load preferences", "chat_id", chatID, "error", err)
+		}
+		result.Preferences = prefs
+	}
+
+	if b.workspace != nil {
+		result.WorkspaceChanges = b.workspace.Summary(b.workspaceLimit)
+	}
+
+	result.template = b.loadPromptTemplate(ctx, result.Channel)
+
 	return result, nil
 }
 
@@ -65,8 +131,12 @@ func (b *Builder) loadSystemPrompt(ctx context.Context, result *Context) error {
 		return fmt.Errorf("failed to read USER.md: %w", err)
 	}
 
+	result.Soul = string(soulContent)
+	result.User = string(userContent)
+
 	agentsContent, err := b.storage.ReadFile(ctx, "config/AGENTS.md")
 	if err == nil && len(agentsContent) > 0 {
+		result.Agents = string(agentsContent)
 		result.SystemPrompt = fmt.Sprintf("%s\n\n%s\n\n%s", string(soulContent), string(userContent), string(agentsContent))
 	} else {
 		result.SystemPrompt = fmt.Sprintf("%s\n\n%s", string(soulContent), string(userContent))
@@ -76,35 +146,86 @@ func (b *Builder) loadSystemPrompt(ctx context.Context, result *Context) error {
 }
 
 func (b *Builder) loadMemory(ctx context.Context, result *Context) error {
+	if b.sections.NoMemory {
+		return nil
+	}
+
+	if b.memoryIndexed {
+		result.Memory = "Long-term memory is indexed. Use memory_search to look up facts, preferences and notes relevant to the conversation, and memory_upsert to save new ones."
+		return nil
+	}
+
 	memory, err := b.memoryStorage.GetMemory(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get memory: %w", err)
 	}
 
-	result.Memory = memory
+	result.Memory = b.fitMemory(ctx, memory)
 	return nil
 }
 
+// loadDailyNotes adds the notes of the last days, newest first, until they
+// fill the daily note budget.
 func (b *Builder) loadDailyNotes(ctx context.Context, result *Context) error {
-	notes := make([]string, 0, 7)
+	if b.sections.NoDailyNotes {
+		return nil
+	}
+
+	days := b.sections.DailyNoteDays
+	if days <= 0 {
+		days = defaultDailyNoteDays
+	}
 
-	for i := 0; i < 7; i++ {
+	notes := make([]string, 0, days)
+	remaining := b.sections.DailyNoteTokens
+
+	for i := 0; i < days; i++ {
 		date := time.Now().AddDate(0, 0, -i).Format("2006-01-02")
 		note, err := b.memoryStorage.GetDailyNote(ctx, date)
 		if err != nil {
 			continue
 		}
 
-		if note != "" {
-			notes = append(notes, fmt.Sprintf("## %s\n%s", date, note))
+		if note == "" {
+			continue
+		}
+
+		note = fmt.Sprintf("## %s\n%s", date, note)
+		if b.sections.DailyNoteTokens > 0 {
+			if remaining <= 0 {
+				break
+			}
+			note = truncateTokens(note, remaining)
+			remaining -= llm.EstimateTokens(note)
 		}
+		notes = append(notes, note)
 	}
 
 	result.DailyNotes = notes
 	return nil
 }
 
+// BuildSystemPrompt renders the prompt template, if there is one, and the
+// built-in layout otherwise.
 func (c *Context) BuildSystemPrompt(toolSchemas []tools.ToolSchema) string {
+	if c.template != nil {
+		prompt, err := c.renderTemplate(toolSchemas)
+		if err == nil {
+			return prompt
+		}
+		logger.Warn("Failed to render prompt template, using the default prompt", "template", c.template.Name(), "error", err)
+	}
+
+	prompt := c.defaultSystemPrompt(toolSchemas)
+	for _, section := range []string{c.ConversationTemplate, c.ChannelGuidance, c.Skills} {
+		if section != "" {
+			prompt += "\n\n" + section
+		}
+	}
+	return prompt
+}
+
+func (c *Context) defaultSystemPrompt(toolSchemas []tools.ToolSchema) string {
 	var prompt strings.Builder
 
 	prompt.WriteString(c.SystemPrompt)
@@ -124,16 +245,35 @@ func (c *Context) BuildSystemPrompt(toolSchemas []tools.ToolSchema) string {
 		}
 	}
 
+	if c.Preferences != nil && !c.Preferences.Empty() {
+		prompt.WriteString("## Conversation Preferences\n")
+		prompt.WriteString("The user set these preferences for this conversation. Follow them unless asked otherwise:\n")
+		prompt.WriteString(c.Preferences.String())
+		prompt.WriteString("\n\n")
+	}
+
+	if c.WorkspaceChanges != "" {
+		prompt.WriteString("## Recent Workspace Changes\n")
+		prompt.WriteString("The user changed these files outside of this conversation:\n")
+		prompt.WriteString(c.WorkspaceChanges)
+		prompt.WriteString("\n")
+	}
+
 	if len(toolSchemas) > 0 {
 		prompt.WriteString("## Available Tools\n")
 		prompt.WriteString("You have access to the following tools:\n\n")
-
-		for _, tool := range toolSchemas {
-			prompt.WriteString(fmt.Sprintf("- **%s**: %s\n", tool.Name, tool.Description))
-		}
+		writeToolList(&prompt, toolSchemas, c.toolDetail)
 
 		prompt.WriteString("\n")
-		prompt.WriteString(`When you need to use a tool, respond in the following JSON format:
+		prompt.WriteString(ToolFormat)
+	}
+
+	return prompt.String()
+}
+
+// ToolFormat tells the model how to call tools and answer, in the format the
+// agent parses. Prompt templates must include it when tools are available.
+const ToolFormat = `When you need to use a tool, respond in the following JSON format:
 {
   "thought": "Your reasoning about what to do",
   "tool_calls": [
@@ -152,11 +292,7 @@ When you have a final answer and don't need to use any more tools, respond in th
   "thought": "Your reasoning",
   "final_answer": "Your final answer to the user"
 }
-`)
-	}
-
-	return prompt.String()
-}
+`
 
 func (c *Context) GetTokenEstimate() int {
 	totalTokens := len(c.SystemPrompt)
@@ -171,4 +307,4 @@ func (c *Context) GetTokenEstimate() int {
 	}
 
 	return totalTokens / 4
-}
\ No newline at end of file
+}
@@ -162,6 +162,23 @@ func TestBuilder_BuildSystemPrompt_NoTools(t *testing.T) {
 	}
 }
 
+func TestBuilder_BuildSystemPrompt_WorkspaceChanges(t *testing.T) {
+	ctx := &Context{
+		SystemPrompt:     "You are a helpful AI assistant.",
+		WorkspaceChanges: "- modified main.go (2026-01-01 10:00:00)\n",
+	}
+
+	prompt := ctx.BuildSystemPrompt([]tools.ToolSchema{})
+
+	if !contains(prompt, "## Recent Workspace Changes") {
+		t.Error("Workspace changes section not included in prompt")
+	}
+
+	if !contains(prompt, "modified main.go") {
+		t.Error("Workspace change not included in prompt")
+	}
+}
+
 func TestBuilder_GetTokenEstimate(t *testing.T) {
 	ctx := &Context{
 		SystemPrompt: "You are a helpful AI assistant.",
@@ -0,0 +1,129 @@
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const (
+	preferencesKeyPrefix = "preferences:"
+	maxPreferenceLength  = 100
+)
+
+var PreferenceFields = []string{"language", "verbosity", "units", "code_style"}
+
+var preferenceChoices = map[string][]string{
+	"verbosity": {"concise", "normal", "detailed"},
+	"units":     {"metric", "imperial"},
+}
+
+type Preferences struct {
+	Language  string `json:"language,omitempty"`
+	Verbosity string `json:"verbosity,omitempty"`
+	Units     string `json:"units,omitempty"`
+	CodeStyle string `json:"code_style,omitempty"`
+}
+
+func (p *Preferences) field(name string) (*string, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "-", "_")) {
+	case "language":
+		return &p.Language, nil
+	case "verbosity":
+		return &p.Verbosity, nil
+	case "units":
+		return &p.Units, nil
+	case "code_style":
+		return &p.CodeStyle, nil
+	}
+	return nil, fmt.Errorf("unknown preference %q, expected one of: %s", name, strings.Join(PreferenceFields, ", "))
+}
+
+func (p *Preferences) Get(name string) string {
+	value, err := p.field(name)
+	if err != nil {
+		return ""
+	}
+	return *value
+}
+
+func (p *Preferences) Set(name, value string) error {
+	target, err := p.field(name)
+	if err != nil {
+		return err
+	}
+
+	value = strings.TrimSpace(value)
+	if len(value) > maxPreferenceLength {
+		return fmt.Errorf("preference %s is too long (max %d characters)", name, maxPreferenceLength)
+	}
+
+	if choices, ok := preferenceChoices[strings.ToLower(name)]; ok && value != "" {
+		value = strings.ToLower(value)
+		valid := false
+		for _, choice := range choices {
+			if value == choice {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid %s %q, expected one of: %s", name, value, strings.Join(choices, ", "))
+		}
+	}
+
+	*target = value
+	return nil
+}
+
+func (p *Preferences) Empty() bool {
+	return *p == Preferences{}
+}
+
+func (p *Preferences) String() string {
+	var lines []string
+	for _, name := range PreferenceFields {
+		if value := p.Get(name); value != "" {
+			lines = append(lines, fmt.Sprintf("- %s: %s", name, value))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func LoadPreferences(ctx context.Context, memoryStorage storage.MemoryStorage, chatID string) (*Preferences, error) {
+	prefs := &Preferences{}
+
+	data, err := memoryStorage.GetConfig(ctx, preferencesKeyPrefix+chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preferences: %w", err)
+	}
+	if data == "" {
+		return prefs, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), prefs); err != nil {
+		return nil, fmt.Errorf("failed to parse preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+func SavePreferences(ctx context.Context, memoryStorage storage.MemoryStorage, chatID string, prefs *Preferences) error {
+	value := ""
+	if !prefs.Empty() {
+		data, err := json.Marshal(prefs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal preferences: %w", err)
+		}
+		value = string(data)
+	}
+
+	if err := memoryStorage.SetConfig(ctx, preferencesKeyPrefix+chatID, value); err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package context
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+// promptTemplateDir holds the user-editable prompt templates: PROMPT.md for
+// every channel, and PROMPT.<channel>.md to override it on one channel.
+const promptTemplateDir = "config"
+
+// PromptData is what a prompt template is rendered with.
+type PromptData struct {
+	Soul   string
+	User   string
+	Agents string
+	// Identity is Soul, User and Agents joined the way the default prompt
+	// starts.
+	Identity         string
+	Memory           string
+	DailyNotes       []string
+	Preferences      string
+	WorkspaceChanges string
+	Tools            []tools.ToolSchema
+	// ToolList describes Tools at the configured level of detail.
+	ToolList string
+	// ToolFormat explains the JSON format the agent parses. Without it the
+	// model does not know how to call tools.
+	ToolFormat           string
+	ConversationTemplate string
+	ChannelGuidance      string
+	Skills               string
+	Channel              string
+	ChatID               string
+	Time                 time.Time
+}
+
+var promptFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+func promptTemplatePath(channel string) string {
+	if channel == "" {
+		return promptTemplateDir + "/PROMPT.md"
+	}
+	return promptTemplateDir + "/PROMPT." + channel + ".md"
+}
+
+// loadPromptTemplate returns the template for the channel, or nil to use the
+// default prompt. A template that does not parse is skipped with a warning
+// rather than failing every message.
+func (b *Builder) loadPromptTemplate(ctx context.Context, channel string) *template.Template {
+	paths := []string{promptTemplatePath("")}
+	if channel != "" && !strings.ContainsAny(channel, `/\.`) {
+		paths = append([]string{promptTemplatePath(channel)}, paths...)
+	}
+
+	for _, path := range paths {
+		if exists, err := b.storage.FileExists(ctx, path); err != nil || !exists {
+			continue
+		}
+
+		text, err := b.storage.ReadFile(ctx, path)
+		if err != nil {
+			logger.Warn("Failed to read prompt template", "path", path, "error", err)
+			continue
+		}
+
+		tmpl, err := template.New(path).Funcs(promptFuncs).Option("missingkey=error").Parse(string(text))
+		if err != nil {
+			logger.Warn("Invalid prompt template, using the default prompt", "path", path, "error", err)
+			return nil
+		}
+		return tmpl
+	}
+
+	return nil
+}
+
+func (c *Context) renderTemplate(toolSchemas []tools.ToolSchema) (string, error) {
+	data := PromptData{
+		Soul:                 c.Soul,
+		User:                 c.User,
+		Agents:               c.Agents,
+		Identity:             c.SystemPrompt,
+		Memory:               c.Memory,
+		DailyNotes:           c.DailyNotes,
+		WorkspaceChanges:     c.WorkspaceChanges,
+		Tools:                toolSchemas,
+		ToolFormat:           ToolFormat,
+		ConversationTemplate: c.ConversationTemplate,
+		ChannelGuidance:      c.ChannelGuidance,
+		Skills:               c.Skills,
+		Channel:              c.Channel,
+		ChatID:               c.ChatID,
+		Time:                 c.Time,
+	}
+	if c.Preferences != nil {
+		data.Preferences = c.Preferences.String()
+	}
+
+	var toolList strings.Builder
+	writeToolList(&toolList, toolSchemas, c.toolDetail)
+	data.ToolList = toolList.String()
+
+	var buf bytes.Buffer
+	if err := c.template.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+
+	prompt := strings.TrimSpace(buf.String())
+	if len(toolSchemas) > 0 && !strings.Contains(prompt, `"tool_calls"`) {
+		logger.Warn("Prompt template does not include .ToolFormat, the model will not know how to call tools", "template", c.template.Name())
+	}
+	return prompt, nil
+}
@@ -0,0 +1,76 @@
+package context
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func newPromptTestBuilder(t *testing.T, files map[string]string) *Builder {
+	tempDir := t.TempDir()
+	fileStorage := storage.NewFileStorage(tempDir)
+	files["config/SOUL.md"] = "You are Claw."
+	files["config/USER.md"] = "The user is Ana."
+	for path, content := range files {
+		if err := fileStorage.WriteFile(context.Background(), path, []byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	return NewBuilder(&Config{
+		Storage:       fileStorage,
+		MemoryStorage: storage.NewFileSystemMemoryStorage(filepath.Join(tempDir, "memory")),
+	})
+}
+
+func TestPromptTemplates(t *testing.T) {
+	builder := newPromptTestBuilder(t, map[string]string{
+		"config/PROMPT.md":          "{{.Soul}}\nTalking to: {{.User}} on {{.Channel}} at {{.Time.Format \"2006\"}}\nTools: {{range .Tools}}{{.Name}} {{end}}\n{{.ToolFormat}}\n{{.Skills}}",
+		"config/PROMPT.telegram.md": "{{.Soul}} Be terse.",
+	})
+	schemas := []tools.ToolSchema{{Name: "get_time", Description: "Get the time"}}
+
+	ctx := tools.WithChatID(tools.WithChannel(context.Background(), "websocket"), "chat")
+	result, err := builder.Build(ctx, schemas)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	result.Skills = "## Active Skills"
+
+	prompt := result.BuildSystemPrompt(schemas)
+	for _, want := range []string{"You are Claw.", "Talking to: The user is Ana. on websocket at 20", "Tools: get_time", `"tool_calls"`, "## Active Skills"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt, got:\n%s", want, prompt)
+		}
+	}
+
+	ctx = tools.WithChannel(context.Background(), "telegram")
+	result, _ = builder.Build(ctx, nil)
+	if prompt := result.BuildSystemPrompt(nil); prompt != "You are Claw. Be terse." {
+		t.Errorf("Expected the Telegram override, got %q", prompt)
+	}
+}
+
+func TestPromptTemplateFallsBackToDefault(t *testing.T) {
+	for name, text := range map[string]string{
+		"parse error":   "{{.Soul",
+		"unknown field": "{{.Nope}}",
+	} {
+		builder := newPromptTestBuilder(t, map[string]string{"config/PROMPT.md": text})
+
+		result, err := builder.Build(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("%s: Build failed: %v", name, err)
+		}
+		result.ChannelGuidance = "Keep it short."
+
+		prompt := result.BuildSystemPrompt(nil)
+		if !strings.HasPrefix(prompt, "You are Claw.\n\nThe user is Ana.") || !strings.HasSuffix(prompt, "\n\nKeep it short.") {
+			t.Errorf("%s: expected the default prompt, got %q", name, prompt)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package context
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+// How much of each tool the prompt describes.
+const (
+	ToolDetailNames        = "names"
+	ToolDetailDescriptions = "descriptions"
+	ToolDetailFull         = "full"
+)
+
+const defaultDailyNoteDays = 7
+
+// Sections controls what goes into the system prompt and how large each part
+// may get, in estimated tokens. The zero value includes everything without
+// limits.
+type Sections struct {
+	NoMemory     bool
+	MemoryTokens int
+	// SummarizeMemory condenses memory over MemoryTokens with Summarize
+	// instead of cutting it off.
+	SummarizeMemory bool
+
+	NoDailyNotes    bool
+	DailyNoteDays   int
+	DailyNoteTokens int
+
+	// ToolDetail is one of the ToolDetail constants; empty means
+	// descriptions.
+	ToolDetail string
+}
+
+// Summarizer condenses text to about maxTokens.
+type Summarizer func(ctx context.Context, text string, maxTokens int) (string, error)
+
+// memorySummary caches the summary of the memory it was made from, so memory
+// is only summarized again after it changes.
+type memorySummary struct {
+	mu      sync.Mutex
+	source  [sha256.Size]byte
+	summary string
+}
+
+func (b *Builder) fitMemory(ctx context.Context, memory string) string {
+	limit := b.sections.MemoryTokens
+	if limit <= 0 || llm.EstimateTokens(memory) <= limit {
+		return memory
+	}
+
+	if b.sections.SummarizeMemory && b.summarize != nil {
+		source := sha256.Sum256([]byte(memory))
+
+		b.memorySummary.mu.Lock()
+		defer b.memorySummary.mu.Unlock()
+		if b.memorySummary.summary != "" && b.memorySummary.source == source {
+			return b.memorySummary.summary
+		}
+
+		summary, err := b.summarize(ctx, memory, limit)
+		if err == nil && summary != "" {
+			logger.Info("Summarized memory for the prompt", "tokens", llm.EstimateTokens(memory), "summary_tokens", llm.EstimateTokens(summary))
+			b.memorySummary.source = source
+			b.memorySummary.summary = truncateTokens(summary, limit)
+			return b.memorySummary.summary
+		}
+		logger.Warn("Failed to summarize memory, truncating it", "error", err)
+	}
+
+	return truncateTokens(memory, limit)
+}
+
+// truncateTokens cuts text to about limit tokens at a line break and notes
+// that the rest was left out.
+func truncateTokens(text string, limit int) string {
+	if limit <= 0 || llm.EstimateTokens(text) <= limit {
+		return text
+	}
+
+	const marker = "\n[truncated]"
+	var kept strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if llm.EstimateTokens(kept.String()+line) > limit {
+			break
+		}
+		kept.WriteString(line)
+	}
+
+	if kept.Len() == 0 {
+		runes := []rune(text)
+		for n := len(runes); n > 0; n /= 2 {
+			if llm.EstimateTokens(string(runes[:n])) <= limit {
+				return string(runes[:n]) + marker
+			}
+		}
+		return strings.TrimPrefix(marker, "\n")
+	}
+	return strings.TrimRight(kept.String(), "\n") + marker
+}
+
+func writeToolList(prompt *strings.Builder, toolSchemas []tools.ToolSchema, detail string) {
+	if detail == ToolDetailNames {
+		names := make([]string, 0, len(toolSchemas))
+		for _, tool := range toolSchemas {
+			names = append(names, tool.Name)
+		}
+		prompt.WriteString(strings.Join(names, ", "))
+		prompt.WriteString("\n")
+		return
+	}
+
+	for _, tool := range toolSchemas {
+		prompt.WriteString(fmt.Sprintf("- **%s**: %s\n", tool.Name, tool.Description))
+		if detail == ToolDetailFull && len(tool.Parameters) > 0 {
+			var params bytes.Buffer
+			if err := json.Compact(&params, tool.Parameters); err == nil {
+				prompt.WriteString(fmt.Sprintf("  Parameters: %s\n", params.String()))
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func newSectionsTestBuilder(t *testing.T, sections Sections, summarize Summarizer) (*Builder, storage.MemoryStorage) {
+	tempDir := t.TempDir()
+	fileStorage := storage.NewFileStorage(tempDir)
+	fileStorage.WriteFile(context.Background(), "config/SOUL.md", []byte("Soul"))
+	fileStorage.WriteFile(context.Background(), "config/USER.md", []byte("User"))
+	memoryStorage := storage.NewFileSystemMemoryStorage(filepath.Join(tempDir, "memory"))
+
+	return NewBuilder(&Config{
+		Storage:       fileStorage,
+		MemoryStorage: memoryStorage,
+		Sections:      sections,
+		Summarize:     summarize,
+	}), memoryStorage
+}
+
+func TestMemoryBudget(t *testing.T) {
+	ctx := context.Background()
+	memory := strings.Repeat("- The user likes hiking in the Alps\n", 50)
+
+	builder, memoryStorage := newSectionsTestBuilder(t, Sections{MemoryTokens: 40}, nil)
+	memoryStorage.SetMemory(ctx, memory)
+
+	result, err := builder.Build(ctx, nil)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if tokens := llm.EstimateTokens(result.Memory); tokens > 45 || !strings.HasSuffix(result.Memory, "[truncated]") {
+		t.Errorf("Expected memory cut to about 40 tokens, got %d tokens: %q", tokens, result.Memory)
+	}
+	if !strings.HasPrefix(result.Memory, "- The user likes hiking in the Alps\n") {
+		t.Errorf("Expected whole lines to be kept, got %q", result.Memory)
+	}
+
+	calls := 0
+	summarize := func(ctx context.Context, text string, maxTokens int) (string, error) {
+		calls++
+		if maxTokens != 40 || text != memory {
+			t.Errorf("Unexpected summary request for %d tokens", maxTokens)
+		}
+		return "- Likes hiking", nil
+	}
+	builder, memoryStorage = newSectionsTestBuilder(t, Sections{MemoryTokens: 40, SummarizeMemory: true}, summarize)
+	memoryStorage.SetMemory(ctx, memory)
+
+	for i := 0; i < 2; i++ {
+		result, _ = builder.Build(ctx, nil)
+		if result.Memory != "- Likes hiking" {
+			t.Errorf("Expected the summarized memory, got %q", result.Memory)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the summary to be reused until memory changes, got %d calls", calls)
+	}
+
+	failing := func(ctx context.Context, text string, maxTokens int) (string, error) {
+		return "", errors.New("LLM down")
+	}
+	builder, memoryStorage = newSectionsTestBuilder(t, Sections{MemoryTokens: 40, SummarizeMemory: true}, failing)
+	memoryStorage.SetMemory(ctx, memory)
+	if result, _ = builder.Build(ctx, nil); !strings.HasSuffix(result.Memory, "[truncated]") {
+		t.Errorf("Expected truncation when summarizing fails, got %q", result.Memory)
+	}
+}
+
+func TestDisabledSectionsAndDailyNoteBudget(t *testing.T) {
+	ctx := context.Background()
+
+	builder, memoryStorage := newSectionsTestBuilder(t, Sections{NoMemory: true, DailyNoteDays: 2, DailyNoteTokens: 30}, nil)
+	memoryStorage.SetMemory(ctx, "secret memory")
+	for i := 0; i < 3; i++ {
+		date := time.Now().AddDate(0, 0, -i).Format("2006-01-02")
+		memoryStorage.SetDailyNote(ctx, date, strings.Repeat("Worked on the garden shed. ", 5))
+	}
+
+	result, err := builder.Build(ctx, nil)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if result.Memory != "" {
+		t.Errorf("Expected memory to be left out, got %q", result.Memory)
+	}
+	if len(result.DailyNotes) == 0 || len(result.DailyNotes) > 2 {
+		t.Fatalf("Expected at most two days of notes, got %d", len(result.DailyNotes))
+	}
+	total := 0
+	for _, note := range result.DailyNotes {
+		total += llm.EstimateTokens(note)
+	}
+	if total > 35 {
+		t.Errorf("Expected the notes to fit about 30 tokens, got %d", total)
+	}
+
+	builder, _ = newSectionsTestBuilder(t, Sections{NoDailyNotes: true}, nil)
+	if result, _ = builder.Build(ctx, nil); len(result.DailyNotes) != 0 {
+		t.Errorf("Expected daily notes to be left out, got %v", result.DailyNotes)
+	}
+}
+
+func TestToolDetail(t *testing.T) {
+	schemas := []tools.ToolSchema{
+		{Name: "get_time", Description: "Get the current time", Parameters: json.RawMessage(`{"type": "object"}`)},
+		{Name: "echo", Description: "Echo a message", Parameters: json.RawMessage(`{"type": "object"}`)},
+	}
+
+	for detail, want := range map[string]string{
+		ToolDetailNames: "get_time, echo\n",
+		"":              "- **get_time**: Get the current time\n- **echo**",
+		ToolDetailFull:  "- **get_time**: Get the current time\n  Parameters: {\"type\":\"object\"}\n",
+	} {
+		prompt := (&Context{SystemPrompt: "Soul", toolDetail: detail}).BuildSystemPrompt(schemas)
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q with detail %q, got:\n%s", want, detail, prompt)
+		}
+		if detail != ToolDetailFull && strings.Contains(prompt, "Parameters:") {
+			t.Errorf("Expected no parameters with detail %q", detail)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const (
+	toolCallDir      = "tool_calls"
+	maxEmbeddedImage = 5 * 1024 * 1024
+)
+
+var (
+	ErrEmptySession  = errors.New("session has no messages")
+	ErrInvalidChatID = errors.New("invalid chat id")
+)
+
+type ToolCallRecord struct {
+	ResponseID string        `json:"response_id"`
+	Request    string        `json:"request"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Tools      []bus.ToolUse `json:"tools"`
+}
+
+func SaveToolCalls(ctx context.Context, store storage.Storage, chatID string, record *ToolCallRecord) error {
+	if !validChatID(chatID) {
+		return ErrInvalidChatID
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tool calls: %w", err)
+	}
+
+	filePath := path.Join(toolCallDir, chatID, record.ResponseID+".json")
+	if err := store.WriteFile(ctx, filePath, data); err != nil {
+		return fmt.Errorf("failed to write tool calls: %w", err)
+	}
+	return nil
+}
+
+func LoadToolCalls(ctx context.Context, store storage.Storage, chatID string) ([]*ToolCallRecord, error) {
+	if !validChatID(chatID) {
+		return nil, ErrInvalidChatID
+	}
+
+	files, err := store.ListFiles(ctx, path.Join(toolCallDir, chatID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tool calls: %w", err)
+	}
+
+	records := make([]*ToolCallRecord, 0, len(files))
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".json") {
+			continue
+		}
+
+		data, err := store.ReadFile(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tool calls: %w", err)
+		}
+
+		var record ToolCallRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	return records, nil
+}
+
+type Config struct {
+	SessionStorage storage.SessionStorage
+	Storage        storage.Storage
+}
+
+type Exporter struct {
+	sessionStorage storage.SessionStorage
+	storage        storage.Storage
+	now            func() time.Time
+}
+
+func NewExporter(config *Config) (*Exporter, error) {
+	if config == nil || config.SessionStorage == nil {
+		return nil, fmt.Errorf("session storage is required")
+	}
+
+	return &Exporter{
+		sessionStorage: config.SessionStorage,
+		storage:        config.Storage,
+		now:            time.Now,
+	}, nil
+}
+
+func (e *Exporter) Export(ctx context.Context, chatID string) ([]byte, error) {
+	if !validChatID(chatID) {
+		return nil, ErrInvalidChatID
+	}
+
+	messages, err := e.sessionStorage.GetMessages(ctx, chatID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, ErrEmptySession
+	}
+
+	page := &pageData{
+		ChatID:     chatID,
+		ExportedAt: e.now().UTC().Format("2006-01-02 15:04 MST"),
+	}
+
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			continue
+		}
+
+		view := messageView{
+			Role: msg.Role,
+			Body: renderContent(msg.Content, e.embedImage(ctx)),
+		}
+		if msg.Timestamp > 0 {
+			view.Time = time.Unix(msg.Timestamp, 0).UTC().Format("2006-01-02 15:04")
+		}
+		page.Messages = append(page.Messages, view)
+	}
+
+	if e.storage != nil {
+		records, err := LoadToolCalls(ctx, e.storage, chatID)
+		if err != nil {
+			return nil, err
+		}
+		page.ToolCalls = records
+	}
+
+	var buf bytes.Buffer
+	if err := pageTemplate.Execute(&buf, page); err != nil {
+		return nil, fmt.Errorf("failed to render export: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *Exporter) embedImage(ctx context.Context) func(filePath, mimeType string) (string, bool) {
+	return func(filePath, mimeType string) (string, bool) {
+		if e.storage == nil {
+			return "", false
+		}
+
+		if mimeType == "" {
+			mimeType = mime.TypeByExtension(path.Ext(filePath))
+		}
+		if !strings.HasPrefix(mimeType, "image/") {
+			return "", false
+		}
+
+		data, err := e.storage.ReadFile(ctx, filePath)
+		if err != nil || len(data) > maxEmbeddedImage {
+			return "", false
+		}
+
+		return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), true
+	}
+}
+
+func validChatID(chatID string) bool {
+	return chatID != "" && chatID != "." && chatID != ".." && !strings.ContainsAny(chatID, "/\\")
+}
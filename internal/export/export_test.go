@@ -0,0 +1,182 @@
+package export
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func newTestExporter(t *testing.T) (*Exporter, storage.SessionStorage, storage.Storage) {
+	t.Helper()
+
+	dir := t.TempDir()
+	sessions := storage.NewFileSystemSessionStorage(dir + "/sessions")
+	files := storage.NewFileStorage(dir)
+
+	exporter, err := NewExporter(&Config{SessionStorage: sessions, Storage: files})
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	exporter.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	return exporter, sessions, files
+}
+
+func TestExportRendersMessages(t *testing.T) {
+	ctx := context.Background()
+	exporter, sessions, files := newTestExporter(t)
+
+	image := []byte("\x89PNG fake image")
+	if err := files.WriteFile(ctx, "uploads/cat.png", image); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+
+	sessions.SaveMessage(ctx, "chat-1", "system", "internal prompt")
+	sessions.SaveMessage(ctx, "chat-1", "user", fmt.Sprintf("What is <this>?\n\n[Attached photo saved to uploads/cat.png (image/png, %d bytes)]", len(image)))
+	sessions.SaveMessage(ctx, "chat-1", "assistant", "A **cat**. Try `ls`:\n```python\n# list files\nprint(\"hi\", 42)\n```")
+
+	page, err := exporter.Export(ctx, "chat-1")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	html := string(page)
+
+	for _, want := range []string{
+		"<h1>Conversation chat-1</h1>",
+		"2 messages · exported 2024-05-01 12:00 UTC",
+		"What is &lt;this&gt;?",
+		`<img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(image) + `" alt="cat.png">`,
+		"<strong>cat</strong>",
+		"<code>ls</code>",
+		`<span class="comment"># list files</span>`,
+		`<span class="string">&#34;hi&#34;</span>`,
+		`<span class="number">42</span>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected export to contain %q", want)
+		}
+	}
+	if strings.Contains(html, "internal prompt") {
+		t.Error("Expected system messages to be left out")
+	}
+}
+
+func TestExportFallsBackForMissingAttachments(t *testing.T) {
+	ctx := context.Background()
+	exporter, sessions, _ := newTestExporter(t)
+
+	sessions.SaveMessage(ctx, "chat-1", "user", "[Attached document saved to uploads/report.pdf (application/pdf, 2048 bytes)]\n[Attached photo saved to uploads/gone.jpg (1024 bytes)]")
+
+	page, err := exporter.Export(ctx, "chat-1")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	html := string(page)
+	if !strings.Contains(html, "Attached document: report.pdf (2048 bytes)") || !strings.Contains(html, "Attached photo: gone.jpg (1024 bytes)") {
+		t.Errorf("Expected attachment placeholders, got %s", html)
+	}
+	if strings.Contains(html, "<img") {
+		t.Error("Expected no embedded images")
+	}
+}
+
+func TestExportIncludesToolCalls(t *testing.T) {
+	ctx := context.Background()
+	exporter, sessions, files := newTestExporter(t)
+
+	sessions.SaveMessage(ctx, "chat-1", "user", "read the notes")
+	sessions.SaveMessage(ctx, "chat-1", "assistant", "Done")
+
+	err := SaveToolCalls(ctx, files, "chat-1", &ToolCallRecord{
+		ResponseID: "resp-1",
+		Request:    "read the notes",
+		Timestamp:  time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC),
+		Tools: []bus.ToolUse{
+			{Name: "read_file", Input: `{"path":"notes.md"}`, Output: "<notes>", Duration: 12},
+			{Name: "web_search", Error: "timeout", Duration: 3000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to save tool calls: %v", err)
+	}
+
+	page, err := exporter.Export(ctx, "chat-1")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	html := string(page)
+
+	for _, want := range []string{
+		"Appendix: tool calls",
+		"2024-05-01 11:00 · read the notes",
+		"<td>read_file</td>",
+		"&lt;notes&gt;",
+		"Error: timeout",
+		"<td>3000 ms</td>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected export to contain %q", want)
+		}
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	ctx := context.Background()
+	exporter, _, _ := newTestExporter(t)
+
+	if _, err := exporter.Export(ctx, "empty"); !errors.Is(err, ErrEmptySession) {
+		t.Errorf("Expected ErrEmptySession, got %v", err)
+	}
+	for _, chatID := range []string{"", "..", "../secrets", `a\b`} {
+		if _, err := exporter.Export(ctx, chatID); !errors.Is(err, ErrInvalidChatID) {
+			t.Errorf("Expected ErrInvalidChatID for %q, got %v", chatID, err)
+		}
+	}
+	if _, err := NewExporter(&Config{}); err == nil {
+		t.Error("Expected error without session storage")
+	}
+}
+
+func TestShareStore(t *testing.T) {
+	ctx := context.Background()
+	shares := NewShareStore(storage.NewFileStorage(t.TempDir()))
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	shares.now = func() time.Time { return now }
+
+	share, err := shares.Create(ctx, "chat-1", 0)
+	if err != nil {
+		t.Fatalf("Failed to create share: %v", err)
+	}
+	if len(share.Token) != 32 || !share.ExpiresAt.Equal(now.Add(DefaultShareTTL)) {
+		t.Errorf("Unexpected share %+v", share)
+	}
+
+	resolved, err := shares.Resolve(ctx, share.Token)
+	if err != nil || resolved.ChatID != "chat-1" {
+		t.Fatalf("Expected share to resolve, got %+v, %v", resolved, err)
+	}
+
+	if _, err := shares.Resolve(ctx, "../../config"); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Expected ErrShareNotFound for malformed token, got %v", err)
+	}
+	if _, err := shares.Create(ctx, "chat-1", MaxShareTTL+time.Hour); err == nil {
+		t.Error("Expected error for ttl above the maximum")
+	}
+
+	now = now.Add(DefaultShareTTL)
+	if _, err := shares.Resolve(ctx, share.Token); !errors.Is(err, ErrShareExpired) {
+		t.Errorf("Expected ErrShareExpired, got %v", err)
+	}
+	if _, err := shares.Resolve(ctx, share.Token); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Expected expired share to be removed, got %v", err)
+	}
+}
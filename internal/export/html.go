@@ -0,0 +1,224 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type pageData struct {
+	ChatID     string
+	ExportedAt string
+	Messages   []messageView
+	ToolCalls  []*ToolCallRecord
+}
+
+type messageView struct {
+	Role string
+	Time string
+	Body template.HTML
+}
+
+type imageEmbedder func(filePath, mimeType string) (string, bool)
+
+var (
+	attachmentPattern = regexp.MustCompile(`^\[Attached (\w+) saved to (\S+) \((?:([^,()]+), )?(\d+) bytes\)\]$`)
+	inlineCodePattern = regexp.MustCompile("`([^`\n]+)`")
+	boldPattern       = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+
+	cStylePattern    = regexp.MustCompile(`(?s)(/\*.*?\*/|//[^\n]*)|("(?:\\.|[^"\\\n])*"|'(?:\\.|[^'\\\n])*'|` + "`[^`]*`" + `)|\b(\d+(?:\.\d+)?)\b|\b[A-Za-z_]\w*\b`)
+	hashStylePattern = regexp.MustCompile(`(#[^\n]*)|("(?:\\.|[^"\\\n])*"|'(?:\\.|[^'\\\n])*')|\b(\d+(?:\.\d+)?)\b|\b[A-Za-z_]\w*\b`)
+)
+
+var hashCommentLanguages = map[string]bool{
+	"python": true, "py": true, "sh": true, "bash": true, "shell": true, "zsh": true,
+	"yaml": true, "yml": true, "toml": true, "ruby": true, "rb": true, "dockerfile": true,
+}
+
+var keywords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"def": true, "default": true, "defer": true, "do": true, "elif": true, "else": true,
+	"except": true, "export": true, "false": true, "False": true, "finally": true, "fn": true,
+	"for": true, "from": true, "func": true, "function": true, "go": true, "if": true,
+	"import": true, "in": true, "interface": true, "let": true, "map": true, "new": true,
+	"nil": true, "None": true, "null": true, "package": true, "pub": true, "raise": true,
+	"range": true, "return": true, "select": true, "struct": true, "switch": true, "then": true,
+	"throw": true, "true": true, "True": true, "try": true, "type": true, "var": true,
+	"while": true, "with": true, "yield": true, "async": true, "await": true, "fi": true,
+	"done": true, "echo": true, "self": true, "this": true, "impl": true, "use": true,
+}
+
+func renderContent(text string, embed imageEmbedder) template.HTML {
+	var out strings.Builder
+	var paragraph []string
+
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		out.WriteString("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				out.WriteString("<br>\n")
+			}
+			out.WriteString(renderInline(line))
+		}
+		out.WriteString("</p>\n")
+		paragraph = nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			lang := strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+
+			code := make([]string, 0)
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			writeCodeBlock(&out, lang, strings.Join(code, "\n"))
+		case trimmed == "":
+			flush()
+		case attachmentPattern.MatchString(trimmed):
+			flush()
+			writeAttachment(&out, attachmentPattern.FindStringSubmatch(trimmed), embed)
+		default:
+			paragraph = append(paragraph, lines[i])
+		}
+	}
+	flush()
+
+	return template.HTML(out.String())
+}
+
+func renderInline(line string) string {
+	var out strings.Builder
+	last := 0
+	for _, match := range inlineCodePattern.FindAllStringSubmatchIndex(line, -1) {
+		out.WriteString(boldPattern.ReplaceAllString(html.EscapeString(line[last:match[0]]), "<strong>$1</strong>"))
+		out.WriteString("<code>" + html.EscapeString(line[match[2]:match[3]]) + "</code>")
+		last = match[1]
+	}
+	out.WriteString(boldPattern.ReplaceAllString(html.EscapeString(line[last:]), "<strong>$1</strong>"))
+	return out.String()
+}
+
+func writeCodeBlock(out *strings.Builder, lang, code string) {
+	out.WriteString(`<div class="code">`)
+	if lang != "" {
+		fmt.Fprintf(out, `<div class="lang">%s</div>`, html.EscapeString(lang))
+	}
+	fmt.Fprintf(out, `<pre><code class="language-%s">%s</code></pre></div>`+"\n", html.EscapeString(lang), highlight(lang, code))
+}
+
+func writeAttachment(out *strings.Builder, match []string, embed imageEmbedder) {
+	kind, filePath, mimeType, size := match[1], match[2], match[3], match[4]
+
+	if embed != nil {
+		if src, ok := embed(filePath, mimeType); ok {
+			fmt.Fprintf(out, `<figure class="attachment"><img src="%s" alt="%s"></figure>`+"\n",
+				html.EscapeString(src), html.EscapeString(path.Base(filePath)))
+			return
+		}
+	}
+
+	fmt.Fprintf(out, `<p class="attachment">Attached %s: %s (%s bytes)</p>`+"\n",
+		html.EscapeString(kind), html.EscapeString(path.Base(filePath)), size)
+}
+
+func highlight(lang, code string) string {
+	pattern := cStylePattern
+	if hashCommentLanguages[strings.ToLower(lang)] {
+		pattern = hashStylePattern
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringSubmatchIndex(code, -1) {
+		out.WriteString(html.EscapeString(code[last:match[0]]))
+
+		token := code[match[0]:match[1]]
+		class := ""
+		switch {
+		case match[2] >= 0:
+			class = "comment"
+		case match[4] >= 0:
+			class = "string"
+		case match[6] >= 0:
+			class = "number"
+		case keywords[token]:
+			class = "keyword"
+		}
+
+		if class == "" {
+			out.WriteString(html.EscapeString(token))
+		} else {
+			fmt.Fprintf(&out, `<span class="%s">%s</span>`, class, html.EscapeString(token))
+		}
+		last = match[1]
+	}
+	out.WriteString(html.EscapeString(code[last:]))
+
+	return out.String()
+}
+
+var pageTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Conversation {{.ChatID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; background: #f5f5f7; color: #1d1d1f; margin: 0; }
+main { max-width: 820px; margin: 0 auto; padding: 24px 16px 48px; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 24px; }
+header p { color: #666; font-size: 14px; }
+.message { border-radius: 12px; padding: 12px 16px; margin: 12px 0; background: #fff; box-shadow: 0 1px 2px rgba(0,0,0,.08); }
+.message.user { background: #e3efff; margin-left: 15%; }
+.message.assistant { margin-right: 15%; }
+.meta { font-size: 12px; color: #888; margin-bottom: 4px; text-transform: capitalize; }
+.message p { margin: 6px 0; line-height: 1.5; }
+code { font-family: "SFMono-Regular", Menlo, Consolas, monospace; font-size: 13px; background: #f0f0f0; padding: 1px 4px; border-radius: 4px; }
+.code { margin: 8px 0; }
+.code .lang { font-size: 11px; color: #999; text-transform: uppercase; }
+pre { background: #1e1e1e; color: #d4d4d4; padding: 12px; border-radius: 8px; overflow-x: auto; }
+pre code { background: none; padding: 0; color: inherit; }
+.keyword { color: #569cd6; }
+.string { color: #ce9178; }
+.number { color: #b5cea8; }
+.comment { color: #6a9955; font-style: italic; }
+.attachment img { max-width: 100%; border-radius: 8px; }
+p.attachment { color: #666; font-style: italic; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; margin-bottom: 16px; }
+th, td { border: 1px solid #ddd; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #fafafa; }
+</style>
+</head>
+<body>
+<main>
+<header>
+<h1>Conversation {{.ChatID}}</h1>
+<p>{{len .Messages}} messages · exported {{.ExportedAt}}</p>
+</header>
+{{range .Messages}}<section class="message {{.Role}}">
+<div class="meta">{{.Role}}{{if .Time}} · {{.Time}}{{end}}</div>
+{{.Body}}</section>
+{{end}}{{if .ToolCalls}}<section id="tool-calls">
+<h2>Appendix: tool calls</h2>
+{{range .ToolCalls}}<h3>{{.Timestamp.UTC.Format "2006-01-02 15:04"}} · {{.Request}}</h3>
+<table>
+<tr><th>Tool</th><th>Input</th><th>Output</th><th>Duration</th></tr>
+{{range .Tools}}<tr><td>{{.Name}}</td><td>{{.Input}}</td><td>{{if .Error}}Error: {{.Error}}{{else}}{{.Output}}{{end}}</td><td>{{.Duration}} ms</td></tr>
+{{end}}</table>
+{{end}}</section>
+{{end}}</main>
+</body>
+</html>
+`))
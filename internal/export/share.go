@@ -0,0 +1,117 @@
+package export
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const (
+	sharesDir       = "shares"
+	DefaultShareTTL = 24 * time.Hour
+	MaxShareTTL     = 30 * 24 * time.Hour
+)
+
+var (
+	ErrShareNotFound = errors.New("share link not found")
+	ErrShareExpired  = errors.New("share link expired")
+
+	shareTokenPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+type Share struct {
+	Token     string    `json:"token"`
+	ChatID    string    `json:"chat_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ShareStore struct {
+	storage storage.Storage
+	now     func() time.Time
+}
+
+func NewShareStore(storage storage.Storage) *ShareStore {
+	return &ShareStore{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+func (s *ShareStore) Create(ctx context.Context, chatID string, ttl time.Duration) (*Share, error) {
+	if chatID == "" {
+		return nil, fmt.Errorf("chat id cannot be empty")
+	}
+	if ttl <= 0 {
+		ttl = DefaultShareTTL
+	}
+	if ttl > MaxShareTTL {
+		return nil, fmt.Errorf("share ttl cannot exceed %s", MaxShareTTL)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate share token: %w", err)
+	}
+
+	now := s.now()
+	share := &Share{
+		Token:     hex.EncodeToString(buf),
+		ChatID:    chatID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	data, err := json.MarshalIndent(share, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal share: %w", err)
+	}
+	if err := s.storage.WriteFile(ctx, sharePath(share.Token), data); err != nil {
+		return nil, fmt.Errorf("failed to save share: %w", err)
+	}
+
+	return share, nil
+}
+
+func (s *ShareStore) Resolve(ctx context.Context, token string) (*Share, error) {
+	if !shareTokenPattern.MatchString(token) {
+		return nil, ErrShareNotFound
+	}
+
+	exists, err := s.storage.FileExists(ctx, sharePath(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check share: %w", err)
+	}
+	if !exists {
+		return nil, ErrShareNotFound
+	}
+
+	data, err := s.storage.ReadFile(ctx, sharePath(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read share: %w", err)
+	}
+
+	var share Share
+	if err := json.Unmarshal(data, &share); err != nil {
+		return nil, fmt.Errorf("failed to parse share: %w", err)
+	}
+
+	if !s.now().Before(share.ExpiresAt) {
+		s.storage.DeleteFile(ctx, sharePath(token))
+		return nil, ErrShareExpired
+	}
+
+	return &share, nil
+}
+
+func sharePath(token string) string {
+	return path.Join(sharesDir, token+".json")
+}
@@ -0,0 +1,405 @@
+package filetools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const (
+	// maxArchiveSize limits both the archive file and what it unpacks to, so
+	// a small archive cannot fill the disk.
+	maxArchiveSize    = 50 << 20
+	maxArchiveEntries = 10000
+)
+
+type archiveEntry struct {
+	name string
+	data []byte
+	size int64
+}
+
+// CreateArchiveTool packs files and directories into a zip or tar.gz file.
+type CreateArchiveTool struct {
+	storage storage.Storage
+}
+
+func NewCreateArchiveTool(storage storage.Storage) *CreateArchiveTool {
+	return &CreateArchiveTool{
+		storage: storage,
+	}
+}
+
+func (t *CreateArchiveTool) Name() string {
+	return "create_archive"
+}
+
+func (t *CreateArchiveTool) Description() string {
+	return "Pack files and directories into a .zip or .tar.gz archive"
+}
+
+func (t *CreateArchiveTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{Invalidates: []string{"read_file"}}
+}
+
+func (t *CreateArchiveTool) Parameters() json.RawMessage {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"paths": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Files and directories to pack; they keep their names inside the archive"
+			},
+			"destination": {
+				"type": "string",
+				"description": "Path of the archive to create, ending in .zip, .tar.gz or .tgz"
+			},
+			"overwrite": {
+				"type": "boolean",
+				"description": "Replace the archive if it already exists (default false)"
+			}
+		},
+		"required": ["paths", "destination"],
+		"additionalProperties": false
+	}`)
+	return params
+}
+
+func (t *CreateArchiveTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	destination, err := localPathParam(params, "destination")
+	if err != nil {
+		return "", err
+	}
+	format := archiveFormat(destination)
+	if format == "" {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "destination must end in .zip, .tar.gz or .tgz",
+		}
+	}
+
+	paths, _ := params["paths"].([]interface{})
+	if len(paths) == 0 {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "paths must list at least one file or directory",
+		}
+	}
+
+	if overwrite, _ := params["overwrite"].(bool); !overwrite {
+		if exists, err := t.storage.FileExists(ctx, destination); err == nil && exists {
+			return "", &tools.ToolError{
+				Code:    "ALREADY_EXISTS",
+				Message: fmt.Sprintf("%s already exists; set overwrite to replace it", destination),
+			}
+		}
+	}
+
+	var entries []archiveEntry
+	var total int64
+	for _, raw := range paths {
+		source, err := localPathParam(map[string]interface{}{"path": raw}, "path")
+		if err != nil {
+			return "", err
+		}
+		files, err := sourceFiles(ctx, t.storage, source)
+		if err != nil {
+			return "", err
+		}
+
+		for _, file := range files {
+			from := path.Join(source, file)
+			if from == destination {
+				continue
+			}
+			data, err := t.storage.ReadFile(ctx, from)
+			if err != nil {
+				return "", &tools.ToolError{Code: "EXECUTION_FAILED", Message: fmt.Sprintf("failed to read %s", from), Err: err}
+			}
+
+			total += int64(len(data))
+			if total > maxArchiveSize || len(entries) >= maxArchiveEntries {
+				return "", &tools.ToolError{
+					Code:    "TOO_LARGE",
+					Message: fmt.Sprintf("archives may hold at most %d files and %d bytes", maxArchiveEntries, maxArchiveSize),
+				}
+			}
+			entries = append(entries, archiveEntry{name: path.Join(path.Base(source), file), data: data})
+		}
+	}
+
+	var buf bytes.Buffer
+	if format == "zip" {
+		err = writeZip(&buf, entries)
+	} else {
+		err = writeTarGz(&buf, entries)
+	}
+	if err != nil {
+		return "", &tools.ToolError{Code: "EXECUTION_FAILED", Message: "failed to create archive", Err: err}
+	}
+
+	if err := t.storage.WriteFile(ctx, destination, buf.Bytes()); err != nil {
+		return "", &tools.ToolError{Code: "EXECUTION_FAILED", Message: "failed to write archive", Err: err}
+	}
+
+	return fmt.Sprintf("Created %s with %d files (%d bytes)", destination, len(entries), buf.Len()), nil
+}
+
+// ExtractArchiveTool unpacks or lists a zip or tar(.gz) file.
+type ExtractArchiveTool struct {
+	storage storage.Storage
+}
+
+func NewExtractArchiveTool(storage storage.Storage) *ExtractArchiveTool {
+	return &ExtractArchiveTool{
+		storage: storage,
+	}
+}
+
+func (t *ExtractArchiveTool) Name() string {
+	return "extract_archive"
+}
+
+func (t *ExtractArchiveTool) Description() string {
+	return "Extract a .zip, .tar or .tar.gz archive into a directory, or list its contents"
+}
+
+func (t *ExtractArchiveTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{Invalidates: []string{"read_file"}}
+}
+
+func (t *ExtractArchiveTool) Parameters() json.RawMessage {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "The archive to extract"
+			},
+			"destination": {
+				"type": "string",
+				"description": "Directory to extract into (default: next to the archive, named after it)"
+			},
+			"list": {
+				"type": "boolean",
+				"description": "Only list the files in the archive"
+			},
+			"overwrite": {
+				"type": "boolean",
+				"description": "Replace files that already exist (default false)"
+			}
+		},
+		"required": ["path"],
+		"additionalProperties": false
+	}`)
+	return params
+}
+
+func (t *ExtractArchiveTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	archivePath, err := localPathParam(params, "path")
+	if err != nil {
+		return "", err
+	}
+
+	destination := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(archivePath, ".gz"), ".tar"), path.Ext(archivePath))
+	if _, ok := params["destination"]; ok {
+		if destination, err = localPathParam(params, "destination"); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := t.storage.ReadFile(ctx, archivePath)
+	if err != nil {
+		return "", &tools.ToolError{Code: "EXECUTION_FAILED", Message: "failed to read archive", Err: err}
+	}
+	if len(data) > maxArchiveSize {
+		return "", &tools.ToolError{Code: "TOO_LARGE", Message: fmt.Sprintf("archive is larger than %d bytes", maxArchiveSize)}
+	}
+
+	list, _ := params["list"].(bool)
+	entries, err := readArchive(data, !list)
+	if err != nil {
+		return "", &tools.ToolError{Code: "INVALID_ARCHIVE", Message: err.Error()}
+	}
+
+	if list {
+		var out strings.Builder
+		var total int64
+		for _, entry := range entries {
+			fmt.Fprintf(&out, "%s (%d bytes)\n", entry.name, entry.size)
+			total += entry.size
+		}
+		return fmt.Sprintf("%s holds %d files, %d bytes unpacked:\n\n%s", archivePath, len(entries), total, out.String()), nil
+	}
+
+	if overwrite, _ := params["overwrite"].(bool); !overwrite {
+		var existing []string
+		for _, entry := range entries {
+			if exists, err := t.storage.FileExists(ctx, path.Join(destination, entry.name)); err == nil && exists {
+				existing = append(existing, path.Join(destination, entry.name))
+			}
+		}
+		if len(existing) > 0 {
+			return "", &tools.ToolError{
+				Code:    "ALREADY_EXISTS",
+				Message: fmt.Sprintf("%s already exists; set overwrite to replace it", strings.Join(existing, ", ")),
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		target := path.Join(destination, entry.name)
+		if err := t.storage.WriteFile(ctx, target, entry.data); err != nil {
+			return "", &tools.ToolError{Code: "EXECUTION_FAILED", Message: fmt.Sprintf("failed to write %s", target), Err: err}
+		}
+	}
+
+	return fmt.Sprintf("Extracted %d files from %s into %s", len(entries), archivePath, destination), nil
+}
+
+func archiveFormat(name string) string {
+	switch lower := strings.ToLower(name); {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	}
+	return ""
+}
+
+func writeZip(w io.Writer, entries []archiveEntry) error {
+	archive := zip.NewWriter(w)
+	for _, entry := range entries {
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeTarGz(w io.Writer, entries []archiveEntry) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readArchive returns the regular files of an archive. Entries whose name
+// would leave the destination fail the whole archive. With unpack the
+// contents are read too, up to maxArchiveSize in total.
+func readArchive(data []byte, unpack bool) ([]archiveEntry, error) {
+	var entries []archiveEntry
+	var total int64
+
+	add := func(name string, size int64, r io.Reader) error {
+		name = strings.ReplaceAll(name, `\`, "/")
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("archive entry %q points outside the destination", name)
+		}
+		if len(entries) >= maxArchiveEntries {
+			return fmt.Errorf("archive has more than %d files", maxArchiveEntries)
+		}
+
+		entry := archiveEntry{name: path.Clean(name), size: size}
+		if unpack {
+			content, err := io.ReadAll(io.LimitReader(r, maxArchiveSize-total+1))
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			total += int64(len(content))
+			if total > maxArchiveSize {
+				return fmt.Errorf("archive unpacks to more than %d bytes", maxArchiveSize)
+			}
+			entry.data, entry.size = content, int64(len(content))
+		}
+		entries = append(entries, entry)
+		return nil
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zip archive: %w", err)
+		}
+		for _, file := range archive.File {
+			if !file.Mode().IsRegular() {
+				continue
+			}
+			r, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+			}
+			err = add(file.Name, int64(file.UncompressedSize64), r)
+			r.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip archive: %w", err)
+		}
+		defer gz.Close()
+		err = readTar(gz, add)
+		return entries, err
+
+	case len(data) > 262 && string(data[257:262]) == "ustar":
+		err := readTar(bytes.NewReader(data), add)
+		return entries, err
+	}
+
+	return nil, fmt.Errorf("unsupported archive format, expected zip or tar(.gz)")
+}
+
+func readTar(r io.Reader, add func(name string, size int64, r io.Reader) error) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		// Links and devices are skipped, so they cannot point outside the
+		// destination either.
+		if header.Typeflag == tar.TypeReg {
+			if err := add(header.Name, header.Size, archive); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package filetools
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	tempDir := newTransferTest(t)
+	fs := storage.NewFileStorage(tempDir)
+	ctx := context.Background()
+
+	for _, name := range []string{"bundle.zip", "bundle.tar.gz"} {
+		create := NewCreateArchiveTool(fs)
+		result, err := create.Execute(ctx, map[string]interface{}{
+			"paths":       []interface{}{"notes", "todo.md"},
+			"destination": "export/" + name,
+		})
+		if err != nil || !strings.Contains(result, "3 files") {
+			t.Fatalf("Expected %s to hold 3 files, got %q, %v", name, result, err)
+		}
+
+		extract := NewExtractArchiveTool(fs)
+		result, err = extract.Execute(ctx, map[string]interface{}{"path": "export/" + name, "list": true})
+		if err != nil || !strings.Contains(result, "notes/daily/b.md (1 bytes)") || !strings.Contains(result, "todo.md (4 bytes)") {
+			t.Errorf("Unexpected listing of %s: %q, %v", name, result, err)
+		}
+
+		if _, err := extract.Execute(ctx, map[string]interface{}{"path": "export/" + name, "destination": "import"}); err != nil {
+			t.Fatalf("Failed to extract %s: %v", name, err)
+		}
+		assertFile(t, tempDir, "import/notes/a.md", "a")
+		assertFile(t, tempDir, "import/notes/daily/b.md", "b")
+		assertFile(t, tempDir, "import/todo.md", "todo")
+
+		if _, err := extract.Execute(ctx, map[string]interface{}{"path": "export/" + name, "destination": "import"}); err == nil {
+			t.Errorf("Expected extracting %s over existing files to need overwrite", name)
+		}
+		if _, err := extract.Execute(ctx, map[string]interface{}{"path": "export/" + name, "destination": "import", "overwrite": true}); err != nil {
+			t.Errorf("Expected overwrite to replace the files, got %v", err)
+		}
+		os.RemoveAll(filepath.Join(tempDir, "import"))
+	}
+
+	if _, err := NewCreateArchiveTool(fs).Execute(ctx, map[string]interface{}{"paths": []interface{}{"notes"}, "destination": "notes.rar"}); err == nil {
+		t.Error("Expected an unknown archive format to be rejected")
+	}
+}
+
+func TestExtractArchiveRejectsTraversal(t *testing.T) {
+	tempDir := t.TempDir()
+	fs := storage.NewFileStorage(filepath.Join(tempDir, "workspace"))
+	ctx := context.Background()
+
+	for _, name := range []string{"../evil.txt", "/etc/evil.txt", `..\evil.txt`} {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		w, _ := archive.Create(name)
+		w.Write([]byte("evil"))
+		archive.Close()
+		if err := fs.WriteFile(ctx, "evil.zip", buf.Bytes()); err != nil {
+			t.Fatalf("Failed to write archive: %v", err)
+		}
+
+		if _, err := NewExtractArchiveTool(fs).Execute(ctx, map[string]interface{}{"path": "evil.zip"}); err == nil {
+			t.Errorf("Expected the entry %q to be rejected", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "evil.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written outside the workspace, got %v", err)
+	}
+
+	if _, err := NewExtractArchiveTool(fs).Execute(ctx, map[string]interface{}{"path": "evil.zip", "destination": "../out"}); err == nil {
+		t.Error("Expected a destination outside the workspace to be rejected")
+	}
+}
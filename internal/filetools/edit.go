@@ -0,0 +1,352 @@
+package filetools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const (
+	// editContextLines is how many unchanged lines around each change the
+	// result shows.
+	editContextLines = 3
+	backupSuffix     = ".bak"
+)
+
+// EditFileTool changes part of a file, either by replacing an exact piece of
+// text or by applying a unified diff, so files do not have to be rewritten
+// whole through write_file.
+type EditFileTool struct {
+	storage storage.Storage
+}
+
+func NewEditFileTool(storage storage.Storage) *EditFileTool {
+	return &EditFileTool{
+		storage: storage,
+	}
+}
+
+func (t *EditFileTool) Name() string {
+	return "edit_file"
+}
+
+func (t *EditFileTool) Description() string {
+	return "Edit part of a file by replacing old_text with new_text, or by applying a unified diff. The previous version is kept as <path>.bak"
+}
+
+func (t *EditFileTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{Invalidates: []string{"read_file"}}
+}
+
+func (t *EditFileTool) Parameters() json.RawMessage {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "The path to the file to edit"
+			},
+			"old_text": {
+				"type": "string",
+				"description": "Exact text to replace, including whitespace. It must appear once unless replace_all is set"
+			},
+			"new_text": {
+				"type": "string",
+				"description": "Text to put in place of old_text"
+			},
+			"replace_all": {
+				"type": "boolean",
+				"description": "Replace every occurrence of old_text"
+			},
+			"diff": {
+				"type": "string",
+				"description": "Unified diff to apply instead of old_text/new_text, with @@ hunk headers"
+			}
+		},
+		"required": ["path"],
+		"additionalProperties": false
+	}`)
+	return params
+}
+
+func (t *EditFileTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	path, ok := params["path"].(string)
+	if !ok || path == "" {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "path parameter must be a non-empty string",
+		}
+	}
+	if !filepath.IsLocal(path) {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PATH",
+			Message: fmt.Sprintf("path %q is outside the data directory", path),
+		}
+	}
+
+	diff, hasDiff := params["diff"].(string)
+	oldText, hasOld := params["old_text"].(string)
+	newText, hasNew := params["new_text"].(string)
+	if hasDiff == (hasOld || hasNew) || (!hasDiff && (!hasOld || !hasNew)) {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "pass either old_text and new_text, or diff",
+		}
+	}
+
+	data, err := t.storage.ReadFile(ctx, path)
+	if err != nil {
+		return "", &tools.ToolError{
+			Code:    "EXECUTION_FAILED",
+			Message: "failed to read file",
+			Err:     err,
+		}
+	}
+	original := string(data)
+
+	var edited string
+	var changes []lineRange
+	if hasDiff {
+		edited, changes, err = applyUnifiedDiff(original, diff)
+	} else {
+		replaceAll, _ := params["replace_all"].(bool)
+		edited, changes, err = replaceText(original, oldText, newText, replaceAll)
+	}
+	if err != nil {
+		return "", &tools.ToolError{
+			Code:    "EDIT_FAILED",
+			Message: err.Error(),
+		}
+	}
+	if edited == original {
+		return fmt.Sprintf("No changes to %s", path), nil
+	}
+
+	if err := t.storage.WriteFile(ctx, path+backupSuffix, data); err != nil {
+		return "", &tools.ToolError{
+			Code:    "EXECUTION_FAILED",
+			Message: "failed to back up file",
+			Err:     err,
+		}
+	}
+	if err := t.storage.WriteFile(ctx, path, []byte(edited)); err != nil {
+		return "", &tools.ToolError{
+			Code:    "EXECUTION_FAILED",
+			Message: "failed to write file",
+			Err:     err,
+		}
+	}
+
+	return fmt.Sprintf("Edited %s (previous version saved as %s%s):\n\n%s", path, path, backupSuffix, showChanges(edited, changes)), nil
+}
+
+// lineRange is a changed part of the edited file, as 0-based line numbers
+// [start, end).
+type lineRange struct {
+	start, end int
+}
+
+func replaceText(content, oldText, newText string, replaceAll bool) (string, []lineRange, error) {
+	if oldText == "" {
+		return "", nil, fmt.Errorf("old_text cannot be empty")
+	}
+
+	count := strings.Count(content, oldText)
+	switch {
+	case count == 0:
+		return "", nil, fmt.Errorf("old_text was not found in the file; read the file and copy the text exactly")
+	case count > 1 && !replaceAll:
+		return "", nil, fmt.Errorf("old_text appears %d times; add surrounding lines to make it unique, or set replace_all", count)
+	}
+
+	var edited strings.Builder
+	var changes []lineRange
+	rest := content
+	for {
+		i := strings.Index(rest, oldText)
+		if i < 0 {
+			break
+		}
+		edited.WriteString(rest[:i])
+		start := strings.Count(edited.String(), "\n")
+		edited.WriteString(newText)
+		changes = append(changes, lineRange{start, start + strings.Count(newText, "\n") + 1})
+		rest = rest[i+len(oldText):]
+	}
+	edited.WriteString(rest)
+
+	return edited.String(), changes, nil
+}
+
+type hunk struct {
+	oldStart int
+	oldCount int
+	lines    []string
+}
+
+func parseUnifiedDiff(diff string) ([]hunk, error) {
+	var hunks []hunk
+	for _, line := range strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			fields := strings.Fields(line)
+			if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			start, count, hasCount := strings.Cut(fields[1][1:], ",")
+			oldStart, err := strconv.Atoi(start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hunk header %q", line)
+			}
+			oldCount := 1
+			if hasCount {
+				if oldCount, err = strconv.Atoi(count); err != nil {
+					return nil, fmt.Errorf("invalid hunk header %q", line)
+				}
+			}
+			hunks = append(hunks, hunk{oldStart: oldStart, oldCount: oldCount})
+		case len(hunks) == 0, strings.HasPrefix(line, `\`):
+			// File headers before the first hunk and "\ No newline at end of file".
+		case line == "":
+			// Trailing newline of the diff, or a blank context line whose
+			// leading space was trimmed.
+			hunks[len(hunks)-1].lines = append(hunks[len(hunks)-1].lines, " ")
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			hunks[len(hunks)-1].lines = append(hunks[len(hunks)-1].lines, line)
+		default:
+			return nil, fmt.Errorf("invalid diff line %q, lines must start with a space, - or +", line)
+		}
+	}
+
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("diff has no @@ hunks")
+	}
+	for i := range hunks {
+		// Drop the blank lines the trailing newline of the diff added.
+		lines := hunks[i].lines
+		for len(lines) > 0 && lines[len(lines)-1] == " " {
+			lines = lines[:len(lines)-1]
+		}
+		hunks[i].lines = lines
+	}
+	return hunks, nil
+}
+
+// applyUnifiedDiff applies the hunks in order. A hunk whose lines moved is
+// still applied at the match closest to where the header puts it.
+func applyUnifiedDiff(content, diff string) (string, []lineRange, error) {
+	hunks, err := parseUnifiedDiff(diff)
+	if err != nil {
+		return "", nil, err
+	}
+
+	trailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	var changes []lineRange
+	offset := 0
+	for n, h := range hunks {
+		var before, after []string
+		for _, line := range h.lines {
+			text := line[1:]
+			if line[0] != '+' {
+				before = append(before, text)
+			}
+			if line[0] != '-' {
+				after = append(after, text)
+			}
+		}
+
+		// A hunk that only adds lines has no old lines, and its header names
+		// the line they go after.
+		near := h.oldStart - 1 + offset
+		if h.oldCount == 0 {
+			near = h.oldStart + offset
+		}
+		at := findBlock(lines, before, near)
+		if at < 0 {
+			return "", nil, fmt.Errorf("hunk %d does not match the file; read the file again and make the context lines match exactly", n+1)
+		}
+
+		lines = append(lines[:at], append(append([]string{}, after...), lines[at+len(before):]...)...)
+		offset += len(after) - len(before)
+		for i := range changes {
+			if changes[i].start >= at {
+				changes[i].start += len(after) - len(before)
+				changes[i].end += len(after) - len(before)
+			}
+		}
+		changes = append(changes, lineRange{at, at + max(len(after), 1)})
+	}
+
+	edited := strings.Join(lines, "\n")
+	if trailingNewline || content == "" {
+		edited += "\n"
+	}
+	return edited, changes, nil
+}
+
+// findBlock returns where block appears in lines, preferring the position
+// closest to near, or -1.
+func findBlock(lines, block []string, near int) int {
+	best := -1
+	for i := 0; i+len(block) <= len(lines); i++ {
+		match := true
+		for j := range block {
+			if lines[i+j] != block[j] {
+				match = false
+				break
+			}
+		}
+		if match && (best < 0 || abs(i-near) < abs(best-near)) {
+			best = i
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// showChanges prints the changed lines with line numbers and some unchanged
+// lines around them.
+func showChanges(content string, changes []lineRange) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].start < changes[j].start })
+
+	var merged []lineRange
+	for _, change := range changes {
+		r := lineRange{max(change.start-editContextLines, 0), min(change.end+editContextLines, len(lines))}
+		if len(merged) > 0 && r.start <= merged[len(merged)-1].end {
+			merged[len(merged)-1].end = max(merged[len(merged)-1].end, r.end)
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	var out strings.Builder
+	for i, r := range merged {
+		if i > 0 {
+			out.WriteString("...\n")
+		}
+		for n := r.start; n < r.end; n++ {
+			fmt.Fprintf(&out, "%4d | %s\n", n+1, lines[n])
+		}
+	}
+	return strings.TrimRight(out.String(), "\n")
+}
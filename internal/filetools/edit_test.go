@@ -0,0 +1,158 @@
+package filetools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const editTestFile = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+}
+
+func greet() {
+	fmt.Println("hello")
+}
+`
+
+func newEditTest(t *testing.T) (*EditFileTool, string) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte(editTestFile), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	return NewEditFileTool(storage.NewFileStorage(tempDir)), tempDir
+}
+
+func readTestFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestEditFileReplace(t *testing.T) {
+	tool, dir := newEditTest(t)
+	ctx := context.Background()
+
+	_, err := tool.Execute(ctx, map[string]interface{}{"path": "main.go", "old_text": `fmt.Println("hello")`, "new_text": `fmt.Println("hi")`})
+	if err == nil || !strings.Contains(err.Error(), "appears 2 times") {
+		t.Errorf("Expected ambiguous text to be rejected, got %v", err)
+	}
+
+	result, err := tool.Execute(ctx, map[string]interface{}{
+		"path":     "main.go",
+		"old_text": "func greet() {\n\tfmt.Println(\"hello\")",
+		"new_text": "func greet(name string) {\n\tfmt.Println(\"hello\", name)",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.Contains(result, "   9 | func greet(name string) {") || !strings.Contains(result, "   7 | }") {
+		t.Errorf("Expected the changed lines with context, got:\n%s", result)
+	}
+	if strings.Contains(result, "package main") {
+		t.Errorf("Expected only the lines around the change, got:\n%s", result)
+	}
+
+	if edited := readTestFile(t, filepath.Join(dir, "main.go")); !strings.Contains(edited, `fmt.Println("hello", name)`) {
+		t.Errorf("Expected the file to be edited, got:\n%s", edited)
+	}
+	if backup := readTestFile(t, filepath.Join(dir, "main.go.bak")); backup != editTestFile {
+		t.Errorf("Expected the previous version as backup, got:\n%s", backup)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"path": "main.go", "old_text": "fmt.Println", "new_text": "log.Println", "replace_all": true}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if edited := readTestFile(t, filepath.Join(dir, "main.go")); strings.Contains(edited, "fmt.Println") {
+		t.Errorf("Expected every occurrence to be replaced, got:\n%s", edited)
+	}
+}
+
+func TestEditFileDiff(t *testing.T) {
+	tool, dir := newEditTest(t)
+
+	// The second hunk's header is off by one line, as model-written diffs
+	// often are.
+	diff := `--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+ 
+-import "fmt"
++import "log"
+@@ -7,3 +7,4 @@
+ 
+ func greet() {
+-	fmt.Println("hello")
++	log.Println("hello")
++	log.Println("bye")
+`
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"path": "main.go", "diff": diff})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	want := strings.Replace(strings.Replace(editTestFile, `import "fmt"`, `import "log"`, 1),
+		"func greet() {\n\tfmt.Println(\"hello\")", "func greet() {\n\tlog.Println(\"hello\")\n\tlog.Println(\"bye\")", 1)
+	if edited := readTestFile(t, filepath.Join(dir, "main.go")); edited != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, edited)
+	}
+	if !strings.Contains(result, `  11 | 	log.Println("bye")`) {
+		t.Errorf("Expected the new lines in the result, got:\n%s", result)
+	}
+
+	stale := "@@ -5,1 +5,1 @@\n-func start() {\n+func run() {\n"
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"path": "main.go", "diff": stale}); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected a hunk that does not match to be rejected, got %v", err)
+	}
+	if edited := readTestFile(t, filepath.Join(dir, "main.go")); edited != want {
+		t.Error("Expected a failed edit to leave the file alone")
+	}
+}
+
+func TestApplyUnifiedDiffInsertOnly(t *testing.T) {
+	content := "one\ntwo\nthree\n"
+
+	edited, _, err := applyUnifiedDiff(content, "@@ -2,0 +3 @@\n+two and a half\n")
+	if err != nil {
+		t.Fatalf("applyUnifiedDiff failed: %v", err)
+	}
+	if edited != "one\ntwo\ntwo and a half\nthree\n" {
+		t.Errorf("Expected the line to go after line 2, got %q", edited)
+	}
+
+	edited, _, err = applyUnifiedDiff(content, "@@ -0,0 +1 @@\n+zero\n")
+	if err != nil {
+		t.Fatalf("applyUnifiedDiff failed: %v", err)
+	}
+	if edited != "zero\none\ntwo\nthree\n" {
+		t.Errorf("Expected the line to go before line 1, got %q", edited)
+	}
+}
+
+func TestEditFileRejectsInvalidInput(t *testing.T) {
+	tool, _ := newEditTest(t)
+
+	for _, params := range []map[string]interface{}{
+		{"path": "../outside.go", "old_text": "a", "new_text": "b"},
+		{"path": "/etc/passwd", "old_text": "a", "new_text": "b"},
+		{"path": "main.go", "old_text": "package main"},
+		{"path": "main.go", "old_text": "a", "new_text": "b", "diff": "@@ -1 +1 @@"},
+		{"path": "main.go", "old_text": "missing", "new_text": "b"},
+		{"path": "main.go", "diff": "not a diff"},
+	} {
+		if _, err := tool.Execute(context.Background(), params); err == nil {
+			t.Errorf("Expected %v to be rejected", params)
+		}
+	}
+}
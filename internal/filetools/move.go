@@ -0,0 +1,254 @@
+package filetools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+// MoveFileTool moves or renames a file or directory.
+type MoveFileTool struct {
+	storage storage.Storage
+}
+
+func NewMoveFileTool(storage storage.Storage) *MoveFileTool {
+	return &MoveFileTool{
+		storage: storage,
+	}
+}
+
+func (t *MoveFileTool) Name() string {
+	return "move_file"
+}
+
+func (t *MoveFileTool) Description() string {
+	return "Move or rename a file or directory"
+}
+
+func (t *MoveFileTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{Invalidates: []string{"read_file"}}
+}
+
+func (t *MoveFileTool) Parameters() json.RawMessage {
+	return transferParameters("move")
+}
+
+func (t *MoveFileTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	return transfer(ctx, t.storage, params, true)
+}
+
+// CopyFileTool copies a file or directory.
+type CopyFileTool struct {
+	storage storage.Storage
+}
+
+func NewCopyFileTool(storage storage.Storage) *CopyFileTool {
+	return &CopyFileTool{
+		storage: storage,
+	}
+}
+
+func (t *CopyFileTool) Name() string {
+	return "copy_file"
+}
+
+func (t *CopyFileTool) Description() string {
+	return "Copy a file or directory"
+}
+
+func (t *CopyFileTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{Invalidates: []string{"read_file"}}
+}
+
+func (t *CopyFileTool) Parameters() json.RawMessage {
+	return transferParameters("copy")
+}
+
+func (t *CopyFileTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	return transfer(ctx, t.storage, params, false)
+}
+
+func transferParameters(verb string) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{
+		"type": "object",
+		"properties": {
+			"source": {
+				"type": "string",
+				"description": "The file or directory to %[1]s"
+			},
+			"destination": {
+				"type": "string",
+				"description": "The full new path, not the directory to %[1]s into"
+			},
+			"overwrite": {
+				"type": "boolean",
+				"description": "Replace files that already exist at the destination (default false)"
+			}
+		},
+		"required": ["source", "destination"],
+		"additionalProperties": false
+	}`, verb))
+}
+
+func transfer(ctx context.Context, store storage.Storage, params map[string]interface{}, move bool) (string, error) {
+	source, err := localPathParam(params, "source")
+	if err != nil {
+		return "", err
+	}
+	destination, err := localPathParam(params, "destination")
+	if err != nil {
+		return "", err
+	}
+	overwrite, _ := params["overwrite"].(bool)
+
+	if source == destination || strings.HasPrefix(destination, source+"/") {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PATH",
+			Message: fmt.Sprintf("cannot %s %s into itself", transferVerb(move), source),
+		}
+	}
+
+	files, err := sourceFiles(ctx, store, source)
+	if err != nil {
+		return "", err
+	}
+
+	var existing []string
+	for _, file := range files {
+		target := path.Join(destination, file)
+		if exists, err := store.FileExists(ctx, target); err != nil {
+			return "", &tools.ToolError{Code: "EXECUTION_FAILED", Message: "failed to check the destination", Err: err}
+		} else if exists {
+			existing = append(existing, target)
+		}
+	}
+	if len(existing) > 0 && !overwrite {
+		return "", &tools.ToolError{
+			Code:    "ALREADY_EXISTS",
+			Message: fmt.Sprintf("%s already exists; set overwrite to replace it", strings.Join(existing, ", ")),
+		}
+	}
+
+	if renamer, ok := store.(storage.Renamer); ok && move {
+		exists, err := store.FileExists(ctx, destination)
+		if err == nil && (!exists || len(files) == 1 && files[0] == "") {
+			if err := renamer.Rename(ctx, source, destination); err == nil {
+				return summarizeTransfer(true, source, destination, len(files)), nil
+			}
+		}
+	}
+
+	for _, file := range files {
+		from, to := path.Join(source, file), path.Join(destination, file)
+		data, err := store.ReadFile(ctx, from)
+		if err != nil {
+			return "", &tools.ToolError{Code: "EXECUTION_FAILED", Message: fmt.Sprintf("failed to read %s", from), Err: err}
+		}
+		if err := store.WriteFile(ctx, to, data); err != nil {
+			return "", &tools.ToolError{Code: "EXECUTION_FAILED", Message: fmt.Sprintf("failed to write %s", to), Err: err}
+		}
+		if move {
+			if err := store.DeleteFile(ctx, from); err != nil {
+				return "", &tools.ToolError{Code: "EXECUTION_FAILED", Message: fmt.Sprintf("failed to delete %s", from), Err: err}
+			}
+		}
+	}
+
+	if move && (len(files) > 1 || files[0] != "") {
+		removeEmptyDirs(ctx, store, source, files)
+	}
+
+	return summarizeTransfer(move, source, destination, len(files)), nil
+}
+
+// sourceFiles lists the files to transfer relative to source: just "" for a
+// single file.
+func sourceFiles(ctx context.Context, store storage.Storage, source string) ([]string, error) {
+	listed, err := store.ListFiles(ctx, source)
+	if err != nil {
+		return nil, &tools.ToolError{Code: "EXECUTION_FAILED", Message: "failed to list the source", Err: err}
+	}
+
+	var files []string
+	for _, file := range listed {
+		file = filepath.ToSlash(file)
+		if file == source {
+			return []string{""}, nil
+		}
+		if rel, ok := strings.CutPrefix(file, source+"/"); ok {
+			files = append(files, rel)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, &tools.ToolError{
+			Code:    "NOT_FOUND",
+			Message: fmt.Sprintf("%s does not exist or is an empty directory", source),
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// removeEmptyDirs deletes the directories a move left behind, deepest first.
+// Storages without directories have nothing to delete, so errors are ignored.
+func removeEmptyDirs(ctx context.Context, store storage.Storage, source string, files []string) {
+	dirs := map[string]bool{source: true}
+	for _, file := range files {
+		for dir := path.Dir(file); dir != "."; dir = path.Dir(dir) {
+			dirs[path.Join(source, dir)] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	for _, dir := range sorted {
+		store.DeleteFile(ctx, dir)
+	}
+}
+
+func localPathParam(params map[string]interface{}, name string) (string, error) {
+	value, ok := params[name].(string)
+	if !ok || value == "" {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: fmt.Sprintf("%s parameter must be a non-empty string", name),
+		}
+	}
+	if !filepath.IsLocal(value) {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PATH",
+			Message: fmt.Sprintf("path %q is outside the data directory", value),
+		}
+	}
+	return path.Clean(filepath.ToSlash(value)), nil
+}
+
+func transferVerb(move bool) string {
+	if move {
+		return "move"
+	}
+	return "copy"
+}
+
+func summarizeTransfer(move bool, source, destination string, files int) string {
+	verb := "Copied"
+	if move {
+		verb = "Moved"
+	}
+	if files == 1 {
+		return fmt.Sprintf("%s %s to %s", verb, source, destination)
+	}
+	return fmt.Sprintf("%s %d files from %s to %s", verb, files, source, destination)
+}
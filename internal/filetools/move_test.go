@@ -0,0 +1,105 @@
+package filetools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func newTransferTest(t *testing.T) string {
+	tempDir := t.TempDir()
+	for name, content := range map[string]string{
+		"notes/a.md":       "a",
+		"notes/daily/b.md": "b",
+		"todo.md":          "todo",
+	} {
+		full := filepath.Join(tempDir, name)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	return tempDir
+}
+
+func assertFile(t *testing.T, dir, name, want string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil || string(data) != want {
+		t.Errorf("Expected %s to contain %q, got %q, %v", name, want, data, err)
+	}
+}
+
+func assertMissing(t *testing.T, dir, name string) {
+	t.Helper()
+	if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be gone, got %v", name, err)
+	}
+}
+
+func TestMoveFileTool(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range map[string]func(dir string) storage.Storage{
+		"rename": func(dir string) storage.Storage { return storage.NewFileStorage(dir) },
+		// Embedding only the interface hides Rename, as with object storage.
+		"copy": func(dir string) storage.Storage { return struct{ storage.Storage }{storage.NewFileStorage(dir)} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := newTransferTest(t)
+			tool := NewMoveFileTool(store(dir))
+
+			result, err := tool.Execute(ctx, map[string]interface{}{"source": "notes", "destination": "archive/2024"})
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if result != "Moved 2 files from notes to archive/2024" {
+				t.Errorf("Unexpected result %q", result)
+			}
+			assertFile(t, dir, "archive/2024/a.md", "a")
+			assertFile(t, dir, "archive/2024/daily/b.md", "b")
+			assertMissing(t, dir, "notes")
+
+			if _, err := tool.Execute(ctx, map[string]interface{}{"source": "todo.md", "destination": "archive/2024/a.md"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+				t.Errorf("Expected an existing destination to be refused, got %v", err)
+			}
+			if _, err := tool.Execute(ctx, map[string]interface{}{"source": "todo.md", "destination": "archive/2024/a.md", "overwrite": true}); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			assertFile(t, dir, "archive/2024/a.md", "todo")
+			assertMissing(t, dir, "todo.md")
+		})
+	}
+}
+
+func TestCopyFileTool(t *testing.T) {
+	dir := newTransferTest(t)
+	tool := NewCopyFileTool(storage.NewFileStorage(dir))
+	ctx := context.Background()
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"source": "notes", "destination": "backup/notes"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	assertFile(t, dir, "backup/notes/daily/b.md", "b")
+	assertFile(t, dir, "notes/daily/b.md", "b")
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"source": "todo.md", "destination": "todo-copy.md"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	assertFile(t, dir, "todo-copy.md", "todo")
+
+	for _, params := range []map[string]interface{}{
+		{"source": "notes", "destination": "notes/inner"},
+		{"source": "../outside", "destination": "inside"},
+		{"source": "todo.md", "destination": "/tmp/todo.md"},
+		{"source": "missing.md", "destination": "found.md"},
+	} {
+		if _, err := tool.Execute(ctx, params); err == nil {
+			t.Errorf("Expected %v to be rejected", params)
+		}
+	}
+}
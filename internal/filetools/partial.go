@@ -0,0 +1,177 @@
+package filetools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+// lineSelection selects part of a file for read_file. Start and end count from
+// 1 and include both ends; zero leaves that end open.
+type lineSelection struct {
+	start, end int
+	head, tail int
+}
+
+func parseLineRange(params map[string]interface{}) (*lineSelection, error) {
+	var sel lineSelection
+	for name, value := range map[string]*int{"start_line": &sel.start, "end_line": &sel.end, "head": &sel.head, "tail": &sel.tail} {
+		raw, ok := params[name]
+		if !ok {
+			continue
+		}
+		n, ok := raw.(float64)
+		if !ok || n < 1 || n != float64(int(n)) {
+			return nil, fmt.Errorf("%s must be a positive whole number", name)
+		}
+		*value = int(n)
+	}
+
+	ranged := sel.start > 0 || sel.end > 0
+	switch {
+	case !ranged && sel.head == 0 && sel.tail == 0:
+		return nil, nil
+	case ranged && (sel.head > 0 || sel.tail > 0), sel.head > 0 && sel.tail > 0:
+		return nil, fmt.Errorf("use only one of start_line/end_line, head and tail")
+	case sel.end > 0 && sel.end < sel.start:
+		return nil, fmt.Errorf("end_line must not be before start_line")
+	}
+	return &sel, nil
+}
+
+// apply returns the selected lines followed by a note of which lines of the
+// file they are.
+func (s *lineSelection) apply(content string) string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	total := len(lines)
+
+	start, end := 1, total
+	switch {
+	case s.head > 0:
+		end = min(s.head, total)
+	case s.tail > 0:
+		start = max(total-s.tail+1, 1)
+	default:
+		start = max(s.start, 1)
+		if s.end > 0 {
+			end = min(s.end, total)
+		}
+	}
+
+	if start > end {
+		return fmt.Sprintf("[no lines in range, the file has %d lines]", total)
+	}
+
+	selected := strings.Join(lines[start-1:end], "")
+	if !strings.HasSuffix(selected, "\n") {
+		selected += "\n"
+	}
+	return selected + fmt.Sprintf("[lines %d-%d of %d]", start, end, total)
+}
+
+// truncateBytes cuts content to at most limit bytes without splitting a
+// character.
+func truncateBytes(content string, limit int) string {
+	if len(content) <= limit {
+		return content
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut] + fmt.Sprintf("\n[truncated to %d of %d bytes]", cut, len(content))
+}
+
+type AppendFileTool struct {
+	storage storage.Storage
+}
+
+func NewAppendFileTool(storage storage.Storage) *AppendFileTool {
+	return &AppendFileTool{
+		storage: storage,
+	}
+}
+
+func (t *AppendFileTool) Name() string {
+	return "append_file"
+}
+
+func (t *AppendFileTool) Description() string {
+	return "Append content to the end of a file, creating it if needed, without rewriting the file"
+}
+
+func (t *AppendFileTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{Invalidates: []string{"read_file"}}
+}
+
+func (t *AppendFileTool) Parameters() json.RawMessage {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "The path to the file to append to"
+			},
+			"content": {
+				"type": "string",
+				"description": "The content to append"
+			},
+			"newline": {
+				"type": "boolean",
+				"description": "End the content with a line break if it has none (default true)",
+				"default": true
+			}
+		},
+		"required": ["path", "content"],
+		"additionalProperties": false
+	}`)
+	return params
+}
+
+func (t *AppendFileTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	path, ok := params["path"].(string)
+	if !ok || path == "" {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "path parameter must be a non-empty string",
+		}
+	}
+	if !filepath.IsLocal(path) {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PATH",
+			Message: fmt.Sprintf("path %q is outside the data directory", path),
+		}
+	}
+
+	content, ok := params["content"].(string)
+	if !ok {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "content parameter must be a string",
+		}
+	}
+
+	if newline, ok := params["newline"].(bool); (!ok || newline) && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	if err := storage.AppendFile(ctx, t.storage, path, []byte(content)); err != nil {
+		return "", &tools.ToolError{
+			Code:    "EXECUTION_FAILED",
+			Message: "failed to append to file",
+			Err:     err,
+		}
+	}
+
+	return fmt.Sprintf("Appended %d bytes to %s", len(content), path), nil
+}
@@ -0,0 +1,88 @@
+package filetools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func TestReadFileRanges(t *testing.T) {
+	tempDir := t.TempDir()
+	var log strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&log, "line %d\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "app.log"), []byte(log.String()), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	tool := NewReadFileTool(storage.NewFileStorage(tempDir))
+
+	for _, tc := range []struct {
+		params map[string]interface{}
+		want   string
+	}{
+		{map[string]interface{}{"head": 2.0}, "line 1\nline 2\n[lines 1-2 of 100]"},
+		{map[string]interface{}{"tail": 2.0}, "line 99\nline 100\n[lines 99-100 of 100]"},
+		{map[string]interface{}{"start_line": 50.0, "end_line": 51.0}, "line 50\nline 51\n[lines 50-51 of 100]"},
+		{map[string]interface{}{"start_line": 99.0}, "line 99\nline 100\n[lines 99-100 of 100]"},
+		{map[string]interface{}{"start_line": 200.0}, "[no lines in range, the file has 100 lines]"},
+		{map[string]interface{}{"max_bytes": 14.0}, "line 1\nline 2\n\n[truncated to 14 of 792 bytes]"},
+	} {
+		tc.params["path"] = "app.log"
+		result, err := tool.Execute(context.Background(), tc.params)
+		if err != nil {
+			t.Errorf("Execute(%v) failed: %v", tc.params, err)
+			continue
+		}
+		if result != tc.want {
+			t.Errorf("Execute(%v) = %q, want %q", tc.params, result, tc.want)
+		}
+	}
+
+	for _, params := range []map[string]interface{}{
+		{"path": "app.log", "head": 2.0, "tail": 2.0},
+		{"path": "app.log", "start_line": 5.0, "tail": 2.0},
+		{"path": "app.log", "start_line": 5.0, "end_line": 4.0},
+		{"path": "app.log", "head": 0.0},
+	} {
+		if _, err := tool.Execute(context.Background(), params); err == nil {
+			t.Errorf("Expected %v to be rejected", params)
+		}
+	}
+}
+
+func TestTruncateBytesKeepsCharacters(t *testing.T) {
+	if result := truncateBytes("日本語", 4); !strings.HasPrefix(result, "日\n") {
+		t.Errorf("Expected the cut before a partial character, got %q", result)
+	}
+}
+
+func TestAppendFileTool(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewAppendFileTool(storage.NewFileStorage(tempDir))
+	ctx := context.Background()
+
+	for _, params := range []map[string]interface{}{
+		{"path": "notes/journal.md", "content": "first"},
+		{"path": "notes/journal.md", "content": "second\n"},
+		{"path": "notes/journal.md", "content": "third", "newline": false},
+	} {
+		if _, err := tool.Execute(ctx, params); err != nil {
+			t.Fatalf("Execute(%v) failed: %v", params, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "notes", "journal.md"))
+	if err != nil || string(data) != "first\nsecond\nthird" {
+		t.Errorf("Expected the appended lines, got %q, %v", data, err)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"path": "../escape.md", "content": "x"}); err == nil {
+		t.Error("Expected a path outside the data directory to be rejected")
+	}
+}
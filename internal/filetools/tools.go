@@ -6,11 +6,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/wjffsx/miniclaw_go/internal/storage"
 	"github.com/wjffsx/miniclaw_go/internal/tools"
 )
 
+// readFileCacheTTL is short because files also change outside of the tools.
+const readFileCacheTTL = time.Minute
+
 type FileToolsConfig struct {
 	Storage      storage.Storage
 	AllowedPaths []string
@@ -31,7 +35,11 @@ func (t *ReadFileTool) Name() string {
 }
 
 func (t *ReadFileTool) Description() string {
-	return "Read the contents of a file"
+	return "Read the contents of a file, or part of it for large files such as logs"
+}
+
+func (t *ReadFileTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{CacheTTL: readFileCacheTTL}
 }
 
 func (t *ReadFileTool) Parameters() json.RawMessage {
@@ -41,6 +49,31 @@ func (t *ReadFileTool) Parameters() json.RawMessage {
 			"path": {
 				"type": "string",
 				"description": "The path to the file to read"
+			},
+			"start_line": {
+				"type": "integer",
+				"description": "First line to read, counting from 1",
+				"minimum": 1
+			},
+			"end_line": {
+				"type": "integer",
+				"description": "Last line to read (inclusive)",
+				"minimum": 1
+			},
+			"head": {
+				"type": "integer",
+				"description": "Read only the first N lines",
+				"minimum": 1
+			},
+			"tail": {
+				"type": "integer",
+				"description": "Read only the last N lines",
+				"minimum": 1
+			},
+			"max_bytes": {
+				"type": "integer",
+				"description": "Return at most this many bytes",
+				"minimum": 1
 			}
 		},
 		"required": ["path"],
@@ -65,6 +98,14 @@ func (t *ReadFileTool) Execute(ctx context.Context, params map[string]interface{
 		}
 	}
 
+	lines, err := parseLineRange(params)
+	if err != nil {
+		return "", &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: err.Error(),
+		}
+	}
+
 	data, err := t.storage.ReadFile(ctx, path)
 	if err != nil {
 		return "", &tools.ToolError{
@@ -74,7 +115,15 @@ func (t *ReadFileTool) Execute(ctx context.Context, params map[string]interface{
 		}
 	}
 
-	return string(data), nil
+	content := string(data)
+	if lines != nil {
+		content = lines.apply(content)
+	}
+	if maxBytes, ok := params["max_bytes"].(float64); ok && maxBytes >= 1 {
+		content = truncateBytes(content, int(maxBytes))
+	}
+
+	return content, nil
 }
 
 type WriteFileTool struct {
@@ -95,6 +144,10 @@ func (t *WriteFileTool) Description() string {
 	return "Write content to a file"
 }
 
+func (t *WriteFileTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{Invalidates: []string{"read_file"}}
+}
+
 func (t *WriteFileTool) Parameters() json.RawMessage {
 	params := json.RawMessage(`{
 		"type": "object",
@@ -227,6 +280,10 @@ func (t *DeleteFileTool) Description() string {
 	return "Delete a file"
 }
 
+func (t *DeleteFileTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{RequiresConfirmation: true, AdminOnly: true, Invalidates: []string{"read_file"}}
+}
+
 func (t *DeleteFileTool) Parameters() json.RawMessage {
 	params := json.RawMessage(`{
 		"type": "object",
@@ -341,6 +398,12 @@ func NewFileTools(storage storage.Storage) []tools.Tool {
 	return []tools.Tool{
 		NewReadFileTool(storage),
 		NewWriteFileTool(storage),
+		NewEditFileTool(storage),
+		NewAppendFileTool(storage),
+		NewMoveFileTool(storage),
+		NewCopyFileTool(storage),
+		NewCreateArchiveTool(storage),
+		NewExtractArchiveTool(storage),
 		NewListDirTool(storage),
 		NewDeleteFileTool(storage),
 		NewFileExistsTool(storage),
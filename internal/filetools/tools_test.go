@@ -308,11 +308,11 @@ func TestNewFileTools(t *testing.T) {
 
 	tools := NewFileTools(fileStorage)
 
-	if len(tools) != 5 {
-		t.Errorf("Expected 5 tools, got %d", len(tools))
+	if len(tools) != 11 {
+		t.Errorf("Expected 11 tools, got %d", len(tools))
 	}
 
-	toolNames := []string{"read_file", "write_file", "list_dir", "delete_file", "file_exists"}
+	toolNames := []string{"read_file", "write_file", "edit_file", "append_file", "move_file", "copy_file", "create_archive", "extract_archive", "list_dir", "delete_file", "file_exists"}
 	for i, tool := range tools {
 		if tool.Name() != toolNames[i] {
 			t.Errorf("Expected tool name '%s', got '%s'", toolNames[i], tool.Name())
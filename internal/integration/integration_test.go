@@ -24,7 +24,7 @@ func TestAgentIntegration(t *testing.T) {
 	sessionStorage := storage.NewFileSystemSessionStorage(tempDir)
 	memoryStorage := storage.NewFileSystemMemoryStorage(tempDir)
 
-	messageBus := bus.NewInMemoryMessageBus(ctx)
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
 
 	cfg := &config.Config{
 		LLM: config.LLMConfig{
@@ -335,7 +335,7 @@ func TestMultiModelManagerIntegration(t *testing.T) {
 func TestMessageBusIntegration(t *testing.T) {
 	ctx := context.Background()
 
-	messageBus := bus.NewInMemoryMessageBus(ctx)
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
 	messageBus.Start()
 
 	messageReceived := make(chan bool, 1)
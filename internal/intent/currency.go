@@ -0,0 +1,221 @@
+package intent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRatesURL = "https://api.frankfurter.app/latest"
+	defaultRatesTTL = time.Hour
+	ratesTimeout    = 5 * time.Second
+)
+
+var currencyNames = map[string]string{
+	"$": "USD", "dollar": "USD", "dollars": "USD", "us dollar": "USD", "us dollars": "USD",
+	"€": "EUR", "euro": "EUR", "euros": "EUR",
+	"£": "GBP", "pound": "GBP", "pounds": "GBP", "pound sterling": "GBP", "pounds sterling": "GBP", "quid": "GBP",
+	"¥": "JPY", "yen": "JPY",
+	"yuan": "CNY", "rmb": "CNY", "renminbi": "CNY",
+	"franc": "CHF", "francs": "CHF", "swiss franc": "CHF", "swiss francs": "CHF",
+	"rupee": "INR", "rupees": "INR", "won": "KRW",
+	"canadian dollar": "CAD", "canadian dollars": "CAD",
+	"australian dollar": "AUD", "australian dollars": "AUD",
+}
+
+var currencyCodes = strings.Fields(`AUD BGN BRL CAD CHF CNY CZK DKK EUR GBP HKD HUF IDR ILS INR ISK JPY
+	KRW MXN MYR NOK NZD PHP PLN RON SEK SGD THB TRY USD ZAR`)
+
+type Rates struct {
+	Base  string
+	Date  string
+	Rates map[string]float64
+}
+
+type RatesProvider interface {
+	Rates(ctx context.Context, base string) (*Rates, error)
+}
+
+type cachedRates struct {
+	rates     *Rates
+	fetchedAt time.Time
+}
+
+type HTTPRatesProvider struct {
+	baseURL string
+	ttl     time.Duration
+	client  *http.Client
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedRates
+}
+
+func NewHTTPRatesProvider(baseURL string, ttl time.Duration) *HTTPRatesProvider {
+	if baseURL == "" {
+		baseURL = defaultRatesURL
+	}
+	if ttl <= 0 {
+		ttl = defaultRatesTTL
+	}
+
+	return &HTTPRatesProvider{
+		baseURL: baseURL,
+		ttl:     ttl,
+		client:  &http.Client{Timeout: ratesTimeout},
+		now:     time.Now,
+		cache:   make(map[string]cachedRates),
+	}
+}
+
+func (p *HTTPRatesProvider) Rates(ctx context.Context, base string) (*Rates, error) {
+	p.mu.Lock()
+	cached, ok := p.cache[base]
+	p.mu.Unlock()
+
+	if ok && p.now().Sub(cached.fetchedAt) < p.ttl {
+		return cached.rates, nil
+	}
+
+	rates, err := p.fetch(ctx, base)
+	if err != nil {
+		if ok {
+			logger.Warn("Failed to refresh exchange rates, using cached rates", "base", base, "date", cached.rates.Date, "error", err)
+			return cached.rates, nil
+		}
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.cache[base] = cachedRates{rates: rates, fetchedAt: p.now()}
+	p.mu.Unlock()
+
+	return rates, nil
+}
+
+func (p *HTTPRatesProvider) fetch(ctx context.Context, base string) (*Rates, error) {
+	endpoint, err := url.Parse(p.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rates URL: %w", err)
+	}
+	query := endpoint.Query()
+	query.Set("from", base)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange rates request failed with status: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Base  string             `json:"base"`
+		Date  string             `json:"date"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode exchange rates: %w", err)
+	}
+	if len(body.Rates) == 0 {
+		return nil, fmt.Errorf("no exchange rates returned for %s", base)
+	}
+
+	return &Rates{Base: base, Date: body.Date, Rates: body.Rates}, nil
+}
+
+type CurrencyHandler struct {
+	provider RatesProvider
+}
+
+func NewCurrencyHandler(provider RatesProvider) *CurrencyHandler {
+	return &CurrencyHandler{provider: provider}
+}
+
+func (h *CurrencyHandler) Name() string {
+	return "currency"
+}
+
+func (h *CurrencyHandler) Handle(ctx context.Context, text string) (string, bool) {
+	if symbol, rest, ok := cutCurrencySymbol(text); ok {
+		amount, target, found := strings.Cut(rest, " ")
+		if !found {
+			return "", false
+		}
+		text = amount + " " + symbol + " " + target
+	}
+
+	c, ok := parseConversion(text)
+	if !ok {
+		return "", false
+	}
+
+	from, ok := currencyCode(c.from)
+	if !ok {
+		return "", false
+	}
+	to, ok := currencyCode(c.to)
+	if !ok || from == to {
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ratesTimeout)
+	defer cancel()
+
+	rates, err := h.provider.Rates(ctx, from)
+	if err != nil {
+		logger.Warn("Currency fast path unavailable", "error", err)
+		return "", false
+	}
+
+	rate, ok := rates.Rates[to]
+	if !ok {
+		return "", false
+	}
+
+	answer := fmt.Sprintf("%s %s = %s %s (1 %s = %s %s", formatMoney(c.amount), from, formatMoney(c.amount*rate), to, from, formatNumber(rate), to)
+	if rates.Date != "" {
+		answer += ", rates from " + rates.Date
+	}
+	return answer + ")", true
+}
+
+func cutCurrencySymbol(text string) (string, string, bool) {
+	for _, symbol := range []string{"$", "€", "£", "¥"} {
+		if rest, ok := strings.CutPrefix(text, symbol); ok {
+			return symbol, strings.TrimSpace(rest), true
+		}
+	}
+	return "", "", false
+}
+
+func currencyCode(name string) (string, bool) {
+	if code, ok := currencyNames[name]; ok {
+		return code, true
+	}
+	code := strings.ToUpper(name)
+	for _, known := range currencyCodes {
+		if code == known {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+func formatMoney(amount float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", amount), "0"), ".")
+}
@@ -0,0 +1,172 @@
+package intent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	daysUntilPattern  = regexp.MustCompile(`^(?:how many )?(days|weeks) (until|till|til|to|before|since|from) (.+)$`)
+	dateOffsetPattern = regexp.MustCompile(`^(?:what (?:date|day) (?:is|will it be) )?(\d+) (days?|weeks?|months?|years?) (from now|from today|later|ago|before today)$`)
+	ordinalPattern    = regexp.MustCompile(`(\d+)(st|nd|rd|th)\b`)
+)
+
+var dateLayouts = []string{
+	"2006-01-02",
+	"January 2 2006",
+	"Jan 2 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"1/2/2006",
+}
+
+var yearlessLayouts = []string{
+	"January 2",
+	"Jan 2",
+	"2 January",
+	"2 Jan",
+}
+
+type DateHandler struct {
+	now func() time.Time
+}
+
+func NewDateHandler() *DateHandler {
+	return &DateHandler{now: time.Now}
+}
+
+func (h *DateHandler) Name() string {
+	return "dates"
+}
+
+func (h *DateHandler) Handle(ctx context.Context, text string) (string, bool) {
+	today := truncateDay(h.now())
+
+	if match := daysUntilPattern.FindStringSubmatch(text); match != nil {
+		since := match[2] == "since" || match[2] == "from"
+
+		target, ok := parseDate(match[3], today, since)
+		if !ok {
+			return "", false
+		}
+
+		days := daysBetween(today, target)
+		if since {
+			days = -days
+		}
+
+		label := target.Format("Monday, January 2, 2006")
+		var answer string
+		switch {
+		case days == 0:
+			return fmt.Sprintf("%s is today.", label), true
+		case days < 0 && since:
+			return fmt.Sprintf("%s is %s from now.", label, pluralize(-days, "day")), true
+		case days < 0:
+			return fmt.Sprintf("%s was %s ago.", label, pluralize(-days, "day")), true
+		case since:
+			answer = fmt.Sprintf("%s since %s", pluralize(days, "day"), label)
+		default:
+			answer = fmt.Sprintf("%s until %s", pluralize(days, "day"), label)
+		}
+
+		if match[1] == "weeks" || days >= 14 {
+			answer += fmt.Sprintf(" (%s and %s)", pluralize(days/7, "week"), pluralize(days%7, "day"))
+		}
+		return answer + ".", true
+	}
+
+	if match := dateOffsetPattern.FindStringSubmatch(text); match != nil {
+		amount, err := strconv.Atoi(match[1])
+		if err != nil || amount > 10000 {
+			return "", false
+		}
+		if match[3] == "ago" || match[3] == "before today" {
+			amount = -amount
+		}
+
+		var target time.Time
+		switch strings.TrimSuffix(match[2], "s") {
+		case "day":
+			target = today.AddDate(0, 0, amount)
+		case "week":
+			target = today.AddDate(0, 0, amount*7)
+		case "month":
+			target = today.AddDate(0, amount, 0)
+		case "year":
+			target = today.AddDate(amount, 0, 0)
+		}
+
+		return fmt.Sprintf("%s %s %s is %s.", match[1], match[2], match[3], target.Format("Monday, January 2, 2006")), true
+	}
+
+	return "", false
+}
+
+func parseDate(text string, today time.Time, past bool) (time.Time, bool) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "the ")
+	text = ordinalPattern.ReplaceAllString(text, "$1")
+	text = strings.NewReplacer(",", "", " of ", " ").Replace(text)
+
+	switch text {
+	case "today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	case "christmas":
+		text = "december 25"
+	case "new year", "new years", "new year's", "new years day", "new year's day":
+		text = "january 1"
+	case "halloween":
+		text = "october 31"
+	}
+
+	for _, layout := range dateLayouts {
+		if date, err := time.ParseInLocation(layout, text, today.Location()); err == nil {
+			return date, true
+		}
+	}
+
+	for _, layout := range yearlessLayouts {
+		date, err := time.ParseInLocation(layout, text, today.Location())
+		if err != nil {
+			continue
+		}
+
+		date = time.Date(today.Year(), date.Month(), date.Day(), 0, 0, 0, 0, today.Location())
+		if past && date.After(today) {
+			date = date.AddDate(-1, 0, 0)
+		} else if !past && date.Before(today) {
+			date = date.AddDate(1, 0, 0)
+		}
+		return date, true
+	}
+
+	return time.Time{}, false
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween counts calendar days from one date to another. It compares
+// the dates as UTC midnights so that DST changes and dates centuries away,
+// past what a time.Duration holds, are counted correctly.
+func daysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int((toDay.Unix() - fromDay.Unix()) / 86400)
+}
+
+func pluralize(count int, word string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", word)
+	}
+	return fmt.Sprintf("%d %ss", count, word)
+}
@@ -0,0 +1,87 @@
+package intent
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+var logger = logging.For("intent")
+
+type Handler interface {
+	Name() string
+	Handle(ctx context.Context, text string) (string, bool)
+}
+
+type Router struct {
+	handlers []Handler
+}
+
+func NewRouter(handlers ...Handler) *Router {
+	return &Router{handlers: handlers}
+}
+
+func (r *Router) Answer(ctx context.Context, text string) (string, string, bool) {
+	query, asked := normalize(text)
+	if query == "" {
+		return "", "", false
+	}
+	if asked {
+		ctx = context.WithValue(ctx, questionKey{}, true)
+	}
+
+	for _, handler := range r.handlers {
+		if answer, ok := handler.Handle(ctx, query); ok {
+			return answer, handler.Name(), true
+		}
+	}
+
+	return "", "", false
+}
+
+var questionPrefixes = []string{
+	"what is ", "what's ", "whats ", "how much is ", "how many ", "calculate ", "compute ", "convert ",
+}
+
+type questionKey struct{}
+
+// isQuestion reports whether the text started with one of questionPrefixes,
+// which handlers may take as a sign that ambiguous input like 1/2 is meant
+// to be answered.
+func isQuestion(ctx context.Context) bool {
+	asked, _ := ctx.Value(questionKey{}).(bool)
+	return asked
+}
+
+func normalize(text string) (string, bool) {
+	query := strings.ToLower(strings.TrimSpace(text))
+	query = strings.TrimRight(query, "?!. ")
+	query = strings.Join(strings.Fields(query), " ")
+
+	for _, prefix := range questionPrefixes {
+		if strings.HasPrefix(query, prefix) {
+			if prefix == "how many " {
+				return query, true
+			}
+			return strings.TrimSpace(strings.TrimPrefix(query, prefix)), true
+		}
+	}
+
+	return query, false
+}
+
+func formatNumber(value float64) string {
+	if math.Abs(value) >= 1e15 || (value != 0 && math.Abs(value) < 1e-6) {
+		return strconv.FormatFloat(value, 'g', 6, 64)
+	}
+
+	rounded := math.Round(value*1e6) / 1e6
+	text := strconv.FormatFloat(rounded, 'f', -1, 64)
+	if text == "-0" {
+		return "0"
+	}
+	return text
+}
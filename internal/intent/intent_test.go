@@ -0,0 +1,190 @@
+package intent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"2 + 3 * 4", 14},
+		{"(2 + 3) * 4", 20},
+		{"2^10 / 4", 256},
+		{"-3 + 5", 2},
+		{"2 ^ -1", 0.5},
+		{"10 % 3", 1},
+		{"50% + 1", 1.5},
+		{"1,000 * 3", 3000},
+		{"7 × 6", 42},
+	}
+
+	for _, tt := range tests {
+		got, err := Evaluate(tt.expression)
+		if err != nil {
+			t.Errorf("Evaluate(%q) returned error: %v", tt.expression, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+
+	for _, expression := range []string{"1 / 0", "(1 + 2", "2 +", "3 ) 4"} {
+		if _, err := Evaluate(expression); err == nil {
+			t.Errorf("Expected error for %q", expression)
+		}
+	}
+}
+
+func TestRouterAnswers(t *testing.T) {
+	router := NewRouter(NewMathHandler(), NewUnitHandler(), &DateHandler{
+		now: func() time.Time { return time.Date(2026, time.February, 10, 15, 0, 0, 0, time.UTC) },
+	})
+
+	tests := []struct {
+		text    string
+		handler string
+		want    string
+	}{
+		{"What is 2 + 3 * 4?", "math", "2 + 3 * 4 = 14"},
+		{"calculate 20% of 150", "math", "20% of 150 = 30"},
+		{"what is 1/2", "math", "1/2 = 0.5"},
+		{"1/2 =", "math", "1/2 = 0.5"},
+		{"5 miles in km", "units", "5 mi = 8.04672 km"},
+		{"Convert 100 F to C", "units", "100 °F = 37.777778 °C"},
+		{"0 celsius to fahrenheit", "units", "0 °C = 32 °F"},
+		{"12 in in cm", "units", "12 in = 30.48 cm"},
+		{"1.5 GB to MiB", "units", "1.5 GB = 1430.511475 MiB"},
+		{"How many days until March 1?", "dates", "19 days until Sunday, March 1, 2026 (2 weeks and 5 days)."},
+		{"days since jan 1st", "dates", "40 days since Thursday, January 1, 2026 (5 weeks and 5 days)."},
+		{"days until christmas", "dates", "318 days until Friday, December 25, 2026 (45 weeks and 3 days)."},
+		{"days until 2026-02-11", "dates", "1 day until Wednesday, February 11, 2026."},
+		{"days until 2500-01-01", "dates", "173085 days until Friday, January 1, 2500 (24726 weeks and 3 days)."},
+		{"what date is 30 days from now", "dates", "30 days from now is Thursday, March 12, 2026."},
+		{"2 weeks ago", "dates", "2 weeks ago is Tuesday, January 27, 2026."},
+	}
+
+	for _, tt := range tests {
+		answer, handler, ok := router.Answer(context.Background(), tt.text)
+		if !ok {
+			t.Errorf("Expected %q to be answered", tt.text)
+			continue
+		}
+		if handler != tt.handler || answer != tt.want {
+			t.Errorf("Answer(%q) = %q via %s, want %q via %s", tt.text, answer, handler, tt.want, tt.handler)
+		}
+	}
+}
+
+func TestRouterFallsBack(t *testing.T) {
+	router := NewRouter(NewMathHandler(), NewUnitHandler(), NewDateHandler())
+
+	for _, text := range []string{
+		"",
+		"hello there",
+		"what is the capital of france",
+		"5 miles in kg",
+		"5 parsecs in km",
+		"2026-03-01",
+		"call me at 555-1234",
+		"-5",
+		"days until my birthday",
+		"1 / 0",
+		"1/2",
+		"12-25",
+	} {
+		if answer, handler, ok := router.Answer(context.Background(), text); ok {
+			t.Errorf("Expected %q to fall back to the agent, got %q via %s", text, answer, handler)
+		}
+	}
+}
+
+func TestCurrencyHandler(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("from") != "USD" {
+			http.Error(w, "unsupported", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"amount":1.0,"base":"USD","date":"2026-02-09","rates":{"EUR":0.92,"GBP":0.79}}`)
+	}))
+	defer server.Close()
+
+	provider := NewHTTPRatesProvider(server.URL, time.Hour)
+	router := NewRouter(NewUnitHandler(), NewCurrencyHandler(provider))
+	ctx := context.Background()
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"100 usd in eur", "100 USD = 92 EUR (1 USD = 0.92 EUR, rates from 2026-02-09)"},
+		{"$250 to gbp", "250 USD = 197.5 GBP (1 USD = 0.79 GBP, rates from 2026-02-09)"},
+		{"how much is 10 dollars in euros?", "10 USD = 9.2 EUR (1 USD = 0.92 EUR, rates from 2026-02-09)"},
+	}
+	for _, tt := range tests {
+		answer, handler, ok := router.Answer(ctx, tt.text)
+		if !ok || handler != "currency" || answer != tt.want {
+			t.Errorf("Answer(%q) = %q via %s (ok=%v), want %q", tt.text, answer, handler, ok, tt.want)
+		}
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("Expected rates to be fetched once and cached, got %d requests", requests.Load())
+	}
+
+	if answer, handler, ok := router.Answer(ctx, "10 pounds in kg"); !ok || handler != "units" {
+		t.Errorf("Expected pounds to kg to be a unit conversion, got %q via %s", answer, handler)
+	}
+
+	for _, text := range []string{"100 eur to usd", "100 usd to xyz", "100 men to war"} {
+		if answer, _, ok := router.Answer(ctx, text); ok {
+			t.Errorf("Expected %q to fall back, got %q", text, answer)
+		}
+	}
+}
+
+func TestHTTPRatesProviderUsesStaleRatesOnError(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"base":"EUR","date":"2026-02-09","rates":{"USD":1.08}}`)
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, time.February, 10, 9, 0, 0, 0, time.UTC)
+	provider := NewHTTPRatesProvider(server.URL, time.Hour)
+	provider.now = func() time.Time { return now }
+
+	if _, err := provider.Rates(context.Background(), "EUR"); err != nil {
+		t.Fatalf("Failed to fetch rates: %v", err)
+	}
+
+	fail.Store(true)
+	now = now.Add(2 * time.Hour)
+
+	rates, err := provider.Rates(context.Background(), "EUR")
+	if err != nil {
+		t.Fatalf("Expected stale rates to be used, got %v", err)
+	}
+	if rates.Rates["USD"] != 1.08 {
+		t.Errorf("Unexpected rates %+v", rates.Rates)
+	}
+
+	if _, err := provider.Rates(context.Background(), "GBP"); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Expected an error without cached rates, got %v", err)
+	}
+}
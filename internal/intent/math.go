@@ -0,0 +1,241 @@
+package intent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	percentOfPattern  = regexp.MustCompile(`^(.+?)\s*% of (.+)$`)
+	binaryOpPattern   = regexp.MustCompile(`[\d)%]\s*[-+*/%^×÷]`)
+	dateNumberPattern = regexp.MustCompile(`^\d{1,4}([-/])\d{1,2}([-/])\d{1,4}$`)
+	shortDatePattern  = regexp.MustCompile(`^\d{1,2}[-/]\d{1,2}$`)
+)
+
+type MathHandler struct{}
+
+func NewMathHandler() *MathHandler {
+	return &MathHandler{}
+}
+
+func (h *MathHandler) Name() string {
+	return "math"
+}
+
+func (h *MathHandler) Handle(ctx context.Context, text string) (string, bool) {
+	expression, equals := strings.CutSuffix(strings.TrimSpace(text), "=")
+	expression = strings.TrimSpace(expression)
+
+	if match := percentOfPattern.FindStringSubmatch(expression); match != nil {
+		percent, err := Evaluate(match[1])
+		if err != nil {
+			return "", false
+		}
+		total, err := Evaluate(match[2])
+		if err != nil {
+			return "", false
+		}
+		return fmt.Sprintf("%s%% of %s = %s", match[1], match[2], formatNumber(percent*total/100)), true
+	}
+
+	if !binaryOpPattern.MatchString(expression) || strings.ContainsAny(expression, "abcdefghijklmnopqrstuvwxyz") {
+		return "", false
+	}
+	if dateNumberPattern.MatchString(expression) {
+		return "", false
+	}
+	// A bare 1/2 or 3-4 is as likely a date or a range as a sum.
+	if shortDatePattern.MatchString(expression) && !equals && !isQuestion(ctx) {
+		return "", false
+	}
+
+	result, err := Evaluate(expression)
+	if err != nil || math.IsNaN(result) || math.IsInf(result, 0) {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s = %s", expression, formatNumber(result)), true
+}
+
+func Evaluate(expression string) (float64, error) {
+	expression = strings.NewReplacer("×", "*", "÷", "/", ",", "").Replace(expression)
+
+	p := &exprParser{input: expression}
+	value, err := p.parseSum()
+	if err != nil {
+		return 0, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+
+	return value, nil
+}
+
+type exprParser struct {
+	input string
+	pos   int
+	depth int
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() byte {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *exprParser) parseSum() (float64, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseProduct()
+		if err != nil {
+			return 0, err
+		}
+
+		if op == '+' {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+}
+
+func (p *exprParser) parseProduct() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+
+		switch op {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.parseUnary()
+		return -value, err
+	case '+':
+		p.pos++
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+func (p *exprParser) parsePower() (float64, error) {
+	base, err := p.parseAtom()
+	if err != nil {
+		return 0, err
+	}
+
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Pow(base, exponent), nil
+}
+
+func (p *exprParser) parseAtom() (float64, error) {
+	if p.peek() == '(' {
+		p.depth++
+		if p.depth > 32 {
+			return 0, fmt.Errorf("expression is nested too deeply")
+		}
+		p.pos++
+
+		value, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		p.depth--
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, fmt.Errorf("expected a number at position %d", start)
+	}
+
+	value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+	}
+
+	if p.peek() == '%' && p.isPercentSign() {
+		p.pos++
+		value /= 100
+	}
+
+	return value, nil
+}
+
+func (p *exprParser) isPercentSign() bool {
+	rest := strings.TrimSpace(p.input[p.pos+1:])
+	if rest == "" {
+		return true
+	}
+	return strings.ContainsAny(rest[:1], "+-*/)")
+}
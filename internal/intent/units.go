@@ -0,0 +1,150 @@
+package intent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var conversionPattern = regexp.MustCompile(`^(-?[\d,]*\.?\d+)\s*([a-z°$€£¥/ ]+?)\s+(?:in|to|into|as)\s+([a-z°$€£¥/ ]+)$`)
+
+type unit struct {
+	symbol    string
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+func (u unit) toBase(value float64) float64 {
+	return (value + u.offset) * u.factor
+}
+
+func (u unit) fromBase(value float64) float64 {
+	return value/u.factor - u.offset
+}
+
+var units = map[string]unit{}
+
+func registerUnit(symbol, dimension string, factor, offset float64, aliases ...string) {
+	u := unit{symbol: symbol, dimension: dimension, factor: factor, offset: offset}
+	units[symbol] = u
+	for _, alias := range aliases {
+		units[alias] = u
+	}
+}
+
+func init() {
+	registerUnit("mm", "length", 0.001, 0, "millimeter", "millimeters", "millimetre", "millimetres")
+	registerUnit("cm", "length", 0.01, 0, "centimeter", "centimeters", "centimetre", "centimetres")
+	registerUnit("m", "length", 1, 0, "meter", "meters", "metre", "metres")
+	registerUnit("km", "length", 1000, 0, "kilometer", "kilometers", "kilometre", "kilometres", "kms")
+	registerUnit("in", "length", 0.0254, 0, "inch", "inches", `"`)
+	registerUnit("ft", "length", 0.3048, 0, "foot", "feet", "'")
+	registerUnit("yd", "length", 0.9144, 0, "yard", "yards")
+	registerUnit("mi", "length", 1609.344, 0, "mile", "miles")
+	registerUnit("nmi", "length", 1852, 0, "nautical mile", "nautical miles")
+
+	registerUnit("mg", "mass", 0.000001, 0, "milligram", "milligrams")
+	registerUnit("g", "mass", 0.001, 0, "gram", "grams")
+	registerUnit("kg", "mass", 1, 0, "kilogram", "kilograms", "kilo", "kilos", "kgs")
+	registerUnit("t", "mass", 1000, 0, "tonne", "tonnes", "metric ton", "metric tons")
+	registerUnit("oz", "mass", 0.028349523125, 0, "ounce", "ounces")
+	registerUnit("lb", "mass", 0.45359237, 0, "lbs", "pound", "pounds")
+	registerUnit("st", "mass", 6.35029318, 0, "stone", "stones")
+
+	registerUnit("ml", "volume", 0.001, 0, "milliliter", "milliliters", "millilitre", "millilitres")
+	registerUnit("l", "volume", 1, 0, "liter", "liters", "litre", "litres")
+	registerUnit("tsp", "volume", 0.00492892159375, 0, "teaspoon", "teaspoons")
+	registerUnit("tbsp", "volume", 0.01478676478125, 0, "tablespoon", "tablespoons")
+	registerUnit("fl oz", "volume", 0.0295735295625, 0, "fluid ounce", "fluid ounces")
+	registerUnit("cup", "volume", 0.2365882365, 0, "cups")
+	registerUnit("pt", "volume", 0.473176473, 0, "pint", "pints")
+	registerUnit("qt", "volume", 0.946352946, 0, "quart", "quarts")
+	registerUnit("gal", "volume", 3.785411784, 0, "gallon", "gallons")
+
+	registerUnit("°C", "temperature", 1, 273.15, "c", "°c", "celsius", "degrees celsius", "degrees c")
+	registerUnit("°F", "temperature", 5.0/9.0, 459.67, "f", "°f", "fahrenheit", "degrees fahrenheit", "degrees f")
+	registerUnit("K", "temperature", 1, 0, "k", "kelvin", "kelvins")
+
+	registerUnit("km/h", "speed", 1/3.6, 0, "kph", "kmh", "kilometers per hour", "kilometres per hour")
+	registerUnit("mph", "speed", 0.44704, 0, "miles per hour")
+	registerUnit("m/s", "speed", 1, 0, "meters per second", "metres per second")
+	registerUnit("kn", "speed", 1852.0/3600.0, 0, "knot", "knots")
+
+	registerUnit("B", "data", 1, 0, "b", "byte", "bytes")
+	registerUnit("KB", "data", 1e3, 0, "kb", "kilobyte", "kilobytes")
+	registerUnit("MB", "data", 1e6, 0, "mb", "megabyte", "megabytes")
+	registerUnit("GB", "data", 1e9, 0, "gb", "gigabyte", "gigabytes")
+	registerUnit("TB", "data", 1e12, 0, "tb", "terabyte", "terabytes")
+	registerUnit("KiB", "data", 1024, 0, "kib", "kibibyte", "kibibytes")
+	registerUnit("MiB", "data", 1024*1024, 0, "mib", "mebibyte", "mebibytes")
+	registerUnit("GiB", "data", 1024*1024*1024, 0, "gib", "gibibyte", "gibibytes")
+}
+
+type conversion struct {
+	amount float64
+	from   string
+	to     string
+}
+
+func parseConversion(text string) (*conversion, bool) {
+	match := conversionPattern.FindStringSubmatch(text)
+	if match == nil {
+		return nil, false
+	}
+
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64)
+	if err != nil {
+		return nil, false
+	}
+
+	return &conversion{
+		amount: amount,
+		from:   strings.TrimSpace(match[2]),
+		to:     strings.TrimSpace(match[3]),
+	}, true
+}
+
+type UnitHandler struct{}
+
+func NewUnitHandler() *UnitHandler {
+	return &UnitHandler{}
+}
+
+func (h *UnitHandler) Name() string {
+	return "units"
+}
+
+func (h *UnitHandler) Handle(ctx context.Context, text string) (string, bool) {
+	c, ok := parseConversion(text)
+	if !ok {
+		return "", false
+	}
+
+	result, from, to, err := ConvertUnits(c.amount, c.from, c.to)
+	if err != nil {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s %s = %s %s", formatNumber(c.amount), from, formatNumber(result), to), true
+}
+
+func ConvertUnits(amount float64, from, to string) (float64, string, string, error) {
+	source, ok := units[from]
+	if !ok {
+		return 0, "", "", fmt.Errorf("unknown unit: %s", from)
+	}
+
+	target, ok := units[to]
+	if !ok {
+		return 0, "", "", fmt.Errorf("unknown unit: %s", to)
+	}
+
+	if source.dimension != target.dimension {
+		return 0, "", "", fmt.Errorf("cannot convert %s to %s", source.dimension, target.dimension)
+	}
+
+	return target.fromBase(source.toBase(amount)), source.symbol, target.symbol, nil
+}
@@ -7,19 +7,61 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
+const (
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	defaultAnthropicVersion = "2023-06-01"
+)
+
 type AnthropicProvider struct {
 	config      *Config
 	httpClient  *http.Client
+	baseURL     string
 	rateLimiter *RateLimiter
 	monitor     *Monitor
 }
 
 type AnthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is a string, or a list of AnthropicInputBlock for messages
+	// with images.
+	Content interface{} `json:"content"`
+}
+
+type AnthropicInputBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *AnthropicImageSource `json:"source,omitempty"`
+}
+
+// AnthropicImageSource is an image given as base64 data or by URL.
+type AnthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+func newAnthropicMessage(msg Message) AnthropicMessage {
+	if len(msg.Parts) == 0 {
+		return AnthropicMessage{Role: string(msg.Role), Content: msg.Content}
+	}
+
+	blocks := make([]AnthropicInputBlock, 0, len(msg.Parts)+1)
+	for _, part := range msg.ContentParts() {
+		switch {
+		case part.Type != ContentImage:
+			blocks = append(blocks, AnthropicInputBlock{Type: "text", Text: part.Text})
+		case part.ImageURL != "":
+			blocks = append(blocks, AnthropicInputBlock{Type: "image", Source: &AnthropicImageSource{Type: "url", URL: part.ImageURL}})
+		default:
+			blocks = append(blocks, AnthropicInputBlock{Type: "image", Source: &AnthropicImageSource{Type: "base64", MediaType: part.MediaType, Data: part.Data}})
+		}
+	}
+	return AnthropicMessage{Role: string(msg.Role), Content: blocks}
 }
 
 type AnthropicRequest struct {
@@ -28,22 +70,58 @@ type AnthropicRequest struct {
 	Messages  []AnthropicMessage `json:"messages"`
 	System    string             `json:"system,omitempty"`
 	Stream    bool               `json:"stream,omitempty"`
+	Thinking  *AnthropicThinking `json:"thinking,omitempty"`
+}
+
+// AnthropicThinking enables extended thinking with a token budget for the
+// model's reasoning.
+type AnthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type AnthropicContentBlock struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Thinking string `json:"thinking"`
+}
+
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 type AnthropicResponse struct {
-	ID      string `json:"id"`
-	Type    string `json:"type"`
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-	Usage struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	ID      string                  `json:"id"`
+	Type    string                  `json:"type"`
+	Content []AnthropicContentBlock `json:"content"`
+	Usage   AnthropicUsage          `json:"usage"`
+}
+
+// anthropicStreamEvent is the data of one server-sent event in a streamed
+// response. Only the fields used by the event's type are set.
+type anthropicStreamEvent struct {
+	Type         string                `json:"type"`
+	Message      AnthropicResponse     `json:"message"`
+	ContentBlock AnthropicContentBlock `json:"content_block"`
+	Delta        struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Thinking string `json:"thinking"`
+	} `json:"delta"`
+	Usage AnthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
 }
 
 func NewAnthropicProvider(config *Config) *AnthropicProvider {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
+	}
+
 	return &AnthropicProvider{
 		config: config,
 		httpClient: &http.Client{
@@ -54,13 +132,16 @@ func NewAnthropicProvider(config *Config) *AnthropicProvider {
 				IdleConnTimeout:     90 * time.Second,
 			},
 		},
-		rateLimiter: NewRateLimiter(50, time.Minute),
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		rateLimiter: NewRateLimiter(defaultAnthropicRateLimit, time.Minute),
 		monitor:     NewMonitor(),
 	}
 }
 
 func (p *AnthropicProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
-	p.rateLimiter.Wait()
+	if err := p.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 
 	startTime := time.Now()
 	var lastErr error
@@ -95,7 +176,10 @@ func (p *AnthropicProvider) Complete(ctx context.Context, req *CompletionRequest
 	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
 }
 
-func (p *AnthropicProvider) doRequest(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+// newRequest converts req to the Messages API format. With a thinking budget
+// the budget is added to max_tokens, since the API counts the reasoning
+// towards it and the answer should keep the configured limit.
+func (p *AnthropicProvider) newRequest(req *CompletionRequest, stream bool) *AnthropicRequest {
 	if req.MaxTokens == 0 {
 		req.MaxTokens = p.config.MaxTokens
 	}
@@ -104,34 +188,45 @@ func (p *AnthropicProvider) doRequest(ctx context.Context, req *CompletionReques
 		Model:     p.config.Model,
 		MaxTokens: req.MaxTokens,
 		Messages:  make([]AnthropicMessage, 0),
-		Stream:    false,
+		Stream:    stream,
+	}
+
+	if p.config.ThinkingBudget > 0 {
+		anthropicReq.Thinking = &AnthropicThinking{
+			Type:         "enabled",
+			BudgetTokens: p.config.ThinkingBudget,
+		}
+		anthropicReq.MaxTokens += p.config.ThinkingBudget
 	}
 
 	for _, msg := range req.Messages {
 		if msg.Role == RoleSystem {
-			anthropicReq.System = msg.Content
+			if anthropicReq.System != "" {
+				anthropicReq.System += "\n\n"
+			}
+			anthropicReq.System += msg.Content
 		} else {
-			anthropicReq.Messages = append(anthropicReq.Messages, AnthropicMessage{
-				Role:    string(msg.Role),
-				Content: msg.Content,
-			})
+			anthropicReq.Messages = append(anthropicReq.Messages, newAnthropicMessage(msg))
 		}
 	}
 
+	return anthropicReq
+}
+
+func (p *AnthropicProvider) doRequest(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	anthropicReq := p.newRequest(req, false)
+
 	reqBody, err := json.Marshal(anthropicReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/messages", bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", p.config.APIKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
-	httpReq.Header.Set("anthropic-dangerous-direct-browser-access", "false")
+	p.setHeaders(httpReq)
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
@@ -153,13 +248,20 @@ func (p *AnthropicProvider) doRequest(ctx context.Context, req *CompletionReques
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	content := ""
-	if len(anthropicResp.Content) > 0 {
-		content = anthropicResp.Content[0].Text
+	// With thinking enabled the answer follows one or more thinking blocks.
+	var content, thinking strings.Builder
+	for _, block := range anthropicResp.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
+		}
 	}
 
 	return &CompletionResponse{
-		Content: content,
+		Content:  content.String(),
+		Thinking: thinking.String(),
 		Usage: Usage{
 			PromptTokens:     anthropicResp.Usage.InputTokens,
 			CompletionTokens: anthropicResp.Usage.OutputTokens,
@@ -168,81 +270,133 @@ func (p *AnthropicProvider) doRequest(ctx context.Context, req *CompletionReques
 	}, nil
 }
 
+// StreamComplete streams the answer to callback as it is generated. The
+// model's reasoning, if thinking is enabled, goes to the handler set with
+// WithThinkingHandler instead, so it never mixes with the answer.
 func (p *AnthropicProvider) StreamComplete(ctx context.Context, req *CompletionRequest, callback func(chunk string) error) error {
-	p.rateLimiter.Wait()
-
-	if req.MaxTokens == 0 {
-		req.MaxTokens = p.config.MaxTokens
+	if err := p.rateLimiter.Wait(ctx); err != nil {
+		return err
 	}
 
-	anthropicReq := &AnthropicRequest{
-		Model:     p.config.Model,
-		MaxTokens: req.MaxTokens,
-		Messages:  make([]AnthropicMessage, 0),
-		Stream:    true,
-	}
+	startTime := time.Now()
+	usage, err := p.doStream(ctx, req, callback)
+	p.monitor.RecordRequest("anthropic", time.Since(startTime), usage.TotalTokens, err)
+	return err
+}
 
-	for _, msg := range req.Messages {
-		if msg.Role == RoleSystem {
-			anthropicReq.System = msg.Content
-		} else {
-			anthropicReq.Messages = append(anthropicReq.Messages, AnthropicMessage{
-				Role:    string(msg.Role),
-				Content: msg.Content,
-			})
-		}
-	}
+func (p *AnthropicProvider) doStream(ctx context.Context, req *CompletionRequest, callback func(chunk string) error) (Usage, error) {
+	anthropicReq := p.newRequest(req, true)
 
 	reqBody, err := json.Marshal(anthropicReq)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return Usage{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/messages", bytes.NewReader(reqBody))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return Usage{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", p.config.APIKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
-	httpReq.Header.Set("anthropic-dangerous-direct-browser-access", "false")
+	p.setHeaders(httpReq)
+	httpReq.Header.Set("Accept", "text/event-stream")
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return Usage{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return HandleHTTPError(resp.StatusCode, string(body))
+		return Usage{}, HandleHTTPError(resp.StatusCode, string(body))
 	}
 
-	decoder := json.NewDecoder(resp.Body)
-	for {
-		var event map[string]interface{}
-		if err := decoder.Decode(&event); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("failed to decode stream: %w", err)
+	onThinking := thinkingHandlerFromContext(ctx)
+	var usage Usage
+
+	// Each event is an "event:" line naming its type, then a "data:" line
+	// with the JSON payload, which repeats the type.
+	scanner := newLineScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
 		}
 
-		if eventType, ok := event["type"].(string); ok && eventType == "content_block_delta" {
-			if delta, ok := event["delta"].(map[string]interface{}); ok {
-				if text, ok := delta["text"].(string); ok {
-					if err := callback(text); err != nil {
-						return err
-					}
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			return usage, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			usage.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_start":
+			if event.ContentBlock.Type == "text" && event.ContentBlock.Text != "" {
+				if err := callback(event.ContentBlock.Text); err != nil {
+					return usage, err
+				}
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				if err := callback(event.Delta.Text); err != nil {
+					return usage, err
+				}
+			case "thinking_delta":
+				if onThinking != nil {
+					onThinking(event.Delta.Thinking)
 				}
 			}
+		case "message_delta":
+			usage.CompletionTokens = event.Usage.OutputTokens
+		case "message_stop":
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+			return usage, nil
+		case "error":
+			return usage, anthropicStreamError(event.Error.Type, event.Error.Message)
 		}
 	}
 
-	return nil
+	if err := scanner.Err(); err != nil {
+		return usage, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return usage, fmt.Errorf("stream ended before the message was complete")
+}
+
+// anthropicStreamError maps an error sent mid-stream to the same errors as
+// the equivalent HTTP status, so overloads are retried elsewhere.
+func anthropicStreamError(errorType, message string) error {
+	status := http.StatusInternalServerError
+	switch errorType {
+	case "overloaded_error":
+		status = http.StatusServiceUnavailable
+	case "rate_limit_error":
+		status = http.StatusTooManyRequests
+	case "invalid_request_error":
+		status = http.StatusBadRequest
+	}
+	return HandleHTTPError(status, fmt.Sprintf("%s: %s", errorType, message))
+}
+
+func (p *AnthropicProvider) setHeaders(httpReq *http.Request) {
+	version := p.config.APIVersion
+	if version == "" {
+		version = defaultAnthropicVersion
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.config.APIKey)
+	httpReq.Header.Set("anthropic-version", version)
+	httpReq.Header.Set("anthropic-dangerous-direct-browser-access", "false")
+
+	setExtraHeaders(httpReq, p.config.Headers)
 }
 
 func (p *AnthropicProvider) GetModel() string {
 	return p.config.Model
 }
+
+func (p *AnthropicProvider) setRateLimiter(limiter *RateLimiter) {
+	p.rateLimiter = limiter
+}
@@ -1,6 +1,13 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -37,3 +44,213 @@ func TestAnthropicProviderGetModel(t *testing.T) {
 		t.Errorf("expected 'claude-sonnet-4-5', got %s", model)
 	}
 }
+
+func TestAnthropicProviderVersionOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("anthropic-version") != "2024-01-01" {
+			t.Errorf("expected version override, got %s", r.Header.Get("anthropic-version"))
+		}
+		if r.Header.Get("x-api-key") != "test-api-key" {
+			t.Errorf("unexpected api key %s", r.Header.Get("x-api-key"))
+		}
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"ok"}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&Config{
+		Provider:   "anthropic",
+		APIKey:     "test-api-key",
+		Model:      "claude-sonnet-4-5",
+		MaxTokens:  1024,
+		BaseURL:    server.URL + "/v1",
+		APIVersion: "2024-01-01",
+	})
+
+	resp, err := provider.Complete(context.Background(), &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if resp.Content != "ok" {
+		t.Errorf("expected 'ok', got %s", resp.Content)
+	}
+}
+
+const anthropicThinkingStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","content":[],"usage":{"input_tokens":12,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"The user wants "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"a greeting."}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"abc"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":" there!"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":20}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func TestAnthropicProviderStreamComplete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req AnthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Error("expected a streaming request")
+		}
+		if req.Thinking == nil || req.Thinking.BudgetTokens != 2048 || req.MaxTokens != 1024+2048 {
+			t.Errorf("expected the thinking budget on top of max_tokens, got %+v, %d", req.Thinking, req.MaxTokens)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, anthropicThinkingStream)
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&Config{
+		APIKey:         "test-api-key",
+		Model:          "claude-sonnet-4-5",
+		MaxTokens:      1024,
+		BaseURL:        server.URL,
+		ThinkingBudget: 2048,
+	})
+
+	var content, thinking strings.Builder
+	ctx := WithThinkingHandler(context.Background(), func(chunk string) {
+		thinking.WriteString(chunk)
+	})
+	var chunks int
+	err := provider.StreamComplete(ctx, &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+	}, func(chunk string) error {
+		chunks++
+		content.WriteString(chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if content.String() != "Hello there!" || chunks != 2 {
+		t.Errorf("expected the answer in 2 chunks, got %q in %d", content.String(), chunks)
+	}
+	if thinking.String() != "The user wants a greeting." {
+		t.Errorf("expected reasoning to go to the thinking handler, got %q", thinking.String())
+	}
+}
+
+func TestAnthropicProviderStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&Config{APIKey: "test-api-key", MaxTokens: 1024, BaseURL: server.URL})
+
+	err := provider.StreamComplete(context.Background(), &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+	}, func(chunk string) error { return nil })
+	if !errors.Is(err, ErrServerUnavailable) || !IsRetryableError(err) {
+		t.Errorf("expected a retryable overload error, got %v", err)
+	}
+
+	// A stream cut off before message_stop is an error, not a short answer.
+	truncated := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n")
+	}))
+	defer truncated.Close()
+
+	provider = NewAnthropicProvider(&Config{APIKey: "test-api-key", MaxTokens: 1024, BaseURL: truncated.URL})
+	err = provider.StreamComplete(context.Background(), &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+	}, func(chunk string) error { return nil })
+	if err == nil {
+		t.Error("expected an error for a truncated stream")
+	}
+}
+
+func TestAnthropicProviderThinkingBlocks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"content":[{"type":"thinking","thinking":"Add them up.","signature":"abc"},{"type":"text","text":"4"}],"usage":{"input_tokens":5,"output_tokens":9}}`)
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&Config{APIKey: "test-api-key", MaxTokens: 1024, BaseURL: server.URL, ThinkingBudget: 1024})
+
+	resp, err := provider.Complete(context.Background(), &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "2+2?"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "4" || resp.Thinking != "Add them up." {
+		t.Errorf("expected the answer apart from the reasoning, got %+v", resp)
+	}
+}
+
+func TestAnthropicProviderImages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			System   string `json:"system"`
+			Messages []struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		want := `[{"type":"text","text":"What is this?"},{"type":"image","source":{"type":"base64","media_type":"image/jpeg","data":"/9j/"}},{"type":"image","source":{"type":"url","url":"https://example.com/cat.jpg"}}]`
+		if req.System != "Be brief." || len(req.Messages) != 1 || string(req.Messages[0].Content) != want {
+			t.Errorf("unexpected request: %s %s", req.System, req.Messages)
+		}
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"A cat."}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&Config{APIKey: "test-api-key", MaxTokens: 1024, BaseURL: server.URL})
+
+	resp, err := provider.Complete(context.Background(), &CompletionRequest{
+		Messages: []Message{
+			{Role: RoleSystem, Content: "Be brief."},
+			{Role: RoleUser, Content: "What is this?", Parts: []ContentPart{
+				ImageDataPart("image/jpeg", []byte{0xff, 0xd8, 0xff}),
+				ImageURLPart("https://example.com/cat.jpg"),
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "A cat." {
+		t.Errorf("expected 'A cat.', got %s", resp.Content)
+	}
+}
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const (
+	BudgetActionReject    = "reject"
+	BudgetActionDowngrade = "downgrade"
+
+	costRetentionDays = 31
+)
+
+type BudgetConfig struct {
+	DailyLimit     float64
+	ChatDailyLimit float64
+	Action         string
+	DowngradeModel string
+}
+
+type BudgetStatus struct {
+	Day            string  `json:"day"`
+	ChatID         string  `json:"chat_id,omitempty"`
+	Spent          float64 `json:"spent"`
+	ChatSpent      float64 `json:"chat_spent"`
+	DailyLimit     float64 `json:"daily_limit,omitempty"`
+	ChatDailyLimit float64 `json:"chat_daily_limit,omitempty"`
+	Exceeded       bool    `json:"exceeded"`
+	Action         string  `json:"action,omitempty"`
+}
+
+type chatIDKey struct{}
+
+func WithChatID(ctx context.Context, chatID string) context.Context {
+	return context.WithValue(ctx, chatIDKey{}, chatID)
+}
+
+func ChatIDFromContext(ctx context.Context) (string, bool) {
+	chatID, ok := ctx.Value(chatIDKey{}).(string)
+	return chatID, ok && chatID != ""
+}
+
+func (c *ModelConfig) CostOf(usage Usage) float64 {
+	if c.InputPrice == 0 && c.OutputPrice == 0 {
+		return float64(usage.TotalTokens) * c.Cost / 1e6
+	}
+	return (float64(usage.PromptTokens)*c.InputPrice + float64(usage.CompletionTokens)*c.OutputPrice) / 1e6
+}
+
+func (c *ModelConfig) unitPrice() float64 {
+	if c.InputPrice == 0 && c.OutputPrice == 0 {
+		return c.Cost
+	}
+	return (c.InputPrice + c.OutputPrice) / 2
+}
+
+func (mmm *MultiModelManager) SetBudget(config *BudgetConfig) error {
+	if config != nil {
+		switch config.Action {
+		case "":
+			config.Action = BudgetActionReject
+		case BudgetActionReject, BudgetActionDowngrade:
+		default:
+			return fmt.Errorf("unsupported budget action: %s", config.Action)
+		}
+	}
+
+	mmm.mu.Lock()
+	defer mmm.mu.Unlock()
+
+	if config != nil && config.DowngradeModel != "" {
+		if _, ok := mmm.providers[config.DowngradeModel]; !ok {
+			return fmt.Errorf("downgrade model %s not found", config.DowngradeModel)
+		}
+	}
+
+	mmm.budget = config
+	return nil
+}
+
+// SetCostStorage persists the daily cost reports in store and loads the
+// ones already there.
+func (mmm *MultiModelManager) SetCostStorage(ctx context.Context, store storage.Storage) error {
+	return mmm.monitor.SetCostStorage(ctx, store)
+}
+
+func (mmm *MultiModelManager) BudgetStatus(chatID string) *BudgetStatus {
+	mmm.mu.RLock()
+	budget := mmm.budget
+	mmm.mu.RUnlock()
+
+	day := time.Now().Format("2006-01-02")
+	report := mmm.monitor.CostReport(day)
+
+	status := &BudgetStatus{
+		Day:       day,
+		ChatID:    chatID,
+		Spent:     report.Total.Cost,
+		ChatSpent: report.ByChat[chatID].Cost,
+	}
+
+	if budget != nil {
+		status.DailyLimit = budget.DailyLimit
+		status.ChatDailyLimit = budget.ChatDailyLimit
+		status.Action = budget.Action
+		status.Exceeded = (budget.DailyLimit > 0 && status.Spent >= budget.DailyLimit) ||
+			(budget.ChatDailyLimit > 0 && chatID != "" && status.ChatSpent >= budget.ChatDailyLimit)
+	}
+
+	return status
+}
+
+func (mmm *MultiModelManager) applyBudget(ctx context.Context, candidates []string) ([]string, error) {
+	mmm.mu.RLock()
+	budget := mmm.budget
+	mmm.mu.RUnlock()
+
+	if budget == nil {
+		return candidates, nil
+	}
+
+	chatID, _ := ChatIDFromContext(ctx)
+	status := mmm.BudgetStatus(chatID)
+	if !status.Exceeded {
+		return candidates, nil
+	}
+
+	if budget.Action != BudgetActionDowngrade {
+		return nil, NewLLMError("BUDGET_EXCEEDED", fmt.Sprintf("daily budget exceeded: spent $%.4f today ($%.4f in this chat)", status.Spent, status.ChatSpent), ErrBudgetExceeded)
+	}
+
+	cheapest := budget.DowngradeModel
+	if cheapest == "" {
+		cheapest = mmm.cheapestModel()
+	}
+
+	logger.Warn("Daily budget exceeded, downgrading model", "chat_id", chatID, "model", cheapest)
+	return []string{cheapest}, nil
+}
+
+func (mmm *MultiModelManager) cheapestModel() string {
+	mmm.mu.RLock()
+	defer mmm.mu.RUnlock()
+
+	names := make([]string, 0, len(mmm.models))
+	for name := range mmm.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.SliceStable(names, func(i, j int) bool {
+		return mmm.models[names[i]].unitPrice() < mmm.models[names[j]].unitPrice()
+	})
+
+	return names[0]
+}
+
+func (mmm *MultiModelManager) recordUsage(ctx context.Context, name string, usage Usage) {
+	mmm.mu.RLock()
+	config, ok := mmm.models[name]
+	mmm.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	chatID, _ := ChatIDFromContext(ctx)
+	mmm.monitor.RecordUsage(name, config.Provider, chatID, usage, config.CostOf(usage))
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func TestCostTracking(t *testing.T) {
+	usage := Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}
+	priced := &fakeProvider{name: "priced", usage: usage}
+	blended := &fakeProvider{name: "blended", usage: usage}
+	manager := newRoutedManager(map[string]*fakeProvider{"priced": priced, "blended": blended}, map[string]float64{"blended": 2}, "priced")
+	manager.models["priced"].Provider = "openai"
+	manager.models["priced"].InputPrice = 3
+	manager.models["priced"].OutputPrice = 15
+
+	ctx := WithChatID(context.Background(), "chat-1")
+	if _, err := manager.Complete(ctx, nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if _, err := manager.Complete(WithModel(WithChatID(context.Background(), "chat-2"), "blended"), nil); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	report := manager.Monitor().CostReport(time.Now().Format("2006-01-02"))
+
+	expected := map[string]float64{"chat-1": 0.0105, "chat-2": 0.003}
+	for chatID, cost := range expected {
+		if got := report.ByChat[chatID].Cost; math.Abs(got-cost) > 1e-9 {
+			t.Errorf("Expected %s to cost %f, got %f", chatID, cost, got)
+		}
+	}
+	if math.Abs(report.Total.Cost-0.0135) > 1e-9 || report.Total.Requests != 2 || report.Total.PromptTokens != 2000 {
+		t.Errorf("Unexpected totals %+v", report.Total)
+	}
+	if report.ByProvider["openai"].Requests != 1 || report.ByModel["blended"].Requests != 1 {
+		t.Errorf("Expected per provider and model stats, got %+v %+v", report.ByProvider, report.ByModel)
+	}
+}
+
+func TestBudgetLimits(t *testing.T) {
+	usage := Usage{PromptTokens: 100000, TotalTokens: 100000}
+	premium := &fakeProvider{name: "premium", usage: usage}
+	budget := &fakeProvider{name: "budget", usage: usage}
+	manager := newRoutedManager(map[string]*fakeProvider{"premium": premium, "budget": budget}, map[string]float64{"premium": 10, "budget": 1}, "premium")
+
+	if err := manager.SetBudget(&BudgetConfig{ChatDailyLimit: 1.5, Action: "shrug"}); err == nil {
+		t.Error("Expected unknown budget action to be rejected")
+	}
+	if err := manager.SetBudget(&BudgetConfig{ChatDailyLimit: 1.5}); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+
+	chat := WithChatID(context.Background(), "chat")
+	for i := 0; i < 2; i++ {
+		if _, err := manager.Complete(chat, nil); err != nil {
+			t.Fatalf("Expected request %d within budget, got %v", i+1, err)
+		}
+	}
+
+	status := manager.BudgetStatus("chat")
+	if !status.Exceeded || math.Abs(status.ChatSpent-2) > 1e-9 {
+		t.Errorf("Expected chat budget to be exceeded, got %+v", status)
+	}
+
+	if _, err := manager.Complete(chat, nil); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected budget error, got %v", err)
+	}
+	if _, err := manager.Complete(WithChatID(context.Background(), "other"), nil); err != nil {
+		t.Errorf("Expected other chats to keep their own budget, got %v", err)
+	}
+
+	if err := manager.SetBudget(&BudgetConfig{ChatDailyLimit: 1.5, Action: BudgetActionDowngrade}); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+	resp, err := manager.Complete(chat, nil)
+	if err != nil {
+		t.Fatalf("Expected downgrade instead of an error, got %v", err)
+	}
+	if resp.Content != "budget" {
+		t.Errorf("Expected cheapest model after exceeding the budget, got %s", resp.Content)
+	}
+}
+
+func TestCostReportsSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewFileStorage(t.TempDir())
+	today := time.Now().Format("2006-01-02")
+	old := time.Now().AddDate(0, 0, -costRetentionDays-1).Format("2006-01-02")
+	if err := store.WriteFile(ctx, costReportPath(old), []byte(`{"day":"`+old+`"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	monitor := NewMonitor()
+	if err := monitor.SetCostStorage(ctx, store); err != nil {
+		t.Fatalf("SetCostStorage failed: %v", err)
+	}
+	monitor.RecordUsage("gpt", "openai", "chat-1", Usage{PromptTokens: 10, CompletionTokens: 5}, 0.5)
+
+	if exists, _ := store.FileExists(ctx, costReportPath(old)); exists {
+		t.Error("Expected reports past retention to be deleted")
+	}
+
+	restarted := NewMonitor()
+	if err := restarted.SetCostStorage(ctx, store); err != nil {
+		t.Fatalf("SetCostStorage failed: %v", err)
+	}
+	report := restarted.CostReport(today)
+	if report.Total.Cost != 0.5 || report.ByChat["chat-1"].Requests != 1 || report.ByModel["gpt"].PromptTokens != 10 {
+		t.Errorf("Expected today's spending to be loaded, got %+v", report)
+	}
+	if days := restarted.CostDays(); len(days) != 1 || days[0] != today {
+		t.Errorf("Expected only today to be loaded, got %v", days)
+	}
+}
@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// compatProvider is a gateway that speaks the OpenAI chat completions API
+// and is used through the OpenAI client.
+type compatProvider struct {
+	baseURL string
+	// keepModelPrefix is set for gateways whose model names start with a
+	// vendor, such as OpenRouter's "anthropic/claude-sonnet-4.5" and
+	// "openrouter/auto". For the others a leading "<provider>/" is
+	// stripped, so names written as e.g. "groq/llama-3.3-70b-versatile"
+	// work too.
+	keepModelPrefix bool
+}
+
+var compatProviders = map[string]compatProvider{
+	"openrouter": {baseURL: "https://openrouter.ai/api/v1", keepModelPrefix: true},
+	"groq":       {baseURL: "https://api.groq.com/openai/v1"},
+	"deepseek":   {baseURL: "https://api.deepseek.com/v1"},
+}
+
+// newCompatProvider creates the OpenAI client for a gateway alias, filling
+// in its default base URL and normalizing the model name.
+func newCompatProvider(config *Config) (*OpenAIProvider, error) {
+	compat, ok := compatProviders[config.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
+	}
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("API key is required for %s provider", config.Provider)
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("model is required for %s provider", config.Provider)
+	}
+
+	if config.BaseURL == "" {
+		config.BaseURL = compat.baseURL
+	}
+	if !compat.keepModelPrefix {
+		config.Model = strings.TrimPrefix(config.Model, config.Provider+"/")
+	}
+
+	return NewOpenAIProvider(config), nil
+}
+
+func setExtraHeaders(httpReq *http.Request, headers map[string]string) {
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+}
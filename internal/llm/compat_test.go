@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewCompatProvider(t *testing.T) {
+	tests := []struct {
+		provider string
+		model    string
+		baseURL  string
+		expected string
+	}{
+		{"openrouter", "anthropic/claude-sonnet-4.5", "https://openrouter.ai/api/v1", "anthropic/claude-sonnet-4.5"},
+		{"openrouter", "openrouter/auto", "https://openrouter.ai/api/v1", "openrouter/auto"},
+		{"groq", "groq/llama-3.3-70b-versatile", "https://api.groq.com/openai/v1", "llama-3.3-70b-versatile"},
+		{"groq", "meta-llama/llama-4-scout-17b-16e-instruct", "https://api.groq.com/openai/v1", "meta-llama/llama-4-scout-17b-16e-instruct"},
+		{"deepseek", "deepseek/deepseek-chat", "https://api.deepseek.com/v1", "deepseek-chat"},
+	}
+
+	for _, tt := range tests {
+		provider, err := newCompatProvider(&Config{Provider: tt.provider, APIKey: "key", Model: tt.model})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.provider, err)
+		}
+		if provider.baseURL != tt.baseURL || provider.GetModel() != tt.expected || provider.name != tt.provider {
+			t.Errorf("%s: expected %s at %s, got %s at %s", tt.provider, tt.expected, tt.baseURL, provider.GetModel(), provider.baseURL)
+		}
+	}
+
+	if _, err := newCompatProvider(&Config{Provider: "groq", Model: "llama-3.3-70b-versatile"}); err == nil {
+		t.Error("expected an error without an API key")
+	}
+	if _, err := newCompatProvider(&Config{Provider: "deepseek", APIKey: "key"}); err == nil {
+		t.Error("expected an error without a model")
+	}
+}
+
+func TestCompatProviderHeadersAndUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("HTTP-Referer") != "https://example.com" || r.Header.Get("X-Title") != "MiniClaw" {
+			t.Errorf("expected extra headers, got %v", r.Header)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-api-key" {
+			t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+		}
+
+		var req OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			// DeepSeek-style usage without a total.
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":7,"completion_tokens":3,"prompt_cache_hit_tokens":5}}`)
+			return
+		}
+		if req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+			t.Error("expected usage to be requested for streams")
+		}
+		// Groq reports the usage of a stream under x_groq.
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}],\"x_groq\":{\"usage\":{\"prompt_tokens\":4,\"completion_tokens\":2,\"total_tokens\":6}}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider, err := newCompatProvider(&Config{
+		Provider: "groq",
+		APIKey:   "test-api-key",
+		Model:    "llama-3.3-70b-versatile",
+		BaseURL:  server.URL,
+		Headers:  map[string]string{"HTTP-Referer": "https://example.com", "X-Title": "MiniClaw"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := provider.Complete(context.Background(), &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "ok" || resp.Usage.TotalTokens != 10 {
+		t.Errorf("expected the total to be added up, got %+v", resp)
+	}
+
+	var content strings.Builder
+	err = provider.StreamComplete(context.Background(), &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+	}, func(chunk string) error {
+		content.WriteString(chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content.String() != "Hello" {
+		t.Errorf("expected 'Hello', got %s", content.String())
+	}
+	if metrics := provider.monitor.GetMetrics().ProviderMetrics["groq"]; metrics == nil || metrics.TotalTokens != 16 {
+		t.Errorf("expected stream usage to be recorded under groq, got %+v", metrics)
+	}
+}
@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultEmbeddingModel = "text-embedding-3-small"
+
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	GetModel() string
+}
+
+type EmbeddingConfig struct {
+	Provider string
+	APIKey   string
+	Model    string
+	BaseURL  string
+}
+
+func NewEmbedder(config *EmbeddingConfig) (Embedder, error) {
+	if config == nil {
+		return nil, fmt.Errorf("embedding config cannot be nil")
+	}
+
+	switch config.Provider {
+	case "", "openai":
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("API key is required for OpenAI embeddings")
+		}
+		return NewOpenAIEmbedder(config), nil
+
+	case "ollama":
+		ollamaConfig := *config
+		if ollamaConfig.BaseURL == "" {
+			ollamaConfig.BaseURL = defaultOllamaBaseURL + "/v1"
+		}
+		if ollamaConfig.Model == "" {
+			ollamaConfig.Model = "nomic-embed-text"
+		}
+		return NewOpenAIEmbedder(&ollamaConfig), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported embedding provider: %s", config.Provider)
+	}
+}
+
+type OpenAIEmbedder struct {
+	config     *EmbeddingConfig
+	httpClient *http.Client
+	baseURL    string
+	model      string
+}
+
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func NewOpenAIEmbedder(config *EmbeddingConfig) *OpenAIEmbedder {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+
+	model := config.Model
+	if model == "" {
+		model = defaultEmbeddingModel
+	}
+
+	return &OpenAIEmbedder{
+		config:     config,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		model:      model,
+	}
+}
+
+func (e *OpenAIEmbedder) GetModel() string {
+	return e.model
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	reqBody, err := json.Marshal(&openAIEmbeddingRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/embeddings", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+	}
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, HandleHTTPError(resp.StatusCode, string(body))
+	}
+
+	var embeddingResp openAIEmbeddingResponse
+	if err := json.Unmarshal(body, &embeddingResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if len(embeddingResp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddingResp.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range embeddingResp.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+
+	return vectors, nil
+}
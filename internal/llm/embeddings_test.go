@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("Expected bearer token, got %s", r.Header.Get("Authorization"))
+		}
+
+		var req openAIEmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != defaultEmbeddingModel || len(req.Input) != 2 {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+	}))
+	defer server.Close()
+
+	embedder, err := NewEmbedder(&EmbeddingConfig{Provider: "openai", APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create embedder: %v", err)
+	}
+
+	vectors, err := embedder.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("Expected embeddings in input order, got %v", vectors)
+	}
+}
+
+func TestNewEmbedderValidation(t *testing.T) {
+	if _, err := NewEmbedder(&EmbeddingConfig{Provider: "openai"}); err == nil {
+		t.Error("Expected error without API key")
+	}
+
+	if _, err := NewEmbedder(&EmbeddingConfig{Provider: "unknown"}); err == nil {
+		t.Error("Expected error for unsupported provider")
+	}
+
+	embedder, err := NewEmbedder(&EmbeddingConfig{Provider: "ollama"})
+	if err != nil || embedder.GetModel() != "nomic-embed-text" {
+		t.Errorf("Expected ollama embedder with default model, got %v", err)
+	}
+}
@@ -15,6 +15,7 @@ var (
 	ErrTimeout           = errors.New("request timeout")
 	ErrConnectionError   = errors.New("connection error")
 	ErrInvalidRequest    = errors.New("invalid request")
+	ErrBudgetExceeded    = errors.New("budget exceeded")
 )
 
 type LLMError struct {
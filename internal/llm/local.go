@@ -16,10 +16,12 @@ type LocalProvider struct {
 }
 
 type LocalModelConfig struct {
-	Enabled bool
-	Path    string
-	Type    string
-	Params  LocalModelParams
+	Enabled   bool
+	Path      string
+	Type      string
+	KeepAlive string
+	AutoPull  bool
+	Params    LocalModelParams
 }
 
 type LocalModelParams struct {
@@ -3,7 +3,6 @@ package llm
 import (
 	"context"
 	"fmt"
-	"log"
 )
 
 type Manager struct {
@@ -27,7 +26,7 @@ func NewManager(config *Config) (*Manager, error) {
 			config.Model = "claude-sonnet-4-5"
 		}
 		provider = NewAnthropicProvider(config)
-		log.Printf("Initialized Anthropic provider with model: %s", config.Model)
+		logger.Info("Initialized provider", "provider", "anthropic", "model", config.Model)
 
 	case "openai":
 		if config.APIKey == "" {
@@ -37,7 +36,22 @@ func NewManager(config *Config) (*Manager, error) {
 			config.Model = "gpt-4o"
 		}
 		provider = NewOpenAIProvider(config)
-		log.Printf("Initialized OpenAI provider with model: %s", config.Model)
+		logger.Info("Initialized provider", "provider", "openai", "model", config.Model)
+
+	case "azure":
+		if err := validateAzureConfig(config); err != nil {
+			return nil, err
+		}
+		provider = NewOpenAIProvider(config)
+		logger.Info("Initialized provider", "provider", "azure", "deployment", config.Deployment)
+
+	case "openrouter", "groq", "deepseek":
+		compat, err := newCompatProvider(config)
+		if err != nil {
+			return nil, err
+		}
+		provider = compat
+		logger.Info("Initialized provider", "provider", config.Provider, "model", config.Model)
 
 	case "local":
 		if config.LocalModel.Path == "" {
@@ -47,7 +61,11 @@ func NewManager(config *Config) (*Manager, error) {
 			config.LocalModel.Type = "llama"
 		}
 		provider = NewLocalProvider(config)
-		log.Printf("Initialized local provider with model: %s (%s)", config.LocalModel.Path, config.LocalModel.Type)
+		logger.Info("Initialized provider", "provider", "local", "path", config.LocalModel.Path, "type", config.LocalModel.Type)
+
+	case "ollama":
+		provider = NewOllamaProvider(config)
+		logger.Info("Initialized provider", "provider", "ollama", "model", config.Model)
 
 	default:
 		return nil, fmt.Errorf("unsupported LLM provider: %s", config.Provider)
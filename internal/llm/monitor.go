@@ -1,10 +1,25 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
 )
 
+// costsDir holds one JSON cost report per day.
+const costsDir = "llm/costs"
+
+func costReportPath(day string) string {
+	return path.Join(costsDir, day+".json")
+}
+
 type Metrics struct {
 	mu              sync.RWMutex
 	TotalRequests   int64
@@ -17,6 +32,8 @@ type Metrics struct {
 	LastRequestTime time.Time
 	ErrorCounts     map[string]int64
 	ProviderMetrics map[string]*ProviderMetrics
+	Failovers       int64
+	FailoverCounts  map[string]int64
 }
 
 type ProviderMetrics struct {
@@ -29,8 +46,45 @@ type ProviderMetrics struct {
 	MaxLatency     time.Duration
 }
 
+type CostStats struct {
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (s CostStats) add(usage Usage, cost float64) CostStats {
+	s.Requests++
+	s.PromptTokens += int64(usage.PromptTokens)
+	s.CompletionTokens += int64(usage.CompletionTokens)
+	s.Cost += cost
+	return s
+}
+
+type CostReport struct {
+	Day        string               `json:"day"`
+	Total      CostStats            `json:"total"`
+	ByChat     map[string]CostStats `json:"by_chat"`
+	ByProvider map[string]CostStats `json:"by_provider"`
+	ByModel    map[string]CostStats `json:"by_model"`
+}
+
+func newCostReport(day string) *CostReport {
+	return &CostReport{
+		Day:        day,
+		ByChat:     make(map[string]CostStats),
+		ByProvider: make(map[string]CostStats),
+		ByModel:    make(map[string]CostStats),
+	}
+}
+
 type Monitor struct {
 	metrics *Metrics
+	costs   map[string]*CostReport
+
+	// saveMu keeps cost reports written in the order they were recorded.
+	saveMu sync.Mutex
+	store  storage.Storage
 }
 
 func NewMonitor() *Monitor {
@@ -40,10 +94,163 @@ func NewMonitor() *Monitor {
 			MaxLatency:      0,
 			ErrorCounts:     make(map[string]int64),
 			ProviderMetrics: make(map[string]*ProviderMetrics),
+			FailoverCounts:  make(map[string]int64),
 		},
+		costs: make(map[string]*CostReport),
+	}
+}
+
+// SetCostStorage keeps the daily cost reports in store so that spending,
+// and with it the budget, survives a restart. Reports already in store are
+// loaded.
+func (m *Monitor) SetCostStorage(ctx context.Context, store storage.Storage) error {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	files, err := store.ListFiles(ctx, costsDir)
+	if err != nil {
+		return fmt.Errorf("failed to list cost reports: %w", err)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -costRetentionDays).Format("2006-01-02")
+	loaded := make(map[string]*CostReport)
+	for _, file := range files {
+		name := path.Base(strings.ReplaceAll(file, "\\", "/"))
+		day := strings.TrimSuffix(name, ".json")
+		if day == name {
+			continue
+		}
+		if day < cutoff {
+			if err := store.DeleteFile(ctx, costReportPath(day)); err != nil {
+				logger.Warn("Failed to delete old cost report", "day", day, "error", err)
+			}
+			continue
+		}
+
+		data, err := store.ReadFile(ctx, costReportPath(day))
+		if err != nil {
+			return fmt.Errorf("failed to read cost report %s: %w", day, err)
+		}
+		report := newCostReport(day)
+		if err := json.Unmarshal(data, report); err != nil {
+			logger.Warn("Skipping unreadable cost report", "day", day, "error", err)
+			continue
+		}
+		loaded[day] = report
+	}
+
+	m.metrics.mu.Lock()
+	for day, report := range loaded {
+		if _, ok := m.costs[day]; !ok {
+			m.costs[day] = report
+		}
+	}
+	m.metrics.mu.Unlock()
+
+	m.store = store
+	return nil
+}
+
+func (m *Monitor) RecordUsage(model, provider, chatID string, usage Usage, cost float64) {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	m.metrics.mu.Lock()
+	day := time.Now().Format("2006-01-02")
+	report, ok := m.costs[day]
+	var expired []string
+	if !ok {
+		report = newCostReport(day)
+		m.costs[day] = report
+
+		cutoff := time.Now().AddDate(0, 0, -costRetentionDays).Format("2006-01-02")
+		for d := range m.costs {
+			if d < cutoff {
+				delete(m.costs, d)
+				expired = append(expired, d)
+			}
+		}
+	}
+
+	report.Total = report.Total.add(usage, cost)
+	report.ByModel[model] = report.ByModel[model].add(usage, cost)
+	if provider != "" {
+		report.ByProvider[provider] = report.ByProvider[provider].add(usage, cost)
+	}
+	if chatID != "" {
+		report.ByChat[chatID] = report.ByChat[chatID].add(usage, cost)
+	}
+
+	var data []byte
+	var err error
+	if m.store != nil {
+		data, err = json.Marshal(report)
+	}
+	m.metrics.mu.Unlock()
+
+	if m.store == nil {
+		return
+	}
+	if err != nil {
+		logger.Warn("Failed to marshal cost report", "day", day, "error", err)
+		return
+	}
+
+	ctx := context.Background()
+	if err := m.store.WriteFile(ctx, costReportPath(day), data); err != nil {
+		logger.Warn("Failed to save cost report", "day", day, "error", err)
+	}
+	for _, d := range expired {
+		if err := m.store.DeleteFile(ctx, costReportPath(d)); err != nil {
+			logger.Warn("Failed to delete old cost report", "day", d, "error", err)
+		}
 	}
 }
 
+func (m *Monitor) CostReport(day string) *CostReport {
+	m.metrics.mu.RLock()
+	defer m.metrics.mu.RUnlock()
+
+	copy := newCostReport(day)
+	report, ok := m.costs[day]
+	if !ok {
+		return copy
+	}
+
+	copy.Total = report.Total
+	for k, v := range report.ByChat {
+		copy.ByChat[k] = v
+	}
+	for k, v := range report.ByProvider {
+		copy.ByProvider[k] = v
+	}
+	for k, v := range report.ByModel {
+		copy.ByModel[k] = v
+	}
+
+	return copy
+}
+
+func (m *Monitor) CostDays() []string {
+	m.metrics.mu.RLock()
+	defer m.metrics.mu.RUnlock()
+
+	days := make([]string, 0, len(m.costs))
+	for day := range m.costs {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days
+}
+
+func (m *Monitor) RecordFailover(from, to string) {
+	m.metrics.mu.Lock()
+	defer m.metrics.mu.Unlock()
+
+	m.metrics.Failovers++
+	m.metrics.FailoverCounts[from+" -> "+to]++
+}
+
 func (m *Monitor) RecordRequest(provider string, latency time.Duration, tokens int, err error) {
 	m.metrics.mu.Lock()
 	defer m.metrics.mu.Unlock()
@@ -112,12 +319,18 @@ func (m *Monitor) GetMetrics() *Metrics {
 		LastRequestTime: m.metrics.LastRequestTime,
 		ErrorCounts:     make(map[string]int64),
 		ProviderMetrics: make(map[string]*ProviderMetrics),
+		Failovers:       m.metrics.Failovers,
+		FailoverCounts:  make(map[string]int64),
 	}
 
 	for k, v := range m.metrics.ErrorCounts {
 		copy.ErrorCounts[k] = v
 	}
 
+	for k, v := range m.metrics.FailoverCounts {
+		copy.FailoverCounts[k] = v
+	}
+
 	for k, v := range m.metrics.ProviderMetrics {
 		copy.ProviderMetrics[k] = &ProviderMetrics{
 			TotalRequests:  v.TotalRequests,
@@ -167,4 +380,7 @@ func (m *Monitor) Reset() {
 	m.metrics.LastRequestTime = time.Time{}
 	m.metrics.ErrorCounts = make(map[string]int64)
 	m.metrics.ProviderMetrics = make(map[string]*ProviderMetrics)
+	m.metrics.Failovers = 0
+	m.metrics.FailoverCounts = make(map[string]int64)
+	m.costs = make(map[string]*CostReport)
 }
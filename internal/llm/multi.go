@@ -3,19 +3,37 @@ package llm
 import (
 	"context"
 	"fmt"
-	"log"
+	"strings"
 	"sync"
+
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 )
 
+var logger = logging.For("llm")
+
 type ModelConfig struct {
-	Name        string           `yaml:"name"`
-	Provider    string           `yaml:"provider"`
-	APIKey      string           `yaml:"api_key,omitempty"`
-	Model       string           `yaml:"model"`
-	BaseURL     string           `yaml:"base_url,omitempty"`
-	MaxTokens   int              `yaml:"max_tokens"`
-	Temperature float64          `yaml:"temperature"`
-	LocalModel  LocalModelConfig `yaml:"local_model,omitempty"`
+	Name           string            `yaml:"name"`
+	Provider       string            `yaml:"provider"`
+	APIKey         string            `yaml:"api_key,omitempty"`
+	Model          string            `yaml:"model"`
+	BaseURL        string            `yaml:"base_url,omitempty"`
+	Organization   string            `yaml:"organization,omitempty"`
+	Project        string            `yaml:"project,omitempty"`
+	Deployment     string            `yaml:"deployment,omitempty"`
+	APIVersion     string            `yaml:"api_version,omitempty"`
+	MaxTokens      int               `yaml:"max_tokens"`
+	ContextWindow  int               `yaml:"context_window,omitempty"`
+	ThinkingBudget int               `yaml:"thinking_budget,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty"`
+	Temperature    float64           `yaml:"temperature"`
+	LocalModel     LocalModelConfig  `yaml:"local_model,omitempty"`
+	Cost           float64           `yaml:"cost,omitempty"`
+	InputPrice     float64           `yaml:"input_price,omitempty"`
+	OutputPrice    float64           `yaml:"output_price,omitempty"`
+	RateLimit      int               `yaml:"rate_limit,omitempty"`
+	// Vision sends images to the model. Without it they are left out of
+	// requests, since text-only models reject them.
+	Vision bool `yaml:"vision,omitempty"`
 }
 
 type MultiModelManager struct {
@@ -24,6 +42,11 @@ type MultiModelManager struct {
 	models       map[string]*ModelConfig
 	currentModel string
 	defaultModel string
+	routing      *RoutingConfig
+	budget       *BudgetConfig
+	monitor      *Monitor
+	rateLimits   *rateLimiterPool
+	wrap         func(name string, provider LLMProvider) LLMProvider
 }
 
 func NewMultiModelManager(models []*ModelConfig, defaultModel string) (*MultiModelManager, error) {
@@ -32,11 +55,13 @@ func NewMultiModelManager(models []*ModelConfig, defaultModel string) (*MultiMod
 		models:       make(map[string]*ModelConfig),
 		currentModel: defaultModel,
 		defaultModel: defaultModel,
+		monitor:      NewMonitor(),
+		rateLimits:   newRateLimiterPool(),
 	}
 
 	for _, modelConfig := range models {
 		if err := mmm.AddModel(modelConfig); err != nil {
-			log.Printf("Warning: failed to add model %s: %v", modelConfig.Name, err)
+			logger.Warn("Failed to add model", "model", modelConfig.Name, "error", err)
 		}
 	}
 
@@ -56,13 +81,21 @@ func (mmm *MultiModelManager) AddModel(config *ModelConfig) error {
 	}
 
 	llmConfig := &Config{
-		Provider:    config.Provider,
-		APIKey:      config.APIKey,
-		Model:       config.Model,
-		BaseURL:     config.BaseURL,
-		MaxTokens:   config.MaxTokens,
-		Temperature: config.Temperature,
-		LocalModel:  config.LocalModel,
+		Provider:     config.Provider,
+		APIKey:       config.APIKey,
+		Model:        config.Model,
+		BaseURL:      config.BaseURL,
+		Organization: config.Organization,
+		Project:      config.Project,
+		Deployment:   config.Deployment,
+		APIVersion:   config.APIVersion,
+		MaxTokens:    config.MaxTokens,
+		Temperature:  config.Temperature,
+		LocalModel:   config.LocalModel,
+
+		ContextWindow:  config.ContextWindow,
+		ThinkingBudget: config.ThinkingBudget,
+		Headers:        config.Headers,
 	}
 
 	var provider LLMProvider
@@ -74,21 +107,38 @@ func (mmm *MultiModelManager) AddModel(config *ModelConfig) error {
 			return fmt.Errorf("API key is required for Anthropic provider")
 		}
 		provider = NewAnthropicProvider(llmConfig)
-		log.Printf("Added Anthropic model: %s (%s)", config.Name, config.Model)
+		logger.Info("Added model", "name", config.Name, "provider", "anthropic", "model", config.Model)
 
 	case "openai":
 		if config.APIKey == "" {
 			return fmt.Errorf("API key is required for OpenAI provider")
 		}
 		provider = NewOpenAIProvider(llmConfig)
-		log.Printf("Added OpenAI model: %s (%s)", config.Name, config.Model)
+		logger.Info("Added model", "name", config.Name, "provider", "openai", "model", config.Model)
+
+	case "azure":
+		if err := validateAzureConfig(llmConfig); err != nil {
+			return err
+		}
+		provider = NewOpenAIProvider(llmConfig)
+		logger.Info("Added model", "name", config.Name, "provider", "azure", "deployment", llmConfig.Deployment)
+
+	case "openrouter", "groq", "deepseek":
+		if provider, err = newCompatProvider(llmConfig); err != nil {
+			return err
+		}
+		logger.Info("Added model", "name", config.Name, "provider", config.Provider, "model", llmConfig.Model)
 
 	case "local":
 		if config.LocalModel.Path == "" {
 			return fmt.Errorf("model path is required for local provider")
 		}
 		provider = NewLocalProvider(llmConfig)
-		log.Printf("Added local model: %s (%s)", config.Name, config.LocalModel.Path)
+		logger.Info("Added model", "name", config.Name, "provider", "local", "path", config.LocalModel.Path)
+
+	case "ollama":
+		provider = NewOllamaProvider(llmConfig)
+		logger.Info("Added model", "name", config.Name, "provider", "ollama", "model", llmConfig.Model)
 
 	default:
 		return fmt.Errorf("unsupported provider: %s", config.Provider)
@@ -98,6 +148,10 @@ func (mmm *MultiModelManager) AddModel(config *ModelConfig) error {
 		return fmt.Errorf("failed to create provider: %w", err)
 	}
 
+	if limited, ok := provider.(rateLimited); ok {
+		limited.setRateLimiter(mmm.rateLimits.get(config))
+	}
+
 	mmm.providers[config.Name] = provider
 	mmm.models[config.Name] = config
 
@@ -121,7 +175,7 @@ func (mmm *MultiModelManager) RemoveModel(name string) error {
 
 	if mmm.currentModel == name {
 		mmm.currentModel = mmm.defaultModel
-		log.Printf("Switched to default model: %s", mmm.defaultModel)
+		logger.Info("Switched to default model", "model", mmm.defaultModel)
 	}
 
 	return nil
@@ -136,7 +190,7 @@ func (mmm *MultiModelManager) SwitchModel(name string) error {
 	}
 
 	mmm.currentModel = name
-	log.Printf("Switched to model: %s", name)
+	logger.Info("Switched model", "model", name)
 
 	return nil
 }
@@ -171,24 +225,86 @@ func (mmm *MultiModelManager) ListModels() []string {
 	return models
 }
 
+type modelKey struct{}
+
+func WithModel(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, modelKey{}, name)
+}
+
+func ModelFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(modelKey{}).(string)
+	return name, ok && name != ""
+}
+
 func (mmm *MultiModelManager) Complete(ctx context.Context, messages []Message) (*CompletionResponse, error) {
+	var resp *CompletionResponse
+	err := mmm.withFailover(ctx, messages, func(provider LLMProvider, req *CompletionRequest) (Usage, error) {
+		var err error
+		resp, err = provider.Complete(ctx, req)
+		if err != nil {
+			return Usage{}, err
+		}
+		return resp.Usage, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (mmm *MultiModelManager) StreamComplete(ctx context.Context, messages []Message, callback func(chunk string) error) error {
+	return mmm.withFailover(ctx, messages, func(provider LLMProvider, req *CompletionRequest) (Usage, error) {
+		var content strings.Builder
+		req.Stream = true
+		err := provider.StreamComplete(ctx, req, func(chunk string) error {
+			content.WriteString(chunk)
+			return callback(chunk)
+		})
+		if err != nil && content.Len() > 0 {
+			return Usage{}, &noFailoverError{err: err}
+		}
+
+		usage := Usage{
+			PromptTokens:     EstimateMessagesTokens(req.Messages),
+			CompletionTokens: EstimateTokens(content.String()),
+		}
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		return usage, err
+	})
+}
+
+func (mmm *MultiModelManager) prepareModel(name string, messages []Message) (LLMProvider, *CompletionRequest, error) {
 	mmm.mu.RLock()
-	provider, ok := mmm.providers[mmm.currentModel]
+	provider, ok := mmm.providers[name]
+	config := mmm.models[name]
+	wrap := mmm.wrap
 	mmm.mu.RUnlock()
 
 	if !ok {
-		return nil, fmt.Errorf("current model %s not found", mmm.currentModel)
+		return nil, nil, fmt.Errorf("model %s not found", name)
+	}
+	if wrap != nil {
+		provider = wrap(name, provider)
+	}
+	if !config.Vision {
+		messages = WithoutImages(messages)
 	}
 
-	config := mmm.models[mmm.currentModel]
-	req := &CompletionRequest{
+	return provider, &CompletionRequest{
 		Messages:    messages,
 		Model:       config.Model,
 		MaxTokens:   config.MaxTokens,
 		Temperature: config.Temperature,
-	}
+	}, nil
+}
 
-	return provider.Complete(ctx, req)
+// WrapProviders installs a decorator applied to every provider when a request
+// is made, e.g. for fault injection.
+func (mmm *MultiModelManager) WrapProviders(wrap func(name string, provider LLMProvider) LLMProvider) {
+	mmm.mu.Lock()
+	defer mmm.mu.Unlock()
+	mmm.wrap = wrap
 }
 
 func (mmm *MultiModelManager) GetProvider() string {
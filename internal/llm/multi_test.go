@@ -60,10 +60,25 @@ func TestMultiModelManagerAddModel(t *testing.T) {
 		t.Errorf("expected no error, got %v", err)
 	}
 
+	err = manager.AddModel(&ModelConfig{
+		Name:     "cheap",
+		Provider: "deepseek",
+		APIKey:   "key2",
+		Model:    "deepseek/deepseek-chat",
+	})
+
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if provider, ok := manager.providers["cheap"].(*OpenAIProvider); !ok || provider.GetModel() != "deepseek-chat" {
+		t.Errorf("expected an OpenAI-compatible provider for deepseek, got %v", manager.providers["cheap"])
+	}
+
 	modelsList := manager.ListModels()
 
-	if len(modelsList) != 2 {
-		t.Errorf("expected 2 models, got %d", len(modelsList))
+	if len(modelsList) != 3 {
+		t.Errorf("expected 3 models, got %d", len(modelsList))
 	}
 }
 
@@ -0,0 +1,327 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOllamaBaseURL = "http://localhost:11434"
+	defaultOllamaModel   = "llama3.2"
+)
+
+type OllamaProvider struct {
+	config     *Config
+	httpClient *http.Client
+	baseURL    string
+	monitor    *Monitor
+	pullMu     sync.Mutex
+}
+
+type OllamaModel struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Digest     string    `json:"digest"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+type OllamaPullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model     string                 `json:"model"`
+	Messages  []OpenAIMessage        `json:"messages"`
+	Stream    bool                   `json:"stream"`
+	KeepAlive string                 `json:"keep_alive,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error,omitempty"`
+}
+
+func NewOllamaProvider(config *Config) *OllamaProvider {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+
+	if config.Model == "" {
+		config.Model = defaultOllamaModel
+	}
+
+	return &OllamaProvider{
+		config: config,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConns:        10,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		baseURL: strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1"),
+		monitor: NewMonitor(),
+	}
+}
+
+func (p *OllamaProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	startTime := time.Now()
+
+	var resp *CompletionResponse
+	err := p.withModel(ctx, func() error {
+		var err error
+		resp, err = p.chat(ctx, req, nil)
+		return err
+	})
+
+	if err != nil {
+		p.monitor.RecordRequest("ollama", time.Since(startTime), 0, err)
+		return nil, err
+	}
+
+	p.monitor.RecordRequest("ollama", time.Since(startTime), resp.Usage.TotalTokens, nil)
+	return resp, nil
+}
+
+func (p *OllamaProvider) StreamComplete(ctx context.Context, req *CompletionRequest, callback func(chunk string) error) error {
+	return p.withModel(ctx, func() error {
+		_, err := p.chat(ctx, req, callback)
+		return err
+	})
+}
+
+func (p *OllamaProvider) GetModel() string {
+	return p.config.Model
+}
+
+func (p *OllamaProvider) withModel(ctx context.Context, call func() error) error {
+	err := call()
+	if err == nil || !errors.Is(err, ErrInvalidModel) || !p.config.LocalModel.AutoPull {
+		return err
+	}
+
+	logger.Info("Ollama model not found locally, pulling it", "model", p.config.Model)
+	if err := p.PullModel(ctx, p.config.Model, nil); err != nil {
+		return err
+	}
+
+	return call()
+}
+
+func (p *OllamaProvider) chat(ctx context.Context, req *CompletionRequest, callback func(chunk string) error) (*CompletionResponse, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = p.config.MaxTokens
+	}
+
+	chatReq := &ollamaChatRequest{
+		Model:     p.config.Model,
+		Messages:  make([]OpenAIMessage, 0, len(req.Messages)),
+		Stream:    callback != nil,
+		KeepAlive: p.config.LocalModel.KeepAlive,
+		Options:   make(map[string]interface{}),
+	}
+
+	for _, msg := range req.Messages {
+		chatReq.Messages = append(chatReq.Messages, OpenAIMessage{
+			Role:    string(msg.Role),
+			Content: msg.Content,
+		})
+	}
+
+	if maxTokens > 0 {
+		chatReq.Options["num_predict"] = maxTokens
+	}
+	if p.config.Temperature > 0 {
+		chatReq.Options["temperature"] = p.config.Temperature
+	}
+	if p.config.ContextWindow > 0 {
+		chatReq.Options["num_ctx"] = p.config.ContextWindow
+	}
+
+	resp, err := p.post(ctx, "/api/chat", chatReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	result := &CompletionResponse{}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk ollamaChatResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		if chunk.Error != "" {
+			return nil, NewLLMError("SERVER_ERROR", chunk.Error, ErrServerUnavailable)
+		}
+
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			if callback != nil {
+				if err := callback(chunk.Message.Content); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if chunk.Done {
+			result.Usage = Usage{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+				TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+			}
+			break
+		}
+	}
+
+	result.Content = content.String()
+	return result, nil
+}
+
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]OllamaModel, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, NewLLMError("CONNECTION_ERROR", fmt.Sprintf("failed to reach Ollama at %s: %v", p.baseURL, err), ErrConnectionError)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, HandleHTTPError(resp.StatusCode, string(body))
+	}
+
+	var tags struct {
+		Models []OllamaModel `json:"models"`
+	}
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return tags.Models, nil
+}
+
+func (p *OllamaProvider) PullModel(ctx context.Context, name string, progress func(OllamaPullProgress)) error {
+	p.pullMu.Lock()
+	defer p.pullMu.Unlock()
+
+	resp, err := p.post(ctx, "/api/pull", map[string]interface{}{
+		"model":  name,
+		"stream": true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pull model %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var update OllamaPullProgress
+		if err := decoder.Decode(&update); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode pull progress: %w", err)
+		}
+
+		if update.Error != "" {
+			return fmt.Errorf("failed to pull model %s: %s", name, update.Error)
+		}
+
+		if progress != nil {
+			progress(update)
+		}
+
+		if update.Status == "success" {
+			logger.Info("Pulled Ollama model", "model", name)
+			return nil
+		}
+	}
+}
+
+func (p *OllamaProvider) DeleteModel(ctx context.Context, name string) error {
+	reqBody, err := json.Marshal(map[string]string{"model": name})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", p.baseURL+"/api/delete", bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return NewLLMError("CONNECTION_ERROR", fmt.Sprintf("failed to reach Ollama at %s: %v", p.baseURL, err), ErrConnectionError)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return HandleHTTPError(resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+func (p *OllamaProvider) post(ctx context.Context, path string, payload interface{}) (*http.Response, error) {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, NewLLMError("CONNECTION_ERROR", fmt.Sprintf("failed to reach Ollama at %s: %v", p.baseURL, err), ErrConnectionError)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, HandleHTTPError(resp.StatusCode, string(body))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeOllama struct {
+	mu       sync.Mutex
+	models   map[string]bool
+	requests []ollamaChatRequest
+	pulls    int
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/tags":
+		fmt.Fprint(w, `{"models":[`)
+		first := true
+		for name := range f.models {
+			if !first {
+				fmt.Fprint(w, ",")
+			}
+			first = false
+			fmt.Fprintf(w, `{"name":%q,"size":42}`, name)
+		}
+		fmt.Fprint(w, `]}`)
+
+	case "/api/pull":
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.pulls++
+		f.models[req["model"].(string)] = true
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"downloading","total":100,"completed":100}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+
+	case "/api/chat":
+		var req ollamaChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.requests = append(f.requests, req)
+
+		if !f.models[req.Model] {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"error":"model '%s' not found"}`, req.Model)
+			return
+		}
+
+		if req.Stream {
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Hel"},"done":false}`)
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":"lo"},"done":false}`)
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":5,"eval_count":2}`)
+			return
+		}
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":"Hello"},"done":true,"prompt_eval_count":5,"eval_count":2}`)
+	}
+}
+
+func TestOllamaProviderComplete(t *testing.T) {
+	fake := &fakeOllama{models: map[string]bool{"llama3.2": true}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	provider := NewOllamaProvider(&Config{
+		Provider:      "ollama",
+		BaseURL:       server.URL + "/v1",
+		MaxTokens:     128,
+		ContextWindow: 8192,
+		LocalModel:    LocalModelConfig{KeepAlive: "10m"},
+	})
+
+	if provider.GetModel() != defaultOllamaModel {
+		t.Errorf("Expected default model, got %s", provider.GetModel())
+	}
+
+	resp, err := provider.Complete(context.Background(), &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to complete: %v", err)
+	}
+
+	if resp.Content != "Hello" || resp.Usage.TotalTokens != 7 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	req := fake.requests[0]
+	if req.KeepAlive != "10m" || req.Stream {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+	if req.Options["num_ctx"] != float64(8192) || req.Options["num_predict"] != float64(128) {
+		t.Errorf("Expected options to be forwarded, got %v", req.Options)
+	}
+}
+
+func TestOllamaProviderStream(t *testing.T) {
+	fake := &fakeOllama{models: map[string]bool{"qwen": true}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	provider := NewOllamaProvider(&Config{Provider: "ollama", Model: "qwen", BaseURL: server.URL})
+
+	var chunks []string
+	err := provider.StreamComplete(context.Background(), &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "Hi"}},
+	}, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream: %v", err)
+	}
+
+	if strings.Join(chunks, "|") != "Hel|lo" {
+		t.Errorf("Unexpected chunks: %v", chunks)
+	}
+}
+
+func TestOllamaProviderAutoPull(t *testing.T) {
+	fake := &fakeOllama{models: map[string]bool{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := context.Background()
+	request := &CompletionRequest{Messages: []Message{{Role: RoleUser, Content: "Hi"}}}
+
+	provider := NewOllamaProvider(&Config{Provider: "ollama", Model: "phi3", BaseURL: server.URL})
+	if _, err := provider.Complete(ctx, request); err == nil {
+		t.Fatal("Expected missing model error without auto pull")
+	}
+
+	provider = NewOllamaProvider(&Config{Provider: "ollama", Model: "phi3", BaseURL: server.URL, LocalModel: LocalModelConfig{AutoPull: true}})
+	resp, err := provider.Complete(ctx, request)
+	if err != nil {
+		t.Fatalf("Expected model to be pulled, got %v", err)
+	}
+	if resp.Content != "Hello" || fake.pulls != 1 {
+		t.Errorf("Unexpected response %+v after %d pulls", resp, fake.pulls)
+	}
+
+	models, err := provider.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list models: %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "phi3" {
+		t.Errorf("Expected pulled model to be listed, got %+v", models)
+	}
+}
+
+func TestMultiModelManagerOllama(t *testing.T) {
+	manager, err := NewMultiModelManager([]*ModelConfig{
+		{Name: "local", Provider: "ollama", Model: "llama3.2"},
+	}, "local")
+	if err != nil {
+		t.Fatalf("Expected ollama model without API key, got %v", err)
+	}
+
+	if manager.GetProvider() != "ollama" {
+		t.Errorf("Expected ollama provider, got %s", manager.GetProvider())
+	}
+}
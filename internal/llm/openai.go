@@ -7,21 +7,55 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
 
+const defaultAzureAPIVersion = "2024-06-01"
+
 type OpenAIProvider struct {
+	name        string
 	config      *Config
 	httpClient  *http.Client
 	baseURL     string
+	azure       bool
 	rateLimiter *RateLimiter
 	monitor     *Monitor
 }
 
 type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is a string, or a list of OpenAIContentPart for messages
+	// with images.
+	Content interface{} `json:"content"`
+}
+
+type OpenAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *OpenAIImageURL `json:"image_url,omitempty"`
+}
+
+// OpenAIImageURL points to an image, which may be embedded as a data URL.
+type OpenAIImageURL struct {
+	URL string `json:"url"`
+}
+
+func newOpenAIMessage(msg Message) OpenAIMessage {
+	if len(msg.Parts) == 0 {
+		return OpenAIMessage{Role: string(msg.Role), Content: msg.Content}
+	}
+
+	parts := make([]OpenAIContentPart, 0, len(msg.Parts)+1)
+	for _, part := range msg.ContentParts() {
+		if part.Type == ContentImage {
+			parts = append(parts, OpenAIContentPart{Type: "image_url", ImageURL: &OpenAIImageURL{URL: part.URL()}})
+		} else {
+			parts = append(parts, OpenAIContentPart{Type: "text", Text: part.Text})
+		}
+	}
+	return OpenAIMessage{Role: string(msg.Role), Content: parts}
 }
 
 type OpenAIRequest struct {
@@ -30,6 +64,46 @@ type OpenAIRequest struct {
 	MaxTokens   int             `json:"max_tokens,omitempty"`
 	Temperature float64         `json:"temperature,omitempty"`
 	Stream      bool            `json:"stream,omitempty"`
+
+	StreamOptions *OpenAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+// OpenAIStreamOptions asks for token usage in the last chunk of a stream.
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// usage converts u, adding up the total for gateways that leave it out.
+func (u *OpenAIUsage) usage() Usage {
+	total := u.TotalTokens
+	if total == 0 {
+		total = u.PromptTokens + u.CompletionTokens
+	}
+	return Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      total,
+	}
+}
+
+// openAIStreamChunk is one chunk of a streamed response. Groq reports the
+// usage of a stream under x_groq instead of usage.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *OpenAIUsage `json:"usage"`
+	XGroq *struct {
+		Usage *OpenAIUsage `json:"usage"`
+	} `json:"x_groq"`
 }
 
 type OpenAIResponse struct {
@@ -45,11 +119,7 @@ type OpenAIResponse struct {
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage OpenAIUsage `json:"usage"`
 }
 
 func NewOpenAIProvider(config *Config) *OpenAIProvider {
@@ -58,7 +128,13 @@ func NewOpenAIProvider(config *Config) *OpenAIProvider {
 		baseURL = "https://api.openai.com/v1"
 	}
 
+	name := config.Provider
+	if name == "" {
+		name = "openai"
+	}
+
 	return &OpenAIProvider{
+		name:   name,
 		config: config,
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
@@ -68,14 +144,17 @@ func NewOpenAIProvider(config *Config) *OpenAIProvider {
 				IdleConnTimeout:     90 * time.Second,
 			},
 		},
-		baseURL:     baseURL,
-		rateLimiter: NewRateLimiter(60, time.Minute),
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		azure:       config.Provider == "azure",
+		rateLimiter: NewRateLimiter(defaultOpenAIRateLimit, time.Minute),
 		monitor:     NewMonitor(),
 	}
 }
 
 func (p *OpenAIProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
-	p.rateLimiter.Wait()
+	if err := p.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 
 	startTime := time.Now()
 	var lastErr error
@@ -85,7 +164,7 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req *CompletionRequest) (
 		if attempt > 0 {
 			select {
 			case <-ctx.Done():
-				p.monitor.RecordRequest("openai", time.Since(startTime), 0, ctx.Err())
+				p.monitor.RecordRequest(p.name, time.Since(startTime), 0, ctx.Err())
 				return nil, ctx.Err()
 			case <-time.After(time.Duration(attempt) * time.Second):
 			}
@@ -93,7 +172,7 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req *CompletionRequest) (
 
 		resp, err := p.doRequest(ctx, req)
 		if err == nil {
-			p.monitor.RecordRequest("openai", time.Since(startTime), resp.Usage.TotalTokens, nil)
+			p.monitor.RecordRequest(p.name, time.Since(startTime), resp.Usage.TotalTokens, nil)
 			return resp, nil
 		}
 
@@ -106,7 +185,7 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req *CompletionRequest) (
 		break
 	}
 
-	p.monitor.RecordRequest("openai", time.Since(startTime), 0, lastErr)
+	p.monitor.RecordRequest(p.name, time.Since(startTime), 0, lastErr)
 	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
 }
 
@@ -124,10 +203,7 @@ func (p *OpenAIProvider) doRequest(ctx context.Context, req *CompletionRequest)
 	}
 
 	for _, msg := range req.Messages {
-		openAIReq.Messages = append(openAIReq.Messages, OpenAIMessage{
-			Role:    string(msg.Role),
-			Content: msg.Content,
-		})
+		openAIReq.Messages = append(openAIReq.Messages, newOpenAIMessage(msg))
 	}
 
 	reqBody, err := json.Marshal(openAIReq)
@@ -135,14 +211,12 @@ func (p *OpenAIProvider) doRequest(ctx context.Context, req *CompletionRequest)
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/chat/completions", p.baseURL)
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.completionsURL(), bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
+	p.setHeaders(httpReq)
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
@@ -171,17 +245,22 @@ func (p *OpenAIProvider) doRequest(ctx context.Context, req *CompletionRequest)
 
 	return &CompletionResponse{
 		Content: content,
-		Usage: Usage{
-			PromptTokens:     openAIResp.Usage.PromptTokens,
-			CompletionTokens: openAIResp.Usage.CompletionTokens,
-			TotalTokens:      openAIResp.Usage.TotalTokens,
-		},
+		Usage:   openAIResp.Usage.usage(),
 	}, nil
 }
 
 func (p *OpenAIProvider) StreamComplete(ctx context.Context, req *CompletionRequest, callback func(chunk string) error) error {
-	p.rateLimiter.Wait()
+	if err := p.rateLimiter.Wait(ctx); err != nil {
+		return err
+	}
 
+	startTime := time.Now()
+	usage, err := p.doStream(ctx, req, callback)
+	p.monitor.RecordRequest(p.name, time.Since(startTime), usage.TotalTokens, err)
+	return err
+}
+
+func (p *OpenAIProvider) doStream(ctx context.Context, req *CompletionRequest, callback func(chunk string) error) (Usage, error) {
 	if req.MaxTokens == 0 {
 		req.MaxTokens = p.config.MaxTokens
 	}
@@ -193,39 +272,39 @@ func (p *OpenAIProvider) StreamComplete(ctx context.Context, req *CompletionRequ
 		Temperature: p.config.Temperature,
 		Stream:      true,
 	}
+	// Older Azure API versions reject stream_options.
+	if !p.azure {
+		openAIReq.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
+	}
 
 	for _, msg := range req.Messages {
-		openAIReq.Messages = append(openAIReq.Messages, OpenAIMessage{
-			Role:    string(msg.Role),
-			Content: msg.Content,
-		})
+		openAIReq.Messages = append(openAIReq.Messages, newOpenAIMessage(msg))
 	}
 
 	reqBody, err := json.Marshal(openAIReq)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return Usage{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/chat/completions", p.baseURL)
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.completionsURL(), bytes.NewReader(reqBody))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return Usage{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
+	p.setHeaders(httpReq)
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return Usage{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return HandleHTTPError(resp.StatusCode, string(body))
+		return Usage{}, HandleHTTPError(resp.StatusCode, string(body))
 	}
 
+	var usage Usage
 	scanner := newLineScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -235,28 +314,82 @@ func (p *OpenAIProvider) StreamComplete(ctx context.Context, req *CompletionRequ
 
 		if strings.HasPrefix(line, "data: ") {
 			data := strings.TrimPrefix(line, "data: ")
-			var event map[string]interface{}
-			if err := json.Unmarshal([]byte(data), &event); err != nil {
+			var chunk openAIStreamChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 				continue
 			}
 
-			if choices, ok := event["choices"].([]interface{}); ok && len(choices) > 0 {
-				if choice, ok := choices[0].(map[string]interface{}); ok {
-					if delta, ok := choice["delta"].(map[string]interface{}); ok {
-						if content, ok := delta["content"].(string); ok {
-							if err := callback(content); err != nil {
-								return err
-							}
-						}
-					}
+			if chunk.Usage != nil {
+				usage = chunk.Usage.usage()
+			} else if chunk.XGroq != nil && chunk.XGroq.Usage != nil {
+				usage = chunk.XGroq.Usage.usage()
+			}
+
+			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+				if err := callback(chunk.Choices[0].Delta.Content); err != nil {
+					return usage, err
 				}
 			}
 		}
 	}
 
+	return usage, nil
+}
+
+func (p *OpenAIProvider) completionsURL() string {
+	if !p.azure {
+		return fmt.Sprintf("%s/chat/completions", p.baseURL)
+	}
+
+	deployment := p.config.Deployment
+	if deployment == "" {
+		deployment = p.config.Model
+	}
+
+	apiVersion := p.config.APIVersion
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		p.baseURL, url.PathEscape(deployment), url.QueryEscape(apiVersion))
+}
+
+func (p *OpenAIProvider) setHeaders(httpReq *http.Request) {
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	if p.azure {
+		httpReq.Header.Set("api-key", p.config.APIKey)
+	} else {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
+		if p.config.Organization != "" {
+			httpReq.Header.Set("OpenAI-Organization", p.config.Organization)
+		}
+		if p.config.Project != "" {
+			httpReq.Header.Set("OpenAI-Project", p.config.Project)
+		}
+	}
+
+	setExtraHeaders(httpReq, p.config.Headers)
+}
+
+func validateAzureConfig(config *Config) error {
+	if config.APIKey == "" {
+		return fmt.Errorf("API key is required for Azure OpenAI provider")
+	}
+	if config.BaseURL == "" {
+		return fmt.Errorf("base URL (resource endpoint) is required for Azure OpenAI provider")
+	}
+	if config.Deployment == "" && config.Model == "" {
+		return fmt.Errorf("deployment name is required for Azure OpenAI provider")
+	}
 	return nil
 }
 
 func (p *OpenAIProvider) GetModel() string {
 	return p.config.Model
 }
+
+func (p *OpenAIProvider) setRateLimiter(limiter *RateLimiter) {
+	p.rateLimiter = limiter
+}
@@ -1,6 +1,11 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -37,3 +42,135 @@ func TestOpenAIProviderGetModel(t *testing.T) {
 		t.Errorf("expected 'gpt-4o', got %s", model)
 	}
 }
+
+func TestOpenAIProviderOrganizationHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-api-key" {
+			t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("OpenAI-Organization") != "org-123" {
+			t.Errorf("expected organization header, got %s", r.Header.Get("OpenAI-Organization"))
+		}
+		if r.Header.Get("OpenAI-Project") != "proj-456" {
+			t.Errorf("expected project header, got %s", r.Header.Get("OpenAI-Project"))
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&Config{
+		Provider:     "openai",
+		APIKey:       "test-api-key",
+		Model:        "gpt-4o",
+		BaseURL:      server.URL + "/v1/",
+		Organization: "org-123",
+		Project:      "proj-456",
+	})
+
+	resp, err := provider.Complete(context.Background(), &CompletionRequest{
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if resp.Content != "ok" {
+		t.Errorf("expected 'ok', got %s", resp.Content)
+	}
+}
+
+func TestOpenAIProviderAzure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/my-gpt4/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("api-version") != "2024-02-01" {
+			t.Errorf("unexpected api-version %s", r.URL.Query().Get("api-version"))
+		}
+		if r.Header.Get("api-key") != "azure-key" {
+			t.Errorf("expected api-key header, got %s", r.Header.Get("api-key"))
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no authorization header, got %s", r.Header.Get("Authorization"))
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"azure"}}]}`)
+	}))
+	defer server.Close()
+
+	manager, err := NewManager(&Config{
+		Provider:   "azure",
+		APIKey:     "azure-key",
+		BaseURL:    server.URL,
+		Deployment: "my-gpt4",
+		APIVersion: "2024-02-01",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	resp, err := manager.Complete(context.Background(), []Message{{Role: RoleUser, Content: "hi"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if resp.Content != "azure" {
+		t.Errorf("expected 'azure', got %s", resp.Content)
+	}
+}
+
+func TestValidateAzureConfig(t *testing.T) {
+	if err := validateAzureConfig(&Config{APIKey: "key", Deployment: "gpt"}); err == nil {
+		t.Error("expected error for missing endpoint")
+	}
+
+	if err := validateAzureConfig(&Config{APIKey: "key", BaseURL: "https://example.openai.azure.com"}); err == nil {
+		t.Error("expected error for missing deployment")
+	}
+
+	if err := validateAzureConfig(&Config{APIKey: "key", BaseURL: "https://example.openai.azure.com", Model: "gpt-4o"}); err != nil {
+		t.Errorf("expected model to be used as deployment, got %v", err)
+	}
+}
+
+func TestOpenAIProviderImages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string          `json:"role"`
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if len(req.Messages) != 2 || string(req.Messages[0].Content) != `"Be brief."` {
+			t.Errorf("expected text messages to keep string content, got %+v", req.Messages)
+		}
+		want := `[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBO"}},{"type":"image_url","image_url":{"url":"https://example.com/cat.jpg"}}]`
+		if string(req.Messages[1].Content) != want {
+			t.Errorf("unexpected image content:\n%s\nwant\n%s", req.Messages[1].Content, want)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"A cat."}}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&Config{APIKey: "test-api-key", Model: "gpt-4o", BaseURL: server.URL})
+
+	resp, err := provider.Complete(context.Background(), &CompletionRequest{
+		Messages: []Message{
+			{Role: RoleSystem, Content: "Be brief."},
+			{Role: RoleUser, Content: "What is this?", Parts: []ContentPart{
+				ImageDataPart("image/png", []byte{0x89, 'P', 'N'}),
+				ImageURLPart("https://example.com/cat.jpg"),
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.Content != "A cat." {
+		t.Errorf("expected 'A cat.', got %s", resp.Content)
+	}
+}
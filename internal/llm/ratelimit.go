@@ -1,58 +1,240 @@
 package llm
 
 import (
+	"context"
+	"strings"
 	"sync"
 	"time"
 )
 
+type Priority int
+
+const (
+	PriorityBackground  Priority = 0
+	PriorityInteractive Priority = 10
+)
+
+const (
+	defaultAnthropicRateLimit = 50
+	defaultOpenAIRateLimit    = 60
+	maxRateLimitPoll          = 100 * time.Millisecond
+)
+
+type priorityKey struct{}
+
+// WithPriority marks requests made with ctx. Requests without a priority are
+// treated as interactive.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityInteractive
+}
+
+type RateLimitConfig struct {
+	RequestsPerMinute int
+	// BackgroundShare caps the fraction of the window that background
+	// requests may use, keeping headroom for interactive chats.
+	BackgroundShare float64
+}
+
 type RateLimiter struct {
-	mu           sync.Mutex
-	requests     []time.Time
-	maxRequests  int
-	timeWindow   time.Duration
+	mu              sync.Mutex
+	requests        []time.Time
+	maxRequests     int
+	backgroundLimit int
+	timeWindow      time.Duration
+	waiting         map[Priority]int
 }
 
 func NewRateLimiter(maxRequests int, timeWindow time.Duration) *RateLimiter {
+	return NewPriorityRateLimiter(maxRequests, timeWindow, 1)
+}
+
+func NewPriorityRateLimiter(maxRequests int, timeWindow time.Duration, backgroundShare float64) *RateLimiter {
+	if backgroundShare <= 0 || backgroundShare > 1 {
+		backgroundShare = 1
+	}
+
+	backgroundLimit := int(float64(maxRequests) * backgroundShare)
+	if backgroundLimit < 1 {
+		backgroundLimit = 1
+	}
+
 	return &RateLimiter{
-		requests:   make([]time.Time, 0, maxRequests),
-		maxRequests: maxRequests,
-		timeWindow: timeWindow,
+		requests:        make([]time.Time, 0, maxRequests),
+		maxRequests:     maxRequests,
+		backgroundLimit: backgroundLimit,
+		timeWindow:      timeWindow,
+		waiting:         make(map[Priority]int),
 	}
 }
 
 func (r *RateLimiter) Allow() bool {
+	_, ok := r.Reserve()
+	return ok
+}
+
+// Reserve records a request if one is available and otherwise returns how
+// long until one is, without waiting.
+func (r *RateLimiter) Reserve() (time.Duration, bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	now := time.Now()
+	return r.tryAcquire(PriorityInteractive, time.Now())
+}
+
+// Wait blocks until the request may proceed. While a higher priority request
+// is waiting, lower priority requests are held back even if capacity frees up.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	priority := PriorityFromContext(ctx)
+
+	r.mu.Lock()
+	r.waiting[priority]++
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.waiting[priority]--
+		r.mu.Unlock()
+	}()
+
+	for {
+		r.mu.Lock()
+		delay, ok := r.tryAcquire(priority, time.Now())
+		r.mu.Unlock()
+
+		if ok {
+			return nil
+		}
+
+		if delay <= 0 || delay > maxRateLimitPoll {
+			delay = maxRateLimitPoll
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *RateLimiter) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = make([]time.Time, 0, r.maxRequests)
+}
 
+// tryAcquire records a request if one is available for priority and otherwise
+// returns how long until the oldest request leaves the window.
+func (r *RateLimiter) tryAcquire(priority Priority, now time.Time) (time.Duration, bool) {
 	cutoff := now.Add(-r.timeWindow)
 
-	validRequests := make([]time.Time, 0, r.maxRequests)
+	valid := r.requests[:0]
 	for _, req := range r.requests {
 		if req.After(cutoff) {
-			validRequests = append(validRequests, req)
+			valid = append(valid, req)
 		}
 	}
+	r.requests = valid
 
-	r.requests = validRequests
+	limit := r.maxRequests
+	if priority < PriorityInteractive {
+		limit = r.backgroundLimit
+	}
+
+	for waiting, count := range r.waiting {
+		if waiting > priority && count > 0 {
+			return 0, false
+		}
+	}
 
-	if len(r.requests) >= r.maxRequests {
-		return false
+	if len(r.requests) >= limit {
+		return r.requests[0].Add(r.timeWindow).Sub(now), false
 	}
 
 	r.requests = append(r.requests, now)
-	return true
+	return 0, true
 }
 
-func (r *RateLimiter) Wait() {
-	for !r.Allow() {
-		time.Sleep(100 * time.Millisecond)
+type rateLimited interface {
+	setRateLimiter(limiter *RateLimiter)
+}
+
+// SetRateLimits configures requests per minute per provider name. Models of
+// the same provider, base URL and API key share one limiter unless the model
+// sets its own RateLimit.
+func (mmm *MultiModelManager) SetRateLimits(limits map[string]RateLimitConfig) {
+	mmm.mu.Lock()
+	defer mmm.mu.Unlock()
+
+	mmm.rateLimits.setLimits(limits)
+	for name, provider := range mmm.providers {
+		if limited, ok := provider.(rateLimited); ok {
+			limited.setRateLimiter(mmm.rateLimits.get(mmm.models[name]))
+		}
 	}
 }
 
-func (r *RateLimiter) Reset() {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.requests = make([]time.Time, 0, r.maxRequests)
-}
\ No newline at end of file
+// rateLimiterPool hands out one limiter per provider account so that several
+// models configured against the same key share a single budget.
+type rateLimiterPool struct {
+	mu       sync.Mutex
+	limits   map[string]RateLimitConfig
+	limiters map[string]*RateLimiter
+}
+
+func newRateLimiterPool() *rateLimiterPool {
+	return &rateLimiterPool{
+		limits:   make(map[string]RateLimitConfig),
+		limiters: make(map[string]*RateLimiter),
+	}
+}
+
+func (p *rateLimiterPool) setLimits(limits map[string]RateLimitConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.limits = make(map[string]RateLimitConfig, len(limits))
+	for provider, limit := range limits {
+		p.limits[strings.ToLower(provider)] = limit
+	}
+	p.limiters = make(map[string]*RateLimiter)
+}
+
+func (p *rateLimiterPool) get(config *ModelConfig) *RateLimiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	limit, ok := p.limits[strings.ToLower(config.Provider)]
+	if !ok || limit.RequestsPerMinute <= 0 {
+		limit.RequestsPerMinute = defaultRateLimit(config.Provider)
+	}
+
+	key := strings.Join([]string{config.Provider, config.BaseURL, config.APIKey}, "\x00")
+	if config.RateLimit > 0 {
+		limit.RequestsPerMinute = config.RateLimit
+		key += "\x00" + config.Name
+	}
+
+	limiter, ok := p.limiters[key]
+	if !ok {
+		limiter = NewPriorityRateLimiter(limit.RequestsPerMinute, time.Minute, limit.BackgroundShare)
+		p.limiters[key] = limiter
+	}
+	return limiter
+}
+
+func defaultRateLimit(provider string) int {
+	if provider == "anthropic" {
+		return defaultAnthropicRateLimit
+	}
+	return defaultOpenAIRateLimit
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBackgroundShare(t *testing.T) {
+	limiter := NewPriorityRateLimiter(4, time.Minute, 0.5)
+	background := WithPriority(context.Background(), PriorityBackground)
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(background); err != nil {
+			t.Fatalf("Expected background request %d to pass, got %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(background, 50*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected background request beyond its share to wait, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if !limiter.Allow() {
+			t.Errorf("Expected interactive request %d to use the reserved capacity", i)
+		}
+	}
+	if limiter.Allow() {
+		t.Error("Expected limiter to be exhausted")
+	}
+}
+
+func TestRateLimiterInteractivePreemptsBackground(t *testing.T) {
+	limiter := NewRateLimiter(1, 150*time.Millisecond)
+	if !limiter.Allow() {
+		t.Fatal("Expected first request to pass")
+	}
+
+	order := make(chan Priority, 2)
+	wait := func(priority Priority) {
+		if err := limiter.Wait(WithPriority(context.Background(), priority)); err == nil {
+			order <- priority
+		}
+	}
+
+	go wait(PriorityBackground)
+	time.Sleep(30 * time.Millisecond)
+	go wait(PriorityInteractive)
+
+	select {
+	case first := <-order:
+		if first != PriorityInteractive {
+			t.Errorf("Expected interactive request to go first, got %v", first)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a request to be admitted")
+	}
+
+	select {
+	case second := <-order:
+		if second != PriorityBackground {
+			t.Errorf("Expected background request second, got %v", second)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected background request to be admitted after the window")
+	}
+}
+
+func TestRateLimitsSharedAcrossModels(t *testing.T) {
+	manager, err := NewMultiModelManager([]*ModelConfig{
+		{Name: "fast", Provider: "openai", APIKey: "key-1", Model: "gpt-4o-mini"},
+		{Name: "smart", Provider: "openai", APIKey: "key-1", Model: "gpt-4o"},
+		{Name: "other", Provider: "openai", APIKey: "key-2", Model: "gpt-4o"},
+		{Name: "batch", Provider: "openai", APIKey: "key-1", Model: "gpt-4o", RateLimit: 5},
+	}, "fast")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	limiter := func(name string) *RateLimiter {
+		return manager.providers[name].(*OpenAIProvider).rateLimiter
+	}
+
+	if limiter("fast") != limiter("smart") {
+		t.Error("Expected models sharing a key to share a limiter")
+	}
+	if limiter("fast") == limiter("other") || limiter("fast") == limiter("batch") {
+		t.Error("Expected separate limiters for other keys and model overrides")
+	}
+	if limiter("fast").maxRequests != defaultOpenAIRateLimit || limiter("batch").maxRequests != 5 {
+		t.Errorf("Unexpected limits %d and %d", limiter("fast").maxRequests, limiter("batch").maxRequests)
+	}
+
+	manager.SetRateLimits(map[string]RateLimitConfig{"OpenAI": {RequestsPerMinute: 500, BackgroundShare: 0.2}})
+
+	if limiter("fast") != limiter("smart") || limiter("fast").maxRequests != 500 || limiter("fast").backgroundLimit != 100 {
+		t.Errorf("Expected configured limit to apply to shared limiter, got %+v", limiter("fast"))
+	}
+	if limiter("batch").maxRequests != 5 {
+		t.Errorf("Expected model override to win, got %d", limiter("batch").maxRequests)
+	}
+}
@@ -0,0 +1,174 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/tracing"
+)
+
+const (
+	RoutingNone     = ""
+	RoutingFailover = "failover"
+	RoutingCheapest = "cheapest"
+	RoutingLatency  = "latency"
+)
+
+type RoutingConfig struct {
+	Policy string
+	Models []string
+}
+
+func (mmm *MultiModelManager) SetRouting(config *RoutingConfig) error {
+	if config == nil {
+		config = &RoutingConfig{}
+	}
+
+	switch config.Policy {
+	case RoutingNone, RoutingFailover, RoutingCheapest, RoutingLatency:
+	default:
+		return fmt.Errorf("unsupported routing policy: %s", config.Policy)
+	}
+
+	mmm.mu.Lock()
+	defer mmm.mu.Unlock()
+
+	for _, name := range config.Models {
+		if _, ok := mmm.providers[name]; !ok {
+			return fmt.Errorf("routing model %s not found", name)
+		}
+	}
+
+	mmm.routing = config
+	return nil
+}
+
+func (mmm *MultiModelManager) Monitor() *Monitor {
+	return mmm.monitor
+}
+
+func (mmm *MultiModelManager) route(ctx context.Context) []string {
+	mmm.mu.RLock()
+	defer mmm.mu.RUnlock()
+
+	requested := mmm.currentModel
+	override, pinned := ModelFromContext(ctx)
+	if pinned {
+		requested = override
+	}
+
+	if mmm.routing == nil || mmm.routing.Policy == RoutingNone {
+		return []string{requested}
+	}
+
+	pool := make([]string, 0, len(mmm.models))
+	for _, name := range mmm.routing.Models {
+		if _, ok := mmm.providers[name]; ok {
+			pool = append(pool, name)
+		}
+	}
+	if len(pool) == 0 {
+		for name := range mmm.models {
+			pool = append(pool, name)
+		}
+		sort.Strings(pool)
+	}
+
+	switch mmm.routing.Policy {
+	case RoutingCheapest:
+		sort.SliceStable(pool, func(i, j int) bool {
+			return mmm.models[pool[i]].unitPrice() < mmm.models[pool[j]].unitPrice()
+		})
+	case RoutingLatency:
+		metrics := mmm.monitor.GetMetrics()
+		sort.SliceStable(pool, func(i, j int) bool {
+			return averageLatency(metrics, pool[i]) < averageLatency(metrics, pool[j])
+		})
+	}
+
+	if !pinned && mmm.routing.Policy != RoutingFailover {
+		return pool
+	}
+
+	candidates := []string{requested}
+	for _, name := range pool {
+		if name != requested {
+			candidates = append(candidates, name)
+		}
+	}
+
+	return candidates
+}
+
+func (mmm *MultiModelManager) withFailover(ctx context.Context, messages []Message, call func(provider LLMProvider, req *CompletionRequest) (Usage, error)) error {
+	candidates, err := mmm.applyBudget(ctx, mmm.route(ctx))
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for i, name := range candidates {
+		provider, req, err := mmm.prepareModel(name, messages)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			logger.Warn("Failing over to another model", "from", candidates[i-1], "to", name, "error", lastErr)
+			mmm.monitor.RecordFailover(candidates[i-1], name)
+		}
+
+		_, span := tracing.Start(ctx, "llm.request", tracing.String("llm.model", name), tracing.String("llm.provider_model", req.Model), tracing.Bool("llm.failover", i > 0))
+		startTime := time.Now()
+		usage, err := call(provider, req)
+		mmm.monitor.RecordRequest(name, time.Since(startTime), usage.TotalTokens, err)
+		span.SetAttributes(tracing.Int("llm.prompt_tokens", usage.PromptTokens), tracing.Int("llm.completion_tokens", usage.CompletionTokens))
+		span.RecordError(err)
+		span.End()
+		if err == nil {
+			mmm.recordUsage(ctx, name, usage)
+			return nil
+		}
+
+		lastErr = err
+		if ctx.Err() != nil || !shouldFailover(err) {
+			return err
+		}
+	}
+
+	return lastErr
+}
+
+type noFailoverError struct {
+	err error
+}
+
+func (e *noFailoverError) Error() string {
+	return e.err.Error()
+}
+
+func (e *noFailoverError) Unwrap() error {
+	return e.err
+}
+
+func shouldFailover(err error) bool {
+	var noFailover *noFailoverError
+	if errors.As(err, &noFailover) {
+		return false
+	}
+
+	var netErr net.Error
+	return IsRetryableError(err) || errors.Is(err, ErrConnectionError) || errors.As(err, &netErr)
+}
+
+func averageLatency(metrics *Metrics, name string) time.Duration {
+	pm, ok := metrics.ProviderMetrics[name]
+	if !ok || pm.TotalRequests == 0 {
+		return 0
+	}
+	return pm.TotalLatency / time.Duration(pm.TotalRequests)
+}
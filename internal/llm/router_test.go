@@ -0,0 +1,167 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	name  string
+	err   error
+	calls int
+	usage Usage
+	last  *CompletionRequest
+}
+
+func (p *fakeProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	p.calls++
+	p.last = req
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &CompletionResponse{Content: p.name, Usage: p.usage}, nil
+}
+
+func (p *fakeProvider) StreamComplete(ctx context.Context, req *CompletionRequest, callback func(chunk string) error) error {
+	p.calls++
+	if err := callback(p.name); err != nil {
+		return err
+	}
+	return p.err
+}
+
+func (p *fakeProvider) GetModel() string {
+	return p.name
+}
+
+func newRoutedManager(providers map[string]*fakeProvider, costs map[string]float64, current string) *MultiModelManager {
+	mmm := &MultiModelManager{
+		providers:    make(map[string]LLMProvider),
+		models:       make(map[string]*ModelConfig),
+		currentModel: current,
+		defaultModel: current,
+		monitor:      NewMonitor(),
+	}
+
+	for name, provider := range providers {
+		mmm.providers[name] = provider
+		mmm.models[name] = &ModelConfig{Name: name, Model: name, Cost: costs[name]}
+	}
+
+	return mmm
+}
+
+func TestFailoverOnRetryableError(t *testing.T) {
+	unavailable := NewLLMError("SERVICE_UNAVAILABLE", "Service unavailable", ErrServerUnavailable)
+	primary := &fakeProvider{name: "primary", err: unavailable}
+	backup := &fakeProvider{name: "backup"}
+	manager := newRoutedManager(map[string]*fakeProvider{"primary": primary, "backup": backup}, nil, "primary")
+
+	if _, err := manager.Complete(context.Background(), nil); err == nil {
+		t.Fatal("Expected error without a routing policy")
+	}
+
+	if err := manager.SetRouting(&RoutingConfig{Policy: RoutingFailover, Models: []string{"primary", "backup"}}); err != nil {
+		t.Fatalf("Failed to set routing: %v", err)
+	}
+
+	resp, err := manager.Complete(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Expected failover to succeed, got %v", err)
+	}
+	if resp.Content != "backup" {
+		t.Errorf("Expected backup response, got %s", resp.Content)
+	}
+
+	metrics := manager.Monitor().GetMetrics()
+	if metrics.Failovers != 1 || metrics.FailoverCounts["primary -> backup"] != 1 {
+		t.Errorf("Expected failover to be recorded, got %d %v", metrics.Failovers, metrics.FailoverCounts)
+	}
+	if metrics.ProviderMetrics["primary"].FailedReqs != 2 || metrics.ProviderMetrics["backup"].SuccessfulReqs != 1 {
+		t.Errorf("Expected per-model request metrics, got %+v", metrics.ProviderMetrics)
+	}
+}
+
+func TestNoFailoverOnValidationError(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: NewLLMError("BAD_REQUEST", "Invalid request", ErrInvalidRequest)}
+	backup := &fakeProvider{name: "backup"}
+	manager := newRoutedManager(map[string]*fakeProvider{"primary": primary, "backup": backup}, nil, "primary")
+	manager.SetRouting(&RoutingConfig{Policy: RoutingFailover})
+
+	if _, err := manager.Complete(context.Background(), nil); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+	if backup.calls != 0 {
+		t.Error("Expected no failover for validation errors")
+	}
+}
+
+func TestNoFailoverAfterPartialStream(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: NewLLMError("SERVER_ERROR", "Internal server error", ErrServerUnavailable)}
+	backup := &fakeProvider{name: "backup"}
+	manager := newRoutedManager(map[string]*fakeProvider{"primary": primary, "backup": backup}, nil, "primary")
+	manager.SetRouting(&RoutingConfig{Policy: RoutingFailover})
+
+	err := manager.StreamComplete(context.Background(), nil, func(chunk string) error { return nil })
+	if !errors.Is(err, ErrServerUnavailable) || backup.calls != 0 {
+		t.Errorf("Expected interrupted stream to fail without failover, got %v", err)
+	}
+}
+
+func TestRoutingPolicies(t *testing.T) {
+	providers := map[string]*fakeProvider{"a": {name: "a"}, "b": {name: "b"}, "c": {name: "c"}}
+	manager := newRoutedManager(providers, map[string]float64{"a": 15, "b": 0.5, "c": 3}, "a")
+
+	manager.SetRouting(&RoutingConfig{Policy: RoutingCheapest})
+	if route := manager.route(context.Background()); route[0] != "b" || route[1] != "c" || route[2] != "a" {
+		t.Errorf("Expected cheapest-first order, got %v", route)
+	}
+
+	if route := manager.route(WithModel(context.Background(), "a")); route[0] != "a" {
+		t.Errorf("Expected explicit model to be tried first, got %v", route)
+	}
+
+	manager.Monitor().RecordRequest("a", 50*time.Millisecond, 0, nil)
+	manager.Monitor().RecordRequest("b", 900*time.Millisecond, 0, nil)
+	manager.Monitor().RecordRequest("c", 200*time.Millisecond, 0, nil)
+
+	manager.SetRouting(&RoutingConfig{Policy: RoutingLatency})
+	if route := manager.route(context.Background()); route[0] != "a" || route[1] != "c" || route[2] != "b" {
+		t.Errorf("Expected fastest-first order, got %v", route)
+	}
+
+	if err := manager.SetRouting(&RoutingConfig{Policy: "random"}); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+	if err := manager.SetRouting(&RoutingConfig{Policy: RoutingFailover, Models: []string{"missing"}}); err == nil {
+		t.Error("Expected error for unknown routing model")
+	}
+}
+
+func TestImagesOnlySentToVisionModels(t *testing.T) {
+	vision := &fakeProvider{name: "vision"}
+	text := &fakeProvider{name: "text"}
+	manager := newRoutedManager(map[string]*fakeProvider{"vision": vision, "text": text}, nil, "vision")
+	manager.models["vision"].Vision = true
+
+	messages := []Message{{Role: RoleUser, Content: "What is this?", Parts: []ContentPart{ImageURLPart("https://example.com/cat.jpg")}}}
+
+	if _, err := manager.Complete(context.Background(), messages); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !vision.last.Messages[0].HasImages() {
+		t.Error("Expected the image to be sent to the vision model")
+	}
+
+	if _, err := manager.Complete(WithModel(context.Background(), "text"), messages); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sent := text.last.Messages[0]; sent.HasImages() || sent.Content != "What is this?" {
+		t.Errorf("Expected only the text to be sent to the text model, got %+v", sent)
+	}
+	if !messages[0].HasImages() {
+		t.Error("Expected the caller's messages to keep their images")
+	}
+}
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultContextWindow = 8192
+	messageTokenOverhead = 4
+	// imageTokens is a rough cost of an image, which providers bill by its
+	// size: about a megapixel costs 765 tokens at OpenAI and 1,300 at
+	// Anthropic.
+	imageTokens = 1000
+)
+
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"claude", 200000},
+	{"gpt-4o", 128000},
+	{"gpt-4.1", 1047576},
+	{"gpt-4-turbo", 128000},
+	{"o1", 128000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5", 16385},
+}
+
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	ascii := 0
+	other := 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+
+	return (ascii+3)/4 + other
+}
+
+func EstimateMessagesTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += EstimateTokens(msg.Content) + messageTokenOverhead
+		for _, part := range msg.Parts {
+			if part.Type == ContentImage {
+				total += imageTokens
+			} else {
+				total += EstimateTokens(part.Text)
+			}
+		}
+	}
+	return total
+}
+
+func DefaultContextWindow(model string) int {
+	model = strings.ToLower(model)
+	for _, window := range contextWindows {
+		if strings.HasPrefix(model, window.prefix) {
+			return window.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+func (mmm *MultiModelManager) ContextWindow() int {
+	mmm.mu.RLock()
+	defer mmm.mu.RUnlock()
+
+	config, ok := mmm.models[mmm.currentModel]
+	if !ok {
+		return defaultContextWindow
+	}
+
+	if config.ContextWindow > 0 {
+		return config.ContextWindow
+	}
+
+	return DefaultContextWindow(config.Model)
+}
+
+func (mmm *MultiModelManager) MaxTokens() int {
+	mmm.mu.RLock()
+	defer mmm.mu.RUnlock()
+
+	config, ok := mmm.models[mmm.currentModel]
+	if !ok {
+		return 0
+	}
+
+	return config.MaxTokens
+}
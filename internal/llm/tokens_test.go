@@ -0,0 +1,42 @@
+package llm
+
+import "testing"
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens(""); got != 0 {
+		t.Errorf("Expected 0 tokens for empty text, got %d", got)
+	}
+
+	if got := EstimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("Expected 2 tokens, got %d", got)
+	}
+
+	if got := EstimateTokens("你好"); got != 2 {
+		t.Errorf("Expected 2 tokens for non-ASCII text, got %d", got)
+	}
+}
+
+func TestEstimateMessagesTokensCountsImages(t *testing.T) {
+	text := []Message{{Role: RoleUser, Content: "abcdefgh"}}
+	withImage := []Message{{Role: RoleUser, Content: "abcdefgh", Parts: []ContentPart{ImageURLPart("https://example.com/cat.jpg")}}}
+
+	if got := EstimateMessagesTokens(withImage) - EstimateMessagesTokens(text); got != imageTokens {
+		t.Errorf("Expected an image to add %d tokens, got %d", imageTokens, got)
+	}
+}
+
+func TestDefaultContextWindow(t *testing.T) {
+	tests := map[string]int{
+		"claude-sonnet-4-5": 200000,
+		"gpt-4o-mini":       128000,
+		"gpt-4":             8192,
+		"gpt-3.5-turbo":     16385,
+		"llama":             defaultContextWindow,
+	}
+
+	for model, expected := range tests {
+		if got := DefaultContextWindow(model); got != expected {
+			t.Errorf("Expected %d for %s, got %d", expected, model, got)
+		}
+	}
+}
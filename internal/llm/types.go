@@ -1,6 +1,9 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"encoding/base64"
+)
 
 type MessageRole string
 
@@ -13,6 +16,90 @@ const (
 type Message struct {
 	Role    MessageRole `json:"role"`
 	Content string      `json:"content"`
+	// Parts follow Content in multimodal messages, e.g. images for the
+	// model to look at. Providers without vision support drop images.
+	Parts []ContentPart `json:"parts,omitempty"`
+}
+
+type ContentPartType string
+
+const (
+	ContentText  ContentPartType = "text"
+	ContentImage ContentPartType = "image"
+)
+
+// ContentPart is a part of a multimodal message. An image is given either
+// by ImageURL or as base64 Data with its MediaType.
+type ContentPart struct {
+	Type      ContentPartType `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ImageURL  string          `json:"image_url,omitempty"`
+	MediaType string          `json:"media_type,omitempty"`
+	Data      string          `json:"data,omitempty"`
+}
+
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: ContentText, Text: text}
+}
+
+func ImageURLPart(url string) ContentPart {
+	return ContentPart{Type: ContentImage, ImageURL: url}
+}
+
+// ImageDataPart embeds an image, e.g. a photo read from storage.
+func ImageDataPart(mediaType string, data []byte) ContentPart {
+	return ContentPart{Type: ContentImage, MediaType: mediaType, Data: base64.StdEncoding.EncodeToString(data)}
+}
+
+// URL returns the URL of an image, which is a data URL for embedded images.
+func (p ContentPart) URL() string {
+	if p.ImageURL != "" {
+		return p.ImageURL
+	}
+	return "data:" + p.MediaType + ";base64," + p.Data
+}
+
+// ContentParts returns Content as a text part followed by Parts.
+func (m Message) ContentParts() []ContentPart {
+	parts := make([]ContentPart, 0, len(m.Parts)+1)
+	if m.Content != "" {
+		parts = append(parts, TextPart(m.Content))
+	}
+	return append(parts, m.Parts...)
+}
+
+func (m Message) HasImages() bool {
+	for _, part := range m.Parts {
+		if part.Type == ContentImage {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutImages returns messages with their images left out, sharing the
+// slice if there are none.
+func WithoutImages(messages []Message) []Message {
+	var stripped []Message
+	for i, msg := range messages {
+		if !msg.HasImages() {
+			continue
+		}
+		if stripped == nil {
+			stripped = append([]Message(nil), messages...)
+		}
+		parts := make([]ContentPart, 0, len(msg.Parts))
+		for _, part := range msg.Parts {
+			if part.Type != ContentImage {
+				parts = append(parts, part)
+			}
+		}
+		stripped[i].Parts = parts
+	}
+	if stripped == nil {
+		return messages
+	}
+	return stripped
 }
 
 type CompletionRequest struct {
@@ -25,7 +112,10 @@ type CompletionRequest struct {
 
 type CompletionResponse struct {
 	Content string `json:"content"`
-	Usage   Usage  `json:"usage"`
+	// Thinking is the model's reasoning, for providers that return it
+	// separately from the answer.
+	Thinking string `json:"thinking,omitempty"`
+	Usage    Usage  `json:"usage"`
 }
 
 type Usage struct {
@@ -41,11 +131,36 @@ type LLMProvider interface {
 }
 
 type Config struct {
-	Provider    string          `yaml:"provider"`
-	APIKey      string          `yaml:"api_key"`
-	Model       string          `yaml:"model"`
-	BaseURL     string          `yaml:"base_url,omitempty"`
-	MaxTokens   int             `yaml:"max_tokens"`
-	Temperature float64         `yaml:"temperature"`
-	LocalModel  LocalModelConfig `yaml:"local_model"`
+	Provider     string           `yaml:"provider"`
+	APIKey       string           `yaml:"api_key"`
+	Model        string           `yaml:"model"`
+	BaseURL      string           `yaml:"base_url,omitempty"`
+	Organization string           `yaml:"organization,omitempty"`
+	Project      string           `yaml:"project,omitempty"`
+	Deployment   string           `yaml:"deployment,omitempty"`
+	APIVersion   string           `yaml:"api_version,omitempty"`
+	MaxTokens    int              `yaml:"max_tokens"`
+	Temperature  float64          `yaml:"temperature"`
+	LocalModel   LocalModelConfig `yaml:"local_model"`
+
+	ContextWindow int `yaml:"context_window,omitempty"`
+	// ThinkingBudget enables extended thinking with this many tokens for
+	// reasoning, on providers that support it.
+	ThinkingBudget int `yaml:"thinking_budget,omitempty"`
+	// Headers are sent with every request, e.g. OpenRouter's HTTP-Referer
+	// and X-Title attribution headers.
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+type thinkingHandlerKey struct{}
+
+// WithThinkingHandler makes streamed requests made with ctx pass the model's
+// reasoning to handler as it arrives, apart from the answer.
+func WithThinkingHandler(ctx context.Context, handler func(chunk string)) context.Context {
+	return context.WithValue(ctx, thinkingHandlerKey{}, handler)
+}
+
+func thinkingHandlerFromContext(ctx context.Context) func(chunk string) {
+	handler, _ := ctx.Value(thinkingHandlerKey{}).(func(chunk string))
+	return handler
 }
@@ -0,0 +1,181 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type Config struct {
+	Level   string
+	Format  string
+	Modules map[string]string
+}
+
+type state struct {
+	handler slog.Handler
+	level   slog.Level
+	modules map[string]slog.Level
+}
+
+func (s *state) levelFor(component string) slog.Level {
+	if level, ok := s.modules[component]; ok {
+		return level
+	}
+	return s.level
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{
+		handler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		level:   slog.LevelInfo,
+	})
+}
+
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level: %s", value)
+}
+
+func Setup(config *Config, w io.Writer) error {
+	if config == nil {
+		config = &Config{}
+	}
+	if w == nil {
+		w = os.Stderr
+	}
+
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return err
+	}
+
+	modules, err := parseModuleLevels(config.Modules)
+	if err != nil {
+		return err
+	}
+
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+	var handler slog.Handler
+	switch strings.ToLower(config.Format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return fmt.Errorf("unknown log format: %s", config.Format)
+	}
+
+	current.Store(&state{handler: handler, level: level, modules: modules})
+	slog.SetDefault(slog.New(&componentHandler{}))
+
+	return nil
+}
+
+// SetLevels changes the log levels without touching the output, so they can
+// follow a reloaded config.
+func SetLevels(level string, modules map[string]string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	moduleLevels, err := parseModuleLevels(modules)
+	if err != nil {
+		return err
+	}
+
+	previous := current.Load()
+	current.Store(&state{handler: previous.handler, level: parsed, modules: moduleLevels})
+	return nil
+}
+
+func parseModuleLevels(modules map[string]string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level, len(modules))
+	for component, value := range modules {
+		moduleLevel, err := ParseLevel(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level for %s: %w", component, err)
+		}
+		levels[strings.ToLower(component)] = moduleLevel
+	}
+	return levels, nil
+}
+
+func For(component string) *slog.Logger {
+	return slog.New(&componentHandler{component: component})
+}
+
+func Or(logger *slog.Logger, component string) *slog.Logger {
+	if logger != nil {
+		return logger
+	}
+	return For(component)
+}
+
+type componentHandler struct {
+	component string
+	wrap      []func(slog.Handler) slog.Handler
+}
+
+func (h *componentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= current.Load().levelFor(h.component)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	handler := current.Load().handler
+	if h.component != "" {
+		handler = handler.WithAttrs([]slog.Attr{slog.String("component", h.component)})
+	}
+	for _, wrap := range h.wrap {
+		handler = wrap(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithAttrs(attrs)
+	})
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithGroup(name)
+	})
+}
+
+func (h *componentHandler) with(wrap func(slog.Handler) slog.Handler) slog.Handler {
+	wraps := make([]func(slog.Handler) slog.Handler, 0, len(h.wrap)+1)
+	wraps = append(wraps, h.wrap...)
+	return &componentHandler{component: h.component, wrap: append(wraps, wrap)}
+}
+
+func Preview(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "..."
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestSetupLevelsAndModules(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(&Config{Level: "warn", Format: FormatJSON, Modules: map[string]string{"mcp": "debug"}}, &buf); err != nil {
+		t.Fatalf("Failed to set up logging: %v", err)
+	}
+	t.Cleanup(func() { Setup(nil, nil) })
+
+	agent := For("agent")
+	mcp := For("mcp").With("client", "filesystem")
+
+	agent.Info("hidden")
+	agent.Warn("shown", "chat_id", "42")
+	mcp.Debug("connected")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d: %s", len(lines), buf.String())
+	}
+
+	var first, second map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Expected JSON output, got %s", lines[0])
+	}
+	json.Unmarshal([]byte(lines[1]), &second)
+
+	if first["msg"] != "shown" || first["component"] != "agent" || first["chat_id"] != "42" {
+		t.Errorf("Unexpected agent record %v", first)
+	}
+	if second["msg"] != "connected" || second["component"] != "mcp" || second["client"] != "filesystem" || second["level"] != "DEBUG" {
+		t.Errorf("Unexpected mcp record %v", second)
+	}
+}
+
+func TestLoggersFollowLaterSetup(t *testing.T) {
+	logger := For("scheduler")
+
+	var buf bytes.Buffer
+	if err := Setup(&Config{Level: "debug"}, &buf); err != nil {
+		t.Fatalf("Failed to set up logging: %v", err)
+	}
+	t.Cleanup(func() { Setup(nil, nil) })
+
+	logger.Debug("tick", "tasks", 3)
+	log.Printf("legacy %s", "message")
+
+	output := buf.String()
+	if !strings.Contains(output, "component=scheduler") || !strings.Contains(output, "tasks=3") {
+		t.Errorf("Expected logger created before setup to use the new handler, got %s", output)
+	}
+	if !strings.Contains(output, `msg="legacy message"`) {
+		t.Errorf("Expected standard log output to be routed through slog, got %s", output)
+	}
+}
+
+func TestSetupRejectsInvalidConfig(t *testing.T) {
+	for _, config := range []*Config{
+		{Level: "verbose"},
+		{Format: "xml"},
+		{Modules: map[string]string{"agent": "loud"}},
+	} {
+		if err := Setup(config, &bytes.Buffer{}); err == nil {
+			t.Errorf("Expected error for %+v", config)
+		}
+	}
+}
+
+func TestSetLevelsKeepsOutput(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(&Config{Level: "info"}, &buf); err != nil {
+		t.Fatalf("Failed to set up logging: %v", err)
+	}
+	t.Cleanup(func() { Setup(nil, nil) })
+
+	if err := SetLevels("error", map[string]string{"agent": "debug"}); err != nil {
+		t.Fatalf("Failed to change levels: %v", err)
+	}
+
+	For("mcp").Warn("hidden")
+	For("agent").Debug("shown")
+
+	if output := buf.String(); strings.Contains(output, "hidden") || !strings.Contains(output, "shown") {
+		t.Errorf("Expected new levels on the same output, got %s", output)
+	}
+	if err := SetLevels("loud", nil); err == nil {
+		t.Error("Expected unknown level to be rejected")
+	}
+}
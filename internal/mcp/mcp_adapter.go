@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -60,10 +61,11 @@ type AdapterConfig struct {
 }
 
 type MCPAdapter struct {
-	client   *MCPClient
-	config   *AdapterConfig
-	registry *tools.ToolRegistry
-	mu       sync.RWMutex
+	client     *MCPClient
+	config     *AdapterConfig
+	registry   *tools.ToolRegistry
+	registered []string
+	mu         sync.RWMutex
 }
 
 func NewAdapter(client *MCPClient, config *AdapterConfig, registry *tools.ToolRegistry) (*MCPAdapter, error) {
@@ -82,11 +84,22 @@ func NewAdapter(client *MCPClient, config *AdapterConfig, registry *tools.ToolRe
 		config.Prefix = "mcp_"
 	}
 
-	return &MCPAdapter{
+	adapter := &MCPAdapter{
 		client:   client,
 		config:   config,
 		registry: registry,
-	}, nil
+	}
+
+	client.OnToolsChanged(func() {
+		if adapter.registry == nil {
+			return
+		}
+		if err := adapter.RefreshTools(client.ctx); err != nil {
+			client.logger.Error("Failed to refresh adapter tools", "error", err)
+		}
+	})
+
+	return adapter, nil
 }
 
 func (a *MCPAdapter) RegisterTools(ctx context.Context) error {
@@ -122,6 +135,8 @@ func (a *MCPAdapter) RegisterTools(ctx context.Context) error {
 		if err := a.registry.Register(tool); err != nil {
 			return fmt.Errorf("failed to register tool %s: %w", toolName, err)
 		}
+
+		a.registered = append(a.registered, toolName)
 	}
 
 	return nil
@@ -131,14 +146,12 @@ func (a *MCPAdapter) UnregisterTools() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	mcpTools := a.client.GetTools()
-
-	for _, mcpTool := range mcpTools {
-		toolName := a.config.Prefix + mcpTool.Name
-
+	for _, toolName := range a.registered {
 		a.registry.Unregister(toolName)
 	}
 
+	a.registered = nil
+
 	return nil
 }
 
@@ -309,13 +322,16 @@ func (m *MCPManager) ConnectAll(ctx context.Context) error {
 	}
 	m.mu.RUnlock()
 
+	// A server that is down should not keep the others from connecting;
+	// health checks retry the ones that failed.
+	var errs []error
 	for _, name := range names {
 		if err := m.ConnectClient(ctx, name); err != nil {
-			return fmt.Errorf("failed to connect client %s: %w", name, err)
+			errs = append(errs, fmt.Errorf("failed to connect client %s: %w", name, err))
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 func (m *MCPManager) DisconnectAll() error {
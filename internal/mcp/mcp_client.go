@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 	"github.com/wjffsx/miniclaw_go/internal/tools"
+	"github.com/wjffsx/miniclaw_go/internal/tracing"
 )
 
 type ClientConfig struct {
@@ -18,6 +22,19 @@ type ClientConfig struct {
 	Timeout    int
 	MaxRetries int
 	RetryDelay int
+	Command    string
+	Args       []string
+	Env        map[string]string
+	WorkDir    string
+
+	Fixture     string
+	FixtureMode string
+
+	Logger *slog.Logger
+}
+
+func (c *ClientConfig) logger() *slog.Logger {
+	return logging.Or(c.Logger, "mcp").With("server", c.Name)
 }
 
 type MCPClient struct {
@@ -29,6 +46,14 @@ type MCPClient struct {
 	initialized bool
 	ctx         context.Context
 	cancel      context.CancelFunc
+	listeners   []func()
+	logger      *slog.Logger
+
+	// state and lastError are set by Connect and the health checks.
+	state     ClientState
+	lastError string
+	failures  int
+	retryAt   time.Time
 }
 
 type MCPTool struct {
@@ -44,13 +69,19 @@ const (
 	StateConnecting   ClientState = "connecting"
 	StateConnected    ClientState = "connected"
 	StateError        ClientState = "error"
+	// StateDegraded means the server stopped answering after connecting and
+	// is being reconnected. Its tools are unavailable meanwhile.
+	StateDegraded ClientState = "degraded"
 )
 
 type ClientStatus struct {
+	Name      string
 	State     ClientState
 	Connected bool
 	ToolCount int
 	Error     string
+	// Failures counts the failed health checks and reconnects in a row.
+	Failures int
 }
 
 func NewClient(config *ClientConfig) (*MCPClient, error) {
@@ -69,6 +100,7 @@ func NewClient(config *ClientConfig) (*MCPClient, error) {
 		tools:  make(map[string]*MCPTool),
 		ctx:    ctx,
 		cancel: cancel,
+		logger: config.logger(),
 	}
 
 	return client, nil
@@ -88,9 +120,12 @@ func (c *MCPClient) Connect(ctx context.Context) error {
 	}
 
 	c.protocol = protocol
+	c.protocol.SetNotificationHandler(c.handleNotification)
 
 	if err := c.protocol.Connect(ctx); err != nil {
+		c.protocol.Close()
 		c.connected = false
+		c.setFailedLocked(err)
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 
@@ -99,19 +134,74 @@ func (c *MCPClient) Connect(ctx context.Context) error {
 	if err := c.initializeTools(ctx); err != nil {
 		c.protocol.Close()
 		c.connected = false
+		c.setFailedLocked(err)
 		return fmt.Errorf("failed to initialize tools: %w", err)
 	}
 
 	c.initialized = true
+	c.state = StateConnected
+	c.lastError = ""
 
 	return nil
 }
 
+// setFailedLocked records a failed connection attempt. A client that was
+// connected before stays degraded rather than failed.
+func (c *MCPClient) setFailedLocked(err error) {
+	if c.state != StateDegraded {
+		c.state = StateError
+	}
+	c.lastError = err.Error()
+}
+
+// Ping checks that the server is still reachable.
+func (c *MCPClient) Ping(ctx context.Context) error {
+	c.mu.RLock()
+	if !c.connected {
+		c.mu.RUnlock()
+		return fmt.Errorf("client not connected")
+	}
+	protocol := c.protocol
+	c.mu.RUnlock()
+
+	return protocol.Ping(ctx)
+}
+
+// Reconnect drops the current connection, if any, and connects again.
+func (c *MCPClient) Reconnect(ctx context.Context) error {
+	c.mu.Lock()
+	if c.protocol != nil {
+		c.protocol.Close()
+	}
+	c.connected = false
+	c.initialized = false
+	c.mu.Unlock()
+
+	return c.Connect(ctx)
+}
+
+// markDegraded records that a connected server stopped answering and closes
+// the connection, so ExecuteTool fails fast until it is reconnected.
+func (c *MCPClient) markDegraded(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.protocol != nil {
+		c.protocol.Close()
+	}
+	c.connected = false
+	c.initialized = false
+	c.tools = make(map[string]*MCPTool)
+	c.state = StateDegraded
+	c.lastError = err.Error()
+}
+
 func (c *MCPClient) Disconnect() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if !c.connected {
+		c.state = StateDisconnected
 		return nil
 	}
 
@@ -124,6 +214,7 @@ func (c *MCPClient) Disconnect() error {
 	c.connected = false
 	c.initialized = false
 	c.tools = make(map[string]*MCPTool)
+	c.state = StateDisconnected
 
 	return nil
 }
@@ -138,15 +229,18 @@ func (c *MCPClient) GetStatus() *ClientStatus {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	state := StateDisconnected
-	if c.connected {
-		state = StateConnected
+	state := c.state
+	if state == "" {
+		state = StateDisconnected
 	}
 
 	return &ClientStatus{
+		Name:      c.config.Name,
 		State:     state,
 		Connected: c.connected,
 		ToolCount: len(c.tools),
+		Error:     c.lastError,
+		Failures:  c.failures,
 	}
 }
 
@@ -184,7 +278,10 @@ func (c *MCPClient) ExecuteTool(ctx context.Context, name string, params map[str
 	}
 	c.mu.RUnlock()
 
+	ctx, span := tracing.Start(ctx, "mcp.call_tool", tracing.String("mcp.server", c.config.Name), tracing.String("mcp.tool", name))
 	result, err := c.protocol.CallTool(ctx, name, params)
+	span.RecordError(err)
+	span.End()
 	if err != nil {
 		return nil, fmt.Errorf("failed to call tool: %w", err)
 	}
@@ -207,6 +304,66 @@ func (c *MCPClient) initializeTools(ctx context.Context) error {
 	return nil
 }
 
+func (c *MCPClient) OnToolsChanged(listener func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listener)
+}
+
+// RefreshTools lists the server's tools again. The list is fetched without
+// holding c.mu, so tool calls and status checks are not blocked while the
+// server answers.
+func (c *MCPClient) RefreshTools(ctx context.Context) error {
+	c.mu.RLock()
+	if !c.connected {
+		c.mu.RUnlock()
+		return fmt.Errorf("client not connected")
+	}
+	protocol := c.protocol
+	c.mu.RUnlock()
+
+	toolsList, err := protocol.ListTools(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	refreshed := make(map[string]*MCPTool, len(toolsList))
+	for _, tool := range toolsList {
+		refreshed[tool.Name] = tool
+	}
+
+	c.mu.Lock()
+	if !c.connected || c.protocol != protocol {
+		c.mu.Unlock()
+		return fmt.Errorf("client reconnected while refreshing tools")
+	}
+	c.tools = refreshed
+
+	listeners := make([]func(), len(c.listeners))
+	copy(listeners, c.listeners)
+	c.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+
+	return nil
+}
+
+func (c *MCPClient) handleNotification(method string, params json.RawMessage) {
+	switch method {
+	case "notifications/tools/list_changed":
+		c.logger.Info("MCP server reported a tool list change")
+		go func() {
+			if err := c.RefreshTools(c.ctx); err != nil {
+				c.logger.Error("Failed to refresh tools", "error", err)
+			}
+		}()
+	case "notifications/message":
+		c.logger.Info("MCP server message", "params", string(params))
+	}
+}
+
 func (c *MCPClient) Close() error {
 	c.cancel()
 	return c.Disconnect()
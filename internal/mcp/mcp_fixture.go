@@ -0,0 +1,342 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	FixtureModeRecord = "record"
+	FixtureModeReplay = "replay"
+)
+
+const (
+	ExchangeRequest            = "request"
+	ExchangeNotification       = "notification"
+	ExchangeServerNotification = "server_notification"
+)
+
+type Fixture struct {
+	Server     string            `json:"server"`
+	RecordedAt time.Time         `json:"recorded_at"`
+	Exchanges  []FixtureExchange `json:"exchanges"`
+}
+
+type FixtureExchange struct {
+	Kind     string          `json:"kind"`
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+
+	return &fixture, nil
+}
+
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+
+	return nil
+}
+
+type RecordingTransport struct {
+	transport Transport
+	path      string
+	mu        sync.Mutex
+	fixture   *Fixture
+}
+
+func NewRecordingTransport(transport Transport, server, path string) *RecordingTransport {
+	return &RecordingTransport{
+		transport: transport,
+		path:      path,
+		fixture: &Fixture{
+			Server:     server,
+			RecordedAt: time.Now().UTC(),
+			Exchanges:  make([]FixtureExchange, 0),
+		},
+	}
+}
+
+func (t *RecordingTransport) sendRequest(ctx context.Context, method string, payload map[string]interface{}) ([]byte, error) {
+	response, err := t.transport.sendRequest(ctx, method, payload)
+
+	exchange := FixtureExchange{
+		Kind:   ExchangeRequest,
+		Method: method,
+		Params: marshalParams(payload["params"]),
+	}
+	if err != nil {
+		exchange.Error = err.Error()
+	} else if json.Valid(response) {
+		exchange.Response = response
+	} else {
+		exchange.Error = fmt.Sprintf("invalid JSON response: %s", string(response))
+	}
+	t.record(exchange)
+
+	return response, err
+}
+
+func (t *RecordingTransport) sendNotification(ctx context.Context, method string, payload map[string]interface{}) error {
+	err := t.transport.sendNotification(ctx, method, payload)
+
+	exchange := FixtureExchange{
+		Kind:   ExchangeNotification,
+		Method: method,
+		Params: marshalParams(payload["params"]),
+	}
+	if err != nil {
+		exchange.Error = err.Error()
+	}
+	t.record(exchange)
+
+	return err
+}
+
+func (t *RecordingTransport) setNotificationHandler(handler NotificationHandler) {
+	source, ok := t.transport.(notificationSource)
+	if !ok {
+		return
+	}
+
+	source.setNotificationHandler(func(method string, params json.RawMessage) {
+		t.record(FixtureExchange{
+			Kind:   ExchangeServerNotification,
+			Method: method,
+			Params: params,
+		})
+		if handler != nil {
+			handler(method, params)
+		}
+	})
+}
+
+func (t *RecordingTransport) record(exchange FixtureExchange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.fixture.Exchanges = append(t.fixture.Exchanges, exchange)
+}
+
+func (t *RecordingTransport) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fixture.Save(t.path)
+}
+
+func (t *RecordingTransport) Close() error {
+	closeErr := t.transport.Close()
+
+	if err := t.Save(); err != nil {
+		return err
+	}
+
+	return closeErr
+}
+
+type ReplayTransport struct {
+	mu       sync.Mutex
+	fixture  *Fixture
+	used     []bool
+	lastUsed map[string]int
+	handler  NotificationHandler
+	closed   bool
+}
+
+func NewReplayTransport(fixture *Fixture) *ReplayTransport {
+	return &ReplayTransport{
+		fixture:  fixture,
+		used:     make([]bool, len(fixture.Exchanges)),
+		lastUsed: make(map[string]int),
+	}
+}
+
+func (t *ReplayTransport) setNotificationHandler(handler NotificationHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handler = handler
+}
+
+func (t *ReplayTransport) sendRequest(ctx context.Context, method string, payload map[string]interface{}) ([]byte, error) {
+	t.mu.Lock()
+
+	if t.closed {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("transport closed")
+	}
+
+	index, err := t.match(ExchangeRequest, method, payload["params"])
+	if err != nil {
+		t.mu.Unlock()
+		return nil, err
+	}
+
+	exchange := t.fixture.Exchanges[index]
+	notifications := t.serverNotificationsAfter(index)
+	handler := t.handler
+	t.mu.Unlock()
+
+	if handler != nil {
+		for _, notification := range notifications {
+			go handler(notification.Method, notification.Params)
+		}
+	}
+
+	if exchange.Error != "" {
+		return nil, fmt.Errorf("%s", exchange.Error)
+	}
+
+	return withResponseID(exchange.Response, payload["id"]), nil
+}
+
+func (t *ReplayTransport) sendNotification(ctx context.Context, method string, payload map[string]interface{}) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return fmt.Errorf("transport closed")
+	}
+
+	index, err := t.match(ExchangeNotification, method, payload["params"])
+	if err != nil {
+		return err
+	}
+
+	if exchange := t.fixture.Exchanges[index]; exchange.Error != "" {
+		return fmt.Errorf("%s", exchange.Error)
+	}
+
+	return nil
+}
+
+func (t *ReplayTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+func (t *ReplayTransport) Unused() []FixtureExchange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	unused := make([]FixtureExchange, 0)
+	for i, exchange := range t.fixture.Exchanges {
+		if !t.used[i] && exchange.Kind != ExchangeServerNotification {
+			unused = append(unused, exchange)
+		}
+	}
+	return unused
+}
+
+func (t *ReplayTransport) match(kind, method string, params interface{}) (int, error) {
+	want := normalizeParams(marshalParams(params))
+	key := kind + " " + method + " " + string(marshalParams(want))
+
+	for i, exchange := range t.fixture.Exchanges {
+		if t.used[i] || exchange.Kind != kind || exchange.Method != method {
+			continue
+		}
+		if !reflect.DeepEqual(normalizeParams(exchange.Params), want) {
+			continue
+		}
+		t.used[i] = true
+		t.lastUsed[key] = i
+		return i, nil
+	}
+
+	if index, ok := t.lastUsed[key]; ok {
+		return index, nil
+	}
+
+	return 0, fmt.Errorf("no recorded %s for %s with params %s in fixture for %s", kind, method, string(marshalParams(params)), t.fixture.Server)
+}
+
+func (t *ReplayTransport) serverNotificationsAfter(index int) []FixtureExchange {
+	notifications := make([]FixtureExchange, 0)
+	for i := index + 1; i < len(t.fixture.Exchanges); i++ {
+		exchange := t.fixture.Exchanges[i]
+		if exchange.Kind != ExchangeServerNotification {
+			break
+		}
+		if t.used[i] {
+			continue
+		}
+		t.used[i] = true
+		notifications = append(notifications, exchange)
+	}
+	return notifications
+}
+
+func marshalParams(params interface{}) json.RawMessage {
+	if params == nil {
+		return nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func normalizeParams(params json.RawMessage) interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(params, &value); err != nil {
+		return string(params)
+	}
+	return value
+}
+
+func withResponseID(response json.RawMessage, id interface{}) []byte {
+	if id == nil {
+		return response
+	}
+
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(response, &message); err != nil {
+		return response
+	}
+
+	rawID, err := json.Marshal(id)
+	if err != nil {
+		return response
+	}
+	message["id"] = rawID
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return response
+	}
+	return data
+}
@@ -0,0 +1,248 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func newReplayClient(t *testing.T) *MCPClient {
+	t.Helper()
+
+	client, err := NewClient(&ClientConfig{
+		Name:        "weather",
+		Fixture:     filepath.Join("testdata", "weather.json"),
+		FixtureMode: FixtureModeReplay,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestReplayAdapterRegistersTools(t *testing.T) {
+	client := newReplayClient(t)
+	registry := tools.NewToolRegistry()
+
+	adapter, err := NewAdapter(client, &AdapterConfig{Prefix: "weather_"}, registry)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if err := adapter.RegisterTools(context.Background()); err != nil {
+		t.Fatalf("Failed to register tools: %v", err)
+	}
+
+	forecast, ok := registry.Get("weather_forecast")
+	if !ok {
+		t.Fatal("Expected weather_forecast to be registered")
+	}
+	if _, ok := registry.Get("weather_alerts"); !ok {
+		t.Error("Expected weather_alerts to be registered")
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(forecast.Parameters(), &schema); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if required, _ := schema["required"].([]interface{}); len(required) != 1 || required[0] != "city" {
+		t.Errorf("Expected city to be required, got %v", schema["required"])
+	}
+
+	result, err := forecast.Execute(context.Background(), map[string]interface{}{"city": "Berlin"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result != "Berlin: 12°C, light rain\n" {
+		t.Errorf("Unexpected result %q", result)
+	}
+}
+
+func TestReplayToolErrors(t *testing.T) {
+	client := newReplayClient(t)
+	ctx := context.Background()
+
+	call, err := client.ExecuteTool(ctx, "forecast", map[string]interface{}{"city": "Atlantis"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if call.Error != "unknown city: Atlantis" {
+		t.Errorf("Expected tool error from the server, got %q", call.Error)
+	}
+
+	call, err = client.ExecuteTool(ctx, "alerts", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(call.Error, "status: 502") {
+		t.Errorf("Expected recorded transport error, got %q", call.Error)
+	}
+
+	call, err = client.ExecuteTool(ctx, "forecast", map[string]interface{}{"city": "Paris"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(call.Error, "no recorded request") {
+		t.Errorf("Expected unrecorded call to fail, got %q", call.Error)
+	}
+}
+
+func TestReplayRepeatsLastMatch(t *testing.T) {
+	client := newReplayClient(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		call, err := client.ExecuteTool(ctx, "forecast", map[string]interface{}{"city": "Berlin"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if call.Error != "" {
+			t.Fatalf("Call %d: expected no error, got %q", i, call.Error)
+		}
+	}
+}
+
+func TestReplayServerNotification(t *testing.T) {
+	client := newReplayClient(t)
+
+	changed := make(chan struct{}, 1)
+	client.OnToolsChanged(func() {
+		changed <- struct{}{}
+	})
+
+	call, err := client.ExecuteTool(context.Background(), "alerts", map[string]interface{}{"region": "EU"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if call.Result != "No active alerts\n" {
+		t.Errorf("Unexpected result %q", call.Result)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected recorded tool list change to be replayed")
+	}
+
+	if _, ok := client.GetTool("radar"); !ok {
+		t.Error("Expected radar tool after the replayed refresh")
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+
+		var result interface{}
+		switch request.Method {
+		case "tools/list":
+			result = map[string]interface{}{
+				"tools": []map[string]interface{}{{"name": "echo", "description": "Echo back"}},
+			}
+		case "tools/call":
+			result = map[string]interface{}{
+				"content": []map[string]interface{}{{"type": "text", "text": "pong"}},
+			}
+		default:
+			result = map[string]interface{}{}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+
+	path := filepath.Join(t.TempDir(), "fixtures", "echo.json")
+	ctx := context.Background()
+
+	recorder, err := NewClient(&ClientConfig{Name: "echo", Endpoint: server.URL, Fixture: path, FixtureMode: FixtureModeRecord})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := recorder.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if call, err := recorder.ExecuteTool(ctx, "echo", map[string]interface{}{"text": "ping"}); err != nil || call.Result != "pong\n" {
+		t.Fatalf("Unexpected live call result %+v, %v", call, err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Failed to close recorder: %v", err)
+	}
+	server.Close()
+
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	if fixture.Server != "echo" || len(fixture.Exchanges) != 4 {
+		t.Fatalf("Expected 4 recorded exchanges for echo, got %+v", fixture)
+	}
+
+	replay, err := NewClient(&ClientConfig{Name: "echo", Fixture: path, FixtureMode: FixtureModeReplay})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := replay.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect to replay: %v", err)
+	}
+	defer replay.Close()
+
+	call, err := replay.ExecuteTool(ctx, "echo", map[string]interface{}{"text": "ping"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if call.Result != "pong\n" {
+		t.Errorf("Expected replayed result 'pong', got %q", call.Result)
+	}
+}
+
+func TestNewProtocolFixtureErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *ClientConfig
+	}{
+		{"replay without fixture", &ClientConfig{Name: "x", FixtureMode: FixtureModeReplay}},
+		{"record without fixture", &ClientConfig{Name: "x", Endpoint: "http://localhost", FixtureMode: FixtureModeRecord}},
+		{"missing fixture file", &ClientConfig{Name: "x", Fixture: "testdata/missing.json", FixtureMode: FixtureModeReplay}},
+		{"unknown mode", &ClientConfig{Name: "x", Endpoint: "http://localhost", Fixture: "x.json", FixtureMode: "rewind"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewProtocol(tt.config); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestReplayTransportUnused(t *testing.T) {
+	fixture, err := LoadFixture(filepath.Join("testdata", "weather.json"))
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+
+	transport := NewReplayTransport(fixture)
+	protocol := &JSONRPCProtocol{transport: transport}
+	if err := protocol.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	if unused := transport.Unused(); len(unused) != 6 {
+		t.Errorf("Expected 6 unused exchanges after connecting, got %d", len(unused))
+	}
+}
@@ -0,0 +1,157 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHealthInterval   = 30 * time.Second
+	defaultHealthTimeout    = 10 * time.Second
+	defaultHealthMaxBackoff = 5 * time.Minute
+)
+
+// HealthConfig controls how often servers are pinged and how reconnects back
+// off. Zero values use the defaults.
+type HealthConfig struct {
+	Interval   time.Duration
+	Timeout    time.Duration
+	MaxBackoff time.Duration
+}
+
+// StartHealthChecks pings every connected server each interval until the
+// manager is closed. A server that does not answer is marked degraded and its
+// tools are unregistered; it is then reconnected with exponential backoff
+// and its tools registered again once it is back. Servers that failed to
+// connect in the first place are retried the same way.
+func (m *MCPManager) StartHealthChecks(config *HealthConfig) {
+	health := HealthConfig{}
+	if config != nil {
+		health = *config
+	}
+	if health.Interval <= 0 {
+		health.Interval = defaultHealthInterval
+	}
+	if health.Timeout <= 0 {
+		health.Timeout = defaultHealthTimeout
+	}
+	if health.MaxBackoff <= 0 {
+		health.MaxBackoff = defaultHealthMaxBackoff
+	}
+
+	go func() {
+		ticker := time.NewTicker(health.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				m.CheckHealth(&health)
+			}
+		}
+	}()
+}
+
+// CheckHealth runs one round of health checks, checking the servers in
+// parallel so one slow server does not delay the rest.
+func (m *MCPManager) CheckHealth(config *HealthConfig) {
+	m.mu.RLock()
+	adapters := make([]*MCPAdapter, 0, len(m.adapters))
+	for _, adapter := range m.adapters {
+		adapters = append(adapters, adapter)
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, adapter := range adapters {
+		// A replayed fixture has no server that could go away.
+		if adapter.client.config.FixtureMode == FixtureModeReplay {
+			continue
+		}
+
+		wg.Add(1)
+		go func(adapter *MCPAdapter) {
+			defer wg.Done()
+			m.checkClient(adapter, config)
+		}(adapter)
+	}
+	wg.Wait()
+}
+
+func (m *MCPManager) checkClient(adapter *MCPAdapter, config *HealthConfig) {
+	client := adapter.client
+
+	if client.IsConnected() {
+		ctx, cancel := context.WithTimeout(m.ctx, config.Timeout)
+		err := client.Ping(ctx)
+		cancel()
+		if err == nil {
+			client.recordHealthy()
+			return
+		}
+
+		client.logger.Warn("MCP server is not responding, reconnecting", "error", err)
+		if err := adapter.UnregisterTools(); err != nil {
+			client.logger.Error("Failed to unregister tools", "error", err)
+		}
+		client.markDegraded(err)
+	} else if !client.dueForReconnect(time.Now()) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, config.Timeout)
+	err := client.Reconnect(ctx)
+	cancel()
+	if err != nil {
+		delay := client.recordFailure(config.Interval, config.MaxBackoff)
+		client.logger.Warn("Failed to reconnect to MCP server", "error", err, "retry_in", delay)
+		return
+	}
+
+	client.recordHealthy()
+	if err := adapter.RefreshTools(m.ctx); err != nil {
+		client.logger.Error("Failed to register tools after reconnecting", "error", err)
+		return
+	}
+	client.logger.Info("Reconnected to MCP server", "tools", len(client.GetTools()))
+}
+
+func (c *MCPClient) recordHealthy() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = 0
+	c.retryAt = time.Time{}
+}
+
+// recordFailure counts a failed reconnect and returns how long to wait before
+// the next one, doubling from interval up to maxBackoff.
+func (c *MCPClient) recordFailure(interval, maxBackoff time.Duration) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures++
+	delay := interval
+	for i := 1; i < c.failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	c.retryAt = time.Now().Add(delay)
+	return delay
+}
+
+// dueForReconnect reports whether a client that failed or was degraded should
+// be reconnected now. Clients disconnected on purpose are left alone.
+func (c *MCPClient) dueForReconnect(now time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.state != StateError && c.state != StateDegraded {
+		return false
+	}
+	return !now.Before(c.retryAt)
+}
@@ -0,0 +1,141 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+// flakyServer is an MCP server over HTTP that can be taken down and brought
+// back.
+func flakyServer(t *testing.T, down *atomic.Bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		var request struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+
+		var result interface{} = map[string]interface{}{}
+		if request.Method == "tools/list" {
+			result = map[string]interface{}{
+				"tools": []map[string]interface{}{{"name": "echo", "description": "Echo back"}},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMCPManagerHealthChecks(t *testing.T) {
+	var down atomic.Bool
+	server := flakyServer(t, &down)
+
+	registry := tools.NewToolRegistry()
+	manager := NewMCPManager(registry)
+	defer manager.Close()
+
+	client, _ := NewClient(&ClientConfig{Name: "echo", Endpoint: server.URL})
+	manager.AddClient(client, &AdapterConfig{Prefix: "mcp_echo_"})
+	if err := manager.ConnectAll(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	health := &HealthConfig{Interval: time.Hour, Timeout: time.Second, MaxBackoff: 4 * time.Hour}
+	manager.CheckHealth(health)
+	if status := client.GetStatus(); status.State != StateConnected {
+		t.Fatalf("Expected a healthy server to stay connected, got %+v", status)
+	}
+
+	down.Store(true)
+	manager.CheckHealth(health)
+	status := client.GetStatus()
+	if status.State != StateDegraded || status.Connected || status.Failures != 1 || status.Error == "" {
+		t.Errorf("Expected an unreachable server to be degraded, got %+v", status)
+	}
+	if _, ok := registry.Get("mcp_echo_echo"); ok {
+		t.Error("Expected tools of a degraded server to be unregistered")
+	}
+
+	// The next attempt waits for the backoff, which doubles up to the maximum.
+	manager.CheckHealth(health)
+	if status := client.GetStatus(); status.Failures != 1 {
+		t.Errorf("Expected no reconnect before the backoff elapsed, got %d failures", status.Failures)
+	}
+	if delay := client.recordFailure(time.Hour, 4*time.Hour); delay != 2*time.Hour {
+		t.Errorf("Expected the backoff to double, got %s", delay)
+	}
+	for i := 0; i < 3; i++ {
+		client.recordFailure(time.Hour, 4*time.Hour)
+	}
+	if delay := client.recordFailure(time.Hour, 4*time.Hour); delay != 4*time.Hour {
+		t.Errorf("Expected the backoff to be capped, got %s", delay)
+	}
+
+	down.Store(false)
+	client.mu.Lock()
+	client.retryAt = time.Time{}
+	client.mu.Unlock()
+	manager.CheckHealth(health)
+
+	status = client.GetStatus()
+	if status.State != StateConnected || !status.Connected || status.Failures != 0 || status.Error != "" {
+		t.Errorf("Expected the server to be reconnected, got %+v", status)
+	}
+	if _, ok := registry.Get("mcp_echo_echo"); !ok {
+		t.Error("Expected tools to be registered again after reconnecting")
+	}
+}
+
+func TestMCPManagerConnectAllRetriesFailedClients(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	server := flakyServer(t, &down)
+	healthy := flakyServer(t, new(atomic.Bool))
+
+	registry := tools.NewToolRegistry()
+	manager := NewMCPManager(registry)
+	defer manager.Close()
+
+	failing, _ := NewClient(&ClientConfig{Name: "failing", Endpoint: server.URL})
+	working, _ := NewClient(&ClientConfig{Name: "working", Endpoint: healthy.URL})
+	manager.AddClient(failing, &AdapterConfig{Prefix: "mcp_failing_"})
+	manager.AddClient(working, &AdapterConfig{Prefix: "mcp_working_"})
+
+	if err := manager.ConnectAll(context.Background()); err == nil {
+		t.Error("Expected an error for the server that is down")
+	}
+	if !working.IsConnected() {
+		t.Error("Expected the other server to connect anyway")
+	}
+	if status := failing.GetStatus(); status.State != StateError {
+		t.Errorf("Expected state %s, got %s", StateError, status.State)
+	}
+
+	down.Store(false)
+	manager.CheckHealth(&HealthConfig{Interval: time.Hour, Timeout: time.Second, MaxBackoff: time.Hour})
+	if !failing.IsConnected() {
+		t.Error("Expected a client that failed to connect to be retried")
+	}
+	if _, ok := registry.Get("mcp_failing_echo"); !ok {
+		t.Error("Expected its tools to be registered once connected")
+	}
+
+	manager.DisconnectClient("working")
+	manager.CheckHealth(&HealthConfig{Interval: time.Hour, Timeout: time.Second, MaxBackoff: time.Hour})
+	if working.IsConnected() {
+		t.Error("Expected a client disconnected on purpose to stay disconnected")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/wjffsx/miniclaw_go/internal/tools"
@@ -22,6 +23,20 @@ type Protocol interface {
 	ListPrompts(ctx context.Context) ([]map[string]interface{}, error)
 	GetPrompt(ctx context.Context, name string, args map[string]interface{}) (string, error)
 	SendNotification(ctx context.Context, method string, params map[string]interface{}) error
+	SetNotificationHandler(handler NotificationHandler)
+	Ping(ctx context.Context) error
+}
+
+type NotificationHandler func(method string, params json.RawMessage)
+
+type Transport interface {
+	sendRequest(ctx context.Context, method string, payload map[string]interface{}) ([]byte, error)
+	sendNotification(ctx context.Context, method string, payload map[string]interface{}) error
+	Close() error
+}
+
+type notificationSource interface {
+	setNotificationHandler(handler NotificationHandler)
 }
 
 type HTTPTransport struct {
@@ -76,9 +91,19 @@ func (t *HTTPTransport) sendRequest(ctx context.Context, method string, payload
 	return responseBody, nil
 }
 
+func (t *HTTPTransport) sendNotification(ctx context.Context, method string, payload map[string]interface{}) error {
+	_, err := t.sendRequest(ctx, method, payload)
+	return err
+}
+
+func (t *HTTPTransport) Close() error {
+	return nil
+}
+
 type JSONRPCProtocol struct {
-	transport *HTTPTransport
-	requestID int
+	transport Transport
+	// requestID is shared by tool calls and health checks running at once.
+	requestID atomic.Int64
 }
 
 func NewProtocol(config *ClientConfig) (Protocol, error) {
@@ -86,20 +111,58 @@ func NewProtocol(config *ClientConfig) (Protocol, error) {
 		return nil, fmt.Errorf("config cannot be nil")
 	}
 
-	if config.Endpoint == "" {
-		return nil, fmt.Errorf("endpoint cannot be empty")
-	}
-
 	timeout := 30
 	if config.Timeout > 0 {
 		timeout = config.Timeout
 	}
 
-	transport := NewHTTPTransport(config.Endpoint, config.Headers, timeout)
+	var transport Transport
+
+	switch config.FixtureMode {
+	case "":
+	case FixtureModeReplay:
+		if config.Fixture == "" {
+			return nil, fmt.Errorf("fixture path cannot be empty in replay mode")
+		}
+		fixture, err := LoadFixture(config.Fixture)
+		if err != nil {
+			return nil, err
+		}
+		return &JSONRPCProtocol{
+			transport: NewReplayTransport(fixture),
+		}, nil
+	case FixtureModeRecord:
+		if config.Fixture == "" {
+			return nil, fmt.Errorf("fixture path cannot be empty in record mode")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported fixture mode: %s", config.FixtureMode)
+	}
+
+	switch config.Transport {
+	case "stdio":
+		if config.Command == "" {
+			return nil, fmt.Errorf("command cannot be empty for stdio transport")
+		}
+		transport = NewStdioTransport(config, timeout)
+	case "sse", "streamable_http":
+		if config.Endpoint == "" {
+			return nil, fmt.Errorf("endpoint cannot be empty")
+		}
+		transport = NewSSETransport(config, timeout)
+	default:
+		if config.Endpoint == "" {
+			return nil, fmt.Errorf("endpoint cannot be empty")
+		}
+		transport = NewHTTPTransport(config.Endpoint, config.Headers, timeout)
+	}
+
+	if config.FixtureMode == FixtureModeRecord {
+		transport = NewRecordingTransport(transport, config.Name, config.Fixture)
+	}
 
 	return &JSONRPCProtocol{
 		transport: transport,
-		requestID: 0,
 	}, nil
 }
 
@@ -125,11 +188,20 @@ func (p *JSONRPCProtocol) Connect(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize MCP connection: %w", err)
 	}
 
+	initialized := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/initialized",
+	}
+
+	if err := p.transport.sendNotification(ctx, "notifications/initialized", initialized); err != nil {
+		return fmt.Errorf("failed to send initialized notification: %w", err)
+	}
+
 	return nil
 }
 
 func (p *JSONRPCProtocol) Close() error {
-	return nil
+	return p.transport.Close()
 }
 
 func (p *JSONRPCProtocol) ListTools(ctx context.Context) ([]*MCPTool, error) {
@@ -373,15 +445,35 @@ func (p *JSONRPCProtocol) SendNotification(ctx context.Context, method string, p
 		"params":  params,
 	}
 
-	_, err := p.transport.sendRequest(ctx, method, payload)
-	if err != nil {
+	if err := p.transport.sendNotification(ctx, method, payload); err != nil {
 		return fmt.Errorf("failed to send notification: %w", err)
 	}
 
 	return nil
 }
 
+// Ping checks that the server still answers. Any response counts, including
+// an error from a server that does not implement ping.
+func (p *JSONRPCProtocol) Ping(ctx context.Context) error {
+	payload := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      p.nextRequestID(),
+		"method":  "ping",
+	}
+
+	if _, err := p.transport.sendRequest(ctx, "ping", payload); err != nil {
+		return fmt.Errorf("failed to ping MCP server: %w", err)
+	}
+
+	return nil
+}
+
+func (p *JSONRPCProtocol) SetNotificationHandler(handler NotificationHandler) {
+	if source, ok := p.transport.(notificationSource); ok {
+		source.setNotificationHandler(handler)
+	}
+}
+
 func (p *JSONRPCProtocol) nextRequestID() int {
-	p.requestID++
-	return p.requestID
+	return int(p.requestID.Add(1))
 }
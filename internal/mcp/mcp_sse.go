@@ -0,0 +1,631 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionHeader     = "Mcp-Session-Id"
+	maxSSERetryDelay  = 30 * time.Second
+	sseEndpointWait   = 10 * time.Second
+	sseCloseTimeout   = 5 * time.Second
+	eventStreamType   = "text/event-stream"
+	jsonContentType   = "application/json"
+	sseAcceptHeader   = jsonContentType + ", " + eventStreamType
+	sseMaxLineSize    = 10 * 1024 * 1024
+	sseInitialBufSize = 64 * 1024
+)
+
+var (
+	errStreamUnsupported = errors.New("server does not support event streams")
+	errSessionExpired    = errors.New("MCP session expired")
+)
+
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry int
+}
+
+func readSSE(r io.Reader, handle func(event sseEvent) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, sseInitialBufSize), sseMaxLineSize)
+
+	var event sseEvent
+	var data []string
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			if len(data) > 0 || event.Event != "" {
+				event.Data = strings.Join(data, "\n")
+				if !handle(event) {
+					return nil
+				}
+			}
+			event = sseEvent{}
+			data = data[:0]
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				event.Retry = ms
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+type SSETransport struct {
+	client     *http.Client
+	endpoint   string
+	headers    map[string]string
+	timeout    time.Duration
+	legacy     bool
+	retryDelay time.Duration
+	name       string
+	logger     *slog.Logger
+
+	mu           sync.Mutex
+	sessionID    string
+	postURL      string
+	lastEventID  string
+	initPayload  map[string]interface{}
+	handler      NotificationHandler
+	streamCancel context.CancelFunc
+	endpointCh   chan struct{}
+	closed       bool
+
+	pending   map[string]chan []byte
+	pendingMu sync.Mutex
+}
+
+func NewSSETransport(config *ClientConfig, timeout int) *SSETransport {
+	retryDelay := time.Duration(config.RetryDelay) * time.Second
+	if retryDelay <= 0 {
+		retryDelay = time.Second
+	}
+
+	return &SSETransport{
+		client:     &http.Client{},
+		endpoint:   config.Endpoint,
+		headers:    config.Headers,
+		timeout:    time.Duration(timeout) * time.Second,
+		legacy:     config.Transport == "sse",
+		retryDelay: retryDelay,
+		name:       config.Name,
+		logger:     config.logger(),
+		pending:    make(map[string]chan []byte),
+	}
+}
+
+func (t *SSETransport) setNotificationHandler(handler NotificationHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handler = handler
+}
+
+func (t *SSETransport) SessionID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID
+}
+
+func (t *SSETransport) sendRequest(ctx context.Context, method string, payload map[string]interface{}) ([]byte, error) {
+	if method == "initialize" {
+		t.mu.Lock()
+		t.initPayload = payload
+		t.mu.Unlock()
+	}
+
+	if t.legacy {
+		return t.sendLegacyRequest(ctx, payload)
+	}
+
+	response, err := t.sendStreamableRequest(ctx, payload)
+	if errors.Is(err, errSessionExpired) && method != "initialize" {
+		t.logger.Info("MCP session expired, re-initializing")
+		if err := t.reinitialize(ctx); err != nil {
+			return nil, err
+		}
+		response, err = t.sendStreamableRequest(ctx, payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if method == "initialize" {
+		t.startStream()
+	}
+
+	return response, nil
+}
+
+func (t *SSETransport) reinitialize(ctx context.Context) error {
+	t.mu.Lock()
+	t.sessionID = ""
+	initPayload := t.initPayload
+	t.mu.Unlock()
+
+	if initPayload == nil {
+		return errSessionExpired
+	}
+
+	if _, err := t.sendStreamableRequest(ctx, initPayload); err != nil {
+		return fmt.Errorf("failed to re-initialize MCP session: %w", err)
+	}
+
+	initialized := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/initialized",
+	}
+
+	return t.sendNotification(ctx, "notifications/initialized", initialized)
+}
+
+func (t *SSETransport) sendStreamableRequest(ctx context.Context, payload map[string]interface{}) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	resp, err := t.post(ctx, t.endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if sessionID := resp.Header.Get(sessionHeader); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), eventStreamType) {
+		id := fmt.Sprintf("%v", payload["id"])
+
+		var response []byte
+		err := readSSE(resp.Body, func(event sseEvent) bool {
+			if event.Data == "" {
+				return true
+			}
+			if responseID, ok := t.dispatch([]byte(event.Data)); ok && responseID == id {
+				response = []byte(event.Data)
+				return false
+			}
+			return true
+		})
+		if response != nil {
+			return response, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event stream: %w", err)
+		}
+		return nil, fmt.Errorf("event stream closed before response")
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return body, nil
+}
+
+func (t *SSETransport) sendLegacyRequest(ctx context.Context, payload map[string]interface{}) ([]byte, error) {
+	postURL, err := t.waitForEndpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id := fmt.Sprintf("%v", payload["id"])
+	responseCh := make(chan []byte, 1)
+
+	t.pendingMu.Lock()
+	t.pending[id] = responseCh
+	t.pendingMu.Unlock()
+
+	defer func() {
+		t.pendingMu.Lock()
+		delete(t.pending, id)
+		t.pendingMu.Unlock()
+	}()
+
+	resp, err := t.post(ctx, postURL, payload)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-responseCh:
+		return response, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("request timed out after %v", t.timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *SSETransport) sendNotification(ctx context.Context, method string, payload map[string]interface{}) error {
+	target := t.endpoint
+	if t.legacy {
+		postURL, err := t.waitForEndpoint(ctx)
+		if err != nil {
+			return err
+		}
+		target = postURL
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	resp, err := t.post(ctx, target, payload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (t *SSETransport) post(ctx context.Context, target string, payload map[string]interface{}) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", jsonContentType)
+	req.Header.Set("Accept", sseAcceptHeader)
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound && req.Header.Get(sessionHeader) != "" {
+		resp.Body.Close()
+		return nil, errSessionExpired
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("request failed with status: %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+func (t *SSETransport) setHeaders(req *http.Request) {
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+
+	if sessionID != "" {
+		req.Header.Set(sessionHeader, sessionID)
+	}
+}
+
+func (t *SSETransport) waitForEndpoint(ctx context.Context) (string, error) {
+	t.startStream()
+
+	t.mu.Lock()
+	endpointCh := t.endpointCh
+	t.mu.Unlock()
+
+	timer := time.NewTimer(sseEndpointWait)
+	defer timer.Stop()
+
+	select {
+	case <-endpointCh:
+	case <-timer.C:
+		return "", fmt.Errorf("timed out waiting for SSE endpoint from %s", t.name)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.postURL == "" {
+		return "", fmt.Errorf("transport closed")
+	}
+
+	return t.postURL, nil
+}
+
+func (t *SSETransport) startStream() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed || t.streamCancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.streamCancel = cancel
+	t.endpointCh = make(chan struct{})
+
+	go t.listen(ctx)
+}
+
+func (t *SSETransport) listen(ctx context.Context) {
+	t.mu.Lock()
+	delay := t.retryDelay
+	t.mu.Unlock()
+
+	for {
+		connected, err := t.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if errors.Is(err, errStreamUnsupported) {
+			t.logger.Info("MCP server does not offer a notification stream")
+			return
+		}
+
+		if errors.Is(err, errSessionExpired) {
+			if err := t.reinitialize(ctx); err != nil {
+				t.logger.Error("Failed to re-initialize MCP session", "error", err)
+			}
+		} else if err != nil {
+			t.logger.Warn("MCP event stream disconnected", "error", err)
+		}
+
+		t.mu.Lock()
+		if connected {
+			delay = t.retryDelay
+		}
+		if t.legacy {
+			t.postURL = ""
+			t.endpointCh = make(chan struct{})
+		}
+		t.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxSSERetryDelay {
+			delay = maxSSERetryDelay
+		}
+	}
+}
+
+func (t *SSETransport) stream(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", t.endpoint, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", eventStreamType)
+	t.setHeaders(req)
+
+	t.mu.Lock()
+	if t.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", t.lastEventID)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusMethodNotAllowed && !t.legacy {
+		return false, errStreamUnsupported
+	}
+
+	if resp.StatusCode == http.StatusNotFound && req.Header.Get(sessionHeader) != "" {
+		return false, errSessionExpired
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, fmt.Errorf("stream request failed with status: %d", resp.StatusCode)
+	}
+
+	err = readSSE(resp.Body, func(event sseEvent) bool {
+		t.mu.Lock()
+		if event.ID != "" {
+			t.lastEventID = event.ID
+		}
+		if event.Retry > 0 {
+			t.retryDelay = time.Duration(event.Retry) * time.Millisecond
+		}
+		t.mu.Unlock()
+
+		if event.Event == "endpoint" {
+			t.setEndpoint(event.Data)
+			return true
+		}
+
+		if event.Data != "" {
+			t.dispatch([]byte(event.Data))
+		}
+		return true
+	})
+
+	if err == nil {
+		err = io.EOF
+	}
+
+	return true, err
+}
+
+func (t *SSETransport) setEndpoint(data string) {
+	base, err := url.Parse(t.endpoint)
+	if err != nil {
+		t.logger.Error("Invalid MCP endpoint", "endpoint", t.endpoint, "error", err)
+		return
+	}
+
+	ref, err := url.Parse(strings.TrimSpace(data))
+	if err != nil {
+		t.logger.Warn("Invalid SSE endpoint event", "error", err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.postURL = base.ResolveReference(ref).String()
+
+	select {
+	case <-t.endpointCh:
+	default:
+		close(t.endpointCh)
+	}
+}
+
+func (t *SSETransport) dispatch(data []byte) (string, bool) {
+	var message struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.logger.Warn("Invalid message from MCP server", "error", err)
+		return "", false
+	}
+
+	if message.Method != "" {
+		if len(message.ID) > 0 {
+			go t.replyToServerRequest(message.ID, message.Method)
+			return "", false
+		}
+
+		t.mu.Lock()
+		handler := t.handler
+		t.mu.Unlock()
+
+		if handler != nil {
+			handler(message.Method, message.Params)
+		}
+		return "", false
+	}
+
+	if len(message.ID) == 0 {
+		return "", false
+	}
+
+	id := string(bytes.Trim(message.ID, `"`))
+
+	t.pendingMu.Lock()
+	responseCh, ok := t.pending[id]
+	t.pendingMu.Unlock()
+
+	if ok {
+		select {
+		case responseCh <- data:
+		default:
+		}
+	}
+
+	return id, true
+}
+
+func (t *SSETransport) replyToServerRequest(id json.RawMessage, method string) {
+	reply := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+	}
+
+	if method == "ping" {
+		reply["result"] = map[string]interface{}{}
+	} else {
+		reply["error"] = map[string]interface{}{
+			"code":    -32601,
+			"message": "method not found: " + method,
+		}
+	}
+
+	if err := t.sendNotification(context.Background(), method, reply); err != nil {
+		t.logger.Error("Failed to reply to MCP server request", "method", method, "error", err)
+	}
+}
+
+func (t *SSETransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	cancel := t.streamCancel
+	sessionID := t.sessionID
+	t.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+
+	if t.legacy || sessionID == "" {
+		return nil
+	}
+
+	ctx, cancelDelete := context.WithTimeout(context.Background(), sseCloseTimeout)
+	defer cancelDelete()
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", t.endpoint, nil)
+	if err != nil {
+		return nil
+	}
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.logger.Warn("Failed to terminate MCP session", "error", err)
+		return nil
+	}
+	resp.Body.Close()
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type streamableServer struct {
+	mu            sync.Mutex
+	sessions      int
+	sessionID     string
+	toolNames     []string
+	notify        chan string
+	deleted       bool
+	expireSession bool
+}
+
+func newStreamableServer() *streamableServer {
+	return &streamableServer{
+		toolNames: []string{"echo"},
+		notify:    make(chan string, 1),
+	}
+}
+
+func (s *streamableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case "GET":
+		if r.Header.Get(sessionHeader) != s.sessionID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", eventStreamType)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		s.mu.Unlock()
+		defer s.mu.Lock()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case method := <-s.notify:
+				fmt.Fprintf(w, "id: 1\ndata: {\"jsonrpc\":\"2.0\",\"method\":%q}\n\n", method)
+				w.(http.Flusher).Flush()
+			}
+		}
+
+	case "DELETE":
+		s.deleted = true
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	json.Unmarshal(body, &request)
+
+	if request.Method == "initialize" {
+		s.sessions++
+		s.sessionID = fmt.Sprintf("session-%d", s.sessions)
+		w.Header().Set(sessionHeader, s.sessionID)
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{}}`, request.ID)
+		return
+	}
+
+	if s.expireSession || r.Header.Get(sessionHeader) != s.sessionID {
+		s.expireSession = false
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(request.ID) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	tools := make([]string, 0, len(s.toolNames))
+	for _, name := range s.toolNames {
+		tools = append(tools, fmt.Sprintf(`{"name":%q,"description":"test","inputSchema":{"type":"object"}}`, name))
+	}
+
+	w.Header().Set("Content-Type", eventStreamType)
+	fmt.Fprint(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{\"level\":\"info\"}}\n\n")
+	fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"id\":%s,\"result\":{\"tools\":[%s]}}\n\n", request.ID, strings.Join(tools, ","))
+}
+
+func TestReadSSE(t *testing.T) {
+	input := ": comment\nid: 7\nevent: message\ndata: line one\ndata: line two\nretry: 1500\n\ndata: second\n\n"
+
+	var events []sseEvent
+	err := readSSE(strings.NewReader(input), func(event sseEvent) bool {
+		events = append(events, event)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+
+	if events[0].ID != "7" || events[0].Event != "message" || events[0].Retry != 1500 {
+		t.Errorf("Unexpected first event: %+v", events[0])
+	}
+
+	if events[0].Data != "line one\nline two" {
+		t.Errorf("Expected multi-line data, got %q", events[0].Data)
+	}
+
+	if events[1].Data != "second" {
+		t.Errorf("Expected second event data, got %q", events[1].Data)
+	}
+}
+
+func TestNewProtocolSSEEmptyEndpoint(t *testing.T) {
+	_, err := NewProtocol(&ClientConfig{Name: "test", Transport: "streamable_http"})
+	if err == nil {
+		t.Error("Expected error for empty endpoint")
+	}
+}
+
+func TestStreamableHTTPToolListChanged(t *testing.T) {
+	handler := newStreamableServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, err := NewClient(&ClientConfig{
+		Name:      "streamable",
+		Endpoint:  server.URL,
+		Transport: "streamable_http",
+		Timeout:   5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	if len(client.GetTools()) != 1 {
+		t.Fatalf("Expected 1 tool, got %d", len(client.GetTools()))
+	}
+
+	changed := make(chan struct{}, 1)
+	client.OnToolsChanged(func() {
+		changed <- struct{}{}
+	})
+
+	handler.mu.Lock()
+	handler.toolNames = append(handler.toolNames, "reverse")
+	handler.mu.Unlock()
+	handler.notify <- "notifications/tools/list_changed"
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected tool list change to be picked up")
+	}
+
+	if _, ok := client.GetTool("reverse"); !ok {
+		t.Error("Expected new tool to be available after refresh")
+	}
+
+	client.Close()
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	if !handler.deleted {
+		t.Error("Expected session to be terminated on close")
+	}
+}
+
+func TestStreamableHTTPSessionExpiry(t *testing.T) {
+	handler := newStreamableServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	protocol, err := NewProtocol(&ClientConfig{
+		Name:      "streamable",
+		Endpoint:  server.URL,
+		Transport: "streamable_http",
+		Timeout:   5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create protocol: %v", err)
+	}
+	defer protocol.Close()
+
+	ctx := context.Background()
+	if err := protocol.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	handler.mu.Lock()
+	handler.expireSession = true
+	handler.mu.Unlock()
+
+	tools, err := protocol.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("Expected request to succeed after re-initialization, got %v", err)
+	}
+
+	if len(tools) != 1 {
+		t.Errorf("Expected 1 tool, got %d", len(tools))
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	if handler.sessions != 2 {
+		t.Errorf("Expected 2 sessions, got %d", handler.sessions)
+	}
+}
+
+func TestLegacySSETransport(t *testing.T) {
+	responses := make(chan string, 10)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", eventStreamType)
+		fmt.Fprint(w, "event: endpoint\ndata: /messages?session=abc\n\n")
+		w.(http.Flusher).Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case response := <-responses:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", response)
+				w.(http.Flusher).Flush()
+			}
+		}
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session") != "abc" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+
+		if len(request.ID) > 0 {
+			result := `{}`
+			if request.Method == "tools/list" {
+				result = `{"tools":[{"name":"legacy","description":"test","inputSchema":{}}]}`
+			}
+			responses <- fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`, request.ID, result)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	protocol, err := NewProtocol(&ClientConfig{
+		Name:      "legacy",
+		Endpoint:  server.URL + "/sse",
+		Transport: "sse",
+		Timeout:   5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create protocol: %v", err)
+	}
+	defer protocol.Close()
+
+	ctx := context.Background()
+	if err := protocol.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	tools, err := protocol.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list tools: %v", err)
+	}
+
+	if len(tools) != 1 || tools[0].Name != "legacy" {
+		t.Errorf("Expected legacy tool, got %+v", tools)
+	}
+}
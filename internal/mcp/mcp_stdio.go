@@ -0,0 +1,463 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const maxStdioMessageSize = 10 * 1024 * 1024
+
+// stableUptime is how long a server has to stay up before it exits for its
+// earlier crashes to stop counting against maxRetries.
+const stableUptime = time.Minute
+
+type StdioTransport struct {
+	command    string
+	args       []string
+	env        map[string]string
+	workDir    string
+	name       string
+	timeout    time.Duration
+	maxRetries int
+	retryDelay time.Duration
+	logger     *slog.Logger
+	now        func() time.Time
+
+	mu          sync.Mutex
+	startMu     sync.Mutex
+	writeMu     sync.Mutex
+	cmd         *exec.Cmd
+	stdin       io.WriteCloser
+	running     bool
+	closed      bool
+	restarts    int
+	startedAt   time.Time
+	uptime      time.Duration
+	pending     map[string]chan []byte
+	pendingMu   sync.Mutex
+	done        chan struct{}
+	initPayload map[string]interface{}
+	handler     NotificationHandler
+}
+
+func NewStdioTransport(config *ClientConfig, timeout int) *StdioTransport {
+	maxRetries := config.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+
+	retryDelay := time.Duration(config.RetryDelay) * time.Second
+	if retryDelay <= 0 {
+		retryDelay = time.Second
+	}
+
+	return &StdioTransport{
+		command:    config.Command,
+		args:       config.Args,
+		env:        config.Env,
+		workDir:    config.WorkDir,
+		name:       config.Name,
+		timeout:    time.Duration(timeout) * time.Second,
+		maxRetries: maxRetries,
+		retryDelay: retryDelay,
+		logger:     config.logger(),
+		now:        time.Now,
+		pending:    make(map[string]chan []byte),
+	}
+}
+
+func (t *StdioTransport) setNotificationHandler(handler NotificationHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handler = handler
+}
+
+func (t *StdioTransport) start() error {
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Dir = t.workDir
+	cmd.Env = os.Environ()
+	for key, value := range t.env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdout: %w", err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stderr: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start MCP server %s: %w", t.command, err)
+	}
+
+	done := make(chan struct{})
+	readDone := make(chan struct{})
+
+	t.cmd = cmd
+	t.stdin = stdin
+	t.done = done
+	t.running = true
+	t.startedAt = t.now()
+
+	go func() {
+		t.readLoop(stdout)
+		close(readDone)
+	}()
+	go t.logStderr(stderr)
+	go t.wait(cmd, readDone, done)
+
+	t.logger.Info("MCP stdio server started", "command", t.command, "pid", cmd.Process.Pid)
+
+	return nil
+}
+
+// ensureRunning starts the server, or restarts it after a crash. startMu
+// keeps concurrent callers from starting it twice while t.mu stays free for
+// writes and responses during the retry delay.
+func (t *StdioTransport) ensureRunning(ctx context.Context) error {
+	t.startMu.Lock()
+	defer t.startMu.Unlock()
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return fmt.Errorf("transport closed")
+	}
+
+	if t.running {
+		t.mu.Unlock()
+		return nil
+	}
+
+	restarted := t.cmd != nil
+	if restarted {
+		if t.uptime >= stableUptime {
+			t.restarts = 0
+		}
+		if t.restarts >= t.maxRetries {
+			t.mu.Unlock()
+			return fmt.Errorf("MCP server %s exceeded %d restarts", t.name, t.maxRetries)
+		}
+		t.restarts++
+		t.logger.Warn("Restarting MCP stdio server", "attempt", t.restarts, "max_retries", t.maxRetries)
+	}
+	t.mu.Unlock()
+
+	if restarted {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(t.retryDelay):
+		}
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return fmt.Errorf("transport closed")
+	}
+
+	if err := t.start(); err != nil {
+		t.mu.Unlock()
+		return err
+	}
+
+	initPayload := t.initPayload
+	t.mu.Unlock()
+
+	if restarted && initPayload != nil {
+		if _, err := t.roundTrip(ctx, initPayload); err != nil {
+			return fmt.Errorf("failed to re-initialize MCP server: %w", err)
+		}
+		initialized := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "notifications/initialized",
+		}
+		if err := t.write(initialized); err != nil {
+			return fmt.Errorf("failed to re-initialize MCP server: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (t *StdioTransport) sendRequest(ctx context.Context, method string, payload map[string]interface{}) ([]byte, error) {
+	if method == "initialize" {
+		t.mu.Lock()
+		t.initPayload = payload
+		t.mu.Unlock()
+	}
+
+	if err := t.ensureRunning(ctx); err != nil {
+		return nil, err
+	}
+
+	return t.roundTrip(ctx, payload)
+}
+
+func (t *StdioTransport) roundTrip(ctx context.Context, payload map[string]interface{}) ([]byte, error) {
+	id := fmt.Sprintf("%v", payload["id"])
+	responseCh := make(chan []byte, 1)
+
+	t.pendingMu.Lock()
+	t.pending[id] = responseCh
+	t.pendingMu.Unlock()
+
+	defer func() {
+		t.pendingMu.Lock()
+		delete(t.pending, id)
+		t.pendingMu.Unlock()
+	}()
+
+	t.mu.Lock()
+	done := t.done
+	t.mu.Unlock()
+
+	if err := t.write(payload); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-responseCh:
+		return response, nil
+	case <-done:
+		return nil, fmt.Errorf("MCP server %s exited before responding", t.name)
+	case <-timer.C:
+		return nil, fmt.Errorf("request timed out after %v", t.timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *StdioTransport) sendNotification(ctx context.Context, method string, payload map[string]interface{}) error {
+	if err := t.ensureRunning(ctx); err != nil {
+		return err
+	}
+
+	return t.write(payload)
+}
+
+func (t *StdioTransport) write(payload map[string]interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	data = append(data, '\n')
+
+	t.mu.Lock()
+	stdin := t.stdin
+	t.mu.Unlock()
+
+	if stdin == nil {
+		return fmt.Errorf("MCP server %s is not running", t.name)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if _, err := stdin.Write(data); err != nil {
+		return fmt.Errorf("failed to write to MCP server: %w", err)
+	}
+
+	return nil
+}
+
+func (t *StdioTransport) readLoop(stdout io.Reader) {
+	reader := bufio.NewReaderSize(stdout, 64*1024)
+
+	for {
+		line, size, err := readLimitedLine(reader, maxStdioMessageSize)
+		if line == nil && size > 0 {
+			t.logger.Warn("MCP server sent oversized message, dropping", "bytes", size)
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			t.dispatch(trimmed)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readLimitedLine reads up to the next newline. Lines longer than limit are
+// discarded as they are read and returned as nil along with their size.
+func readLimitedLine(reader *bufio.Reader, limit int) ([]byte, int, error) {
+	var line []byte
+	size := 0
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		size += len(chunk)
+		if size <= limit {
+			line = append(line, chunk...)
+		} else {
+			line = nil
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if size > limit {
+			return nil, size, err
+		}
+		return line, size, err
+	}
+}
+
+func (t *StdioTransport) dispatch(line []byte) {
+	var message struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+
+	if err := json.Unmarshal(line, &message); err != nil {
+		t.logger.Debug("MCP server output", "line", string(line))
+		return
+	}
+
+	if message.Method != "" {
+		if len(message.ID) > 0 {
+			t.replyToServerRequest(message.ID, message.Method)
+			return
+		}
+
+		t.mu.Lock()
+		handler := t.handler
+		t.mu.Unlock()
+
+		if handler != nil {
+			handler(message.Method, message.Params)
+		}
+		return
+	}
+
+	if len(message.ID) == 0 {
+		return
+	}
+
+	id := string(bytes.Trim(message.ID, `"`))
+
+	t.pendingMu.Lock()
+	responseCh, ok := t.pending[id]
+	t.pendingMu.Unlock()
+
+	if !ok {
+		t.logger.Warn("Dropping response for unknown request", "id", id)
+		return
+	}
+
+	response := make([]byte, len(line))
+	copy(response, line)
+
+	select {
+	case responseCh <- response:
+	default:
+	}
+}
+
+func (t *StdioTransport) replyToServerRequest(id json.RawMessage, method string) {
+	reply := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+	}
+
+	if method == "ping" {
+		reply["result"] = map[string]interface{}{}
+	} else {
+		reply["error"] = map[string]interface{}{
+			"code":    -32601,
+			"message": "method not found: " + method,
+		}
+	}
+
+	if err := t.write(reply); err != nil {
+		t.logger.Error("Failed to reply to MCP server request", "method", method, "error", err)
+	}
+}
+
+func (t *StdioTransport) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		t.logger.Info("MCP server stderr", "line", scanner.Text())
+	}
+}
+
+func (t *StdioTransport) wait(cmd *exec.Cmd, readDone, done chan struct{}) {
+	<-readDone
+	err := cmd.Wait()
+
+	t.mu.Lock()
+	if t.cmd == cmd {
+		t.running = false
+		t.stdin = nil
+		t.uptime = t.now().Sub(t.startedAt)
+	}
+	closed := t.closed
+	t.mu.Unlock()
+
+	close(done)
+
+	if !closed {
+		t.logger.Warn("MCP stdio server exited", "error", err)
+	}
+}
+
+func (t *StdioTransport) IsRunning() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running
+}
+
+func (t *StdioTransport) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	cmd := t.cmd
+	stdin := t.stdin
+	done := t.done
+	running := t.running
+	t.mu.Unlock()
+
+	if !running || cmd == nil {
+		return nil
+	}
+
+	if stdin != nil {
+		stdin.Close()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+		<-done
+	}
+
+	return nil
+}
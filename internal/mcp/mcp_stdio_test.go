@@ -0,0 +1,221 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStdioHelperProcess(t *testing.T) {
+	if os.Getenv("MCP_STDIO_HELPER") != "1" {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "helper started")
+	fmt.Println("not json")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil || len(request.ID) == 0 {
+			continue
+		}
+
+		switch request.Method {
+		case "tools/list":
+			fmt.Printf(`{"jsonrpc":"2.0","id":%s,"result":{"tools":[{"name":"echo","description":"Echo input","inputSchema":{"type":"object"}}]}}`+"\n", request.ID)
+		case "tools/call":
+			fmt.Printf(`{"jsonrpc":"2.0","id":%s,"result":{"content":[{"type":"text","text":"pong"}]}}`+"\n", request.ID)
+		case "crash":
+			os.Exit(1)
+		default:
+			fmt.Printf(`{"jsonrpc":"2.0","id":%s,"result":{}}`+"\n", request.ID)
+		}
+	}
+	os.Exit(0)
+}
+
+func newHelperConfig() *ClientConfig {
+	return &ClientConfig{
+		Name:      "helper",
+		Transport: "stdio",
+		Command:   os.Args[0],
+		Args:      []string{"-test.run=TestStdioHelperProcess"},
+		Env:       map[string]string{"MCP_STDIO_HELPER": "1"},
+		Timeout:   5,
+	}
+}
+
+func TestNewProtocolStdioEmptyCommand(t *testing.T) {
+	config := &ClientConfig{
+		Name:      "test",
+		Transport: "stdio",
+	}
+
+	_, err := NewProtocol(config)
+
+	if err == nil {
+		t.Error("Expected error for empty command")
+	}
+}
+
+func TestStdioProtocolListAndCallTools(t *testing.T) {
+	protocol, err := NewProtocol(newHelperConfig())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer protocol.Close()
+
+	ctx := context.Background()
+	if err := protocol.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	mcpTools, err := protocol.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list tools: %v", err)
+	}
+
+	if len(mcpTools) != 1 || mcpTools[0].Name != "echo" {
+		t.Fatalf("Expected echo tool, got %+v", mcpTools)
+	}
+
+	call, err := protocol.CallTool(ctx, "echo", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed to call tool: %v", err)
+	}
+
+	if call.Error != "" {
+		t.Fatalf("Expected no tool error, got %s", call.Error)
+	}
+
+	if call.Result != "pong\n" {
+		t.Errorf("Expected result 'pong', got %q", call.Result)
+	}
+}
+
+func TestStdioTransportRestartsAfterCrash(t *testing.T) {
+	transport := NewStdioTransport(newHelperConfig(), 5)
+	defer transport.Close()
+
+	ctx := context.Background()
+	initialize := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "initialize"}
+	if _, err := transport.sendRequest(ctx, "initialize", initialize); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	crash := map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "crash"}
+	if _, err := transport.sendRequest(ctx, "crash", crash); err == nil {
+		t.Fatal("Expected error when server crashes")
+	}
+
+	if transport.IsRunning() {
+		t.Fatal("Expected transport to report the crashed server as stopped")
+	}
+
+	list := map[string]interface{}{"jsonrpc": "2.0", "id": 3, "method": "tools/list"}
+	response, err := transport.sendRequest(ctx, "tools/list", list)
+	if err != nil {
+		t.Fatalf("Expected request to succeed after restart, got %v", err)
+	}
+
+	if !json.Valid(response) {
+		t.Errorf("Expected valid JSON response, got %s", response)
+	}
+
+	if transport.restarts != 1 {
+		t.Errorf("Expected 1 restart, got %d", transport.restarts)
+	}
+}
+
+func TestStdioTransportClosed(t *testing.T) {
+	transport := NewStdioTransport(newHelperConfig(), 5)
+	transport.Close()
+
+	payload := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "ping"}
+	if _, err := transport.sendRequest(context.Background(), "ping", payload); err == nil {
+		t.Error("Expected error after close")
+	}
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestStdioTransportForgetsCrashesAfterStableUptime(t *testing.T) {
+	config := newHelperConfig()
+	config.MaxRetries = 1
+	transport := NewStdioTransport(config, 5)
+	transport.retryDelay = time.Millisecond
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	transport.now = clock.Now
+	defer transport.Close()
+
+	ctx := context.Background()
+	crash := func() {
+		t.Helper()
+		request := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "crash"}
+		if _, err := transport.sendRequest(ctx, "crash", request); err == nil || strings.Contains(err.Error(), "exceeded") {
+			t.Fatalf("Expected the server to crash, got %v", err)
+		}
+	}
+	list := func() error {
+		request := map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "tools/list"}
+		_, err := transport.sendRequest(ctx, "tools/list", request)
+		return err
+	}
+
+	crash()
+	if err := list(); err != nil {
+		t.Fatalf("Expected the first restart to be allowed, got %v", err)
+	}
+
+	clock.Advance(stableUptime)
+	crash()
+	if err := list(); err != nil {
+		t.Fatalf("Expected a server that ran for a while to be restarted, got %v", err)
+	}
+
+	crash()
+	clock.Advance(10 * stableUptime)
+	if err := list(); err == nil || !strings.Contains(err.Error(), "exceeded") {
+		t.Fatalf("Expected a server that crashed right away to count against the limit however late it is called again, got %v", err)
+	}
+}
+
+func TestReadLimitedLine(t *testing.T) {
+	input := strings.Repeat("x", 100) + "\n" + "ok\n"
+	reader := bufio.NewReaderSize(strings.NewReader(input), 16)
+
+	line, size, err := readLimitedLine(reader, 50)
+	if line != nil || size != 101 || err != nil {
+		t.Fatalf("Expected oversized line to be dropped, got %q, %d, %v", line, size, err)
+	}
+
+	line, _, err = readLimitedLine(reader, 50)
+	if string(line) != "ok\n" || err != nil {
+		t.Errorf("Expected next line to be read, got %q, %v", line, err)
+	}
+}
@@ -0,0 +1,320 @@
+// Package plugins runs tool plugins, executables built with pkg/plugin, and
+// registers their tools alongside the built-in ones.
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+const (
+	defaultStartTimeout = 10 * time.Second
+	reloadDelay         = 500 * time.Millisecond
+	// stableUptime is how long a plugin has to run before it is restarted
+	// when it crashes.
+	stableUptime = time.Minute
+)
+
+type Config struct {
+	Directory    string
+	StartTimeout time.Duration
+	Logger       *slog.Logger
+}
+
+type loaded struct {
+	plugin  *Plugin
+	tools   []string
+	started time.Time
+}
+
+// Loader starts the plugins in a directory and keeps the tool registry in
+// step with them.
+type Loader struct {
+	dir          string
+	startTimeout time.Duration
+	registry     *tools.ToolRegistry
+	logger       *slog.Logger
+
+	mu      sync.Mutex
+	plugins map[string]*loaded
+
+	timersMu sync.Mutex
+	timers   map[string]*time.Timer
+	watcher  *fsnotify.Watcher
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func NewLoader(cfg *Config, registry *tools.ToolRegistry) *Loader {
+	startTimeout := cfg.StartTimeout
+	if startTimeout <= 0 {
+		startTimeout = defaultStartTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Loader{
+		dir:          cfg.Directory,
+		startTimeout: startTimeout,
+		registry:     registry,
+		logger:       logging.Or(cfg.Logger, "plugins"),
+		plugins:      make(map[string]*loaded),
+		timers:       make(map[string]*time.Timer),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// LoadAll starts every plugin in the directory, creating it if needed. A
+// plugin that fails to start is logged and skipped.
+func (l *Loader) LoadAll(ctx context.Context) error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create plugins directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read plugins directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(l.dir, entry.Name())
+		if !isPlugin(path) {
+			continue
+		}
+		if err := l.Load(ctx, path); err != nil {
+			l.logger.Error("Failed to load plugin", "path", path, "error", err)
+		}
+	}
+
+	l.logger.Info("Plugins loaded", "dir", l.dir, "plugins", len(l.Plugins()))
+	return nil
+}
+
+// Load starts the plugin at path and registers its tools, replacing the
+// running one of that path once the new one is up. If it fails to start the
+// old one keeps running. Tools whose names are taken are skipped.
+func (l *Loader) Load(ctx context.Context, path string) error {
+	p, err := Start(ctx, path, l.startTimeout, l.logger)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	if l.ctx.Err() != nil {
+		l.mu.Unlock()
+		p.Close()
+		return fmt.Errorf("plugin loader closed")
+	}
+
+	old := l.remove(path)
+	entry := &loaded{plugin: p, started: time.Now()}
+	for _, tool := range p.Tools() {
+		if err := l.registry.Register(tool); err != nil {
+			l.logger.Warn("Skipping plugin tool", "plugin", p.Name(), "tool", tool.Name(), "error", err)
+			continue
+		}
+		entry.tools = append(entry.tools, tool.Name())
+	}
+	l.plugins[path] = entry
+	l.mu.Unlock()
+
+	l.logger.Info("Registered plugin tools", "plugin", p.Name(), "tools", strings.Join(entry.tools, ", "))
+
+	l.stop(old)
+	go l.supervise(path, entry)
+	return nil
+}
+
+// Unload removes the tools of the plugin at path and stops it.
+func (l *Loader) Unload(path string) {
+	l.mu.Lock()
+	entry := l.remove(path)
+	l.mu.Unlock()
+
+	l.stop(entry)
+}
+
+// remove takes the plugin at path out of the loader and unregisters its
+// tools. The caller holds l.mu and stops the plugin after releasing it.
+func (l *Loader) remove(path string) *loaded {
+	entry, ok := l.plugins[path]
+	if !ok {
+		return nil
+	}
+	delete(l.plugins, path)
+
+	for _, name := range entry.tools {
+		l.registry.Unregister(name)
+	}
+	return entry
+}
+
+func (l *Loader) stop(entry *loaded) {
+	if entry == nil {
+		return
+	}
+	if err := entry.plugin.Close(); err != nil {
+		l.logger.Warn("Failed to stop plugin", "plugin", entry.plugin.Name(), "error", err)
+	}
+	l.logger.Info("Unloaded plugin", "plugin", entry.plugin.Name(), "tools", len(entry.tools))
+}
+
+// supervise unregisters the tools of a plugin that exits on its own. One
+// that had been running for a while is started again; one that crashes soon
+// after starting stays unloaded until its file changes.
+func (l *Loader) supervise(path string, entry *loaded) {
+	select {
+	case <-l.ctx.Done():
+		return
+	case <-entry.plugin.Exited():
+	}
+
+	l.mu.Lock()
+	if l.plugins[path] != entry {
+		l.mu.Unlock()
+		return
+	}
+	l.remove(path)
+	l.mu.Unlock()
+
+	l.logger.Warn("Plugin crashed, unregistered its tools", "plugin", entry.plugin.Name(), "tools", len(entry.tools))
+	if time.Since(entry.started) >= stableUptime {
+		l.scheduleReload(path)
+	}
+}
+
+// Plugins returns the names of the running plugins.
+func (l *Loader) Plugins() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	names := make([]string, 0, len(l.plugins))
+	for _, entry := range l.plugins {
+		names = append(names, entry.plugin.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Watch reloads plugins that are added or replaced in the directory and
+// unloads removed ones, until Close.
+func (l *Loader) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(l.dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	l.watcher = watcher
+
+	go l.processEvents()
+
+	l.logger.Info("Plugin watcher started", "dir", l.dir)
+	return nil
+}
+
+func (l *Loader) processEvents() {
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case event, ok := <-l.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename|fsnotify.Chmod) != 0 {
+				l.scheduleReload(event.Name)
+			}
+		case err, ok := <-l.watcher.Errors:
+			if !ok {
+				return
+			}
+			l.logger.Error("Plugin watcher error", "error", err)
+		}
+	}
+}
+
+// scheduleReload reloads or unloads the plugin at path once the changes to
+// it have settled.
+func (l *Loader) scheduleReload(path string) {
+	l.timersMu.Lock()
+	defer l.timersMu.Unlock()
+
+	if timer, ok := l.timers[path]; ok {
+		timer.Reset(reloadDelay)
+		return
+	}
+	l.timers[path] = time.AfterFunc(reloadDelay, func() {
+		l.timersMu.Lock()
+		delete(l.timers, path)
+		l.timersMu.Unlock()
+
+		if l.ctx.Err() != nil {
+			return
+		}
+		if !isPlugin(path) {
+			l.Unload(path)
+			return
+		}
+		if err := l.Load(l.ctx, path); err != nil {
+			l.logger.Error("Failed to reload plugin", "path", path, "error", err)
+		}
+	})
+}
+
+// Close stops watching and stops every plugin.
+func (l *Loader) Close() {
+	l.cancel()
+	if l.watcher != nil {
+		l.watcher.Close()
+	}
+
+	l.timersMu.Lock()
+	for path, timer := range l.timers {
+		timer.Stop()
+		delete(l.timers, path)
+	}
+	l.timersMu.Unlock()
+
+	l.mu.Lock()
+	entries := make([]*loaded, 0, len(l.plugins))
+	for path := range l.plugins {
+		entries = append(entries, l.remove(path))
+	}
+	l.mu.Unlock()
+
+	for _, entry := range entries {
+		l.stop(entry)
+	}
+}
+
+// isPlugin reports whether path is an executable that may be a plugin.
+// Hidden files are skipped, such as those editors and copies leave behind.
+func isPlugin(path string) bool {
+	if strings.HasPrefix(filepath.Base(path), ".") {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,245 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+	"github.com/wjffsx/miniclaw_go/pkg/plugin"
+)
+
+type shoutTool struct{}
+
+func (shoutTool) Name() string        { return "shout" }
+func (shoutTool) Description() string { return "Upper-cases text" }
+func (shoutTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}}}`)
+}
+func (shoutTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	text, _ := params["text"].(string)
+	// Printing must not break the protocol.
+	fmt.Println("shouting", text)
+	return strings.ToUpper(text), nil
+}
+
+type brokenTool struct{}
+
+func (brokenTool) Name() string                { return "broken" }
+func (brokenTool) Description() string         { return "Always fails" }
+func (brokenTool) Parameters() json.RawMessage { return nil }
+func (brokenTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	return "", errors.New("out of order")
+}
+
+func TestPluginHelperProcess(t *testing.T) {
+	if os.Getenv("PLUGIN_HELPER") != "1" {
+		return
+	}
+	plugin.Serve(shoutTool{}, brokenTool{})
+}
+
+// writeScript puts an executable shell script with body into dir.
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func writeHelperPlugin(t *testing.T, dir, name string) string {
+	return writeScript(t, dir, name, fmt.Sprintf("PLUGIN_HELPER=1 exec %q -test.run=TestPluginHelperProcess", os.Args[0]))
+}
+
+func newTestLoader(t *testing.T) (*Loader, *tools.ToolRegistry, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin fixtures are shell scripts")
+	}
+
+	dir := filepath.Join(t.TempDir(), "plugins")
+	registry := tools.NewToolRegistry()
+	loader := NewLoader(&Config{Directory: dir, StartTimeout: 5 * time.Second}, registry)
+	t.Cleanup(loader.Close)
+	return loader, registry, dir
+}
+
+func TestLoadAll(t *testing.T) {
+	loader, registry, dir := newTestLoader(t)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeHelperPlugin(t, dir, "text")
+	writeScript(t, dir, "chatty", "echo hello")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeHelperPlugin(t, dir, ".text.swp")
+
+	if err := loader.LoadAll(context.Background()); err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if got := loader.Plugins(); len(got) != 1 || got[0] != "text" {
+		t.Fatalf("Expected only the text plugin to load, got %v", got)
+	}
+
+	shout, ok := registry.Get("shout")
+	if !ok {
+		t.Fatal("Expected the shout tool to be registered")
+	}
+	if shout.Description() != "Upper-cases text" {
+		t.Errorf("Unexpected description %q", shout.Description())
+	}
+	result, err := shout.Execute(context.Background(), map[string]interface{}{"text": "hi"})
+	if err != nil || result != "HI" {
+		t.Errorf("Expected HI, got %q, %v", result, err)
+	}
+
+	broken, ok := registry.Get("broken")
+	if !ok {
+		t.Fatal("Expected the broken tool to be registered")
+	}
+	if string(broken.Parameters()) != `{"type":"object","properties":{}}` {
+		t.Errorf("Expected empty parameters to become an empty object schema, got %s", broken.Parameters())
+	}
+	if _, err := broken.Execute(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "out of order") {
+		t.Errorf("Expected the tool's error, got %v", err)
+	}
+
+	loader.Unload(filepath.Join(dir, "text"))
+	if _, ok := registry.Get("shout"); ok {
+		t.Error("Expected the shout tool to be removed with its plugin")
+	}
+	if _, err := shout.Execute(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("Expected calls to a stopped plugin to fail, got %v", err)
+	}
+}
+
+func TestLoadSkipsTakenNames(t *testing.T) {
+	loader, registry, dir := newTestLoader(t)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	first := writeHelperPlugin(t, dir, "first")
+	second := writeHelperPlugin(t, dir, "second")
+	if err := loader.Load(context.Background(), first); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := loader.Load(context.Background(), second); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// Unloading the second plugin must leave the first one's tools alone.
+	loader.Unload(second)
+	if _, ok := registry.Get("shout"); !ok {
+		t.Error("Expected the first plugin's shout tool to stay registered")
+	}
+	if got := loader.Plugins(); len(got) != 1 || got[0] != "first" {
+		t.Errorf("Expected only the first plugin to run, got %v", got)
+	}
+}
+
+func TestWatchReloadsPlugins(t *testing.T) {
+	loader, registry, dir := newTestLoader(t)
+	if err := loader.LoadAll(context.Background()); err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if err := loader.Watch(); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	waitFor := func(what string, condition func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for !condition() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	registered := func() bool {
+		_, ok := registry.Get("shout")
+		return ok
+	}
+
+	path := writeHelperPlugin(t, dir, "text")
+	waitFor("the added plugin to load", registered)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("the removed plugin to unload", func() bool { return !registered() })
+
+	writeHelperPlugin(t, dir, "text")
+	waitFor("the plugin to load again", registered)
+	if got := loader.Plugins(); len(got) != 1 {
+		t.Errorf("Expected one running plugin, got %v", got)
+	}
+}
+
+func TestFailedReloadKeepsRunningPlugin(t *testing.T) {
+	loader, registry, dir := newTestLoader(t)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeHelperPlugin(t, dir, "text")
+	if err := loader.Load(context.Background(), path); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	writeScript(t, dir, "text", "echo hello")
+	if err := loader.Load(context.Background(), path); err == nil {
+		t.Fatal("Expected the broken plugin to fail to load")
+	}
+
+	shout, ok := registry.Get("shout")
+	if !ok {
+		t.Fatal("Expected the running plugin's tools to stay registered")
+	}
+	if result, err := shout.Execute(context.Background(), map[string]interface{}{"text": "hi"}); err != nil || result != "HI" {
+		t.Errorf("Expected the running plugin to keep working, got %q, %v", result, err)
+	}
+}
+
+func TestCrashedPluginIsUnregistered(t *testing.T) {
+	loader, registry, dir := newTestLoader(t)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeHelperPlugin(t, dir, "text")
+	if err := loader.Load(context.Background(), path); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	loader.mu.Lock()
+	p := loader.plugins[path].plugin
+	loader.mu.Unlock()
+	if err := p.cmd.Process.Kill(); err != nil {
+		t.Fatalf("Failed to kill plugin: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(loader.Plugins()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the crashed plugin to be removed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, ok := registry.Get("shout"); ok {
+		t.Error("Expected the crashed plugin's tools to be unregistered")
+	}
+}
@@ -0,0 +1,199 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/pkg/plugin"
+)
+
+// stopTimeout is how long a plugin gets to exit once its stdin is closed.
+const stopTimeout = 5 * time.Second
+
+// Plugin is a running plugin executable.
+type Plugin struct {
+	path   string
+	name   string
+	cmd    *exec.Cmd
+	client *rpc.Client
+	exited chan struct{}
+	logger *slog.Logger
+	tools  []plugin.ToolSchema
+}
+
+// Start runs the executable at path and asks it for its tools, giving up
+// after timeout.
+func Start(ctx context.Context, path string, timeout time.Duration, logger *slog.Logger) (*Plugin, error) {
+	name := filepath.Base(path)
+	logger = logger.With("plugin", name)
+
+	cmd := exec.Command(path)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Env = append(os.Environ(), plugin.MagicCookieKey+"="+plugin.MagicCookieValue)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stderr: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", name, err)
+	}
+
+	p := &Plugin{
+		path:   path,
+		name:   name,
+		cmd:    cmd,
+		client: jsonrpc.NewClient(&pipeConn{ReadCloser: stdout, WriteCloser: stdin}),
+		exited: make(chan struct{}),
+		logger: logger,
+	}
+	// Wait closes the pipes, so stderr is read to the end first.
+	stderrDone := make(chan struct{})
+	go func() {
+		p.logStderr(stderr)
+		close(stderrDone)
+	}()
+	go func() {
+		<-stderrDone
+		err := cmd.Wait()
+		close(p.exited)
+		if err != nil {
+			logger.Warn("Plugin exited", "error", err)
+		} else {
+			logger.Debug("Plugin exited")
+		}
+	}()
+
+	listCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var reply plugin.ListReply
+	if err := p.call(listCtx, "List", plugin.ListArgs{}, &reply); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("plugin %s did not list its tools: %w", name, err)
+	}
+	p.tools = reply.Tools
+
+	logger.Info("Plugin started", "pid", cmd.Process.Pid, "tools", len(p.tools))
+	return p, nil
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Exited is closed once the plugin process has exited.
+func (p *Plugin) Exited() <-chan struct{} {
+	return p.exited
+}
+
+// Tools wraps the tools the plugin serves for the tool registry.
+func (p *Plugin) Tools() []*Tool {
+	wrapped := make([]*Tool, 0, len(p.tools))
+	for _, schema := range p.tools {
+		wrapped = append(wrapped, &Tool{plugin: p, schema: schema})
+	}
+	return wrapped
+}
+
+func (p *Plugin) call(ctx context.Context, method string, args, reply interface{}) error {
+	call := p.client.Go(plugin.ServiceName+"."+method, args, reply, make(chan *rpc.Call, 1))
+
+	select {
+	case <-call.Done:
+		if errors.Is(call.Error, rpc.ErrShutdown) || errors.Is(call.Error, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("plugin %s is not running", p.name)
+		}
+		return call.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes the plugin's stdin, which tells it to exit, and kills it if
+// it does not.
+func (p *Plugin) Close() error {
+	p.client.Close()
+
+	select {
+	case <-p.exited:
+		return nil
+	case <-time.After(stopTimeout):
+	}
+
+	p.logger.Warn("Plugin did not exit, killing it")
+	if err := p.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("failed to kill plugin %s: %w", p.name, err)
+	}
+	<-p.exited
+	return nil
+}
+
+func (p *Plugin) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		p.logger.Info("Plugin output", "line", scanner.Text())
+	}
+}
+
+// Tool is a tool served by a plugin.
+type Tool struct {
+	plugin *Plugin
+	schema plugin.ToolSchema
+}
+
+func (t *Tool) Name() string {
+	return t.schema.Name
+}
+
+func (t *Tool) Description() string {
+	return t.schema.Description
+}
+
+func (t *Tool) Parameters() json.RawMessage {
+	if len(t.schema.Parameters) == 0 || string(t.schema.Parameters) == "null" {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return t.schema.Parameters
+}
+
+func (t *Tool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	var reply plugin.ExecuteReply
+	if err := t.plugin.call(ctx, "Execute", plugin.ExecuteArgs{Tool: t.schema.Name, Params: params}, &reply); err != nil {
+		return "", err
+	}
+	return reply.Result, nil
+}
+
+// pipeConn joins the plugin's stdout and stdin into the connection RPC runs
+// on.
+type pipeConn struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (c *pipeConn) Close() error {
+	err := c.WriteCloser.Close()
+	c.ReadCloser.Close()
+	return err
+}
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ActionAgentPrompt = "agent_prompt"
+	ActionToolCall    = "tool_call"
+	// ActionConsolidateMemory tidies MEMORY.md with the LLM.
+	ActionConsolidateMemory = "consolidate_memory"
+)
+
+// Action describes what a task does. Unlike a TaskFunc it is saved with the
+// task, so the handler can be rebuilt after a restart.
+type Action struct {
+	Type    string                 `json:"type"`
+	Channel string                 `json:"channel,omitempty"`
+	ChatID  string                 `json:"chat_id,omitempty"`
+	Prompt  string                 `json:"prompt,omitempty"`
+	Tool    string                 `json:"tool,omitempty"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+func (a *Action) Validate() error {
+	switch a.Type {
+	case ActionAgentPrompt:
+		if a.ChatID == "" {
+			return fmt.Errorf("agent_prompt action requires chat_id")
+		}
+		if a.Prompt == "" {
+			return fmt.Errorf("agent_prompt action requires prompt")
+		}
+	case ActionToolCall:
+		if a.Tool == "" {
+			return fmt.Errorf("tool_call action requires tool")
+		}
+	case "":
+		return fmt.Errorf("action type is required")
+	}
+	return nil
+}
+
+// ActionFactory builds the handler for an action.
+type ActionFactory func(action *Action) (TaskFunc, error)
+
+func (m *TaskManager) RegisterAction(actionType string, factory ActionFactory) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.actions[actionType] = factory
+}
+
+func (m *TaskManager) buildHandler(action *Action) (TaskFunc, error) {
+	if err := action.Validate(); err != nil {
+		return nil, err
+	}
+
+	factory, ok := m.actions[action.Type]
+	if !ok {
+		return nil, fmt.Errorf("no handler registered for action type %q", action.Type)
+	}
+
+	handler, err := factory(action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s action: %w", action.Type, err)
+	}
+	return handler, nil
+}
+
+// AddActionTask adds a task whose handler is built from config.Action.
+func (m *TaskManager) AddActionTask(config *TaskConfig) error {
+	if config.Action == nil {
+		return fmt.Errorf("task %s has no action", config.ID)
+	}
+
+	m.mu.RLock()
+	handler, err := m.buildHandler(config.Action)
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return m.AddTask(config, handler)
+}
+
+// PromptTask describes an agent_prompt task created by an operator rather
+// than from a chat.
+type PromptTask struct {
+	Name     string `json:"name,omitempty"`
+	Schedule string `json:"schedule"`
+	Prompt   string `json:"prompt"`
+	Channel  string `json:"channel,omitempty"`
+	ChatID   string `json:"chat_id"`
+	Misfire  string `json:"misfire,omitempty"`
+}
+
+// TaskConfig checks the task and turns it into a task config. The schedule
+// is read like the schedule_task tool's: a delay such as "in 2 hours" or a
+// cron expression.
+func (p *PromptTask) TaskConfig(now time.Time) (*TaskConfig, error) {
+	if strings.TrimSpace(p.Prompt) == "" {
+		return nil, fmt.Errorf("prompt cannot be empty")
+	}
+	if p.ChatID == "" {
+		return nil, fmt.Errorf("chat_id is required")
+	}
+	if err := ValidateMisfire(p.Misfire); err != nil {
+		return nil, err
+	}
+
+	cronExpr, runAt, err := ParseSchedule(p.Schedule, now)
+	if err != nil {
+		return nil, err
+	}
+
+	name := p.Name
+	if strings.TrimSpace(name) == "" {
+		name = p.Prompt
+	}
+
+	return &TaskConfig{
+		ID:       fmt.Sprintf("task-%d", now.UnixNano()),
+		Name:     name,
+		CronExpr: cronExpr,
+		RunAt:    runAt,
+		Enabled:  true,
+		Misfire:  p.Misfire,
+		Action: &Action{
+			Type:    ActionAgentPrompt,
+			Channel: p.Channel,
+			ChatID:  p.ChatID,
+			Prompt:  p.Prompt,
+		},
+	}, nil
+}
@@ -6,7 +6,20 @@ import (
 	"time"
 )
 
+// cronMacros are the shorthands Parse accepts besides "@every <duration>".
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
 type CronSchedule struct {
+	// every is set for "@every" schedules, which ignore the fields.
+	every    time.Duration
 	second   []int
 	minute   []int
 	hour     []int
@@ -31,6 +44,25 @@ func (p *CronParser) SetLocation(loc *time.Location) {
 }
 
 func (p *CronParser) Parse(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if interval, ok := strings.CutPrefix(expr, "@every"); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil {
+			return nil, fmt.Errorf("invalid @every interval: %w", err)
+		}
+		if every < time.Second {
+			return nil, fmt.Errorf("invalid @every interval: must be at least 1s, got %s", every)
+		}
+		return &CronSchedule{every: every.Truncate(time.Second), location: p.location}, nil
+	}
+	if strings.HasPrefix(expr, "@") {
+		macro, ok := cronMacros[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cron macro %s", expr)
+		}
+		expr = macro
+	}
+
 	parts := strings.Fields(expr)
 
 	if len(parts) != 5 && len(parts) != 6 {
@@ -235,6 +267,10 @@ func (s *CronSchedule) Next(t time.Time) time.Time {
 		s.location = time.Local
 	}
 
+	if s.every > 0 {
+		return s.nextInterval(t.In(s.location))
+	}
+
 	t = t.In(s.location).Add(time.Second).Truncate(time.Second)
 
 	for {
@@ -312,11 +348,65 @@ func (s *CronSchedule) nextValue(current int, values []int, min, max int) int {
 	return min
 }
 
+// nextInterval returns the first run of an @every schedule after t.
+// Intervals shorter than a day are counted from local midnight, so @every 15m
+// runs at :00, :15, :30 and :45 and @every 2h30m at 00:00, 02:30 and so on
+// until 22:30, whenever it was scheduled. Whole days are counted from
+// 1970-01-01 and run at midnight; other intervals from its midnight.
+func (s *CronSchedule) nextInterval(t time.Time) time.Time {
+	switch {
+	case s.every%(24*time.Hour) == 0:
+		days := int64(s.every / (24 * time.Hour))
+		day := civilDay(t) + 1
+		return s.midnight(day + (days-day%days)%days)
+	case s.every < 24*time.Hour:
+		today := s.midnight(civilDay(t))
+		next := today.Add((t.Sub(today)/s.every + 1) * s.every)
+		if tomorrow := s.midnight(civilDay(t) + 1); !next.Before(tomorrow) {
+			return tomorrow
+		}
+		return next
+	default:
+		epoch := s.midnight(0)
+		return epoch.Add((t.Sub(epoch)/s.every + 1) * s.every)
+	}
+}
+
+// prevInterval returns the last run of an @every schedule at or before t.
+func (s *CronSchedule) prevInterval(t time.Time) time.Time {
+	switch {
+	case s.every%(24*time.Hour) == 0:
+		days := int64(s.every / (24 * time.Hour))
+		day := civilDay(t)
+		return s.midnight(day - day%days)
+	case s.every < 24*time.Hour:
+		today := s.midnight(civilDay(t))
+		return today.Add(t.Sub(today) / s.every * s.every)
+	default:
+		epoch := s.midnight(0)
+		return epoch.Add(t.Sub(epoch) / s.every * s.every)
+	}
+}
+
+// civilDay numbers the calendar date of t, counting from 1970-01-01.
+func civilDay(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+func (s *CronSchedule) midnight(day int64) time.Time {
+	date := time.Unix(day*86400, 0).UTC()
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, s.location)
+}
+
 func (s *CronSchedule) Prev(t time.Time) time.Time {
 	if s.location == nil {
 		s.location = time.Local
 	}
 
+	if s.every > 0 {
+		return s.prevInterval(t.In(s.location).Add(-time.Second))
+	}
+
 	t = t.In(s.location).Add(-time.Second).Truncate(time.Second)
 
 	for {
@@ -372,6 +462,9 @@ func (s *CronSchedule) prevValue(current int, values []int, min, max int) int {
 }
 
 func (s *CronSchedule) String() string {
+	if s.every > 0 {
+		return fmt.Sprintf("CronSchedule{every: %s}", s.every)
+	}
 	return fmt.Sprintf("CronSchedule{second: %v, minute: %v, hour: %v, day: %v, month: %v, weekday: %v}",
 		s.second, s.minute, s.hour, s.day, s.month, s.weekday)
 }
@@ -272,3 +272,92 @@ func TestNextMonthly(t *testing.T) {
 		t.Errorf("Expected next day to be 1, got %d", next.Day())
 	}
 }
+
+func TestParseMacros(t *testing.T) {
+	parser := NewCronParser()
+	parser.SetLocation(time.UTC)
+	from := time.Date(2024, 1, 10, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"@hourly", time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 15m", time.Date(2024, 1, 10, 10, 15, 0, 0, time.UTC)},
+		{"@every 2h", time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next for %q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"@fortnightly", "@every", "@every 500ms", "@every soon"} {
+		if _, err := parser.Parse(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestEveryInLocation(t *testing.T) {
+	india := time.FixedZone("IST", 5*3600+1800)
+	parser := NewCronParser()
+	parser.SetLocation(india)
+
+	from := time.Date(2024, 1, 10, 10, 20, 0, 0, india)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"@every 1h", time.Date(2024, 1, 10, 11, 0, 0, 0, india)},
+		{"@every 15m", time.Date(2024, 1, 10, 10, 30, 0, 0, india)},
+		{"@every 2h30m", time.Date(2024, 1, 10, 12, 30, 0, 0, india)},
+		{"@every 48h", time.Date(2024, 1, 12, 0, 0, 0, 0, india)},
+	}
+	for _, tt := range tests {
+		schedule, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next for %q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	schedule, err := parser.Parse("@every 2h30m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := time.Date(2024, 1, 10, 22, 30, 0, 0, india)
+	if got, want := schedule.Next(last), time.Date(2024, 1, 11, 0, 0, 0, 0, india); !got.Equal(want) {
+		t.Errorf("Expected the last run of the day to be followed by midnight, got %v", got)
+	}
+	if got := schedule.Prev(last.Add(time.Hour)); !got.Equal(last) {
+		t.Errorf("Prev = %v, want %v", got, last)
+	}
+}
+
+func TestEveryPrev(t *testing.T) {
+	parser := NewCronParser()
+	parser.SetLocation(time.UTC)
+	schedule, err := parser.Parse("@every 15m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2024, 1, 10, 10, 15, 0, 0, time.UTC)
+	if got, want := schedule.Prev(at), time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Prev = %v, want %v", got, want)
+	}
+	if got, want := schedule.Prev(at.Add(time.Minute)), at; !got.Equal(want) {
+		t.Errorf("Prev = %v, want %v", got, want)
+	}
+}
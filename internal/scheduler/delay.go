@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	delayPattern = regexp.MustCompile(`^(?:(?:\d+|\ban?\b)\s*[a-z]+\s*(?:,|and)?\s*)+$`)
+	delayPart    = regexp.MustCompile(`(\d+|\ban?\b)\s*([a-z]+)`)
+)
+
+var delayUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// ParseDelay parses delays such as "in 2 hours", "an hour and 30 minutes" or
+// "90m".
+func ParseDelay(expr string) (time.Duration, error) {
+	text := strings.ToLower(strings.TrimSpace(expr))
+	text = strings.TrimSpace(strings.TrimPrefix(text, "in "))
+
+	if d, err := time.ParseDuration(text); err == nil {
+		if d <= 0 {
+			return 0, fmt.Errorf("delay must be positive")
+		}
+		return d, nil
+	}
+
+	if !delayPattern.MatchString(text) {
+		return 0, fmt.Errorf("invalid delay %q", expr)
+	}
+
+	var total time.Duration
+	for _, match := range delayPart.FindAllStringSubmatch(text, -1) {
+		unit, ok := delayUnits[match[2]]
+		if !ok {
+			return 0, fmt.Errorf("unknown time unit %q", match[2])
+		}
+
+		count := 1
+		if match[1] != "a" && match[1] != "an" {
+			count, _ = strconv.Atoi(match[1])
+		}
+		total += time.Duration(count) * unit
+	}
+
+	if total <= 0 {
+		return 0, fmt.Errorf("delay must be positive")
+	}
+	return total, nil
+}
+
+// ParseSchedule accepts either a cron expression, for recurring tasks, or a
+// delay such as "in 2 hours", for tasks that run once. Exactly one of the
+// results is set.
+func ParseSchedule(expr string, now time.Time) (string, time.Time, error) {
+	if delay, err := ParseDelay(expr); err == nil {
+		return "", now.Add(delay), nil
+	}
+
+	cronExpr := strings.TrimSpace(expr)
+	if _, err := ParseCronExpression(cronExpr); err != nil {
+		return "", time.Time{}, fmt.Errorf("expected a cron expression such as \"0 9 * * 1-5\" or \"@daily\", or a delay such as \"in 2 hours\": %w", err)
+	}
+	return cronExpr, time.Time{}, nil
+}
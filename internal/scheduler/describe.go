@@ -0,0 +1,274 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxListedTimes is how many times of day Describe spells out before it
+// falls back to describing the minutes and hours separately.
+const maxListedTimes = 4
+
+// Describe renders the schedule as text, such as "every weekday at 09:00" or
+// "every 15 minutes".
+func (s *CronSchedule) Describe() string {
+	if s.every > 0 {
+		return "every " + describeInterval(s.every)
+	}
+
+	days := s.describeDays()
+	clock, fixed := s.describeClock()
+	if !fixed {
+		switch {
+		case days == "every day":
+			return clock
+		case strings.HasPrefix(days, "every day "):
+			return clock + strings.TrimPrefix(days, "every day")
+		case strings.HasPrefix(days, "every weekday"):
+			return clock + " on weekdays" + strings.TrimPrefix(days, "every weekday")
+		}
+		return clock + " " + strings.Replace(days, "every ", "on ", 1)
+	}
+	return days + " " + clock
+}
+
+// DescribeCronExpression describes expr, or returns it unchanged when it
+// does not parse.
+func DescribeCronExpression(expr string) string {
+	schedule, err := ParseCronExpression(expr)
+	if err != nil {
+		return expr
+	}
+	return schedule.Describe()
+}
+
+// describeClock describes the times of day. fixed reports whether they are
+// a few points in time ("at 09:00") rather than a repetition ("every hour").
+func (s *CronSchedule) describeClock() (string, bool) {
+	allSeconds := s.isAllValues(s.second, 0, 59)
+	allMinutes := s.isAllValues(s.minute, 0, 59)
+	allHours := s.isAllValues(s.hour, 0, 23)
+
+	if allSeconds {
+		return "every second" + s.describeHours(allMinutes, allHours), false
+	}
+	if step, ok := evenStep(s.second, 0, 59); ok {
+		return fmt.Sprintf("every %d seconds", step) + s.describeHours(allMinutes, allHours), false
+	}
+
+	seconds := len(s.second) != 1 || s.second[0] != 0
+	if !seconds && allMinutes {
+		return "every minute" + s.describeHours(true, allHours), false
+	}
+	if step, ok := evenStep(s.minute, 0, 59); ok && !seconds {
+		return fmt.Sprintf("every %d minutes", step) + s.describeHours(true, allHours), false
+	}
+	if allHours && !seconds && len(s.minute) == 1 {
+		if s.minute[0] == 0 {
+			return "every hour", false
+		}
+		return fmt.Sprintf("every hour at :%02d", s.minute[0]), false
+	}
+	if step, ok := evenStep(s.hour, 0, 23); ok && !seconds && len(s.minute) == 1 {
+		return fmt.Sprintf("at minute %d of every %s hour", s.minute[0], ordinal(step)), false
+	}
+
+	if count := len(s.second) * len(s.minute) * len(s.hour); count <= maxListedTimes {
+		times := make([]string, 0, count)
+		for _, hour := range s.hour {
+			for _, minute := range s.minute {
+				for _, second := range s.second {
+					if seconds {
+						times = append(times, fmt.Sprintf("%02d:%02d:%02d", hour, minute, second))
+					} else {
+						times = append(times, fmt.Sprintf("%02d:%02d", hour, minute))
+					}
+				}
+			}
+		}
+		return "at " + joinList(times), true
+	}
+
+	clock := "at minute " + joinInts(s.minute)
+	if seconds {
+		clock = "at second " + joinInts(s.second) + " of minute " + joinInts(s.minute)
+	}
+	if !allHours {
+		clock += " past hour " + joinInts(s.hour)
+	} else {
+		clock += " of every hour"
+	}
+	return clock, false
+}
+
+// describeHours limits a repetition within the hour to the hours it runs in.
+func (s *CronSchedule) describeHours(allMinutes, allHours bool) string {
+	var limit string
+	if !allMinutes {
+		limit = " during minute " + joinInts(s.minute)
+	}
+	if allHours {
+		return limit
+	}
+	if isContiguous(s.hour) && len(s.hour) > 1 {
+		return limit + fmt.Sprintf(" from %02d:00 to %02d:59", s.hour[0], s.hour[len(s.hour)-1])
+	}
+
+	hours := make([]string, len(s.hour))
+	for i, hour := range s.hour {
+		hours[i] = fmt.Sprintf("%02d:00", hour)
+	}
+	return limit + " in the hour from " + joinList(hours)
+}
+
+func (s *CronSchedule) describeDays() string {
+	allDays := s.isAllValues(s.day, 1, 31)
+	allWeekdays := s.isAllValues(s.weekday, 0, 6)
+	allMonths := s.isAllValues(s.month, 1, 12)
+
+	months := "every month"
+	if !allMonths {
+		names := make([]string, len(s.month))
+		for i, month := range s.month {
+			names[i] = time.Month(month).String()
+		}
+		months = joinList(names)
+	}
+
+	var days string
+	switch {
+	case !allDays:
+		ordinals := make([]string, len(s.day))
+		for i, day := range s.day {
+			ordinals[i] = ordinal(day)
+		}
+		days = "on the " + joinList(ordinals) + " of " + months
+		if !allWeekdays {
+			days += " if it is a " + describeWeekdays(s.weekday, "or")
+		}
+		return days
+	case !allWeekdays:
+		days = "every " + describeWeekdays(s.weekday, "and")
+	default:
+		days = "every day"
+	}
+
+	if !allMonths {
+		days += " in " + months
+	}
+	return days
+}
+
+func describeWeekdays(weekdays []int, conjunction string) string {
+	if len(weekdays) == 5 && isContiguous(weekdays) && weekdays[0] == 1 {
+		return "weekday"
+	}
+
+	// Weeks start on Monday here, so Sunday comes last.
+	names := make([]string, 0, len(weekdays))
+	for _, weekday := range weekdays {
+		if weekday != int(time.Sunday) {
+			names = append(names, time.Weekday(weekday).String())
+		}
+	}
+	if weekdays[0] == int(time.Sunday) {
+		names = append(names, time.Sunday.String())
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " " + conjunction + " " + names[len(names)-1]
+}
+
+// describeInterval names d in its largest whole unit, such as "90 minutes",
+// and splits it up from two of a unit on, as in "2 hours and 30 minutes".
+func describeInterval(d time.Duration) string {
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+		{time.Second, "second"},
+	}
+
+	var parts []string
+	for _, unit := range units {
+		n := d / unit.size
+		switch {
+		case d%unit.size == 0 && n == 1 && len(parts) == 0:
+			return unit.name
+		case d%unit.size == 0:
+			return joinList(append(parts, pluralize(int(n), unit.name)))
+		case n >= 2:
+			parts = append(parts, pluralize(int(n), unit.name))
+			d -= n * unit.size
+		}
+	}
+	return d.String()
+}
+
+func pluralize(n int, name string) string {
+	if n == 1 {
+		return "1 " + name
+	}
+	return fmt.Sprintf("%d %ss", n, name)
+}
+
+// evenStep reports whether values are every step-th value from min, as
+// written with */step.
+func evenStep(values []int, min, max int) (int, bool) {
+	if len(values) < 2 || values[0] != min {
+		return 0, false
+	}
+	step := values[1] - values[0]
+	for i := 1; i < len(values); i++ {
+		if values[i]-values[i-1] != step {
+			return 0, false
+		}
+	}
+	return step, step > 1 && values[len(values)-1]+step > max
+}
+
+func isContiguous(values []int) bool {
+	for i := 1; i < len(values); i++ {
+		if values[i] != values[i-1]+1 {
+			return false
+		}
+	}
+	return true
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+func joinInts(values []int) string {
+	if len(values) > 1 && isContiguous(values) {
+		return fmt.Sprintf("%d-%d", values[0], values[len(values)-1])
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinList(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
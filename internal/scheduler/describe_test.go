@@ -0,0 +1,41 @@
+package scheduler
+
+import "testing"
+
+func TestDescribeCronExpression(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"0 9 * * 1-5", "every weekday at 09:00"},
+		{"30 7 * * *", "every day at 07:30"},
+		{"0 9 * * 1,3,5", "every Monday, Wednesday and Friday at 09:00"},
+		{"0 18 * * 0", "every Sunday at 18:00"},
+		{"0 9,17 * * *", "every day at 09:00 and 17:00"},
+		{"@daily", "every day at 00:00"},
+		{"@weekly", "every Sunday at 00:00"},
+		{"@monthly", "on the 1st of every month at 00:00"},
+		{"@yearly", "on the 1st of January at 00:00"},
+		{"0 12 22 * *", "on the 22nd of every month at 12:00"},
+		{"@hourly", "every hour"},
+		{"5 * * * *", "every hour at :05"},
+		{"0 */2 * * *", "at minute 0 of every 2nd hour"},
+		{"0 9 * * 6,0", "every Saturday and Sunday at 09:00"},
+		{"* * * * *", "every minute"},
+		{"*/15 * * * *", "every 15 minutes"},
+		{"*/15 9-17 * * 1-5", "every 15 minutes from 09:00 to 17:59 on weekdays"},
+		{"*/30 * * * * *", "every 30 seconds"},
+		{"0 0 * 12 *", "every day in December at 00:00"},
+		{"@every 15m", "every 15 minutes"},
+		{"@every 1h", "every hour"},
+		{"@every 90m", "every 90 minutes"},
+		{"@every 2h30m", "every 2 hours and 30 minutes"},
+		{"not a schedule", "not a schedule"},
+	}
+
+	for _, tt := range tests {
+		if got := DescribeCronExpression(tt.expr); got != tt.want {
+			t.Errorf("DescribeCronExpression(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package scheduler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+// TaskRun is the saved record of a single task execution.
+type TaskRun struct {
+	TaskID    string        `json:"task_id"`
+	Status    TaskStatus    `json:"status"`
+	Output    string        `json:"output,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+func newTaskRun(result *TaskResult) *TaskRun {
+	run := &TaskRun{
+		TaskID:    result.TaskID,
+		Status:    result.Status,
+		Output:    result.Output,
+		Duration:  result.Duration,
+		Timestamp: result.Timestamp,
+	}
+	if result.Error != nil {
+		run.Error = result.Error.Error()
+	}
+	return run
+}
+
+type TaskHistoryStore interface {
+	Record(run *TaskRun) error
+	// List returns the runs of a task, newest first.
+	List(taskID string, limit, offset int) ([]*TaskRun, error)
+	Clear(taskID string) error
+	// Prune drops runs older than before and all but the newest maxRuns runs
+	// of each task. Zero values disable either rule.
+	Prune(before time.Time, maxRuns int) (int, error)
+}
+
+// FileTaskHistoryStore keeps one JSON lines file per task.
+type FileTaskHistoryStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileTaskHistoryStore(dir string) *FileTaskHistoryStore {
+	return &FileTaskHistoryStore{dir: dir}
+}
+
+func (s *FileTaskHistoryStore) path(taskID string) (string, error) {
+	if taskID == "" || taskID == "." || taskID == ".." {
+		return "", fmt.Errorf("invalid task ID %q", taskID)
+	}
+	return filepath.Join(s.dir, url.PathEscape(taskID)+".jsonl"), nil
+}
+
+func (s *FileTaskHistoryStore) Record(run *TaskRun) error {
+	path, err := s.path(run.TaskID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task run: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	if _, err := storage.AppendLines(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write task run: %w", err)
+	}
+	return nil
+}
+
+func (s *FileTaskHistoryStore) List(taskID string, limit, offset int) ([]*TaskRun, error) {
+	path, err := s.path(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	runs, err := readTaskRuns(path)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Newest first.
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+
+	if offset >= len(runs) {
+		return []*TaskRun{}, nil
+	}
+	if offset > 0 {
+		runs = runs[offset:]
+	}
+	if limit > 0 && limit < len(runs) {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+func (s *FileTaskHistoryStore) Clear(taskID string) error {
+	path, err := s.path(taskID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear task history: %w", err)
+	}
+	return nil
+}
+
+func (s *FileTaskHistoryStore) Prune(before time.Time, maxRuns int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.jsonl"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list history files: %w", err)
+	}
+
+	pruned := 0
+	for _, path := range paths {
+		runs, err := readTaskRuns(path)
+		if err != nil {
+			return pruned, err
+		}
+
+		kept := runs[:0]
+		for _, run := range runs {
+			if before.IsZero() || !run.Timestamp.Before(before) {
+				kept = append(kept, run)
+			}
+		}
+		if maxRuns > 0 && len(kept) > maxRuns {
+			kept = kept[len(kept)-maxRuns:]
+		}
+		if len(kept) == len(runs) {
+			continue
+		}
+
+		pruned += len(runs) - len(kept)
+		if err := writeTaskRuns(path, kept); err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+}
+
+func readTaskRuns(path string) ([]*TaskRun, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []*TaskRun{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	runs := make([]*TaskRun, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var run TaskRun
+		// A line cut short by a crash is skipped rather than failing the
+		// whole history.
+		if err := json.Unmarshal([]byte(line), &run); err != nil {
+			continue
+		}
+		runs = append(runs, &run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return runs, nil
+}
+
+func writeTaskRuns(path string, runs []*TaskRun) error {
+	if len(runs) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove history file: %w", err)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, run := range runs {
+		data, err := json.Marshal(run)
+		if err != nil {
+			return fmt.Errorf("failed to marshal task run: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	if err := storage.WriteFileAtomic(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+// recentRuns merges the latest runs of the given tasks, newest first.
+func recentRuns(store TaskHistoryStore, taskIDs []string, limit int) []*TaskRun {
+	var runs []*TaskRun
+	for _, taskID := range taskIDs {
+		taskRuns, err := store.List(taskID, limit, 0)
+		if err != nil {
+			continue
+		}
+		runs = append(runs, taskRuns...)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Timestamp.After(runs[j].Timestamp) })
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs
+}
+
+type taskOutputKey struct{}
+
+// ReportOutput records what a task handler produced, so it is saved with the
+// run in the task history.
+func ReportOutput(ctx context.Context, output string) {
+	if sink, ok := ctx.Value(taskOutputKey{}).(*string); ok {
+		*sink = output
+	}
+}
+
+func withTaskOutput(ctx context.Context, sink *string) context.Context {
+	return context.WithValue(ctx, taskOutputKey{}, sink)
+}
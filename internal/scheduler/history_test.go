@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileTaskHistoryStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileTaskHistoryStore(dir)
+	start := time.Now().Add(-time.Hour)
+
+	for i := 0; i < 5; i++ {
+		run := &TaskRun{TaskID: "report", Status: StatusCompleted, Output: fmt.Sprintf("run %d", i), Timestamp: start.Add(time.Duration(i) * time.Minute)}
+		if err := store.Record(run); err != nil {
+			t.Fatalf("Failed to record run: %v", err)
+		}
+	}
+	store.Record(&TaskRun{TaskID: "other/task", Status: StatusFailed, Error: "boom", Timestamp: start})
+
+	runs, err := store.List("report", 2, 1)
+	if err != nil {
+		t.Fatalf("Failed to list runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Output != "run 3" || runs[1].Output != "run 2" {
+		t.Errorf("Expected runs 3 and 2 newest first, got %+v", runs)
+	}
+	if runs, _ := store.List("report", 10, 10); len(runs) != 0 {
+		t.Errorf("Expected no runs past the end, got %d", len(runs))
+	}
+	if runs, _ := store.List("other/task", 0, 0); len(runs) != 1 || runs[0].Error != "boom" {
+		t.Errorf("Expected run of task with a slash in its ID, got %+v", runs)
+	}
+	if _, err := store.List("..", 0, 0); err == nil {
+		t.Error("Expected invalid task ID to be rejected")
+	}
+
+	pruned, err := store.Prune(start.Add(90*time.Second), 2)
+	if err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if pruned != 4 {
+		t.Errorf("Expected 4 runs pruned, got %d", pruned)
+	}
+	if runs, _ := store.List("report", 0, 0); len(runs) != 2 || runs[0].Output != "run 4" {
+		t.Errorf("Expected newest 2 runs kept, got %+v", runs)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl")); len(files) != 1 {
+		t.Errorf("Expected history file of a fully pruned task to be removed, got %v", files)
+	}
+
+	if err := store.Clear("report"); err != nil {
+		t.Fatalf("Failed to clear history: %v", err)
+	}
+	if runs, _ := store.List("report", 0, 0); len(runs) != 0 {
+		t.Errorf("Expected cleared history, got %d runs", len(runs))
+	}
+}
+
+func TestTaskRunsAreRecorded(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileTaskHistoryStore(filepath.Join(dir, "history"))
+	manager := NewTaskManager(NewScheduler(&SchedulerConfig{TickInterval: time.Hour}), &TaskManagerConfig{
+		TasksFile: filepath.Join(dir, "tasks.json"),
+		History:   store,
+	})
+	manager.RegisterAction(ActionToolCall, func(action *Action) (TaskFunc, error) {
+		return func(ctx context.Context) error {
+			ReportOutput(ctx, "backed up")
+			return nil
+		}, nil
+	})
+	manager.Start()
+	manager.GetScheduler().Start()
+	defer manager.GetScheduler().Stop()
+	defer manager.Stop()
+
+	err := manager.AddActionTask(&TaskConfig{ID: "backup", Name: "Backup", CronExpr: "0 3 * * *", Enabled: true, Action: &Action{Type: ActionToolCall, Tool: "backup"}})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := manager.TriggerTask("backup"); err != nil {
+		t.Fatalf("Failed to trigger task: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	var runs []*TaskRun
+	for time.Now().Before(deadline) {
+		if runs, _ = manager.GetTaskHistory("backup", 10, 0); len(runs) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(runs) != 1 || runs[0].Status != StatusCompleted || runs[0].Output != "backed up" {
+		t.Fatalf("Expected recorded run with output, got %+v", runs)
+	}
+
+	recent, ok := manager.GetStats()["recent_runs"].([]*TaskRun)
+	if !ok || len(recent) != 1 || recent[0].TaskID != "backup" {
+		t.Errorf("Expected recent runs in stats, got %+v", manager.GetStats()["recent_runs"])
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "history", "backup.jsonl")); err != nil {
+		t.Errorf("Expected history file: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+)
+
+// Misfire policies decide what happens to the runs of a cron task that were
+// due while the process was down.
+const (
+	// MisfireSkip waits for the next occurrence.
+	MisfireSkip = "skip"
+	// MisfireRunOnce runs the task once on start if any run was missed.
+	MisfireRunOnce = "run_once"
+	// MisfireRunAll runs every missed run, up to MaxMissedRuns.
+	MisfireRunAll = "run_all"
+)
+
+const defaultMaxMissedRuns = 10
+
+func ValidateMisfire(policy string) error {
+	switch policy {
+	case "", MisfireSkip, MisfireRunOnce, MisfireRunAll:
+		return nil
+	}
+	return fmt.Errorf("misfire policy must be %s, %s or %s, got %q", MisfireSkip, MisfireRunOnce, MisfireRunAll, policy)
+}
+
+// missedRuns counts the occurrences of expr after lastRun up to now,
+// stopping at limit.
+func missedRuns(expr string, lastRun, now time.Time, limit int) (int, error) {
+	schedule, err := ParseCronExpression(expr)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for next := schedule.Next(lastRun); !next.After(now) && count < limit; next = schedule.Next(next) {
+		count++
+	}
+	return count, nil
+}
+
+// catchUpRuns is how many runs the task owes for the time since its last
+// run, following its misfire policy.
+func catchUpRuns(task *Task, now time.Time) int {
+	if task.OneOff() || task.LastRun.IsZero() || !task.Enabled {
+		return 0
+	}
+
+	limit := 0
+	switch task.Misfire {
+	case MisfireRunOnce:
+		limit = 1
+	case MisfireRunAll:
+		limit = task.MaxMissedRuns
+		if limit <= 0 {
+			limit = defaultMaxMissedRuns
+		}
+	}
+	if limit == 0 {
+		return 0
+	}
+
+	missed, err := missedRuns(task.CronExpr, task.LastRun, now, limit)
+	if err != nil {
+		return 0
+	}
+	return missed
+}
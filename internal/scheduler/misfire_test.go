@@ -0,0 +1,21 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMissedRuns(t *testing.T) {
+	lastRun := time.Date(2024, 1, 10, 9, 0, 0, 0, time.Local)
+	now := time.Date(2024, 1, 10, 13, 30, 0, 0, time.Local)
+
+	if got, _ := missedRuns("0 * * * *", lastRun, now, 10); got != 4 {
+		t.Errorf("Expected 4 missed hourly runs, got %d", got)
+	}
+	if got, _ := missedRuns("0 * * * *", lastRun, now, 2); got != 2 {
+		t.Errorf("Expected the count to stop at the limit, got %d", got)
+	}
+	if got, _ := missedRuns("0 9 * * *", lastRun, now, 10); got != 0 {
+		t.Errorf("Expected no missed daily runs, got %d", got)
+	}
+}
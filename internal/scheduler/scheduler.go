@@ -2,10 +2,15 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 )
 
 type TaskStatus string
@@ -18,6 +23,25 @@ const (
 	StatusCancelled TaskStatus = "cancelled"
 )
 
+// Overlap policies decide what happens when a task is due while its previous
+// run has not finished.
+const (
+	// OverlapSkip drops the new run.
+	OverlapSkip = "skip"
+	// OverlapQueue starts the new run once the previous one finishes. At
+	// most one run waits.
+	OverlapQueue = "queue"
+	// OverlapCancel cancels the previous run and starts the new one once
+	// it has stopped.
+	OverlapCancel = "cancel_previous"
+)
+
+const defaultWorkers = 4
+
+// ErrTaskTimeout is the error of a run that took longer than its task's
+// Timeout.
+var ErrTaskTimeout = errors.New("task timed out")
+
 type TaskFunc func(ctx context.Context) error
 
 type Task struct {
@@ -25,7 +49,9 @@ type Task struct {
 	Name        string
 	Description string
 	CronExpr    string
+	RunAt       time.Time
 	Handler     TaskFunc
+	Action      *Action
 	Status      TaskStatus
 	LastRun     time.Time
 	NextRun     time.Time
@@ -35,6 +61,23 @@ type Task struct {
 	Enabled     bool
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	// Misfire is one of the Misfire policies; empty means MisfireSkip.
+	Misfire       string
+	MaxMissedRuns int
+	// Overlap is one of the Overlap policies; empty means OverlapSkip.
+	Overlap string
+	// Jitter delays each cron run by a random time up to Jitter, so tasks
+	// on the same schedule do not all start at once.
+	Jitter time.Duration
+	// Timeout cancels a run that takes longer and marks it failed.
+	Timeout time.Duration
+	// missed counts the catch-up runs still to be started, one at a time.
+	missed int
+	// active is set while a run executes; cancelRun stops it. queued
+	// means another run starts when it finishes.
+	active    bool
+	cancelRun context.CancelFunc
+	queued    bool
 }
 
 type Scheduler struct {
@@ -46,11 +89,14 @@ type Scheduler struct {
 	running    bool
 	taskChan   chan *Task
 	resultChan chan *TaskResult
+	workers    int
+	logger     *slog.Logger
 }
 
 type TaskResult struct {
 	TaskID    string
 	Status    TaskStatus
+	Output    string
 	Error     error
 	Duration  time.Duration
 	Timestamp time.Time
@@ -58,6 +104,10 @@ type TaskResult struct {
 
 type SchedulerConfig struct {
 	TickInterval time.Duration
+	// Workers is how many tasks may run at the same time; further due
+	// tasks wait for a free worker.
+	Workers int
+	Logger  *slog.Logger
 }
 
 func NewScheduler(config *SchedulerConfig) *Scheduler {
@@ -69,6 +119,11 @@ func NewScheduler(config *SchedulerConfig) *Scheduler {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	workers := config.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
 	return &Scheduler{
 		tasks:      make(map[string]*Task),
 		ctx:        ctx,
@@ -76,6 +131,8 @@ func NewScheduler(config *SchedulerConfig) *Scheduler {
 		ticker:     time.NewTicker(config.TickInterval),
 		taskChan:   make(chan *Task, 100),
 		resultChan: make(chan *TaskResult, 100),
+		workers:    workers,
+		logger:     logging.Or(config.Logger, "scheduler"),
 	}
 }
 
@@ -92,7 +149,7 @@ func (s *Scheduler) Start() error {
 	go s.run()
 	go s.processTasks()
 
-	log.Println("Scheduler started")
+	s.logger.Info("Scheduler started")
 
 	return nil
 }
@@ -112,7 +169,7 @@ func (s *Scheduler) Stop() error {
 	close(s.taskChan)
 	close(s.resultChan)
 
-	log.Println("Scheduler stopped")
+	s.logger.Info("Scheduler stopped")
 
 	return nil
 }
@@ -135,14 +192,22 @@ func (s *Scheduler) AddTask(task *Task) error {
 		return fmt.Errorf("task name cannot be empty")
 	}
 
-	if task.CronExpr == "" {
-		return fmt.Errorf("task cron expression cannot be empty")
+	if task.CronExpr == "" && task.RunAt.IsZero() {
+		return fmt.Errorf("task needs a cron expression or a run time")
 	}
 
 	if task.Handler == nil {
 		return fmt.Errorf("task handler cannot be nil")
 	}
 
+	if err := ValidateMisfire(task.Misfire); err != nil {
+		return err
+	}
+
+	if err := ValidateOverlap(task.Overlap); err != nil {
+		return err
+	}
+
 	if _, exists := s.tasks[task.ID]; exists {
 		return fmt.Errorf("task with ID %s already exists", task.ID)
 	}
@@ -153,15 +218,19 @@ func (s *Scheduler) AddTask(task *Task) error {
 	task.UpdatedAt = now
 	task.Enabled = true
 
-	nextRun, err := s.calculateNextRun(task.CronExpr, now)
-	if err != nil {
-		return fmt.Errorf("failed to calculate next run: %w", err)
+	if task.CronExpr == "" {
+		task.NextRun = task.RunAt
+	} else {
+		nextRun, err := s.nextRun(task, now)
+		if err != nil {
+			return fmt.Errorf("failed to calculate next run: %w", err)
+		}
+		task.NextRun = nextRun
 	}
-	task.NextRun = nextRun
 
 	s.tasks[task.ID] = task
 
-	log.Printf("Task added: %s (ID: %s, Next run: %s)", task.Name, task.ID, task.NextRun)
+	s.logger.Info("Task added", "task", task.Name, "task_id", task.ID, "next_run", task.NextRun)
 
 	return nil
 }
@@ -176,7 +245,7 @@ func (s *Scheduler) RemoveTask(taskID string) error {
 
 	delete(s.tasks, taskID)
 
-	log.Printf("Task removed: %s", taskID)
+	s.logger.Info("Task removed", "task_id", taskID)
 
 	return nil
 }
@@ -201,6 +270,20 @@ func (s *Scheduler) ListTasks() []*Task {
 	return tasks
 }
 
+// SnapshotTasks copies the tasks, so their fields can be read while the
+// scheduler keeps updating them.
+func (s *Scheduler) SnapshotTasks() []Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := make([]Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, *task)
+	}
+
+	return tasks
+}
+
 func (s *Scheduler) EnableTask(taskID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -213,7 +296,7 @@ func (s *Scheduler) EnableTask(taskID string) error {
 	task.Enabled = true
 	task.UpdatedAt = time.Now()
 
-	log.Printf("Task enabled: %s", taskID)
+	s.logger.Info("Task enabled", "task_id", taskID)
 
 	return nil
 }
@@ -230,11 +313,38 @@ func (s *Scheduler) DisableTask(taskID string) error {
 	task.Enabled = false
 	task.UpdatedAt = time.Now()
 
-	log.Printf("Task disabled: %s", taskID)
+	s.logger.Info("Task disabled", "task_id", taskID)
 
 	return nil
 }
 
+// Postpone moves the next run of a task back by d. For cron tasks only the
+// next occurrence moves; later ones follow the expression again.
+func (s *Scheduler) Postpone(taskID string, d time.Duration) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.tasks[taskID]
+	if !exists {
+		return time.Time{}, fmt.Errorf("task with ID %s not found", taskID)
+	}
+
+	now := time.Now()
+	from := task.NextRun
+	if from.Before(now) {
+		from = now
+	}
+	task.NextRun = from.Add(d)
+	if task.OneOff() {
+		task.RunAt = task.NextRun
+	}
+	task.UpdatedAt = now
+
+	s.logger.Info("Task postponed", "task_id", taskID, "next_run", task.NextRun)
+
+	return task.NextRun, nil
+}
+
 func (s *Scheduler) TriggerTask(taskID string) error {
 	s.mu.RLock()
 	task, exists := s.tasks[taskID]
@@ -256,6 +366,23 @@ func (s *Scheduler) TriggerTask(taskID string) error {
 	}
 }
 
+// OneOff reports whether the task runs once at RunAt instead of following a
+// cron expression.
+func (t *Task) OneOff() bool {
+	return t.CronExpr == ""
+}
+
+// SetTickInterval changes how often due tasks are checked.
+func (s *Scheduler) SetTickInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("tick interval must be positive")
+	}
+
+	s.ticker.Reset(interval)
+	s.logger.Info("Scheduler tick interval changed", "interval", interval)
+	return nil
+}
+
 func (s *Scheduler) GetResults() <-chan *TaskResult {
 	return s.resultChan
 }
@@ -272,13 +399,25 @@ func (s *Scheduler) run() {
 }
 
 func (s *Scheduler) checkAndScheduleTasks() {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	now := time.Now()
 
 	for _, task := range s.tasks {
-		if !task.Enabled {
+		if !task.Enabled || (task.OneOff() && task.NextRun.IsZero()) {
+			continue
+		}
+
+		if task.missed > 0 && task.Status != StatusRunning {
+			select {
+			case s.taskChan <- task:
+				task.missed--
+				task.LastRun = now
+				task.Status = StatusRunning
+			default:
+				s.logger.Warn("Task queue is full, delaying missed run", "task_id", task.ID)
+			}
 			continue
 		}
 
@@ -286,15 +425,30 @@ func (s *Scheduler) checkAndScheduleTasks() {
 			select {
 			case s.taskChan <- task:
 				task.LastRun = now
-				task.NextRun, _ = s.calculateNextRun(task.CronExpr, now)
+				if task.OneOff() {
+					// Cleared so the task does not run again before the task
+					// manager removes it.
+					task.NextRun = time.Time{}
+				} else {
+					task.NextRun, _ = s.nextRun(task, now)
+				}
 			default:
-				log.Printf("Task queue is full, skipping task: %s", task.ID)
+				s.logger.Warn("Task queue is full, skipping task", "task_id", task.ID)
 			}
 		}
 	}
 }
 
+func ValidateOverlap(policy string) error {
+	switch policy {
+	case "", OverlapSkip, OverlapQueue, OverlapCancel:
+		return nil
+	}
+	return fmt.Errorf("overlap policy must be %s, %s or %s, got %q", OverlapSkip, OverlapQueue, OverlapCancel, policy)
+}
+
 func (s *Scheduler) processTasks() {
+	workers := make(chan struct{}, s.workers)
 	for {
 		select {
 		case <-s.ctx.Done():
@@ -304,37 +458,103 @@ func (s *Scheduler) processTasks() {
 				return
 			}
 
-			go s.executeTask(task)
+			ctx, ok := s.claim(task)
+			if !ok {
+				continue
+			}
+
+			select {
+			case workers <- struct{}{}:
+			case <-s.ctx.Done():
+				return
+			}
+			go func() {
+				defer func() { <-workers }()
+				s.executeTask(ctx, task)
+			}()
 		}
 	}
 }
 
-func (s *Scheduler) executeTask(task *Task) {
+// claim marks the task as running and returns the context for the run, or
+// applies the overlap policy when the previous run has not finished.
+func (s *Scheduler) claim(task *Task) (context.Context, bool) {
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if task.active {
+		switch task.Overlap {
+		case OverlapQueue:
+			task.queued = true
+			s.logger.Info("Task still running, queueing the next run", "task_id", task.ID)
+		case OverlapCancel:
+			task.queued = true
+			task.cancelRun()
+			s.logger.Info("Task still running, cancelling it for the next run", "task_id", task.ID)
+		default:
+			s.logger.Warn("Task still running, skipping the next run", "task_id", task.ID)
+		}
+		return nil, false
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if task.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(s.ctx, task.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(s.ctx)
+	}
+	task.active = true
+	task.cancelRun = cancel
 	task.Status = StatusRunning
 	task.UpdatedAt = time.Now()
-	s.mu.Unlock()
+	return ctx, true
+}
 
+func (s *Scheduler) executeTask(ctx context.Context, task *Task) {
 	startTime := time.Now()
 
-	log.Printf("Task started: %s (ID: %s)", task.Name, task.ID)
+	s.logger.Debug("Task started", "task", task.Name, "task_id", task.ID)
 
-	err := task.Handler(s.ctx)
+	var output string
+	err := task.Handler(withTaskOutput(llm.WithPriority(ctx, llm.PriorityBackground), &output))
 
 	duration := time.Since(startTime)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if err != nil {
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if timedOut {
+		err = fmt.Errorf("%w after %s", ErrTaskTimeout, task.Timeout)
+	}
+	// Cancelled by OverlapCancel rather than by stopping the scheduler.
+	cancelled := !timedOut && err != nil && ctx.Err() != nil && s.ctx.Err() == nil
+
+	task.cancelRun()
+	task.active = false
+	task.cancelRun = nil
+	if task.queued && s.running {
+		task.queued = false
+		select {
+		case s.taskChan <- task:
+		default:
+			s.logger.Warn("Task queue is full, dropping queued run", "task_id", task.ID)
+		}
+	}
+
+	if cancelled {
+		task.Status = StatusCancelled
+		s.logger.Info("Task cancelled", "task", task.Name, "task_id", task.ID, "duration", duration)
+	} else if err != nil {
 		task.Status = StatusFailed
 		task.ErrorCount++
 		task.LastError = err
-		log.Printf("Task failed: %s (ID: %s, Error: %v)", task.Name, task.ID, err)
+		s.logger.Error("Task failed", "task", task.Name, "task_id", task.ID, "error", err)
 	} else {
 		task.Status = StatusCompleted
 		task.RunCount++
-		log.Printf("Task completed: %s (ID: %s, Duration: %v)", task.Name, task.ID, duration)
+		s.logger.Info("Task completed", "task", task.Name, "task_id", task.ID, "duration", duration)
 	}
 
 	task.UpdatedAt = time.Now()
@@ -342,16 +562,49 @@ func (s *Scheduler) executeTask(task *Task) {
 	result := &TaskResult{
 		TaskID:    task.ID,
 		Status:    task.Status,
+		Output:    output,
 		Error:     err,
 		Duration:  duration,
 		Timestamp: time.Now(),
 	}
 
+	// Stop closes the channel; runs it cancelled finish after that.
+	if !s.running {
+		return
+	}
+
 	select {
 	case s.resultChan <- result:
 	default:
-		log.Printf("Result queue is full, dropping result for task: %s", task.ID)
+		s.logger.Warn("Result queue is full, dropping result", "task_id", task.ID)
+	}
+}
+
+// CatchUp queues runs missed while the process was down. They are started
+// one after another on the following ticks.
+func (s *Scheduler) CatchUp(taskID string, runs int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.tasks[taskID]
+	if !exists {
+		return fmt.Errorf("task with ID %s not found", taskID)
+	}
+
+	task.missed = runs
+	s.logger.Info("Catching up on missed runs", "task_id", taskID, "runs", runs, "last_run", task.LastRun)
+
+	return nil
+}
+
+// nextRun is the first run of a cron task after from, delayed by up to the
+// task's Jitter.
+func (s *Scheduler) nextRun(task *Task, from time.Time) (time.Time, error) {
+	next, err := s.calculateNextRun(task.CronExpr, from)
+	if err != nil || task.Jitter <= 0 {
+		return next, err
 	}
+	return next.Add(time.Duration(rand.Int63n(int64(task.Jitter)))), nil
 }
 
 func (s *Scheduler) calculateNextRun(cronExpr string, from time.Time) (time.Time, error) {
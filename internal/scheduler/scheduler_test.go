@@ -2,6 +2,8 @@ package scheduler
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -321,3 +323,213 @@ func TestCronParserInvalid(t *testing.T) {
 		t.Error("Expected error for invalid cron expression")
 	}
 }
+
+// startLongTask adds a task whose runs block until release is closed or
+// their context is cancelled, and reports each start on started.
+func startLongTask(t *testing.T, s *Scheduler, id, overlap string, started chan<- string, release <-chan struct{}) {
+	t.Helper()
+	err := s.AddTask(&Task{
+		ID:       id,
+		Name:     id,
+		CronExpr: "0 0 1 1 *",
+		Overlap:  overlap,
+		Handler: func(ctx context.Context) error {
+			started <- id
+			select {
+			case <-release:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+}
+
+func waitStarted(t *testing.T, started <-chan string, want string) {
+	t.Helper()
+	select {
+	case id := <-started:
+		if id != want {
+			t.Fatalf("Expected %s to start, got %s", want, id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for %s to start", want)
+	}
+}
+
+func expectNotStarted(t *testing.T, started <-chan string) {
+	t.Helper()
+	select {
+	case id := <-started:
+		t.Fatalf("Expected no run to start, %s started", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func waitResult(t *testing.T, s *Scheduler) *TaskResult {
+	t.Helper()
+	select {
+	case result := <-s.GetResults():
+		return result
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a result")
+		return nil
+	}
+}
+
+func TestOverlapSkip(t *testing.T) {
+	s := NewScheduler(&SchedulerConfig{TickInterval: time.Hour})
+	started, release := make(chan string, 10), make(chan struct{})
+	startLongTask(t, s, "long", "", started, release)
+	s.Start()
+	defer s.Stop()
+
+	s.TriggerTask("long")
+	waitStarted(t, started, "long")
+	s.TriggerTask("long")
+	expectNotStarted(t, started)
+
+	close(release)
+	if result := waitResult(t, s); result.Status != StatusCompleted {
+		t.Errorf("Expected the first run to complete, got %s", result.Status)
+	}
+	expectNotStarted(t, started)
+}
+
+func TestOverlapQueue(t *testing.T) {
+	s := NewScheduler(&SchedulerConfig{TickInterval: time.Hour})
+	started, release := make(chan string, 10), make(chan struct{})
+	startLongTask(t, s, "long", OverlapQueue, started, release)
+	s.Start()
+	defer s.Stop()
+
+	s.TriggerTask("long")
+	waitStarted(t, started, "long")
+	s.TriggerTask("long")
+	s.TriggerTask("long")
+	expectNotStarted(t, started)
+
+	close(release)
+	waitResult(t, s)
+	waitStarted(t, started, "long")
+	waitResult(t, s)
+	expectNotStarted(t, started)
+}
+
+func TestOverlapCancelPrevious(t *testing.T) {
+	s := NewScheduler(&SchedulerConfig{TickInterval: time.Hour})
+	started, release := make(chan string, 10), make(chan struct{})
+	startLongTask(t, s, "long", OverlapCancel, started, release)
+	s.Start()
+	defer s.Stop()
+
+	s.TriggerTask("long")
+	waitStarted(t, started, "long")
+	s.TriggerTask("long")
+
+	if result := waitResult(t, s); result.Status != StatusCancelled {
+		t.Errorf("Expected the first run to be cancelled, got %s", result.Status)
+	}
+	waitStarted(t, started, "long")
+
+	close(release)
+	if result := waitResult(t, s); result.Status != StatusCompleted {
+		t.Errorf("Expected the second run to complete, got %s", result.Status)
+	}
+	if task, _ := s.GetTask("long"); task.ErrorCount != 0 {
+		t.Errorf("Expected a cancelled run not to count as an error, got %d errors", task.ErrorCount)
+	}
+}
+
+func TestWorkerPoolLimitsConcurrentTasks(t *testing.T) {
+	s := NewScheduler(&SchedulerConfig{TickInterval: time.Hour, Workers: 2})
+	started, release := make(chan string, 10), make(chan struct{})
+	for _, id := range []string{"a", "b", "c"} {
+		startLongTask(t, s, id, "", started, release)
+	}
+	s.Start()
+	defer s.Stop()
+
+	for _, id := range []string{"a", "b", "c"} {
+		s.TriggerTask(id)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case id := <-started:
+			if id == "c" {
+				t.Fatal("Expected c to wait for a free worker")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for the first tasks to start")
+		}
+	}
+	expectNotStarted(t, started)
+
+	close(release)
+	waitStarted(t, started, "c")
+}
+
+func TestValidateOverlap(t *testing.T) {
+	s := NewScheduler(&SchedulerConfig{TickInterval: time.Hour})
+	err := s.AddTask(&Task{ID: "x", Name: "x", CronExpr: "* * * * *", Overlap: "parallel", Handler: func(ctx context.Context) error { return nil }})
+	if err == nil {
+		t.Error("Expected an unknown overlap policy to be rejected")
+	}
+}
+
+func TestTaskTimeout(t *testing.T) {
+	s := NewScheduler(&SchedulerConfig{TickInterval: time.Hour})
+	cancelled := make(chan struct{})
+	s.AddTask(&Task{
+		ID:       "slow",
+		Name:     "slow",
+		CronExpr: "0 0 1 1 *",
+		Timeout:  50 * time.Millisecond,
+		Handler: func(ctx context.Context) error {
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		},
+	})
+	s.Start()
+	defer s.Stop()
+
+	s.TriggerTask("slow")
+	result := waitResult(t, s)
+	if result.Status != StatusFailed || !errors.Is(result.Error, ErrTaskTimeout) {
+		t.Errorf("Expected the run to fail with a timeout, got %s: %v", result.Status, result.Error)
+	}
+	select {
+	case <-cancelled:
+	default:
+		t.Error("Expected the handler's context to be cancelled")
+	}
+	if run := newTaskRun(result); !strings.Contains(run.Error, "timed out after 50ms") {
+		t.Errorf("Expected the timeout in the recorded run, got %q", run.Error)
+	}
+}
+
+func TestJitterDelaysNextRun(t *testing.T) {
+	s := NewScheduler(&SchedulerConfig{TickInterval: time.Hour})
+	task := &Task{CronExpr: "0 * * * *", Jitter: 10 * time.Minute}
+	from := time.Date(2024, 1, 10, 10, 30, 0, 0, time.Local)
+	scheduled := time.Date(2024, 1, 10, 11, 0, 0, 0, time.Local)
+
+	delayed := false
+	for i := 0; i < 20; i++ {
+		next, err := s.nextRun(task, from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.Before(scheduled) || !next.Before(scheduled.Add(task.Jitter)) {
+			t.Fatalf("Expected the next run within the jitter after %v, got %v", scheduled, next)
+		}
+		delayed = delayed || next.After(scheduled)
+	}
+	if !delayed {
+		t.Error("Expected jitter to delay some runs")
+	}
+}
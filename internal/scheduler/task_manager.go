@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
 )
 
 type TaskManager struct {
@@ -17,6 +21,15 @@ type TaskManager struct {
 	mu        sync.RWMutex
 	ctx       context.Context
 	cancel    context.CancelFunc
+	logger    *slog.Logger
+	actions   map[string]ActionFactory
+	started   bool
+	history   TaskHistoryStore
+	retention time.Duration
+	maxRuns   int
+	// unloaded keeps tasks that could not be rebuilt so saving does not
+	// drop them from the file.
+	unloaded []TaskConfig
 }
 
 type TaskConfig struct {
@@ -24,11 +37,30 @@ type TaskConfig struct {
 	Name        string
 	Description string
 	CronExpr    string
+	RunAt       time.Time `json:",omitzero"`
 	Enabled     bool
+	Action      *Action `json:",omitempty"`
+	// Misfire decides what happens to runs missed while the process was
+	// down, judged from LastRun on start.
+	Misfire       string    `json:",omitempty"`
+	MaxMissedRuns int       `json:",omitempty"`
+	LastRun       time.Time `json:",omitzero"`
+	// Overlap decides what happens when the task is due while still
+	// running.
+	Overlap string `json:",omitempty"`
+	// Jitter and Timeout are in seconds.
+	Jitter  int `json:",omitempty"`
+	Timeout int `json:",omitempty"`
 }
 
 type TaskManagerConfig struct {
 	TasksFile string
+	Logger    *slog.Logger
+	// History records every run when set. Runs older than HistoryRetention
+	// and all but the newest HistoryMaxRuns of each task are pruned.
+	History          TaskHistoryStore
+	HistoryRetention time.Duration
+	HistoryMaxRuns   int
 }
 
 func NewTaskManager(scheduler *Scheduler, config *TaskManagerConfig) *TaskManager {
@@ -38,6 +70,11 @@ func NewTaskManager(scheduler *Scheduler, config *TaskManagerConfig) *TaskManage
 		}
 	}
 
+	logger := config.Logger
+	if logger == nil && scheduler != nil {
+		logger = scheduler.logger
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &TaskManager{
@@ -45,14 +82,21 @@ func NewTaskManager(scheduler *Scheduler, config *TaskManagerConfig) *TaskManage
 		tasksFile: config.TasksFile,
 		ctx:       ctx,
 		cancel:    cancel,
+		logger:    logging.Or(logger, "scheduler"),
+		actions:   make(map[string]ActionFactory),
+		history:   config.History,
+		retention: config.HistoryRetention,
+		maxRuns:   config.HistoryMaxRuns,
 	}
 }
 
 func (m *TaskManager) Start() error {
 	if err := m.loadTasks(); err != nil {
-		log.Printf("Warning: failed to load tasks: %v", err)
+		m.logger.Warn("Failed to load tasks", "error", err)
 	}
+	m.started = true
 
+	m.pruneHistory()
 	go m.watchResults()
 
 	return nil
@@ -61,8 +105,13 @@ func (m *TaskManager) Start() error {
 func (m *TaskManager) Stop() error {
 	m.cancel()
 
+	// Saving without having loaded would overwrite the file with nothing.
+	if !m.started {
+		return nil
+	}
+
 	if err := m.saveTasks(); err != nil {
-		log.Printf("Warning: failed to save tasks: %v", err)
+		m.logger.Warn("Failed to save tasks", "error", err)
 	}
 
 	return nil
@@ -70,20 +119,30 @@ func (m *TaskManager) Stop() error {
 
 func (m *TaskManager) AddTask(config *TaskConfig, handler TaskFunc) error {
 	task := &Task{
-		ID:          config.ID,
-		Name:        config.Name,
-		Description: config.Description,
-		CronExpr:    config.CronExpr,
-		Handler:     handler,
-		Enabled:     config.Enabled,
+		ID:            config.ID,
+		Name:          config.Name,
+		Description:   config.Description,
+		CronExpr:      config.CronExpr,
+		RunAt:         config.RunAt,
+		Handler:       handler,
+		Enabled:       config.Enabled,
+		Action:        config.Action,
+		Misfire:       config.Misfire,
+		MaxMissedRuns: config.MaxMissedRuns,
+		Overlap:       config.Overlap,
+		Jitter:        time.Duration(config.Jitter) * time.Second,
+		Timeout:       time.Duration(config.Timeout) * time.Second,
 	}
 
 	if err := m.scheduler.AddTask(task); err != nil {
 		return err
 	}
+	if !config.Enabled {
+		m.scheduler.DisableTask(task.ID)
+	}
 
 	if err := m.saveTasks(); err != nil {
-		log.Printf("Warning: failed to save tasks: %v", err)
+		m.logger.Warn("Failed to save tasks", "error", err)
 	}
 
 	return nil
@@ -95,7 +154,7 @@ func (m *TaskManager) RemoveTask(taskID string) error {
 	}
 
 	if err := m.saveTasks(); err != nil {
-		log.Printf("Warning: failed to save tasks: %v", err)
+		m.logger.Warn("Failed to save tasks", "error", err)
 	}
 
 	return nil
@@ -115,7 +174,7 @@ func (m *TaskManager) EnableTask(taskID string) error {
 	}
 
 	if err := m.saveTasks(); err != nil {
-		log.Printf("Warning: failed to save tasks: %v", err)
+		m.logger.Warn("Failed to save tasks", "error", err)
 	}
 
 	return nil
@@ -127,18 +186,44 @@ func (m *TaskManager) DisableTask(taskID string) error {
 	}
 
 	if err := m.saveTasks(); err != nil {
-		log.Printf("Warning: failed to save tasks: %v", err)
+		m.logger.Warn("Failed to save tasks", "error", err)
 	}
 
 	return nil
 }
 
+func (m *TaskManager) PostponeTask(taskID string, d time.Duration) (time.Time, error) {
+	nextRun, err := m.scheduler.Postpone(taskID, d)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := m.saveTasks(); err != nil {
+		m.logger.Warn("Failed to save tasks", "error", err)
+	}
+
+	return nextRun, nil
+}
+
 func (m *TaskManager) TriggerTask(taskID string) error {
 	return m.scheduler.TriggerTask(taskID)
 }
 
+const statsRecentRuns = 10
+
 func (m *TaskManager) GetStats() map[string]interface{} {
-	return m.scheduler.GetStats()
+	stats := m.scheduler.GetStats()
+
+	if m.history != nil {
+		tasks := m.scheduler.ListTasks()
+		taskIDs := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			taskIDs = append(taskIDs, task.ID)
+		}
+		stats["recent_runs"] = recentRuns(m.history, taskIDs, statsRecentRuns)
+	}
+
+	return stats
 }
 
 func (m *TaskManager) GetScheduler() *Scheduler {
@@ -150,41 +235,67 @@ func (m *TaskManager) loadTasks() error {
 	defer m.mu.Unlock()
 
 	if _, err := os.Stat(m.tasksFile); os.IsNotExist(err) {
-		log.Printf("Tasks file does not exist: %s", m.tasksFile)
+		m.logger.Info("Tasks file does not exist", "path", m.tasksFile)
 		return nil
 	}
 
-	data, err := os.ReadFile(m.tasksFile)
+	configs, err := ReadTasksFile(m.tasksFile)
 	if err != nil {
-		return fmt.Errorf("failed to read tasks file: %w", err)
-	}
-
-	var configs []TaskConfig
-	if err := json.Unmarshal(data, &configs); err != nil {
-		return fmt.Errorf("failed to unmarshal tasks: %w", err)
+		return err
 	}
 
+	m.unloaded = nil
+	loaded := 0
 	for _, config := range configs {
+		if config.Action == nil {
+			m.logger.Warn("Skipping task without an action", "task_id", config.ID)
+			m.unloaded = append(m.unloaded, config)
+			continue
+		}
+
+		handler, err := m.buildHandler(config.Action)
+		if err != nil {
+			m.logger.Warn("Failed to restore task", "task_id", config.ID, "error", err)
+			m.unloaded = append(m.unloaded, config)
+			continue
+		}
+
 		task := &Task{
-			ID:          config.ID,
-			Name:        config.Name,
-			Description: config.Description,
-			CronExpr:    config.CronExpr,
-			Enabled:     config.Enabled,
-			Status:      StatusPending,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+			ID:            config.ID,
+			Name:          config.Name,
+			Description:   config.Description,
+			CronExpr:      config.CronExpr,
+			RunAt:         config.RunAt,
+			Handler:       handler,
+			Action:        config.Action,
+			Status:        StatusPending,
+			LastRun:       config.LastRun,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+			Misfire:       config.Misfire,
+			MaxMissedRuns: config.MaxMissedRuns,
+			Overlap:       config.Overlap,
+			Jitter:        time.Duration(config.Jitter) * time.Second,
+			Timeout:       time.Duration(config.Timeout) * time.Second,
 		}
 
 		if err := m.scheduler.AddTask(task); err != nil {
-			log.Printf("Warning: failed to add task %s: %v", config.ID, err)
+			m.logger.Warn("Failed to add task", "task_id", config.ID, "error", err)
+			m.unloaded = append(m.unloaded, config)
 			continue
 		}
+		if !config.Enabled {
+			m.scheduler.DisableTask(task.ID)
+		}
+		if runs := catchUpRuns(task, time.Now()); runs > 0 {
+			m.scheduler.CatchUp(task.ID, runs)
+		}
 
-		log.Printf("Task loaded: %s (ID: %s)", task.Name, task.ID)
+		loaded++
+		m.logger.Debug("Task loaded", "task", task.Name, "task_id", task.ID)
 	}
 
-	log.Printf("Loaded %d tasks from file", len(configs))
+	m.logger.Info("Loaded tasks from file", "count", loaded, "skipped", len(m.unloaded), "path", m.tasksFile)
 
 	return nil
 }
@@ -193,30 +304,40 @@ func (m *TaskManager) saveTasks() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	tasks := m.scheduler.ListTasks()
-	configs := make([]TaskConfig, 0, len(tasks))
+	return WriteTasksFile(m.tasksFile, append(taskConfigs(m.scheduler.SnapshotTasks()), m.unloaded...))
+}
 
-	for _, task := range tasks {
-		configs = append(configs, TaskConfig{
-			ID:          task.ID,
-			Name:        task.Name,
-			Description: task.Description,
-			CronExpr:    task.CronExpr,
-			Enabled:     task.Enabled,
-		})
+// ReadTasksFile reads the tasks saved by a task manager. A missing file
+// holds no tasks.
+func ReadTasksFile(path string) ([]TaskConfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tasks file: %w", err)
 	}
 
+	var configs []TaskConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tasks: %w", err)
+	}
+	return configs, nil
+}
+
+// WriteTasksFile replaces the tasks in path. A running task manager
+// overwrites the file when its tasks change, so it has to be stopped first.
+func WriteTasksFile(path string, configs []TaskConfig) error {
 	data, err := json.MarshalIndent(configs, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal tasks: %w", err)
 	}
 
-	dir := filepath.Dir(m.tasksFile)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	if err := os.WriteFile(m.tasksFile, data, 0644); err != nil {
+	if err := storage.WriteFileAtomic(path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write tasks file: %w", err)
 	}
 
@@ -226,10 +347,15 @@ func (m *TaskManager) saveTasks() error {
 func (m *TaskManager) watchResults() {
 	resultChan := m.scheduler.GetResults()
 
+	pruneTicker := time.NewTicker(time.Hour)
+	defer pruneTicker.Stop()
+
 	for {
 		select {
 		case <-m.ctx.Done():
 			return
+		case <-pruneTicker.C:
+			m.pruneHistory()
 		case result, ok := <-resultChan:
 			if !ok {
 				return
@@ -243,18 +369,31 @@ func (m *TaskManager) watchResults() {
 func (m *TaskManager) handleResult(result *TaskResult) {
 	task, exists := m.scheduler.GetTask(result.TaskID)
 	if !exists {
-		log.Printf("Warning: task %s not found for result", result.TaskID)
+		m.logger.Warn("Task not found for result", "task_id", result.TaskID)
 		return
 	}
 
-	log.Printf("Task result: %s - Status: %s, Duration: %v", task.Name, result.Status, result.Duration)
+	m.logger.Debug("Task result", "task", task.Name, "status", result.Status, "duration", result.Duration)
 
 	if result.Error != nil {
-		log.Printf("Task error: %s - %v", task.Name, result.Error)
+		m.logger.Error("Task error", "task", task.Name, "error", result.Error)
+	}
+
+	if m.history != nil {
+		if err := m.history.Record(newTaskRun(result)); err != nil {
+			m.logger.Warn("Failed to record task run", "task_id", result.TaskID, "error", err)
+		}
+	}
+
+	if task.OneOff() {
+		if err := m.RemoveTask(task.ID); err != nil {
+			m.logger.Warn("Failed to remove one-off task", "task_id", task.ID, "error", err)
+		}
+		return
 	}
 
 	if err := m.saveTasks(); err != nil {
-		log.Printf("Warning: failed to save tasks after result: %v", err)
+		m.logger.Warn("Failed to save tasks after result", "error", err)
 	}
 }
 
@@ -262,20 +401,31 @@ func (m *TaskManager) ExportTasks() ([]byte, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	tasks := m.scheduler.ListTasks()
-	configs := make([]TaskConfig, 0, len(tasks))
+	return json.MarshalIndent(taskConfigs(m.scheduler.SnapshotTasks()), "", "  ")
+}
+
+func taskConfigs(tasks []Task) []TaskConfig {
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
 
+	configs := make([]TaskConfig, 0, len(tasks))
 	for _, task := range tasks {
 		configs = append(configs, TaskConfig{
-			ID:          task.ID,
-			Name:        task.Name,
-			Description: task.Description,
-			CronExpr:    task.CronExpr,
-			Enabled:     task.Enabled,
+			ID:            task.ID,
+			Name:          task.Name,
+			Description:   task.Description,
+			CronExpr:      task.CronExpr,
+			RunAt:         task.RunAt,
+			Enabled:       task.Enabled,
+			Action:        task.Action,
+			Misfire:       task.Misfire,
+			LastRun:       task.LastRun,
+			MaxMissedRuns: task.MaxMissedRuns,
+			Overlap:       task.Overlap,
+			Jitter:        int(task.Jitter / time.Second),
+			Timeout:       int(task.Timeout / time.Second),
 		})
 	}
-
-	return json.MarshalIndent(configs, "", "  ")
+	return configs
 }
 
 func (m *TaskManager) ImportTasks(data []byte) error {
@@ -290,17 +440,27 @@ func (m *TaskManager) ImportTasks(data []byte) error {
 			task.Name = config.Name
 			task.Description = config.Description
 			task.CronExpr = config.CronExpr
+			task.RunAt = config.RunAt
 			task.Enabled = config.Enabled
+			task.Misfire = config.Misfire
+			task.MaxMissedRuns = config.MaxMissedRuns
+			task.Overlap = config.Overlap
+			task.Jitter = time.Duration(config.Jitter) * time.Second
+			task.Timeout = time.Duration(config.Timeout) * time.Second
 			task.UpdatedAt = time.Now()
 
-			nextRun, err := m.scheduler.calculateNextRun(task.CronExpr, time.Now())
-			if err != nil {
-				log.Printf("Warning: failed to calculate next run for task %s: %v", config.ID, err)
-				continue
+			if task.OneOff() {
+				task.NextRun = task.RunAt
+			} else {
+				nextRun, err := m.scheduler.nextRun(task, time.Now())
+				if err != nil {
+					m.logger.Warn("Failed to calculate next run", "task_id", config.ID, "error", err)
+					continue
+				}
+				task.NextRun = nextRun
 			}
-			task.NextRun = nextRun
 
-			log.Printf("Task updated: %s (ID: %s)", task.Name, task.ID)
+			m.logger.Info("Task updated", "task", task.Name, "task_id", task.ID)
 		}
 	}
 
@@ -311,12 +471,39 @@ func (m *TaskManager) ImportTasks(data []byte) error {
 	return nil
 }
 
-func (m *TaskManager) GetTaskHistory(taskID string, limit int) ([]*TaskResult, error) {
-	return nil, fmt.Errorf("task history not implemented")
+// GetTaskHistory returns the runs of a task, newest first.
+func (m *TaskManager) GetTaskHistory(taskID string, limit, offset int) ([]*TaskRun, error) {
+	if m.history == nil {
+		return nil, fmt.Errorf("task history is not enabled")
+	}
+	return m.history.List(taskID, limit, offset)
 }
 
 func (m *TaskManager) ClearTaskHistory(taskID string) error {
-	return fmt.Errorf("task history not implemented")
+	if m.history == nil {
+		return fmt.Errorf("task history is not enabled")
+	}
+	return m.history.Clear(taskID)
+}
+
+func (m *TaskManager) pruneHistory() {
+	if m.history == nil || (m.retention <= 0 && m.maxRuns <= 0) {
+		return
+	}
+
+	var before time.Time
+	if m.retention > 0 {
+		before = time.Now().Add(-m.retention)
+	}
+
+	pruned, err := m.history.Prune(before, m.maxRuns)
+	if err != nil {
+		m.logger.Warn("Failed to prune task history", "error", err)
+		return
+	}
+	if pruned > 0 {
+		m.logger.Info("Pruned task history", "runs", pruned)
+	}
 }
 
 func (m *TaskManager) ValidateCronExpression(expr string) error {
@@ -349,7 +536,7 @@ func (m *TaskManager) PauseScheduler() error {
 	if err := m.scheduler.Stop(); err != nil {
 		return err
 	}
-	log.Println("Scheduler paused")
+	m.logger.Info("Scheduler paused")
 	return nil
 }
 
@@ -357,7 +544,7 @@ func (m *TaskManager) ResumeScheduler() error {
 	if err := m.scheduler.Start(); err != nil {
 		return err
 	}
-	log.Println("Scheduler resumed")
+	m.logger.Info("Scheduler resumed")
 	return nil
 }
 
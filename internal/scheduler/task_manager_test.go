@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestTaskManager(tasksFile string, ran chan<- string) *TaskManager {
+	manager := NewTaskManager(NewScheduler(&SchedulerConfig{TickInterval: time.Hour}), &TaskManagerConfig{TasksFile: tasksFile})
+	manager.RegisterAction(ActionAgentPrompt, func(action *Action) (TaskFunc, error) {
+		return func(ctx context.Context) error {
+			ran <- action.Prompt
+			return nil
+		}, nil
+	})
+	return manager
+}
+
+func TestTaskActionsSurviveRestart(t *testing.T) {
+	tasksFile := filepath.Join(t.TempDir(), "tasks.json")
+	ran := make(chan string, 1)
+
+	manager := newTestTaskManager(tasksFile, ran)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("Failed to start task manager: %v", err)
+	}
+
+	err := manager.AddActionTask(&TaskConfig{
+		ID:       "standup",
+		Name:     "Standup reminder",
+		CronExpr: "0 9 * * *",
+		Enabled:  true,
+		Action:   &Action{Type: ActionAgentPrompt, ChatID: "chat", Prompt: "Remind me about standup"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	manager.AddActionTask(&TaskConfig{
+		ID:       "paused",
+		Name:     "Paused",
+		CronExpr: "0 9 * * *",
+		Action:   &Action{Type: ActionAgentPrompt, ChatID: "chat", Prompt: "later"},
+	})
+	if err := manager.AddActionTask(&TaskConfig{ID: "bad", Name: "Bad", CronExpr: "0 9 * * *", Action: &Action{Type: ActionAgentPrompt}}); err == nil {
+		t.Error("Expected action without chat_id and prompt to be rejected")
+	}
+	manager.Stop()
+
+	data, _ := os.ReadFile(tasksFile)
+	var saved []TaskConfig
+	json.Unmarshal(data, &saved)
+	if len(saved) != 2 || saved[0].Action == nil || saved[0].Action.Type != ActionAgentPrompt {
+		t.Fatalf("Expected tasks to be saved with their actions, got %s", data)
+	}
+	saved = append(saved, TaskConfig{ID: "backup", Name: "Backup", CronExpr: "0 3 * * *", Enabled: true, Action: &Action{Type: ActionToolCall, Tool: "backup"}})
+	data, _ = json.Marshal(saved)
+	os.WriteFile(tasksFile, data, 0644)
+
+	restarted := newTestTaskManager(tasksFile, ran)
+	sched := restarted.GetScheduler()
+	sched.Start()
+	defer sched.Stop()
+	if err := restarted.Start(); err != nil {
+		t.Fatalf("Failed to restart task manager: %v", err)
+	}
+
+	task, ok := restarted.GetTask("standup")
+	if !ok || task.Handler == nil || task.Action.Prompt != "Remind me about standup" {
+		t.Fatalf("Expected task to be restored with a handler, got %+v", task)
+	}
+	if paused, ok := restarted.GetTask("paused"); !ok || paused.Enabled {
+		t.Error("Expected disabled task to stay disabled")
+	}
+	if _, ok := restarted.GetTask("backup"); ok {
+		t.Error("Expected task with an unregistered action type not to be scheduled")
+	}
+
+	if err := restarted.TriggerTask("standup"); err != nil {
+		t.Fatalf("Failed to trigger task: %v", err)
+	}
+	select {
+	case prompt := <-ran:
+		if prompt != "Remind me about standup" {
+			t.Errorf("Unexpected prompt %q", prompt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected restored task to run")
+	}
+
+	restarted.RemoveTask("paused")
+	data, _ = os.ReadFile(tasksFile)
+	saved = nil
+	json.Unmarshal(data, &saved)
+	if len(saved) != 2 || saved[1].ID != "backup" {
+		t.Errorf("Expected unrestorable task to be kept in the file, got %+v", saved)
+	}
+}
+
+func TestMissedRunsCaughtUpOnStart(t *testing.T) {
+	tasksFile := filepath.Join(t.TempDir(), "tasks.json")
+	lastRun := time.Now().Add(-5*time.Hour - time.Minute)
+	configs := []TaskConfig{
+		{ID: "skip", Name: "Skip", CronExpr: "0 * * * *", Enabled: true, LastRun: lastRun,
+			Action: &Action{Type: ActionAgentPrompt, ChatID: "chat", Prompt: "skip"}},
+		{ID: "once", Name: "Once", CronExpr: "0 * * * *", Enabled: true, LastRun: lastRun, Misfire: MisfireRunOnce,
+			Action: &Action{Type: ActionAgentPrompt, ChatID: "chat", Prompt: "once"}},
+		{ID: "all", Name: "All", CronExpr: "0 * * * *", Enabled: true, LastRun: lastRun, Misfire: MisfireRunAll, MaxMissedRuns: 3,
+			Action: &Action{Type: ActionAgentPrompt, ChatID: "chat", Prompt: "all"}},
+		{ID: "new", Name: "New", CronExpr: "0 * * * *", Enabled: true, Misfire: MisfireRunAll,
+			Action: &Action{Type: ActionAgentPrompt, ChatID: "chat", Prompt: "new"}},
+	}
+	data, _ := json.Marshal(configs)
+	os.WriteFile(tasksFile, data, 0644)
+
+	manager := newTestTaskManager(tasksFile, make(chan string, 10))
+	if err := manager.Start(); err != nil {
+		t.Fatalf("Failed to start task manager: %v", err)
+	}
+	defer manager.Stop()
+
+	for id, want := range map[string]int{"skip": 0, "once": 1, "all": 3, "new": 0} {
+		task, ok := manager.GetTask(id)
+		if !ok {
+			t.Fatalf("Task %s was not loaded", id)
+		}
+		if task.missed != want {
+			t.Errorf("Task %s: expected %d missed runs, got %d", id, want, task.missed)
+		}
+	}
+
+	exported, _ := manager.ExportTasks()
+	var saved []TaskConfig
+	json.Unmarshal(exported, &saved)
+	for _, config := range saved {
+		if config.ID == "all" && (config.Misfire != MisfireRunAll || config.MaxMissedRuns != 3 || !config.LastRun.Equal(lastRun)) {
+			t.Errorf("Expected misfire policy and last run to be saved, got %+v", config)
+		}
+	}
+}
+
+func TestMissedRunsRunOneAtATime(t *testing.T) {
+	ran := make(chan string, 10)
+	scheduler := NewScheduler(&SchedulerConfig{TickInterval: time.Hour})
+	scheduler.AddTask(&Task{ID: "all", Name: "All", CronExpr: "0 0 1 1 *", Handler: func(ctx context.Context) error {
+		ran <- "all"
+		return nil
+	}})
+	scheduler.CatchUp("all", 2)
+
+	scheduler.checkAndScheduleTasks()
+	scheduler.checkAndScheduleTasks()
+	task, _ := scheduler.GetTask("all")
+	if task.missed != 1 || len(scheduler.taskChan) != 1 {
+		t.Fatalf("Expected one missed run to be queued while the first runs, got %d queued and %d left", len(scheduler.taskChan), task.missed)
+	}
+
+	task.Status = StatusCompleted
+	scheduler.checkAndScheduleTasks()
+	if task.missed != 0 || len(scheduler.taskChan) != 2 {
+		t.Errorf("Expected the second missed run to be queued, got %d queued and %d left", len(scheduler.taskChan), task.missed)
+	}
+}
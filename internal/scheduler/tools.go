@@ -0,0 +1,256 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+// NewSchedulerTools lets the model create, list, cancel and snooze tasks for
+// the chat it is talking to. Tasks run an agent_prompt action, so the answer
+// is delivered to the chat over the channel the task was created from.
+func NewSchedulerTools(manager *TaskManager) []tools.Tool {
+	return []tools.Tool{
+		newScheduleTaskTool(manager),
+		newListTasksTool(manager),
+		newCancelTaskTool(manager),
+		newSnoozeTaskTool(manager),
+	}
+}
+
+func requestChatID(ctx context.Context) (string, error) {
+	chatID, ok := tools.ChatIDFromContext(ctx)
+	if !ok {
+		return "", &tools.ToolError{Code: "NO_CHAT", Message: "tasks can only be managed from a chat"}
+	}
+	return chatID, nil
+}
+
+// chatTask returns the task if it belongs to the requesting chat. Tasks of
+// other chats are reported as missing.
+func chatTask(ctx context.Context, manager *TaskManager, params map[string]interface{}) (*Task, error) {
+	chatID, err := requestChatID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	taskID, _ := params["task_id"].(string)
+	task, exists := manager.GetTask(taskID)
+	if !exists || task.Action == nil || task.Action.ChatID != chatID {
+		return nil, &tools.ToolError{Code: "NOT_FOUND", Message: fmt.Sprintf("task %q not found", taskID)}
+	}
+	return task, nil
+}
+
+func describeSchedule(task *Task) string {
+	if task.OneOff() {
+		return "once"
+	}
+	return fmt.Sprintf("%s, cron %s", DescribeCronExpression(task.CronExpr), task.CronExpr)
+}
+
+func newScheduleTaskTool(manager *TaskManager) tools.Tool {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"schedule": {
+				"type": "string",
+				"description": "When to run: a delay such as \"in 2 hours\" or \"in 30 minutes\" for a one-off task, or a cron expression such as \"0 9 * * 1-5\", a macro such as \"@daily\" or an interval such as \"@every 15m\" for a recurring one"
+			},
+			"prompt": {
+				"type": "string",
+				"description": "Instruction you will receive when the task fires, e.g. \"Remind the user to call the dentist\""
+			},
+			"name": {
+				"type": "string",
+				"description": "Short name for the task (optional)"
+			},
+			"misfire": {
+				"type": "string",
+				"enum": ["skip", "run_once", "run_all"],
+				"description": "For recurring tasks, what to do with runs missed while the assistant was offline: skip them (default), run once on start, or run each missed run"
+			}
+		},
+		"required": ["schedule", "prompt"],
+		"additionalProperties": false
+	}`)
+
+	return tools.NewBaseTool(
+		"schedule_task",
+		"Schedule a reminder or recurring task for this chat. When it fires, the prompt is handled like a message from the user and your answer is sent to this chat",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			chatID, err := requestChatID(ctx)
+			if err != nil {
+				return "", err
+			}
+
+			prompt, _ := params["prompt"].(string)
+			if strings.TrimSpace(prompt) == "" {
+				return "", &tools.ToolError{Code: "INVALID_PARAM", Message: "prompt cannot be empty"}
+			}
+
+			schedule, _ := params["schedule"].(string)
+			now := time.Now()
+			cronExpr, runAt, err := ParseSchedule(schedule, now)
+			if err != nil {
+				return "", &tools.ToolError{Code: "INVALID_SCHEDULE", Message: err.Error()}
+			}
+
+			name, _ := params["name"].(string)
+			if strings.TrimSpace(name) == "" {
+				name = prompt
+			}
+
+			misfire, _ := params["misfire"].(string)
+			if err := ValidateMisfire(misfire); err != nil {
+				return "", &tools.ToolError{Code: "INVALID_PARAM", Message: err.Error()}
+			}
+
+			channel, _ := tools.ChannelFromContext(ctx)
+			config := &TaskConfig{
+				ID:       fmt.Sprintf("chat-%d", now.UnixNano()),
+				Name:     name,
+				CronExpr: cronExpr,
+				RunAt:    runAt,
+				Enabled:  true,
+				Misfire:  misfire,
+				Action: &Action{
+					Type:    ActionAgentPrompt,
+					Channel: channel,
+					ChatID:  chatID,
+					Prompt:  prompt,
+				},
+			}
+			if err := manager.AddActionTask(config); err != nil {
+				return "", fmt.Errorf("failed to schedule task: %w", err)
+			}
+
+			nextRun, _ := manager.GetNextRunTime(config.ID)
+			if cronExpr != "" {
+				return fmt.Sprintf("Scheduled task %s (%s) to run %s, next run at %s", config.ID, name, DescribeCronExpression(cronExpr), nextRun.Format(time.RFC3339)), nil
+			}
+			return fmt.Sprintf("Scheduled task %s (%s), next run at %s", config.ID, name, nextRun.Format(time.RFC3339)), nil
+		},
+	)
+}
+
+func newListTasksTool(manager *TaskManager) tools.Tool {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {},
+		"additionalProperties": false
+	}`)
+
+	return tools.NewBaseTool(
+		"list_tasks",
+		"List the scheduled tasks and reminders of this chat",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			chatID, err := requestChatID(ctx)
+			if err != nil {
+				return "", err
+			}
+
+			var owned []*Task
+			for _, task := range manager.ListTasks() {
+				if task.Action != nil && task.Action.ChatID == chatID {
+					owned = append(owned, task)
+				}
+			}
+			if len(owned) == 0 {
+				return "No scheduled tasks", nil
+			}
+			sort.Slice(owned, func(i, j int) bool { return owned[i].NextRun.Before(owned[j].NextRun) })
+
+			var output strings.Builder
+			output.WriteString(fmt.Sprintf("Found %d scheduled tasks:\n\n", len(owned)))
+			for _, task := range owned {
+				next := task.NextRun.Format(time.RFC3339)
+				if !task.Enabled {
+					next = "paused"
+				}
+				output.WriteString(fmt.Sprintf("- %s: %s (%s, next run %s)\n", task.ID, task.Name, describeSchedule(task), next))
+			}
+			return output.String(), nil
+		},
+	)
+}
+
+func newCancelTaskTool(manager *TaskManager) tools.Tool {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"task_id": {
+				"type": "string",
+				"description": "ID of the task, as shown by list_tasks"
+			}
+		},
+		"required": ["task_id"],
+		"additionalProperties": false
+	}`)
+
+	return tools.NewBaseTool(
+		"cancel_task",
+		"Cancel a scheduled task or reminder of this chat",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			task, err := chatTask(ctx, manager, params)
+			if err != nil {
+				return "", err
+			}
+
+			if err := manager.RemoveTask(task.ID); err != nil {
+				return "", fmt.Errorf("failed to cancel task: %w", err)
+			}
+			return fmt.Sprintf("Cancelled task %s (%s)", task.ID, task.Name), nil
+		},
+	)
+}
+
+func newSnoozeTaskTool(manager *TaskManager) tools.Tool {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"task_id": {
+				"type": "string",
+				"description": "ID of the task, as shown by list_tasks"
+			},
+			"delay": {
+				"type": "string",
+				"description": "How long to postpone the next run, e.g. \"10 minutes\" or \"1 hour\""
+			}
+		},
+		"required": ["task_id", "delay"],
+		"additionalProperties": false
+	}`)
+
+	return tools.NewBaseTool(
+		"snooze_task",
+		"Postpone the next run of a scheduled task or reminder of this chat",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			task, err := chatTask(ctx, manager, params)
+			if err != nil {
+				return "", err
+			}
+
+			raw, _ := params["delay"].(string)
+			delay, err := ParseDelay(raw)
+			if err != nil {
+				return "", &tools.ToolError{Code: "INVALID_DELAY", Message: err.Error()}
+			}
+
+			nextRun, err := manager.PostponeTask(task.ID, delay)
+			if err != nil {
+				return "", fmt.Errorf("failed to snooze task: %w", err)
+			}
+			return fmt.Sprintf("Snoozed task %s (%s) until %s", task.ID, task.Name, nextRun.Format(time.RFC3339)), nil
+		},
+	)
+}
@@ -0,0 +1,221 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+func TestParseDelay(t *testing.T) {
+	tests := []struct {
+		expr string
+		want time.Duration
+	}{
+		{"in 2 hours", 2 * time.Hour},
+		{"30 minutes", 30 * time.Minute},
+		{"in an hour and 15 mins", 75 * time.Minute},
+		{"1 day, 2h", 26 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"In 1 Week", 7 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDelay(tt.expr)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDelay(%q) = %v, %v; want %v", tt.expr, got, err, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "tomorrow", "in 2 fortnights", "0 9 * * *", "-5m"} {
+		if _, err := ParseDelay(expr); err == nil {
+			t.Errorf("Expected ParseDelay(%q) to fail", expr)
+		}
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	now := time.Now()
+
+	cronExpr, runAt, err := ParseSchedule("0 9 * * 1-5", now)
+	if err != nil || cronExpr != "0 9 * * 1-5" || !runAt.IsZero() {
+		t.Errorf("Expected recurring schedule, got %q %v %v", cronExpr, runAt, err)
+	}
+
+	cronExpr, runAt, err = ParseSchedule("in 2 hours", now)
+	if err != nil || cronExpr != "" || !runAt.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("Expected one-off schedule, got %q %v %v", cronExpr, runAt, err)
+	}
+
+	if _, _, err := ParseSchedule("61 * * * *", now); err == nil {
+		t.Error("Expected invalid cron expression to be rejected")
+	}
+}
+
+func runSchedulerTool(t *testing.T, manager *TaskManager, ctx context.Context, name string, params map[string]interface{}) (string, error) {
+	t.Helper()
+	for _, tool := range NewSchedulerTools(manager) {
+		if tool.Name() == name {
+			return tool.Execute(ctx, params)
+		}
+	}
+	t.Fatalf("Tool %s not found", name)
+	return "", nil
+}
+
+func TestPromptTask(t *testing.T) {
+	now := time.Now()
+
+	config, err := (&PromptTask{Schedule: "@daily", Prompt: "Summarize the news", Channel: "discord", ChatID: "42"}).TaskConfig(now)
+	if err != nil {
+		t.Fatalf("Failed to build task: %v", err)
+	}
+	if config.CronExpr != "@daily" || config.Name != "Summarize the news" || !config.Enabled || config.Action.Validate() != nil || config.Action.Channel != "discord" {
+		t.Errorf("Unexpected task %+v %+v", config, config.Action)
+	}
+
+	tasksFile := filepath.Join(t.TempDir(), "tasks", "tasks.json")
+	if configs, err := ReadTasksFile(tasksFile); err != nil || len(configs) != 0 {
+		t.Errorf("Expected no tasks without a file, got %v, %v", configs, err)
+	}
+	if err := WriteTasksFile(tasksFile, []TaskConfig{*config}); err != nil {
+		t.Fatalf("Failed to write tasks: %v", err)
+	}
+	if configs, err := ReadTasksFile(tasksFile); err != nil || len(configs) != 1 || configs[0].Action.Prompt != "Summarize the news" {
+		t.Errorf("Expected the task back, got %v, %v", configs, err)
+	}
+
+	for _, invalid := range []PromptTask{
+		{Schedule: "@daily", ChatID: "42"},
+		{Schedule: "@daily", Prompt: "x"},
+		{Schedule: "whenever", Prompt: "x", ChatID: "42"},
+		{Schedule: "@daily", Prompt: "x", ChatID: "42", Misfire: "sometimes"},
+	} {
+		if _, err := invalid.TaskConfig(now); err == nil {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestSchedulerToolsAreBoundToChat(t *testing.T) {
+	ran := make(chan string, 1)
+	manager := newTestTaskManager(filepath.Join(t.TempDir(), "tasks.json"), ran)
+	manager.Start()
+	defer manager.Stop()
+
+	ctx := tools.WithChannel(tools.WithChatID(context.Background(), "alice"), "websocket")
+	other := tools.WithChatID(context.Background(), "bob")
+
+	result, err := runSchedulerTool(t, manager, ctx, "schedule_task", map[string]interface{}{
+		"schedule": "in 2 hours",
+		"prompt":   "Remind the user to stretch",
+	})
+	if err != nil {
+		t.Fatalf("Failed to schedule task: %v", err)
+	}
+
+	tasks := manager.ListTasks()
+	if len(tasks) != 1 {
+		t.Fatalf("Expected one task, got %d", len(tasks))
+	}
+	task := tasks[0]
+	if !strings.Contains(result, task.ID) || !task.OneOff() {
+		t.Errorf("Expected one-off task %s in result %q", task.ID, result)
+	}
+	if task.Action.ChatID != "alice" || task.Action.Channel != "websocket" {
+		t.Errorf("Expected task bound to the requesting chat and channel, got %+v", task.Action)
+	}
+
+	if _, err := runSchedulerTool(t, manager, ctx, "schedule_task", map[string]interface{}{"schedule": "every so often", "prompt": "x"}); toolErrorCode(err) != "INVALID_SCHEDULE" {
+		t.Errorf("Expected invalid schedule error, got %v", err)
+	}
+	if _, err := runSchedulerTool(t, manager, context.Background(), "schedule_task", map[string]interface{}{"schedule": "in 1h", "prompt": "x"}); toolErrorCode(err) != "NO_CHAT" {
+		t.Errorf("Expected error without a chat, got %v", err)
+	}
+
+	if list, _ := runSchedulerTool(t, manager, other, "list_tasks", nil); list != "No scheduled tasks" {
+		t.Errorf("Expected other chats not to see the task, got %q", list)
+	}
+	if list, _ := runSchedulerTool(t, manager, ctx, "list_tasks", nil); !strings.Contains(list, task.ID) {
+		t.Errorf("Expected task in list, got %q", list)
+	}
+
+	before := task.NextRun
+	if _, err := runSchedulerTool(t, manager, ctx, "snooze_task", map[string]interface{}{"task_id": task.ID, "delay": "10 minutes"}); err != nil {
+		t.Fatalf("Failed to snooze task: %v", err)
+	}
+	if !task.NextRun.Equal(before.Add(10*time.Minute)) || !task.RunAt.Equal(task.NextRun) {
+		t.Errorf("Expected next run to move by 10 minutes, got %v -> %v", before, task.NextRun)
+	}
+
+	if _, err := runSchedulerTool(t, manager, other, "cancel_task", map[string]interface{}{"task_id": task.ID}); toolErrorCode(err) != "NOT_FOUND" {
+		t.Errorf("Expected other chats not to cancel the task, got %v", err)
+	}
+	if _, err := runSchedulerTool(t, manager, ctx, "cancel_task", map[string]interface{}{"task_id": task.ID}); err != nil {
+		t.Fatalf("Failed to cancel task: %v", err)
+	}
+	if len(manager.ListTasks()) != 0 {
+		t.Error("Expected task to be removed")
+	}
+}
+
+func TestOneOffTaskRunsOnce(t *testing.T) {
+	ran := make(chan string, 2)
+	manager := NewTaskManager(NewScheduler(&SchedulerConfig{TickInterval: 10 * time.Millisecond}), &TaskManagerConfig{TasksFile: filepath.Join(t.TempDir(), "tasks.json")})
+	manager.RegisterAction(ActionAgentPrompt, func(action *Action) (TaskFunc, error) {
+		return func(ctx context.Context) error {
+			ran <- action.Prompt
+			return nil
+		}, nil
+	})
+	manager.Start()
+	manager.GetScheduler().Start()
+	defer manager.GetScheduler().Stop()
+	defer manager.Stop()
+
+	err := manager.AddActionTask(&TaskConfig{
+		ID:      "once",
+		Name:    "Once",
+		RunAt:   time.Now(),
+		Enabled: true,
+		Action:  &Action{Type: ActionAgentPrompt, ChatID: "chat", Prompt: "ping"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Expected one-off task to run")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, exists := manager.GetTask("once"); !exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected one-off task to be removed after it ran")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-ran:
+		t.Error("Expected one-off task to run only once")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func toolErrorCode(err error) string {
+	var toolErr *tools.ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr.Code
+	}
+	return ""
+}
@@ -1,21 +1,23 @@
 package search
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
-
-	"github.com/wjffsx/miniclaw_go/internal/tools"
 )
 
 type BraveSearchClient struct {
 	apiKey     string
 	baseURL    string
+	newsURL    string
 	httpClient *http.Client
+	defaults   SearchOptions
 }
 
 type SearchConfig struct {
@@ -23,12 +25,24 @@ type SearchConfig struct {
 	BaseURL    string
 	MaxResults int
 	Timeout    time.Duration
+	// Country, Language and SafeSearch apply to searches that do not set
+	// their own.
+	Country    string
+	Language   string
+	SafeSearch string
+	// EngineID is the Programmable Search Engine ID (cx) Google needs.
+	EngineID string
 }
 
 type SearchResult struct {
 	Title   string `json:"title"`
 	URL     string `json:"url"`
 	Snippet string `json:"description"`
+	// Published is when the page was published, as a timestamp or relative
+	// like "2 hours ago", if the provider knows.
+	Published string `json:"page_age,omitempty"`
+	// Source is the publisher; empty means the host of URL.
+	Source string `json:"-"`
 }
 
 type SearchResponse struct {
@@ -40,6 +54,19 @@ type SearchResponse struct {
 	} `json:"web"`
 }
 
+type braveNewsResponse struct {
+	Results []struct {
+		Title       string `json:"title"`
+		URL         string `json:"url"`
+		Description string `json:"description"`
+		Age         string `json:"age"`
+		PageAge     string `json:"page_age"`
+		MetaURL     struct {
+			Hostname string `json:"hostname"`
+		} `json:"meta_url"`
+	} `json:"results"`
+}
+
 func NewBraveSearchClient(config *SearchConfig) *BraveSearchClient {
 	if config == nil {
 		config = &SearchConfig{
@@ -64,137 +91,134 @@ func NewBraveSearchClient(config *SearchConfig) *BraveSearchClient {
 	return &BraveSearchClient{
 		apiKey:  config.APIKey,
 		baseURL: baseURL,
+		newsURL: strings.Replace(baseURL, "/web/search", "/news/search", 1),
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
 		},
+		defaults: SearchOptions{
+			Country:    config.Country,
+			Language:   config.Language,
+			SafeSearch: config.SafeSearch,
+		},
 	}
 }
 
-func (c *BraveSearchClient) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
-	if count <= 0 {
-		count = 10
-	}
-
-	if count > 20 {
-		count = 20
-	}
-
-	searchURL := fmt.Sprintf("%s?q=%s&count=%d", c.baseURL, url.QueryEscape(query), count)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+func (c *BraveSearchClient) Name() string {
+	return "brave"
+}
 
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Accept-Encoding", "gzip")
-	req.Header.Set("X-Subscription-Token", c.apiKey)
+func (c *BraveSearchClient) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	return c.SearchWithOptions(ctx, query, count, SearchOptions{})
+}
 
-	resp, err := c.httpClient.Do(req)
+// SearchWithOptions searches like Search, restricted by opts.
+func (c *BraveSearchClient) SearchWithOptions(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	params, err := c.params(query, count, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform search: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("search failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
 	var searchResp SearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := c.get(ctx, c.baseURL, params, &searchResp); err != nil {
+		return nil, err
 	}
 
 	return searchResp.Web.Results, nil
 }
 
-type WebSearchTool struct {
-	client *BraveSearchClient
-}
-
-func NewWebSearchTool(client *BraveSearchClient) *WebSearchTool {
-	return &WebSearchTool{
-		client: client,
+// SearchNews searches Brave's news index. Results carry their publisher and
+// publication time.
+func (c *BraveSearchClient) SearchNews(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	params, err := c.params(query, count, opts)
+	if err != nil {
+		return nil, err
 	}
-}
 
-func (t *WebSearchTool) Name() string {
-	return "web_search"
-}
+	var newsResp braveNewsResponse
+	if err := c.get(ctx, c.newsURL, params, &newsResp); err != nil {
+		return nil, err
+	}
 
-func (t *WebSearchTool) Description() string {
-	return "Search the web for information using Brave Search API"
+	results := make([]SearchResult, 0, len(newsResp.Results))
+	for _, result := range newsResp.Results {
+		published := result.PageAge
+		if published == "" {
+			published = result.Age
+		}
+		results = append(results, SearchResult{
+			Title:     result.Title,
+			URL:       result.URL,
+			Snippet:   result.Description,
+			Published: published,
+			Source:    strings.TrimPrefix(result.MetaURL.Hostname, "www."),
+		})
+	}
+	return results, nil
 }
 
-func (t *WebSearchTool) Parameters() json.RawMessage {
-	params := json.RawMessage(`{
-		"type": "object",
-		"properties": {
-			"query": {
-				"type": "string",
-				"description": "The search query"
-			},
-			"count": {
-				"type": "integer",
-				"description": "Number of results to return (1-20, default 10)",
-				"default": 10,
-				"minimum": 1,
-				"maximum": 20
-			}
-		},
-		"required": ["query"],
-		"additionalProperties": false
-	}`)
-	return params
-}
+func (c *BraveSearchClient) params(query string, count int, opts SearchOptions) (url.Values, error) {
+	count = clampCount(count, 20)
+	opts = opts.withDefaults(c.defaults)
 
-func (t *WebSearchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
-	query, ok := params["query"].(string)
-	if !ok {
-		return "", &tools.ToolError{
-			Code:    "INVALID_PARAM",
-			Message: "query parameter must be a string",
-		}
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("count", fmt.Sprint(count))
+	if opts.Page > 1 {
+		params.Set("offset", fmt.Sprint(min(opts.Page, maxPage)-1))
 	}
-
-	if query == "" {
-		return "", &tools.ToolError{
-			Code:    "INVALID_PARAM",
-			Message: "query parameter cannot be empty",
+	if opts.Freshness != "" {
+		freshness, err := parseFreshness(opts.Freshness)
+		if err != nil {
+			return nil, err
 		}
+		params.Set("freshness", freshness)
+	}
+	if opts.Country != "" {
+		params.Set("country", strings.ToUpper(opts.Country))
+	}
+	if opts.Language != "" {
+		params.Set("search_lang", strings.ToLower(opts.Language))
 	}
+	if opts.SafeSearch != "" {
+		params.Set("safesearch", opts.SafeSearch)
+	}
+	return params, nil
+}
 
-	count := 10
-	if c, ok := params["count"].(float64); ok {
-		count = int(c)
-		if count < 1 {
-			count = 1
-		}
-		if count > 20 {
-			count = 20
-		}
+func (c *BraveSearchClient) get(ctx context.Context, endpoint string, params url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	results, err := t.client.Search(ctx, query, count)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("X-Subscription-Token", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", &tools.ToolError{
-			Code:    "EXECUTION_FAILED",
-			Message: "failed to perform web search",
-			Err:     err,
-		}
+		return fmt.Errorf("failed to perform search: %w", err)
 	}
+	defer resp.Body.Close()
 
-	if len(results) == 0 {
-		return "No search results found", nil
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
 	}
 
-	output := fmt.Sprintf("Found %d search results for '%s':\n\n", len(results), query)
-	for i, result := range results {
-		output += fmt.Sprintf("%d. %s\n", i+1, result.Title)
-		output += fmt.Sprintf("   URL: %s\n", result.URL)
-		output += fmt.Sprintf("   %s\n\n", result.Snippet)
+	// Asking for gzip explicitly turns off the transport's transparent
+	// decompression.
+	body := io.Reader(resp.Body)
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decompress response: %w", err)
+		}
+		defer gz.Close()
+		body = gz
 	}
 
-	return output, nil
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
 }
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/wjffsx/miniclaw_go/internal/tools"
@@ -309,3 +311,64 @@ func TestWebSearchTool_Execute_CustomCount(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && (s[:len(substr)] == substr || contains(s[1:], substr)))
 }
+
+func TestWebSearchTool_Execute_Options(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"web":{"results":[
+			{"title":"A","url":"https://example.com/a"},
+			{"title":"A again","url":"http://www.example.com/a/#top"},
+			{"title":"B","url":"https://example.com/b"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	client := NewBraveSearchClient(&SearchConfig{APIKey: "test-api-key", BaseURL: server.URL, Country: "de", SafeSearch: "strict"})
+	tool := NewWebSearchTool(client)
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{
+		"query":      "go",
+		"count":      float64(2),
+		"page":       float64(3),
+		"freshness":  "week",
+		"language":   "EN",
+		"safesearch": "off",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	for key, want := range map[string]string{"q": "go", "count": "2", "offset": "2", "freshness": "pw", "country": "DE", "search_lang": "en", "safesearch": "off"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("Expected %s=%q, got %q", key, want, got)
+		}
+	}
+	if strings.Contains(result, "A again") || !strings.Contains(result, "5. A") || !strings.Contains(result, "6. B") {
+		t.Errorf("Expected deduplicated results numbered from the page, got %q", result)
+	}
+	if !strings.Contains(result, "page 4") {
+		t.Errorf("Expected a hint about the next page, got %q", result)
+	}
+
+	for _, params := range []map[string]interface{}{
+		{"query": "go", "freshness": "decade"},
+		{"query": "go", "safesearch": "high"},
+	} {
+		if _, err := tool.Execute(context.Background(), params); err == nil {
+			t.Errorf("Expected %v to be rejected", params)
+		}
+	}
+}
+
+func TestParseFreshness(t *testing.T) {
+	for input, want := range map[string]string{"day": "pd", "Month": "pm", "py": "py", "2024-01-01to2024-06-30": "2024-01-01to2024-06-30"} {
+		if got, err := parseFreshness(input); err != nil || got != want {
+			t.Errorf("parseFreshness(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := parseFreshness("2024-01-01"); err == nil {
+		t.Error("Expected a single date to be rejected")
+	}
+}
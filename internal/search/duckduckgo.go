@@ -0,0 +1,157 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DuckDuckGoClient scrapes the HTML version of DuckDuckGo, which needs no API
+// key. Its markup can change without notice, so it is best used as a
+// fallback.
+type DuckDuckGoClient struct {
+	baseURL    string
+	httpClient *http.Client
+	defaults   SearchOptions
+}
+
+var (
+	ddgResultPattern  = regexp.MustCompile(`(?is)<a[^>]+class="[^"]*result__a[^"]*"[^>]+href="([^"]+)"[^>]*>(.*?)</a>`)
+	ddgSnippetPattern = regexp.MustCompile(`(?is)class="[^"]*result__snippet[^"]*"[^>]*>(.*?)</(?:a|div|td)>`)
+	ddgTagPattern     = regexp.MustCompile(`<[^>]*>`)
+)
+
+var ddgSafeSearch = map[string]string{"off": "-2", "moderate": "-1", "strict": "1"}
+
+func NewDuckDuckGoClient(config *SearchConfig) *DuckDuckGoClient {
+	if config == nil {
+		config = &SearchConfig{}
+	}
+
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://html.duckduckgo.com/html/"
+	}
+
+	return &DuckDuckGoClient{
+		baseURL:    baseURL,
+		httpClient: newHTTPClient(config),
+		defaults: SearchOptions{
+			Country:    config.Country,
+			Language:   config.Language,
+			SafeSearch: config.SafeSearch,
+		},
+	}
+}
+
+func (c *DuckDuckGoClient) Name() string {
+	return "duckduckgo"
+}
+
+func (c *DuckDuckGoClient) SearchWithOptions(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	count = clampCount(count, 20)
+	opts = opts.withDefaults(c.defaults)
+
+	params := url.Values{}
+	params.Set("q", query)
+	if opts.Page > 1 {
+		params.Set("s", fmt.Sprint((opts.Page-1)*count))
+	}
+	if opts.Freshness != "" {
+		period, from, to := freshnessPeriod(opts.Freshness)
+		if from != "" {
+			params.Set("df", from+".."+to)
+		} else {
+			params.Set("df", period)
+		}
+	}
+	if opts.Country != "" {
+		// Regions are country-language pairs such as us-en or de-de.
+		params.Set("kl", strings.ToLower(opts.Country)+"-"+strings.ToLower(orDefault(opts.Language, "en")))
+	}
+	if level, ok := ddgSafeSearch[opts.SafeSearch]; ok {
+		params.Set("kp", level)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; miniclaw)")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// DuckDuckGo answers bots it throttles with 202 and a challenge page.
+	if resp.StatusCode == http.StatusAccepted {
+		resp.StatusCode = http.StatusTooManyRequests
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	results := parseDuckDuckGoResults(string(page))
+	return results[:min(len(results), count)], nil
+}
+
+func parseDuckDuckGoResults(page string) []SearchResult {
+	links := ddgResultPattern.FindAllStringSubmatchIndex(page, -1)
+	results := make([]SearchResult, 0, len(links))
+	for i, link := range links {
+		result := SearchResult{
+			URL:   ddgResultURL(html.UnescapeString(page[link[2]:link[3]])),
+			Title: ddgText(page[link[4]:link[5]]),
+		}
+
+		// The snippet sits between this link and the next one.
+		rest := page[link[1]:]
+		if i+1 < len(links) {
+			rest = page[link[1]:links[i+1][0]]
+		}
+		if match := ddgSnippetPattern.FindStringSubmatch(rest); match != nil {
+			result.Snippet = ddgText(match[1])
+		}
+
+		if result.URL != "" {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// ddgResultURL resolves DuckDuckGo's redirect links to the result itself.
+// Ads, which go through y.js, are dropped.
+func ddgResultURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if strings.HasSuffix(u.Host, "duckduckgo.com") {
+		return ""
+	}
+	if u.Scheme == "" {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+func ddgText(fragment string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(ddgTagPattern.ReplaceAllString(fragment, ""))), " ")
+}
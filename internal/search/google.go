@@ -0,0 +1,117 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GoogleSearchClient uses the Custom Search JSON API of a Programmable Search
+// Engine.
+type GoogleSearchClient struct {
+	apiKey     string
+	engineID   string
+	baseURL    string
+	httpClient *http.Client
+	defaults   SearchOptions
+}
+
+type googleResponse struct {
+	Items []struct {
+		Title   string `json:"title"`
+		Link    string `json:"link"`
+		Snippet string `json:"snippet"`
+	} `json:"items"`
+}
+
+func NewGoogleSearchClient(config *SearchConfig) (*GoogleSearchClient, error) {
+	if config == nil || config.APIKey == "" || config.EngineID == "" {
+		return nil, fmt.Errorf("google needs an API key and a search engine ID")
+	}
+
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://www.googleapis.com/customsearch/v1"
+	}
+
+	return &GoogleSearchClient{
+		apiKey:     config.APIKey,
+		engineID:   config.EngineID,
+		baseURL:    baseURL,
+		httpClient: newHTTPClient(config),
+		defaults: SearchOptions{
+			Country:    config.Country,
+			Language:   config.Language,
+			SafeSearch: config.SafeSearch,
+		},
+	}, nil
+}
+
+func (c *GoogleSearchClient) Name() string {
+	return "google"
+}
+
+func (c *GoogleSearchClient) SearchWithOptions(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	// The API returns at most 10 results per request.
+	count = clampCount(count, 10)
+	opts = opts.withDefaults(c.defaults)
+
+	params := url.Values{}
+	params.Set("key", c.apiKey)
+	params.Set("cx", c.engineID)
+	params.Set("q", query)
+	params.Set("num", fmt.Sprint(count))
+	if opts.Page > 1 {
+		params.Set("start", fmt.Sprint((opts.Page-1)*count+1))
+	}
+	if opts.Freshness != "" {
+		period, from, to := freshnessPeriod(opts.Freshness)
+		if from != "" {
+			params.Set("sort", "date:r:"+strings.ReplaceAll(from, "-", "")+":"+strings.ReplaceAll(to, "-", ""))
+		} else {
+			params.Set("dateRestrict", period+"1")
+		}
+	}
+	if opts.Country != "" {
+		params.Set("gl", strings.ToLower(opts.Country))
+	}
+	if opts.Language != "" {
+		params.Set("lr", "lang_"+strings.ToLower(opts.Language))
+	}
+	switch opts.SafeSearch {
+	case "off":
+		params.Set("safe", "off")
+	case "moderate", "strict":
+		params.Set("safe", "active")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	var searchResp googleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(searchResp.Items))
+	for _, item := range searchResp.Items {
+		results = append(results, SearchResult{Title: item.Title, URL: item.Link, Snippet: item.Snippet})
+	}
+	return results, nil
+}
@@ -0,0 +1,109 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+// NewsSearchTool searches recent news articles, giving the publisher and
+// publication time of each.
+type NewsSearchTool struct {
+	provider NewsSearcher
+}
+
+func NewNewsSearchTool(provider NewsSearcher) *NewsSearchTool {
+	return &NewsSearchTool{
+		provider: provider,
+	}
+}
+
+func (t *NewsSearchTool) Name() string {
+	return "news_search"
+}
+
+func (t *NewsSearchTool) Description() string {
+	return "Search recent news articles, with their source and publication time"
+}
+
+// Policy reuses results briefly; news goes stale faster than web pages.
+func (t *NewsSearchTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{CacheTTL: 5 * time.Minute}
+}
+
+func (t *NewsSearchTool) Parameters() json.RawMessage {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"query": {
+				"type": "string",
+				"description": "The search query"
+			},
+			"count": {
+				"type": "integer",
+				"description": "Number of articles to return (1-20, default 10)",
+				"default": 10,
+				"minimum": 1,
+				"maximum": 20
+			},
+			"freshness": {
+				"type": "string",
+				"description": "Only return articles published within this period: day, week, month, year, or a range like 2024-01-01to2024-06-30"
+			},
+			"country": {
+				"type": "string",
+				"description": "Two-letter country code to get news for, e.g. US or DE"
+			},
+			"language": {
+				"type": "string",
+				"description": "Language code of the articles, e.g. en or de"
+			},
+			"site": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Only return articles from these domains, e.g. [\"reuters.com\", \"apnews.com\"]"
+			}
+		},
+		"required": ["query"],
+		"additionalProperties": false
+	}`)
+	return params
+}
+
+func (t *NewsSearchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	query, count, opts, err := searchParams(params)
+	if err != nil {
+		return "", err
+	}
+
+	results, err := t.provider.SearchNews(ctx, query, count, opts)
+	if err != nil {
+		return "", &tools.ToolError{
+			Code:    "EXECUTION_FAILED",
+			Message: "failed to search news",
+			Err:     err,
+		}
+	}
+
+	results = dedupeResults(results)
+	if len(results) == 0 {
+		return "No news found", nil
+	}
+
+	output := fmt.Sprintf("Found %d news articles for '%s':\n\n", len(results), query)
+	for i, result := range results {
+		output += fmt.Sprintf("%d. %s\n", i+1, result.Title)
+		source := sourceOf(result)
+		if result.Published != "" {
+			source += ", " + formatPublished(result.Published)
+		}
+		output += fmt.Sprintf("   Source: %s\n", source)
+		output += fmt.Sprintf("   URL: %s\n", result.URL)
+		output += fmt.Sprintf("   %s\n\n", result.Snippet)
+	}
+
+	return output, nil
+}
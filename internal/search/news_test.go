@@ -0,0 +1,77 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewsSearchTool(t *testing.T) {
+	var query, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, path = r.URL.Query().Get("q"), r.URL.Path
+		w.Write([]byte(`{"type":"news","results":[
+			{"title":"Markets rally","url":"https://www.reuters.com/markets/rally","description":"Stocks rose.","age":"2 hours ago","page_age":"2024-05-01T06:30:00","meta_url":{"hostname":"www.reuters.com"}},
+			{"title":"Rain expected","url":"https://apnews.com/weather","description":"Bring an umbrella.","age":"1 hour ago"}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewBraveSearchClient(&SearchConfig{APIKey: "test-api-key", BaseURL: server.URL + "/res/v1/web/search"})
+	tool := NewNewsSearchTool(NewsProvider(client))
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{
+		"query": "markets",
+		"site":  []interface{}{"https://www.reuters.com/", "apnews.com"},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if path != "/res/v1/news/search" {
+		t.Errorf("Expected the news endpoint, got %s", path)
+	}
+	if query != "markets (site:reuters.com OR site:apnews.com)" {
+		t.Errorf("Expected the sites in the query, got %q", query)
+	}
+	for _, want := range []string{"Source: reuters.com, 2024-05-01 06:30", "Source: apnews.com, 1 hour ago"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in %q", want, result)
+		}
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"query": "markets", "site": "bad site"}); err == nil {
+		t.Error("Expected a site with spaces to be rejected")
+	}
+}
+
+func TestNewsProvider(t *testing.T) {
+	if NewsProvider(NewDuckDuckGoClient(nil)) != nil {
+		t.Error("Expected duckduckgo not to search news")
+	}
+	if NewsProvider(NewFallbackProvider(&stubProvider{name: "a"}, NewDuckDuckGoClient(nil))) != nil {
+		t.Error("Expected a chain without news providers not to search news")
+	}
+
+	brave := NewBraveSearchClient(&SearchConfig{BaseURL: "http://127.0.0.1:1"})
+	if news := NewsProvider(NewFallbackProvider(NewDuckDuckGoClient(nil), brave)); news != NewsSearcher(brave) {
+		t.Errorf("Expected the only news provider of a chain, got %v", news)
+	}
+
+	limited := &stubProvider{name: "limited", err: ErrRateLimited}
+	news := NewsProvider(NewFallbackProvider(brave, &newsStub{stubProvider: limited}))
+	if _, err := news.SearchNews(context.Background(), "go", 5, SearchOptions{}); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected every news provider to be tried, got %v", err)
+	}
+}
+
+type newsStub struct {
+	*stubProvider
+}
+
+func (p *newsStub) SearchNews(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	return p.SearchWithOptions(ctx, query, count, opts)
+}
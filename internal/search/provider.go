@@ -0,0 +1,257 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+var logger = logging.For("search")
+
+// maxPage is the last page the tool asks for; Brave's offset goes up to 9.
+const maxPage = 10
+
+// rateLimitCooldown is how long a fallback chain skips a provider after it
+// answered with 429.
+const rateLimitCooldown = time.Minute
+
+// ErrRateLimited is wrapped by provider errors for 429 responses.
+var ErrRateLimited = errors.New("search provider rate limited")
+
+// freshnessCodes maps the freshness names the tool accepts to the codes
+// SearchOptions carries. A "YYYY-MM-DDtoYYYY-MM-DD" date range is kept as
+// is.
+var freshnessCodes = map[string]string{
+	"day":   "pd",
+	"week":  "pw",
+	"month": "pm",
+	"year":  "py",
+}
+
+var freshnessRange = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})to(\d{4}-\d{2}-\d{2})$`)
+
+var safeSearchLevels = []string{"off", "moderate", "strict"}
+
+// SearchProvider is a web search backend of the web_search tool.
+type SearchProvider interface {
+	Name() string
+	SearchWithOptions(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error)
+}
+
+// NewsSearcher is implemented by providers that can also search news.
+type NewsSearcher interface {
+	SearchNews(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error)
+}
+
+// NewsProvider returns what of provider can search news: provider itself, a
+// fallback chain of its providers that can, or nil.
+func NewsProvider(provider SearchProvider) NewsSearcher {
+	fallback, ok := provider.(*FallbackProvider)
+	if !ok {
+		news, _ := provider.(NewsSearcher)
+		return news
+	}
+
+	var providers []SearchProvider
+	for _, provider := range fallback.providers {
+		if _, ok := provider.(NewsSearcher); ok {
+			providers = append(providers, provider)
+		}
+	}
+	switch len(providers) {
+	case 0:
+		return nil
+	case 1:
+		return providers[0].(NewsSearcher)
+	}
+	return NewFallbackProvider(providers...)
+}
+
+// SearchOptions narrows a search. Empty fields use the provider's defaults,
+// and the search engine's own defaults after that.
+type SearchOptions struct {
+	// Page is 1-based; each page holds count results.
+	Page int
+	// Freshness is day, week, month, year, a code such as pw, or a
+	// "YYYY-MM-DDtoYYYY-MM-DD" range.
+	Freshness string
+	// Country is a two-letter country code, Language a language code such
+	// as en or de.
+	Country    string
+	Language   string
+	SafeSearch string
+}
+
+func (o SearchOptions) withDefaults(defaults SearchOptions) SearchOptions {
+	o.Country = orDefault(o.Country, defaults.Country)
+	o.Language = orDefault(o.Language, defaults.Language)
+	o.SafeSearch = strings.ToLower(orDefault(o.SafeSearch, defaults.SafeSearch))
+	return o
+}
+
+// parseFreshness turns a freshness filter into pd, pw, pm, py or a date
+// range.
+func parseFreshness(freshness string) (string, error) {
+	freshness = strings.ToLower(strings.TrimSpace(freshness))
+	if code, ok := freshnessCodes[freshness]; ok {
+		return code, nil
+	}
+	for _, code := range freshnessCodes {
+		if freshness == code {
+			return code, nil
+		}
+	}
+	if freshnessRange.MatchString(freshness) {
+		return freshness, nil
+	}
+	return "", fmt.Errorf("unknown freshness %q, expected day, week, month, year or YYYY-MM-DDtoYYYY-MM-DD", freshness)
+}
+
+// freshnessPeriod returns the period letter (d, w, m or y) of a freshness
+// code, or the dates of a range.
+func freshnessPeriod(code string) (period, from, to string) {
+	if match := freshnessRange.FindStringSubmatch(code); match != nil {
+		return "", match[1], match[2]
+	}
+	return strings.TrimPrefix(code, "p"), "", ""
+}
+
+func validSafeSearch(level string) bool {
+	for _, valid := range safeSearchLevels {
+		if strings.EqualFold(level, valid) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeResults drops results whose URL only differs from an earlier one in
+// scheme, a www. prefix, a trailing slash or the fragment.
+func dedupeResults(results []SearchResult) []SearchResult {
+	seen := make(map[string]bool, len(results))
+	deduped := results[:0]
+	for _, result := range results {
+		key := result.URL
+		if u, err := url.Parse(result.URL); err == nil && u.Host != "" {
+			key = strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimSuffix(u.EscapedPath(), "/") + "?" + u.RawQuery
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, result)
+	}
+	return deduped
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func clampCount(count, limit int) int {
+	if count <= 0 {
+		count = 10
+	}
+	return min(count, limit)
+}
+
+func newHTTPClient(config *SearchConfig) *http.Client {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// statusError describes an unsuccessful response, wrapping ErrRateLimited
+// for 429.
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("search failed with status %d: %s: %w", resp.StatusCode, string(body), ErrRateLimited)
+	}
+	return fmt.Errorf("search failed with status %d: %s", resp.StatusCode, string(body))
+}
+
+// FallbackProvider tries its providers in order until one answers. A
+// provider that was rate limited is skipped for a while.
+type FallbackProvider struct {
+	providers []SearchProvider
+
+	mu           sync.Mutex
+	limitedUntil map[string]time.Time
+}
+
+func NewFallbackProvider(providers ...SearchProvider) *FallbackProvider {
+	return &FallbackProvider{
+		providers:    providers,
+		limitedUntil: make(map[string]time.Time),
+	}
+}
+
+func (p *FallbackProvider) Name() string {
+	names := make([]string, 0, len(p.providers))
+	for _, provider := range p.providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+func (p *FallbackProvider) SearchWithOptions(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	return p.search(ctx, func(provider SearchProvider) ([]SearchResult, error) {
+		return provider.SearchWithOptions(ctx, query, count, opts)
+	})
+}
+
+// SearchNews tries the providers that can search news.
+func (p *FallbackProvider) SearchNews(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	return p.search(ctx, func(provider SearchProvider) ([]SearchResult, error) {
+		news, ok := provider.(NewsSearcher)
+		if !ok {
+			return nil, fmt.Errorf("news search is not supported")
+		}
+		return news.SearchNews(ctx, query, count, opts)
+	})
+}
+
+func (p *FallbackProvider) search(ctx context.Context, search func(SearchProvider) ([]SearchResult, error)) ([]SearchResult, error) {
+	var errs []error
+	for _, provider := range p.providers {
+		p.mu.Lock()
+		until := p.limitedUntil[provider.Name()]
+		p.mu.Unlock()
+		if time.Now().Before(until) {
+			errs = append(errs, fmt.Errorf("%s: %w until %s", provider.Name(), ErrRateLimited, until.Format(time.TimeOnly)))
+			continue
+		}
+
+		results, err := search(provider)
+		if err == nil {
+			return results, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		if errors.Is(err, ErrRateLimited) {
+			p.mu.Lock()
+			p.limitedUntil[provider.Name()] = time.Now().Add(rateLimitCooldown)
+			p.mu.Unlock()
+		}
+		logger.Warn("Search provider failed, trying the next one", "provider", provider.Name(), "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+	return nil, fmt.Errorf("all search providers failed: %w", errors.Join(errs...))
+}
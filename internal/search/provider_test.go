@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type stubProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Name() string {
+	return p.name
+}
+
+func (p *stubProvider) SearchWithOptions(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []SearchResult{{Title: p.name, URL: "https://example.com/" + p.name}}, nil
+}
+
+func TestFallbackProvider(t *testing.T) {
+	limited := &stubProvider{name: "brave", err: fmt.Errorf("search failed with status 429: %w", ErrRateLimited)}
+	broken := &stubProvider{name: "searxng", err: errors.New("connection refused")}
+	working := &stubProvider{name: "duckduckgo"}
+	provider := NewFallbackProvider(limited, broken, working)
+
+	for i := 0; i < 2; i++ {
+		results, err := provider.SearchWithOptions(context.Background(), "go", 5, SearchOptions{})
+		if err != nil || len(results) != 1 || results[0].Title != "duckduckgo" {
+			t.Fatalf("Expected the last provider to answer, got %v, %v", results, err)
+		}
+	}
+	if limited.calls != 1 || broken.calls != 2 {
+		t.Errorf("Expected the rate-limited provider to be skipped afterwards, got %d and %d calls", limited.calls, broken.calls)
+	}
+
+	working.err = errors.New("blocked")
+	if _, err := provider.SearchWithOptions(context.Background(), "go", 5, SearchOptions{}); err == nil || !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected every provider's error, got %v", err)
+	}
+}
+
+func TestProviderRequests(t *testing.T) {
+	var query url.Values
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	config := &SearchConfig{BaseURL: server.URL, APIKey: "key", EngineID: "engine", Country: "de", Language: "de", SafeSearch: "strict"}
+	searxng, err := NewSearxNGClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create searxng client: %v", err)
+	}
+	google, err := NewGoogleSearchClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create google client: %v", err)
+	}
+
+	tests := []struct {
+		provider SearchProvider
+		body     string
+		opts     SearchOptions
+		want     map[string]string
+	}{
+		{
+			provider: searxng,
+			body:     `{"results":[{"title":"Go","url":"https://go.dev","content":"The Go language"}]}`,
+			opts:     SearchOptions{Page: 2, Freshness: "pm"},
+			want:     map[string]string{"format": "json", "pageno": "2", "time_range": "month", "language": "de-DE", "safesearch": "2"},
+		},
+		{
+			provider: google,
+			body:     `{"items":[{"title":"Go","link":"https://go.dev","snippet":"The Go language"}]}`,
+			opts:     SearchOptions{Page: 3, Freshness: "2024-01-01to2024-06-30", SafeSearch: "off"},
+			want:     map[string]string{"cx": "engine", "num": "5", "start": "11", "sort": "date:r:20240101:20240630", "gl": "de", "lr": "lang_de", "safe": "off"},
+		},
+		{
+			provider: NewDuckDuckGoClient(config),
+			body: `<div class="result"><h2><a rel="nofollow" class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev&amp;rut=x">The <b>Go</b> language</a></h2>
+				<a class="result__snippet" href="#">The Go language &amp; tools</a></div>
+				<div class="result"><a class="result__a" href="https://duckduckgo.com/y.js?ad=1">Ad</a></div>`,
+			opts: SearchOptions{Freshness: "pw"},
+			want: map[string]string{"q": "go", "df": "w", "kl": "de-de", "kp": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		body = tt.body
+		results, err := tt.provider.SearchWithOptions(context.Background(), "go", 5, tt.opts)
+		if err != nil {
+			t.Errorf("%s: search failed: %v", tt.provider.Name(), err)
+			continue
+		}
+		for key, want := range tt.want {
+			if got := query.Get(key); got != want {
+				t.Errorf("%s: expected %s=%q, got %q", tt.provider.Name(), key, want, got)
+			}
+		}
+		if len(results) != 1 || results[0].URL != "https://go.dev" || results[0].Snippet == "" {
+			t.Errorf("%s: unexpected results %+v", tt.provider.Name(), results)
+		}
+	}
+
+	if _, err := searxng.SearchWithOptions(context.Background(), "go", 5, SearchOptions{Freshness: "2024-01-01to2024-06-30"}); err == nil {
+		t.Error("Expected searxng to reject a date range")
+	}
+}
+
+func TestStatusErrorRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, err := NewBraveSearchClient(&SearchConfig{BaseURL: server.URL}).Search(context.Background(), "go", 5)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected a 429 to be reported as rate limited, got %v", err)
+	}
+}
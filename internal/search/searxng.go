@@ -0,0 +1,122 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SearxNGClient searches a SearxNG instance, which needs the json format
+// enabled in its settings.
+type SearxNGClient struct {
+	baseURL    string
+	httpClient *http.Client
+	defaults   SearchOptions
+}
+
+type searxNGResponse struct {
+	Results []struct {
+		Title   string `json:"title"`
+		URL     string `json:"url"`
+		Content string `json:"content"`
+		// PublishedDate is only set by some engines, mostly in the news
+		// category.
+		PublishedDate string `json:"publishedDate"`
+	} `json:"results"`
+}
+
+var searxNGSafeSearch = map[string]string{"off": "0", "moderate": "1", "strict": "2"}
+
+var searxNGTimeRanges = map[string]string{"d": "day", "w": "week", "m": "month", "y": "year"}
+
+func NewSearxNGClient(config *SearchConfig) (*SearxNGClient, error) {
+	if config == nil || config.BaseURL == "" {
+		return nil, fmt.Errorf("searxng needs the URL of an instance")
+	}
+
+	return &SearxNGClient{
+		baseURL:    strings.TrimSuffix(config.BaseURL, "/") + "/search",
+		httpClient: newHTTPClient(config),
+		defaults: SearchOptions{
+			Country:    config.Country,
+			Language:   config.Language,
+			SafeSearch: config.SafeSearch,
+		},
+	}, nil
+}
+
+func (c *SearxNGClient) Name() string {
+	return "searxng"
+}
+
+func (c *SearxNGClient) SearchWithOptions(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	return c.search(ctx, query, count, opts, "")
+}
+
+// SearchNews searches the news category of the instance.
+func (c *SearxNGClient) SearchNews(ctx context.Context, query string, count int, opts SearchOptions) ([]SearchResult, error) {
+	return c.search(ctx, query, count, opts, "news")
+}
+
+func (c *SearxNGClient) search(ctx context.Context, query string, count int, opts SearchOptions, category string) ([]SearchResult, error) {
+	count = clampCount(count, 20)
+	opts = opts.withDefaults(c.defaults)
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	if category != "" {
+		params.Set("categories", category)
+	}
+	if opts.Page > 1 {
+		params.Set("pageno", fmt.Sprint(opts.Page))
+	}
+	if opts.Freshness != "" {
+		period, _, _ := freshnessPeriod(opts.Freshness)
+		timeRange, ok := searxNGTimeRanges[period]
+		if !ok {
+			return nil, fmt.Errorf("searxng does not support freshness %s", opts.Freshness)
+		}
+		params.Set("time_range", timeRange)
+	}
+	if opts.Language != "" {
+		language := strings.ToLower(opts.Language)
+		if opts.Country != "" {
+			language += "-" + strings.ToUpper(opts.Country)
+		}
+		params.Set("language", language)
+	}
+	if level, ok := searxNGSafeSearch[opts.SafeSearch]; ok {
+		params.Set("safesearch", level)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	var searchResp searxNGResponse
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(searchResp.Results))
+	for _, result := range searchResp.Results {
+		results = append(results, SearchResult{Title: result.Title, URL: result.URL, Snippet: result.Content, Published: result.PublishedDate})
+	}
+	return results[:min(len(results), count)], nil
+}
@@ -0,0 +1,250 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+type WebSearchTool struct {
+	provider SearchProvider
+}
+
+func NewWebSearchTool(provider SearchProvider) *WebSearchTool {
+	return &WebSearchTool{
+		provider: provider,
+	}
+}
+
+func (t *WebSearchTool) Name() string {
+	return "web_search"
+}
+
+func (t *WebSearchTool) Description() string {
+	return "Search the web for information"
+}
+
+// Policy reuses results for repeated queries, which cost API quota.
+func (t *WebSearchTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{CacheTTL: 10 * time.Minute}
+}
+
+func (t *WebSearchTool) Parameters() json.RawMessage {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"query": {
+				"type": "string",
+				"description": "The search query"
+			},
+			"count": {
+				"type": "integer",
+				"description": "Number of results to return (1-20, default 10)",
+				"default": 10,
+				"minimum": 1,
+				"maximum": 20
+			},
+			"page": {
+				"type": "integer",
+				"description": "Page of results to return, for going past the first results (1-10, default 1)",
+				"minimum": 1,
+				"maximum": 10
+			},
+			"freshness": {
+				"type": "string",
+				"description": "Only return pages discovered within this period: day, week, month, year, or a range like 2024-01-01to2024-06-30"
+			},
+			"country": {
+				"type": "string",
+				"description": "Two-letter country code to get results for, e.g. US or DE"
+			},
+			"language": {
+				"type": "string",
+				"description": "Language code of the results, e.g. en or de"
+			},
+			"safesearch": {
+				"type": "string",
+				"enum": ["off", "moderate", "strict"],
+				"description": "How strictly to filter adult content"
+			},
+			"site": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Only return results from these domains, e.g. [\"go.dev\", \"github.com\"]"
+			}
+		},
+		"required": ["query"],
+		"additionalProperties": false
+	}`)
+	return params
+}
+
+func (t *WebSearchTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	query, count, opts, err := searchParams(params)
+	if err != nil {
+		return "", err
+	}
+
+	results, err := t.provider.SearchWithOptions(ctx, query, count, opts)
+	if err != nil {
+		return "", &tools.ToolError{
+			Code:    "EXECUTION_FAILED",
+			Message: "failed to perform web search",
+			Err:     err,
+		}
+	}
+
+	results = dedupeResults(results)
+	if len(results) == 0 {
+		return "No search results found", nil
+	}
+
+	output := fmt.Sprintf("Found %d search results for '%s':\n\n", len(results), query)
+	if opts.Page > 1 {
+		output = fmt.Sprintf("Found %d search results for '%s' on page %d:\n\n", len(results), query, opts.Page)
+	}
+	first := (opts.Page-1)*count + 1
+	for i, result := range results {
+		output += fmt.Sprintf("%d. %s\n", first+i, result.Title)
+		output += fmt.Sprintf("   URL: %s\n", result.URL)
+		if result.Published != "" {
+			output += fmt.Sprintf("   Published: %s\n", formatPublished(result.Published))
+		}
+		output += fmt.Sprintf("   %s\n\n", result.Snippet)
+	}
+
+	if len(results) >= count && opts.Page < maxPage {
+		output += fmt.Sprintf("More results may be available with page %d.\n", opts.Page+1)
+	}
+
+	return output, nil
+}
+
+// searchParams reads the parameters web_search and news_search share. Sites
+// are added to the query as site: operators, which every provider supports.
+func searchParams(params map[string]interface{}) (string, int, SearchOptions, error) {
+	opts := SearchOptions{Page: 1}
+
+	query, ok := params["query"].(string)
+	if !ok {
+		return "", 0, opts, &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "query parameter must be a string",
+		}
+	}
+
+	if query == "" {
+		return "", 0, opts, &tools.ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "query parameter cannot be empty",
+		}
+	}
+
+	count := 10
+	if c, ok := params["count"].(float64); ok {
+		count = int(c)
+		if count < 1 {
+			count = 1
+		}
+		if count > 20 {
+			count = 20
+		}
+	}
+
+	if p, ok := params["page"].(float64); ok {
+		opts.Page = max(1, min(int(p), maxPage))
+	}
+	opts.Country, _ = params["country"].(string)
+	opts.Language, _ = params["language"].(string)
+
+	if freshness, _ := params["freshness"].(string); freshness != "" {
+		code, err := parseFreshness(freshness)
+		if err != nil {
+			return "", 0, opts, &tools.ToolError{Code: "INVALID_PARAM", Message: err.Error()}
+		}
+		opts.Freshness = code
+	}
+	if safeSearch, _ := params["safesearch"].(string); safeSearch != "" {
+		if !validSafeSearch(safeSearch) {
+			return "", 0, opts, &tools.ToolError{
+				Code:    "INVALID_PARAM",
+				Message: "safesearch must be off, moderate or strict",
+			}
+		}
+		opts.SafeSearch = safeSearch
+	}
+
+	var sites []string
+	switch site := params["site"].(type) {
+	case string:
+		sites = []string{site}
+	case []interface{}:
+		for _, s := range site {
+			if s, ok := s.(string); ok {
+				sites = append(sites, s)
+			}
+		}
+	}
+	query, err := restrictToSites(query, sites)
+	if err != nil {
+		return "", 0, opts, &tools.ToolError{Code: "INVALID_PARAM", Message: err.Error()}
+	}
+
+	return query, count, opts, nil
+}
+
+// restrictToSites adds site: operators for the given domains to query,
+// joined with OR when there are several.
+func restrictToSites(query string, sites []string) (string, error) {
+	var operators []string
+	for _, site := range sites {
+		site = strings.TrimSpace(site)
+		if i := strings.Index(site, "://"); i >= 0 {
+			site = site[i+3:]
+		}
+		site = strings.TrimSuffix(strings.TrimPrefix(site, "www."), "/")
+		if site == "" {
+			continue
+		}
+		if strings.ContainsAny(site, " \t\"()") {
+			return "", fmt.Errorf("invalid site %q, expected a domain like example.com", site)
+		}
+		operators = append(operators, "site:"+site)
+	}
+
+	switch len(operators) {
+	case 0:
+		return query, nil
+	case 1:
+		return query + " " + operators[0], nil
+	}
+	return query + " (" + strings.Join(operators, " OR ") + ")", nil
+}
+
+// formatPublished shortens a timestamp to minutes; anything that does not
+// parse, like "2 hours ago", is shown as is.
+func formatPublished(published string) string {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, published); err == nil {
+			return t.Format("2006-01-02 15:04")
+		}
+	}
+	return published
+}
+
+// sourceOf is the publisher of a result: what the provider reported, or the
+// host of its URL.
+func sourceOf(result SearchResult) string {
+	if result.Source != "" {
+		return result.Source
+	}
+	if u, err := url.Parse(result.URL); err == nil {
+		return strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	return ""
+}
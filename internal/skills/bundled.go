@@ -0,0 +1,293 @@
+package skills
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+//go:embed bundled/*.md
+var bundledFS embed.FS
+
+const bundledManifestFile = ".bundled.json"
+
+type BundledSkill struct {
+	Name    string
+	Version string
+	Content []byte
+}
+
+type bundledManifest struct {
+	Skills map[string]bundledManifestEntry `json:"skills"`
+}
+
+type bundledManifestEntry struct {
+	Version     string    `json:"version"`
+	Checksum    string    `json:"checksum"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+type InstallReport struct {
+	Installed []string
+	Upgraded  []string
+	Skipped   []string
+	Conflicts []string
+}
+
+type BundledInstaller struct {
+	storage  storage.Storage
+	dir      string
+	disabled map[string]bool
+}
+
+func ListBundledSkills() ([]*BundledSkill, error) {
+	entries, err := fs.ReadDir(bundledFS, "bundled")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundled skills: %w", err)
+	}
+
+	parser := NewSkillParser(nil)
+	bundled := make([]*BundledSkill, 0, len(entries))
+
+	for _, entry := range entries {
+		content, err := bundledFS.ReadFile(path.Join("bundled", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundled skill %s: %w", entry.Name(), err)
+		}
+
+		skill, err := parser.ParseContent(string(content), entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundled skill %s: %w", entry.Name(), err)
+		}
+
+		bundled = append(bundled, &BundledSkill{
+			Name:    skill.Name,
+			Version: skill.Metadata["version"],
+			Content: content,
+		})
+	}
+
+	sort.Slice(bundled, func(i, j int) bool {
+		return bundled[i].Name < bundled[j].Name
+	})
+
+	return bundled, nil
+}
+
+func NewBundledInstaller(storage storage.Storage, dir string, disabled []string) *BundledInstaller {
+	disabledSet := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		disabledSet[strings.ToLower(name)] = true
+	}
+
+	return &BundledInstaller{
+		storage:  storage,
+		dir:      dir,
+		disabled: disabledSet,
+	}
+}
+
+func (i *BundledInstaller) IsDisabled(name string) bool {
+	return i.disabled[strings.ToLower(name)]
+}
+
+func (i *BundledInstaller) Install(ctx context.Context) (*InstallReport, error) {
+	bundled, err := ListBundledSkills()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := i.loadManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &InstallReport{}
+
+	for _, skill := range bundled {
+		if i.IsDisabled(skill.Name) {
+			report.Skipped = append(report.Skipped, skill.Name)
+			continue
+		}
+
+		target := filepath.Join(i.dir, skill.Name+".md")
+		entry, known := manifest.Skills[skill.Name]
+
+		exists, err := i.exists(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !exists && known:
+			report.Skipped = append(report.Skipped, skill.Name)
+			continue
+
+		case !exists:
+			if err := i.write(ctx, target, skill.Content); err != nil {
+				return nil, err
+			}
+			report.Installed = append(report.Installed, skill.Name)
+
+		case !known:
+			current, err := i.read(ctx, target)
+			if err != nil {
+				return nil, err
+			}
+			if checksum(current) != checksum(skill.Content) {
+				report.Skipped = append(report.Skipped, skill.Name)
+				continue
+			}
+
+		case compareVersions(skill.Version, entry.Version) <= 0:
+			continue
+
+		default:
+			current, err := i.read(ctx, target)
+			if err != nil {
+				return nil, err
+			}
+
+			if checksum(current) != entry.Checksum {
+				if err := i.write(ctx, target+".new", skill.Content); err != nil {
+					return nil, err
+				}
+				logger.Warn("Bundled skill was modified locally, new version written alongside", "skill", skill.Name, "version", skill.Version, "path", target+".new")
+				report.Conflicts = append(report.Conflicts, skill.Name)
+				entry.Version = skill.Version
+				manifest.Skills[skill.Name] = entry
+				continue
+			}
+
+			if err := i.write(ctx, target, skill.Content); err != nil {
+				return nil, err
+			}
+			report.Upgraded = append(report.Upgraded, skill.Name)
+		}
+
+		manifest.Skills[skill.Name] = bundledManifestEntry{
+			Version:     skill.Version,
+			Checksum:    checksum(skill.Content),
+			InstalledAt: time.Now(),
+		}
+	}
+
+	if err := i.saveManifest(ctx, manifest); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (i *BundledInstaller) ApplyDisabled(registry *SkillRegistry) {
+	for _, skill := range registry.ListAll() {
+		if skill.Metadata["bundled"] == "true" && i.IsDisabled(skill.Name) {
+			if err := registry.Disable(skill.ID); err != nil {
+				logger.Error("Failed to disable bundled skill", "skill", skill.Name, "error", err)
+			}
+		}
+	}
+}
+
+func (i *BundledInstaller) loadManifest(ctx context.Context) (*bundledManifest, error) {
+	manifest := &bundledManifest{Skills: make(map[string]bundledManifestEntry)}
+	manifestPath := filepath.Join(i.dir, bundledManifestFile)
+
+	exists, err := i.exists(ctx, manifestPath)
+	if err != nil || !exists {
+		return manifest, err
+	}
+
+	data, err := i.read(ctx, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundled skills manifest: %w", err)
+	}
+
+	if manifest.Skills == nil {
+		manifest.Skills = make(map[string]bundledManifestEntry)
+	}
+
+	return manifest, nil
+}
+
+func (i *BundledInstaller) saveManifest(ctx context.Context, manifest *bundledManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundled skills manifest: %w", err)
+	}
+
+	return i.write(ctx, filepath.Join(i.dir, bundledManifestFile), data)
+}
+
+func (i *BundledInstaller) exists(ctx context.Context, path string) (bool, error) {
+	if filepath.IsAbs(path) {
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return i.storage.FileExists(ctx, path)
+}
+
+func (i *BundledInstaller) read(ctx context.Context, path string) ([]byte, error) {
+	if filepath.IsAbs(path) {
+		return os.ReadFile(path)
+	}
+	return i.storage.ReadFile(ctx, path)
+}
+
+func (i *BundledInstaller) write(ctx context.Context, path string, data []byte) error {
+	if filepath.IsAbs(path) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create skills directory: %w", err)
+		}
+		return os.WriteFile(path, data, 0644)
+	}
+	return i.storage.WriteFile(ctx, path, data)
+}
+
+func checksum(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func compareVersions(a, b string) int {
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for n := 0; n < len(partsA) || n < len(partsB); n++ {
+		var x, y int
+		if n < len(partsA) {
+			x, _ = strconv.Atoi(partsA[n])
+		}
+		if n < len(partsB) {
+			y, _ = strconv.Atoi(partsB[n])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
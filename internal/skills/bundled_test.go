@@ -0,0 +1,172 @@
+package skills
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListBundledSkills(t *testing.T) {
+	bundled, err := ListBundledSkills()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, skill := range bundled {
+		names[skill.Name] = true
+		if skill.Version == "" {
+			t.Errorf("Expected bundled skill %s to have a version", skill.Name)
+		}
+	}
+
+	for _, name := range []string{"summarizer", "planner", "translator", "email-draft"} {
+		if !names[name] {
+			t.Errorf("Expected bundled skill %s", name)
+		}
+	}
+}
+
+func TestBundledInstallerFirstRun(t *testing.T) {
+	dir := t.TempDir()
+	installer := NewBundledInstaller(nil, dir, []string{"Translator"})
+
+	report, err := installer.Install(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(report.Installed) != 3 {
+		t.Errorf("Expected 3 installed skills, got %v", report.Installed)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "translator.md")); !os.IsNotExist(err) {
+		t.Error("Expected disabled skill not to be installed")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, bundledManifestFile)); err != nil {
+		t.Errorf("Expected manifest to be written: %v", err)
+	}
+
+	report, err = installer.Install(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(report.Installed) != 0 || len(report.Upgraded) != 0 {
+		t.Errorf("Expected second run to be a no-op, got %+v", report)
+	}
+}
+
+func TestBundledInstallerUpgrade(t *testing.T) {
+	dir := t.TempDir()
+	installer := NewBundledInstaller(nil, dir, nil)
+	ctx := context.Background()
+
+	if _, err := installer.Install(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	manifest, err := installer.loadManifest(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+
+	for name, entry := range manifest.Skills {
+		entry.Version = "0.1.0"
+		manifest.Skills[name] = entry
+	}
+	if err := installer.saveManifest(ctx, manifest); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	modified := filepath.Join(dir, "planner.md")
+	if err := os.WriteFile(modified, []byte("---\nname: planner\ndescription: mine\n---\nCustom"), 0644); err != nil {
+		t.Fatalf("Failed to modify skill: %v", err)
+	}
+
+	report, err := installer.Install(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(report.Upgraded) != 3 {
+		t.Errorf("Expected 3 upgraded skills, got %v", report.Upgraded)
+	}
+
+	if len(report.Conflicts) != 1 || report.Conflicts[0] != "planner" {
+		t.Errorf("Expected planner conflict, got %v", report.Conflicts)
+	}
+
+	content, _ := os.ReadFile(modified)
+	if !strings.Contains(string(content), "Custom") {
+		t.Error("Expected locally modified skill to be preserved")
+	}
+
+	if _, err := os.Stat(modified + ".new"); err != nil {
+		t.Errorf("Expected new version next to modified skill: %v", err)
+	}
+}
+
+func TestBundledInstallerRespectsDeletion(t *testing.T) {
+	dir := t.TempDir()
+	installer := NewBundledInstaller(nil, dir, nil)
+	ctx := context.Background()
+
+	if _, err := installer.Install(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "summarizer.md")); err != nil {
+		t.Fatalf("Failed to remove skill: %v", err)
+	}
+
+	if _, err := installer.Install(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "summarizer.md")); !os.IsNotExist(err) {
+		t.Error("Expected deleted skill not to be reinstalled")
+	}
+}
+
+func TestBundledInstallerApplyDisabled(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	if _, err := NewBundledInstaller(nil, dir, nil).Install(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	registry := NewSkillRegistry(nil)
+	if err := registry.LoadFromDirectory(ctx, dir); err != nil {
+		t.Fatalf("Failed to load skills: %v", err)
+	}
+
+	NewBundledInstaller(nil, dir, []string{"planner"}).ApplyDisabled(registry)
+
+	skill, ok := registry.GetByName("planner")
+	if !ok {
+		t.Fatal("Expected planner skill to be registered")
+	}
+
+	if skill.Enabled {
+		t.Error("Expected planner skill to be disabled")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	if compareVersions("1.2.0", "1.10.0") != -1 {
+		t.Error("Expected 1.2.0 < 1.10.0")
+	}
+
+	if compareVersions("v2", "1.9.9") != 1 {
+		t.Error("Expected v2 > 1.9.9")
+	}
+
+	if compareVersions("1.0", "1.0.0") != 0 {
+		t.Error("Expected 1.0 == 1.0.0")
+	}
+}
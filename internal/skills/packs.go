@@ -0,0 +1,394 @@
+package skills
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SetCacheDir sets where skills from sources such as git repositories are
+// downloaded to. Each source gets a directory of its own inside it.
+func (r *SkillRegistry) SetCacheDir(dir string) error {
+	// The parser reads relative paths from storage, so skills in the cache
+	// need absolute ones.
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve skill cache directory: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cacheDir = absDir
+	return nil
+}
+
+func (r *SkillRegistry) LoadFromGit(ctx context.Context, url, ref string) error {
+	return r.LoadFromSource(ctx, NewGitSource(url, ref))
+}
+
+func (r *SkillRegistry) LoadFromURL(ctx context.Context, url string) error {
+	return r.LoadFromSource(ctx, NewURLSource(url))
+}
+
+// LoadFromSource fetches a source into the cache and registers its skills,
+// replacing those from the last load. If the fetch fails the cached copy is
+// used, so a source that is offline for a while keeps its skills.
+func (r *SkillRegistry) LoadFromSource(ctx context.Context, source Source) error {
+	r.mu.RLock()
+	cacheDir := r.cacheDir
+	r.mu.RUnlock()
+
+	if cacheDir == "" {
+		return fmt.Errorf("no cache directory set for skill sources")
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create skill cache directory: %w", err)
+	}
+
+	name := source.Name()
+	dir := filepath.Join(cacheDir, name)
+	if err := source.Fetch(ctx, dir); err != nil {
+		if _, statErr := os.Stat(dir); statErr != nil {
+			return fmt.Errorf("failed to fetch skill source %s: %w", name, err)
+		}
+		logger.Warn("Failed to fetch skill source, using cached copy", "source", name, "error", err)
+	}
+
+	skills, err := r.parseSourceDirectory(ctx, dir, name)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded := make(map[string]bool, len(skills))
+	ids := make([]string, 0, len(skills))
+	for _, skill := range skills {
+		if old, exists := r.skills[skill.ID]; exists {
+			// Keep a skill the user disabled disabled across refreshes.
+			skill.Enabled = old.Enabled
+			r.index.Remove(skill.ID)
+		}
+		r.skills[skill.ID] = skill
+		if skill.Enabled {
+			r.index.Add(skill)
+		}
+		r.syncScriptToolsLocked(skill)
+		loaded[skill.ID] = true
+		ids = append(ids, skill.ID)
+	}
+
+	for _, id := range r.sources[name] {
+		if !loaded[id] {
+			delete(r.skills, id)
+			r.index.Remove(id)
+			r.removeScriptToolsLocked(id)
+		}
+	}
+	r.sources[name] = ids
+
+	return nil
+}
+
+// parseSourceDirectory parses the skills in a downloaded source. Skill packs
+// often contain other Markdown files such as a README, so files that are not
+// skills are skipped rather than failing the whole source.
+func (r *SkillRegistry) parseSourceDirectory(ctx context.Context, dir, name string) ([]*Skill, error) {
+	var skills []*Skill
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symlinks could point anywhere on the machine.
+		if !info.Mode().IsRegular() || !strings.HasSuffix(strings.ToLower(path), ".md") {
+			return nil
+		}
+
+		skill, err := r.parser.Parse(ctx, path)
+		if err != nil {
+			logger.Warn("Skipping file in skill source", "source", name, "path", path, "error", err)
+			return nil
+		}
+		if skill.Metadata == nil {
+			skill.Metadata = make(map[string]string)
+		}
+		skill.Metadata[sourceMetadata] = name
+		skills = append(skills, skill)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read skill source %s: %w", name, err)
+	}
+
+	return skills, nil
+}
+
+// UnloadSource removes the skills loaded from a source. The cached copy is
+// kept, so loading the source again works offline.
+func (r *SkillRegistry) UnloadSource(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := r.sources[name]
+	for _, id := range ids {
+		delete(r.skills, id)
+		r.index.Remove(id)
+		r.removeScriptToolsLocked(id)
+	}
+	delete(r.sources, name)
+
+	return len(ids)
+}
+
+func (r *SkillRegistry) sourceSkillCount(name string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.sources[name])
+}
+
+// SourceConfig describes a skill pack. Exactly one of Git and URL is set.
+// Name defaults to one derived from the location.
+type SourceConfig struct {
+	Name     string
+	Git      string
+	Ref      string
+	URL      string
+	Disabled bool
+}
+
+type SourceManagerConfig struct {
+	Sources []SourceConfig
+	// RefreshInterval is how often enabled sources are fetched again; zero
+	// loads them only once.
+	RefreshInterval time.Duration
+}
+
+// SourceStatus reports the state of a skill source.
+type SourceStatus struct {
+	Name        string
+	Location    string
+	Enabled     bool
+	Skills      int
+	LastFetched time.Time
+	LastError   string
+}
+
+// SourceManager loads skill packs into a registry, keeps them up to date and
+// lets them be turned on and off by name.
+type SourceManager struct {
+	registry *SkillRegistry
+	interval time.Duration
+
+	// loadMu keeps two loads of the same source from racing each other.
+	loadMu  sync.Mutex
+	mu      sync.Mutex
+	sources []*managedSource
+}
+
+type managedSource struct {
+	source      Source
+	location    string
+	enabled     bool
+	lastFetched time.Time
+	lastError   error
+}
+
+func NewSourceManager(registry *SkillRegistry, config *SourceManagerConfig) (*SourceManager, error) {
+	m := &SourceManager{
+		registry: registry,
+		interval: config.RefreshInterval,
+	}
+
+	names := make(map[string]bool)
+	for _, sourceConfig := range config.Sources {
+		source, location, err := newSource(sourceConfig)
+		if err != nil {
+			return nil, err
+		}
+		if names[source.Name()] {
+			return nil, fmt.Errorf("skill source %s is defined more than once", source.Name())
+		}
+		names[source.Name()] = true
+
+		m.sources = append(m.sources, &managedSource{
+			source:   source,
+			location: location,
+			enabled:  !sourceConfig.Disabled,
+		})
+	}
+
+	return m, nil
+}
+
+func newSource(config SourceConfig) (Source, string, error) {
+	if config.Name != "" && unsafeSourceNameChars.MatchString(config.Name) {
+		return nil, "", fmt.Errorf("invalid skill source name %q: use letters, digits, '.', '_' and '-'", config.Name)
+	}
+
+	switch {
+	case config.Git != "" && config.URL != "":
+		return nil, "", fmt.Errorf("skill source %s sets both git and url", config.Name)
+	case config.Git != "":
+		if strings.HasPrefix(config.Git, "-") || strings.HasPrefix(config.Ref, "-") {
+			return nil, "", fmt.Errorf("skill source %s: git repository and ref cannot start with '-'", config.Name)
+		}
+		source := NewGitSource(config.Git, config.Ref)
+		if config.Name != "" {
+			source.name = config.Name
+		}
+		location := config.Git
+		if config.Ref != "" {
+			location += "#" + config.Ref
+		}
+		return source, location, nil
+	case config.URL != "":
+		source := NewURLSource(config.URL)
+		if config.Name != "" {
+			source.name = config.Name
+		}
+		return source, config.URL, nil
+	}
+	return nil, "", fmt.Errorf("skill source %s needs a git repository or url", config.Name)
+}
+
+// LoadAll loads every enabled source, continuing past failures.
+func (m *SourceManager) LoadAll(ctx context.Context) error {
+	m.mu.Lock()
+	sources := make([]*managedSource, 0, len(m.sources))
+	for _, source := range m.sources {
+		if source.enabled {
+			sources = append(sources, source)
+		}
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, source := range sources {
+		if err := m.load(ctx, source); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *SourceManager) load(ctx context.Context, source *managedSource) error {
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+
+	name := source.source.Name()
+	err := m.registry.LoadFromSource(ctx, source.source)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source.lastFetched = time.Now()
+	source.lastError = err
+	if err != nil {
+		return err
+	}
+
+	// Disabled while it was being fetched.
+	if !source.enabled {
+		m.registry.UnloadSource(name)
+		return nil
+	}
+
+	logger.Info("Loaded skill source", "source", name, "skills", m.registry.sourceSkillCount(name))
+	return nil
+}
+
+// Start refreshes the enabled sources every refresh interval until ctx is
+// done.
+func (m *SourceManager) Start(ctx context.Context) {
+	if m.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.LoadAll(ctx); err != nil {
+					logger.Error("Failed to refresh skill sources", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Enable turns a source on and loads its skills.
+func (m *SourceManager) Enable(ctx context.Context, name string) error {
+	m.mu.Lock()
+	source := m.find(name)
+	if source == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("skill source %s not found", name)
+	}
+	source.enabled = true
+	m.mu.Unlock()
+
+	return m.load(ctx, source)
+}
+
+// Disable turns a source off and removes its skills from the registry.
+func (m *SourceManager) Disable(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source := m.find(name)
+	if source == nil {
+		return fmt.Errorf("skill source %s not found", name)
+	}
+	source.enabled = false
+	m.registry.UnloadSource(name)
+
+	return nil
+}
+
+func (m *SourceManager) Sources() []SourceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]SourceStatus, 0, len(m.sources))
+	for _, source := range m.sources {
+		status := SourceStatus{
+			Name:        source.source.Name(),
+			Location:    source.location,
+			Enabled:     source.enabled,
+			Skills:      m.registry.sourceSkillCount(source.source.Name()),
+			LastFetched: source.lastFetched,
+		}
+		if source.lastError != nil {
+			status.LastError = source.lastError.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (m *SourceManager) find(name string) *managedSource {
+	for _, source := range m.sources {
+		if source.source.Name() == name {
+			return source
+		}
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package skills
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func packSkill(name string) string {
+	return "---\nname: " + name + "\ndescription: " + name + " skill\n---\nUse " + name + "."
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	writer.Close()
+	return buf.Bytes()
+}
+
+func tarGzArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	writer := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		writer.Write([]byte(content))
+	}
+	writer.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// archiveServer serves whatever archive is current, or an error while it is
+// nil.
+type archiveServer struct {
+	mu      sync.Mutex
+	archive []byte
+}
+
+func (s *archiveServer) set(archive []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archive = archive
+}
+
+func (s *archiveServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.archive == nil {
+		http.Error(w, "gone", http.StatusInternalServerError)
+		return
+	}
+	w.Write(s.archive)
+}
+
+func newPackRegistry(t *testing.T) *SkillRegistry {
+	registry := NewSkillRegistry(storage.NewFileStorage(t.TempDir()))
+	registry.SetCacheDir(t.TempDir())
+	return registry
+}
+
+func skillNames(registry *SkillRegistry) map[string]bool {
+	names := make(map[string]bool)
+	for _, skill := range registry.ListAll() {
+		names[skill.Name] = true
+	}
+	return names
+}
+
+func TestLoadFromURL(t *testing.T) {
+	archives := map[string][]byte{
+		"zip": zipArchive(t, map[string]string{
+			"pack/weather.md":   packSkill("weather"),
+			"pack/docs/news.md": packSkill("news"),
+			"pack/README.md":    "# A skill pack",
+			"pack/script.sh":    "echo hi",
+		}),
+		"tar.gz": tarGzArchive(t, map[string]string{
+			"pack/weather.md": packSkill("weather"),
+			"pack/news.md":    packSkill("news"),
+			"../escape.md":    packSkill("escape"),
+		}),
+	}
+
+	for format, archive := range archives {
+		t.Run(format, func(t *testing.T) {
+			server := httptest.NewServer(&archiveServer{archive: archive})
+			defer server.Close()
+
+			registry := newPackRegistry(t)
+			if err := registry.LoadFromURL(context.Background(), server.URL+"/pack"); err != nil {
+				t.Fatalf("Expected pack to load, got %v", err)
+			}
+
+			names := skillNames(registry)
+			if !names["weather"] || !names["news"] {
+				t.Errorf("Expected weather and news skills, got %v", names)
+			}
+			if format == "tar.gz" && !names["escape"] {
+				t.Errorf("Expected a ../ path to be kept inside the cache, got %v", names)
+			}
+			if _, err := os.Stat(filepath.Join(registry.cacheDir, "escape.md")); err == nil {
+				t.Error("Expected nothing to be written outside the cache")
+			}
+
+			source := NewURLSource(server.URL + "/pack").Name()
+			for _, skill := range registry.ListAll() {
+				if skill.Metadata[sourceMetadata] != source {
+					t.Errorf("Expected skill %s to record source %s, got %q", skill.Name, source, skill.Metadata[sourceMetadata])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFromURLRefresh(t *testing.T) {
+	pack := &archiveServer{archive: zipArchive(t, map[string]string{
+		"weather.md": packSkill("weather"),
+		"news.md":    packSkill("news"),
+	})}
+	server := httptest.NewServer(pack)
+	defer server.Close()
+
+	registry := newPackRegistry(t)
+	source := NewURLSource(server.URL)
+	ctx := context.Background()
+	if err := registry.LoadFromSource(ctx, source); err != nil {
+		t.Fatalf("Expected pack to load, got %v", err)
+	}
+
+	var newsID string
+	for _, skill := range registry.ListAll() {
+		if skill.Name == "news" {
+			newsID = skill.ID
+		}
+	}
+	registry.Disable(newsID)
+
+	pack.set(zipArchive(t, map[string]string{
+		"news.md":  packSkill("news"),
+		"stock.md": packSkill("stock"),
+	}))
+	if err := registry.LoadFromSource(ctx, source); err != nil {
+		t.Fatalf("Expected pack to refresh, got %v", err)
+	}
+
+	names := skillNames(registry)
+	if names["weather"] || !names["news"] || !names["stock"] || len(names) != 2 {
+		t.Errorf("Expected the refreshed pack to replace the old one, got %v", names)
+	}
+	if news, _ := registry.Get(newsID); news.Enabled {
+		t.Error("Expected a disabled skill to stay disabled after a refresh")
+	}
+
+	pack.set(nil)
+	if err := registry.LoadFromSource(ctx, source); err != nil {
+		t.Fatalf("Expected the cached copy to be used when the download fails, got %v", err)
+	}
+	if registry.CountAll() != 2 {
+		t.Errorf("Expected the cached skills to stay loaded, got %d", registry.CountAll())
+	}
+
+	if err := registry.LoadFromURL(ctx, server.URL+"/other"); err == nil {
+		t.Error("Expected an error for a source that was never downloaded")
+	}
+}
+
+func TestLoadFromGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	os.WriteFile(filepath.Join(repo, "weather.md"), []byte(packSkill("weather")), 0644)
+	git("add", ".")
+	git("commit", "-q", "-m", "weather")
+	git("tag", "v1")
+	os.WriteFile(filepath.Join(repo, "news.md"), []byte(packSkill("news")), 0644)
+	outside := filepath.Join(t.TempDir(), "secret.md")
+	os.WriteFile(outside, []byte(packSkill("secret")), 0644)
+	if err := os.Symlink(outside, filepath.Join(repo, "secret.md")); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "news")
+
+	ctx := context.Background()
+	registry := newPackRegistry(t)
+	if err := registry.LoadFromGit(ctx, "file://"+repo, "v1"); err != nil {
+		t.Fatalf("Expected repository to load, got %v", err)
+	}
+	if names := skillNames(registry); !names["weather"] || names["news"] {
+		t.Errorf("Expected only the skills at v1, got %v", names)
+	}
+
+	registry = newPackRegistry(t)
+	if err := registry.LoadFromGit(ctx, "file://"+repo, ""); err != nil {
+		t.Fatalf("Expected repository to load, got %v", err)
+	}
+	if names := skillNames(registry); !names["weather"] || !names["news"] || names["secret"] {
+		t.Errorf("Expected the skills on the default branch without the symlinked one, got %v", names)
+	}
+
+	if err := registry.LoadFromGit(ctx, "file://"+repo, "--upload-pack=touch /tmp/pwned"); err == nil {
+		t.Error("Expected a ref that looks like an option to be rejected")
+	}
+}
+
+func TestSourceManager(t *testing.T) {
+	server := httptest.NewServer(&archiveServer{archive: zipArchive(t, map[string]string{"weather.md": packSkill("weather")})})
+	defer server.Close()
+
+	registry := newPackRegistry(t)
+	manager, err := NewSourceManager(registry, &SourceManagerConfig{
+		Sources: []SourceConfig{
+			{Name: "weather", URL: server.URL},
+			{Name: "news", URL: server.URL + "/news", Disabled: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected manager to be created, got %v", err)
+	}
+
+	ctx := context.Background()
+	if err := manager.LoadAll(ctx); err != nil {
+		t.Fatalf("Expected enabled sources to load, got %v", err)
+	}
+	if registry.CountAll() != 1 {
+		t.Errorf("Expected 1 skill, got %d", registry.CountAll())
+	}
+
+	if err := manager.Disable("weather"); err != nil {
+		t.Fatalf("Expected source to be disabled, got %v", err)
+	}
+	if registry.CountAll() != 0 {
+		t.Errorf("Expected a disabled source to unload its skills, got %d", registry.CountAll())
+	}
+
+	if err := manager.Enable(ctx, "weather"); err != nil {
+		t.Fatalf("Expected source to be enabled, got %v", err)
+	}
+	statuses := manager.Sources()
+	if len(statuses) != 2 || !statuses[0].Enabled || statuses[0].Skills != 1 || statuses[1].Enabled {
+		t.Errorf("Unexpected source statuses: %+v", statuses)
+	}
+
+	if err := manager.Disable("missing"); err == nil {
+		t.Error("Expected an error for an unknown source")
+	}
+
+	for _, config := range []SourceConfig{
+		{Name: "both", Git: "https://example.com/a.git", URL: "https://example.com/a.zip"},
+		{Name: "neither"},
+		{Name: "bad/name", URL: "https://example.com/a.zip"},
+		{Name: "option", Git: "https://example.com/a.git", Ref: "--upload-pack=id"},
+	} {
+		if _, err := NewSourceManager(registry, &SourceManagerConfig{Sources: []SourceConfig{config}}); err == nil {
+			t.Errorf("Expected source %q to be rejected", config.Name)
+		}
+	}
+}
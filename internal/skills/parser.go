@@ -5,9 +5,11 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -15,6 +17,12 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+var (
+	memoryNamespacePattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	scriptNamePattern        = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	scriptPlaceholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+)
+
 type SkillParser struct {
 	storage storage.Storage
 }
@@ -62,7 +70,12 @@ func (p *SkillParser) ParseContent(content, path string) (*Skill, error) {
 		Description: getString(metadata, "description"),
 		Category:    getString(metadata, "category"),
 		Tags:        getStringSlice(metadata, "tags"),
-		Requires:    getStringSlice(metadata, "requires"),
+		Requires:    append(getStringSlice(metadata, "requires"), getStringSlice(metadata, "required_tools")...),
+		Triggers:    getTrigger(metadata, "triggers"),
+		Examples:    getStringSlice(metadata, "examples"),
+		Priority:    getInt(metadata, "priority"),
+		Memory:      strings.ToLower(getString(metadata, "memory")),
+		Path:        path,
 		Content:     skillContent,
 		Metadata:    extractMetadata(metadata),
 		Enabled:     getBool(metadata, "enabled", true),
@@ -78,10 +91,85 @@ func (p *SkillParser) ParseContent(content, path string) (*Skill, error) {
 		return nil, fmt.Errorf("skill description is required")
 	}
 
+	if skill.Memory != "" && !memoryNamespacePattern.MatchString(skill.Memory) {
+		return nil, fmt.Errorf("invalid memory namespace %q: use letters, digits, - and _", skill.Memory)
+	}
+
+	if err := skill.Triggers.Compile(); err != nil {
+		return nil, err
+	}
+
+	scripts, err := getScripts(metadata, "scripts")
+	if err != nil {
+		return nil, err
+	}
+	skill.Scripts = scripts
+
 	return skill, nil
 }
 
 func (p *SkillParser) ParseDirectory(ctx context.Context, dir string) ([]*Skill, error) {
+	files, err := p.skillFiles(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	skills := make([]*Skill, 0, len(files))
+
+	for _, file := range files {
+		skill, err := p.Parse(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse skill file %s: %w", file, err)
+		}
+
+		skills = append(skills, skill)
+	}
+
+	return skills, nil
+}
+
+// SkillFileError is a problem with one skill file.
+type SkillFileError struct {
+	Path string
+	Err  error
+}
+
+func (e *SkillFileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// CheckDirectory parses every skill file in dir like ParseDirectory, but
+// goes on past broken files to report all of them. A skill whose name is
+// already taken is reported too, since skills are looked up by name.
+func (p *SkillParser) CheckDirectory(ctx context.Context, dir string) ([]*Skill, []*SkillFileError, error) {
+	files, err := p.skillFiles(ctx, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var skills []*Skill
+	var problems []*SkillFileError
+	names := make(map[string]string)
+
+	for _, file := range files {
+		skill, err := p.Parse(ctx, file)
+		if err != nil {
+			problems = append(problems, &SkillFileError{Path: file, Err: err})
+			continue
+		}
+		if other, ok := names[skill.Name]; ok {
+			problems = append(problems, &SkillFileError{Path: file, Err: fmt.Errorf("skill name %q is also used by %s", skill.Name, other)})
+			continue
+		}
+
+		names[skill.Name] = file
+		skills = append(skills, skill)
+	}
+
+	return skills, problems, nil
+}
+
+func (p *SkillParser) skillFiles(ctx context.Context, dir string) ([]string, error) {
 	var files []string
 	var err error
 
@@ -95,22 +183,13 @@ func (p *SkillParser) ParseDirectory(ctx context.Context, dir string) ([]*Skill,
 		return nil, fmt.Errorf("failed to list skill directory: %w", err)
 	}
 
-	skills := make([]*Skill, 0, len(files))
-
+	skillFiles := files[:0]
 	for _, file := range files {
-		if !strings.HasSuffix(strings.ToLower(file), ".md") {
-			continue
-		}
-
-		skill, err := p.Parse(ctx, file)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse skill file %s: %w", file, err)
+		if strings.HasSuffix(strings.ToLower(file), ".md") {
+			skillFiles = append(skillFiles, file)
 		}
-
-		skills = append(skills, skill)
 	}
-
-	return skills, nil
+	return skillFiles, nil
 }
 
 func (p *SkillParser) listAbsoluteDirectory(ctx context.Context, dir string) ([]string, error) {
@@ -158,6 +237,88 @@ func getStringSlice(m map[string]interface{}, key string) []string {
 	return make([]string, 0)
 }
 
+func getInt(m map[string]interface{}, key string) int {
+	switch v := m[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// getTrigger reads either a list of keywords or a map with keywords and
+// patterns lists.
+func getTrigger(m map[string]interface{}, key string) SkillTrigger {
+	switch v := m[key].(type) {
+	case []interface{}:
+		return SkillTrigger{Keywords: getStringSlice(m, key)}
+	case map[string]interface{}:
+		return SkillTrigger{
+			Keywords: getStringSlice(v, "keywords"),
+			Patterns: getStringSlice(v, "patterns"),
+		}
+	}
+	return SkillTrigger{}
+}
+
+// getScripts reads the scripts list, checking that each script has a name
+// and command and only uses placeholders for parameters it declares.
+func getScripts(m map[string]interface{}, key string) ([]SkillScript, error) {
+	entries, ok := m[key].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	scripts := make([]SkillScript, 0, len(entries))
+	names := make(map[string]bool)
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("script %d must be a map with a name and command", i+1)
+		}
+
+		script := SkillScript{
+			Name:        getString(fields, "name"),
+			Description: getString(fields, "description"),
+			Command:     getString(fields, "command"),
+			Args:        getStringSlice(fields, "args"),
+		}
+		if !scriptNamePattern.MatchString(script.Name) {
+			return nil, fmt.Errorf("invalid script name %q: use letters, digits, - and _", script.Name)
+		}
+		if names[script.Name] {
+			return nil, fmt.Errorf("script %s is defined more than once", script.Name)
+		}
+		names[script.Name] = true
+		if script.Command == "" {
+			return nil, fmt.Errorf("script %s needs a command", script.Name)
+		}
+
+		parameters, ok := fields["parameters"].(map[string]interface{})
+		if !ok {
+			parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		properties, _ := parameters["properties"].(map[string]interface{})
+		for _, arg := range script.Args {
+			for _, match := range scriptPlaceholderPattern.FindAllStringSubmatch(arg, -1) {
+				if _, ok := properties[match[1]]; !ok {
+					return nil, fmt.Errorf("script %s uses {{%s}} but has no such parameter", script.Name, match[1])
+				}
+			}
+		}
+
+		data, err := json.Marshal(parameters)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters for script %s: %w", script.Name, err)
+		}
+		script.Parameters = data
+
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
 func getBool(m map[string]interface{}, key string, defaultValue bool) bool {
 	if val, ok := m[key]; ok {
 		if b, ok := val.(bool); ok {
@@ -176,7 +337,14 @@ func extractMetadata(m map[string]interface{}) map[string]string {
 		"category":    true,
 		"tags":        true,
 		"requires":    true,
+		"memory":      true,
+		"triggers":    true,
+		"examples":    true,
+		"priority":    true,
+		"scripts":     true,
 		"enabled":     true,
+
+		"required_tools": true,
 	}
 
 	for key, val := range m {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/wjffsx/miniclaw_go/internal/storage"
@@ -73,6 +74,52 @@ This is a test skill content.
 	}
 }
 
+func TestParseContentSelectionHints(t *testing.T) {
+	parser := NewSkillParser(nil)
+
+	content := `---
+name: translator
+description: Translate text
+requires: [read_file]
+required_tools: [web_search]
+triggers:
+  keywords: [translate, 翻译]
+  patterns: ['into (french|german)']
+examples:
+  - "How do you say hello in Spanish?"
+priority: 3
+---
+Translate.
+`
+
+	skill, err := parser.ParseContent(content, "translator.md")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if strings.Join(skill.Requires, ",") != "read_file,web_search" {
+		t.Errorf("Expected requires and required_tools to be combined, got %v", skill.Requires)
+	}
+	if len(skill.Triggers.Keywords) != 2 || !skill.Triggers.MatchesPattern("Put this INTO French") {
+		t.Errorf("Expected trigger keywords and a case-insensitive pattern, got %+v", skill.Triggers)
+	}
+	if len(skill.Examples) != 1 || skill.Priority != 3 {
+		t.Errorf("Expected an example and priority 3, got %v and %d", skill.Examples, skill.Priority)
+	}
+	if len(skill.Metadata) != 0 {
+		t.Errorf("Expected no leftover metadata, got %v", skill.Metadata)
+	}
+
+	skill, err = parser.ParseContent("---\nname: a\ndescription: b\ntriggers: [summarize, tl;dr]\n---\n", "a.md")
+	if err != nil || !skill.Triggers.MatchesKeyword("TL;DR please") {
+		t.Errorf("Expected a list of triggers to be keywords, got %+v, %v", skill, err)
+	}
+
+	if _, err := parser.ParseContent("---\nname: a\ndescription: b\ntriggers:\n  patterns: ['(']\n---\n", "a.md"); err == nil {
+		t.Error("Expected an invalid trigger pattern to be rejected")
+	}
+}
+
 func TestParseContentInvalidFormat(t *testing.T) {
 	parser := NewSkillParser(nil)
 
@@ -118,6 +165,87 @@ category: "test"
 	}
 }
 
+func TestParseContentMemoryNamespace(t *testing.T) {
+	parser := NewSkillParser(nil)
+
+	skill, err := parser.ParseContent("---\nname: jobs\ndescription: Track job applications\nmemory: Job-Search\n---\nBody", "jobs.md")
+	if err != nil {
+		t.Fatalf("Failed to parse skill: %v", err)
+	}
+	if skill.Memory != "job-search" {
+		t.Errorf("Expected memory namespace job-search, got %q", skill.Memory)
+	}
+	if _, ok := skill.Metadata["memory"]; ok {
+		t.Error("Expected memory not to be copied into metadata")
+	}
+
+	if _, err := parser.ParseContent("---\nname: jobs\ndescription: d\nmemory: ../global\n---\nBody", "jobs.md"); err == nil {
+		t.Error("Expected error for invalid memory namespace")
+	}
+}
+
+func TestParseContentScripts(t *testing.T) {
+	parser := NewSkillParser(nil)
+
+	content := `---
+name: weather
+description: Weather forecasts
+scripts:
+  - name: forecast
+    description: Get the forecast for a city
+    command: python3
+    args: ["forecast.py", "--city", "{{city}}", "{{ days }}"]
+    parameters:
+      type: object
+      properties:
+        city: {type: string}
+        days: {type: integer}
+      required: [city]
+  - name: sources
+    command: cat
+    args: [sources.txt]
+---
+Body`
+
+	skill, err := parser.ParseContent(content, "skills/weather/SKILL.md")
+	if err != nil {
+		t.Fatalf("Failed to parse skill: %v", err)
+	}
+	if skill.Path != "skills/weather/SKILL.md" {
+		t.Errorf("Expected path to be recorded, got %q", skill.Path)
+	}
+	if len(skill.Scripts) != 2 {
+		t.Fatalf("Expected 2 scripts, got %d", len(skill.Scripts))
+	}
+
+	forecast := skill.Scripts[0]
+	if forecast.Name != "forecast" || forecast.Command != "python3" || len(forecast.Args) != 4 {
+		t.Errorf("Unexpected script: %+v", forecast)
+	}
+	if !strings.Contains(string(forecast.Parameters), `"required":["city"]`) {
+		t.Errorf("Expected parameters to be kept as JSON schema, got %s", forecast.Parameters)
+	}
+	if string(skill.Scripts[1].Parameters) != `{"properties":{},"type":"object"}` {
+		t.Errorf("Expected an empty object schema by default, got %s", skill.Scripts[1].Parameters)
+	}
+	if _, ok := skill.Metadata["scripts"]; ok {
+		t.Error("Expected scripts not to be copied into metadata")
+	}
+
+	invalid := []string{
+		"scripts:\n  - name: run\n",
+		"scripts:\n  - name: bad name\n    command: ls\n",
+		"scripts:\n  - name: run\n    command: ls\n  - name: run\n    command: pwd\n",
+		"scripts:\n  - name: run\n    command: cat\n    args: ['{{file}}']\n",
+		"scripts:\n  - ls\n",
+	}
+	for _, scripts := range invalid {
+		if _, err := parser.ParseContent("---\nname: s\ndescription: d\n"+scripts+"---\nBody", "s.md"); err == nil {
+			t.Errorf("Expected error for %q", scripts)
+		}
+	}
+}
+
 func TestParseDirectory(t *testing.T) {
 	tempDir := t.TempDir()
 	store := storage.NewFileStorage(tempDir)
@@ -151,6 +279,34 @@ category: "test"
 	}
 }
 
+func TestCheckDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	parser := NewSkillParser(storage.NewFileStorage(tempDir))
+
+	files := map[string]string{
+		"a_weather.md": "---\nname: weather\ndescription: Check the weather\n---\nUse get_weather.",
+		"b_broken.md":  "---\nname: broken\n---\nNo description.",
+		"c_copy.md":    "---\nname: weather\ndescription: Another weather skill\n---\n",
+		"notes.txt":    "not a skill",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	skills, problems, err := parser.CheckDirectory(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(skills) != 1 || skills[0].Name != "weather" {
+		t.Errorf("Expected only the first weather skill, got %d skills", len(skills))
+	}
+	if len(problems) != 2 || !strings.Contains(problems[0].Error(), "b_broken.md: skill description is required") || !strings.Contains(problems[1].Error(), "also used by") {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+}
+
 func TestParseDirectoryEmpty(t *testing.T) {
 	tempDir := t.TempDir()
 	store := storage.NewFileStorage(tempDir)
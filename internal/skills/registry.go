@@ -6,14 +6,23 @@ import (
 	"sync"
 
 	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
 )
 
 type SkillRegistry struct {
-	mu      sync.RWMutex
-	skills  map[string]*Skill
-	index   *SkillIndex
-	storage storage.Storage
-	parser  *SkillParser
+	mu       sync.RWMutex
+	skills   map[string]*Skill
+	index    *SkillIndex
+	storage  storage.Storage
+	parser   *SkillParser
+	cacheDir string
+	// sources maps a source name to the IDs of the skills loaded from it.
+	sources map[string][]string
+
+	toolRegistry *tools.ToolRegistry
+	scriptRunner ScriptRunner
+	// scriptTools maps a skill ID to the names of its script tools.
+	scriptTools map[string][]string
 }
 
 func NewSkillRegistry(storage storage.Storage) *SkillRegistry {
@@ -22,6 +31,9 @@ func NewSkillRegistry(storage storage.Storage) *SkillRegistry {
 		index:   NewSkillIndex(),
 		storage: storage,
 		parser:  NewSkillParser(storage),
+		sources: make(map[string][]string),
+
+		scriptTools: make(map[string][]string),
 	}
 }
 
@@ -43,6 +55,7 @@ func (r *SkillRegistry) Register(skill *Skill) error {
 
 	r.skills[skill.ID] = skill
 	r.index.Add(skill)
+	r.syncScriptToolsLocked(skill)
 
 	return nil
 }
@@ -57,6 +70,7 @@ func (r *SkillRegistry) Unregister(skillID string) error {
 
 	delete(r.skills, skillID)
 	r.index.Remove(skillID)
+	r.removeScriptToolsLocked(skillID)
 
 	return nil
 }
@@ -160,6 +174,7 @@ func (r *SkillRegistry) Enable(skillID string) error {
 
 	r.index.Remove(skillID)
 	r.index.Add(skill)
+	r.syncScriptToolsLocked(skill)
 
 	return nil
 }
@@ -177,6 +192,7 @@ func (r *SkillRegistry) Disable(skillID string) error {
 	skill.Update()
 
 	r.index.Remove(skillID)
+	r.removeScriptToolsLocked(skillID)
 
 	return nil
 }
@@ -204,6 +220,10 @@ func (r *SkillRegistry) Clear() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	for id := range r.scriptTools {
+		r.removeScriptToolsLocked(id)
+	}
 	r.skills = make(map[string]*Skill)
 	r.index = NewSkillIndex()
+	r.sources = make(map[string][]string)
 }
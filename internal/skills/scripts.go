@@ -0,0 +1,157 @@
+package skills
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+var unsafeToolNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// ScriptRunner runs a skill script's command line in a directory. The exec
+// tool implements it, so scripts are sandboxed like the commands the model
+// runs itself.
+type ScriptRunner interface {
+	Run(ctx context.Context, args []string, dir string) (string, error)
+}
+
+// SetScriptTools registers the scripts of enabled skills as tools in
+// registry, now and whenever skills are added, changed or removed.
+func (r *SkillRegistry) SetScriptTools(registry *tools.ToolRegistry, runner ScriptRunner) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id := range r.scriptTools {
+		r.removeScriptToolsLocked(id)
+	}
+	r.toolRegistry = registry
+	r.scriptRunner = runner
+	for _, skill := range r.skills {
+		r.syncScriptToolsLocked(skill)
+	}
+}
+
+// syncScriptToolsLocked replaces the tools registered for a skill with the
+// ones it declares now.
+func (r *SkillRegistry) syncScriptToolsLocked(skill *Skill) {
+	r.removeScriptToolsLocked(skill.ID)
+	if r.toolRegistry == nil || !skill.Enabled || len(skill.Scripts) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(skill.Scripts))
+	for _, script := range skill.Scripts {
+		tool := newScriptTool(skill, script, r.scriptRunner)
+		if err := r.toolRegistry.Register(tool); err != nil {
+			logger.Warn("Failed to register skill script", "skill", skill.Name, "script", script.Name, "error", err)
+			continue
+		}
+		names = append(names, tool.Name())
+	}
+	r.scriptTools[skill.ID] = names
+}
+
+func (r *SkillRegistry) removeScriptToolsLocked(skillID string) {
+	for _, name := range r.scriptTools[skillID] {
+		r.toolRegistry.Unregister(name)
+	}
+	delete(r.scriptTools, skillID)
+}
+
+type scriptTool struct {
+	name   string
+	skill  string
+	script SkillScript
+	dir    string
+	runner ScriptRunner
+}
+
+func newScriptTool(skill *Skill, script SkillScript, runner ScriptRunner) *scriptTool {
+	dir := ""
+	if skill.Path != "" {
+		dir = filepath.Dir(skill.Path)
+	}
+	return &scriptTool{
+		name:   ScriptToolName(skill.Name, script.Name),
+		skill:  skill.Name,
+		script: script,
+		dir:    dir,
+		runner: runner,
+	}
+}
+
+// ScriptToolName is the tool name a skill's script is registered under, e.g.
+// skill_weather_forecast.
+func ScriptToolName(skill, script string) string {
+	return "skill_" + toolNamePart(skill) + "_" + toolNamePart(script)
+}
+
+func toolNamePart(name string) string {
+	return strings.Trim(unsafeToolNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+func (t *scriptTool) Name() string {
+	return t.name
+}
+
+func (t *scriptTool) Description() string {
+	description := t.script.Description
+	if description == "" {
+		description = fmt.Sprintf("Run the %s script", t.script.Name)
+	}
+	return fmt.Sprintf("%s (from the %s skill)", description, t.skill)
+}
+
+func (t *scriptTool) Parameters() json.RawMessage {
+	return t.script.Parameters
+}
+
+// Policy matches the exec tool, since scripts run commands the same way.
+func (t *scriptTool) Policy() tools.ToolPolicy {
+	return tools.ToolPolicy{AdminOnly: true}
+}
+
+func (t *scriptTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	if t.runner == nil {
+		return "", &tools.ToolError{
+			Code:    "EXECUTION_FAILED",
+			Message: "skill scripts need the exec tool to be enabled",
+		}
+	}
+
+	args := []string{t.script.Command}
+	for _, arg := range t.script.Args {
+		// An argument that is only a placeholder is left out when the
+		// parameter is, so optional flags can be written as their own
+		// arguments.
+		if match := scriptPlaceholderPattern.FindStringSubmatch(arg); match != nil && match[0] == arg {
+			if _, ok := params[match[1]]; !ok {
+				continue
+			}
+		}
+		args = append(args, scriptPlaceholderPattern.ReplaceAllStringFunc(arg, func(placeholder string) string {
+			name := scriptPlaceholderPattern.FindStringSubmatch(placeholder)[1]
+			return paramString(params[name])
+		}))
+	}
+
+	return t.runner.Run(ctx, args, t.dir)
+}
+
+func paramString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64, bool, int:
+		return fmt.Sprint(v)
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
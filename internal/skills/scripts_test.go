@@ -0,0 +1,99 @@
+package skills
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+type recordingRunner struct {
+	args []string
+	dir  string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, args []string, dir string) (string, error) {
+	r.args = args
+	r.dir = dir
+	return "sunny", nil
+}
+
+const scriptSkill = `---
+name: Weather Report
+description: Weather forecasts
+scripts:
+  - name: forecast
+    description: Get the forecast for a city
+    command: python3
+    args: ["forecast.py", "--city={{city}}", "{{days}}"]
+    parameters:
+      type: object
+      properties:
+        city: {type: string}
+        days: {type: integer}
+---
+Body`
+
+func TestScriptTools(t *testing.T) {
+	parser := NewSkillParser(nil)
+	skill, err := parser.ParseContent(scriptSkill, "skills/weather/SKILL.md")
+	if err != nil {
+		t.Fatalf("Failed to parse skill: %v", err)
+	}
+
+	registry := NewSkillRegistry(nil)
+	if err := registry.Register(skill); err != nil {
+		t.Fatalf("Failed to register skill: %v", err)
+	}
+
+	toolRegistry := tools.NewToolRegistry()
+	runner := &recordingRunner{}
+	registry.SetScriptTools(toolRegistry, runner)
+
+	name := ScriptToolName(skill.Name, "forecast")
+	if name != "skill_weather_report_forecast" {
+		t.Errorf("Expected a namespaced tool name, got %s", name)
+	}
+	tool, ok := toolRegistry.Get(name)
+	if !ok {
+		t.Fatalf("Expected script tool %s to be registered", name)
+	}
+	if !strings.Contains(tool.Description(), "Get the forecast for a city") || !strings.Contains(string(tool.Parameters()), "city") {
+		t.Errorf("Unexpected tool schema: %s %s", tool.Description(), tool.Parameters())
+	}
+	if policy := tool.(tools.PolicyProvider).Policy(); !policy.AdminOnly {
+		t.Error("Expected script tools to be admin-only like exec_command")
+	}
+
+	ctx := context.Background()
+	result, err := tool.Execute(ctx, map[string]interface{}{"city": "Paris", "days": float64(3)})
+	if err != nil || result != "sunny" {
+		t.Fatalf("Expected script output, got %q, %v", result, err)
+	}
+	if got := strings.Join(runner.args, " "); got != "python3 forecast.py --city=Paris 3" {
+		t.Errorf("Expected placeholders to be filled in, got %q", got)
+	}
+	if runner.dir != "skills/weather" {
+		t.Errorf("Expected script to run in the skill directory, got %q", runner.dir)
+	}
+
+	tool.Execute(ctx, map[string]interface{}{"city": "Oslo"})
+	if got := strings.Join(runner.args, " "); got != "python3 forecast.py --city=Oslo" {
+		t.Errorf("Expected a missing optional argument to be left out, got %q", got)
+	}
+
+	registry.Disable(skill.ID)
+	if _, ok := toolRegistry.Get(name); ok {
+		t.Error("Expected tool to be removed when the skill is disabled")
+	}
+	registry.Enable(skill.ID)
+	if _, ok := toolRegistry.Get(name); !ok {
+		t.Error("Expected tool to return when the skill is enabled")
+	}
+
+	registry.Unregister(skill.ID)
+	if _, ok := toolRegistry.Get(name); ok {
+		t.Error("Expected tool to be removed with the skill")
+	}
+}
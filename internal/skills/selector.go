@@ -54,7 +54,7 @@ func NewSkillSelector(registry *SkillRegistry, llm llm.LLMProvider, config *Sele
 }
 
 func (s *SkillSelector) Select(ctx context.Context, userMessage string) ([]*Skill, error) {
-	switch s.config.Method {
+	switch s.GetConfig().Method {
 	case "keyword":
 		return s.selectByKeyword(userMessage)
 	case "llm":
@@ -73,7 +73,7 @@ func (s *SkillSelector) selectByKeyword(userMessage string) ([]*Skill, error) {
 
 	for _, skill := range s.registry.List() {
 		score := s.calculateKeywordScore(skill, keywords, userMessage)
-		if score >= s.config.Threshold {
+		if score >= s.GetConfig().Threshold {
 			candidates = append(candidates, &SkillSelection{
 				Skill:     skill,
 				Score:     score,
@@ -114,7 +114,7 @@ Respond with a JSON object in the following format:
   ]
 }
 
-Select at most %d skills. Only select skills that are directly relevant to the user's request.`, skillList, userMessage, s.config.MaxActive)
+Select at most %d skills. Only select skills that are directly relevant to the user's request.`, skillList, userMessage, s.GetConfig().MaxActive)
 
 	messages := []llm.Message{
 		{
@@ -146,7 +146,7 @@ func (s *SkillSelector) selectHybrid(ctx context.Context, userMessage string) ([
 		return nil, err
 	}
 
-	if len(keywordResults) > 0 && len(keywordResults) <= s.config.MaxActive {
+	if len(keywordResults) > 0 && len(keywordResults) <= s.GetConfig().MaxActive {
 		return keywordResults, nil
 	}
 
@@ -161,9 +161,18 @@ func (s *SkillSelector) selectHybrid(ctx context.Context, userMessage string) ([
 }
 
 func (s *SkillSelector) calculateKeywordScore(skill *Skill, keywords []string, message string) float64 {
+	if skill.Triggers.MatchesPattern(message) {
+		return 1.0
+	}
+
 	var score float64
 	lowerMessage := strings.ToLower(message)
 
+	if skill.Triggers.MatchesKeyword(message) {
+		score += 0.5
+	}
+	score += 0.6 * exampleSimilarity(skill.Examples, keywords)
+
 	for _, keyword := range keywords {
 		if strings.Contains(strings.ToLower(skill.Name), keyword) {
 			score += 0.3
@@ -193,12 +202,42 @@ func (s *SkillSelector) calculateKeywordScore(skill *Skill, keywords []string, m
 	return math.Min(score, 1.0)
 }
 
+// exampleSimilarity is the largest share of an example's keywords that the
+// message also contains.
+func exampleSimilarity(examples []string, keywords []string) float64 {
+	inMessage := make(map[string]bool, len(keywords))
+	for _, keyword := range keywords {
+		inMessage[keyword] = true
+	}
+
+	var best float64
+	for _, example := range examples {
+		exampleKeywords := extractKeywords(example)
+		if len(exampleKeywords) == 0 {
+			continue
+		}
+
+		shared := 0
+		for _, keyword := range exampleKeywords {
+			if inMessage[keyword] {
+				shared++
+			}
+		}
+		best = math.Max(best, float64(shared)/float64(len(exampleKeywords)))
+	}
+	return best
+}
+
 func (s *SkillSelector) buildSkillList(skills []*Skill) string {
 	var builder strings.Builder
 
 	for i, skill := range skills {
-		builder.WriteString(fmt.Sprintf("%d. ID: %s, Name: %s, Description: %s, Tags: %v\n",
+		builder.WriteString(fmt.Sprintf("%d. ID: %s, Name: %s, Description: %s, Tags: %v",
 			i+1, skill.ID, skill.Name, skill.Description, skill.Tags))
+		if len(skill.Examples) > 0 {
+			builder.WriteString(fmt.Sprintf(", Examples: %q", skill.Examples))
+		}
+		builder.WriteString("\n")
 	}
 
 	return builder.String()
@@ -235,13 +274,14 @@ func (s *SkillSelector) rankAndFilter(candidates []*SkillSelection) []*Skill {
 
 	for i := 0; i < len(candidates); i++ {
 		for j := i + 1; j < len(candidates); j++ {
-			if candidates[i].Score < candidates[j].Score {
+			if candidates[i].Score < candidates[j].Score ||
+				(candidates[i].Score == candidates[j].Score && candidates[i].Skill.Priority < candidates[j].Skill.Priority) {
 				candidates[i], candidates[j] = candidates[j], candidates[i]
 			}
 		}
 	}
 
-	maxSkills := s.config.MaxActive
+	maxSkills := s.GetConfig().MaxActive
 	if maxSkills <= 0 {
 		maxSkills = 5
 	}
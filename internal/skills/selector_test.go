@@ -2,6 +2,7 @@ package skills
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/wjffsx/miniclaw_go/internal/llm"
@@ -266,3 +267,60 @@ func (m *mockLLMProvider) StreamComplete(ctx context.Context, req *llm.Completio
 func (m *mockLLMProvider) GetModel() string {
 	return "mock"
 }
+
+func TestSelectByTriggersAndExamples(t *testing.T) {
+	registry := NewSkillRegistry(nil)
+	selector := NewSkillSelector(registry, nil, &SelectionConfig{
+		Method:    "keyword",
+		Threshold: 0.5,
+	})
+
+	translator := NewSkill("translator", "Convert text between languages", "language")
+	translator.Triggers = SkillTrigger{Keywords: []string{"翻译"}, Patterns: []string{`\bin (spanish|french)\b`}}
+	if err := translator.Triggers.Compile(); err != nil {
+		t.Fatalf("Failed to compile triggers: %v", err)
+	}
+	planner := NewSkill("planner", "Break work into steps", "productivity")
+	planner.Examples = []string{"Plan my week around three deadlines"}
+	registry.Register(translator)
+	registry.Register(planner)
+
+	tests := map[string]string{
+		"How do I say goodbye in Spanish?": "translator",
+		"请帮我翻译这句话":                         "translator",
+		"Can you plan my week?":            "planner",
+	}
+	for message, expected := range tests {
+		selections, err := selector.Select(nil, message)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(selections) != 1 || selections[0].Name != expected {
+			t.Errorf("Expected %s for %q, got %v", expected, message, selections)
+		}
+	}
+}
+
+func TestSelectPrefersHigherPriority(t *testing.T) {
+	registry := NewSkillRegistry(nil)
+	selector := NewSkillSelector(registry, nil, &SelectionConfig{
+		Method:    "keyword",
+		Threshold: 0.5,
+		MaxActive: 1,
+	})
+
+	for i, priority := range []int{1, 5, 2} {
+		skill := NewSkill(fmt.Sprintf("notes%d", i), "Keep notes", "notes")
+		skill.Triggers = SkillTrigger{Keywords: []string{"note"}}
+		skill.Priority = priority
+		registry.Register(skill)
+	}
+
+	selections, err := selector.Select(nil, "take a note")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(selections) != 1 || selections[0].Priority != 5 {
+		t.Errorf("Expected the highest priority skill, got %v", selections)
+	}
+}
@@ -1,6 +1,10 @@
 package skills
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -10,7 +14,19 @@ type Skill struct {
 	Description string            `json:"description"`
 	Category    string            `json:"category"`
 	Tags        []string          `json:"tags"`
+	// Requires lists the tools the skill needs to work.
 	Requires    []string          `json:"requires"`
+	Triggers    SkillTrigger      `json:"triggers"`
+	// Examples are user messages the skill is meant for.
+	Examples    []string          `json:"examples,omitempty"`
+	// Priority breaks ties between equally relevant skills, higher first.
+	Priority    int               `json:"priority,omitempty"`
+	// Memory names a private memory area only visible while the skill is active.
+	Memory      string            `json:"memory,omitempty"`
+	// Scripts are commands the skill offers as tools.
+	Scripts     []SkillScript     `json:"scripts,omitempty"`
+	// Path is the file the skill was loaded from, if any.
+	Path        string            `json:"path,omitempty"`
 	Content     string            `json:"content"`
 	Metadata    map[string]string `json:"metadata"`
 	Enabled     bool              `json:"enabled"`
@@ -18,10 +34,60 @@ type Skill struct {
 	UpdatedAt   time.Time         `json:"updated_at"`
 }
 
+// SkillScript is a command a skill exposes as the tool
+// skill_<skill>_<script>. Parameters is a JSON schema for the tool's input;
+// "{{name}}" in Args is replaced by the value of parameter name. Scripts run
+// in the skill's directory.
+type SkillScript struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Command     string          `json:"command"`
+	Args        []string        `json:"args,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// SkillTrigger selects a skill whenever a message contains one of the
+// keywords or matches one of the patterns, ignoring case.
 type SkillTrigger struct {
 	Keywords   []string `json:"keywords"`
+	Patterns   []string `json:"patterns,omitempty"`
 	Intent     string   `json:"intent"`
 	Confidence float64  `json:"confidence"`
+
+	compiled []*regexp.Regexp
+}
+
+// Compile prepares the patterns for MatchesPattern. The parser calls it.
+func (t *SkillTrigger) Compile() error {
+	compiled := make([]*regexp.Regexp, 0, len(t.Patterns))
+	for _, pattern := range t.Patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return fmt.Errorf("invalid trigger pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	t.compiled = compiled
+	return nil
+}
+
+func (t *SkillTrigger) MatchesKeyword(message string) bool {
+	lowerMessage := strings.ToLower(message)
+	for _, keyword := range t.Keywords {
+		if keyword != "" && strings.Contains(lowerMessage, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *SkillTrigger) MatchesPattern(message string) bool {
+	for _, re := range t.compiled {
+		if re.MatchString(message) {
+			return true
+		}
+	}
+	return false
 }
 
 func NewSkill(name, description, category string) *Skill {
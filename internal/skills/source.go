@@ -0,0 +1,266 @@
+package skills
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	maxArchiveSize   = 50 << 20
+	maxSkillFileSize = 1 << 20
+	downloadTimeout  = 2 * time.Minute
+	sourceMetadata   = "source"
+)
+
+var unsafeSourceNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// Source fetches skill files from outside the skills directory, such as a
+// community skill pack.
+type Source interface {
+	// Name identifies the source and names its cache directory.
+	Name() string
+	// Fetch brings dir up to date with the source, creating it if needed.
+	Fetch(ctx context.Context, dir string) error
+}
+
+// GitSource clones a git repository with the git command. Ref is a branch,
+// tag or commit; empty means the default branch.
+type GitSource struct {
+	URL  string
+	Ref  string
+	name string
+}
+
+func NewGitSource(url, ref string) *GitSource {
+	location := url
+	if ref != "" {
+		location += "#" + ref
+	}
+	return &GitSource{URL: url, Ref: ref, name: sourceName(location)}
+}
+
+func (s *GitSource) Name() string {
+	return s.name
+}
+
+func (s *GitSource) Fetch(ctx context.Context, dir string) error {
+	// Either would be taken as an option by git.
+	if strings.HasPrefix(s.URL, "-") || strings.HasPrefix(s.Ref, "-") {
+		return fmt.Errorf("git repository and ref cannot start with '-'")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err := runGit(ctx, "", "init", "-q", dir); err != nil {
+			return err
+		}
+		if err := runGit(ctx, dir, "remote", "add", "origin", s.URL); err != nil {
+			return err
+		}
+	} else if err := runGit(ctx, dir, "remote", "set-url", "origin", s.URL); err != nil {
+		return err
+	}
+
+	ref := s.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := runGit(ctx, dir, "fetch", "-q", "--depth", "1", "origin", ref); err != nil {
+		return err
+	}
+	return runGit(ctx, dir, "reset", "-q", "--hard", "FETCH_HEAD")
+}
+
+func runGit(ctx context.Context, dir string, args ...string) error {
+	command := args[0]
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s failed: %w: %s", command, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// URLSource downloads a zip or tar(.gz) archive and keeps the Markdown files
+// in it.
+type URLSource struct {
+	URL    string
+	name   string
+	client *http.Client
+}
+
+func NewURLSource(url string) *URLSource {
+	return &URLSource{
+		URL:    url,
+		name:   sourceName(url),
+		client: &http.Client{Timeout: downloadTimeout},
+	}
+}
+
+func (s *URLSource) Name() string {
+	return s.name
+}
+
+func (s *URLSource) Fetch(ctx context.Context, dir string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download skill pack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download skill pack: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to download skill pack: %w", err)
+	}
+	if len(data) > maxArchiveSize {
+		return fmt.Errorf("skill pack is larger than %d bytes", maxArchiveSize)
+	}
+
+	// Extract next to the cache and swap it in, so a failed download leaves
+	// the previous copy in place.
+	staging := dir + ".tmp"
+	os.RemoveAll(staging)
+	if err := extractSkillFiles(data, staging); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		os.RemoveAll(staging)
+		return fmt.Errorf("failed to replace cached skill pack: %w", err)
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		return fmt.Errorf("failed to replace cached skill pack: %w", err)
+	}
+	return nil
+}
+
+func extractSkillFiles(data []byte, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create skill pack directory: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return extractZip(data, dir)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to read skill pack: %w", err)
+		}
+		defer gz.Close()
+		return extractTar(gz, dir)
+	case len(data) > 262 && string(data[257:262]) == "ustar":
+		return extractTar(bytes.NewReader(data), dir)
+	}
+	return fmt.Errorf("unsupported skill pack format, expected a zip or tar archive")
+}
+
+func extractZip(data []byte, dir string) error {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to read skill pack: %w", err)
+	}
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		err = writeSkillFile(dir, file.Name, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTar(r io.Reader, dir string) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read skill pack: %w", err)
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			if err := writeSkillFile(dir, header.Name, archive); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeSkillFile keeps only Markdown files. Paths are cleaned as if rooted at
+// dir, so "../" cannot escape it.
+func writeSkillFile(dir, name string, r io.Reader) error {
+	if !strings.HasSuffix(strings.ToLower(name), ".md") {
+		return nil
+	}
+
+	clean := path.Clean("/" + strings.ReplaceAll(name, `\`, "/"))
+	target := filepath.Join(dir, filepath.FromSlash(clean))
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSkillFileSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxSkillFileSize {
+		return fmt.Errorf("%s is larger than %d bytes", name, maxSkillFileSize)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// sourceName is readable and unique per location, e.g.
+// "skills.git-1a2b3c4d" for https://example.com/team/skills.git.
+func sourceName(location string) string {
+	hash := sha256.Sum256([]byte(location))
+	base := path.Base(strings.TrimSuffix(strings.SplitN(location, "#", 2)[0], "/"))
+	base = strings.Trim(unsafeSourceNameChars.ReplaceAllString(base, "_"), "._")
+	if base == "" {
+		base = "source"
+	}
+	return base + "-" + hex.EncodeToString(hash[:])[:8]
+}
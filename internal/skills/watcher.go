@@ -3,7 +3,6 @@ package skills
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,8 +10,11 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
 )
 
+var logger = logging.For("skills")
+
 type SkillFileWatcher struct {
 	registry *SkillRegistry
 	parser   *SkillParser
@@ -66,7 +68,7 @@ func (w *SkillFileWatcher) Watch(path string) error {
 
 	go w.processEvents()
 
-	log.Printf("Skill file watcher started for: %s", path)
+	logger.Info("Skill file watcher started", "path", path)
 	return nil
 }
 
@@ -89,7 +91,7 @@ func (w *SkillFileWatcher) WatchDirectory(dir string) error {
 
 	go w.processEvents()
 
-	log.Printf("Skill file watcher started for directory: %s", dir)
+	logger.Info("Skill file watcher started", "dir", dir)
 	return nil
 }
 
@@ -103,7 +105,7 @@ func (w *SkillFileWatcher) Stop() {
 		w.watcher.Close()
 	}
 
-	log.Println("Skill file watcher stopped")
+	logger.Info("Skill file watcher stopped")
 }
 
 func (w *SkillFileWatcher) processEvents() {
@@ -124,7 +126,7 @@ func (w *SkillFileWatcher) processEvents() {
 			if !ok {
 				return
 			}
-			log.Printf("File watcher error: %v", err)
+			logger.Error("File watcher error", "error", err)
 		}
 	}
 }
@@ -174,16 +176,16 @@ func (w *SkillFileWatcher) processFileChange(event fsnotify.Event) {
 func (w *SkillFileWatcher) handleFileUpdate(path string) {
 	skill, err := w.parser.Parse(w.ctx, path)
 	if err != nil {
-		log.Printf("Failed to parse skill file %s: %v", path, err)
+		logger.Warn("Failed to parse skill file", "path", path, "error", err)
 		return
 	}
 
 	if err := w.registry.Register(skill); err != nil {
-		log.Printf("Failed to register skill %s from file %s: %v", skill.ID, path, err)
+		logger.Error("Failed to register skill", "skill", skill.ID, "path", path, "error", err)
 		return
 	}
 
-	log.Printf("Skill %s (%s) updated from file: %s", skill.ID, skill.Name, path)
+	logger.Info("Skill updated from file", "skill", skill.ID, "name", skill.Name, "path", path)
 }
 
 func (w *SkillFileWatcher) handleFileRemoval(path string) {
@@ -195,9 +197,9 @@ func (w *SkillFileWatcher) handleFileRemoval(path string) {
 	for _, skill := range skills {
 		if strings.HasPrefix(skill.ID, filenameWithoutExt) {
 			if err := w.registry.Unregister(skill.ID); err != nil {
-				log.Printf("Failed to unregister skill %s: %v", skill.ID, err)
+				logger.Error("Failed to unregister skill", "skill", skill.ID, "error", err)
 			} else {
-				log.Printf("Skill %s (%s) removed due to file deletion: %s", skill.ID, skill.Name, path)
+				logger.Info("Skill removed due to file deletion", "skill", skill.ID, "name", skill.Name, "path", path)
 			}
 			break
 		}
@@ -214,11 +216,11 @@ func (w *SkillFileWatcher) ReloadDirectory(ctx context.Context, dir string) erro
 
 	for _, skill := range skills {
 		if err := w.registry.Register(skill); err != nil {
-			log.Printf("Failed to register skill %s: %v", skill.ID, err)
+			logger.Error("Failed to register skill", "skill", skill.ID, "error", err)
 		}
 	}
 
-	log.Printf("Reloaded %d skills from directory: %s", len(skills), dir)
+	logger.Info("Reloaded skills from directory", "count", len(skills), "dir", dir)
 	return nil
 }
 
@@ -0,0 +1,190 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultWhisperModel  = "whisper-1"
+	defaultTTSModel      = "tts-1"
+	defaultTTSVoice      = "alloy"
+	maxErrorBody         = 512
+)
+
+// OpenAITranscriber uses the audio transcription endpoint of OpenAI or a
+// compatible API.
+type OpenAITranscriber struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	language   string
+	httpClient *http.Client
+}
+
+func NewOpenAITranscriber(config *Config) (*OpenAITranscriber, error) {
+	if config == nil || config.APIKey == "" {
+		return nil, fmt.Errorf("openai transcription needs an API key")
+	}
+
+	return &OpenAITranscriber{
+		baseURL:    baseURLOrDefault(config.BaseURL),
+		apiKey:     config.APIKey,
+		model:      orDefault(config.Model, defaultWhisperModel),
+		language:   config.Language,
+		httpClient: newHTTPClient(config),
+	}, nil
+}
+
+func (t *OpenAITranscriber) Name() string {
+	return "openai"
+}
+
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, audio []byte, fileName string) (string, error) {
+	fields := map[string]string{"model": t.model, "response_format": "json"}
+	if t.language != "" {
+		fields["language"] = t.language
+	}
+
+	body, contentType, err := multipartAudio(audio, fileName, fields)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/audio/transcriptions", body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+
+	return doTranscription(t.httpClient, req)
+}
+
+// OpenAISynthesizer uses the speech endpoint of OpenAI or a compatible API.
+type OpenAISynthesizer struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	voice      string
+	httpClient *http.Client
+}
+
+func NewOpenAISynthesizer(config *Config) (*OpenAISynthesizer, error) {
+	if config == nil || config.APIKey == "" {
+		return nil, fmt.Errorf("openai speech needs an API key")
+	}
+
+	return &OpenAISynthesizer{
+		baseURL:    baseURLOrDefault(config.BaseURL),
+		apiKey:     config.APIKey,
+		model:      orDefault(config.Model, defaultTTSModel),
+		voice:      orDefault(config.Voice, defaultTTSVoice),
+		httpClient: newHTTPClient(config),
+	}, nil
+}
+
+func (s *OpenAISynthesizer) Name() string {
+	return "openai"
+}
+
+func (s *OpenAISynthesizer) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	payload, err := json.Marshal(map[string]string{
+		"model":           s.model,
+		"voice":           s.voice,
+		"input":           text,
+		"response_format": "opus",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/audio/speech", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+	return audio, nil
+}
+
+func multipartAudio(audio []byte, fileName string, fields map[string]string) (io.Reader, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("file", orDefault(fileName, "audio.ogg"))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create form: %w", err)
+	}
+	if _, err := part.Write(audio); err != nil {
+		return nil, "", fmt.Errorf("failed to write audio: %w", err)
+	}
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, "", fmt.Errorf("failed to write form: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to write form: %w", err)
+	}
+
+	return &body, writer.FormDataContentType(), nil
+}
+
+func doTranscription(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp)
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode transcription: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return fmt.Errorf("speech API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func baseURLOrDefault(baseURL string) string {
+	return strings.TrimSuffix(orDefault(baseURL, defaultOpenAIBaseURL), "/")
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
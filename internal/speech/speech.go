@@ -0,0 +1,88 @@
+package speech
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultTimeout = 60 * time.Second
+	// maxSpeechLength keeps synthesized replies short enough to listen to
+	// and under the 4096 characters OpenAI accepts.
+	maxSpeechLength = 4000
+)
+
+// Transcriber turns recorded speech into text.
+type Transcriber interface {
+	Name() string
+	Transcribe(ctx context.Context, audio []byte, fileName string) (string, error)
+}
+
+// Synthesizer reads text aloud. The audio is OGG/Opus, which chat apps
+// play as a voice message.
+type Synthesizer interface {
+	Name() string
+	Synthesize(ctx context.Context, text string) ([]byte, error)
+}
+
+// Config configures a speech backend. Only the fields the backend uses are
+// read.
+type Config struct {
+	// BaseURL is the API root, e.g. https://api.openai.com/v1, or the
+	// address of a whisper.cpp server.
+	BaseURL string
+	APIKey  string
+	Model   string
+	// Voice is the TTS voice, e.g. alloy.
+	Voice string
+	// Language is an ISO-639-1 hint for transcription; empty detects it.
+	Language string
+	Timeout  time.Duration
+}
+
+func newHTTPClient(config *Config) *http.Client {
+	timeout := defaultTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+var (
+	fencedCode   = regexp.MustCompile("(?s)```.*?(```|$)")
+	markdownLink = regexp.MustCompile(`\[([^\]\n]+)\]\([^)\s]+\)`)
+	bareURL      = regexp.MustCompile(`https?://\S+`)
+	emphasis     = regexp.MustCompile("[*_~`#>]+")
+)
+
+// SpeakableText prepares a Markdown reply for reading aloud: code blocks and
+// URLs are left out, links keep their text and formatting marks are dropped.
+func SpeakableText(text string) string {
+	text = fencedCode.ReplaceAllString(text, " (code omitted) ")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = bareURL.ReplaceAllString(text, "")
+	text = emphasis.ReplaceAllString(text, "")
+
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "- ")), " ")
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	text = strings.Join(kept, "\n")
+
+	if utf8.RuneCountInString(text) > maxSpeechLength {
+		runes := []rune(text)[:maxSpeechLength]
+		text = string(runes)
+		if i := strings.LastIndexAny(text, ".!?\n"); i > maxSpeechLength/2 {
+			text = text[:i+1]
+		}
+	}
+	return text
+}
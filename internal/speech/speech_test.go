@@ -0,0 +1,122 @@
+package speech
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAITranscriber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("Unexpected request: %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("Expected an audio file: %v", err)
+		}
+		audio, _ := io.ReadAll(file)
+		if string(audio) != "OggS" || header.Filename != "voice.ogg" {
+			t.Errorf("Unexpected file %q: %q", header.Filename, audio)
+		}
+		if r.FormValue("model") != "whisper-1" || r.FormValue("language") != "de" {
+			t.Errorf("Unexpected form: %v", r.Form)
+		}
+
+		w.Write([]byte(`{"text":" Wie wird das Wetter morgen? "}`))
+	}))
+	defer server.Close()
+
+	transcriber, err := NewOpenAITranscriber(&Config{BaseURL: server.URL + "/v1/", APIKey: "sk-test", Language: "de"})
+	if err != nil {
+		t.Fatalf("Failed to create transcriber: %v", err)
+	}
+
+	text, err := transcriber.Transcribe(context.Background(), []byte("OggS"), "voice.ogg")
+	if err != nil {
+		t.Fatalf("Failed to transcribe: %v", err)
+	}
+	if text != "Wie wird das Wetter morgen?" {
+		t.Errorf("Unexpected transcript: %q", text)
+	}
+
+	if _, err := NewOpenAITranscriber(&Config{}); err == nil {
+		t.Error("Expected an error without an API key")
+	}
+}
+
+func TestOpenAISynthesizer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		if r.URL.Path != "/audio/speech" || payload["input"] != "Hello" || payload["voice"] != "nova" || payload["response_format"] != "opus" {
+			t.Errorf("Unexpected request: %s %v", r.URL.Path, payload)
+		}
+		w.Write([]byte("OggS-audio"))
+	}))
+	defer server.Close()
+
+	synthesizer, err := NewOpenAISynthesizer(&Config{BaseURL: server.URL, APIKey: "sk-test", Voice: "nova"})
+	if err != nil {
+		t.Fatalf("Failed to create synthesizer: %v", err)
+	}
+
+	audio, err := synthesizer.Synthesize(context.Background(), "Hello")
+	if err != nil || string(audio) != "OggS-audio" {
+		t.Errorf("Unexpected result: %q, %v", audio, err)
+	}
+}
+
+func TestWhisperCppTranscriber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inference" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if _, _, err := r.FormFile("file"); err != nil {
+			t.Errorf("Expected an audio file: %v", err)
+		}
+		w.Write([]byte(`{"text":"turn on the lights\n"}`))
+	}))
+	defer server.Close()
+
+	transcriber, err := NewWhisperCppTranscriber(&Config{BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create transcriber: %v", err)
+	}
+
+	text, err := transcriber.Transcribe(context.Background(), []byte("RIFF"), "voice.wav")
+	if err != nil || text != "turn on the lights" {
+		t.Errorf("Unexpected result: %q, %v", text, err)
+	}
+}
+
+func TestTranscriptionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"invalid file format"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	transcriber, _ := NewWhisperCppTranscriber(&Config{BaseURL: server.URL})
+	if _, err := transcriber.Transcribe(context.Background(), []byte("x"), ""); err == nil || !strings.Contains(err.Error(), "invalid file format") {
+		t.Errorf("Expected the API error, got %v", err)
+	}
+}
+
+func TestSpeakableText(t *testing.T) {
+	text := "## Result\n\nThe **answer** is in [the docs](https://example.com).\n\n```go\nfmt.Println(1)\n```\n- see https://example.com/x too"
+	want := "Result\nThe answer is in the docs.\n(code omitted)\nsee too"
+
+	if got := SpeakableText(text); got != want {
+		t.Errorf("SpeakableText() = %q, want %q", got, want)
+	}
+
+	long := SpeakableText(strings.Repeat("One sentence. ", 500))
+	if len(long) > maxSpeechLength || !strings.HasSuffix(long, ".") {
+		t.Errorf("Expected long text cut at a sentence under the limit, got %d chars", len(long))
+	}
+}
@@ -0,0 +1,53 @@
+package speech
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WhisperCppTranscriber sends audio to a local whisper.cpp server. The
+// server only reads WAV unless it runs with --convert, which has it convert
+// voice notes with ffmpeg.
+type WhisperCppTranscriber struct {
+	url        string
+	language   string
+	httpClient *http.Client
+}
+
+func NewWhisperCppTranscriber(config *Config) (*WhisperCppTranscriber, error) {
+	if config == nil || config.BaseURL == "" {
+		return nil, fmt.Errorf("whisper.cpp needs the URL of its server")
+	}
+
+	return &WhisperCppTranscriber{
+		url:        strings.TrimSuffix(config.BaseURL, "/") + "/inference",
+		language:   config.Language,
+		httpClient: newHTTPClient(config),
+	}, nil
+}
+
+func (t *WhisperCppTranscriber) Name() string {
+	return "whisper.cpp"
+}
+
+func (t *WhisperCppTranscriber) Transcribe(ctx context.Context, audio []byte, fileName string) (string, error) {
+	fields := map[string]string{"response_format": "json", "temperature": "0"}
+	if t.language != "" {
+		fields["language"] = t.language
+	}
+
+	body, contentType, err := multipartAudio(audio, fileName, fields)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return doTranscription(t.httpClient, req)
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempFilePattern names the temporary files WriteFileAtomic writes before
+// renaming them into place, so Check can spot ones left by a crash.
+const tempFilePattern = ".*.tmp-*"
+
+// WriteFileAtomic replaces path with data so that readers, and the file after
+// a crash, see either the old or the new content but never a partial write.
+// The data is written to a temporary file in the same directory, synced and
+// renamed over path.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, strings.Replace(tempFilePattern, "*", name, 1))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	// Sync the directory so the rename itself survives a crash. Not every
+	// platform supports this, so failures are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// AppendLines appends data, one or more newline-terminated records, to a
+// JSON Lines file and syncs it. A partial record left at the end of the file
+// by an earlier crash is dropped first; the number of bytes dropped is
+// returned.
+func AppendLines(path string, data []byte, perm os.FileMode) (int64, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, perm)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	dropped, err := repairTail(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to repair file: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return dropped, fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return dropped, fmt.Errorf("failed to sync file: %w", err)
+	}
+	return dropped, nil
+}
+
+// repairTail drops a partial last line from a JSON Lines file opened for
+// reading and writing, left by a crash in the middle of an append, so the
+// next record is not written onto the end of it. It returns the number of
+// bytes dropped.
+func repairTail(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return 0, nil
+	}
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return 0, err
+	}
+	if last[0] == '\n' {
+		return 0, nil
+	}
+
+	// Search backwards for the end of the last complete line.
+	const chunkSize = 4096
+	end := size
+	for end > 0 {
+		start := end - chunkSize
+		if start < 0 {
+			start = 0
+		}
+		chunk := make([]byte, end-start)
+		if _, err := file.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			end = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+
+	if err := file.Truncate(end); err != nil {
+		return 0, err
+	}
+	return size - end, nil
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "MEMORY.md")
+
+	if err := WriteFileAtomic(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("new"), 0600); err != nil {
+		t.Fatalf("Failed to replace: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "new" {
+		t.Errorf("Expected the new content, got %q", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left, got %d entries", len(entries))
+	}
+}
+
+func TestSessionStorageRecoversFromTruncatedWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sessions := NewFileSystemSessionStorage(dir)
+
+	sessions.SaveMessage(ctx, "chat", "user", "hello")
+
+	// Simulate a crash halfway through writing the next message.
+	path := filepath.Join(dir, "sessions", "chat", "messages.jsonl")
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString(`{"role":"assistant","cont`)
+	file.Close()
+
+	if err := sessions.SaveMessage(ctx, "chat", "assistant", "hi there"); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+
+	messages, err := sessions.GetMessages(ctx, "chat", 0)
+	if err != nil {
+		t.Fatalf("Failed to load messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Content != "hello" || messages[1].Content != "hi there" {
+		t.Errorf("Expected the partial message to be dropped, got %+v", messages)
+	}
+
+	if report, _ := Check(ctx, dir, false); len(report.Problems) != 0 {
+		t.Errorf("Expected a clean check after recovery, got %+v", report.Problems)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "memory"), 0755)
+	os.WriteFile(filepath.Join(dir, "tasks.json"), []byte(`[{"id":"a"}]`), 0644)
+	os.WriteFile(filepath.Join(dir, "memory", "config.json"), []byte(`{"key":`), 0644)
+	os.WriteFile(filepath.Join(dir, "history.jsonl"), []byte("{\"a\":1}\nnot json\n{\"a\":2}\n{\"a\""), 0644)
+	os.WriteFile(filepath.Join(dir, "memory", ".MEMORY.md.tmp-123"), []byte("partial"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.tmp"), []byte("kept"), 0644)
+	os.WriteFile(filepath.Join(dir, ".draft.tmp-old"), []byte("kept"), 0644)
+
+	report, err := Check(ctx, dir, false)
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	if report.Files != 6 || len(report.Problems) != 3 || len(report.Unrepaired()) != 3 {
+		t.Fatalf("Expected 3 problems in 6 files, got %d files and %+v", report.Files, report.Problems)
+	}
+
+	report, err = Check(ctx, dir, true)
+	if err != nil {
+		t.Fatalf("Failed to repair: %v", err)
+	}
+	unrepaired := report.Unrepaired()
+	if len(unrepaired) != 1 || filepath.Base(unrepaired[0].Path) != "config.json" {
+		t.Errorf("Expected only the invalid JSON file to stay broken, got %+v", unrepaired)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "history.jsonl"))
+	if string(data) != "{\"a\":1}\n{\"a\":2}\n" {
+		t.Errorf("Expected invalid lines to be dropped, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "memory", ".MEMORY.md.tmp-123")); !os.IsNotExist(err) {
+		t.Error("Expected the temporary file to be removed")
+	}
+	for _, name := range []string{"notes.tmp", ".draft.tmp-old"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to be left alone, got %v", name, err)
+		}
+	}
+
+	if report, _ := Check(ctx, filepath.Join(dir, "missing"), false); report == nil || report.Files != 0 {
+		t.Errorf("Expected a missing directory to be empty, got %+v", report)
+	}
+}
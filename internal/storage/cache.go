@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+var logger = logging.For("storage")
+
+const (
+	defaultCachedSessions     = 100
+	defaultSessionFlushPeriod = 5 * time.Second
+)
+
+// messageAppender is implemented by session storage that can write several
+// messages at once, keeping their timestamps.
+type messageAppender interface {
+	AppendMessages(ctx context.Context, chatID string, messages []Message) error
+}
+
+type SessionCacheConfig struct {
+	// MaxSessions is how many sessions are kept in memory. Zero uses the
+	// default.
+	MaxSessions int
+	// FlushInterval is how often new messages are written to the underlying
+	// storage. Zero uses the default.
+	FlushInterval time.Duration
+}
+
+// CachedSessionStorage is a write-behind cache in front of another session
+// storage. New messages are buffered and appended to it in batches, and the
+// messages of recently used sessions are kept in memory so they are not read
+// back from disk on every turn.
+type CachedSessionStorage struct {
+	inner         SessionStorage
+	maxSessions   int
+	flushInterval time.Duration
+
+	// flushMu keeps flushes in order and stops a session from being loaded
+	// while its buffered messages are half written.
+	flushMu  sync.Mutex
+	mu       sync.Mutex
+	order    *list.List
+	sessions map[string]*list.Element
+	pending  map[string][]Message
+}
+
+type cachedSession struct {
+	chatID   string
+	messages []Message
+}
+
+func NewCachedSessionStorage(inner SessionStorage, config *SessionCacheConfig) *CachedSessionStorage {
+	cache := &CachedSessionStorage{
+		inner:         inner,
+		maxSessions:   defaultCachedSessions,
+		flushInterval: defaultSessionFlushPeriod,
+		order:         list.New(),
+		sessions:      make(map[string]*list.Element),
+		pending:       make(map[string][]Message),
+	}
+	if config != nil && config.MaxSessions > 0 {
+		cache.maxSessions = config.MaxSessions
+	}
+	if config != nil && config.FlushInterval > 0 {
+		cache.flushInterval = config.FlushInterval
+	}
+	return cache
+}
+
+// Start flushes buffered messages every flush interval until ctx is done.
+// Call Flush once more on shutdown to write what is left.
+func (c *CachedSessionStorage) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.Flush(ctx); err != nil {
+					logger.Error("Failed to flush session messages", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (c *CachedSessionStorage) SaveMessage(ctx context.Context, chatID string, role string, content string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	msg := Message{
+		Role:      role,
+		Content:   content,
+		Timestamp: time.Now().Unix(),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[chatID] = append(c.pending[chatID], msg)
+	if element, ok := c.sessions[chatID]; ok {
+		session := element.Value.(*cachedSession)
+		session.messages = append(session.messages, msg)
+		c.order.MoveToFront(element)
+	}
+	return nil
+}
+
+func (c *CachedSessionStorage) GetMessages(ctx context.Context, chatID string, limit int) ([]Message, error) {
+	c.mu.Lock()
+	if element, ok := c.sessions[chatID]; ok {
+		c.order.MoveToFront(element)
+		messages := tail(element.Value.(*cachedSession).messages, limit)
+		c.mu.Unlock()
+		return messages, nil
+	}
+	c.mu.Unlock()
+
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	stored, err := c.inner.GetMessages(ctx, chatID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another caller may have loaded it while this one was reading.
+	if element, ok := c.sessions[chatID]; ok {
+		c.order.MoveToFront(element)
+		return tail(element.Value.(*cachedSession).messages, limit), nil
+	}
+
+	messages := append(stored, c.pending[chatID]...)
+	c.sessions[chatID] = c.order.PushFront(&cachedSession{chatID: chatID, messages: messages})
+	for len(c.sessions) > c.maxSessions {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.sessions, oldest.Value.(*cachedSession).chatID)
+	}
+
+	return tail(messages, limit), nil
+}
+
+func (c *CachedSessionStorage) ClearSession(ctx context.Context, chatID string) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	delete(c.pending, chatID)
+	if element, ok := c.sessions[chatID]; ok {
+		c.order.Remove(element)
+		delete(c.sessions, chatID)
+	}
+	c.mu.Unlock()
+
+	return c.inner.ClearSession(ctx, chatID)
+}
+
+func (c *CachedSessionStorage) ListSessions(ctx context.Context) ([]string, error) {
+	sessions, err := c.inner.ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]bool, len(sessions))
+	for _, chatID := range sessions {
+		listed[chatID] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for chatID := range c.pending {
+		if !listed[chatID] {
+			sessions = append(sessions, chatID)
+		}
+	}
+	return sessions, nil
+}
+
+// Flush writes buffered messages to the underlying storage. Messages that
+// fail to be written stay buffered and are retried on the next flush.
+func (c *CachedSessionStorage) Flush(ctx context.Context) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[string][]Message)
+	c.mu.Unlock()
+
+	var errs []error
+	for chatID, messages := range pending {
+		written, err := c.write(ctx, chatID, messages)
+		if err == nil {
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("failed to flush session %s: %w", chatID, err))
+		c.mu.Lock()
+		c.pending[chatID] = append(messages[written:], c.pending[chatID]...)
+		c.mu.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+// write appends messages to the underlying storage and returns how many of
+// them were written.
+func (c *CachedSessionStorage) write(ctx context.Context, chatID string, messages []Message) (int, error) {
+	if appender, ok := c.inner.(messageAppender); ok {
+		if err := appender.AppendMessages(ctx, chatID, messages); err != nil {
+			return 0, err
+		}
+		return len(messages), nil
+	}
+
+	for i, msg := range messages {
+		if err := c.inner.SaveMessage(ctx, chatID, msg.Role, msg.Content); err != nil {
+			return i, err
+		}
+	}
+	return len(messages), nil
+}
+
+// tail returns a copy of the last limit messages, or all of them if limit is
+// zero, so callers cannot change the cached ones.
+func tail(messages []Message, limit int) []Message {
+	if limit > 0 && len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return append(make([]Message, 0, len(messages)), messages...)
+}
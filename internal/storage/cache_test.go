@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCachedSessionStorageWriteBehind(t *testing.T) {
+	ctx := context.Background()
+	inner := NewFileSystemSessionStorage(t.TempDir())
+	cache := NewCachedSessionStorage(inner, &SessionCacheConfig{MaxSessions: 1})
+
+	for i := 0; i < 3; i++ {
+		cache.SaveMessage(ctx, "chat", "user", fmt.Sprintf("message %d", i))
+	}
+
+	if stored, _ := inner.GetMessages(ctx, "chat", 0); len(stored) != 0 {
+		t.Errorf("Expected messages to be buffered until flushed, got %d on disk", len(stored))
+	}
+	if messages, _ := cache.GetMessages(ctx, "chat", 2); len(messages) != 2 || messages[1].Content != "message 2" {
+		t.Errorf("Expected buffered messages to be readable, got %+v", messages)
+	}
+	if sessions, _ := cache.ListSessions(ctx); len(sessions) != 1 || sessions[0] != "chat" {
+		t.Errorf("Expected a session with only buffered messages to be listed, got %v", sessions)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := cache.Flush(ctx); err != nil {
+			t.Fatalf("Failed to flush: %v", err)
+		}
+	}
+	cache.SaveMessage(ctx, "chat", "assistant", "message 3")
+
+	// Loading another session evicts this one, so it is read back from disk
+	// plus what is still buffered.
+	cache.GetMessages(ctx, "other", 0)
+	cache.Flush(ctx)
+
+	for _, storage := range []SessionStorage{cache, inner} {
+		messages, err := storage.GetMessages(ctx, "chat", 0)
+		if err != nil {
+			t.Fatalf("Failed to load messages: %v", err)
+		}
+		if len(messages) != 4 {
+			t.Fatalf("Expected 4 messages without duplicates, got %d", len(messages))
+		}
+		for i, msg := range messages {
+			if msg.Content != fmt.Sprintf("message %d", i) {
+				t.Errorf("Expected messages in order, got %q at %d", msg.Content, i)
+			}
+		}
+	}
+
+	cache.SaveMessage(ctx, "chat", "user", "unsaved")
+	if err := cache.ClearSession(ctx, "chat"); err != nil {
+		t.Fatalf("Failed to clear session: %v", err)
+	}
+	cache.Flush(ctx)
+	if messages, _ := cache.GetMessages(ctx, "chat", 0); len(messages) != 0 {
+		t.Errorf("Expected a cleared session to stay empty, got %+v", messages)
+	}
+}
+
+type failingSessionStorage struct {
+	SessionStorage
+	failures int
+}
+
+func (s *failingSessionStorage) SaveMessage(ctx context.Context, chatID string, role string, content string) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("disk full")
+	}
+	return s.SessionStorage.SaveMessage(ctx, chatID, role, content)
+}
+
+func TestCachedSessionStorageRetriesFailedWrites(t *testing.T) {
+	ctx := context.Background()
+	inner := NewFileSystemSessionStorage(t.TempDir())
+	failing := &failingSessionStorage{SessionStorage: inner}
+	cache := NewCachedSessionStorage(failing, nil)
+
+	cache.SaveMessage(ctx, "chat", "user", "first")
+	cache.SaveMessage(ctx, "chat", "assistant", "second")
+	cache.Flush(ctx)
+
+	failing.failures = 1
+	cache.SaveMessage(ctx, "chat", "user", "third")
+	cache.SaveMessage(ctx, "chat", "assistant", "fourth")
+	if err := cache.Flush(ctx); err == nil {
+		t.Fatal("Expected the failed write to be reported")
+	}
+	cache.SaveMessage(ctx, "chat", "user", "fifth")
+	if err := cache.Flush(ctx); err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+
+	messages, _ := inner.GetMessages(ctx, "chat", 0)
+	var contents []string
+	for _, msg := range messages {
+		contents = append(contents, msg.Content)
+	}
+	if fmt.Sprint(contents) != "[first second third fourth fifth]" {
+		t.Errorf("Expected every message once and in order, got %v", contents)
+	}
+}
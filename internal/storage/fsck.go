@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CheckProblem is something wrong with a file found by Check.
+type CheckProblem struct {
+	Path     string
+	Problem  string
+	Repaired bool
+}
+
+type CheckReport struct {
+	Files    int
+	Problems []CheckProblem
+}
+
+// Unrepaired returns the problems that are still there.
+func (r *CheckReport) Unrepaired() []CheckProblem {
+	var problems []CheckProblem
+	for _, problem := range r.Problems {
+		if !problem.Repaired {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// Check verifies the files under dir: JSON files must parse, every line of a
+// JSON Lines file must be a JSON value, and no temporary files may be left
+// over from interrupted writes. With repair set, invalid lines are removed
+// from JSON Lines files and leftover temporary files are deleted. Invalid
+// JSON files cannot be repaired and are only reported.
+func Check(ctx context.Context, dir string, repair bool) (*CheckReport, error) {
+	report := &CheckReport{}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		report.Files++
+		name := info.Name()
+
+		switch {
+		case isTempFile(name):
+			problem := CheckProblem{Path: path, Problem: "temporary file left by an interrupted write"}
+			if repair {
+				if err := os.Remove(path); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", path, err)
+				}
+				problem.Repaired = true
+			}
+			report.Problems = append(report.Problems, problem)
+
+		case strings.HasSuffix(name, ".jsonl"):
+			problem, err := checkJSONLines(path, info.Mode().Perm(), repair)
+			if err != nil {
+				return err
+			}
+			if problem != nil {
+				report.Problems = append(report.Problems, *problem)
+			}
+
+		case strings.HasSuffix(name, ".json"):
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			if !json.Valid(data) {
+				report.Problems = append(report.Problems, CheckProblem{Path: path, Problem: "not valid JSON"})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s: %w", dir, err)
+	}
+
+	return report, nil
+}
+
+// tempFileName matches the names os.CreateTemp gives tempFilePattern: the
+// target's name between a dot and ".tmp-" followed by random digits.
+var tempFileName = regexp.MustCompile(`^\..+\.tmp-[0-9]+$`)
+
+func isTempFile(name string) bool {
+	return tempFileName.MatchString(name)
+}
+
+func checkJSONLines(path string, perm os.FileMode, repair bool) (*CheckProblem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var valid bytes.Buffer
+	invalid := 0
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			invalid++
+			continue
+		}
+		valid.Write(line)
+		valid.WriteByte('\n')
+	}
+
+	if invalid == 0 {
+		return nil, nil
+	}
+
+	problem := &CheckProblem{Path: path, Problem: fmt.Sprintf("%d invalid line(s)", invalid)}
+	if repair {
+		if err := WriteFileAtomic(path, valid.Bytes(), perm); err != nil {
+			return nil, fmt.Errorf("failed to repair %s: %w", path, err)
+		}
+		problem.Repaired = true
+	}
+	return problem, nil
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ObjectSessionStorage keeps sessions as files in a Storage, such as an
+// S3Storage, using the same layout as FileSystemSessionStorage under dir.
+// Object stores cannot append, so each write reads the session back and
+// replaces it.
+type ObjectSessionStorage struct {
+	store Storage
+	dir   string
+	mu    sync.RWMutex
+}
+
+func NewObjectSessionStorage(store Storage, dir string) *ObjectSessionStorage {
+	return &ObjectSessionStorage{
+		store: store,
+		dir:   dir,
+	}
+}
+
+func (s *ObjectSessionStorage) sessionFile(chatID string) string {
+	return path.Join(s.dir, "sessions", chatID, "messages.jsonl")
+}
+
+func (s *ObjectSessionStorage) SaveMessage(ctx context.Context, chatID string, role string, content string) error {
+	return s.AppendMessages(ctx, chatID, []Message{{
+		Role:      role,
+		Content:   content,
+		Timestamp: time.Now().Unix(),
+	}})
+}
+
+// AppendMessages writes several messages to a session in one go, keeping
+// their timestamps.
+func (s *ObjectSessionStorage) AppendMessages(ctx context.Context, chatID string, messages []Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessionFile := s.sessionFile(chatID)
+
+	data, err := s.store.ReadFile(ctx, sessionFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read session file: %w", err)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+
+	for _, msg := range messages {
+		msgData, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
+		data = append(data, msgData...)
+		data = append(data, '\n')
+	}
+
+	if err := s.store.WriteFile(ctx, sessionFile, data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+func (s *ObjectSessionStorage) GetMessages(ctx context.Context, chatID string, limit int) ([]Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, err := s.store.ReadFile(ctx, s.sessionFile(chatID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Message{}, nil
+		}
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	messages := make([]Message, 0, len(lines))
+	for _, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	if limit > 0 && len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+
+	return messages, nil
+}
+
+func (s *ObjectSessionStorage) ClearSession(ctx context.Context, chatID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.store.DeleteFile(ctx, s.sessionFile(chatID))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear session: %w", err)
+	}
+	return nil
+}
+
+func (s *ObjectSessionStorage) ListSessions(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessionsDir := path.Join(s.dir, "sessions")
+	files, err := s.store.ListFiles(ctx, sessionsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := []string{}
+	seen := make(map[string]bool)
+	for _, file := range files {
+		rel := strings.TrimPrefix(filepath.ToSlash(file), sessionsDir+"/")
+		chatID, _, ok := strings.Cut(rel, "/")
+		if !ok || seen[chatID] {
+			continue
+		}
+		seen[chatID] = true
+		sessions = append(sessions, chatID)
+	}
+
+	return sessions, nil
+}
+
+// ObjectMemoryStorage keeps memory, daily notes and config as files in a
+// Storage, using the same layout as FileSystemMemoryStorage under dir.
+type ObjectMemoryStorage struct {
+	store Storage
+	dir   string
+	mu    sync.Mutex
+}
+
+func NewObjectMemoryStorage(store Storage, dir string) *ObjectMemoryStorage {
+	return &ObjectMemoryStorage{
+		store: store,
+		dir:   dir,
+	}
+}
+
+func (m *ObjectMemoryStorage) read(ctx context.Context, name string) (string, error) {
+	data, err := m.store.ReadFile(ctx, path.Join(m.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (m *ObjectMemoryStorage) GetMemory(ctx context.Context) (string, error) {
+	content, err := m.read(ctx, "memory/MEMORY.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to read memory file: %w", err)
+	}
+	return content, nil
+}
+
+func (m *ObjectMemoryStorage) SetMemory(ctx context.Context, content string) error {
+	return m.store.WriteFile(ctx, path.Join(m.dir, "memory", "MEMORY.md"), []byte(content))
+}
+
+func (m *ObjectMemoryStorage) GetDailyNote(ctx context.Context, date string) (string, error) {
+	content, err := m.read(ctx, "memory/"+date+".md")
+	if err != nil {
+		return "", fmt.Errorf("failed to read daily note: %w", err)
+	}
+	return content, nil
+}
+
+func (m *ObjectMemoryStorage) SetDailyNote(ctx context.Context, date string, content string) error {
+	return m.store.WriteFile(ctx, path.Join(m.dir, "memory", date+".md"), []byte(content))
+}
+
+func (m *ObjectMemoryStorage) GetConfig(ctx context.Context, key string) (string, error) {
+	config, err := m.config(ctx)
+	if err != nil {
+		return "", err
+	}
+	return config[key], nil
+}
+
+func (m *ObjectMemoryStorage) SetConfig(ctx context.Context, key string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	config, err := m.config(ctx)
+	if err != nil {
+		return err
+	}
+	config[key] = value
+
+	configData, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	return m.store.WriteFile(ctx, path.Join(m.dir, "config", "config.json"), configData)
+}
+
+func (m *ObjectMemoryStorage) config(ctx context.Context) (map[string]string, error) {
+	data, err := m.read(ctx, "config/config.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	config := make(map[string]string)
+	if data == "" {
+		return config, nil
+	}
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return config, nil
+}
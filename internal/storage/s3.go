@@ -0,0 +1,366 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service        = "s3"
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3TimeFormat     = "20060102T150405Z"
+	s3DateFormat     = "20060102"
+	s3RequestTimeout = 30 * time.Second
+)
+
+// S3Config configures S3Storage. Endpoint defaults to AWS for Region; set it
+// for other S3-compatible services such as MinIO or Cloudflare R2, which
+// usually also need PathStyle.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	PathStyle       bool
+}
+
+// S3Storage stores files as objects in an S3-compatible bucket, under an
+// optional key prefix. Requests are signed with AWS Signature Version 4.
+type S3Storage struct {
+	config     S3Config
+	endpoint   *url.URL
+	prefix     string
+	httpClient *http.Client
+}
+
+func NewS3Storage(config *S3Config) (*S3Storage, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required for S3 storage")
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("region is required for S3 storage")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("access key ID and secret access key are required for S3 storage")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+	endpointURL, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || endpointURL.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+
+	return &S3Storage{
+		config:     *config,
+		endpoint:   endpointURL,
+		prefix:     strings.Trim(config.Prefix, "/"),
+		httpClient: &http.Client{Timeout: s3RequestTimeout},
+	}, nil
+}
+
+func (s *S3Storage) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.key(name), nil, nil)
+	if err != nil {
+		return nil, s.pathError("read", name, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", name, err)
+	}
+	return data, nil
+}
+
+func (s *S3Storage) WriteFile(ctx context.Context, name string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.key(name), nil, data)
+	if err != nil {
+		return s.pathError("write", name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) DeleteFile(ctx context.Context, name string) error {
+	// S3 deletes succeed for keys that do not exist, unlike os.Remove.
+	exists, err := s.FileExists(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, s.key(name), nil, nil)
+	if err != nil {
+		return s.pathError("remove", name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListFiles returns the files under prefix, relative to the storage root, or
+// prefix itself if it is a file.
+func (s *S3Storage) ListFiles(ctx context.Context, prefix string) ([]string, error) {
+	dir := s.key(prefix)
+
+	files := []string{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if dir != "" {
+			query.Set("prefix", dir)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object list: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			// A prefix of "memory" must not match "memory-old/...".
+			if dir != "" && object.Key != dir && !strings.HasPrefix(object.Key, dir+"/") {
+				continue
+			}
+			files = append(files, s.name(object.Key))
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return files, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3Storage) FileExists(ctx context.Context, name string) (bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, s.key(name), nil, nil)
+	if err != nil {
+		if isS3NotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// key maps a storage path to an object key, keeping it inside the prefix.
+func (s *S3Storage) key(name string) string {
+	key := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if s.prefix == "" {
+		return key
+	}
+	if key == "" {
+		return s.prefix
+	}
+	return s.prefix + "/" + key
+}
+
+func (s *S3Storage) name(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(key, s.prefix), "/")
+}
+
+// s3Error is an error response from S3.
+type s3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("S3 request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("S3 request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func isS3NotFound(err error) bool {
+	s3Err, ok := err.(*s3Error)
+	return ok && s3Err.StatusCode == http.StatusNotFound
+}
+
+// pathError reports missing objects the way os does, so callers checking
+// os.IsNotExist work the same with either storage.
+func (s *S3Storage) pathError(op, name string, err error) error {
+	if isS3NotFound(err) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return fmt.Errorf("failed to %s %s: %w", op, name, err)
+}
+
+func (s *S3Storage) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	if s.config.PathStyle {
+		u.Path += "/" + s.config.Bucket
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+	}
+	u.Path += "/" + key
+	u.RawQuery = encodeS3Query(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body == nil {
+		req.Body = http.NoBody
+		req.ContentLength = 0
+	}
+
+	payload := sha256.Sum256(body)
+	signS3Request(req, hex.EncodeToString(payload[:]), &s.config, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		s3Err := &s3Error{StatusCode: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		xml.Unmarshal(data, s3Err)
+		return nil, s3Err
+	}
+	return resp, nil
+}
+
+// signS3Request adds AWS Signature Version 4 headers to req, signing the host
+// and every header already set on it.
+func signS3Request(req *http.Request, payloadHash string, config *S3Config, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(s3TimeFormat)
+	date := now.Format(s3DateFormat)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if config.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", config.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		encodeS3Path(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, config.Region, s3Service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretAccessKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// encodeS3Path percent-encodes each segment of an object path the way
+// Signature Version 4 expects, leaving the slashes between them.
+func encodeS3Path(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = encodeS3(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// encodeS3Query encodes query parameters sorted by name, as both the request
+// and its signature need them.
+func encodeS3Query(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, encodeS3(name)+"="+encodeS3(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func encodeS3(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Storage interface {
@@ -18,6 +19,39 @@ type Storage interface {
 	FileExists(ctx context.Context, path string) (bool, error)
 }
 
+// Appender is implemented by storages that can add to the end of a file
+// without rewriting it.
+type Appender interface {
+	AppendFile(ctx context.Context, path string, data []byte) error
+}
+
+// AppendFile adds data to the end of a file, creating it if needed. Storages
+// that are not an Appender have the file read and written back whole.
+func AppendFile(ctx context.Context, s Storage, path string, data []byte) error {
+	if appender, ok := s.(Appender); ok {
+		return appender.AppendFile(ctx, path, data)
+	}
+
+	exists, err := s.FileExists(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	var existing []byte
+	if exists {
+		if existing, err = s.ReadFile(ctx, path); err != nil {
+			return err
+		}
+	}
+	return s.WriteFile(ctx, path, append(existing, data...))
+}
+
+// Renamer is implemented by storages that can move a file or directory in
+// one step.
+type Renamer interface {
+	Rename(ctx context.Context, from, to string) error
+}
+
 type SessionStorage interface {
 	SaveMessage(ctx context.Context, chatID string, role string, content string) error
 	GetMessages(ctx context.Context, chatID string, limit int) ([]Message, error)
@@ -76,7 +110,7 @@ func (fs *FileStorage) WriteFile(ctx context.Context, path string, data []byte)
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	return os.WriteFile(fullPath, data, 0644)
+	return WriteFileAtomic(fullPath, data, 0644)
 }
 
 func (fs *FileStorage) DeleteFile(ctx context.Context, path string) error {
@@ -145,6 +179,48 @@ func (fs *FileStorage) FileExists(ctx context.Context, path string) (bool, error
 	return false, err
 }
 
+func (fs *FileStorage) AppendFile(ctx context.Context, path string, data []byte) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	fullPath := filepath.Join(fs.basePath, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	file, err := os.OpenFile(fullPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Rename moves a file or a whole directory. It fails if to is an existing
+// directory.
+func (fs *FileStorage) Rename(ctx context.Context, from, to string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	fullTo := filepath.Join(fs.basePath, to)
+	if err := os.MkdirAll(filepath.Dir(fullTo), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.Rename(filepath.Join(fs.basePath, from), fullTo)
+}
+
 type FileSystemSessionStorage struct {
 	basePath string
 	mu       sync.RWMutex
@@ -157,6 +233,16 @@ func NewFileSystemSessionStorage(basePath string) *FileSystemSessionStorage {
 }
 
 func (s *FileSystemSessionStorage) SaveMessage(ctx context.Context, chatID string, role string, content string) error {
+	return s.AppendMessages(ctx, chatID, []Message{{
+		Role:      role,
+		Content:   content,
+		Timestamp: time.Now().Unix(),
+	}})
+}
+
+// AppendMessages writes several messages to a session in one go, keeping
+// their timestamps.
+func (s *FileSystemSessionStorage) AppendMessages(ctx context.Context, chatID string, messages []Message) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -173,26 +259,21 @@ func (s *FileSystemSessionStorage) SaveMessage(ctx context.Context, chatID strin
 
 	sessionFile := filepath.Join(sessionDir, "messages.jsonl")
 
-	msg := Message{
-		Role:      role,
-		Content:   content,
-		Timestamp: 0,
+	var data []byte
+	for _, msg := range messages {
+		msgData, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
+		data = append(data, msgData...)
+		data = append(data, '\n')
 	}
 
-	msgData, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+	dropped, err := AppendLines(sessionFile, data, 0644)
+	if dropped > 0 {
+		logger.Warn("Dropped partial message left by an interrupted write", "chat_id", chatID, "bytes", dropped)
 	}
-
-	msgData = append(msgData, '\n')
-
-	file, err := os.OpenFile(sessionFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to open session file: %w", err)
-	}
-	defer file.Close()
-
-	if _, err := file.Write(msgData); err != nil {
 		return fmt.Errorf("failed to write message: %w", err)
 	}
 
@@ -297,6 +378,11 @@ func NewFileSystemMemoryStorage(basePath string) *FileSystemMemoryStorage {
 	}
 }
 
+// MemoryFile is the path of the MEMORY.md file.
+func (m *FileSystemMemoryStorage) MemoryFile() string {
+	return filepath.Join(m.basePath, "memory", "MEMORY.md")
+}
+
 func (m *FileSystemMemoryStorage) GetMemory(ctx context.Context) (string, error) {
 	select {
 	case <-ctx.Done():
@@ -337,7 +423,7 @@ func (m *FileSystemMemoryStorage) SetMemory(ctx context.Context, content string)
 
 	memoryFile := filepath.Join(memoryDir, "MEMORY.md")
 
-	return os.WriteFile(memoryFile, []byte(content), 0644)
+	return WriteFileAtomic(memoryFile, []byte(content), 0644)
 }
 
 func (m *FileSystemMemoryStorage) GetDailyNote(ctx context.Context, date string) (string, error) {
@@ -380,7 +466,7 @@ func (m *FileSystemMemoryStorage) SetDailyNote(ctx context.Context, date string,
 
 	noteFile := filepath.Join(memoryDir, date+".md")
 
-	return os.WriteFile(noteFile, []byte(content), 0644)
+	return WriteFileAtomic(noteFile, []byte(content), 0644)
 }
 
 func (m *FileSystemMemoryStorage) GetConfig(ctx context.Context, key string) (string, error) {
@@ -453,5 +539,5 @@ func (m *FileSystemMemoryStorage) SetConfig(ctx context.Context, key string, val
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	return os.WriteFile(configFile, configData, 0644)
+	return WriteFileAtomic(configFile, configData, 0644)
 }
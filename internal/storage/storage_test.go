@@ -360,3 +360,25 @@ func TestDirectoryStructure(t *testing.T) {
 		t.Error("expected session file to exist")
 	}
 }
+
+func TestAppendFile(t *testing.T) {
+	ctx := context.Background()
+	fs := NewFileStorage(t.TempDir())
+
+	// Embedding only the interface hides AppendFile, as with object storage.
+	rewriting := struct{ Storage }{fs}
+
+	for _, store := range []Storage{fs, rewriting} {
+		fs.DeleteFile(ctx, "logs/app.log")
+		for _, line := range []string{"one\n", "two\n"} {
+			if err := AppendFile(ctx, store, "logs/app.log", []byte(line)); err != nil {
+				t.Fatalf("AppendFile failed: %v", err)
+			}
+		}
+
+		data, err := fs.ReadFile(ctx, "logs/app.log")
+		if err != nil || string(data) != "one\ntwo\n" {
+			t.Errorf("Expected both lines with %T, got %q, %v", store, data, err)
+		}
+	}
+}
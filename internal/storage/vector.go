@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type VectorEntry struct {
+	ID        string    `json:"id"`
+	Content   string    `json:"content"`
+	Source    string    `json:"source,omitempty"`
+	Model     string    `json:"model"`
+	Vector    []float32 `json:"vector"`
+	UpdatedAt int64     `json:"updated_at"`
+}
+
+type VectorMatch struct {
+	Entry VectorEntry
+	Score float64
+}
+
+type VectorStore interface {
+	Upsert(ctx context.Context, entries ...VectorEntry) error
+	Get(ctx context.Context, id string) (*VectorEntry, error)
+	Delete(ctx context.Context, ids ...string) error
+	List(ctx context.Context, source string) ([]VectorEntry, error)
+	// Search returns the entries closest to vector. Only entries embedded
+	// with model are compared, or all of them when model is empty.
+	Search(ctx context.Context, vector []float32, model string, limit int) ([]VectorMatch, error)
+}
+
+type FileSystemVectorStore struct {
+	path    string
+	mu      sync.RWMutex
+	entries map[string]VectorEntry
+	loaded  bool
+}
+
+func NewFileSystemVectorStore(basePath string) *FileSystemVectorStore {
+	return &FileSystemVectorStore{
+		path:    filepath.Join(basePath, "memory", "vectors.json"),
+		entries: make(map[string]VectorEntry),
+	}
+}
+
+func (s *FileSystemVectorStore) Upsert(ctx context.Context, entries ...VectorEntry) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, entry := range entries {
+		if entry.ID == "" {
+			return fmt.Errorf("vector entry ID cannot be empty")
+		}
+		if len(entry.Vector) == 0 {
+			return fmt.Errorf("vector entry %s has no vector", entry.ID)
+		}
+		if entry.UpdatedAt == 0 {
+			entry.UpdatedAt = now
+		}
+		s.entries[entry.ID] = entry
+	}
+
+	return s.save()
+}
+
+func (s *FileSystemVectorStore) Get(ctx context.Context, id string) (*VectorEntry, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+func (s *FileSystemVectorStore) Delete(ctx context.Context, ids ...string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		delete(s.entries, id)
+	}
+
+	return s.save()
+}
+
+func (s *FileSystemVectorStore) List(ctx context.Context, source string) ([]VectorEntry, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]VectorEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if source == "" || entry.Source == source {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+
+	return entries, nil
+}
+
+func (s *FileSystemVectorStore) Search(ctx context.Context, vector []float32, model string, limit int) ([]VectorMatch, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	matches := make([]VectorMatch, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if model != "" && entry.Model != model {
+			continue
+		}
+		if len(entry.Vector) != len(vector) {
+			continue
+		}
+		matches = append(matches, VectorMatch{
+			Entry: entry,
+			Score: CosineSimilarity(vector, entry.Vector),
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches, nil
+}
+
+func (s *FileSystemVectorStore) load() error {
+	if s.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read vector store: %w", err)
+	}
+
+	if len(data) > 0 {
+		var entries []VectorEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("failed to parse vector store: %w", err)
+		}
+		for _, entry := range entries {
+			s.entries[entry.ID] = entry
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+func (s *FileSystemVectorStore) save() error {
+	entries := make([]VectorEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vector store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+
+	if err := WriteFileAtomic(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write vector store: %w", err)
+	}
+
+	return nil
+}
+
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
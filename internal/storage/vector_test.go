@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	if got := CosineSimilarity([]float32{1, 0}, []float32{1, 0}); math.Abs(got-1) > 1e-9 {
+		t.Errorf("Expected 1 for identical vectors, got %f", got)
+	}
+
+	if got := CosineSimilarity([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("Expected 0 for orthogonal vectors, got %f", got)
+	}
+
+	if got := CosineSimilarity([]float32{1}, []float32{1, 0}); got != 0 {
+		t.Errorf("Expected 0 for mismatched dimensions, got %f", got)
+	}
+}
+
+func TestFileSystemVectorStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewFileSystemVectorStore(dir)
+
+	err := store.Upsert(ctx,
+		VectorEntry{ID: "tea", Content: "Likes green tea", Source: "agent", Vector: []float32{1, 0, 0}},
+		VectorEntry{ID: "cat", Content: "Has a cat named Miso", Source: "MEMORY.md", Vector: []float32{0, 1, 0}},
+	)
+	if err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	if err := store.Upsert(ctx, VectorEntry{ID: "empty"}); err == nil {
+		t.Error("Expected error for entry without vector")
+	}
+
+	reopened := NewFileSystemVectorStore(dir)
+	matches, err := reopened.Search(ctx, []float32{0.9, 0.1, 0}, "", 1)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+
+	if len(matches) != 1 || matches[0].Entry.ID != "tea" {
+		t.Fatalf("Expected tea to be the closest match, got %+v", matches)
+	}
+
+	entries, _ := reopened.List(ctx, "MEMORY.md")
+	if len(entries) != 1 || entries[0].ID != "cat" {
+		t.Errorf("Expected one MEMORY.md entry, got %+v", entries)
+	}
+
+	if err := reopened.Delete(ctx, "cat"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+
+	entry, err := reopened.Get(ctx, "cat")
+	if err != nil || entry != nil {
+		t.Errorf("Expected deleted entry to be gone, got %+v, %v", entry, err)
+	}
+}
+
+func TestVectorStoreSearchByModel(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileSystemVectorStore(t.TempDir())
+	err := store.Upsert(ctx,
+		VectorEntry{ID: "old", Content: "Embedded by the old model", Model: "small", Vector: []float32{1, 0}},
+		VectorEntry{ID: "new", Content: "Embedded by the new model", Model: "large", Vector: []float32{0, 1}},
+	)
+	if err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	matches, err := store.Search(ctx, []float32{1, 0}, "large", 0)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Entry.ID != "new" {
+		t.Errorf("Expected only entries of the large model, got %+v", matches)
+	}
+}
@@ -0,0 +1,116 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Template struct {
+	Name         string   `yaml:"name" json:"name"`
+	Description  string   `yaml:"description" json:"description"`
+	SystemPrompt string   `yaml:"system_prompt" json:"system_prompt"`
+	Skills       []string `yaml:"skills" json:"skills"`
+	FirstMessage string   `yaml:"first_message" json:"first_message"`
+}
+
+func (t *Template) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("template name cannot be empty")
+	}
+
+	if strings.ContainsAny(t.Name, " \t\n") {
+		return fmt.Errorf("template name cannot contain whitespace: %s", t.Name)
+	}
+
+	return nil
+}
+
+type templateFile struct {
+	Templates []*Template `yaml:"templates"`
+}
+
+type Registry struct {
+	templates map[string]*Template
+	mu        sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		templates: make(map[string]*Template),
+	}
+}
+
+func (r *Registry) Register(template *Template) error {
+	if template == nil {
+		return fmt.Errorf("template cannot be nil")
+	}
+
+	if err := template.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.templates[strings.ToLower(template.Name)] = template
+	return nil
+}
+
+func (r *Registry) Get(name string) (*Template, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	template, ok := r.templates[strings.ToLower(name)]
+	return template, ok
+}
+
+func (r *Registry) List() []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	templates := make([]*Template, 0, len(r.templates))
+	for _, template := range r.templates {
+		templates = append(templates, template)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	return templates
+}
+
+func (r *Registry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.templates)
+}
+
+func (r *Registry) LoadFromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read templates file: %w", err)
+	}
+
+	return r.Load(data)
+}
+
+func (r *Registry) Load(data []byte) error {
+	var file templateFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	for _, template := range file.Templates {
+		if err := r.Register(template); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testTemplates = `templates:
+  - name: weekly-review
+    description: Review the past week
+    system_prompt: Walk through the user's week and summarize open items.
+    skills:
+      - planning
+    first_message: Let's review my week.
+  - name: code-review
+    description: Code review session
+`
+
+func TestRegistryLoad(t *testing.T) {
+	registry := NewRegistry()
+
+	if err := registry.Load([]byte(testTemplates)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if registry.Count() != 2 {
+		t.Fatalf("Expected 2 templates, got %d", registry.Count())
+	}
+
+	template, ok := registry.Get("Weekly-Review")
+	if !ok {
+		t.Fatal("Expected weekly-review template to be found")
+	}
+
+	if template.FirstMessage != "Let's review my week." {
+		t.Errorf("Unexpected first message: %s", template.FirstMessage)
+	}
+
+	if len(template.Skills) != 1 || template.Skills[0] != "planning" {
+		t.Errorf("Expected planning skill, got %v", template.Skills)
+	}
+
+	list := registry.List()
+	if list[0].Name != "code-review" || list[1].Name != "weekly-review" {
+		t.Errorf("Expected templates sorted by name, got %s, %s", list[0].Name, list[1].Name)
+	}
+}
+
+func TestRegistryLoadInvalidName(t *testing.T) {
+	registry := NewRegistry()
+
+	err := registry.Load([]byte("templates:\n  - name: weekly review\n"))
+	if err == nil {
+		t.Error("Expected error for template name with whitespace")
+	}
+}
+
+func TestRegistryLoadFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.yaml")
+	if err := os.WriteFile(path, []byte(testTemplates), 0644); err != nil {
+		t.Fatalf("Failed to write templates file: %v", err)
+	}
+
+	registry := NewRegistry()
+	if err := registry.LoadFromFile(path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, ok := registry.Get("code-review"); !ok {
+		t.Error("Expected code-review template to be found")
+	}
+}
+
+func TestRegistryLoadFromFileNonExistent(t *testing.T) {
+	registry := NewRegistry()
+
+	if err := registry.LoadFromFile("/nonexistent/templates.yaml"); err == nil {
+		t.Error("Expected error for nonexistent file")
+	}
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// CachedTool remembers the results of an idempotent tool for a while, so the
+// same call in the same conversation is answered without running the tool
+// again. Failed calls are not cached.
+type CachedTool struct {
+	tool Tool
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[cacheKey]cachedResult
+}
+
+type cacheKey struct {
+	chatID string
+	params string
+}
+
+type cachedResult struct {
+	result  string
+	expires time.Time
+}
+
+func NewCachedTool(tool Tool, ttl time.Duration) *CachedTool {
+	return &CachedTool{
+		tool:    tool,
+		ttl:     ttl,
+		entries: make(map[cacheKey]cachedResult),
+	}
+}
+
+func (t *CachedTool) Name() string {
+	return t.tool.Name()
+}
+
+func (t *CachedTool) Description() string {
+	return t.tool.Description()
+}
+
+func (t *CachedTool) Parameters() json.RawMessage {
+	return t.tool.Parameters()
+}
+
+func (t *CachedTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	// Marshaling sorts the keys, so params in another order hit the same entry.
+	normalized, err := json.Marshal(params)
+	if err != nil {
+		return t.tool.Execute(ctx, params)
+	}
+	chatID, _ := ChatIDFromContext(ctx)
+	key := cacheKey{chatID: chatID, params: string(normalized)}
+
+	now := time.Now()
+	t.mu.Lock()
+	entry, ok := t.entries[key]
+	t.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.result, nil
+	}
+
+	result, err := t.tool.Execute(ctx, params)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, entry := range t.entries {
+		if !now.Before(entry.expires) {
+			delete(t.entries, key)
+		}
+	}
+	t.entries[key] = cachedResult{result: result, expires: now.Add(t.ttl)}
+
+	return result, nil
+}
+
+// Invalidate drops the cached results of a conversation.
+func (t *CachedTool) Invalidate(chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key := range t.entries {
+		if key.chatID == chatID {
+			delete(t.entries, key)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package tools
+
+import "context"
+
+type contextKey string
+
+const (
+	chatIDKey      contextKey = "chat_id"
+	channelKey     contextKey = "channel"
+	scopedToolsKey contextKey = "scoped_tools"
+)
+
+func WithChatID(ctx context.Context, chatID string) context.Context {
+	return context.WithValue(ctx, chatIDKey, chatID)
+}
+
+func ChatIDFromContext(ctx context.Context) (string, bool) {
+	chatID, ok := ctx.Value(chatIDKey).(string)
+	return chatID, ok && chatID != ""
+}
+
+// WithChannel records the channel the request came from, so tools can send
+// results back to it later.
+func WithChannel(ctx context.Context, channel string) context.Context {
+	return context.WithValue(ctx, channelKey, channel)
+}
+
+func ChannelFromContext(ctx context.Context) (string, bool) {
+	channel, ok := ctx.Value(channelKey).(string)
+	return channel, ok && channel != ""
+}
+
+// WithTools makes extra tools available to calls executed with ctx, on top of
+// the registry. They are used for tools that only exist for one request.
+func WithTools(ctx context.Context, extra ...Tool) context.Context {
+	if len(extra) == 0 {
+		return ctx
+	}
+	scoped := append(append([]Tool{}, scopedTools(ctx)...), extra...)
+	return context.WithValue(ctx, scopedToolsKey, scoped)
+}
+
+func scopedTools(ctx context.Context) []Tool {
+	scoped, _ := ctx.Value(scopedToolsKey).([]Tool)
+	return scoped
+}
@@ -0,0 +1,357 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultExecTimeout   = 30 * time.Second
+	defaultExecMaxOutput = 64 * 1024
+)
+
+var defaultExecEnv = []string{"PATH", "LANG", "LC_ALL", "TZ"}
+
+type ExecConfig struct {
+	BasePath  string
+	Allow     []string
+	Deny      []string
+	Timeout   time.Duration
+	MaxOutput int
+	Env       []string
+}
+
+type ExecTool struct {
+	basePath  string
+	allow     map[string]bool
+	deny      [][]string
+	timeout   time.Duration
+	maxOutput int
+	env       []string
+}
+
+func NewExecTool(config *ExecConfig) (*ExecTool, error) {
+	if config == nil || config.BasePath == "" {
+		return nil, fmt.Errorf("base path is required")
+	}
+
+	basePath, err := filepath.Abs(config.BasePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve base path: %w", err)
+	}
+
+	tool := &ExecTool{
+		basePath:  basePath,
+		allow:     make(map[string]bool),
+		timeout:   config.Timeout,
+		maxOutput: config.MaxOutput,
+	}
+	if tool.timeout <= 0 {
+		tool.timeout = defaultExecTimeout
+	}
+	if tool.maxOutput <= 0 {
+		tool.maxOutput = defaultExecMaxOutput
+	}
+
+	for _, name := range config.Allow {
+		if strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("allowlist entry %q must be a command name, not a path", name)
+		}
+		tool.allow[name] = true
+	}
+	for _, rule := range config.Deny {
+		if fields := strings.Fields(rule); len(fields) > 0 {
+			tool.deny = append(tool.deny, fields)
+		}
+	}
+
+	for _, name := range append(defaultExecEnv, config.Env...) {
+		if value, ok := os.LookupEnv(name); ok {
+			tool.env = append(tool.env, name+"="+value)
+		}
+	}
+	tool.env = append(tool.env, "HOME="+basePath)
+
+	return tool, nil
+}
+
+func (t *ExecTool) Name() string {
+	return "exec_command"
+}
+
+func (t *ExecTool) Policy() ToolPolicy {
+	// Commands may change the files read_file has cached.
+	return ToolPolicy{AdminOnly: true, Invalidates: []string{"read_file"}}
+}
+
+func (t *ExecTool) Description() string {
+	allowed := make([]string, 0, len(t.allow))
+	for name := range t.allow {
+		allowed = append(allowed, name)
+	}
+	sort.Strings(allowed)
+
+	return fmt.Sprintf("Run a command inside the data directory and return its combined output. "+
+		"Commands are executed directly without a shell, so pipes, redirects and variables are not supported. "+
+		"Allowed commands: %s.", strings.Join(allowed, ", "))
+}
+
+func (t *ExecTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"command": {
+				"type": "string",
+				"description": "The command line to run, e.g. 'git log --oneline -5'"
+			},
+			"dir": {
+				"type": "string",
+				"description": "Working directory relative to the data directory (default: the data directory)"
+			}
+		},
+		"required": ["command"],
+		"additionalProperties": false
+	}`)
+}
+
+func (t *ExecTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	command, ok := params["command"].(string)
+	if !ok || strings.TrimSpace(command) == "" {
+		return "", &ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "command parameter must be a non-empty string",
+		}
+	}
+
+	args, err := splitCommand(command)
+	if err != nil {
+		return "", &ToolError{
+			Code:    "INVALID_COMMAND",
+			Message: err.Error(),
+		}
+	}
+
+	workDir := t.basePath
+	if dir, _ := params["dir"].(string); dir != "" {
+		workDir = filepath.Join(t.basePath, dir)
+	}
+	return t.Run(ctx, args, workDir)
+}
+
+// Run runs a command that is already split into arguments, applying the same
+// allowlist, path checks and limits as commands passed to Execute. A relative
+// dir is taken to be inside the data directory.
+func (t *ExecTool) Run(ctx context.Context, args []string, dir string) (string, error) {
+	if len(args) == 0 || args[0] == "" {
+		return "", &ToolError{
+			Code:    "INVALID_COMMAND",
+			Message: "command is empty",
+		}
+	}
+
+	if err := t.checkCommand(args); err != nil {
+		return "", &ToolError{
+			Code:    "COMMAND_NOT_ALLOWED",
+			Message: err.Error(),
+		}
+	}
+
+	workDir := dir
+	if !filepath.IsAbs(workDir) {
+		workDir = filepath.Join(t.basePath, workDir)
+	}
+	if err := validatePath(t.basePath, workDir); err != nil {
+		return "", &ToolError{
+			Code:    "INVALID_PATH",
+			Message: fmt.Sprintf("invalid working directory: %v", err),
+		}
+	}
+
+	for _, arg := range args[1:] {
+		if err := t.checkArgument(workDir, arg); err != nil {
+			return "", &ToolError{
+				Code:    "INVALID_PATH",
+				Message: err.Error(),
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	output := &limitedBuffer{limit: t.maxOutput}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = t.env
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", &ToolError{
+			Code:    "TIMEOUT",
+			Message: fmt.Sprintf("command timed out after %s", t.timeout),
+		}
+	}
+
+	result := output.String()
+	if output.truncated {
+		result += fmt.Sprintf("\n[output truncated to %d bytes]", t.maxOutput)
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result += fmt.Sprintf("\n[exit code %d]", exitErr.ExitCode())
+	default:
+		return "", &ToolError{
+			Code:    "EXECUTION_FAILED",
+			Message: "failed to run command",
+			Err:     err,
+		}
+	}
+
+	if strings.TrimSpace(result) == "" {
+		return "(no output)", nil
+	}
+	return result, nil
+}
+
+// checkCommand enforces the allowlist and deny rules. Deny rules only match
+// the leading words of a command, so a program with subcommand rules must be
+// given its subcommand first: options before it, such as git's -C or -c,
+// could hide the subcommand or define an alias that runs a shell. The
+// allowlist is the real boundary; deny rules only narrow what an allowed
+// program may do.
+func (t *ExecTool) checkCommand(args []string) error {
+	for _, rule := range t.deny {
+		if len(args) >= len(rule) && equalStrings(args[:len(rule)], rule) {
+			return fmt.Errorf("command %q is denied", strings.Join(rule, " "))
+		}
+		if len(rule) > 1 && rule[0] == args[0] && len(args) > 1 && strings.HasPrefix(args[1], "-") {
+			return fmt.Errorf("options before the %s subcommand are not allowed", args[0])
+		}
+	}
+
+	if strings.ContainsAny(args[0], `/\`) {
+		return fmt.Errorf("command must be a program name, not a path")
+	}
+	if !t.allow[args[0]] {
+		return fmt.Errorf("command %q is not in the allowlist", args[0])
+	}
+	return nil
+}
+
+func (t *ExecTool) checkArgument(workDir, arg string) error {
+	value := arg
+	if strings.HasPrefix(arg, "-") {
+		if i := strings.Index(arg, "="); i >= 0 {
+			value = arg[i+1:]
+		} else {
+			return nil
+		}
+	}
+
+	if strings.HasPrefix(value, "~") {
+		return fmt.Errorf("argument %q refers to a home directory", arg)
+	}
+	if !filepath.IsAbs(value) && !strings.Contains(value, "..") {
+		return nil
+	}
+
+	target := value
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(workDir, target)
+	}
+	if err := validatePath(t.basePath, target); err != nil {
+		return fmt.Errorf("argument %q is outside the data directory", arg)
+	}
+	return nil
+}
+
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case strings.ContainsRune("|&;<>`$", r):
+			return nil, fmt.Errorf("shell operator %q is not supported, commands run without a shell", r)
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("command is empty")
+	}
+	return args, nil
+}
+
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining < len(p) {
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestExecTool(t *testing.T, config *ExecConfig) (*ExecTool, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	config.BasePath = dir
+	if config.Allow == nil {
+		config.Allow = []string{"echo", "ls", "pwd", "printenv", "sleep", "git"}
+	}
+
+	tool, err := NewExecTool(config)
+	if err != nil {
+		t.Fatalf("Failed to create exec tool: %v", err)
+	}
+	return tool, dir
+}
+
+func toolErrorCode(err error) string {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr.Code
+	}
+	return ""
+}
+
+func TestExecToolRunsAllowedCommands(t *testing.T) {
+	tool, dir := newTestExecTool(t, &ExecConfig{})
+	ctx := context.Background()
+
+	if err := os.MkdirAll(filepath.Join(dir, "notes"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "notes", "todo.md"), []byte("x"), 0644)
+
+	result, err := tool.Execute(ctx, map[string]interface{}{"command": `echo "hello   world"`})
+	if err != nil || result != "hello   world\n" {
+		t.Errorf("Expected quoted argument to be preserved, got %q, %v", result, err)
+	}
+
+	result, err = tool.Execute(ctx, map[string]interface{}{"command": "ls", "dir": "notes"})
+	if err != nil || !strings.Contains(result, "todo.md") {
+		t.Errorf("Expected listing of notes directory, got %q, %v", result, err)
+	}
+
+	result, err = tool.Execute(ctx, map[string]interface{}{"command": "ls missing.txt"})
+	if err != nil || !strings.Contains(result, "[exit code 2]") {
+		t.Errorf("Expected non-zero exit code in result, got %q, %v", result, err)
+	}
+}
+
+func TestExecToolRejectsCommands(t *testing.T) {
+	tool, _ := newTestExecTool(t, &ExecConfig{Deny: []string{"git push"}})
+	ctx := context.Background()
+
+	tests := []struct {
+		command string
+		dir     string
+		code    string
+	}{
+		{"rm -rf data", "", "COMMAND_NOT_ALLOWED"},
+		{"/bin/echo hi", "", "COMMAND_NOT_ALLOWED"},
+		{"git push origin main", "", "COMMAND_NOT_ALLOWED"},
+		{"git -C . push", "", "COMMAND_NOT_ALLOWED"},
+		{"git --no-pager push", "", "COMMAND_NOT_ALLOWED"},
+		{"git -c alias.x='!sh -c id' x", "", "COMMAND_NOT_ALLOWED"},
+		{"echo hi | sh", "", "INVALID_COMMAND"},
+		{"echo $HOME", "", "INVALID_COMMAND"},
+		{"echo 'unterminated", "", "INVALID_COMMAND"},
+		{"ls /etc", "", "INVALID_PATH"},
+		{"ls ../..", "", "INVALID_PATH"},
+		{"ls ~", "", "INVALID_PATH"},
+		{"ls --hide=/tmp/repo", "", "INVALID_PATH"},
+		{"ls", "../", "INVALID_PATH"},
+	}
+
+	for _, tt := range tests {
+		_, err := tool.Execute(ctx, map[string]interface{}{"command": tt.command, "dir": tt.dir})
+		if code := toolErrorCode(err); code != tt.code {
+			t.Errorf("%q in %q: expected %s, got %v", tt.command, tt.dir, tt.code, err)
+		}
+	}
+
+	for _, command := range []string{"git status", "git log --oneline"} {
+		if _, err := tool.Execute(ctx, map[string]interface{}{"command": command}); toolErrorCode(err) == "COMMAND_NOT_ALLOWED" {
+			t.Errorf("Expected deny rule to only match git push, got %v for %q", err, command)
+		}
+	}
+}
+
+func TestExecToolRun(t *testing.T) {
+	tool, dir := newTestExecTool(t, &ExecConfig{})
+	ctx := context.Background()
+
+	if err := os.MkdirAll(filepath.Join(dir, "skills", "weather"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	result, err := tool.Run(ctx, []string{"echo", "a | b", "$HOME"}, "skills")
+	if err != nil || result != "a | b $HOME\n" {
+		t.Errorf("Expected arguments to be passed as is, got %q, %v", result, err)
+	}
+
+	result, err = tool.Run(ctx, []string{"pwd"}, filepath.Join(dir, "skills", "weather"))
+	if err != nil || !strings.HasSuffix(strings.TrimSpace(result), filepath.Join("skills", "weather")) {
+		t.Errorf("Expected command to run in an absolute directory, got %q, %v", result, err)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		dir  string
+		code string
+	}{
+		{nil, "", "INVALID_COMMAND"},
+		{[]string{"rm", "x"}, "", "COMMAND_NOT_ALLOWED"},
+		{[]string{"ls"}, t.TempDir(), "INVALID_PATH"},
+		{[]string{"ls", "/etc"}, "", "INVALID_PATH"},
+	} {
+		if _, err := tool.Run(ctx, tt.args, tt.dir); toolErrorCode(err) != tt.code {
+			t.Errorf("%v in %q: expected %s, got %v", tt.args, tt.dir, tt.code, err)
+		}
+	}
+}
+
+func TestExecToolScrubsEnvironment(t *testing.T) {
+	t.Setenv("MINICLAW_SECRET", "hunter2")
+	t.Setenv("MINICLAW_VISIBLE", "yes")
+
+	tool, dir := newTestExecTool(t, &ExecConfig{Env: []string{"MINICLAW_VISIBLE"}})
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"command": "printenv"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if strings.Contains(result, "hunter2") {
+		t.Error("Expected unlisted variables to be removed")
+	}
+	if !strings.Contains(result, "MINICLAW_VISIBLE=yes") || !strings.Contains(result, "HOME="+dir) {
+		t.Errorf("Expected passthrough variables and HOME, got %s", result)
+	}
+}
+
+func TestExecToolLimits(t *testing.T) {
+	tool, _ := newTestExecTool(t, &ExecConfig{Timeout: 100 * time.Millisecond, MaxOutput: 10})
+	ctx := context.Background()
+
+	start := time.Now()
+	_, err := tool.Execute(ctx, map[string]interface{}{"command": "sleep 5"})
+	if toolErrorCode(err) != "TIMEOUT" {
+		t.Errorf("Expected TIMEOUT, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("Expected command to be killed at the timeout")
+	}
+
+	result, err := tool.Execute(ctx, map[string]interface{}{"command": "echo 0123456789abcdef"})
+	if err != nil || !strings.HasPrefix(result, "0123456789\n[output truncated to 10 bytes]") {
+		t.Errorf("Expected truncated output, got %q, %v", result, err)
+	}
+}
@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultFetchMaxLength = 20000
+	maxFetchMaxLength     = 100000
+	defaultFetchCacheTTL  = 15 * time.Minute
+	maxCachedPages        = 64
+)
+
+type FetchPageConfig struct {
+	HTTP *HTTPConfig
+	// MaxLength is how many characters of Markdown a call returns by
+	// default.
+	MaxLength int
+	// CacheTTL is how long a fetched page is reused, also across chats;
+	// negative turns the cache off.
+	CacheTTL time.Duration
+}
+
+// FetchPageTool downloads a page and returns its main content as Markdown.
+// It goes through the same URL checks as http_request.
+type FetchPageTool struct {
+	http      *HTTPRequestTool
+	maxLength int
+	cacheTTL  time.Duration
+
+	mu    sync.Mutex
+	pages map[string]*fetchedPage
+}
+
+type fetchedPage struct {
+	url     string
+	article article
+	fetched time.Time
+}
+
+func NewFetchPageTool(config *FetchPageConfig) (*FetchPageTool, error) {
+	if config == nil {
+		config = &FetchPageConfig{}
+	}
+
+	httpTool, err := NewHTTPRequestTool(config.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	tool := &FetchPageTool{
+		http:      httpTool,
+		maxLength: config.MaxLength,
+		cacheTTL:  config.CacheTTL,
+		pages:     make(map[string]*fetchedPage),
+	}
+	if tool.maxLength <= 0 {
+		tool.maxLength = defaultFetchMaxLength
+	}
+	if tool.cacheTTL == 0 {
+		tool.cacheTTL = defaultFetchCacheTTL
+	}
+	return tool, nil
+}
+
+func (t *FetchPageTool) Name() string {
+	return "fetch_page"
+}
+
+func (t *FetchPageTool) Description() string {
+	return "Read the main content of a web page as Markdown, without navigation, ads and other boilerplate. " +
+		"Use this to read articles found with web_search; long pages can be read in parts with offset."
+}
+
+func (t *FetchPageTool) Parameters() json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{
+		"type": "object",
+		"properties": {
+			"url": {
+				"type": "string",
+				"description": "The http or https URL of the page"
+			},
+			"max_length": {
+				"type": "integer",
+				"description": "Maximum number of characters to return (default %d)",
+				"minimum": 100,
+				"maximum": %d
+			},
+			"offset": {
+				"type": "integer",
+				"description": "Character to start at, to continue reading a long page (default 0)",
+				"minimum": 0
+			}
+		},
+		"required": ["url"],
+		"additionalProperties": false
+	}`, t.maxLength, maxFetchMaxLength))
+}
+
+func (t *FetchPageTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	rawURL, ok := params["url"].(string)
+	if !ok || rawURL == "" {
+		return "", &ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "url parameter must be a non-empty string",
+		}
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return "", &ToolError{
+			Code:    "INVALID_PARAM",
+			Message: fmt.Sprintf("invalid url: %v", err),
+		}
+	}
+	if err := t.http.checkURL(target); err != nil {
+		return "", &ToolError{
+			Code:    "URL_NOT_ALLOWED",
+			Message: err.Error(),
+		}
+	}
+
+	maxLength := t.maxLength
+	if n, ok := params["max_length"].(float64); ok {
+		maxLength = max(100, min(int(n), maxFetchMaxLength))
+	}
+	offset := 0
+	if n, ok := params["offset"].(float64); ok && n > 0 {
+		offset = int(n)
+	}
+
+	page, err := t.page(ctx, target)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if page.article.Title != "" {
+		fmt.Fprintf(&out, "# %s\n\n", page.article.Title)
+	}
+	fmt.Fprintf(&out, "URL: %s\n", page.url)
+	if page.article.Published != "" {
+		fmt.Fprintf(&out, "Published: %s\n", page.article.Published)
+	}
+	out.WriteString("\n")
+
+	text, total := page.article.Markdown, utf8.RuneCountInString(page.article.Markdown)
+	if offset >= total && total > 0 {
+		return "", &ToolError{
+			Code:    "INVALID_PARAM",
+			Message: fmt.Sprintf("offset %d is past the end of the page (%d characters)", offset, total),
+		}
+	}
+
+	runes := []rune(text)
+	chunk := runes[offset:min(offset+maxLength, total)]
+	if offset+len(chunk) < total {
+		// End at a line break when there is one in the last fifth.
+		for i := len(chunk) - 1; i > len(chunk)*4/5; i-- {
+			if chunk[i] == '\n' {
+				chunk = chunk[:i]
+				break
+			}
+		}
+	}
+	end := offset + len(chunk)
+	out.WriteString(strings.TrimSpace(string(chunk)))
+
+	if offset > 0 || end < total {
+		fmt.Fprintf(&out, "\n\n[characters %d-%d of %d", offset, end, total)
+		if end < total {
+			fmt.Fprintf(&out, "; call again with offset %d for more", end)
+		}
+		out.WriteString("]")
+	}
+
+	return out.String(), nil
+}
+
+// page returns the extracted page, from the cache when it was fetched
+// recently.
+func (t *FetchPageTool) page(ctx context.Context, target *url.URL) (*fetchedPage, error) {
+	key := target.String()
+	if t.cacheTTL > 0 {
+		t.mu.Lock()
+		page, ok := t.pages[key]
+		t.mu.Unlock()
+		if ok && time.Since(page.fetched) < t.cacheTTL {
+			return page, nil
+		}
+	}
+
+	page, err := t.fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.cacheTTL > 0 {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if len(t.pages) >= maxCachedPages {
+			var oldest string
+			for cached, p := range t.pages {
+				if oldest == "" || p.fetched.Before(t.pages[oldest].fetched) {
+					oldest = cached
+				}
+			}
+			delete(t.pages, oldest)
+		}
+		t.pages[key] = page
+	}
+	return page, nil
+}
+
+func (t *FetchPageTool) fetch(ctx context.Context, target *url.URL) (*fetchedPage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, &ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "failed to create request",
+			Err:     err,
+		}
+	}
+	req.Header.Set("User-Agent", httpUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+
+	resp, err := t.http.client.Do(req)
+	if err != nil {
+		code := "REQUEST_FAILED"
+		if errors.Is(err, errPrivateAddress) {
+			code = "URL_NOT_ALLOWED"
+		}
+		return nil, &ToolError{
+			Code:    code,
+			Message: "failed to fetch page",
+			Err:     err,
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &ToolError{
+			Code:    "REQUEST_FAILED",
+			Message: fmt.Sprintf("page returned %s", resp.Status),
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !isTextContent(mediaType) {
+		return nil, &ToolError{
+			Code:    "UNSUPPORTED_CONTENT",
+			Message: fmt.Sprintf("%s is not a web page; use http_request for other content", mediaType),
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, t.http.maxResponseSize))
+	if err != nil {
+		return nil, &ToolError{
+			Code:    "REQUEST_FAILED",
+			Message: "failed to read page",
+			Err:     err,
+		}
+	}
+
+	page := &fetchedPage{url: resp.Request.URL.String(), fetched: time.Now()}
+	if mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		page.article = extractArticle(string(data), resp.Request.URL)
+	} else {
+		page.article.Markdown = strings.TrimSpace(string(data))
+	}
+	return page, nil
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testArticle = `<!DOCTYPE html>
+<html><head>
+<title>Site | Ignored</title>
+<meta property="og:title" content="Rust &amp; Go compared">
+<meta property="article:published_time" content="2024-05-01T08:00:00Z">
+<script>var tracking = "<article>fake</article>";</script>
+</head><body>
+<header><nav><a href="/">Home</a> <a href="/about">About</a></nav></header>
+<div class="sidebar"><p>Popular posts</p></div>
+<article>
+  <h1>Rust and Go</h1>
+  <p>Both languages are <strong>fast</strong> and <em>safe</em>. See the <a href="/docs/go">Go docs</a>.</p>
+  <div class="share-buttons"><a href="https://twitter.com/share">Tweet</a></div>
+  <h2>Concurrency</h2>
+  <ul><li>goroutines</li><li>async/await</li></ul>
+  <pre><code>func main() {
+	go work()
+}</code></pre>
+  <p>Use <code>go vet</code> often.</p>
+</article>
+<footer>Copyright 2024</footer>
+</body></html>`
+
+func TestFetchPageTool(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testArticle))
+	}))
+	defer server.Close()
+
+	tool, err := NewFetchPageTool(&FetchPageConfig{HTTP: &HTTPConfig{AllowPrivate: true}})
+	if err != nil {
+		t.Fatalf("Failed to create tool: %v", err)
+	}
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/posts/rust-go"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"# Rust & Go compared",
+		"Published: 2024-05-01T08:00:00Z",
+		"# Rust and Go",
+		"Both languages are **fast** and *safe*. See the [Go docs](" + server.URL + "/docs/go).",
+		"## Concurrency",
+		"- goroutines\n- async/await",
+		"```\nfunc main() {\n\tgo work()\n}\n```",
+		"Use `go vet` often.",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in:\n%s", want, result)
+		}
+	}
+	for _, unwanted := range []string{"Home", "Popular posts", "Tweet", "Copyright", "tracking", "fake"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("Expected %q to be left out of:\n%s", unwanted, result)
+		}
+	}
+
+	part, err := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/posts/rust-go", "max_length": float64(100)})
+	if err != nil || !strings.Contains(part, "call again with offset") {
+		t.Errorf("Expected a truncated page with a hint, got %q, %v", part, err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected the page to be fetched once, got %d requests", requests)
+	}
+}
+
+func TestFetchPageToolRejectsBinaryAndPrivate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF"))
+	}))
+	defer server.Close()
+
+	tool, _ := NewFetchPageTool(&FetchPageConfig{HTTP: &HTTPConfig{AllowPrivate: true}})
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL}); err == nil {
+		t.Error("Expected a PDF to be rejected")
+	}
+
+	tool, _ = NewFetchPageTool(nil)
+	var toolErr *ToolError
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL}); !AsToolError(err, &toolErr) || toolErr.Code != "URL_NOT_ALLOWED" {
+		t.Errorf("Expected a private address to be refused, got %v", err)
+	}
+}
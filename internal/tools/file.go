@@ -283,6 +283,10 @@ func (t *DeleteFileTool) Description() string {
 	return "Delete a file or directory. Use with caution as this operation cannot be undone."
 }
 
+func (t *DeleteFileTool) Policy() ToolPolicy {
+	return ToolPolicy{RequiresConfirmation: true, AdminOnly: true}
+}
+
 func (t *DeleteFileTool) Parameters() json.RawMessage {
 	return json.RawMessage(`{
 		"type": "object",
@@ -0,0 +1,366 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultHTTPTimeout      = 30 * time.Second
+	defaultHTTPMaxResponse  = 1024 * 1024
+	defaultHTTPMaxRedirects = 5
+	httpUserAgent           = "miniclaw/1.0"
+)
+
+var (
+	errPrivateAddress = errors.New("requests to private network addresses are not allowed")
+
+	htmlSkipPattern     = regexp.MustCompile(`(?is)<(script|style|noscript|svg|template)\b.*?</(script|style|noscript|svg|template)\s*>|<!--.*?-->`)
+	htmlTitlePattern    = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title\s*>`)
+	htmlHeadPattern     = regexp.MustCompile(`(?is)<head\b.*?</head\s*>`)
+	htmlBlockPattern    = regexp.MustCompile(`(?i)<(br|hr)\b[^>]*>|</?(p|div|section|article|header|footer|nav|main|aside|h[1-6]|ul|ol|li|tr|table|blockquote|pre)\b[^>]*>`)
+	htmlTagPattern      = regexp.MustCompile(`<[^>]*>`)
+	horizontalSpace     = regexp.MustCompile(`[ \t\f\r\v]+`)
+	repeatedBlankLines  = regexp.MustCompile(`\n\s*\n+`)
+	textContentPatterns = []string{"text/", "application/json", "application/xml", "application/javascript", "+json", "+xml"}
+)
+
+type HTTPConfig struct {
+	Allow           []string
+	MaxResponseSize int64
+	MaxRedirects    int
+	Timeout         time.Duration
+	AllowPrivate    bool
+}
+
+type HTTPRequestTool struct {
+	allow           []*regexp.Regexp
+	maxResponseSize int64
+	maxRedirects    int
+	client          *http.Client
+}
+
+func NewHTTPRequestTool(config *HTTPConfig) (*HTTPRequestTool, error) {
+	if config == nil {
+		config = &HTTPConfig{}
+	}
+
+	tool := &HTTPRequestTool{
+		maxResponseSize: config.MaxResponseSize,
+		maxRedirects:    config.MaxRedirects,
+	}
+	if tool.maxResponseSize <= 0 {
+		tool.maxResponseSize = defaultHTTPMaxResponse
+	}
+	if tool.maxRedirects <= 0 {
+		tool.maxRedirects = defaultHTTPMaxRedirects
+	}
+
+	for _, pattern := range config.Allow {
+		re, err := compileURLPattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url pattern %q: %w", pattern, err)
+		}
+		tool.allow = append(tool.allow, re)
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !config.AllowPrivate {
+		// The address check happens at dial time so DNS answers cannot point
+		// the request back into the local network; a proxy would hide the target.
+		dialer.Control = rejectPrivateAddress
+		transport.Proxy = nil
+	}
+	transport.DialContext = dialer.DialContext
+
+	tool.client = &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > tool.maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", tool.maxRedirects)
+			}
+			return tool.checkURL(req.URL)
+		},
+	}
+
+	return tool, nil
+}
+
+func (t *HTTPRequestTool) Name() string {
+	return "http_request"
+}
+
+func (t *HTTPRequestTool) Description() string {
+	return "Fetch a web page or call an HTTP API. HTML pages are converted to plain text by default. " +
+		"Use this to read the full content of URLs returned by web_search."
+}
+
+func (t *HTTPRequestTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"url": {
+				"type": "string",
+				"description": "The http or https URL to request"
+			},
+			"method": {
+				"type": "string",
+				"enum": ["GET", "POST"],
+				"description": "HTTP method (default GET)"
+			},
+			"headers": {
+				"type": "object",
+				"additionalProperties": {"type": "string"},
+				"description": "Extra request headers"
+			},
+			"body": {
+				"type": "string",
+				"description": "Request body for POST requests"
+			},
+			"extract_text": {
+				"type": "boolean",
+				"description": "Convert HTML responses to plain text (default true)"
+			}
+		},
+		"required": ["url"],
+		"additionalProperties": false
+	}`)
+}
+
+func (t *HTTPRequestTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	rawURL, ok := params["url"].(string)
+	if !ok || rawURL == "" {
+		return "", &ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "url parameter must be a non-empty string",
+		}
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return "", &ToolError{
+			Code:    "INVALID_PARAM",
+			Message: fmt.Sprintf("invalid url: %v", err),
+		}
+	}
+	if err := t.checkURL(target); err != nil {
+		return "", &ToolError{
+			Code:    "URL_NOT_ALLOWED",
+			Message: err.Error(),
+		}
+	}
+
+	method := http.MethodGet
+	if m, ok := params["method"].(string); ok && m != "" {
+		method = strings.ToUpper(m)
+	}
+	if method != http.MethodGet && method != http.MethodPost {
+		return "", &ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "method must be GET or POST",
+		}
+	}
+
+	var body io.Reader
+	if b, ok := params["body"].(string); ok && b != "" {
+		if method != http.MethodPost {
+			return "", &ToolError{
+				Code:    "INVALID_PARAM",
+				Message: "body is only supported for POST requests",
+			}
+		}
+		body = strings.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return "", &ToolError{
+			Code:    "INVALID_PARAM",
+			Message: "failed to create request",
+			Err:     err,
+		}
+	}
+	req.Header.Set("User-Agent", httpUserAgent)
+	if headers, ok := params["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			if s, ok := value.(string); ok {
+				req.Header.Set(name, s)
+			}
+		}
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		code := "REQUEST_FAILED"
+		if errors.Is(err, errPrivateAddress) {
+			code = "URL_NOT_ALLOWED"
+		}
+		return "", &ToolError{
+			Code:    code,
+			Message: "http request failed",
+			Err:     err,
+		}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, t.maxResponseSize+1))
+	if err != nil {
+		return "", &ToolError{
+			Code:    "REQUEST_FAILED",
+			Message: "failed to read response",
+			Err:     err,
+		}
+	}
+	truncated := int64(len(data)) > t.maxResponseSize
+	if truncated {
+		data = data[:t.maxResponseSize]
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Status: %s\n", resp.Status)
+	if resp.Request.URL.String() != target.String() {
+		fmt.Fprintf(&out, "Final URL: %s\n", resp.Request.URL)
+	}
+	if contentType != "" {
+		fmt.Fprintf(&out, "Content-Type: %s\n", contentType)
+	}
+	out.WriteString("\n")
+
+	extract := true
+	if e, ok := params["extract_text"].(bool); ok {
+		extract = e
+	}
+
+	switch {
+	case !isTextContent(mediaType):
+		fmt.Fprintf(&out, "(binary content, %d bytes not shown)", len(data))
+	case extract && (mediaType == "text/html" || mediaType == "application/xhtml+xml"):
+		out.WriteString(htmlToText(string(data)))
+	default:
+		out.Write(data)
+	}
+
+	if truncated {
+		fmt.Fprintf(&out, "\n[response truncated to %d bytes]", t.maxResponseSize)
+	}
+
+	return out.String(), nil
+}
+
+func (t *HTTPRequestTool) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("only http and https urls are supported")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("url must include a host")
+	}
+	if u.User != nil {
+		return fmt.Errorf("urls with credentials are not allowed")
+	}
+	if len(t.allow) == 0 {
+		return nil
+	}
+
+	for _, re := range t.allow {
+		if re.MatchString(u.String()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("url %s does not match the allowlist", u.Redacted())
+}
+
+// compileURLPattern turns a pattern such as "https://*.example.com/*" into a
+// regexp. A * in the scheme and host only matches within the host, so it cannot
+// be satisfied by moving the expected host into the path.
+func compileURLPattern(pattern string) (*regexp.Regexp, error) {
+	origin, rest := pattern, ""
+	if i := strings.Index(pattern, "://"); i >= 0 {
+		if j := strings.Index(pattern[i+3:], "/"); j >= 0 {
+			origin, rest = pattern[:i+3+j], pattern[i+3+j:]
+		}
+	}
+
+	return regexp.Compile("^" + wildcardPattern(origin, `[^/?#@]*`) + wildcardPattern(rest, ".*") + "$")
+}
+
+func wildcardPattern(pattern, wildcard string) string {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return strings.Join(parts, wildcard)
+}
+
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+func isTextContent(mediaType string) bool {
+	if mediaType == "" {
+		return true
+	}
+	for _, pattern := range textContentPatterns {
+		if strings.HasPrefix(mediaType, pattern) || strings.HasSuffix(mediaType, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func htmlToText(page string) string {
+	title := ""
+	if match := htmlTitlePattern.FindStringSubmatch(page); match != nil {
+		title = strings.TrimSpace(html.UnescapeString(htmlTagPattern.ReplaceAllString(match[1], "")))
+	}
+
+	text := htmlSkipPattern.ReplaceAllString(page, "")
+	text = htmlHeadPattern.ReplaceAllString(text, "")
+	text = htmlBlockPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = horizontalSpace.ReplaceAllString(text, " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = repeatedBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	text = strings.TrimSpace(text)
+
+	if title != "" {
+		return "Title: " + title + "\n\n" + text
+	}
+	return text
+}
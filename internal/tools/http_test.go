@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestHTTPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, `<html><head><title>Go &amp; Friends</title><style>body{}</style></head>
+<body><script>alert(1)</script><h1>Welcome</h1><p>First   paragraph<br>second line</p>
+<ul><li>one</li><li>two</li></ul><!-- hidden --></body></html>`)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"method":"`+r.Method+`","token":"`+r.Header.Get("X-Token")+`","body":"`+string(body)+`"}`)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("a", 100))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/private/secret", http.StatusFound)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPRequestToolFetchesPages(t *testing.T) {
+	server := newTestHTTPServer(t)
+	tool, err := NewHTTPRequestTool(&HTTPConfig{AllowPrivate: true})
+	if err != nil {
+		t.Fatalf("Failed to create tool: %v", err)
+	}
+	ctx := context.Background()
+
+	result, err := tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/page"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := "Title: Go & Friends\n\nWelcome\n\nFirst paragraph\nsecond line\n\none\n\ntwo"
+	if !strings.HasSuffix(result, want) || !strings.HasPrefix(result, "Status: 200 OK\n") {
+		t.Errorf("Unexpected page text:\n%s", result)
+	}
+	if strings.Contains(result, "alert") || strings.Contains(result, "hidden") {
+		t.Errorf("Expected scripts and comments to be removed:\n%s", result)
+	}
+
+	result, _ = tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/page", "extract_text": false})
+	if !strings.Contains(result, "<h1>Welcome</h1>") {
+		t.Errorf("Expected raw HTML when extraction is disabled, got %s", result)
+	}
+
+	result, err = tool.Execute(ctx, map[string]interface{}{
+		"url":     server.URL + "/echo",
+		"method":  "post",
+		"headers": map[string]interface{}{"X-Token": "abc"},
+		"body":    "hi",
+	})
+	if err != nil || !strings.Contains(result, `{"method":"POST","token":"abc","body":"hi"}`) {
+		t.Errorf("Expected echoed POST request, got %q, %v", result, err)
+	}
+
+	small, _ := NewHTTPRequestTool(&HTTPConfig{AllowPrivate: true, MaxResponseSize: 50})
+	result, _ = small.Execute(ctx, map[string]interface{}{"url": server.URL + "/large"})
+	if !strings.Contains(result, strings.Repeat("a", 50)+"\n[response truncated to 50 bytes]") {
+		t.Errorf("Expected truncated response, got %s", result)
+	}
+
+	result, _ = tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/image"})
+	if !strings.Contains(result, "(binary content, 4 bytes not shown)") {
+		t.Errorf("Expected binary placeholder, got %s", result)
+	}
+
+	result, _ = tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/moved"})
+	if !strings.Contains(result, "Final URL: "+server.URL+"/page") {
+		t.Errorf("Expected redirect to be followed, got %s", result)
+	}
+}
+
+func TestHTTPRequestToolRestrictions(t *testing.T) {
+	server := newTestHTTPServer(t)
+	ctx := context.Background()
+
+	tool, err := NewHTTPRequestTool(&HTTPConfig{
+		Allow:        []string{server.URL + "/page", server.URL + "/loop", server.URL + "/elsewhere"},
+		MaxRedirects: 2,
+		AllowPrivate: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tool: %v", err)
+	}
+
+	tests := []struct {
+		params map[string]interface{}
+		code   string
+	}{
+		{map[string]interface{}{"url": server.URL + "/echo"}, "URL_NOT_ALLOWED"},
+		{map[string]interface{}{"url": "file:///etc/passwd"}, "URL_NOT_ALLOWED"},
+		{map[string]interface{}{"url": server.URL + "/elsewhere"}, "REQUEST_FAILED"},
+		{map[string]interface{}{"url": server.URL + "/loop"}, "REQUEST_FAILED"},
+		{map[string]interface{}{"url": server.URL + "/page", "method": "DELETE"}, "INVALID_PARAM"},
+		{map[string]interface{}{"url": server.URL + "/page", "body": "x"}, "INVALID_PARAM"},
+	}
+	for _, tt := range tests {
+		if _, err := tool.Execute(ctx, tt.params); toolErrorCode(err) != tt.code {
+			t.Errorf("%v: expected %s, got %v", tt.params, tt.code, err)
+		}
+	}
+
+	_, err = tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/loop"})
+	if err == nil || !strings.Contains(err.Error(), "stopped after 2 redirects") {
+		t.Errorf("Expected redirect limit error, got %v", err)
+	}
+
+	public, _ := NewHTTPRequestTool(nil)
+	if _, err := public.Execute(ctx, map[string]interface{}{"url": server.URL + "/page"}); toolErrorCode(err) != "URL_NOT_ALLOWED" {
+		t.Errorf("Expected loopback address to be rejected, got %v", err)
+	}
+}
+
+func TestCompileURLPattern(t *testing.T) {
+	re, err := compileURLPattern("https://*.wikipedia.org/*")
+	if err != nil {
+		t.Fatalf("Failed to compile pattern: %v", err)
+	}
+
+	for url, want := range map[string]bool{
+		"https://en.wikipedia.org/wiki/Go":     true,
+		"http://en.wikipedia.org/wiki/Go":      false,
+		"https://wikipedia.org.evil.com/x":     false,
+		"https://en.wikipedia.org?x=.org/wiki": false,
+		"https://evil.com/.wikipedia.org/x":    false,
+		"https://evil.com?.wikipedia.org/x":    false,
+	} {
+		if got := re.MatchString(url); got != want {
+			t.Errorf("%s: got %v, want %v", url, got, want)
+		}
+	}
+}
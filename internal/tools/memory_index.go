@@ -0,0 +1,385 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wjffsx/miniclaw_go/internal/llm"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const (
+	defaultMemorySearchLimit = 5
+	maxMemorySearchLimit     = 20
+	memoryFileSource         = "MEMORY.md"
+	memoryReindexDelay       = 500 * time.Millisecond
+)
+
+var logger = logging.For("tools")
+
+type MemoryIndex struct {
+	embedder llm.Embedder
+	store    storage.VectorStore
+	// indexMu keeps two reindexes of MEMORY.md from racing each other.
+	indexMu sync.Mutex
+}
+
+func NewMemoryIndex(embedder llm.Embedder, store storage.VectorStore) *MemoryIndex {
+	return &MemoryIndex{
+		embedder: embedder,
+		store:    store,
+	}
+}
+
+func (m *MemoryIndex) Upsert(ctx context.Context, id, content, source string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", fmt.Errorf("memory content cannot be empty")
+	}
+
+	if id == "" {
+		id = memoryID(source, content)
+	}
+
+	vectors, err := m.embedder.Embed(ctx, []string{content})
+	if err != nil {
+		return "", fmt.Errorf("failed to embed memory: %w", err)
+	}
+
+	entry := storage.VectorEntry{
+		ID:      id,
+		Content: content,
+		Source:  source,
+		Model:   m.embedder.GetModel(),
+		Vector:  vectors[0],
+	}
+
+	if err := m.store.Upsert(ctx, entry); err != nil {
+		return "", fmt.Errorf("failed to store memory: %w", err)
+	}
+
+	return id, nil
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, query string, limit int) ([]storage.VectorMatch, error) {
+	vectors, err := m.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	return m.store.Search(ctx, vectors[0], m.embedder.GetModel(), limit)
+}
+
+func (m *MemoryIndex) IndexMemoryFile(ctx context.Context, content string) (int, error) {
+	m.indexMu.Lock()
+	defer m.indexMu.Unlock()
+
+	existing, err := m.store.List(ctx, memoryFileSource)
+	if err != nil {
+		return 0, err
+	}
+
+	stale := make(map[string]bool, len(existing))
+	for _, entry := range existing {
+		if entry.Model == m.embedder.GetModel() {
+			stale[entry.ID] = true
+		}
+	}
+
+	var pending []string
+	var pendingIDs []string
+	for _, chunk := range splitMemoryChunks(content) {
+		id := memoryID(memoryFileSource, chunk)
+		if stale[id] {
+			delete(stale, id)
+			continue
+		}
+		pending = append(pending, chunk)
+		pendingIDs = append(pendingIDs, id)
+	}
+
+	if len(pending) > 0 {
+		vectors, err := m.embedder.Embed(ctx, pending)
+		if err != nil {
+			return 0, fmt.Errorf("failed to embed memory file: %w", err)
+		}
+
+		entries := make([]storage.VectorEntry, 0, len(pending))
+		for i, chunk := range pending {
+			entries = append(entries, storage.VectorEntry{
+				ID:      pendingIDs[i],
+				Content: chunk,
+				Source:  memoryFileSource,
+				Model:   m.embedder.GetModel(),
+				Vector:  vectors[i],
+			})
+		}
+
+		if err := m.store.Upsert(ctx, entries...); err != nil {
+			return 0, fmt.Errorf("failed to store memory file: %w", err)
+		}
+	}
+
+	if len(stale) > 0 {
+		ids := make([]string, 0, len(stale))
+		for id := range stale {
+			ids = append(ids, id)
+		}
+		if err := m.store.Delete(ctx, ids...); err != nil {
+			return 0, fmt.Errorf("failed to remove stale memories: %w", err)
+		}
+	}
+
+	return len(pending), nil
+}
+
+// Reindex indexes the current content of MEMORY.md.
+func (m *MemoryIndex) Reindex(ctx context.Context, memory storage.MemoryStorage) (int, error) {
+	content, err := memory.GetMemory(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read memory: %w", err)
+	}
+	return m.IndexMemoryFile(ctx, content)
+}
+
+// WatchMemoryFile reindexes MEMORY.md when the file at path changes, such as
+// when it is edited by hand or with the file tools, until ctx is done.
+func (m *MemoryIndex) WatchMemoryFile(ctx context.Context, path string, memory storage.MemoryStorage) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// The directory is watched because atomic writes replace the file.
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var timer *time.Timer
+		reindex := func() {
+			indexed, err := m.Reindex(ctx, memory)
+			if err != nil {
+				logger.Warn("Failed to reindex memory", "error", err)
+				return
+			}
+			logger.Debug("Memory reindexed", "new_chunks", indexed)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) != filepath.Base(path) {
+					continue
+				}
+				if timer == nil {
+					timer = time.AfterFunc(memoryReindexDelay, reindex)
+				} else {
+					timer.Reset(memoryReindexDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("Memory watcher error", "error", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// IndexedMemoryStorage reindexes MEMORY.md whenever it is written through
+// it, such as by add_memory or memory consolidation.
+type IndexedMemoryStorage struct {
+	storage.MemoryStorage
+	index *MemoryIndex
+}
+
+func NewIndexedMemoryStorage(memory storage.MemoryStorage, index *MemoryIndex) *IndexedMemoryStorage {
+	return &IndexedMemoryStorage{
+		MemoryStorage: memory,
+		index:         index,
+	}
+}
+
+// SetMemory saves content and reindexes it. A failure to index is logged
+// rather than returned, because the memory itself was saved.
+func (s *IndexedMemoryStorage) SetMemory(ctx context.Context, content string) error {
+	if err := s.MemoryStorage.SetMemory(ctx, content); err != nil {
+		return err
+	}
+
+	if _, err := s.index.IndexMemoryFile(ctx, content); err != nil {
+		logger.Warn("Failed to reindex memory", "error", err)
+	}
+	return nil
+}
+
+func splitMemoryChunks(content string) []string {
+	chunks := make([]string, 0)
+	heading := ""
+
+	for _, block := range strings.Split(content, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+
+		if strings.HasPrefix(block, "#") && !strings.Contains(block, "\n") {
+			heading = block
+			continue
+		}
+
+		if heading != "" {
+			block = heading + "\n" + block
+		}
+		chunks = append(chunks, block)
+	}
+
+	return chunks
+}
+
+func memoryID(source, content string) string {
+	hash := sha256.Sum256([]byte(source + "\n" + content))
+	return "mem-" + hex.EncodeToString(hash[:8])
+}
+
+func NewMemoryIndexTools(index *MemoryIndex) []Tool {
+	return []Tool{
+		NewMemorySearchTool(index),
+		NewMemoryUpsertTool(index),
+	}
+}
+
+func NewMemorySearchTool(index *MemoryIndex) Tool {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"query": {
+				"type": "string",
+				"description": "What to look for in long-term memory"
+			},
+			"limit": {
+				"type": "number",
+				"description": "Maximum number of memories to return (default: 5)"
+			}
+		},
+		"required": ["query"],
+		"additionalProperties": false
+	}`)
+
+	return NewBaseTool(
+		"memory_search",
+		"Search long-term memory for facts, preferences and notes related to a query",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			query, ok := params["query"].(string)
+			if !ok || strings.TrimSpace(query) == "" {
+				return "", &ToolError{
+					Code:    "INVALID_PARAM",
+					Message: "query parameter must be a non-empty string",
+				}
+			}
+
+			limit := defaultMemorySearchLimit
+			if value, ok := params["limit"].(float64); ok && value > 0 {
+				limit = int(value)
+			}
+			if limit > maxMemorySearchLimit {
+				limit = maxMemorySearchLimit
+			}
+
+			matches, err := index.Search(ctx, query, limit)
+			if err != nil {
+				return "", &ToolError{
+					Code:    "SEARCH_FAILED",
+					Message: "failed to search memory",
+					Err:     err,
+				}
+			}
+
+			if len(matches) == 0 {
+				return "No matching memories found.", nil
+			}
+
+			var builder strings.Builder
+			for i, match := range matches {
+				fmt.Fprintf(&builder, "%d. [%s, score %.2f] %s\n", i+1, match.Entry.ID, match.Score, match.Entry.Content)
+			}
+
+			return strings.TrimSpace(builder.String()), nil
+		},
+	)
+}
+
+func NewMemoryUpsertTool(index *MemoryIndex) Tool {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"content": {
+				"type": "string",
+				"description": "The fact, preference or note to remember"
+			},
+			"id": {
+				"type": "string",
+				"description": "ID of an existing memory to replace (optional)"
+			}
+		},
+		"required": ["content"],
+		"additionalProperties": false
+	}`)
+
+	return NewBaseTool(
+		"memory_upsert",
+		"Store or update a memory in long-term memory so it can be found later with memory_search",
+		params,
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			content, ok := params["content"].(string)
+			if !ok || strings.TrimSpace(content) == "" {
+				return "", &ToolError{
+					Code:    "INVALID_PARAM",
+					Message: "content parameter must be a non-empty string",
+				}
+			}
+
+			id, _ := params["id"].(string)
+
+			id, err := index.Upsert(ctx, id, content, "agent")
+			if err != nil {
+				return "", &ToolError{
+					Code:    "UPSERT_FAILED",
+					Message: "failed to store memory",
+					Err:     err,
+				}
+			}
+
+			return fmt.Sprintf("Memory saved with ID %s", id), nil
+		},
+	)
+}
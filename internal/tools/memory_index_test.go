@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+type keywordEmbedder struct {
+	calls atomic.Int32
+}
+
+func (e *keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls.Add(1)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		text = strings.ToLower(text)
+		vectors[i] = []float32{
+			float32(strings.Count(text, "tea")),
+			float32(strings.Count(text, "cat")),
+			0.1,
+		}
+	}
+	return vectors, nil
+}
+
+func (e *keywordEmbedder) GetModel() string {
+	return "keyword"
+}
+
+func TestMemoryIndexTools(t *testing.T) {
+	ctx := context.Background()
+	embedder := &keywordEmbedder{}
+	index := NewMemoryIndex(embedder, storage.NewFileSystemVectorStore(t.TempDir()))
+
+	indexed, err := index.IndexMemoryFile(ctx, "# Pets\n\nHas a cat named Miso\n\n# Drinks\n\nPrefers coffee")
+	if err != nil {
+		t.Fatalf("Failed to index memory file: %v", err)
+	}
+	if indexed != 2 {
+		t.Errorf("Expected 2 chunks to be indexed, got %d", indexed)
+	}
+
+	calls := embedder.calls.Load()
+	if indexed, _ := index.IndexMemoryFile(ctx, "# Pets\n\nHas a cat named Miso\n\n# Drinks\n\nPrefers coffee"); indexed != 0 || embedder.calls.Load() != calls {
+		t.Errorf("Expected unchanged memory file not to be embedded again")
+	}
+
+	tools := NewMemoryIndexTools(index)
+	upsert, search := tools[1], tools[0]
+
+	result, err := upsert.Execute(ctx, map[string]interface{}{"content": "Drinks green tea every morning"})
+	if err != nil {
+		t.Fatalf("Failed to upsert memory: %v", err)
+	}
+	if !strings.Contains(result, "mem-") {
+		t.Errorf("Expected memory ID in result, got %s", result)
+	}
+
+	result, err = search.Execute(ctx, map[string]interface{}{"query": "what tea do they like", "limit": 1.0})
+	if err != nil {
+		t.Fatalf("Failed to search memory: %v", err)
+	}
+	if !strings.Contains(result, "green tea") || strings.Contains(result, "Miso") {
+		t.Errorf("Expected only the tea memory, got %s", result)
+	}
+
+	result, _ = search.Execute(ctx, map[string]interface{}{"query": "cat"})
+	if !strings.Contains(result, "# Pets\nHas a cat named Miso") {
+		t.Errorf("Expected chunk with its heading, got %s", result)
+	}
+
+	if _, err := search.Execute(ctx, map[string]interface{}{"query": " "}); err == nil {
+		t.Error("Expected error for empty query")
+	}
+}
+
+func TestMemoryIndexFollowsMemoryWrites(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	index := NewMemoryIndex(&keywordEmbedder{}, storage.NewFileSystemVectorStore(dir))
+	fileMemory := storage.NewFileSystemMemoryStorage(dir)
+	memory := NewIndexedMemoryStorage(fileMemory, index)
+
+	contains := func(text string) bool {
+		matches, err := index.Search(ctx, text, 0)
+		if err != nil {
+			t.Fatalf("Failed to search memory: %v", err)
+		}
+		for _, match := range matches {
+			if strings.Contains(match.Entry.Content, text) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if err := memory.SetMemory(ctx, "Has a cat named Miso"); err != nil {
+		t.Fatalf("Failed to set memory: %v", err)
+	}
+	if !contains("Miso") {
+		t.Error("Expected memory written through the storage to be indexed")
+	}
+
+	if err := index.WatchMemoryFile(ctx, fileMemory.MemoryFile(), fileMemory); err != nil {
+		t.Fatalf("Failed to watch memory file: %v", err)
+	}
+	if err := os.WriteFile(fileMemory.MemoryFile(), []byte("Drinks green tea"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !contains("green tea") || contains("Miso") {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the edited memory file to be reindexed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
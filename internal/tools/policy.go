@@ -0,0 +1,255 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const retryBackoff = 200 * time.Millisecond
+
+type ToolPolicy struct {
+	Timeout              time.Duration
+	Retries              int
+	MaxConcurrent        int
+	RequiresConfirmation bool
+	AdminOnly            bool
+	// CacheTTL is how long the results of an idempotent tool are reused for
+	// the same params in the same conversation.
+	CacheTTL time.Duration
+	// Invalidates names the cached tools whose results a successful call
+	// makes stale, such as write_file for read_file.
+	Invalidates []string
+}
+
+// PolicyProvider is implemented by tools that declare their own policy.
+type PolicyProvider interface {
+	Policy() ToolPolicy
+}
+
+// PolicyOverride adjusts a tool's declared policy. Zero values keep the
+// declared setting.
+type PolicyOverride struct {
+	Timeout              time.Duration
+	Retries              int
+	MaxConcurrent        int
+	RequiresConfirmation *bool
+	AdminOnly            *bool
+	// CacheTTL below zero turns off a cache the tool declares.
+	CacheTTL time.Duration
+}
+
+type PolicyConfig struct {
+	DefaultTimeout time.Duration
+	Tools          map[string]PolicyOverride
+}
+
+// Confirmer asks the user whether a tool call may run.
+type Confirmer func(ctx context.Context, name string, params map[string]interface{}) (bool, error)
+
+// Authorizer reports whether the user behind ctx may run an admin-only tool.
+type Authorizer func(ctx context.Context, name string) bool
+
+type toolLimits struct {
+	mu     sync.Mutex
+	config PolicyConfig
+	slots  map[string]chan struct{}
+	caches map[string]*CachedTool
+}
+
+func (e *ToolExecutor) SetPolicies(config *PolicyConfig) {
+	e.limits.mu.Lock()
+	defer e.limits.mu.Unlock()
+
+	if config == nil {
+		config = &PolicyConfig{}
+	}
+	e.limits.config = *config
+	e.limits.slots = make(map[string]chan struct{})
+	e.limits.caches = make(map[string]*CachedTool)
+}
+
+func (e *ToolExecutor) SetConfirmer(confirm Confirmer) {
+	e.confirm = confirm
+}
+
+// SetAuthorizer gates admin-only tools. Without one, every caller may run
+// them.
+func (e *ToolExecutor) SetAuthorizer(authorize Authorizer) {
+	e.authorize = authorize
+}
+
+// PolicyFor returns the effective policy for a tool.
+func (e *ToolExecutor) PolicyFor(tool Tool) ToolPolicy {
+	var policy ToolPolicy
+	if provider, ok := tool.(PolicyProvider); ok {
+		policy = provider.Policy()
+	}
+
+	e.limits.mu.Lock()
+	defer e.limits.mu.Unlock()
+
+	if policy.Timeout == 0 {
+		policy.Timeout = e.limits.config.DefaultTimeout
+	}
+
+	override, ok := e.limits.config.Tools[tool.Name()]
+	if !ok {
+		return policy
+	}
+	if override.Timeout > 0 {
+		policy.Timeout = override.Timeout
+	}
+	if override.Retries > 0 {
+		policy.Retries = override.Retries
+	}
+	if override.MaxConcurrent > 0 {
+		policy.MaxConcurrent = override.MaxConcurrent
+	}
+	if override.RequiresConfirmation != nil {
+		policy.RequiresConfirmation = *override.RequiresConfirmation
+	}
+	if override.AdminOnly != nil {
+		policy.AdminOnly = *override.AdminOnly
+	}
+	if override.CacheTTL != 0 {
+		policy.CacheTTL = max(override.CacheTTL, 0)
+	}
+	return policy
+}
+
+// cached returns the cache of a tool, which lives as long as the executor so
+// results are shared between calls.
+func (l *toolLimits) cached(tool Tool, ttl time.Duration) *CachedTool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cache, ok := l.caches[tool.Name()]
+	if !ok || cache.ttl != ttl {
+		cache = NewCachedTool(tool, ttl)
+		l.caches[tool.Name()] = cache
+	}
+	return cache
+}
+
+func (l *toolLimits) invalidate(names []string, chatID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, name := range names {
+		if cache, ok := l.caches[name]; ok {
+			cache.Invalidate(chatID)
+		}
+	}
+}
+
+func (e *ToolExecutor) runWithPolicy(ctx context.Context, tool Tool, policy ToolPolicy, params map[string]interface{}) (string, error) {
+	if policy.AdminOnly && e.authorize != nil && !e.authorize(ctx, tool.Name()) {
+		return "", &ToolError{
+			Code:    "FORBIDDEN",
+			Message: fmt.Sprintf("tool '%s' can only be run by an admin", tool.Name()),
+		}
+	}
+
+	if policy.RequiresConfirmation {
+		if e.confirm == nil {
+			return "", &ToolError{
+				Code:    "CONFIRMATION_REQUIRED",
+				Message: fmt.Sprintf("tool '%s' requires confirmation but no user is available to confirm it", tool.Name()),
+			}
+		}
+
+		approved, err := e.confirm(ctx, tool.Name(), params)
+		if err != nil {
+			return "", &ToolError{
+				Code:    "CONFIRMATION_REQUIRED",
+				Message: "failed to ask for confirmation",
+				Err:     err,
+			}
+		}
+		if !approved {
+			return "", &ToolError{
+				Code:    "CONFIRMATION_DECLINED",
+				Message: fmt.Sprintf("the user declined to run '%s'", tool.Name()),
+			}
+		}
+	}
+
+	if policy.MaxConcurrent > 0 {
+		release, err := e.acquireSlot(ctx, tool.Name(), policy.MaxConcurrent)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+	}
+
+	var result string
+	var err error
+	for attempt := 0; attempt <= policy.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Duration(attempt) * retryBackoff):
+			}
+		}
+
+		result, err = runWithTimeout(ctx, tool, policy.Timeout, params)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return result, err
+}
+
+func (e *ToolExecutor) acquireSlot(ctx context.Context, name string, size int) (func(), error) {
+	e.limits.mu.Lock()
+	slots, ok := e.limits.slots[name]
+	if !ok {
+		slots = make(chan struct{}, size)
+		e.limits.slots[name] = slots
+	}
+	e.limits.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runWithTimeout returns when the timeout expires even if the tool ignores
+// context cancellation.
+func runWithTimeout(ctx context.Context, tool Tool, timeout time.Duration, params map[string]interface{}) (string, error) {
+	if timeout <= 0 {
+		return tool.Execute(ctx, params)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := tool.Execute(ctx, params)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", &ToolError{
+				Code:    "TIMEOUT",
+				Message: fmt.Sprintf("tool '%s' timed out after %s", tool.Name(), timeout),
+			}
+		}
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,280 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type policyTestTool struct {
+	name    string
+	policy  ToolPolicy
+	execute func(ctx context.Context) (string, error)
+}
+
+func (t *policyTestTool) Name() string                { return t.name }
+func (t *policyTestTool) Description() string         { return "test tool" }
+func (t *policyTestTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (t *policyTestTool) Policy() ToolPolicy          { return t.policy }
+
+func (t *policyTestTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	return t.execute(ctx)
+}
+
+func newPolicyExecutor(tool Tool) *ToolExecutor {
+	registry := NewToolRegistry()
+	registry.Register(tool)
+	return NewToolExecutor(registry)
+}
+
+func TestPolicyTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	executor := newPolicyExecutor(&policyTestTool{
+		name:   "stuck",
+		policy: ToolPolicy{Timeout: 50 * time.Millisecond},
+		execute: func(ctx context.Context) (string, error) {
+			<-block
+			return "late", nil
+		},
+	})
+
+	start := time.Now()
+	call, err := executor.Execute(context.Background(), "stuck", nil)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if call.Error == "" || time.Since(start) > time.Second {
+		t.Errorf("Expected tool ignoring its context to time out, got %+v", call)
+	}
+}
+
+func TestPolicyRetries(t *testing.T) {
+	var attempts int32
+	tool := &policyTestTool{
+		name:   "flaky",
+		policy: ToolPolicy{Retries: 2},
+		execute: func(ctx context.Context) (string, error) {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				return "", errors.New("temporary failure")
+			}
+			return "ok", nil
+		},
+	}
+	executor := newPolicyExecutor(tool)
+
+	call, err := executor.Execute(context.Background(), "flaky", nil)
+	if err != nil || call.Result != "ok" {
+		t.Fatalf("Expected retries to succeed, got %+v, %v", call, err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	executor.SetPolicies(&PolicyConfig{Tools: map[string]PolicyOverride{"flaky": {Retries: 1}}})
+	if call, _ := executor.Execute(context.Background(), "flaky", nil); call.Error == "" {
+		t.Error("Expected failure with fewer retries")
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestPolicyMaxConcurrent(t *testing.T) {
+	var running, peak int32
+	executor := newPolicyExecutor(&policyTestTool{
+		name:   "serial",
+		policy: ToolPolicy{MaxConcurrent: 1},
+		execute: func(ctx context.Context) (string, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(30 * time.Millisecond)
+			return "ok", nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			executor.Execute(context.Background(), "serial", nil)
+		}()
+	}
+	wg.Wait()
+
+	if peak != 1 {
+		t.Errorf("Expected at most one concurrent call, got %d", peak)
+	}
+}
+
+func TestPolicyConfirmation(t *testing.T) {
+	var ran bool
+	tool := &policyTestTool{
+		name:   "dangerous",
+		policy: ToolPolicy{RequiresConfirmation: true},
+		execute: func(ctx context.Context) (string, error) {
+			ran = true
+			return "done", nil
+		},
+	}
+	executor := newPolicyExecutor(tool)
+	ctx := context.Background()
+
+	if _, err := executor.runWithPolicy(ctx, tool, executor.PolicyFor(tool), nil); toolErrorCode(err) != "CONFIRMATION_REQUIRED" {
+		t.Errorf("Expected CONFIRMATION_REQUIRED without a confirmer, got %v", err)
+	}
+
+	var asked string
+	approve := false
+	executor.SetConfirmer(func(ctx context.Context, name string, params map[string]interface{}) (bool, error) {
+		asked = name
+		return approve, nil
+	})
+
+	if _, err := executor.runWithPolicy(ctx, tool, executor.PolicyFor(tool), nil); toolErrorCode(err) != "CONFIRMATION_DECLINED" {
+		t.Errorf("Expected CONFIRMATION_DECLINED, got %v", err)
+	}
+	if ran || asked != "dangerous" {
+		t.Errorf("Expected declined tool not to run, ran=%v asked=%q", ran, asked)
+	}
+
+	approve = true
+	if call, err := executor.Execute(ctx, "dangerous", nil); err != nil || call.Result != "done" || !ran {
+		t.Errorf("Expected approved tool to run, got %+v, %v", call, err)
+	}
+}
+
+func TestPolicyOverrides(t *testing.T) {
+	executor := newPolicyExecutor(NewEchoTool())
+	deleteTool := NewDeleteFileTool(t.TempDir())
+
+	if !executor.PolicyFor(deleteTool).RequiresConfirmation {
+		t.Error("Expected delete_file to require confirmation by default")
+	}
+
+	off := false
+	executor.SetPolicies(&PolicyConfig{
+		DefaultTimeout: time.Minute,
+		Tools: map[string]PolicyOverride{
+			"delete_file": {RequiresConfirmation: &off, Timeout: 5 * time.Second},
+		},
+	})
+
+	policy := executor.PolicyFor(deleteTool)
+	if policy.RequiresConfirmation || policy.Timeout != 5*time.Second {
+		t.Errorf("Expected override to apply, got %+v", policy)
+	}
+	if policy := executor.PolicyFor(NewEchoTool()); policy.Timeout != time.Minute {
+		t.Errorf("Expected default timeout, got %+v", policy)
+	}
+}
+
+func TestPolicyAdminOnly(t *testing.T) {
+	tool := &policyTestTool{
+		name:   "admin_tool",
+		policy: ToolPolicy{AdminOnly: true},
+		execute: func(ctx context.Context) (string, error) {
+			return "done", nil
+		},
+	}
+	executor := newPolicyExecutor(tool)
+	ctx := context.Background()
+
+	if _, err := executor.runWithPolicy(ctx, tool, executor.PolicyFor(tool), nil); err != nil {
+		t.Errorf("Expected admin-only tool to run without an authorizer, got %v", err)
+	}
+
+	admin := false
+	executor.SetAuthorizer(func(ctx context.Context, name string) bool {
+		return admin
+	})
+
+	if _, err := executor.runWithPolicy(ctx, tool, executor.PolicyFor(tool), nil); toolErrorCode(err) != "FORBIDDEN" {
+		t.Errorf("Expected FORBIDDEN for a non-admin, got %v", err)
+	}
+
+	admin = true
+	if result, err := executor.runWithPolicy(ctx, tool, executor.PolicyFor(tool), nil); err != nil || result != "done" {
+		t.Errorf("Expected admin to run the tool, got %q, %v", result, err)
+	}
+
+	if !executor.PolicyFor(NewDeleteFileTool(t.TempDir())).AdminOnly {
+		t.Error("Expected delete_file to be admin-only by default")
+	}
+}
+
+func TestPolicyCache(t *testing.T) {
+	var runs int32
+	registry := NewToolRegistry()
+	registry.Register(&policyTestTool{
+		name:   "search",
+		policy: ToolPolicy{CacheTTL: time.Minute},
+		execute: func(ctx context.Context) (string, error) {
+			return fmt.Sprintf("result %d", atomic.AddInt32(&runs, 1)), nil
+		},
+	})
+	registry.Register(&policyTestTool{
+		name:    "write",
+		policy:  ToolPolicy{Invalidates: []string{"search"}},
+		execute: func(ctx context.Context) (string, error) { return "written", nil },
+	})
+	executor := NewToolExecutor(registry)
+
+	chat := WithChatID(context.Background(), "chat")
+	first, _ := executor.Execute(chat, "search", map[string]interface{}{"query": "go", "count": 5.0})
+	second, _ := executor.Execute(chat, "search", map[string]interface{}{"count": 5.0, "query": "go"})
+	if first.Result != "result 1" || second.Result != "result 1" {
+		t.Errorf("Expected the same call to be answered from the cache, got %q and %q", first.Result, second.Result)
+	}
+
+	if call, _ := executor.Execute(chat, "search", map[string]interface{}{"query": "rust"}); call.Result != "result 2" {
+		t.Errorf("Expected other params to run the tool, got %q", call.Result)
+	}
+	if call, _ := executor.Execute(WithChatID(context.Background(), "other"), "search", map[string]interface{}{"query": "go", "count": 5.0}); call.Result != "result 3" {
+		t.Errorf("Expected another conversation to run the tool, got %q", call.Result)
+	}
+
+	executor.Execute(chat, "write", nil)
+	if call, _ := executor.Execute(chat, "search", map[string]interface{}{"query": "go", "count": 5.0}); call.Result != "result 4" {
+		t.Errorf("Expected the write to invalidate the cache, got %q", call.Result)
+	}
+
+	executor.SetPolicies(&PolicyConfig{Tools: map[string]PolicyOverride{"search": {CacheTTL: -1}}})
+	if call, _ := executor.Execute(chat, "search", map[string]interface{}{"query": "go", "count": 5.0}); call.Result != "result 5" {
+		t.Errorf("Expected a negative override to turn the cache off, got %q", call.Result)
+	}
+}
+
+func TestCachedToolDoesNotCacheErrors(t *testing.T) {
+	var runs int32
+	tool := NewCachedTool(&policyTestTool{
+		name: "flaky",
+		execute: func(ctx context.Context) (string, error) {
+			if atomic.AddInt32(&runs, 1) == 1 {
+				return "", errors.New("unavailable")
+			}
+			return "ok", nil
+		},
+	}, time.Minute)
+
+	if _, err := tool.Execute(context.Background(), nil); err == nil {
+		t.Fatal("Expected the first call to fail")
+	}
+	if result, err := tool.Execute(context.Background(), nil); err != nil || result != "ok" {
+		t.Errorf("Expected the failure not to be cached, got %q, %v", result, err)
+	}
+}
@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Readability-style extraction: pick the element holding the article, drop
+// navigation, sharing widgets and the like from it, and convert what is left
+// to Markdown. Like htmlToText it works on the markup with regexps rather
+// than a full HTML parser.
+
+var (
+	tagPattern        = regexp.MustCompile(`(?i)<(/?)([a-z][a-z0-9]*)\b([^>]*)>`)
+	metaPattern       = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+	timePattern       = regexp.MustCompile(`(?is)<time\b[^>]*>`)
+	prePattern        = regexp.MustCompile(`(?is)<pre\b[^>]*>(.*?)</pre\s*>`)
+	codePattern       = regexp.MustCompile(`(?is)<code\b[^>]*>(.*?)</code\s*>`)
+	linkPattern       = regexp.MustCompile(`(?is)<a\b([^>]*)>(.*?)</a\s*>`)
+	strongPattern     = regexp.MustCompile(`(?is)<(?:strong|b)\b[^>]*>(.*?)</(?:strong|b)\s*>`)
+	emphasisPattern   = regexp.MustCompile(`(?is)<(?:em|i)\b[^>]*>(.*?)</(?:em|i)\s*>`)
+	headingPattern    = regexp.MustCompile(`(?is)<h([1-6])\b[^>]*>(.*?)</h[1-6]\s*>`)
+	listItemPattern   = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	cellEndPattern    = regexp.MustCompile(`(?i)</t[dh]\s*>`)
+	markdownBlockTags = regexp.MustCompile(`(?i)<(br|hr)\b[^>]*>|</?(p|div|section|article|main|ul|ol|tr|table|blockquote|figure|figcaption|dl|dt|dd)\b[^>]*>`)
+	placeholder       = regexp.MustCompile("\x00(\\d+)\x00")
+
+	// boilerplatePattern matches the class or id of elements that are rarely
+	// part of an article.
+	boilerplatePattern = regexp.MustCompile(`(?i)\b(comments?|sidebar|share|sharing|social|related|recommended|cookies?|consent|newsletter|subscribe|promo|advert|ads?|banner|breadcrumbs?|menu|navigation|footer|popup|modal)\b`)
+	boilerplateRoles   = []string{"navigation", "banner", "contentinfo", "complementary", "search", "dialog"}
+	boilerplateTags    = []string{"nav", "header", "footer", "aside", "form", "button", "select", "iframe", "dialog", "menu"}
+
+	voidTags = []string{"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "param", "source", "track", "wbr"}
+
+	attrPatterns = map[string]*regexp.Regexp{}
+)
+
+func init() {
+	for _, name := range []string{"class", "id", "role", "href", "content", "property", "name", "datetime"} {
+		attrPatterns[name] = regexp.MustCompile(`(?is)(?:^|\s)` + name + `\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	}
+}
+
+type element struct {
+	name       string
+	attrs      string
+	start, end int // the start tag begins at start, the end tag ends at end
+	inner      int // the start tag ends at inner
+}
+
+// article is what extractArticle found on a page.
+type article struct {
+	Title     string
+	Published string
+	Markdown  string
+}
+
+func extractArticle(page string, base *url.URL) article {
+	var result article
+	for _, tag := range metaPattern.FindAllString(page, -1) {
+		key := attr(tag, "property")
+		if key == "" {
+			key = attr(tag, "name")
+		}
+		switch strings.ToLower(key) {
+		case "og:title":
+			result.Title = attr(tag, "content")
+		case "article:published_time", "date", "pubdate":
+			if result.Published == "" {
+				result.Published = attr(tag, "content")
+			}
+		}
+	}
+	if result.Title == "" {
+		if match := htmlTitlePattern.FindStringSubmatch(page); match != nil {
+			result.Title = inlineText(match[1])
+		}
+	}
+
+	page = htmlSkipPattern.ReplaceAllString(page, "")
+	elements := parseElements(page)
+
+	root := findRoot(page, elements)
+	if result.Published == "" {
+		if tag := timePattern.FindString(page[root.start:root.end]); tag != "" {
+			result.Published = attr(tag, "datetime")
+		}
+	}
+
+	content := removeBoilerplate(page, root, elements)
+	result.Markdown = htmlToMarkdown(content, base)
+	return result
+}
+
+// parseElements pairs start and end tags. Elements that are never closed,
+// like a <p> ended by the next one, are left out.
+func parseElements(page string) []element {
+	var elements []element
+	open := make(map[string][]element)
+	for _, match := range tagPattern.FindAllStringSubmatchIndex(page, -1) {
+		name := strings.ToLower(page[match[4]:match[5]])
+		closing := match[3] > match[2]
+		attrs := page[match[6]:match[7]]
+		if slices.Contains(voidTags, name) || strings.HasSuffix(attrs, "/") {
+			continue
+		}
+
+		if !closing {
+			open[name] = append(open[name], element{name: name, attrs: attrs, start: match[0], inner: match[1]})
+			continue
+		}
+		if stack := open[name]; len(stack) > 0 {
+			el := stack[len(stack)-1]
+			open[name] = stack[:len(stack)-1]
+			el.end = match[1]
+			elements = append(elements, el)
+		}
+	}
+
+	sort.Slice(elements, func(i, j int) bool { return elements[i].start < elements[j].start })
+	return elements
+}
+
+// findRoot picks the element the article is in: the <article> with the most
+// text, else <main>, else <body>, else the whole page.
+func findRoot(page string, elements []element) element {
+	var best element
+	bestLength := -1
+	for _, el := range elements {
+		if el.name != "article" {
+			continue
+		}
+		if length := len(inlineText(page[el.inner:el.end])); length > bestLength {
+			best, bestLength = el, length
+		}
+	}
+	if bestLength > 0 {
+		return best
+	}
+
+	for _, name := range []string{"main", "body"} {
+		for _, el := range elements {
+			if el.name == name || (name == "main" && strings.EqualFold(attr(el.attrs, "role"), "main")) {
+				return el
+			}
+		}
+	}
+	return element{start: 0, inner: 0, end: len(page)}
+}
+
+// removeBoilerplate returns the inside of root without the elements that
+// look like navigation or page furniture. Elements only matched by their
+// class or id are kept when they hold much of the text, so a wrapper called
+// "has-sidebar" does not take the article with it.
+func removeBoilerplate(page string, root element, elements []element) string {
+	rootLength := len(inlineText(page[root.inner:root.end]))
+
+	var cut [][2]int
+	for _, el := range elements {
+		if el.start < root.inner || el.end > root.end || (len(cut) > 0 && el.start < cut[len(cut)-1][1]) {
+			continue
+		}
+
+		remove := slices.Contains(boilerplateTags, el.name) || slices.Contains(boilerplateRoles, strings.ToLower(attr(el.attrs, "role")))
+		if !remove && boilerplatePattern.MatchString(attr(el.attrs, "class")+" "+attr(el.attrs, "id")) {
+			remove = len(inlineText(page[el.inner:el.end]))*3 < rootLength
+		}
+		if remove {
+			cut = append(cut, [2]int{el.start, el.end})
+		}
+	}
+
+	var content strings.Builder
+	pos := root.inner
+	for _, c := range cut {
+		content.WriteString(page[pos:c[0]])
+		content.WriteString("\n")
+		pos = c[1]
+	}
+	end := root.end
+	if root.name != "" {
+		end = strings.LastIndex(page[:root.end], "<")
+	}
+	if end > pos {
+		content.WriteString(page[pos:end])
+	}
+	return content.String()
+}
+
+func htmlToMarkdown(content string, base *url.URL) string {
+	// Preformatted blocks are set aside so their whitespace survives.
+	var blocks []string
+	content = prePattern.ReplaceAllStringFunc(content, func(block string) string {
+		code := prePattern.FindStringSubmatch(block)[1]
+		code = strings.Trim(html.UnescapeString(htmlTagPattern.ReplaceAllString(code, "")), "\n")
+		blocks = append(blocks, "\n\n```\n"+code+"\n```\n\n")
+		return fmt.Sprintf("\x00%d\x00", len(blocks)-1)
+	})
+
+	content = codePattern.ReplaceAllStringFunc(content, func(code string) string {
+		return wrapInline("`", codePattern.FindStringSubmatch(code)[1])
+	})
+	content = linkPattern.ReplaceAllStringFunc(content, func(link string) string {
+		match := linkPattern.FindStringSubmatch(link)
+		text := inlineText(match[2])
+		href := strings.TrimSpace(html.UnescapeString(attr(match[1], "href")))
+		if text == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return text
+		}
+		if u, err := url.Parse(href); err == nil && base != nil {
+			href = base.ResolveReference(u).String()
+		}
+		return "[" + text + "](" + href + ")"
+	})
+	content = strongPattern.ReplaceAllStringFunc(content, func(s string) string {
+		return wrapInline("**", strongPattern.FindStringSubmatch(s)[1])
+	})
+	content = emphasisPattern.ReplaceAllStringFunc(content, func(s string) string {
+		return wrapInline("*", emphasisPattern.FindStringSubmatch(s)[1])
+	})
+	content = headingPattern.ReplaceAllStringFunc(content, func(heading string) string {
+		match := headingPattern.FindStringSubmatch(heading)
+		return "\n\n" + strings.Repeat("#", int(match[1][0]-'0')) + " " + inlineText(match[2]) + "\n\n"
+	})
+	content = listItemPattern.ReplaceAllString(content, "\n- ")
+	content = cellEndPattern.ReplaceAllString(content, " | ")
+	content = markdownBlockTags.ReplaceAllStringFunc(content, func(tag string) string {
+		if strings.HasPrefix(strings.ToLower(tag), "<br") {
+			return "\n"
+		}
+		return "\n\n"
+	})
+
+	content = html.UnescapeString(htmlTagPattern.ReplaceAllString(content, ""))
+	content = horizontalSpace.ReplaceAllString(content, " ")
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	content = repeatedBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+
+	content = placeholder.ReplaceAllStringFunc(content, func(p string) string {
+		var i int
+		fmt.Sscanf(strings.Trim(p, "\x00"), "%d", &i)
+		return blocks[i]
+	})
+	return strings.TrimSpace(repeatedBlankLines.ReplaceAllString(content, "\n\n"))
+}
+
+func wrapInline(marker, fragment string) string {
+	text := inlineText(fragment)
+	if text == "" {
+		return ""
+	}
+	return marker + text + marker
+}
+
+// inlineText is the text of an HTML fragment on one line.
+func inlineText(fragment string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(htmlTagPattern.ReplaceAllString(fragment, " "))), " ")
+}
+
+func attr(tag, name string) string {
+	match := attrPatterns[name].FindStringSubmatch(tag)
+	if match == nil {
+		return ""
+	}
+	return html.UnescapeString(match[1] + match[2] + match[3])
+}
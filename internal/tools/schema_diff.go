@@ -0,0 +1,302 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+const toolSnapshotDir = "tools/snapshots"
+
+type ToolSnapshot struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Tools     []ToolSchema `json:"tools"`
+}
+
+type ToolChange struct {
+	Name    string   `json:"name"`
+	Changes []string `json:"changes"`
+}
+
+type ToolDiff struct {
+	FromVersion int          `json:"from_version"`
+	ToVersion   int          `json:"to_version"`
+	Added       []string     `json:"added,omitempty"`
+	Removed     []string     `json:"removed,omitempty"`
+	Changed     []ToolChange `json:"changed,omitempty"`
+}
+
+func NewToolSnapshot(registry *ToolRegistry) *ToolSnapshot {
+	schemas := registry.GetSchemas()
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Name < schemas[j].Name
+	})
+
+	return &ToolSnapshot{
+		CreatedAt: time.Now(),
+		Tools:     schemas,
+	}
+}
+
+func DiffToolSnapshots(old, current *ToolSnapshot) *ToolDiff {
+	diff := &ToolDiff{}
+	oldTools := make(map[string]ToolSchema)
+	if old != nil {
+		diff.FromVersion = old.Version
+		for _, tool := range old.Tools {
+			oldTools[tool.Name] = tool
+		}
+	}
+
+	currentTools := make(map[string]ToolSchema)
+	if current != nil {
+		diff.ToVersion = current.Version
+		for _, tool := range current.Tools {
+			currentTools[tool.Name] = tool
+		}
+	}
+
+	for name, tool := range currentTools {
+		previous, ok := oldTools[name]
+		if !ok {
+			diff.Added = append(diff.Added, name)
+			continue
+		}
+
+		var changes []string
+		if previous.Description != tool.Description {
+			changes = append(changes, fmt.Sprintf("description: %q -> %q", previous.Description, tool.Description))
+		}
+		changes = append(changes, diffParameters(previous.Parameters, tool.Parameters)...)
+
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, ToolChange{Name: name, Changes: changes})
+		}
+	}
+
+	for name := range oldTools {
+		if _, ok := currentTools[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Name < diff.Changed[j].Name
+	})
+
+	return diff
+}
+
+func (d *ToolDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (d *ToolDiff) String() string {
+	if d.Empty() {
+		return "No tool schema changes."
+	}
+
+	var builder strings.Builder
+	to := "current"
+	if d.ToVersion > 0 {
+		to = fmt.Sprintf("v%d", d.ToVersion)
+	}
+	fmt.Fprintf(&builder, "Tool schema changes (v%d -> %s):", d.FromVersion, to)
+	for _, name := range d.Added {
+		fmt.Fprintf(&builder, "\n+ %s", name)
+	}
+	for _, name := range d.Removed {
+		fmt.Fprintf(&builder, "\n- %s", name)
+	}
+	for _, change := range d.Changed {
+		fmt.Fprintf(&builder, "\n~ %s", change.Name)
+		for _, detail := range change.Changes {
+			fmt.Fprintf(&builder, "\n    %s", detail)
+		}
+	}
+
+	return builder.String()
+}
+
+func diffParameters(old, current json.RawMessage) []string {
+	if bytes.Equal(canonicalJSON(old), canonicalJSON(current)) {
+		return nil
+	}
+
+	var oldSchema, currentSchema map[string]interface{}
+	if json.Unmarshal(old, &oldSchema) != nil || json.Unmarshal(current, &currentSchema) != nil {
+		return []string{"parameters changed"}
+	}
+
+	oldProperties, _ := oldSchema["properties"].(map[string]interface{})
+	currentProperties, _ := currentSchema["properties"].(map[string]interface{})
+
+	var changes []string
+	for _, name := range sortedKeys(currentProperties) {
+		previous, ok := oldProperties[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("parameter added: %s", name))
+			continue
+		}
+		if !bytes.Equal(marshalCanonical(previous), marshalCanonical(currentProperties[name])) {
+			changes = append(changes, fmt.Sprintf("parameter changed: %s: %s -> %s", name, marshalCanonical(previous), marshalCanonical(currentProperties[name])))
+		}
+	}
+
+	for _, name := range sortedKeys(oldProperties) {
+		if _, ok := currentProperties[name]; !ok {
+			changes = append(changes, fmt.Sprintf("parameter removed: %s", name))
+		}
+	}
+
+	oldRequired := requiredSet(oldSchema)
+	currentRequired := requiredSet(currentSchema)
+	for _, name := range sortedKeys(currentRequired) {
+		if !oldRequired[name] {
+			changes = append(changes, fmt.Sprintf("parameter now required: %s", name))
+		}
+	}
+	for _, name := range sortedKeys(oldRequired) {
+		if !currentRequired[name] {
+			changes = append(changes, fmt.Sprintf("parameter now optional: %s", name))
+		}
+	}
+
+	delete(oldSchema, "properties")
+	delete(oldSchema, "required")
+	delete(currentSchema, "properties")
+	delete(currentSchema, "required")
+	if !bytes.Equal(marshalCanonical(oldSchema), marshalCanonical(currentSchema)) {
+		changes = append(changes, fmt.Sprintf("schema changed: %s -> %s", marshalCanonical(oldSchema), marshalCanonical(currentSchema)))
+	}
+
+	return changes
+}
+
+func canonicalJSON(data json.RawMessage) []byte {
+	var value interface{}
+	if len(data) == 0 || json.Unmarshal(data, &value) != nil {
+		return data
+	}
+	return marshalCanonical(value)
+}
+
+func marshalCanonical(value interface{}) []byte {
+	data, _ := json.Marshal(value)
+	return data
+}
+
+func requiredSet(schema map[string]interface{}) map[string]bool {
+	required := make(map[string]bool)
+	list, _ := schema["required"].([]interface{})
+	for _, item := range list {
+		if name, ok := item.(string); ok {
+			required[name] = true
+		}
+	}
+	return required
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type ToolSnapshotStore struct {
+	storage storage.Storage
+}
+
+func NewToolSnapshotStore(storage storage.Storage) *ToolSnapshotStore {
+	return &ToolSnapshotStore{
+		storage: storage,
+	}
+}
+
+func (s *ToolSnapshotStore) Versions(ctx context.Context) ([]int, error) {
+	files, err := s.storage.ListFiles(ctx, toolSnapshotDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tool snapshots: %w", err)
+	}
+
+	versions := make([]int, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(strings.ReplaceAll(file, "\\", "/")), ".json")
+		version, err := strconv.Atoi(strings.TrimPrefix(name, "v"))
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	sort.Ints(versions)
+	return versions, nil
+}
+
+func (s *ToolSnapshotStore) Load(ctx context.Context, version int) (*ToolSnapshot, error) {
+	data, err := s.storage.ReadFile(ctx, snapshotPath(version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool snapshot v%d: %w", version, err)
+	}
+
+	var snapshot ToolSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse tool snapshot v%d: %w", version, err)
+	}
+
+	return &snapshot, nil
+}
+
+func (s *ToolSnapshotStore) Latest(ctx context.Context) (*ToolSnapshot, error) {
+	versions, err := s.Versions(ctx)
+	if err != nil || len(versions) == 0 {
+		return nil, err
+	}
+	return s.Load(ctx, versions[len(versions)-1])
+}
+
+func (s *ToolSnapshotStore) Record(ctx context.Context, snapshot *ToolSnapshot) (*ToolDiff, error) {
+	latest, err := s.Latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffToolSnapshots(latest, snapshot)
+	if latest != nil && diff.Empty() {
+		snapshot.Version = latest.Version
+		diff.ToVersion = latest.Version
+		return diff, nil
+	}
+
+	snapshot.Version = diff.FromVersion + 1
+	diff.ToVersion = snapshot.Version
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool snapshot: %w", err)
+	}
+
+	if err := s.storage.WriteFile(ctx, snapshotPath(snapshot.Version), data); err != nil {
+		return nil, fmt.Errorf("failed to write tool snapshot: %w", err)
+	}
+
+	return diff, nil
+}
+
+func snapshotPath(version int) string {
+	return path.Join(toolSnapshotDir, fmt.Sprintf("v%d.json", version))
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func schemaTool(name, description, parameters string) Tool {
+	return NewBaseTool(name, description, json.RawMessage(parameters), nil)
+}
+
+func TestDiffToolSnapshots(t *testing.T) {
+	old := &ToolSnapshot{Version: 1, Tools: []ToolSchema{
+		{Name: "echo", Description: "Echo text", Parameters: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}},"required":["text"]}`)},
+		{Name: "legacy", Description: "Old tool", Parameters: json.RawMessage(`{}`)},
+		{Name: "same", Description: "Unchanged", Parameters: json.RawMessage(`{"type": "object"}`)},
+	}}
+
+	current := &ToolSnapshot{Version: 2, Tools: []ToolSchema{
+		{Name: "echo", Description: "Echo text back", Parameters: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"},"count":{"type":"integer"}},"required":["text","count"]}`)},
+		{Name: "same", Description: "Unchanged", Parameters: json.RawMessage(`{"type":"object"}`)},
+		{Name: "search", Description: "New tool", Parameters: json.RawMessage(`{}`)},
+	}}
+
+	diff := DiffToolSnapshots(old, current)
+
+	if len(diff.Added) != 1 || diff.Added[0] != "search" {
+		t.Errorf("Expected search to be added, got %v", diff.Added)
+	}
+
+	if len(diff.Removed) != 1 || diff.Removed[0] != "legacy" {
+		t.Errorf("Expected legacy to be removed, got %v", diff.Removed)
+	}
+
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "echo" {
+		t.Fatalf("Expected only echo to change, got %+v", diff.Changed)
+	}
+
+	changes := strings.Join(diff.Changed[0].Changes, "\n")
+	for _, expected := range []string{"description:", "parameter added: count", "parameter now required: count"} {
+		if !strings.Contains(changes, expected) {
+			t.Errorf("Expected %q in changes, got:\n%s", expected, changes)
+		}
+	}
+
+	if !strings.Contains(diff.String(), "(v1 -> v2)") {
+		t.Errorf("Unexpected diff summary: %s", diff)
+	}
+}
+
+func TestToolSnapshotStoreRecord(t *testing.T) {
+	ctx := context.Background()
+	store := NewToolSnapshotStore(storage.NewFileStorage(t.TempDir()))
+
+	registry := NewToolRegistry()
+	registry.Register(schemaTool("echo", "Echo text", `{"type":"object"}`))
+
+	diff, err := store.Record(ctx, NewToolSnapshot(registry))
+	if err != nil {
+		t.Fatalf("Failed to record snapshot: %v", err)
+	}
+	if diff.ToVersion != 1 || len(diff.Added) != 1 {
+		t.Errorf("Expected first snapshot to add echo as v1, got %+v", diff)
+	}
+
+	diff, err = store.Record(ctx, NewToolSnapshot(registry))
+	if err != nil {
+		t.Fatalf("Failed to record snapshot: %v", err)
+	}
+	if !diff.Empty() || diff.ToVersion != 1 {
+		t.Errorf("Expected unchanged tools to keep v1, got %+v", diff)
+	}
+
+	registry.Unregister("echo")
+	registry.Register(schemaTool("echo", "Echo text", `{"type":"object","properties":{"text":{"type":"string"}}}`))
+
+	diff, err = store.Record(ctx, NewToolSnapshot(registry))
+	if err != nil {
+		t.Fatalf("Failed to record snapshot: %v", err)
+	}
+	if diff.FromVersion != 1 || diff.ToVersion != 2 || len(diff.Changed) != 1 {
+		t.Errorf("Expected echo change recorded as v2, got %+v", diff)
+	}
+
+	versions, err := store.Versions(ctx)
+	if err != nil || len(versions) != 2 {
+		t.Errorf("Expected 2 versions, got %v (%v)", versions, err)
+	}
+
+	snapshot, err := store.Load(ctx, 1)
+	if err != nil || len(snapshot.Tools) != 1 || string(canonicalJSON(snapshot.Tools[0].Parameters)) != `{"type":"object"}` {
+		t.Errorf("Expected v1 to keep original schema, got %+v (%v)", snapshot, err)
+	}
+}
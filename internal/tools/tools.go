@@ -3,6 +3,9 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"sync"
+
+	"github.com/wjffsx/miniclaw_go/internal/tracing"
 )
 
 type Tool interface {
@@ -21,7 +24,10 @@ type ToolCall struct {
 	Duration int64                  `json:"duration,omitempty"`
 }
 
+// ToolRegistry is safe for concurrent use, since tools such as those from
+// skills come and go while the agent runs.
 type ToolRegistry struct {
+	mu    sync.RWMutex
 	tools map[string]Tool
 }
 
@@ -39,6 +45,9 @@ func (r *ToolRegistry) Register(tool Tool) error {
 		}
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.tools[tool.Name()]; exists {
 		return &ToolError{
 			Code:    "DUPLICATE_TOOL",
@@ -51,15 +60,21 @@ func (r *ToolRegistry) Register(tool Tool) error {
 }
 
 func (r *ToolRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	delete(r.tools, name)
 }
 
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	tool, exists := r.tools[name]
 	return tool, exists
 }
 
 func (r *ToolRegistry) List() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	tools := make([]Tool, 0, len(r.tools))
 	for _, tool := range r.tools {
 		tools = append(tools, tool)
@@ -68,17 +83,23 @@ func (r *ToolRegistry) List() []Tool {
 }
 
 func (r *ToolRegistry) GetSchemas() []ToolSchema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	schemas := make([]ToolSchema, 0, len(r.tools))
 	for _, tool := range r.tools {
-		schemas = append(schemas, ToolSchema{
-			Name:        tool.Name(),
-			Description: tool.Description(),
-			Parameters:  tool.Parameters(),
-		})
+		schemas = append(schemas, schemaOf(tool))
 	}
 	return schemas
 }
 
+func schemaOf(tool Tool) ToolSchema {
+	return ToolSchema{
+		Name:        tool.Name(),
+		Description: tool.Description(),
+		Parameters:  tool.Parameters(),
+	}
+}
+
 type ToolSchema struct {
 	Name        string          `json:"name"`
 	Description string          `json:"description"`
@@ -86,17 +107,33 @@ type ToolSchema struct {
 }
 
 type ToolExecutor struct {
-	registry *ToolRegistry
+	registry  *ToolRegistry
+	wrap      func(Tool) Tool
+	confirm   Confirmer
+	authorize Authorizer
+	limits    toolLimits
 }
 
 func NewToolExecutor(registry *ToolRegistry) *ToolExecutor {
 	return &ToolExecutor{
 		registry: registry,
+		limits: toolLimits{
+			slots:  make(map[string]chan struct{}),
+			caches: make(map[string]*CachedTool),
+		},
 	}
 }
 
 func (e *ToolExecutor) Execute(ctx context.Context, name string, params map[string]interface{}) (*ToolCall, error) {
 	tool, exists := e.registry.Get(name)
+	if !exists {
+		for _, scoped := range scopedTools(ctx) {
+			if scoped.Name() == name {
+				tool, exists = scoped, true
+				break
+			}
+		}
+	}
 	if !exists {
 		return nil, &ToolError{
 			Code:    "TOOL_NOT_FOUND",
@@ -104,18 +141,34 @@ func (e *ToolExecutor) Execute(ctx context.Context, name string, params map[stri
 		}
 	}
 
+	policy := e.PolicyFor(tool)
+	if policy.CacheTTL > 0 {
+		tool = e.limits.cached(tool, policy.CacheTTL)
+	}
+	if e.wrap != nil {
+		tool = e.wrap(tool)
+	}
+
 	call := &ToolCall{
 		ID:    generateID(),
 		Name:  name,
 		Input: params,
 	}
 
-	result, err := tool.Execute(ctx, params)
+	ctx, span := tracing.Start(ctx, "tool.execute", tracing.String("tool", name))
+	result, err := e.runWithPolicy(ctx, tool, policy, params)
+	span.RecordError(err)
+	span.End()
 	if err != nil {
 		call.Error = err.Error()
 		return call, nil
 	}
 
+	if len(policy.Invalidates) > 0 {
+		chatID, _ := ChatIDFromContext(ctx)
+		e.limits.invalidate(policy.Invalidates, chatID)
+	}
+
 	call.Result = result
 	return call, nil
 }
@@ -134,10 +187,24 @@ func (e *ToolExecutor) ExecuteMultiple(ctx context.Context, calls []ToolCall) ([
 	return results, nil
 }
 
+// SetWrapper installs a decorator applied to each tool before it runs.
+func (e *ToolExecutor) SetWrapper(wrap func(Tool) Tool) {
+	e.wrap = wrap
+}
+
 func (e *ToolExecutor) GetSchemas() []ToolSchema {
 	return e.registry.GetSchemas()
 }
 
+// SchemasFor also includes the tools added to ctx with WithTools.
+func (e *ToolExecutor) SchemasFor(ctx context.Context) []ToolSchema {
+	schemas := e.registry.GetSchemas()
+	for _, tool := range scopedTools(ctx) {
+		schemas = append(schemas, schemaOf(tool))
+	}
+	return schemas
+}
+
 type ToolError struct {
 	Code    string
 	Message string
@@ -390,3 +390,32 @@ func TestTimedTool(t *testing.T) {
 		}
 	})
 }
+
+func TestScopedTools(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(NewEchoTool())
+	executor := NewToolExecutor(registry)
+
+	scoped := NewBaseTool("scoped", "only for one request", json.RawMessage(`{"type": "object"}`),
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			return "scoped result", nil
+		})
+
+	ctx := context.Background()
+	if _, err := executor.Execute(ctx, "scoped", nil); err == nil {
+		t.Error("Expected scoped tool to be unavailable without the context")
+	}
+
+	scopedCtx := WithTools(ctx, scoped)
+	call, err := executor.Execute(scopedCtx, "scoped", nil)
+	if err != nil || call.Result != "scoped result" {
+		t.Errorf("Expected scoped tool to run, got %+v, %v", call, err)
+	}
+
+	if schemas := executor.SchemasFor(scopedCtx); len(schemas) != 2 {
+		t.Errorf("Expected registry and scoped schemas, got %d", len(schemas))
+	}
+	if schemas := executor.SchemasFor(ctx); len(schemas) != 1 {
+		t.Errorf("Expected only registry schemas, got %d", len(schemas))
+	}
+}
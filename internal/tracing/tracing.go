@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is one timed operation. The methods of a span started without a
+// tracer do nothing, so callers never need to check.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs []Attribute
+	err   string
+	ended bool
+}
+
+var current atomic.Pointer[Tracer]
+
+// SetTracer installs the tracer spans are sent to; nil turns tracing off.
+func SetTracer(tracer *Tracer) {
+	current.Store(tracer)
+}
+
+type spanKey struct{}
+
+type traceIDKey struct{}
+
+// WithTraceID makes spans started from ctx without a parent span use the
+// given trace ID, e.g. one carried by a message, so traces and logs share it.
+// IDs that are not 32 hex digits are ignored.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	var id [16]byte
+	if len(traceID) != 2*len(id) {
+		return ctx
+	}
+	if _, err := hex.Decode(id[:], []byte(traceID)); err != nil || id == [16]byte{} {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// Start begins a span as a child of the span in ctx, if any, and returns a
+// context carrying the new span.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	tracer := current.Load()
+	if tracer == nil {
+		return ctx, &Span{}
+	}
+
+	span := &Span{
+		tracer: tracer,
+		name:   name,
+		start:  time.Now(),
+		attrs:  attrs,
+	}
+	rand.Read(span.spanID[:])
+
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent.tracer != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else if traceID, ok := ctx.Value(traceIDKey{}).([16]byte); ok {
+		span.traceID = traceID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s.tracer == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if s.tracer == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err.Error()
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s.tracer == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.enqueue(s)
+}
+
+// TraceID returns the span's trace ID in hex, or "" when tracing is off.
+func (s *Span) TraceID() string {
+	if s.tracer == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
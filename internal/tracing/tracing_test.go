@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type fakeCollector struct {
+	mu       sync.Mutex
+	requests []otlpRequest
+	headers  []http.Header
+}
+
+func (c *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+
+	var req otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header)
+	c.mu.Unlock()
+}
+
+func (c *fakeCollector) spans() map[string]otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	spans := make(map[string]otlpSpan)
+	for _, req := range c.requests {
+		for _, resource := range req.ResourceSpans {
+			for _, scope := range resource.ScopeSpans {
+				for _, span := range scope.Spans {
+					spans[span.Name] = span
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func TestSpansExportedAsOTLP(t *testing.T) {
+	collector := &fakeCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	tracer, err := NewTracer(&Config{
+		Endpoint: server.URL + "/",
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tracer: %v", err)
+	}
+	SetTracer(tracer)
+	t.Cleanup(func() { SetTracer(nil) })
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx, root := Start(WithTraceID(context.Background(), traceID), "agent.handle_message", String("chat_id", "chat"))
+	_, child := Start(ctx, "tool.execute", String("tool", "web_search"), Int("attempt", 2), Bool("cached", false))
+	child.RecordError(errors.New("timeout"))
+	child.End()
+	root.End()
+	root.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to flush spans: %v", err)
+	}
+
+	spans := collector.spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	parent, tool := spans["agent.handle_message"], spans["tool.execute"]
+	if parent.TraceID != traceID || tool.TraceID != traceID {
+		t.Errorf("Expected both spans in trace %s, got %s and %s", traceID, parent.TraceID, tool.TraceID)
+	}
+	if tool.ParentSpanID != parent.SpanID || parent.ParentSpanID != "" {
+		t.Errorf("Expected the tool span under the message span, got %+v and %+v", tool, parent)
+	}
+	if tool.Status.Code != statusCodeError || tool.Status.Message != "timeout" || parent.Status.Code != 0 {
+		t.Errorf("Expected only the tool span to fail, got %+v and %+v", tool.Status, parent.Status)
+	}
+	if len(tool.Attributes) != 3 || *tool.Attributes[1].Value.IntValue != "2" || *tool.Attributes[2].Value.BoolValue {
+		t.Errorf("Unexpected attributes: %+v", tool.Attributes)
+	}
+	if parent.StartTimeUnixNano > parent.EndTimeUnixNano && len(parent.StartTimeUnixNano) == len(parent.EndTimeUnixNano) {
+		t.Errorf("Expected the span to end after it started, got %s to %s", parent.StartTimeUnixNano, parent.EndTimeUnixNano)
+	}
+
+	service := collector.requests[0].ResourceSpans[0].Resource.Attributes[0]
+	if service.Key != "service.name" || *service.Value.StringValue != "miniclaw" {
+		t.Errorf("Expected the default service name, got %+v", service)
+	}
+	if collector.headers[0].Get("Authorization") != "Bearer token" {
+		t.Errorf("Expected the configured headers to be sent")
+	}
+}
+
+func TestSpansWithoutTracer(t *testing.T) {
+	SetTracer(nil)
+
+	ctx, span := Start(context.Background(), "agent.iteration")
+	span.SetAttributes(Int("iteration", 1))
+	span.RecordError(errors.New("ignored"))
+	span.End()
+
+	if span.TraceID() != "" || ctx != context.Background() {
+		t.Error("Expected spans to be no-ops without a tracer")
+	}
+}
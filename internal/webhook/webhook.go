@@ -0,0 +1,258 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+var logger = logging.For("webhook")
+
+const (
+	defaultMaxBodySize = 1 << 20
+	// maxEventLength keeps a huge payload from filling the context window.
+	maxEventLength = 8000
+
+	defaultTemplate = "Webhook {{.Name}}{{with .Event}} ({{.}}){{end}} received:\n```json\n{{json .Payload}}\n```"
+)
+
+// Hook turns requests to /hooks/<Name> into messages for the agent in a
+// chat. The payload is rendered with Template, a text/template that sees
+// .Name, .Event, .Headers and the decoded JSON body as .Payload, and Prompt
+// is put in front to tell the agent what to do with it.
+type Hook struct {
+	Name string
+	// Secret authenticates requests, either as the key of a GitHub style
+	// X-Hub-Signature-256 HMAC of the body, or as a token sent in an
+	// X-Webhook-Token or "Authorization: Bearer" header or a token query
+	// parameter. Without one anybody who knows the URL can post.
+	Secret   string
+	Template string
+	Prompt   string
+	Skills   []string
+	Channel  string
+	ChatID   string
+}
+
+type Config struct {
+	Hooks       []Hook
+	MessageBus  bus.MessageBus
+	MaxBodySize int64
+}
+
+type hook struct {
+	Hook
+	template *template.Template
+}
+
+// Receiver is the HTTP handler for POST /hooks/{name}.
+type Receiver struct {
+	hooks       map[string]*hook
+	messageBus  bus.MessageBus
+	maxBodySize int64
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) string {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	},
+	"truncate": truncate,
+}
+
+func NewReceiver(config *Config) (*Receiver, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	if config.MessageBus == nil {
+		return nil, fmt.Errorf("message bus cannot be nil")
+	}
+
+	maxBodySize := config.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+
+	r := &Receiver{
+		hooks:       make(map[string]*hook, len(config.Hooks)),
+		messageBus:  config.MessageBus,
+		maxBodySize: maxBodySize,
+	}
+
+	for _, h := range config.Hooks {
+		if h.Name == "" || strings.ContainsAny(h.Name, "/ ") {
+			return nil, fmt.Errorf("invalid webhook name %q", h.Name)
+		}
+		if _, exists := r.hooks[h.Name]; exists {
+			return nil, fmt.Errorf("duplicate webhook %q", h.Name)
+		}
+		if h.Channel == "" || h.ChatID == "" {
+			return nil, fmt.Errorf("webhook %q needs a channel and a chat ID", h.Name)
+		}
+
+		text := h.Template
+		if text == "" {
+			text = defaultTemplate
+		}
+		tmpl, err := template.New(h.Name).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template of webhook %q: %w", h.Name, err)
+		}
+
+		if h.Secret == "" {
+			logger.Warn("Webhook has no secret, anyone with the URL can post to it", "hook", h.Name)
+		}
+		r.hooks[h.Name] = &hook{Hook: h, template: tmpl}
+	}
+
+	return r, nil
+}
+
+// Event is what a hook's template is rendered with.
+type Event struct {
+	Name    string
+	Event   string
+	Headers map[string]string
+	Payload interface{}
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h, ok := r.hooks[req.PathValue("name")]
+	if !ok {
+		http.Error(w, "unknown webhook", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, r.maxBodySize))
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !h.authorized(req, body) {
+		logger.Warn("Rejected webhook with a missing or wrong secret", "hook", h.Name, "remote", req.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	content, err := h.render(req, body)
+	if err != nil {
+		logger.Error("Failed to render webhook", "hook", h.Name, "error", err)
+		http.Error(w, "failed to render event", http.StatusInternalServerError)
+		return
+	}
+
+	msg := &bus.Message{
+		ID:        fmt.Sprintf("webhook-%s-%d", h.Name, time.Now().UnixNano()),
+		Channel:   h.Channel,
+		ChatID:    h.ChatID,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			// Payloads come from outside and may try to instruct the
+			// agent, so they never run with admin rights.
+			bus.MetadataUser: bus.User{ID: "webhook:" + h.Name, Name: h.Name},
+		},
+	}
+	if len(h.Skills) > 0 {
+		msg.Metadata[bus.MetadataSkills] = h.Skills
+	}
+
+	if err := r.messageBus.Publish(req.Context(), h.Channel, msg); err != nil {
+		logger.Error("Failed to publish webhook", "hook", h.Name, "error", err)
+		http.Error(w, "failed to queue event", http.StatusServiceUnavailable)
+		return
+	}
+
+	logger.Info("Webhook received", "hook", h.Name, "channel", h.Channel, "chat_id", h.ChatID, "bytes", len(body))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"id": msg.ID})
+}
+
+func (h *hook) authorized(req *http.Request, body []byte) bool {
+	if h.Secret == "" {
+		return true
+	}
+
+	if signature, ok := strings.CutPrefix(req.Header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(expected))
+	}
+
+	token := req.Header.Get("X-Webhook-Token")
+	if token == "" {
+		token, _ = strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		token = req.URL.Query().Get("token")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.Secret)) == 1
+}
+
+func (h *hook) render(req *http.Request, body []byte) (string, error) {
+	event := Event{
+		Name:    h.Name,
+		Event:   eventType(req.Header),
+		Headers: make(map[string]string, len(req.Header)),
+	}
+	for key := range req.Header {
+		if key != "Authorization" && key != "X-Webhook-Token" {
+			event.Headers[key] = req.Header.Get(key)
+		}
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		event.Payload = payload
+	} else {
+		event.Payload = string(body)
+	}
+
+	var buf bytes.Buffer
+	if err := h.template.Execute(&buf, event); err != nil {
+		return "", err
+	}
+
+	content := truncate(maxEventLength, buf.String())
+	if h.Prompt != "" {
+		content = h.Prompt + "\n\n" + content
+	}
+	return content, nil
+}
+
+// eventType reads the event name the common senders put in a header.
+func eventType(header http.Header) string {
+	for _, key := range []string{"X-GitHub-Event", "X-Gitlab-Event", "X-Event-Key", "X-Event-Type"} {
+		if value := header.Get(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func truncate(limit int, text string) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit]) + "\n[truncated]"
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/bus"
+)
+
+func newTestReceiver(t *testing.T, hooks ...Hook) (*Receiver, <-chan *bus.Message) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	messageBus := bus.NewInMemoryMessageBus(ctx, nil)
+	messageBus.Start()
+	t.Cleanup(func() { messageBus.Close() })
+
+	received := make(chan *bus.Message, 4)
+	messageBus.Subscribe(bus.ChannelTelegram, func(ctx context.Context, msg *bus.Message) error {
+		received <- msg
+		return nil
+	})
+
+	receiver, err := NewReceiver(&Config{Hooks: hooks, MessageBus: messageBus})
+	if err != nil {
+		t.Fatalf("Failed to create receiver: %v", err)
+	}
+	return receiver, received
+}
+
+func post(receiver *Receiver, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.Handle("POST /hooks/{name}", receiver)
+
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGitHubWebhook(t *testing.T) {
+	receiver, received := newTestReceiver(t, Hook{
+		Name:     "github",
+		Secret:   "s3cret",
+		Template: "{{.Event}} on {{.Payload.repository.full_name}}: {{.Payload.issue.title}}",
+		Prompt:   "Triage this issue.",
+		Skills:   []string{"triage"},
+		Channel:  bus.ChannelTelegram,
+		ChatID:   "42",
+	})
+
+	body := `{"repository":{"full_name":"wjffsx/miniclaw_go"},"issue":{"title":"Crash on start"}}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+
+	rec := post(receiver, "/hooks/github", body, map[string]string{
+		"X-GitHub-Event":      "issues",
+		"X-Hub-Signature-256": "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+	})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case msg := <-received:
+		if msg.ChatID != "42" || msg.Content != "Triage this issue.\n\nissues on wjffsx/miniclaw_go: Crash on start" {
+			t.Errorf("Unexpected message in %s: %q", msg.ChatID, msg.Content)
+		}
+		if user, ok := msg.User(); !ok || user.Admin || user.ID != "webhook:github" {
+			t.Errorf("Expected a non-admin webhook user, got %+v", user)
+		}
+		if skills := msg.Skills(); len(skills) != 1 || skills[0] != "triage" {
+			t.Errorf("Expected the configured skills, got %v", skills)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the event to be published")
+	}
+
+	rec = post(receiver, "/hooks/github", body, map[string]string{"X-Hub-Signature-256": "sha256=00"})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong signature to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestWebhookTokenAndDefaultTemplate(t *testing.T) {
+	receiver, received := newTestReceiver(t, Hook{
+		Name:    "alerts",
+		Secret:  "token",
+		Channel: bus.ChannelTelegram,
+		ChatID:  "42",
+	})
+
+	if rec := post(receiver, "/hooks/alerts", `{}`, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a request without a token to be rejected, got %d", rec.Code)
+	}
+	if rec := post(receiver, "/hooks/missing", `{}`, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown hook, got %d", rec.Code)
+	}
+
+	rec := post(receiver, "/hooks/alerts?token=token", `{"status":"firing"}`, nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case msg := <-received:
+		if !strings.HasPrefix(msg.Content, "Webhook alerts received:") || !strings.Contains(msg.Content, `"status": "firing"`) {
+			t.Errorf("Expected the payload in the default template, got %q", msg.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the event to be published")
+	}
+}
+
+func TestNewReceiverRejectsInvalidHooks(t *testing.T) {
+	messageBus := bus.NewInMemoryMessageBus(context.Background(), nil)
+
+	for _, hooks := range [][]Hook{
+		{{Name: "", Channel: "telegram", ChatID: "1"}},
+		{{Name: "a", Channel: "telegram"}},
+		{{Name: "a", Channel: "telegram", ChatID: "1", Template: "{{.Payload"}},
+		{{Name: "a", Channel: "telegram", ChatID: "1"}, {Name: "a", Channel: "telegram", ChatID: "2"}},
+	} {
+		if _, err := NewReceiver(&Config{Hooks: hooks, MessageBus: messageBus}); err == nil {
+			t.Errorf("Expected %+v to be rejected", hooks)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+	"github.com/wjffsx/miniclaw_go/internal/tools"
+)
+
+type RecentChangesTool struct {
+	watcher *Watcher
+}
+
+func NewRecentChangesTool(watcher *Watcher) *RecentChangesTool {
+	return &RecentChangesTool{
+		watcher: watcher,
+	}
+}
+
+func (t *RecentChangesTool) Name() string {
+	return "recent_changes"
+}
+
+func (t *RecentChangesTool) Description() string {
+	return "List files in the workspace that were created, modified or deleted outside the agent"
+}
+
+func (t *RecentChangesTool) Parameters() json.RawMessage {
+	params := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"since_minutes": {
+				"type": "integer",
+				"description": "Only return changes from the last N minutes (optional)"
+			},
+			"limit": {
+				"type": "integer",
+				"description": "Maximum number of changes to return (default 20)",
+				"default": 20
+			}
+		},
+		"additionalProperties": false
+	}`)
+	return params
+}
+
+func (t *RecentChangesTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	limit := 20
+	if raw, exists := params["limit"]; exists {
+		l, ok := raw.(float64)
+		if !ok {
+			return "", &tools.ToolError{
+				Code:    "INVALID_PARAM",
+				Message: "limit parameter must be a number",
+			}
+		}
+		if l > 0 {
+			limit = int(l)
+		}
+	}
+
+	var since time.Time
+	if m, ok := params["since_minutes"].(float64); ok && m > 0 {
+		since = time.Now().Add(-time.Duration(m) * time.Minute)
+	}
+
+	changes := t.watcher.RecentChanges(since, limit)
+	if len(changes) == 0 {
+		return "No external changes in the workspace", nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Found %d recent changes in the workspace:\n\n", len(changes)))
+	for i, change := range changes {
+		output.WriteString(fmt.Sprintf("%d. [%s] %s at %s\n", i+1, change.Type, change.Path, change.Timestamp.Format(time.RFC3339)))
+	}
+
+	return output.String(), nil
+}
+
+type TrackedStorage struct {
+	storage  storage.Storage
+	watcher  *Watcher
+	basePath string
+}
+
+func NewTrackedStorage(store storage.Storage, basePath string, watcher *Watcher) *TrackedStorage {
+	absBase, err := filepath.Abs(basePath)
+	if err != nil {
+		absBase = basePath
+	}
+
+	return &TrackedStorage{
+		storage:  store,
+		watcher:  watcher,
+		basePath: absBase,
+	}
+}
+
+func (s *TrackedStorage) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	return s.storage.ReadFile(ctx, path)
+}
+
+func (s *TrackedStorage) WriteFile(ctx context.Context, path string, data []byte) error {
+	s.watcher.MarkInternal(filepath.Join(s.basePath, path))
+	return s.storage.WriteFile(ctx, path, data)
+}
+
+func (s *TrackedStorage) AppendFile(ctx context.Context, path string, data []byte) error {
+	s.watcher.MarkInternal(filepath.Join(s.basePath, path))
+	return storage.AppendFile(ctx, s.storage, path, data)
+}
+
+// Rename fails when the wrapped storage cannot rename, and callers then fall
+// back to copying.
+func (s *TrackedStorage) Rename(ctx context.Context, from, to string) error {
+	renamer, ok := s.storage.(storage.Renamer)
+	if !ok {
+		return fmt.Errorf("storage cannot rename files")
+	}
+
+	s.watcher.MarkInternal(filepath.Join(s.basePath, from))
+	s.watcher.MarkInternal(filepath.Join(s.basePath, to))
+	return renamer.Rename(ctx, from, to)
+}
+
+func (s *TrackedStorage) DeleteFile(ctx context.Context, path string) error {
+	s.watcher.MarkInternal(filepath.Join(s.basePath, path))
+	return s.storage.DeleteFile(ctx, path)
+}
+
+func (s *TrackedStorage) ListFiles(ctx context.Context, prefix string) ([]string, error) {
+	return s.storage.ListFiles(ctx, prefix)
+}
+
+func (s *TrackedStorage) FileExists(ctx context.Context, path string) (bool, error) {
+	return s.storage.FileExists(ctx, path)
+}
@@ -0,0 +1,357 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wjffsx/miniclaw_go/internal/logging"
+)
+
+var logger = logging.For("workspace")
+
+type ChangeType string
+
+const (
+	ChangeCreated  ChangeType = "created"
+	ChangeModified ChangeType = "modified"
+	ChangeDeleted  ChangeType = "deleted"
+)
+
+type Change struct {
+	Path      string     `json:"path"`
+	Type      ChangeType `json:"type"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+type WatcherConfig struct {
+	Directory      string
+	MaxChanges     int
+	IgnorePatterns []string
+	SuppressWindow time.Duration
+}
+
+type Watcher struct {
+	config   *WatcherConfig
+	root     string
+	watcher  *fsnotify.Watcher
+	cancel   context.CancelFunc
+	mu       sync.RWMutex
+	changes  []Change
+	internal map[string]time.Time
+	started  bool
+}
+
+func NewWatcher(config *WatcherConfig) (*Watcher, error) {
+	if config == nil {
+		return nil, fmt.Errorf("watcher config cannot be nil")
+	}
+
+	if config.Directory == "" {
+		return nil, fmt.Errorf("workspace directory cannot be empty")
+	}
+
+	if config.MaxChanges <= 0 {
+		config.MaxChanges = 100
+	}
+
+	if config.SuppressWindow <= 0 {
+		config.SuppressWindow = 2 * time.Second
+	}
+
+	if config.IgnorePatterns == nil {
+		config.IgnorePatterns = []string{".git", "node_modules", "*.swp", "*~", ".DS_Store"}
+	}
+
+	root, err := filepath.Abs(config.Directory)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	return &Watcher{
+		config:   config,
+		root:     root,
+		changes:  make([]Change, 0, config.MaxChanges),
+		internal: make(map[string]time.Time),
+	}, nil
+}
+
+func (w *Watcher) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.started {
+		return fmt.Errorf("workspace watcher already started")
+	}
+
+	info, err := os.Stat(w.root)
+	if err != nil {
+		return fmt.Errorf("failed to access workspace directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("workspace path is not a directory: %s", w.root)
+	}
+
+	// Every run gets its own fsnotify watcher and context so that a stopped
+	// watcher can be started again.
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := w.addRecursive(fsWatcher, w.root); err != nil {
+		fsWatcher.Close()
+		return fmt.Errorf("failed to watch workspace directory: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.watcher = fsWatcher
+	w.cancel = cancel
+	w.started = true
+
+	go w.processEvents(ctx, fsWatcher)
+
+	logger.Info("Workspace watcher started", "root", w.root)
+	return nil
+}
+
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+
+	if w.watcher != nil {
+		w.watcher.Close()
+		w.watcher = nil
+	}
+
+	w.started = false
+
+	logger.Info("Workspace watcher stopped")
+}
+
+func (w *Watcher) IsRunning() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.started
+}
+
+func (w *Watcher) GetRoot() string {
+	return w.root
+}
+
+func (w *Watcher) addRecursive(fsWatcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		if path != w.root && w.isIgnored(path) {
+			return filepath.SkipDir
+		}
+
+		return fsWatcher.Add(path)
+	})
+}
+
+func (w *Watcher) processEvents(ctx context.Context, fsWatcher *fsnotify.Watcher) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return
+			}
+
+			w.handleEvent(fsWatcher, event)
+
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Error("Workspace watcher error", "error", err)
+		}
+	}
+}
+
+func (w *Watcher) handleEvent(fsWatcher *fsnotify.Watcher, event fsnotify.Event) {
+	if w.isIgnored(event.Name) {
+		return
+	}
+
+	var changeType ChangeType
+	switch {
+	case event.Op&fsnotify.Create == fsnotify.Create:
+		changeType = ChangeCreated
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := w.addRecursive(fsWatcher, event.Name); err != nil {
+				logger.Warn("Failed to watch new directory", "path", event.Name, "error", err)
+			}
+		}
+	case event.Op&fsnotify.Write == fsnotify.Write:
+		changeType = ChangeModified
+	case event.Op&fsnotify.Remove == fsnotify.Remove, event.Op&fsnotify.Rename == fsnotify.Rename:
+		changeType = ChangeDeleted
+	default:
+		return
+	}
+
+	w.Record(event.Name, changeType)
+}
+
+func (w *Watcher) Record(path string, changeType ChangeType) {
+	absPath := w.absolutePath(path)
+	relPath := w.relativePath(absPath)
+	now := time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if marked, ok := w.internal[absPath]; ok {
+		if now.Sub(marked) < w.config.SuppressWindow {
+			return
+		}
+		delete(w.internal, absPath)
+	}
+
+	if n := len(w.changes); n > 0 {
+		last := &w.changes[n-1]
+		if last.Path == relPath && now.Sub(last.Timestamp) < 500*time.Millisecond {
+			if last.Type != ChangeCreated || changeType == ChangeDeleted {
+				last.Type = changeType
+			}
+			last.Timestamp = now
+			return
+		}
+	}
+
+	w.changes = append(w.changes, Change{
+		Path:      relPath,
+		Type:      changeType,
+		Timestamp: now,
+	})
+
+	if len(w.changes) > w.config.MaxChanges {
+		w.changes = w.changes[len(w.changes)-w.config.MaxChanges:]
+	}
+}
+
+// MarkInternal records that the agent is about to change path so that the
+// resulting event is not reported as an external change. Paths outside the
+// workspace are ignored.
+func (w *Watcher) MarkInternal(path string) {
+	absPath := w.absolutePath(path)
+	if !w.contains(absPath) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.internal[absPath] = time.Now()
+}
+
+func (w *Watcher) RecentChanges(since time.Time, limit int) []Change {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	result := make([]Change, 0)
+	for i := len(w.changes) - 1; i >= 0; i-- {
+		change := w.changes[i]
+		if !since.IsZero() && change.Timestamp.Before(since) {
+			break
+		}
+		result = append(result, change)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+
+	return result
+}
+
+func (w *Watcher) Clear() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.changes = make([]Change, 0, w.config.MaxChanges)
+}
+
+func (w *Watcher) Summary(limit int) string {
+	changes := w.RecentChanges(time.Time{}, limit)
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	for _, change := range changes {
+		builder.WriteString(fmt.Sprintf("- %s %s (%s)\n", change.Type, change.Path, change.Timestamp.Format("2006-01-02 15:04:05")))
+	}
+
+	return builder.String()
+}
+
+// absolutePath resolves path, relative to the workspace root when it is not
+// absolute, and follows symlinks in its directory so that it can be compared
+// with the paths fsnotify reports.
+func (w *Watcher) absolutePath(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(w.root, path)
+	}
+	path = filepath.Clean(path)
+
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		path = filepath.Join(dir, filepath.Base(path))
+	}
+
+	return path
+}
+
+func (w *Watcher) contains(absPath string) bool {
+	rel, err := filepath.Rel(w.root, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (w *Watcher) relativePath(path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Clean(path))
+	}
+
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return path
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+func (w *Watcher) isIgnored(path string) bool {
+	relPath := w.relativePath(path)
+
+	for _, part := range strings.Split(relPath, "/") {
+		for _, pattern := range w.config.IgnorePatterns {
+			if matched, _ := filepath.Match(pattern, part); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
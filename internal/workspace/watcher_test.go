@@ -0,0 +1,278 @@
+package workspace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wjffsx/miniclaw_go/internal/storage"
+)
+
+func TestNewWatcherNilConfig(t *testing.T) {
+	_, err := NewWatcher(nil)
+	if err == nil {
+		t.Error("Expected error for nil config")
+	}
+}
+
+func TestNewWatcherEmptyDirectory(t *testing.T) {
+	_, err := NewWatcher(&WatcherConfig{})
+	if err == nil {
+		t.Error("Expected error for empty directory")
+	}
+}
+
+func TestStartNonExistent(t *testing.T) {
+	watcher, err := NewWatcher(&WatcherConfig{Directory: "/nonexistent/workspace"})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	if err := watcher.Start(); err == nil {
+		t.Error("Expected error for nonexistent directory")
+	}
+}
+
+func TestRecordAndRecentChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	watcher, err := NewWatcher(&WatcherConfig{Directory: tempDir, MaxChanges: 2})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	watcher.Record(filepath.Join(tempDir, "a.txt"), ChangeCreated)
+	watcher.Record(filepath.Join(tempDir, "b.txt"), ChangeModified)
+	watcher.Record(filepath.Join(tempDir, "c.txt"), ChangeDeleted)
+
+	changes := watcher.RecentChanges(time.Time{}, 0)
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d", len(changes))
+	}
+
+	if changes[0].Path != "c.txt" || changes[0].Type != ChangeDeleted {
+		t.Errorf("Expected most recent change first, got %+v", changes[0])
+	}
+
+	if changes[1].Path != "b.txt" {
+		t.Errorf("Expected b.txt as second change, got %s", changes[1].Path)
+	}
+}
+
+func TestRecordCoalescesRapidEvents(t *testing.T) {
+	tempDir := t.TempDir()
+	watcher, err := NewWatcher(&WatcherConfig{Directory: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	path := filepath.Join(tempDir, "notes.md")
+	watcher.Record(path, ChangeCreated)
+	watcher.Record(path, ChangeModified)
+
+	changes := watcher.RecentChanges(time.Time{}, 0)
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change, got %d", len(changes))
+	}
+
+	if changes[0].Type != ChangeCreated {
+		t.Errorf("Expected created change to be kept, got %s", changes[0].Type)
+	}
+}
+
+func TestMarkInternalSuppressesChange(t *testing.T) {
+	tempDir := t.TempDir()
+	watcher, err := NewWatcher(&WatcherConfig{Directory: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	path := filepath.Join(tempDir, "agent.txt")
+	watcher.MarkInternal(path)
+	watcher.Record(path, ChangeModified)
+
+	if changes := watcher.RecentChanges(time.Time{}, 0); len(changes) != 0 {
+		t.Errorf("Expected agent change to be suppressed, got %d changes", len(changes))
+	}
+}
+
+func TestIgnorePatterns(t *testing.T) {
+	tempDir := t.TempDir()
+	watcher, err := NewWatcher(&WatcherConfig{Directory: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	if !watcher.isIgnored(filepath.Join(tempDir, ".git", "HEAD")) {
+		t.Error("Expected .git paths to be ignored")
+	}
+
+	if !watcher.isIgnored(filepath.Join(tempDir, "main.go.swp")) {
+		t.Error("Expected swap files to be ignored")
+	}
+
+	if watcher.isIgnored(filepath.Join(tempDir, "main.go")) {
+		t.Error("Expected main.go not to be ignored")
+	}
+}
+
+func TestWatchExternalChange(t *testing.T) {
+	tempDir := t.TempDir()
+	watcher, err := NewWatcher(&WatcherConfig{Directory: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("Failed to start watcher: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "external.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(watcher.RecentChanges(time.Time{}, 0)) > 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	changes := watcher.RecentChanges(time.Time{}, 0)
+	if len(changes) == 0 {
+		t.Fatal("Expected external change to be recorded")
+	}
+
+	if changes[0].Path != "external.txt" {
+		t.Errorf("Expected external.txt, got %s", changes[0].Path)
+	}
+}
+
+func TestTrackedStorageMarksWrites(t *testing.T) {
+	tempDir := t.TempDir()
+	watcher, err := NewWatcher(&WatcherConfig{Directory: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	tracked := NewTrackedStorage(storage.NewFileStorage(tempDir), tempDir, watcher)
+	if err := tracked.WriteFile(context.Background(), "written.txt", []byte("data")); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	watcher.Record(filepath.Join(tempDir, "written.txt"), ChangeCreated)
+
+	if changes := watcher.RecentChanges(time.Time{}, 0); len(changes) != 0 {
+		t.Errorf("Expected agent write to be suppressed, got %d changes", len(changes))
+	}
+}
+
+func TestTrackedStorageOutsideWorkspaceRoot(t *testing.T) {
+	baseDir := t.TempDir()
+	workspaceDir := filepath.Join(baseDir, "workspace")
+	if err := os.Mkdir(workspaceDir, 0755); err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	watcher, err := NewWatcher(&WatcherConfig{Directory: workspaceDir})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	tracked := NewTrackedStorage(storage.NewFileStorage(baseDir), baseDir, watcher)
+	if err := tracked.WriteFile(context.Background(), "workspace/written.txt", []byte("data")); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := tracked.WriteFile(context.Background(), "elsewhere.txt", []byte("data")); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	watcher.Record(filepath.Join(workspaceDir, "written.txt"), ChangeCreated)
+	if changes := watcher.RecentChanges(time.Time{}, 0); len(changes) != 0 {
+		t.Errorf("Expected agent write to be suppressed, got %+v", changes)
+	}
+
+	watcher.mu.RLock()
+	_, outside := watcher.internal[filepath.Join(baseDir, "elsewhere.txt")]
+	watcher.mu.RUnlock()
+	if outside {
+		t.Error("Expected writes outside the workspace not to be marked")
+	}
+}
+
+func TestRestartWatcher(t *testing.T) {
+	tempDir := t.TempDir()
+	watcher, err := NewWatcher(&WatcherConfig{Directory: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("Failed to start watcher: %v", err)
+	}
+	watcher.Stop()
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("Failed to restart watcher: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "after.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && len(watcher.RecentChanges(time.Time{}, 0)) == 0 {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if changes := watcher.RecentChanges(time.Time{}, 0); len(changes) == 0 || changes[0].Path != "after.txt" {
+		t.Errorf("Expected the restarted watcher to record after.txt, got %+v", changes)
+	}
+}
+
+func TestRecentChangesTool(t *testing.T) {
+	tempDir := t.TempDir()
+	watcher, err := NewWatcher(&WatcherConfig{Directory: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	tool := NewRecentChangesTool(watcher)
+	if tool.Name() != "recent_changes" {
+		t.Errorf("Expected name 'recent_changes', got '%s'", tool.Name())
+	}
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(result, "No external changes") {
+		t.Errorf("Expected empty result message, got %s", result)
+	}
+
+	watcher.Record(filepath.Join(tempDir, "todo.md"), ChangeModified)
+
+	result, err = tool.Execute(context.Background(), map[string]interface{}{"limit": float64(5)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(result, "todo.md") {
+		t.Errorf("Expected result to mention todo.md, got %s", result)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"limit": "five"}); err == nil {
+		t.Error("Expected error for invalid limit")
+	}
+}
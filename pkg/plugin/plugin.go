@@ -0,0 +1,167 @@
+// Package plugin is what tool plugins for MiniClaw are built with. A plugin
+// is an executable in the plugins directory that serves one or more tools:
+//
+//	type weather struct{}
+//
+//	func (weather) Name() string        { return "weather" }
+//	func (weather) Description() string { return "Current weather for a city" }
+//	func (weather) Parameters() json.RawMessage {
+//		return json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`)
+//	}
+//	func (weather) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+//		return "Sunny in " + params["city"].(string), nil
+//	}
+//
+//	func main() {
+//		plugin.Serve(weather{})
+//	}
+//
+// MiniClaw starts the executable and calls the tools over JSON-RPC on its
+// stdin and stdout, so anything the tools print goes to stderr, which ends
+// up in MiniClaw's log.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+)
+
+// MagicCookieKey is set to MagicCookieValue in the environment of the
+// plugins MiniClaw starts.
+const (
+	MagicCookieKey   = "MINICLAW_PLUGIN"
+	MagicCookieValue = "tools-v1"
+)
+
+// ServiceName is the RPC service plugins serve, with the methods List and
+// Execute.
+const ServiceName = "Plugin"
+
+// Tool has the same methods as the tools MiniClaw has built in.
+type Tool interface {
+	Name() string
+	Description() string
+	Parameters() json.RawMessage
+	Execute(ctx context.Context, params map[string]interface{}) (string, error)
+}
+
+type ToolSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type ListArgs struct{}
+
+type ListReply struct {
+	Tools []ToolSchema `json:"tools"`
+}
+
+type ExecuteArgs struct {
+	Tool   string                 `json:"tool"`
+	Params map[string]interface{} `json:"params"`
+}
+
+type ExecuteReply struct {
+	Result string `json:"result"`
+}
+
+// Service serves tools over RPC. Serve is all most plugins need.
+type Service struct {
+	tools map[string]Tool
+	order []string
+}
+
+func NewService(tools ...Tool) (*Service, error) {
+	s := &Service{tools: make(map[string]Tool, len(tools))}
+	for _, tool := range tools {
+		if tool.Name() == "" {
+			return nil, fmt.Errorf("tool name cannot be empty")
+		}
+		if _, exists := s.tools[tool.Name()]; exists {
+			return nil, fmt.Errorf("tool %s is served twice", tool.Name())
+		}
+		s.tools[tool.Name()] = tool
+		s.order = append(s.order, tool.Name())
+	}
+	return s, nil
+}
+
+func (s *Service) List(args ListArgs, reply *ListReply) error {
+	for _, name := range s.order {
+		tool := s.tools[name]
+		reply.Tools = append(reply.Tools, ToolSchema{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  tool.Parameters(),
+		})
+	}
+	return nil
+}
+
+// Execute runs a tool. MiniClaw gives up on calls that take longer than the
+// tool's timeout, but the plugin is not told, so long-running tools should
+// bound themselves.
+func (s *Service) Execute(args ExecuteArgs, reply *ExecuteReply) error {
+	tool, ok := s.tools[args.Tool]
+	if !ok {
+		return fmt.Errorf("unknown tool %s", args.Tool)
+	}
+
+	result, err := tool.Execute(context.Background(), args.Params)
+	if err != nil {
+		return err
+	}
+	reply.Result = result
+	return nil
+}
+
+// ServeConn answers requests on conn until it is closed.
+func (s *Service) ServeConn(conn io.ReadWriteCloser) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(ServiceName, s); err != nil {
+		return err
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	return nil
+}
+
+// Serve serves tools on stdin and stdout until MiniClaw closes stdin, then
+// exits. Whatever the tools write to os.Stdout is sent to stderr instead so
+// it cannot break the protocol.
+func Serve(tools ...Tool) {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		fmt.Fprintln(os.Stderr, "This is a MiniClaw tool plugin. Put it in the plugins directory instead of running it.")
+		os.Exit(1)
+	}
+
+	service, err := NewService(tools...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+
+	if err := service.ServeConn(&stdioConn{Reader: os.Stdin, Writer: stdout}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// stdioConn joins stdin and stdout into the connection RPC runs on.
+type stdioConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (c *stdioConn) Close() error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/rpc/jsonrpc"
+	"strings"
+	"testing"
+)
+
+type greetTool struct{}
+
+func (greetTool) Name() string        { return "greet" }
+func (greetTool) Description() string { return "Greets someone" }
+func (greetTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}}}`)
+}
+func (greetTool) Execute(ctx context.Context, params map[string]interface{}) (string, error) {
+	return "Hello, " + params["name"].(string), nil
+}
+
+func TestNewServiceRejectsDuplicates(t *testing.T) {
+	if _, err := NewService(greetTool{}, greetTool{}); err == nil {
+		t.Error("Expected a tool served twice to be rejected")
+	}
+}
+
+func TestServeConn(t *testing.T) {
+	service, err := NewService(greetTool{})
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	server, conn := net.Pipe()
+	go service.ServeConn(server)
+	client := jsonrpc.NewClient(conn)
+	defer client.Close()
+
+	var list ListReply
+	if err := client.Call(ServiceName+".List", ListArgs{}, &list); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list.Tools) != 1 || list.Tools[0].Name != "greet" || !strings.Contains(string(list.Tools[0].Parameters), `"name"`) {
+		t.Errorf("Unexpected tools %+v", list.Tools)
+	}
+
+	var reply ExecuteReply
+	if err := client.Call(ServiceName+".Execute", ExecuteArgs{Tool: "greet", Params: map[string]interface{}{"name": "Ada"}}, &reply); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if reply.Result != "Hello, Ada" {
+		t.Errorf("Expected a greeting, got %q", reply.Result)
+	}
+
+	err = client.Call(ServiceName+".Execute", ExecuteArgs{Tool: "wave"}, &reply)
+	if err == nil || !strings.Contains(err.Error(), "unknown tool wave") {
+		t.Errorf("Expected an unknown tool error, got %v", err)
+	}
+}